@@ -27,6 +27,31 @@ func main() {
 		&models.LinkedCard{},
 		&models.Address{},
 		&models.EmailVerification{},
+		&models.LedgerAccount{},
+		&models.JournalEntry{},
+		&models.Posting{},
+		&models.WalletHold{},
+		&models.Job{},
+		&models.IdempotencyKey{},
+		&models.Refund{},
+		&models.RefundStatusEvent{},
+		&models.ReconciliationRun{},
+		&models.ReconciliationMismatch{},
+		&models.AIAgentConfig{},
+		&models.Merchant{},
+		&models.RiskAssessment{},
+		&models.ExternalOrder{},
+		&models.AIPaymentConfirmation{},
+		&models.ScheduledPayment{},
+		&models.ScheduledPaymentRun{},
+		&models.Beneficiary{},
+		&models.PayoutBatch{},
+		&models.PayoutBatchRow{},
+		&models.PhoneVPA{},
+		&models.PhoneVerification{},
+		&models.FeePlan{},
+		&models.FeePlanVersion{},
+		&models.FeePlanAssignment{},
 	)
 
 	if err != nil {
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/zeusnotfound04/Tranza/pkg/razorpay/fake"
+)
+
+// Runs the Razorpay simulator for local development. Start the API with
+// RAZORPAY_BASE_URL pointed at this server and the webhook URL below pointed
+// back at the API.
+func main() {
+	addr := flag.String("addr", ":9090", "address to listen on")
+	webhookURL := flag.String("webhook-url", "http://localhost:8080/webhooks/razorpay", "where to deliver webhooks (empty disables them)")
+	webhookSecret := flag.String("webhook-secret", os.Getenv("RAZORPAY_WEBHOOK_SECRET"), "secret used to sign webhooks")
+	advance := flag.Bool("advance-on-fetch", true, "move payouts along their script each time they are fetched")
+	flag.Parse()
+
+	server := fake.NewServer(fake.Config{
+		KeyID:          os.Getenv("RAZORPAY_KEY_ID"),
+		KeySecret:      os.Getenv("RAZORPAY_KEY_SECRET"),
+		WebhookURL:     *webhookURL,
+		WebhookSecret:  *webhookSecret,
+		AdvanceOnFetch: *advance,
+	})
+
+	log.Printf("Razorpay simulator listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, server))
+}
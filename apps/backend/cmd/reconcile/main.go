@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zeusnotfound04/Tranza/config"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/pkg/razorpay"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"github.com/zeusnotfound04/Tranza/services"
+)
+
+// Reconciles Tranza's records against Razorpay for a day or a date range and
+// prints the mismatch report. The report is also saved for review through the
+// admin API. Exits with status 2 when mismatches are found, so it can gate
+// scheduled checks.
+func main() {
+	yesterday := time.Now().In(services.ReconciliationLocation).AddDate(0, 0, -1).Format("2006-01-02")
+
+	date := flag.String("date", yesterday, "day to reconcile (YYYY-MM-DD, IST)")
+	from := flag.String("from", "", "first day of a range to reconcile (YYYY-MM-DD, IST); overrides -date")
+	to := flag.String("to", "", "last day of the range (YYYY-MM-DD, IST); defaults to -from")
+	flag.Parse()
+
+	start, end, err := services.ReconciliationDay(*date)
+	if *from != "" {
+		if *to == "" {
+			*to = *from
+		}
+		if start, _, err = services.ReconciliationDay(*from); err == nil {
+			_, end, err = services.ReconciliationDay(*to)
+		}
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Load environment variables
+	config.LoadEnv()
+
+	// Connect to database
+	db := config.ConnectDB()
+	defer config.CloseDB(db)
+
+	razorpayClient := razorpay.NewClient(
+		os.Getenv("RAZORPAY_KEY_ID"),
+		os.Getenv("RAZORPAY_KEY_SECRET"),
+	)
+
+	reconciliationService := services.NewReconciliationService(
+		repositories.NewReconciliationRepository(db),
+		repositories.NewTransactionRepository(db),
+		repositories.NewExternalTransferRepository(db),
+		repositories.NewRefundRepository(db),
+		nil,
+		razorpayClient,
+	)
+
+	log.Printf("🔎 Reconciling %s to %s...", start.Format(time.RFC3339), end.Format(time.RFC3339))
+
+	run, err := reconciliationService.Run(start, end, models.ReconciliationTriggerCLI)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("Run %s\n", run.ID)
+	fmt.Printf("Razorpay: %d payments, %d refunds, %d payouts\n", run.RemotePayments, run.RemoteRefunds, run.RemotePayouts)
+	fmt.Printf("Matched: %d  Mismatches: %d\n", run.Matched, run.MismatchCount)
+
+	if run.MismatchCount == 0 {
+		log.Println("✅ Everything matches")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\nSOURCE\tKIND\tRAZORPAY ID\tREMOTE\tLOCAL\tDETAILS")
+	for _, m := range run.Mismatches {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s %s\t%s %s\t%s\n",
+			m.Source, m.Kind, m.RazorpayID,
+			m.RemoteAmount.StringFixed(2), m.RemoteStatus,
+			m.LocalAmount.StringFixed(2), m.LocalStatus,
+			m.Details)
+	}
+	w.Flush()
+
+	os.Exit(2)
+}
@@ -31,9 +31,34 @@ func main() {
 		&models.Wallet{},
 		&models.Address{}, // Added address management table
 		&models.AIPaymentRequest{},
+		&models.AIPaymentConfirmation{},
+		&models.ScheduledPayment{},
+		&models.ScheduledPaymentRun{},
+		&models.Beneficiary{},
+		&models.PayoutBatch{},
+		&models.PayoutBatchRow{},
+		&models.PhoneVPA{},
+		&models.PhoneVerification{},
+		&models.FeePlan{},
+		&models.FeePlanVersion{},
+		&models.FeePlanAssignment{},
 		&models.AISpendingLimit{},
 		&models.AISpendingTracker{},
 		&models.ExternalTransfer{}, // Added missing external transfers table
+		&models.LedgerAccount{},
+		&models.JournalEntry{},
+		&models.Posting{},
+		&models.WalletHold{},
+		&models.Job{},
+		&models.IdempotencyKey{},
+		&models.Refund{},
+		&models.RefundStatusEvent{},
+		&models.ReconciliationRun{},
+		&models.ReconciliationMismatch{},
+		&models.AIAgentConfig{},
+		&models.Merchant{},
+		&models.RiskAssessment{},
+		&models.ExternalOrder{},
 	)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
@@ -17,6 +19,32 @@ type RazorpayConfig struct {
 	RazorpayKeySecret string
 	WebhookSecret     string
 	Environment       string // "test" or "live"
+	BaseURL           string // Overrides the API endpoint, e.g. for a local simulator
+}
+
+// AIConfig selects the model used to read AI payment prompts
+type AIConfig struct {
+	Provider string // "gemini", "openai" or "regex"
+	APIKey   string
+	Model    string
+	BaseURL  string // Overrides the API endpoint, e.g. for an OpenAI-compatible server
+	Timeout  time.Duration
+}
+
+// CatalogConfig selects the store AI shopping orders are placed with
+type CatalogConfig struct {
+	Provider string // "local"
+	File     string // Product file for the local catalog (.json or .csv)
+}
+
+// SMSConfig selects the provider text messages are sent with
+type SMSConfig struct {
+	Provider   string // "twilio" or "log"
+	AccountSID string
+	AuthToken  string
+	From       string // Sending number or messaging service SID
+	BaseURL    string // Overrides the provider's API endpoint
+	Timeout    time.Duration
 }
 
 type OAuthConfig struct {
@@ -105,10 +133,14 @@ func LoadConfig() *RazorpayConfig {
 		RazorpayKeySecret: keySecret,
 		WebhookSecret:     webhookSecret,
 		Environment:       env,
+		BaseURL:           strings.TrimRight(os.Getenv("RAZORPAY_BASE_URL"), "/"),
 	}
 }
 
 func (c *RazorpayConfig) GetBaseURL() string {
+	if c.BaseURL != "" {
+		return c.BaseURL
+	}
 	return "https://api.razorpay.com/v1"
 }
 
@@ -142,3 +174,137 @@ func LoadOAuthConfig() *Config {
 		},
 	}
 }
+
+// LoadIdempotencyKeyTTL returns how long Idempotency-Key responses are kept
+// for replay, read from IDEMPOTENCY_KEY_TTL (e.g. "24h"). Defaults to 24 hours.
+func LoadIdempotencyKeyTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_KEY_TTL"))
+	if err != nil || ttl <= 0 {
+		return 24 * time.Hour
+	}
+	return ttl
+}
+
+// LoadBeneficiaryCoolingOff returns how long transfers to a newly added
+// beneficiary stay limited, read from BENEFICIARY_COOLING_OFF (e.g. "12h").
+// Defaults to 24 hours; "0s" turns the cooling-off period off.
+func LoadBeneficiaryCoolingOff() time.Duration {
+	coolingOff, err := time.ParseDuration(os.Getenv("BENEFICIARY_COOLING_OFF"))
+	if err != nil || coolingOff < 0 {
+		return 24 * time.Hour
+	}
+	return coolingOff
+}
+
+// LoadTransferCancelWindow returns how long a new external transfer waits
+// before it is sent to Razorpay, during which the user can cancel it, read
+// from TRANSFER_CANCEL_WINDOW (e.g. "2m"). Defaults to 30 seconds; "0s" sends
+// transfers right away.
+func LoadTransferCancelWindow() time.Duration {
+	window, err := time.ParseDuration(os.Getenv("TRANSFER_CANCEL_WINDOW"))
+	if err != nil || window < 0 {
+		return 30 * time.Second
+	}
+	return window
+}
+
+// LoadPhoneUPIHandles returns the UPI handles tried, in order, when looking
+// up the UPI ID of a phone number with Razorpay (phone@handle), read as a
+// comma-separated list from PHONE_UPI_HANDLES. Defaults to the largest apps'
+// handles; an empty value turns the lookup off.
+func LoadPhoneUPIHandles() []string {
+	value, ok := os.LookupEnv("PHONE_UPI_HANDLES")
+	if !ok {
+		return []string{"paytm", "ybl", "okaxis", "oksbi", "okhdfcbank", "okicici"}
+	}
+
+	var handles []string
+	for _, handle := range strings.Split(value, ",") {
+		handle = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(handle), "@"))
+		if handle != "" {
+			handles = append(handles, handle)
+		}
+	}
+	return handles
+}
+
+// LoadIFSCDirectoryFile returns the bank branch list (CSV) bank transfers are
+// checked against, read from IFSC_DIRECTORY_FILE. When unset, only the bank
+// code of an IFSC is checked.
+func LoadIFSCDirectoryFile() string {
+	return strings.TrimSpace(os.Getenv("IFSC_DIRECTORY_FILE"))
+}
+
+// LoadAIConfig reads the AI prompt provider settings. AI_PROVIDER picks
+// "gemini", "openai" or "regex"; when unset, Gemini is used if GEMINI_API_KEY
+// is present and pattern matching otherwise.
+func LoadAIConfig() *AIConfig {
+	provider := strings.ToLower(strings.TrimSpace(os.Getenv("AI_PROVIDER")))
+	if provider == "" {
+		provider = "regex"
+		if os.Getenv("GEMINI_API_KEY") != "" {
+			provider = "gemini"
+		}
+	}
+
+	timeout, err := time.ParseDuration(os.Getenv("AI_TIMEOUT"))
+	if err != nil || timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	cfg := &AIConfig{Provider: provider, Timeout: timeout}
+	switch provider {
+	case "gemini":
+		cfg.APIKey = os.Getenv("GEMINI_API_KEY")
+		cfg.Model = os.Getenv("GEMINI_MODEL")
+		cfg.BaseURL = os.Getenv("GEMINI_BASE_URL")
+	case "openai":
+		cfg.APIKey = os.Getenv("OPENAI_API_KEY")
+		cfg.Model = os.Getenv("OPENAI_MODEL")
+		cfg.BaseURL = os.Getenv("OPENAI_BASE_URL")
+	}
+	return cfg
+}
+
+// LoadCatalogConfig reads the shopping catalog settings. CATALOG_PROVIDER
+// defaults to "local", which serves CATALOG_FILE (default data/catalog.json).
+func LoadCatalogConfig() *CatalogConfig {
+	provider := strings.ToLower(strings.TrimSpace(os.Getenv("CATALOG_PROVIDER")))
+	if provider == "" {
+		provider = "local"
+	}
+
+	file := strings.TrimSpace(os.Getenv("CATALOG_FILE"))
+	if file == "" {
+		file = "data/catalog.json"
+	}
+
+	return &CatalogConfig{Provider: provider, File: file}
+}
+
+// LoadSMSConfig reads the SMS provider settings. SMS_PROVIDER picks "twilio"
+// or "log"; when unset, Twilio is used if TWILIO_ACCOUNT_SID is present. The
+// log provider sends nothing.
+func LoadSMSConfig() *SMSConfig {
+	provider := strings.ToLower(strings.TrimSpace(os.Getenv("SMS_PROVIDER")))
+	if provider == "" {
+		provider = "log"
+		if os.Getenv("TWILIO_ACCOUNT_SID") != "" {
+			provider = "twilio"
+		}
+	}
+
+	timeout, err := time.ParseDuration(os.Getenv("SMS_TIMEOUT"))
+	if err != nil || timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	return &SMSConfig{
+		Provider:   provider,
+		AccountSID: os.Getenv("TWILIO_ACCOUNT_SID"),
+		AuthToken:  os.Getenv("TWILIO_AUTH_TOKEN"),
+		From:       os.Getenv("TWILIO_FROM_NUMBER"),
+		BaseURL:    os.Getenv("TWILIO_BASE_URL"),
+		Timeout:    timeout,
+	}
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/services"
+	"github.com/zeusnotfound04/Tranza/utils"
+)
+
+type AIAgentController struct {
+	agentService *services.AIAgentService
+}
+
+func NewAIAgentController(agentService *services.AIAgentService) *AIAgentController {
+	return &AIAgentController{
+		agentService: agentService,
+	}
+}
+
+// SaveAgentConfig creates an agent configuration, or updates the fields sent
+// for an existing one
+// POST /api/v1/ai/agents
+func (ac *AIAgentController) SaveAgentConfig(c *gin.Context) {
+	userID, err := utils.GetUserIDStringFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	var req dto.AIAgentConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request data", err)
+		return
+	}
+
+	config, err := ac.agentService.SaveConfig(userID, &req)
+	if err != nil {
+		utils.BadRequestResponse(c, "Failed to save agent configuration", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Agent configuration saved", config)
+}
+
+// GetAgentConfigs lists the user's agent configurations
+// GET /api/v1/ai/agents
+func (ac *AIAgentController) GetAgentConfigs(c *gin.Context) {
+	userID, err := utils.GetUserIDStringFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	configs, err := ac.agentService.GetConfigs(userID)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to get agent configurations", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Agent configurations retrieved", configs)
+}
+
+// GetAgentConfig returns one agent configuration
+// GET /api/v1/ai/agents/:agent_id
+func (ac *AIAgentController) GetAgentConfig(c *gin.Context) {
+	userID, err := utils.GetUserIDStringFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	config, err := ac.agentService.GetConfig(userID, c.Param("agent_id"))
+	if err != nil {
+		utils.NotFoundResponse(c, "Agent configuration not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Agent configuration retrieved", config)
+}
+
+// DeleteAgentConfig removes an agent configuration
+// DELETE /api/v1/ai/agents/:agent_id
+func (ac *AIAgentController) DeleteAgentConfig(c *gin.Context) {
+	userID, err := utils.GetUserIDStringFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	if err := ac.agentService.DeleteConfig(userID, c.Param("agent_id")); err != nil {
+		utils.NotFoundResponse(c, "Agent configuration not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Agent configuration deleted", nil)
+}
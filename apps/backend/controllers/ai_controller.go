@@ -1,12 +1,16 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/pkg/ask"
 	"github.com/zeusnotfound04/Tranza/services"
 	"github.com/zeusnotfound04/Tranza/utils"
 )
@@ -15,24 +19,27 @@ type AIController struct {
 	aiService      *services.AIService
 	walletService  *services.WalletService
 	paymentService *services.PaymentService
+	assistant      *services.AssistantService
 }
 
 func NewAIController(
 	aiService *services.AIService,
 	walletService *services.WalletService,
 	paymentService *services.PaymentService,
+	assistant *services.AssistantService,
 ) *AIController {
 	return &AIController{
 		aiService:      aiService,
 		walletService:  walletService,
 		paymentService: paymentService,
+		assistant:      assistant,
 	}
 }
 
 // ProcessPaymentRequest handles natural language payment requests
 func (ac *AIController) ProcessPaymentRequest(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	userUUID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
@@ -43,16 +50,41 @@ func (ac *AIController) ProcessPaymentRequest(c *gin.Context) {
 		return
 	}
 
-	// Convert userID to UUID
-	userUUID, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
+	// A payment made with an API key acts as the key's agent; a key bound to
+	// no agent cannot take on one by naming it
+	if value, ok := c.Get("api_key"); ok {
+		if apiKey, ok := value.(*models.APIKey); ok {
+			req.FromAPIKey = true
+			req.APIKeyAgentID = apiKey.AgentID
+			if apiKey.AgentID != "" {
+				if req.AgentID != "" && req.AgentID != apiKey.AgentID {
+					c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("this API key acts as agent %s", apiKey.AgentID)})
+					return
+				}
+				req.AgentID = apiKey.AgentID
+			}
+		}
 	}
 
 	// Process the AI payment request
+	req.IPAddress = c.ClientIP()
 	response, err := ac.aiService.ProcessPaymentRequest(userUUID, req)
 	if err != nil {
+		var policyErr *services.AgentPolicyError
+		if errors.As(err, &policyErr) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "policy": policyErr})
+			return
+		}
+		var riskErr *services.RiskDecisionError
+		if errors.As(err, &riskErr) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "risk": riskErr})
+			return
+		}
+		var limitErr *services.LimitExceededError
+		if errors.As(err, &limitErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "limit": limitErr})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -60,10 +92,11 @@ func (ac *AIController) ProcessPaymentRequest(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusOK, "Payment request processed", response)
 }
 
-// ConfirmPayment handles payment confirmation
+// ConfirmPayment confirms or cancels a payment request with the confirmation
+// token handed out when it was made
 func (ac *AIController) ConfirmPayment(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	userUUID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
@@ -74,13 +107,6 @@ func (ac *AIController) ConfirmPayment(c *gin.Context) {
 		return
 	}
 
-	// Convert userID to UUID
-	userUUID, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
-	}
-
 	// Parse payment ID
 	paymentUUID, err := uuid.Parse(req.PaymentID)
 	if err != nil {
@@ -89,14 +115,43 @@ func (ac *AIController) ConfirmPayment(c *gin.Context) {
 	}
 
 	// Process confirmation
-	result, err := ac.aiService.ConfirmPayment(userUUID, paymentUUID, req.Confirmed)
+	result, err := ac.aiService.ConfirmPayment(userUUID, paymentUUID, &services.PaymentConfirmation{
+		ConfirmationSource: confirmationSource(c, req.ConfirmedBy),
+		Confirmed:          req.Confirmed,
+		Token:              req.ConfirmationToken,
+		Password:           req.Password,
+		TOTPCode:           req.TOTPCode,
+	})
 	if err != nil {
+		var stepUpErr *services.StepUpRequiredError
+		if errors.As(err, &stepUpErr) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error(), "step_up": stepUpErr})
+			return
+		}
+		switch {
+		case errors.Is(err, services.ErrConfirmationTokenInvalid), errors.Is(err, services.ErrStepUpFailed):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, services.ErrConfirmationExpired), errors.Is(err, services.ErrTooManyConfirmationAttempts):
+			c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+			return
+		}
+		var policyErr *services.AgentPolicyError
+		if errors.As(err, &policyErr) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "policy": policyErr})
+			return
+		}
+		var limitErr *services.LimitExceededError
+		if errors.As(err, &limitErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "limit": limitErr})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	if req.Confirmed {
-		utils.SuccessResponse(c, http.StatusOK, "Payment confirmed and processed", result)
+		utils.SuccessResponse(c, http.StatusOK, "Payment confirmed, paying merchant", result)
 	} else {
 		utils.SuccessResponse(c, http.StatusOK, "Payment cancelled", result)
 	}
@@ -155,16 +210,9 @@ func (ac *AIController) GetPaymentHistory(c *gin.Context) {
 
 // GetSpendingLimits returns user's AI spending limits
 func (ac *AIController) GetSpendingLimits(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
-	}
-
-	// Convert userID to UUID
-	userUUID, err := uuid.Parse(userID.(string))
+	userUUID, err := utils.GetUserIDFromContext(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
@@ -180,8 +228,8 @@ func (ac *AIController) GetSpendingLimits(c *gin.Context) {
 
 // UpdateSpendingLimits updates user's AI spending limits
 func (ac *AIController) UpdateSpendingLimits(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	userUUID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
@@ -192,21 +240,32 @@ func (ac *AIController) UpdateSpendingLimits(c *gin.Context) {
 		return
 	}
 
-	// Convert userID to UUID
-	userUUID, err := uuid.Parse(userID.(string))
+	// Update spending limits
+	limits, err := ac.aiService.UpdateSpendingLimits(userUUID, req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Update spending limits
-	limits, err := ac.aiService.UpdateSpendingLimits(userUUID, req)
+	utils.SuccessResponse(c, http.StatusOK, "Spending limits updated", limits)
+}
+
+// GetRemainingBudget returns what the user has spent through AI today, this
+// week and this month, and what each limit has left
+func (ac *AIController) GetRemainingBudget(c *gin.Context) {
+	userUUID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	budget, err := ac.aiService.GetRemainingBudget(userUUID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	utils.SuccessResponse(c, http.StatusOK, "Spending limits updated", limits)
+	utils.SuccessResponse(c, http.StatusOK, "Remaining AI budget retrieved", budget)
 }
 
 // GetSpendingAnalytics returns spending analytics and insights
@@ -302,7 +361,8 @@ func (ac *AIController) CancelPaymentRequest(c *gin.Context) {
 	}
 
 	// Cancel payment request
-	err = ac.aiService.CancelPaymentRequest(userUUID, paymentUUID)
+	source := confirmationSource(c, "")
+	err = ac.aiService.CancelPaymentRequest(userUUID, paymentUUID, &source)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -310,3 +370,90 @@ func (ac *AIController) CancelPaymentRequest(c *gin.Context) {
 
 	utils.SuccessResponse(c, http.StatusOK, "Payment request cancelled", nil)
 }
+
+// AskQuestion answers a question about the user's spending or transfers.
+// Serves both signed-in users and bots.
+func (ac *AIController) AskQuestion(c *gin.Context) {
+	userUUID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req dto.AskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := ac.assistant.Ask(userUUID, &req)
+	if err != nil {
+		if errors.Is(err, ask.ErrUnsupported) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Question answered", response)
+}
+
+// GetPaymentConfirmations returns who confirmed, cancelled or failed to confirm
+// a payment request, and from which channel
+func (ac *AIController) GetPaymentConfirmations(c *gin.Context) {
+	userUUID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	paymentUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+
+	confirmations, err := ac.aiService.GetPaymentConfirmations(userUUID, paymentUUID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Payment confirmations retrieved", confirmations)
+}
+
+// confirmationSource works out the channel a request came from: bot keys are
+// the Slack bot, other API keys are integrations and everything else is the
+// web app. confirmedBy names the Slack user and is only trusted from bot keys.
+func confirmationSource(c *gin.Context, confirmedBy string) services.ConfirmationSource {
+	source := services.ConfirmationSource{
+		Channel:   models.AIConfirmationChannelWeb,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	}
+	if userID, err := utils.GetUserIDFromContext(c); err == nil {
+		source.Actor = userID.String()
+	}
+
+	value, ok := c.Get("api_key")
+	if !ok {
+		return source
+	}
+	apiKey, ok := value.(*models.APIKey)
+	if !ok {
+		return source
+	}
+
+	source.Channel = models.AIConfirmationChannelAPI
+	source.Actor = fmt.Sprintf("api_key:%d", apiKey.ID)
+	if apiKey.IsBot() {
+		source.Channel = models.AIConfirmationChannelSlack
+		if confirmedBy != "" {
+			source.Actor = "slack:" + confirmedBy
+		} else if apiKey.BotUserID != "" {
+			source.Actor = "slack:" + apiKey.BotUserID
+		}
+	}
+	return source
+}
@@ -66,7 +66,7 @@ func (c *APIKeyController) CreateAPIKey(ctx *gin.Context) {
 
 	fmt.Printf("DEBUG CreateAPIKey: Creating API key for user %s with label '%s' and TTL %d hours\n", userUUID, req.Label, req.TTLHours)
 
-	rawKey, err := c.apiKeyService.Generate(ctx.Request.Context(), userUUID, req.Label, req.Password, req.TTLHours)
+	rawKey, err := c.apiKeyService.Generate(ctx.Request.Context(), userUUID, req.Label, req.Password, req.TTLHours, req.AgentID)
 	if err != nil {
 		fmt.Printf("DEBUG CreateAPIKey: Failed to generate API key: %v\n", err)
 		utils.InternalServerErrorResponse(ctx, "Failed to create API key", err)
@@ -79,6 +79,7 @@ func (c *APIKeyController) CreateAPIKey(ctx *gin.Context) {
 		APIKey:   rawKey,
 		Label:    req.Label,
 		TTLHours: req.TTLHours,
+		AgentID:  req.AgentID,
 		Message:  "Universal API key created successfully. This key works with all features including Slack bot integration. Store it securely as it won't be shown again.",
 	}
 
@@ -114,7 +115,7 @@ func (c *APIKeyController) CreateBotAPIKey(ctx *gin.Context) {
 	}
 
 	// Use the same Generate method - all keys are now universal
-	rawKey, err := c.apiKeyService.Generate(ctx.Request.Context(), userUUID, req.Label, req.Password, ttl)
+	rawKey, err := c.apiKeyService.Generate(ctx.Request.Context(), userUUID, req.Label, req.Password, ttl, req.AgentID)
 	if err != nil {
 		utils.InternalServerErrorResponse(ctx, "Failed to create API key", err)
 		return
@@ -126,6 +127,7 @@ func (c *APIKeyController) CreateBotAPIKey(ctx *gin.Context) {
 		WorkspaceID: req.WorkspaceID,
 		BotUserID:   req.BotUserID,
 		TTLHours:    ttl,
+		AgentID:     req.AgentID,
 		Scopes: []string{
 			"*", // Universal access
 		},
@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/services"
+	"github.com/zeusnotfound04/Tranza/utils"
+)
+
+type BeneficiaryController struct {
+	beneficiaryService *services.BeneficiaryService
+}
+
+func NewBeneficiaryController(beneficiaryService *services.BeneficiaryService) *BeneficiaryController {
+	return &BeneficiaryController{
+		beneficiaryService: beneficiaryService,
+	}
+}
+
+// AddBeneficiary saves a transfer recipient under a nickname
+// POST /api/v1/beneficiaries
+func (bc *BeneficiaryController) AddBeneficiary(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	var req dto.CreateBeneficiaryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request data", err)
+		return
+	}
+
+	beneficiary, err := bc.beneficiaryService.AddBeneficiary(userID, &req)
+	if err != nil {
+		respondBeneficiaryError(c, "Failed to add beneficiary", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Beneficiary added", beneficiary)
+}
+
+// GetBeneficiaries lists the user's beneficiaries
+// GET /api/v1/beneficiaries
+func (bc *BeneficiaryController) GetBeneficiaries(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	beneficiaries, err := bc.beneficiaryService.GetBeneficiaries(userID)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to get beneficiaries", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Beneficiaries retrieved", beneficiaries)
+}
+
+// GetBeneficiary returns a beneficiary by ID or nickname
+// GET /api/v1/beneficiaries/:id
+func (bc *BeneficiaryController) GetBeneficiary(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	beneficiary, err := bc.beneficiaryService.GetBeneficiary(userID, c.Param("id"))
+	if err != nil {
+		utils.NotFoundResponse(c, "Beneficiary not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Beneficiary retrieved", beneficiary)
+}
+
+// RenameBeneficiary changes a beneficiary's nickname
+// PUT /api/v1/beneficiaries/:id
+func (bc *BeneficiaryController) RenameBeneficiary(c *gin.Context) {
+	userID, beneficiaryID, ok := bc.beneficiaryParams(c)
+	if !ok {
+		return
+	}
+
+	var req dto.RenameBeneficiaryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request data", err)
+		return
+	}
+
+	beneficiary, err := bc.beneficiaryService.RenameBeneficiary(userID, beneficiaryID, &req)
+	if err != nil {
+		respondBeneficiaryError(c, "Failed to rename beneficiary", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Beneficiary renamed", beneficiary)
+}
+
+// VerifyBeneficiary retries registering a beneficiary whose verification failed
+// POST /api/v1/beneficiaries/:id/verify
+func (bc *BeneficiaryController) VerifyBeneficiary(c *gin.Context) {
+	userID, beneficiaryID, ok := bc.beneficiaryParams(c)
+	if !ok {
+		return
+	}
+
+	beneficiary, err := bc.beneficiaryService.VerifyBeneficiary(userID, beneficiaryID)
+	if err != nil {
+		utils.NotFoundResponse(c, "Beneficiary not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Beneficiary is "+beneficiary.VerificationStatus, beneficiary)
+}
+
+// DeleteBeneficiary removes a beneficiary
+// DELETE /api/v1/beneficiaries/:id
+func (bc *BeneficiaryController) DeleteBeneficiary(c *gin.Context) {
+	userID, beneficiaryID, ok := bc.beneficiaryParams(c)
+	if !ok {
+		return
+	}
+
+	if err := bc.beneficiaryService.DeleteBeneficiary(userID, beneficiaryID); err != nil {
+		utils.NotFoundResponse(c, "Beneficiary not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Beneficiary deleted", nil)
+}
+
+// beneficiaryParams reads the user and the beneficiary ID from the request,
+// responding with an error if either is missing
+func (bc *BeneficiaryController) beneficiaryParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	beneficiaryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid beneficiary ID", err)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, beneficiaryID, true
+}
+
+func respondBeneficiaryError(c *gin.Context, message string, err error) {
+	if errors.Is(err, services.ErrBeneficiaryExists) {
+		utils.ErrorResponse(c, http.StatusConflict, message, err)
+		return
+	}
+	utils.BadRequestResponse(c, message, err)
+}
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/services"
+	"github.com/zeusnotfound04/Tranza/utils"
+)
+
+type ClothingController struct {
+	clothingService *services.ClothingService
+}
+
+func NewClothingController(clothingService *services.ClothingService) *ClothingController {
+	return &ClothingController{
+		clothingService: clothingService,
+	}
+}
+
+// ProcessAIClothingOrder suggests products for a shopping prompt and drafts an order
+// POST /api/v1/ai/clothing/order
+func (cc *ClothingController) ProcessAIClothingOrder(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	var req models.AIClothingOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request data", err)
+		return
+	}
+
+	result, err := cc.clothingService.ProcessAIClothingOrder(userID, &req)
+	if err != nil {
+		utils.BadRequestResponse(c, "Failed to process clothing order", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, result.Message, result)
+}
+
+// ConfirmAIClothingOrder pays for a drafted order and places it with the store
+// POST /api/v1/ai/clothing/confirm
+func (cc *ClothingController) ConfirmAIClothingOrder(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	var req models.ConfirmAIClothingOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request data", err)
+		return
+	}
+
+	result, err := cc.clothingService.ConfirmAIClothingOrder(userID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrClothingOverBudget) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		respondDebitError(c, "Failed to confirm clothing order", err)
+		return
+	}
+
+	if !result.Success {
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": result.Message, "data": result})
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, result.Message, result)
+}
+
+// GetClothingOrders lists the user's clothing orders, optionally filtered by ?status=
+// GET /api/v1/ai/clothing/orders
+func (cc *ClothingController) GetClothingOrders(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	page, limit := utils.GetPaginationParams(c)
+
+	orders, total, err := cc.clothingService.GetOrders(userID, c.Query("status"), page, limit)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to get clothing orders", err)
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, "Clothing orders retrieved", orders, page, limit, total)
+}
+
+// GetClothingOrder returns a clothing order with its latest delivery status
+// GET /api/v1/ai/clothing/orders/:id
+func (cc *ClothingController) GetClothingOrder(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid order ID", err)
+		return
+	}
+
+	order, err := cc.clothingService.GetOrder(userID, orderID)
+	if err != nil {
+		utils.NotFoundResponse(c, "Clothing order not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Clothing order retrieved", order)
+}
+
+// CancelClothingOrder cancels a draft or an unshipped order, refunding it if paid
+// POST /api/v1/ai/clothing/orders/:id/cancel
+func (cc *ClothingController) CancelClothingOrder(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	orderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid order ID", err)
+		return
+	}
+
+	order, err := cc.clothingService.CancelOrder(userID, orderID)
+	if err != nil {
+		utils.BadRequestResponse(c, "Failed to cancel clothing order", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Clothing order cancelled", order)
+}
@@ -1,11 +1,13 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models"
 	"github.com/zeusnotfound04/Tranza/models/dto"
 	"github.com/zeusnotfound04/Tranza/services"
 	"github.com/zeusnotfound04/Tranza/utils"
@@ -75,10 +77,13 @@ func (c *ExternalTransferController) CreateTransfer(ctx *gin.Context) {
 		utils.BadRequestResponse(ctx, "Invalid request body", err)
 		return
 	}
+	req.InitiatedBy = models.InitiatedByUser
+	req.IPAddress = ctx.ClientIP()
+	req.UserAgent = ctx.GetHeader("User-Agent")
 
 	response, err := c.externalTransferService.CreateExternalTransfer(userUUID.String(), &req)
 	if err != nil {
-		utils.BadRequestResponse(ctx, "Failed to create transfer", err)
+		respondDebitError(ctx, "Failed to create transfer", err)
 		return
 	}
 
@@ -103,6 +108,25 @@ func (c *ExternalTransferController) GetTransfer(ctx *gin.Context) {
 	utils.SuccessResponse(ctx, http.StatusOK, "Transfer retrieved successfully", response)
 }
 
+// CancelTransfer cancels a transfer that has not been paid out yet and
+// releases its reserved funds
+// POST /api/transfers/:id/cancel
+func (c *ExternalTransferController) CancelTransfer(ctx *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		utils.UnauthorizedResponse(ctx, "User not authenticated")
+		return
+	}
+
+	response, err := c.externalTransferService.CancelExternalTransfer(userID.String(), ctx.Param("id"))
+	if err != nil {
+		respondCancelTransferError(ctx, err)
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Transfer cancelled successfully", response)
+}
+
 // GetUserTransfers retrieves transfers for the authenticated user with pagination
 // GET /api/transfers
 func (c *ExternalTransferController) GetUserTransfers(ctx *gin.Context) {
@@ -161,6 +185,9 @@ func (c *ExternalTransferController) BotValidateTransfer(ctx *gin.Context) {
 		Amount:         req.Amount,
 		RecipientType:  req.RecipientType,
 		RecipientValue: req.RecipientValue,
+		RecipientIFSC:  req.RecipientIFSC,
+		BeneficiaryID:  req.Beneficiary,
+		InitiatedBy:    models.InitiatedByBot,
 	}
 
 	response, err := c.externalTransferService.ValidateTransferRequest(userUUID.String(), validateReq)
@@ -177,6 +204,11 @@ func (c *ExternalTransferController) BotValidateTransfer(ctx *gin.Context) {
 		TransferFee:   response.TransferFee,
 		TotalAmount:   response.TotalAmount,
 		EstimatedTime: response.EstimatedTime,
+		TransferMode:  response.TransferMode,
+		Bank:          response.Bank,
+		RecipientName: response.RecipientName,
+		ResolvedVPA:   response.ResolvedVPA,
+		ResolvedVia:   response.ResolvedVia,
 	}
 
 	utils.SuccessResponse(ctx, http.StatusOK, "Transfer validation completed", botResponse)
@@ -209,13 +241,20 @@ func (c *ExternalTransferController) BotCreateTransfer(ctx *gin.Context) {
 		Amount:         req.Amount,
 		RecipientType:  req.RecipientType,
 		RecipientValue: req.RecipientValue,
+		RecipientIFSC:  req.RecipientIFSC,
 		RecipientName:  req.RecipientName,
+		BeneficiaryID:  req.Beneficiary,
 		Description:    req.Description,
+		ResolvedVia:    req.ResolvedVia,
+		ResolvedVPA:    req.ResolvedVPA,
+		InitiatedBy:    models.InitiatedByBot,
+		IPAddress:      ctx.ClientIP(),
+		UserAgent:      ctx.GetHeader("User-Agent"),
 	}
 
 	response, err := c.externalTransferService.CreateExternalTransfer(userUUID.String(), transferReq)
 	if err != nil {
-		utils.BadRequestResponse(ctx, "Failed to create transfer", err)
+		respondDebitError(ctx, "Failed to create transfer", err)
 		return
 	}
 
@@ -227,13 +266,25 @@ func (c *ExternalTransferController) BotCreateTransfer(ctx *gin.Context) {
 		TransferFee:   response.TransferFee,
 		TotalAmount:   response.TotalAmount,
 		Status:        response.Status,
-		Recipient:     response.RecipientValue,
+		Recipient:     botRecipient(response),
 		EstimatedTime: response.EstimatedTime,
+
+		CancellableUntil: response.CancellableUntil,
 	}
 
 	utils.SuccessResponse(ctx, http.StatusCreated, "Transfer initiated successfully", botResponse)
 }
 
+// botRecipient names the recipient the way the bot should show it, e.g.
+// "Sunita Sharma (mom@okicici)" for a saved beneficiary or a phone number
+// whose owner was found
+func botRecipient(response *dto.ExternalTransferResponse) string {
+	if (response.BeneficiaryID == "" && response.ResolvedVia == "") || response.RecipientName == "" {
+		return response.RecipientValue
+	}
+	return fmt.Sprintf("%s (%s)", response.RecipientName, response.RecipientValue)
+}
+
 // BotGetTransferStatus retrieves transfer status for bot users
 // GET /api/bot/transfers/:id/status
 func (c *ExternalTransferController) BotGetTransferStatus(ctx *gin.Context) {
@@ -257,11 +308,51 @@ func (c *ExternalTransferController) BotGetTransferStatus(ctx *gin.Context) {
 		Amount:        response.Amount,
 		Recipient:     response.RecipientValue,
 		EstimatedTime: response.EstimatedTime,
+
+		CancellableUntil: response.CancellableUntil,
 	}
 
 	utils.SuccessResponse(ctx, http.StatusOK, "Transfer status retrieved successfully", botResponse)
 }
 
+// BotCancelTransfer cancels a transfer for bot users, e.g. from a Slack
+// "Cancel" button shown while the transfer is queued
+// POST /api/bot/transfers/:id/cancel
+func (c *ExternalTransferController) BotCancelTransfer(ctx *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		utils.UnauthorizedResponse(ctx, "Bot user not authenticated")
+		return
+	}
+
+	response, err := c.externalTransferService.CancelExternalTransfer(userID.String(), ctx.Param("id"))
+	if err != nil {
+		respondCancelTransferError(ctx, err)
+		return
+	}
+
+	botResponse := &dto.BotTransferStatusResponse{
+		TransferID:  response.ID,
+		ReferenceID: response.ReferenceID,
+		Status:      response.Status,
+		Amount:      response.Amount,
+		Recipient:   response.RecipientValue,
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Transfer cancelled successfully", botResponse)
+}
+
+func respondCancelTransferError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrTransferNotFound):
+		utils.NotFoundResponse(ctx, "Transfer not found")
+	case errors.Is(err, services.ErrTransferNotCancellable):
+		utils.ErrorResponse(ctx, http.StatusConflict, "Transfer cannot be cancelled", err)
+	default:
+		utils.BadRequestResponse(ctx, "Failed to cancel transfer", err)
+	}
+}
+
 
 func (c *ExternalTransferController) BotGetWalletBalance(ctx *gin.Context) {
 	userID, exists := ctx.Get("user_id")
@@ -297,22 +388,19 @@ func (c *ExternalTransferController) BotGetWalletBalance(ctx *gin.Context) {
 	utils.SuccessResponse(ctx, http.StatusOK, "Wallet balance retrieved successfully", response)
 }
 
+// GetTransferFees shows the fee plan the user's transfers are priced with
+// GET /api/v1/transfers/fees
 func (c *ExternalTransferController) GetTransferFees(ctx *gin.Context) {
-	response := &dto.TransferFeesResponse{
-		UPIFee:       utils.ParseDecimal("2.00"),   // ₹2 for UPI transfers
-		PhoneFee:     utils.ParseDecimal("5.00"),   // ₹5 for phone transfers
-		MinAmount:    utils.ParseDecimal("1.00"),   // ₹1 minimum
-		MaxAmount:    utils.ParseDecimal("100000"), // ₹1,00,000 maximum
-		DailyLimit:   utils.ParseDecimal("50000"),  // ₹50,000 daily limit
-		MonthlyLimit: utils.ParseDecimal("200000"), // ₹2,00,000 monthly limit
-		FeeStructure: []dto.FeeRange{
-			{
-				MinAmount: utils.ParseDecimal("1"),
-				MaxAmount: utils.ParseDecimal("100000"),
-				Fee:       utils.ParseDecimal("2"),
-				FeeType:   "fixed",
-			},
-		},
+	userID, err := utils.GetUserIDFromContext(ctx)
+	if err != nil {
+		utils.UnauthorizedResponse(ctx, "User not authenticated")
+		return
+	}
+
+	response, err := c.externalTransferService.GetTransferFees(userID)
+	if err != nil {
+		utils.InternalServerErrorResponse(ctx, "Failed to get transfer fees", err)
+		return
 	}
 
 	utils.SuccessResponse(ctx, http.StatusOK, "Transfer fees retrieved successfully", response)
@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"github.com/zeusnotfound04/Tranza/services"
+	"github.com/zeusnotfound04/Tranza/utils"
+)
+
+type FeePlanController struct {
+	feeService *services.FeeService
+}
+
+func NewFeePlanController(feeService *services.FeeService) *FeePlanController {
+	return &FeePlanController{
+		feeService: feeService,
+	}
+}
+
+// CreateFeePlan adds a fee plan transfers can be priced with
+// POST /api/v1/admin/fee-plans
+func (fc *FeePlanController) CreateFeePlan(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	var req dto.CreateFeePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request data", err)
+		return
+	}
+
+	plan, err := fc.feeService.CreatePlan(&req)
+	if err != nil {
+		utils.BadRequestResponse(c, "Failed to create fee plan", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Fee plan created", plan)
+}
+
+// GetFeePlans lists the fee plans
+// GET /api/v1/admin/fee-plans
+func (fc *FeePlanController) GetFeePlans(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	plans, err := fc.feeService.GetPlans()
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to get fee plans", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Fee plans retrieved", plans)
+}
+
+// UpdateFeePlan changes a fee plan as a new version, or deactivates it
+// PUT /api/v1/admin/fee-plans/:id
+func (fc *FeePlanController) UpdateFeePlan(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	var req dto.UpdateFeePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request data", err)
+		return
+	}
+
+	plan, err := fc.feeService.UpdatePlan(c.Param("id"), &req)
+	if err != nil {
+		utils.BadRequestResponse(c, "Failed to update fee plan", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Fee plan updated", plan)
+}
+
+// GetFeePlanVersions lists the terms of every version of a fee plan
+// GET /api/v1/admin/fee-plans/:id/versions
+func (fc *FeePlanController) GetFeePlanVersions(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	versions, err := fc.feeService.GetPlanVersions(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, repositories.ErrFeePlanNotFound) {
+			utils.NotFoundResponse(c, "Fee plan not found")
+			return
+		}
+		utils.BadRequestResponse(c, "Failed to get fee plan versions", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Fee plan versions retrieved", versions)
+}
+
+// AssignFeePlan puts a user on a fee plan, or back on the default one
+// PUT /api/v1/admin/users/:id/fee-plan
+func (fc *FeePlanController) AssignFeePlan(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	var req dto.AssignFeePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request data", err)
+		return
+	}
+
+	if err := fc.feeService.AssignPlan(c.Param("id"), &req); err != nil {
+		utils.BadRequestResponse(c, "Failed to assign fee plan", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Fee plan assigned", nil)
+}
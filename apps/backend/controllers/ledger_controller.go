@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zeusnotfound04/Tranza/services"
+	"github.com/zeusnotfound04/Tranza/utils"
+)
+
+type LedgerController struct {
+	ledgerService *services.LedgerService
+}
+
+func NewLedgerController(ledgerService *services.LedgerService) *LedgerController {
+	return &LedgerController{
+		ledgerService: ledgerService,
+	}
+}
+
+// GetWalletLedger returns the journal entries behind the user's wallet balance
+// GET /api/v1/wallet/ledger
+func (lc *LedgerController) GetWalletLedger(c *gin.Context) {
+	userID, err := utils.GetUserIDStringFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	page, limit := utils.GetPaginationParams(c)
+
+	entries, total, err := lc.ledgerService.GetWalletLedger(userID, page, limit)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to get wallet ledger", err)
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, "Wallet ledger retrieved", entries, page, limit, total)
+}
+
+// ReconcileWallet compares the stored wallet balance with the ledger
+// GET /api/v1/wallet/ledger/reconcile
+func (lc *LedgerController) ReconcileWallet(c *gin.Context) {
+	userID, err := utils.GetUserIDStringFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	result, err := lc.ledgerService.ReconcileWallet(userID)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to reconcile wallet", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Wallet reconciliation completed", result)
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zeusnotfound04/Tranza/services"
+	"github.com/zeusnotfound04/Tranza/utils"
+)
+
+type LimitsController struct {
+	limitsService *services.LimitsService
+}
+
+func NewLimitsController(limitsService *services.LimitsService) *LimitsController {
+	return &LimitsController{
+		limitsService: limitsService,
+	}
+}
+
+// GetWalletLimits returns current usage against the wallet's limits
+// GET /api/v1/wallet/limits
+func (lc *LimitsController) GetWalletLimits(c *gin.Context) {
+	userID, err := utils.GetUserIDStringFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	usage, err := lc.limitsService.GetLimitUsage(userID)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to get wallet limits", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Wallet limits retrieved", usage)
+}
+
+// respondDebitError reports limit breaches as 422 with the limit details,
+// risk decisions as 403 (blocked) or 409 (confirm and retry) with the reasons,
+// and any other debit failure as a bad request
+func respondDebitError(c *gin.Context, message string, err error) {
+	var limitErr *services.LimitExceededError
+	if errors.As(err, &limitErr) {
+		c.JSON(http.StatusUnprocessableEntity, utils.StandardResponse{
+			Success:   false,
+			Message:   message,
+			Data:      limitErr,
+			Error:     err.Error(),
+			Code:      "LIMIT_EXCEEDED",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	var riskErr *services.RiskDecisionError
+	if errors.As(err, &riskErr) {
+		status, code := http.StatusForbidden, "RISK_BLOCKED"
+		if riskErr.NeedsConfirmation() {
+			status, code = http.StatusConflict, "RISK_CONFIRMATION_REQUIRED"
+		}
+		c.JSON(status, utils.StandardResponse{
+			Success:   false,
+			Message:   message,
+			Data:      riskErr,
+			Error:     err.Error(),
+			Code:      code,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	utils.BadRequestResponse(c, message, err)
+}
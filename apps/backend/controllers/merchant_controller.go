@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/services"
+	"github.com/zeusnotfound04/Tranza/utils"
+)
+
+type MerchantController struct {
+	merchantService *services.MerchantService
+}
+
+func NewMerchantController(merchantService *services.MerchantService) *MerchantController {
+	return &MerchantController{
+		merchantService: merchantService,
+	}
+}
+
+// CreateMerchant adds a merchant that AI payments can be paid out to
+// POST /api/v1/admin/merchants
+func (mc *MerchantController) CreateMerchant(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	var req dto.CreateMerchantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request data", err)
+		return
+	}
+
+	merchant, err := mc.merchantService.CreateMerchant(&req)
+	if err != nil {
+		utils.BadRequestResponse(c, "Failed to create merchant", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Merchant created", merchant)
+}
+
+// GetMerchants lists the merchant registry
+// GET /api/v1/admin/merchants
+func (mc *MerchantController) GetMerchants(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	page, limit := utils.GetPaginationParams(c)
+
+	merchants, total, err := mc.merchantService.GetMerchants(page, limit)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to get merchants", err)
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, "Merchants retrieved", merchants, page, limit, total)
+}
+
+// UpdateMerchant changes a merchant's details or deactivates it
+// PUT /api/v1/admin/merchants/:id
+func (mc *MerchantController) UpdateMerchant(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	var req dto.UpdateMerchantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request data", err)
+		return
+	}
+
+	merchant, err := mc.merchantService.UpdateMerchant(c.Param("id"), &req)
+	if err != nil {
+		utils.BadRequestResponse(c, "Failed to update merchant", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Merchant updated", merchant)
+}
@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/services"
+	"github.com/zeusnotfound04/Tranza/utils"
+)
+
+type PayoutBatchController struct {
+	payoutBatchService *services.PayoutBatchService
+}
+
+func NewPayoutBatchController(payoutBatchService *services.PayoutBatchService) *PayoutBatchController {
+	return &PayoutBatchController{
+		payoutBatchService: payoutBatchService,
+	}
+}
+
+// CreateBatch validates a bulk payout, uploaded as a .csv or .json file in the
+// "file" form field or sent as a JSON body, and holds it for confirmation
+// POST /api/v1/transfers/batches
+func (pc *PayoutBatchController) CreateBatch(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	var name, fileName string
+	var rows []dto.PayoutBatchRowInput
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			utils.BadRequestResponse(c, "A .csv or .json file is required", err)
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			utils.BadRequestResponse(c, "Failed to read file", err)
+			return
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(io.LimitReader(file, services.PayoutBatchMaxFileSize+1))
+		if err != nil {
+			utils.BadRequestResponse(c, "Failed to read file", err)
+			return
+		}
+		if len(data) > services.PayoutBatchMaxFileSize {
+			utils.BadRequestResponse(c, fmt.Sprintf("File is larger than %d KB", services.PayoutBatchMaxFileSize/1024), nil)
+			return
+		}
+
+		fileName = fileHeader.Filename
+		rows, err = services.ParsePayoutBatchFile(fileName, data)
+		if err != nil {
+			utils.BadRequestResponse(c, "Invalid payout file", err)
+			return
+		}
+		name = c.PostForm("name")
+	} else {
+		var req dto.CreatePayoutBatchRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.BadRequestResponse(c, "Invalid request data", err)
+			return
+		}
+		name = req.Name
+		rows = req.Rows
+	}
+
+	upload, err := pc.payoutBatchService.CreateBatch(userID, name, fileName, rows)
+	if err != nil {
+		utils.BadRequestResponse(c, "Failed to create payout batch", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Payout batch validated, confirm to pay it", upload)
+}
+
+// GetBatches lists the user's payout batches
+// GET /api/v1/transfers/batches
+func (pc *PayoutBatchController) GetBatches(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	page, limit := utils.GetPaginationParams(c)
+	batches, total, err := pc.payoutBatchService.GetBatches(userID, page, limit)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to get payout batches", err)
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, "Payout batches retrieved", batches, page, limit, total)
+}
+
+// GetBatch returns a payout batch's summary and progress
+// GET /api/v1/transfers/batches/:id
+func (pc *PayoutBatchController) GetBatch(c *gin.Context) {
+	userID, batchID, ok := pc.batchParams(c)
+	if !ok {
+		return
+	}
+
+	batch, err := pc.payoutBatchService.GetBatch(userID, batchID)
+	if err != nil {
+		utils.NotFoundResponse(c, "Payout batch not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Payout batch retrieved", batch)
+}
+
+// GetBatchRows lists a payout batch's rows, optionally filtered by ?status=
+// GET /api/v1/transfers/batches/:id/rows
+func (pc *PayoutBatchController) GetBatchRows(c *gin.Context) {
+	userID, batchID, ok := pc.batchParams(c)
+	if !ok {
+		return
+	}
+
+	page, limit := utils.GetPaginationParams(c)
+	rows, total, err := pc.payoutBatchService.GetBatchRows(userID, batchID, c.Query("status"), page, limit)
+	if err != nil {
+		utils.NotFoundResponse(c, "Payout batch not found")
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, "Payout batch rows retrieved", rows, page, limit, total)
+}
+
+// ConfirmBatch starts paying the valid rows of a payout batch
+// POST /api/v1/transfers/batches/:id/confirm
+func (pc *PayoutBatchController) ConfirmBatch(c *gin.Context) {
+	userID, batchID, ok := pc.batchParams(c)
+	if !ok {
+		return
+	}
+
+	batch, err := pc.payoutBatchService.ConfirmBatch(userID, batchID)
+	if err != nil {
+		respondPayoutBatchError(c, "Failed to confirm payout batch", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusAccepted, "Payout batch confirmed and processing", batch)
+}
+
+// CancelBatch drops a payout batch that has not been confirmed
+// POST /api/v1/transfers/batches/:id/cancel
+func (pc *PayoutBatchController) CancelBatch(c *gin.Context) {
+	userID, batchID, ok := pc.batchParams(c)
+	if !ok {
+		return
+	}
+
+	batch, err := pc.payoutBatchService.CancelBatch(userID, batchID)
+	if err != nil {
+		respondPayoutBatchError(c, "Failed to cancel payout batch", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Payout batch cancelled", batch)
+}
+
+// DownloadResults downloads every row of a payout batch with its outcome as CSV
+// GET /api/v1/transfers/batches/:id/results
+func (pc *PayoutBatchController) DownloadResults(c *gin.Context) {
+	userID, batchID, ok := pc.batchParams(c)
+	if !ok {
+		return
+	}
+
+	data, filename, err := pc.payoutBatchService.ExportBatchResults(userID, batchID)
+	if err != nil {
+		respondPayoutBatchError(c, "Failed to export payout batch results", err)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Data(http.StatusOK, "text/csv", data)
+}
+
+// batchParams reads the user and the batch ID from the request, responding
+// with an error if either is missing
+func (pc *PayoutBatchController) batchParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	batchID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid batch ID", err)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, batchID, true
+}
+
+func respondPayoutBatchError(c *gin.Context, message string, err error) {
+	switch {
+	case err.Error() == "payout batch not found":
+		utils.NotFoundResponse(c, "Payout batch not found")
+	case errors.Is(err, services.ErrPayoutBatchExpired), errors.Is(err, services.ErrPayoutBatchNotPending):
+		utils.ErrorResponse(c, http.StatusConflict, message, err)
+	default:
+		utils.BadRequestResponse(c, message, err)
+	}
+}
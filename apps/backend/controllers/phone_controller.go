@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/services"
+	"github.com/zeusnotfound04/Tranza/utils"
+)
+
+type PhoneController struct {
+	phoneService *services.PhoneService
+}
+
+func NewPhoneController(phoneService *services.PhoneService) *PhoneController {
+	return &PhoneController{
+		phoneService: phoneService,
+	}
+}
+
+// StartPhoneVerification texts a verification code to the user's mobile number
+// POST /api/v1/profile/phone
+func (pc *PhoneController) StartPhoneVerification(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	var req dto.PhoneVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request data", err)
+		return
+	}
+
+	response, err := pc.phoneService.StartPhoneVerification(c.Request.Context(), userID, req.Phone)
+	if err != nil {
+		respondPhoneError(c, "Failed to send verification code", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Verification code sent to your phone", response)
+}
+
+// VerifyPhone adds the mobile number to the user's account with the code sent to it
+// POST /api/v1/profile/phone/verify
+func (pc *PhoneController) VerifyPhone(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	var req dto.VerifyPhoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request data", err)
+		return
+	}
+
+	user, err := pc.phoneService.VerifyPhone(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		respondPhoneError(c, "Failed to verify phone number", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Phone number verified", user)
+}
+
+// RemovePhone takes the mobile number off the user's account
+// DELETE /api/v1/profile/phone
+func (pc *PhoneController) RemovePhone(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	if err := pc.phoneService.RemovePhone(c.Request.Context(), userID); err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to remove phone number", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Phone number removed", nil)
+}
+
+// SavePhoneVPA saves the UPI ID transfers to a phone number are paid at
+// POST /api/v1/phone-vpas
+func (pc *PhoneController) SavePhoneVPA(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	var req dto.SavePhoneVPARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request data", err)
+		return
+	}
+
+	mapping, err := pc.phoneService.SavePhoneVPA(userID, &req)
+	if err != nil {
+		respondPhoneError(c, "Failed to save UPI ID", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "UPI ID saved", mapping)
+}
+
+// GetPhoneVPAs lists the UPI IDs the user saved for phone numbers
+// GET /api/v1/phone-vpas
+func (pc *PhoneController) GetPhoneVPAs(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	mappings, err := pc.phoneService.GetPhoneVPAs(userID)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to get saved UPI IDs", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Saved UPI IDs retrieved", mappings)
+}
+
+// DeletePhoneVPA removes a saved phone UPI ID
+// DELETE /api/v1/phone-vpas/:id
+func (pc *PhoneController) DeletePhoneVPA(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid ID", err)
+		return
+	}
+
+	if err := pc.phoneService.DeletePhoneVPA(userID, id); err != nil {
+		utils.NotFoundResponse(c, "Saved UPI ID not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Saved UPI ID removed", nil)
+}
+
+func respondPhoneError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrPhoneTaken), errors.Is(err, services.ErrPhoneAlreadyOwned):
+		utils.ErrorResponse(c, http.StatusConflict, message, err)
+	case errors.Is(err, services.ErrTooManyAttempts), errors.Is(err, services.ErrResendCooldown):
+		utils.ErrorResponse(c, http.StatusTooManyRequests, message, err)
+	default:
+		utils.BadRequestResponse(c, message, err)
+	}
+}
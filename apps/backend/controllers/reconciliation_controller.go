@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/services"
+	"github.com/zeusnotfound04/Tranza/utils"
+)
+
+type ReconciliationController struct {
+	reconciliationService *services.ReconciliationService
+}
+
+func NewReconciliationController(reconciliationService *services.ReconciliationService) *ReconciliationController {
+	return &ReconciliationController{
+		reconciliationService: reconciliationService,
+	}
+}
+
+// requireAdmin rejects callers without the admin role
+func requireAdmin(c *gin.Context) bool {
+	if c.GetString("user_role") != "admin" {
+		utils.ForbiddenResponse(c, "Insufficient privileges")
+		return false
+	}
+	return true
+}
+
+// RunReconciliation reconciles a day or time range against Razorpay on demand
+// POST /api/v1/admin/reconciliation/runs
+func (rc *ReconciliationController) RunReconciliation(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	var req dto.RunReconciliationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request data", err)
+		return
+	}
+
+	var from, to time.Time
+	var err error
+	if req.Date != "" {
+		from, to, err = services.ReconciliationDay(req.Date)
+	} else {
+		from, err = time.Parse(time.RFC3339, req.From)
+		if err == nil {
+			to, err = time.Parse(time.RFC3339, req.To)
+		}
+	}
+	if err != nil {
+		utils.BadRequestResponse(c, "Provide a date (YYYY-MM-DD) or an RFC 3339 from/to range", err)
+		return
+	}
+
+	run, err := rc.reconciliationService.Run(from, to, models.ReconciliationTriggerAPI)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Reconciliation failed", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Reconciliation completed", run)
+}
+
+// GetRuns lists reconciliation runs
+// GET /api/v1/admin/reconciliation/runs
+func (rc *ReconciliationController) GetRuns(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	page, limit := utils.GetPaginationParams(c)
+
+	runs, total, err := rc.reconciliationService.GetRuns(page, limit)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to get reconciliation runs", err)
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, "Reconciliation runs retrieved", runs, page, limit, total)
+}
+
+// GetRun returns a run with its mismatch report
+// GET /api/v1/admin/reconciliation/runs/:id
+func (rc *ReconciliationController) GetRun(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	run, err := rc.reconciliationService.GetRun(c.Param("id"))
+	if err != nil {
+		utils.NotFoundResponse(c, "Reconciliation run not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Reconciliation run retrieved", run)
+}
+
+// GetUnresolvedMismatches lists mismatches awaiting review across all runs
+// GET /api/v1/admin/reconciliation/mismatches
+func (rc *ReconciliationController) GetUnresolvedMismatches(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	page, limit := utils.GetPaginationParams(c)
+
+	mismatches, total, err := rc.reconciliationService.GetUnresolvedMismatches(page, limit)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to get reconciliation mismatches", err)
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, "Reconciliation mismatches retrieved", mismatches, page, limit, total)
+}
+
+// ResolveMismatch marks a mismatch as reviewed
+// POST /api/v1/admin/reconciliation/mismatches/:id/resolve
+func (rc *ReconciliationController) ResolveMismatch(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	var req dto.ResolveMismatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request data", err)
+		return
+	}
+
+	if err := rc.reconciliationService.ResolveMismatch(c.Param("id"), req.Note); err != nil {
+		utils.BadRequestResponse(c, "Failed to resolve mismatch", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Mismatch resolved", nil)
+}
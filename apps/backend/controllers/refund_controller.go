@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/services"
+	"github.com/zeusnotfound04/Tranza/utils"
+)
+
+type RefundController struct {
+	refundService *services.RefundService
+}
+
+func NewRefundController(refundService *services.RefundService) *RefundController {
+	return &RefundController{
+		refundService: refundService,
+	}
+}
+
+// CreateRefund refunds all or part of a wallet load or AI payment. Omitting
+// the amount refunds whatever has not been refunded yet.
+// POST /api/v1/refunds
+func (rc *RefundController) CreateRefund(c *gin.Context) {
+	userID, err := utils.GetUserIDStringFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	var req dto.CreateRefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request data", err)
+		return
+	}
+
+	refund, err := rc.refundService.CreateRefund(userID, &req)
+	if err != nil {
+		respondDebitError(c, "Failed to create refund", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Refund created", refund)
+}
+
+// GetRefunds lists the user's refunds
+// GET /api/v1/refunds
+func (rc *RefundController) GetRefunds(c *gin.Context) {
+	userID, err := utils.GetUserIDStringFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	page, limit := utils.GetPaginationParams(c)
+
+	refunds, total, err := rc.refundService.GetRefunds(userID, page, limit)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to get refunds", err)
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, "Refunds retrieved", refunds, page, limit, total)
+}
+
+// GetRefund returns a refund with its status history
+// GET /api/v1/refunds/:id
+func (rc *RefundController) GetRefund(c *gin.Context) {
+	userID, err := utils.GetUserIDStringFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	refund, err := rc.refundService.GetRefund(userID, c.Param("id"))
+	if err != nil {
+		utils.NotFoundResponse(c, "Refund not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Refund retrieved", refund)
+}
@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zeusnotfound04/Tranza/services"
+	"github.com/zeusnotfound04/Tranza/utils"
+)
+
+type RiskController struct {
+	riskService *services.RiskService
+}
+
+func NewRiskController(riskService *services.RiskService) *RiskController {
+	return &RiskController{
+		riskService: riskService,
+	}
+}
+
+// GetAssessments lists risk decisions, optionally filtered by user_id and action
+// GET /api/v1/admin/risk/assessments
+func (rc *RiskController) GetAssessments(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	page, limit := utils.GetPaginationParams(c)
+
+	assessments, total, err := rc.riskService.GetAssessments(c.Query("user_id"), c.Query("action"), page, limit)
+	if err != nil {
+		utils.BadRequestResponse(c, "Failed to get risk assessments", err)
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, "Risk assessments retrieved", assessments, page, limit, total)
+}
+
+// GetPaymentAssessment explains how an AI payment request or external transfer was scored
+// GET /api/v1/admin/risk/payments/:id
+func (rc *RiskController) GetPaymentAssessment(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	assessment, err := rc.riskService.GetAssessment(c.Param("id"))
+	if err != nil {
+		utils.NotFoundResponse(c, "Risk assessment not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Risk assessment retrieved", assessment)
+}
@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/services"
+	"github.com/zeusnotfound04/Tranza/utils"
+)
+
+type ScheduledPaymentController struct {
+	scheduledPaymentService *services.ScheduledPaymentService
+}
+
+func NewScheduledPaymentController(scheduledPaymentService *services.ScheduledPaymentService) *ScheduledPaymentController {
+	return &ScheduledPaymentController{
+		scheduledPaymentService: scheduledPaymentService,
+	}
+}
+
+// CreateSchedule schedules a one-off or recurring payment
+// POST /api/v1/schedules
+func (sc *ScheduledPaymentController) CreateSchedule(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	var req dto.CreateScheduledPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request data", err)
+		return
+	}
+
+	schedule, err := sc.scheduledPaymentService.CreateSchedule(userID, &req)
+	if err != nil {
+		utils.BadRequestResponse(c, "Failed to schedule payment", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Payment scheduled", schedule)
+}
+
+// GetSchedules lists the user's scheduled payments, optionally filtered by ?status=
+// GET /api/v1/schedules
+func (sc *ScheduledPaymentController) GetSchedules(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	page, limit := utils.GetPaginationParams(c)
+
+	schedules, total, err := sc.scheduledPaymentService.GetSchedules(userID, c.Query("status"), page, limit)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "Failed to get scheduled payments", err)
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, "Scheduled payments retrieved", schedules, page, limit, total)
+}
+
+// GetSchedule returns one scheduled payment
+// GET /api/v1/schedules/:id
+func (sc *ScheduledPaymentController) GetSchedule(c *gin.Context) {
+	userID, scheduleID, ok := sc.scheduleParams(c)
+	if !ok {
+		return
+	}
+
+	schedule, err := sc.scheduledPaymentService.GetSchedule(userID, scheduleID)
+	if err != nil {
+		utils.NotFoundResponse(c, "Scheduled payment not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Scheduled payment retrieved", schedule)
+}
+
+// GetScheduleRuns lists the runs of a scheduled payment, newest first
+// GET /api/v1/schedules/:id/runs
+func (sc *ScheduledPaymentController) GetScheduleRuns(c *gin.Context) {
+	userID, scheduleID, ok := sc.scheduleParams(c)
+	if !ok {
+		return
+	}
+
+	page, limit := utils.GetPaginationParams(c)
+
+	runs, total, err := sc.scheduledPaymentService.GetScheduleRuns(userID, scheduleID, page, limit)
+	if err != nil {
+		utils.NotFoundResponse(c, "Scheduled payment not found")
+		return
+	}
+
+	utils.PaginatedSuccessResponse(c, "Scheduled payment runs retrieved", runs, page, limit, total)
+}
+
+// PauseSchedule stops a scheduled payment until it is resumed
+// POST /api/v1/schedules/:id/pause
+func (sc *ScheduledPaymentController) PauseSchedule(c *gin.Context) {
+	userID, scheduleID, ok := sc.scheduleParams(c)
+	if !ok {
+		return
+	}
+
+	schedule, err := sc.scheduledPaymentService.PauseSchedule(userID, scheduleID)
+	if err != nil {
+		utils.BadRequestResponse(c, "Failed to pause scheduled payment", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Scheduled payment paused", schedule)
+}
+
+// ResumeSchedule restarts a paused scheduled payment from its next run
+// POST /api/v1/schedules/:id/resume
+func (sc *ScheduledPaymentController) ResumeSchedule(c *gin.Context) {
+	userID, scheduleID, ok := sc.scheduleParams(c)
+	if !ok {
+		return
+	}
+
+	schedule, err := sc.scheduledPaymentService.ResumeSchedule(userID, scheduleID)
+	if err != nil {
+		utils.BadRequestResponse(c, "Failed to resume scheduled payment", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Scheduled payment resumed", schedule)
+}
+
+// CancelSchedule stops a scheduled payment for good
+// POST /api/v1/schedules/:id/cancel
+func (sc *ScheduledPaymentController) CancelSchedule(c *gin.Context) {
+	userID, scheduleID, ok := sc.scheduleParams(c)
+	if !ok {
+		return
+	}
+
+	schedule, err := sc.scheduledPaymentService.CancelSchedule(userID, scheduleID)
+	if err != nil {
+		utils.BadRequestResponse(c, "Failed to cancel scheduled payment", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Scheduled payment cancelled", schedule)
+}
+
+// scheduleParams reads the user and the schedule ID from the request,
+// responding with an error if either is missing
+func (sc *ScheduledPaymentController) scheduleParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	scheduleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequestResponse(c, "Invalid schedule ID", err)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, scheduleID, true
+}
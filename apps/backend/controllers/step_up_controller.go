@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/services"
+	"github.com/zeusnotfound04/Tranza/utils"
+)
+
+type StepUpController struct {
+	stepUpService *services.StepUpService
+}
+
+func NewStepUpController(stepUpService *services.StepUpService) *StepUpController {
+	return &StepUpController{
+		stepUpService: stepUpService,
+	}
+}
+
+// SetupTOTP creates an authenticator secret for the user to scan
+// POST /api/v1/profile/totp/setup
+func (sc *StepUpController) SetupTOTP(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	setup, err := sc.stepUpService.SetupTOTP(c.Request.Context(), userID)
+	if err != nil {
+		utils.BadRequestResponse(c, "Failed to set up authenticator", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Scan the secret with your authenticator app, then enable it with a code", setup)
+}
+
+// EnableTOTP turns on the authenticator with a code from the app
+// POST /api/v1/profile/totp/enable
+func (sc *StepUpController) EnableTOTP(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	var req dto.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request data", err)
+		return
+	}
+
+	if err := sc.stepUpService.EnableTOTP(c.Request.Context(), userID, req.Code); err != nil {
+		respondTOTPError(c, "Failed to enable authenticator", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Authenticator enabled", nil)
+}
+
+// DisableTOTP turns off the authenticator with a current code
+// POST /api/v1/profile/totp/disable
+func (sc *StepUpController) DisableTOTP(c *gin.Context) {
+	userID, err := utils.GetUserIDFromContext(c)
+	if err != nil {
+		utils.UnauthorizedResponse(c, "User authentication failed")
+		return
+	}
+
+	var req dto.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "Invalid request data", err)
+		return
+	}
+
+	if err := sc.stepUpService.DisableTOTP(c.Request.Context(), userID, req.Code); err != nil {
+		respondTOTPError(c, "Failed to disable authenticator", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Authenticator disabled", nil)
+}
+
+func respondTOTPError(c *gin.Context, message string, err error) {
+	if errors.Is(err, services.ErrInvalidTOTPCode) {
+		utils.UnauthorizedResponse(c, err.Error())
+		return
+	}
+	utils.BadRequestResponse(c, message, err)
+}
@@ -13,11 +13,13 @@ import (
 
 type WalletHandler struct {
 	walletService *services.WalletService
+	holdService   *services.HoldService
 }
 
-func NewWalletHandler(walletService *services.WalletService) *WalletHandler {
+func NewWalletHandler(walletService *services.WalletService, holdService *services.HoldService) *WalletHandler {
 	return &WalletHandler{
 		walletService: walletService,
+		holdService:   holdService,
 	}
 }
 
@@ -54,9 +56,17 @@ func (h *WalletHandler) GetWallet(c *gin.Context) {
 	}
 
 	fmt.Printf("DEBUG: Wallet found: %+v\n", wallet)
+	available, held, err := h.holdService.GetAvailableBalance(wallet)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get available balance", err)
+		return
+	}
+
 	response := &dto.WalletResponse{
 		ID:                    wallet.ID.String(),
 		Balance:               wallet.Balance,
+		AvailableBalance:      available,
+		HeldAmount:            held,
 		Currency:              wallet.Currency,
 		Status:                wallet.Status,
 		AIAccessEnabled:       wallet.AIAccessEnabled,
@@ -156,3 +166,20 @@ func (h *WalletHandler) UpdateWalletSettings(c *gin.Context) {
 
 	utils.SuccessResponse(c, http.StatusOK, "Wallet settings updated successfully", nil)
 }
+
+// Get funds currently on hold for pending debits
+func (h *WalletHandler) GetWalletHolds(c *gin.Context) {
+	userID, err := utils.GetUserIDStringFromContext(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "User authentication failed", err)
+		return
+	}
+
+	holds, err := h.holdService.GetActiveHolds(userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get wallet holds", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Wallet holds retrieved", holds)
+}
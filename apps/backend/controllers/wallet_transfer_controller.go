@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/services"
+	"github.com/zeusnotfound04/Tranza/utils"
+)
+
+type WalletTransferController struct {
+	walletTransferService *services.WalletTransferService
+}
+
+func NewWalletTransferController(walletTransferService *services.WalletTransferService) *WalletTransferController {
+	return &WalletTransferController{
+		walletTransferService: walletTransferService,
+	}
+}
+
+// TransferToWallet sends money to another Tranza wallet
+// POST /api/v1/wallet/transfer
+func (c *WalletTransferController) TransferToWallet(ctx *gin.Context) {
+	userID, err := utils.GetUserIDStringFromContext(ctx)
+	if err != nil {
+		utils.UnauthorizedResponse(ctx, "User authentication failed")
+		return
+	}
+
+	var req dto.WalletTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(ctx, "Invalid request body", err)
+		return
+	}
+
+	req.InitiatedBy = models.InitiatedByUser
+	req.IPAddress = ctx.ClientIP()
+	req.UserAgent = ctx.GetHeader("User-Agent")
+
+	response, err := c.walletTransferService.TransferToWallet(userID, &req)
+	if err != nil {
+		respondDebitError(ctx, "Failed to transfer funds", err)
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusCreated, "Transfer completed successfully", response)
+}
+
+// BotTransferToWallet sends money to another Tranza wallet for bot users
+// POST /api/bot/wallet/transfer
+func (c *WalletTransferController) BotTransferToWallet(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedResponse(ctx, "Bot user not authenticated")
+		return
+	}
+
+	// Convert userID to string safely
+	userUUID, ok := userID.(uuid.UUID)
+	if !ok {
+		utils.InternalServerErrorResponse(ctx, "Invalid user ID type", nil)
+		return
+	}
+
+	var req dto.WalletTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(ctx, "Invalid request body", err)
+		return
+	}
+
+	req.InitiatedBy = models.InitiatedByBot
+	req.IPAddress = ctx.ClientIP()
+	req.UserAgent = ctx.GetHeader("User-Agent")
+
+	response, err := c.walletTransferService.TransferToWallet(userUUID.String(), &req)
+	if err != nil {
+		respondDebitError(ctx, "Failed to transfer funds", err)
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusCreated, "Transfer completed successfully", response)
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zeusnotfound04/Tranza/services"
+	"github.com/zeusnotfound04/Tranza/utils"
+)
+
+type WebhookController struct {
+	paymentService *services.PaymentService
+}
+
+func NewWebhookController(paymentService *services.PaymentService) *WebhookController {
+	return &WebhookController{
+		paymentService: paymentService,
+	}
+}
+
+// HandleRazorpayWebhook verifies and applies a Razorpay webhook (payments, refunds and payouts)
+// POST /webhooks/razorpay
+func (wc *WebhookController) HandleRazorpayWebhook(c *gin.Context) {
+	signature := c.GetHeader("X-Razorpay-Signature")
+	if signature == "" {
+		utils.BadRequestResponse(c, "Missing signature", nil)
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		utils.BadRequestResponse(c, "Failed to read request body", err)
+		return
+	}
+
+	if err := wc.paymentService.ProcessWebhookEvent(body, signature); err != nil {
+		if errors.Is(err, services.ErrInvalidWebhookSignature) {
+			utils.UnauthorizedResponse(c, "Signature verification failed")
+			return
+		}
+		// Razorpay retries webhooks that do not get a 2xx response
+		utils.InternalServerErrorResponse(c, "Failed to process webhook", err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Webhook processed", nil)
+}
@@ -0,0 +1,40 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"github.com/zeusnotfound04/Tranza/utils"
+)
+
+// AdminAuthMiddleware lets only admins through and sets user_role for the
+// handlers. The role is read from the database on every request, so taking
+// it away applies at once rather than when the token expires. Must run after
+// JWTAuthMiddleware.
+func AdminAuthMiddleware(userRepo repositories.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := c.Get("user_id")
+		if !ok {
+			utils.UnauthorizedResponse(c, "User not authenticated")
+			c.Abort()
+			return
+		}
+		uid, ok := userID.(uuid.UUID)
+		if !ok {
+			utils.UnauthorizedResponse(c, "User not authenticated")
+			c.Abort()
+			return
+		}
+
+		user, err := userRepo.FindByID(c.Request.Context(), uid)
+		if err != nil || !user.IsAdmin() {
+			utils.ForbiddenResponse(c, "Insufficient privileges")
+			c.Abort()
+			return
+		}
+
+		c.Set("user_role", models.UserRoleAdmin)
+		c.Next()
+	}
+}
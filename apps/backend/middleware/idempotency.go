@@ -0,0 +1,154 @@
+package middlewares
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/services"
+	"github.com/zeusnotfound04/Tranza/utils"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotencyMiddleware makes a money-moving endpoint safe to retry. Requests
+// carrying an Idempotency-Key header run once per user or API key; retries with
+// the same key and payload get the stored response replayed, and a reused key
+// with a different payload is rejected with 422. Retries of a request that
+// ended in a server error are rejected with 409, since it may have moved money
+// before failing. Requests without the header are passed through unchanged.
+// Must run after authentication.
+func IdempotencyMiddleware(s *services.IdempotencyService) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := strings.TrimSpace(ctx.GetHeader(idempotencyKeyHeader))
+		if key == "" {
+			ctx.Next()
+			return
+		}
+		if len(key) > 255 {
+			utils.BadRequestResponse(ctx, "Idempotency-Key must be at most 255 characters", nil)
+			ctx.Abort()
+			return
+		}
+
+		scope, ok := idempotencyScope(ctx)
+		if !ok {
+			utils.UnauthorizedResponse(ctx, "User not authenticated")
+			ctx.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			utils.BadRequestResponse(ctx, "Failed to read request body", err)
+			ctx.Abort()
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		record, claimed, err := s.Begin(scope, key, ctx.Request.Method, ctx.Request.URL.Path, body)
+		if err != nil {
+			respondIdempotencyError(ctx, err)
+			ctx.Abort()
+			return
+		}
+
+		if !claimed {
+			// Replay the stored response
+			ctx.Header("Idempotent-Replayed", "true")
+			ctx.Data(record.ResponseCode, "application/json; charset=utf-8", []byte(record.ResponseBody))
+			ctx.Abort()
+			return
+		}
+
+		writer := &responseWriter{
+			ResponseWriter: ctx.Writer,
+			body:           &strings.Builder{},
+		}
+		ctx.Writer = writer
+
+		// A panicking handler may have committed part of its work; keep the
+		// key failed and let the recovery middleware answer
+		defer func() {
+			if r := recover(); r != nil {
+				if err := s.Fail(record, http.StatusInternalServerError, ""); err != nil {
+					utils.LogError(err, map[string]interface{}{"idempotency_key": key, "action": "fail_idempotency_key"})
+				}
+				panic(r)
+			}
+		}()
+
+		ctx.Next()
+
+		// After a server error the outcome is unknown, so the key is not
+		// freed for a retry that could repeat the operation
+		if ctx.Writer.Status() >= http.StatusInternalServerError {
+			if err := s.Fail(record, ctx.Writer.Status(), writer.body.String()); err != nil {
+				utils.LogError(err, map[string]interface{}{"idempotency_key": key, "action": "fail_idempotency_key"})
+			}
+			return
+		}
+
+		if err := s.Complete(record, ctx.Writer.Status(), writer.body.String()); err != nil {
+			utils.LogError(err, map[string]interface{}{"idempotency_key": key, "action": "complete_idempotency_key"})
+		}
+	}
+}
+
+// idempotencyScope keys requests by API key when one was used, otherwise by user
+func idempotencyScope(ctx *gin.Context) (string, bool) {
+	if apiKey, exists := ctx.Get("api_key"); exists {
+		if key, ok := apiKey.(*models.APIKey); ok {
+			return fmt.Sprintf("api_key:%d", key.ID), true
+		}
+	}
+
+	if userID, exists := ctx.Get("user_id"); exists {
+		switch id := userID.(type) {
+		case uuid.UUID:
+			return "user:" + id.String(), true
+		case string:
+			return "user:" + id, true
+		}
+	}
+
+	return "", false
+}
+
+func respondIdempotencyError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrIdempotencyKeyMismatch):
+		ctx.JSON(http.StatusUnprocessableEntity, utils.StandardResponse{
+			Success:   false,
+			Message:   "Idempotency-Key reused with a different request",
+			Error:     err.Error(),
+			Code:      "IDEMPOTENCY_KEY_MISMATCH",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+	case errors.Is(err, services.ErrIdempotencyKeyInProgress):
+		ctx.JSON(http.StatusConflict, utils.StandardResponse{
+			Success:   false,
+			Message:   "Request with this Idempotency-Key is in progress",
+			Error:     err.Error(),
+			Code:      "IDEMPOTENCY_KEY_IN_PROGRESS",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+	case errors.Is(err, services.ErrIdempotencyKeyFailed):
+		ctx.JSON(http.StatusConflict, utils.StandardResponse{
+			Success:   false,
+			Message:   "Request with this Idempotency-Key failed; check whether it went through before retrying with a new key",
+			Error:     err.Error(),
+			Code:      "IDEMPOTENCY_KEY_FAILED",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+	default:
+		utils.InternalServerErrorResponse(ctx, "Failed to check idempotency key", err)
+	}
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// AIAgentConfig holds the rules for one of a user's AI agents. Payments made
+// through the agent must satisfy these on top of the user's AI spending
+// limits. Zero limits mean the agent has no limit of its own. Merchant lists
+// hold registered merchant names or UPI IDs.
+type AIAgentConfig struct {
+	ID                    uuid.UUID       `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID                uuid.UUID       `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_ai_agent_configs_user_agent"`
+	AgentID               string          `json:"agent_id" gorm:"type:varchar(100);not null;uniqueIndex:idx_ai_agent_configs_user_agent"`
+	Enabled               bool            `json:"enabled" gorm:"not null;default:true"`
+	DailyLimit            decimal.Decimal `json:"daily_limit" gorm:"type:decimal(15,2);default:0.00"`
+	PerTransactionLimit   decimal.Decimal `json:"per_transaction_limit" gorm:"type:decimal(15,2);default:0.00"`
+	AllowedMerchants      []string        `json:"allowed_merchants" gorm:"serializer:json"`
+	BlockedMerchants      []string        `json:"blocked_merchants" gorm:"serializer:json"`
+	AllowedCategories     []string        `json:"allowed_categories" gorm:"serializer:json"`
+	BlockedCategories     []string        `json:"blocked_categories" gorm:"serializer:json"`
+	RequireApproval       bool            `json:"require_approval" gorm:"not null;default:true"`
+	AutoApprovalThreshold decimal.Decimal `json:"auto_approval_threshold" gorm:"type:decimal(15,2);default:0.00"` // Used when RequireApproval is off
+	NotificationSettings  map[string]bool `json:"notification_settings" gorm:"serializer:json"`
+	CreatedAt             time.Time       `json:"created_at"`
+	UpdatedAt             time.Time       `json:"updated_at"`
+}
+
+func (a *AIAgentConfig) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return
+}
@@ -8,21 +8,70 @@ import (
 )
 
 type AIPaymentRequest struct {
-	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	UserID         uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
-	Amount         float64    `json:"amount" gorm:"not null"`
-	Description    string     `json:"description" gorm:"type:text"`
-	MerchantName   string     `json:"merchant_name,omitempty"`
-	AIPrompt       string     `json:"ai_prompt" gorm:"type:text;not null"`
-	ConfirmationID string     `json:"confirmation_id,omitempty"`
-	Status         string     `json:"status" gorm:"default:'pending'"` // pending, confirmed, processed, failed, cancelled
-	ProcessedAt    *time.Time `json:"processed_at,omitempty"`
-	TransactionID  string     `json:"transaction_id,omitempty"`
-	AIResponse     string     `json:"ai_response,omitempty" gorm:"type:text"`
-	RiskLevel      string     `json:"risk_level" gorm:"default:'low'"` // low, medium, high
-	Confidence     float64    `json:"confidence" gorm:"default:0.0"`
-	CreatedAt      time.Time  `json:"created_at"`
-	UpdatedAt      time.Time  `json:"updated_at"`
+	ID            uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID        uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	AgentID       string     `json:"agent_id,omitempty" gorm:"type:varchar(100);index"`
+	Amount        float64    `json:"amount" gorm:"not null"`
+	Description   string     `json:"description" gorm:"type:text"`
+	MerchantName  string     `json:"merchant_name,omitempty"`
+	MerchantUPIID string     `json:"merchant_upi_id,omitempty" gorm:"type:varchar(255)"`
+	UPISource     string     `json:"upi_source,omitempty" gorm:"type:varchar(20)"` // request, prompt, registry, history
+	Recipient     string     `json:"recipient,omitempty"`                          // Person, UPI ID, phone, email or @username being paid
+	Category      string     `json:"category,omitempty" gorm:"type:varchar(100)"`
+	AIPrompt      string     `json:"ai_prompt" gorm:"type:text;not null"`
+	Status        string     `json:"status" gorm:"default:'pending'"` // pending, processing, processed, failed, cancelled, expired, refunded
+	ProcessedAt   *time.Time `json:"processed_at,omitempty"`
+	TransactionID string     `json:"transaction_id,omitempty"`
+	TransferID    *uuid.UUID `json:"transfer_id,omitempty" gorm:"type:uuid;index"` // Payout to the merchant
+	FailureReason string     `json:"failure_reason,omitempty" gorm:"type:text"`
+	AIResponse    string     `json:"ai_response,omitempty" gorm:"type:text"`
+	RiskLevel     string     `json:"risk_level" gorm:"default:'low'"` // low, medium, high
+	RiskScore     int        `json:"risk_score" gorm:"default:0"`
+	IPAddress     string     `json:"ip_address,omitempty" gorm:"size:45"`
+	Confidence    float64    `json:"confidence" gorm:"default:0.0"`
+
+	// Confirmation. The token is handed out once and only its hash is kept.
+	ConfirmationTokenHash string     `json:"-" gorm:"type:varchar(64)"`
+	ConfirmationExpiresAt *time.Time `json:"confirmation_expires_at,omitempty" gorm:"index"`
+	ConfirmationAttempts  int        `json:"-" gorm:"default:0"` // Failed attempts with a wrong token or step-up
+	StepUpRequired        bool       `json:"step_up_required" gorm:"default:false"`
+	ConfirmedAt           *time.Time `json:"confirmed_at,omitempty"`
+	ConfirmedVia          string     `json:"confirmed_via,omitempty" gorm:"type:varchar(20)"` // web, slack, api
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Confirmation actions recorded in the audit trail
+const (
+	AIConfirmationActionConfirmed = "confirmed"
+	AIConfirmationActionCancelled = "cancelled"
+	AIConfirmationActionRejected  = "rejected" // Wrong token or failed step-up
+	AIConfirmationActionExpired   = "expired"
+)
+
+// Channels a payment request can be confirmed from
+const (
+	AIConfirmationChannelWeb    = "web"
+	AIConfirmationChannelSlack  = "slack"
+	AIConfirmationChannelAPI    = "api"
+	AIConfirmationChannelSystem = "system" // Expiry sweeper
+)
+
+// AIPaymentConfirmation is one entry in a payment request's confirmation audit
+// trail: who acted on it, from where and how they proved it was them
+type AIPaymentConfirmation struct {
+	ID               uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	PaymentRequestID uuid.UUID `json:"payment_request_id" gorm:"type:uuid;not null;index"`
+	UserID           uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	Action           string    `json:"action" gorm:"type:varchar(20);not null"`
+	Channel          string    `json:"channel" gorm:"type:varchar(20);not null"`
+	Actor            string    `json:"actor,omitempty" gorm:"type:varchar(255)"`         // User ID, API key or Slack user
+	StepUpMethod     string    `json:"step_up_method,omitempty" gorm:"type:varchar(20)"` // password, totp
+	Reason           string    `json:"reason,omitempty" gorm:"type:text"`
+	IPAddress        string    `json:"ip_address,omitempty" gorm:"size:45"`
+	UserAgent        string    `json:"user_agent,omitempty" gorm:"type:text"`
+	CreatedAt        time.Time `json:"created_at"`
 }
 
 type AISpendingLimit struct {
@@ -30,10 +79,13 @@ type AISpendingLimit struct {
 	UserID                uuid.UUID `json:"user_id" gorm:"type:uuid;not null;unique;index"`
 	DailyLimit            float64   `json:"daily_limit" gorm:"default:10000"`
 	TransactionLimit      float64   `json:"transaction_limit" gorm:"default:2000"`
+	WeeklyLimit           float64   `json:"weekly_limit" gorm:"default:50000"`
 	MonthlyLimit          float64   `json:"monthly_limit" gorm:"default:100000"`
+	Timezone              string    `json:"timezone" gorm:"type:varchar(64);default:'Asia/Kolkata'"` // Limits reset at midnight here
 	AIAccessEnabled       bool      `json:"ai_access_enabled" gorm:"default:false"`
 	RequireConfirmation   bool      `json:"require_confirmation" gorm:"default:true"`
 	ConfirmationThreshold float64   `json:"confirmation_threshold" gorm:"default:1000"`
+	RequireStepUp         bool      `json:"require_step_up" gorm:"default:false"` // Password or TOTP at or above the threshold
 	CreatedAt             time.Time `json:"created_at"`
 	UpdatedAt             time.Time `json:"updated_at"`
 }
@@ -41,9 +93,10 @@ type AISpendingLimit struct {
 type AISpendingTracker struct {
 	ID               uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	UserID           uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
-	Date             time.Time `json:"date" gorm:"type:date;not null;index"`
+	Date             time.Time `json:"date" gorm:"type:date;not null;index"` // Day in the user's time zone
 	DailySpent       float64   `json:"daily_spent" gorm:"default:0"`
 	TransactionCount int       `json:"transaction_count" gorm:"default:0"`
+	WeeklySpent      float64   `json:"weekly_spent" gorm:"default:0"`
 	MonthlySpent     float64   `json:"monthly_spent" gorm:"default:0"`
 	CreatedAt        time.Time `json:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
@@ -51,38 +104,62 @@ type AISpendingTracker struct {
 
 // DTO Types for API requests/responses
 type AIPaymentRequestDTO struct {
-	Prompt      string  `json:"prompt" binding:"required"`
-	Amount      float64 `json:"amount,omitempty"`
-	Merchant    string  `json:"merchant,omitempty"`
-	Description string  `json:"description,omitempty"`
+	Prompt        string  `json:"prompt" binding:"required"`
+	AgentID       string  `json:"agent_id,omitempty" binding:"max=100"`
+	Amount        float64 `json:"amount,omitempty"`
+	Merchant      string  `json:"merchant,omitempty"`
+	MerchantUPI   string  `json:"merchant_upi_id,omitempty" binding:"max=255"`
+	Category      string  `json:"category,omitempty" binding:"max=100"`
+	Description   string  `json:"description,omitempty"`
+	IPAddress     string  `json:"-"` // Filled in by the controller
+	FromAPIKey    bool    `json:"-"` // Set by the controller for API key requests
+	APIKeyAgentID string  `json:"-"` // Agent the API key is bound to, if any
 }
 
 type AIPaymentResponse struct {
-	ID                   string  `json:"id"`
-	Amount               float64 `json:"amount"`
-	Merchant             string  `json:"merchant"`
-	Description          string  `json:"description"`
-	Confidence           float64 `json:"confidence"`
-	RiskLevel            string  `json:"risk_level"`
-	RequiresConfirmation bool    `json:"requires_confirmation"`
-	AIReasoning          string  `json:"ai_reasoning"`
-	WalletBalance        float64 `json:"wallet_balance"`
-	RemainingLimit       float64 `json:"remaining_limit"`
-	Suggestions          string  `json:"suggestions,omitempty"`
+	ID                   string   `json:"id"`
+	AgentID              string   `json:"agent_id,omitempty"`
+	Amount               float64  `json:"amount"`
+	Merchant             string   `json:"merchant"`
+	MerchantUPIID        string   `json:"merchant_upi_id,omitempty"`
+	Recipient            string   `json:"recipient,omitempty"`
+	Description          string   `json:"description"`
+	Confidence           float64  `json:"confidence"`
+	RiskLevel            string   `json:"risk_level"`
+	RiskScore            int      `json:"risk_score"`
+	RiskReasons          []string `json:"risk_reasons,omitempty"`
+	RequiresConfirmation bool     `json:"requires_confirmation"`
+	AIReasoning          string   `json:"ai_reasoning"`
+
+	// Send the token back to confirm. It is shown only here.
+	ConfirmationToken     string     `json:"confirmation_token"`
+	ConfirmationExpiresAt *time.Time `json:"confirmation_expires_at"`
+	StepUpRequired        bool       `json:"step_up_required"`
+	StepUpMethods         []string   `json:"step_up_methods,omitempty"`
+	WalletBalance         float64    `json:"wallet_balance"`
+	RemainingLimit        float64    `json:"remaining_limit"`
+	Suggestions           string     `json:"suggestions,omitempty"`
 }
 
 type AIPaymentConfirmationDTO struct {
-	PaymentID string `json:"payment_id" binding:"required"`
-	Confirmed bool   `json:"confirmed" binding:"required"`
+	PaymentID         string `json:"payment_id" binding:"required"`
+	Confirmed         bool   `json:"confirmed" binding:"required"`
+	ConfirmationToken string `json:"confirmation_token" binding:"required"`
+	Password          string `json:"password,omitempty"`                       // Step-up with the account password
+	TOTPCode          string `json:"totp_code,omitempty"`                      // Step-up with an authenticator code
+	ConfirmedBy       string `json:"confirmed_by,omitempty" binding:"max=255"` // Slack user who confirmed, for bot keys
 }
 
 type AISpendingLimitsDTO struct {
-	DailyLimit            float64 `json:"daily_limit" binding:"min=0,max=50000"`
-	TransactionLimit      float64 `json:"transaction_limit" binding:"min=0,max=10000"`
-	MonthlyLimit          float64 `json:"monthly_limit" binding:"min=0,max=500000"`
-	AIAccessEnabled       bool    `json:"ai_access_enabled"`
-	RequireConfirmation   bool    `json:"require_confirmation"`
-	ConfirmationThreshold float64 `json:"confirmation_threshold" binding:"min=0"`
+	DailyLimit            float64  `json:"daily_limit" binding:"min=0,max=50000"`
+	TransactionLimit      float64  `json:"transaction_limit" binding:"min=0,max=10000"`
+	WeeklyLimit           *float64 `json:"weekly_limit,omitempty" binding:"omitempty,min=0,max=200000"` // Unchanged when omitted
+	MonthlyLimit          float64  `json:"monthly_limit" binding:"min=0,max=500000"`
+	AIAccessEnabled       bool     `json:"ai_access_enabled"`
+	RequireConfirmation   bool     `json:"require_confirmation"`
+	ConfirmationThreshold float64  `json:"confirmation_threshold" binding:"min=0"`
+	RequireStepUp         bool     `json:"require_step_up"`
+	Timezone              string   `json:"timezone,omitempty" binding:"max=64"` // IANA name, e.g. Asia/Kolkata; unchanged when omitted
 }
 
 // BeforeCreate hook
@@ -90,9 +167,6 @@ func (a *AIPaymentRequest) BeforeCreate(tx *gorm.DB) (err error) {
 	if a.ID == uuid.Nil {
 		a.ID = uuid.New()
 	}
-	if a.ConfirmationID == "" {
-		a.ConfirmationID = uuid.New().String()
-	}
 	return
 }
 
@@ -109,3 +183,10 @@ func (a *AISpendingTracker) BeforeCreate(tx *gorm.DB) (err error) {
 	}
 	return
 }
+
+func (a *AIPaymentConfirmation) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return
+}
@@ -32,6 +32,7 @@ type APIKey struct {
 	// Additional bot-specific fields
 	BotWorkspace string `gorm:"type:varchar(100)"` // Slack workspace ID for bot keys
 	BotUserID    string `gorm:"type:varchar(100)"` // Bot's associated user ID
+	AgentID      string `gorm:"type:varchar(100)"` // AI agent the key acts as; its payments follow that agent's rules
 }
 
 // GetScopes returns the scopes as a slice of strings
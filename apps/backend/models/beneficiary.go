@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Beneficiary is a saved transfer recipient. Its Razorpay contact and fund
+// account are created once and reused for every payout to it.
+type Beneficiary struct {
+	ID       uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID   uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index;uniqueIndex:idx_beneficiaries_user_nickname,where:deleted_at IS NULL,priority:1"`
+	Nickname string    `json:"nickname" gorm:"size:50;not null;uniqueIndex:idx_beneficiaries_user_nickname,where:deleted_at IS NULL,priority:2"` // Lower case, e.g. "mom"
+
+	// Recipient Details
+	RecipientType  string `json:"recipient_type" gorm:"size:20;not null"`   // "upi", "phone" or "ifsc"
+	RecipientValue string `json:"recipient_value" gorm:"size:255;not null"` // UPI ID, Phone Number or bank account number
+	RecipientIFSC  string `json:"recipient_ifsc,omitempty" gorm:"size:11"`  // Branch of a bank account
+	Name           string `json:"name" gorm:"size:100;not null"`            // Account holder name sent to Razorpay
+
+	// Cached Razorpay References
+	RazorpayContactID string `json:"razorpay_contact_id,omitempty" gorm:"size:50"`
+	RazorpayFundID    string `json:"razorpay_fund_id,omitempty" gorm:"size:50"`
+
+	// Verification
+	VerificationStatus string     `json:"verification_status" gorm:"size:20;default:'pending';not null"` // pending, verified, failed
+	VerificationError  string     `json:"verification_error,omitempty" gorm:"size:500"`
+	VerifiedAt         *time.Time `json:"verified_at,omitempty"`
+	CoolingOffUntil    time.Time  `json:"cooling_off_until"` // Transfers are limited until then
+
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// TableName returns the table name for Beneficiary
+func (Beneficiary) TableName() string {
+	return "beneficiaries"
+}
+
+// BeforeCreate hook to set UUID
+func (b *Beneficiary) BeforeCreate(tx *gorm.DB) error {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsVerified reports whether the beneficiary's fund account is registered with Razorpay
+func (b *Beneficiary) IsVerified() bool {
+	return b.VerificationStatus == BeneficiaryStatusVerified && b.RazorpayFundID != ""
+}
+
+// InCoolingOff reports whether the beneficiary was added too recently for unrestricted transfers
+func (b *Beneficiary) InCoolingOff(now time.Time) bool {
+	return now.Before(b.CoolingOffUntil)
+}
+
+// Beneficiary Verification Status Constants
+const (
+	BeneficiaryStatusPending  = "pending"
+	BeneficiaryStatusVerified = "verified"
+	BeneficiaryStatusFailed   = "failed"
+)
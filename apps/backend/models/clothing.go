@@ -15,37 +15,73 @@ type ExternalOrder struct {
 	UserID          uuid.UUID       `json:"user_id" gorm:"type:uuid;not null;index"`
 	OrderNumber     string          `json:"order_number" gorm:"unique;not null"`
 	ExternalOrderID string          `json:"external_order_id" gorm:"not null"` // Order ID from external store
+	Catalog         string          `json:"catalog" gorm:"size:50"`            // Catalog provider the order is placed with
 	Website         string          `json:"website" gorm:"not null"`           // Myntra, Amazon, Flipkart, etc.
 	TotalAmount     decimal.Decimal `json:"total_amount" gorm:"type:decimal(10,2);not null"`
+	Budget          decimal.Decimal `json:"budget" gorm:"type:decimal(10,2);default:0"` // Zero when the user set no budget
 	Currency        string          `json:"currency" gorm:"default:'INR'"`
-	Status          string          `json:"status" gorm:"default:'pending'"` // pending, confirmed, shipped, delivered, cancelled
+	Status          string          `json:"status" gorm:"default:'awaiting_confirmation';index"` // See ExternalOrderStatus constants
 	PaymentStatus   string          `json:"payment_status" gorm:"default:'pending'"`
 	TransactionID   string          `json:"transaction_id,omitempty"`
 	TrackingNumber  string          `json:"tracking_number,omitempty"`
+	FailureReason   string          `json:"failure_reason,omitempty" gorm:"type:text"`
 
-	// Delivery details
-	DeliveryAddress   Address    `json:"delivery_address" gorm:"embedded;embeddedPrefix:delivery_"`
+	// Delivery details. The address is copied so later edits do not change the order.
+	DeliveryAddress   Address    `json:"delivery_address" gorm:"serializer:json"`
 	EstimatedDelivery *time.Time `json:"estimated_delivery,omitempty"`
 	DeliveredAt       *time.Time `json:"delivered_at,omitempty"`
 
-	// Order items from external website
-	Items []ExternalOrderItem `json:"items" gorm:"serializer:json"`
+	// Order items from external website, and the suggestions they were picked from
+	Items       []ExternalOrderItem `json:"items" gorm:"serializer:json"`
+	Suggestions []ExternalOrderItem `json:"suggestions,omitempty" gorm:"serializer:json"`
 
 	// AI-related fields
-	IsAIOrder   bool   `json:"is_ai_order" gorm:"default:true"`
-	AIPrompt    string `json:"ai_prompt,omitempty" gorm:"type:text"`
-	AIRequestID string `json:"ai_request_id,omitempty"`
+	IsAIOrder   bool                   `json:"is_ai_order" gorm:"default:true"`
+	AIPrompt    string                 `json:"ai_prompt,omitempty" gorm:"type:text"`
+	AIRequestID string                 `json:"ai_request_id,omitempty"`
+	Analysis    *ClothingOrderAnalysis `json:"analysis,omitempty" gorm:"serializer:json"`
 
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
 
 	// Relations
 	User User `json:"-" gorm:"foreignKey:UserID"`
 }
 
+// TableName returns the table name for ExternalOrder
+func (ExternalOrder) TableName() string {
+	return "external_orders"
+}
+
+// External Order Status Constants
+const (
+	ExternalOrderStatusAwaitingConfirmation = "awaiting_confirmation"
+	ExternalOrderStatusPlacing              = "placing" // Being placed with the store
+	ExternalOrderStatusConfirmed            = "confirmed"
+	ExternalOrderStatusShipped              = "shipped"
+	ExternalOrderStatusDelivered            = "delivered"
+	ExternalOrderStatusCancelled            = "cancelled"
+	ExternalOrderStatusFailed               = "failed"
+)
+
+// External Order Payment Status Constants
+const (
+	ExternalOrderPaymentPending  = "pending"
+	ExternalOrderPaymentPaid     = "paid"
+	ExternalOrderPaymentRefunded = "refunded"
+)
+
+// IsOpen returns true while the store may still change the order's status
+func (eo *ExternalOrder) IsOpen() bool {
+	return eo.Status == ExternalOrderStatusConfirmed || eo.Status == ExternalOrderStatusShipped
+}
+
 // ExternalOrderItem represents items purchased from external websites
 type ExternalOrderItem struct {
 	ProductID  string          `json:"product_id"` // External product ID
+	VariantID  string          `json:"variant_id,omitempty"`
 	Name       string          `json:"name"`
 	Brand      string          `json:"brand"`
 	Category   string          `json:"category"`
@@ -54,8 +90,9 @@ type ExternalOrderItem struct {
 	Quantity   int             `json:"quantity"`
 	Price      decimal.Decimal `json:"price"`
 	ImageURL   string          `json:"image_url"`
-	ProductURL string          `json:"product_url"` // Link to external product page
-	Website    string          `json:"website"`     // Source website
+	ProductURL string          `json:"product_url"`      // Link to external product page
+	Website    string          `json:"website"`          // Source website
+	Reason     string          `json:"reason,omitempty"` // Why the item was suggested
 }
 
 // DTO Types for API requests/responses
@@ -137,20 +174,26 @@ type SuggestedProduct struct {
 	Website     string  `json:"website"`
 }
 
+// ConfirmAIClothingOrderRequest confirms a suggested order. Without selected
+// products the top suggestion is bought; without an address the one chosen
+// when the order was suggested is used.
 type ConfirmAIClothingOrderRequest struct {
-	SelectedProducts []SelectedProduct `json:"selected_products" binding:"required"`
-	AddressID        string            `json:"address_id" binding:"required"`
+	ConfirmationID   string            `json:"confirmation_id" binding:"required"`
+	SelectedProducts []SelectedProduct `json:"selected_products,omitempty" binding:"omitempty,max=10,dive"`
+	AddressID        string            `json:"address_id,omitempty"`
 }
 
+// SelectedProduct picks one of an order's suggestions. Prices are always taken
+// from the catalog; Name, Price, Website and URL are informational.
 type SelectedProduct struct {
 	ID       string  `json:"id" binding:"required"`
-	Name     string  `json:"name" binding:"required"`
-	Price    float64 `json:"price" binding:"required"`
-	Quantity int     `json:"quantity" binding:"required"`
+	Name     string  `json:"name"`
+	Price    float64 `json:"price"`
+	Quantity int     `json:"quantity" binding:"required,min=1,max=10"`
 	Size     string  `json:"size"`
 	Color    string  `json:"color"`
-	Website  string  `json:"website" binding:"required"`
-	URL      string  `json:"url" binding:"required"`
+	Website  string  `json:"website"`
+	URL      string  `json:"url"`
 }
 
 type ConfirmAIClothingOrderResponse struct {
@@ -165,13 +208,20 @@ type ConfirmAIClothingOrderResponse struct {
 }
 
 type ClothingOrderResponse struct {
-	OrderID     string              `json:"order_id"`
-	OrderNumber string              `json:"order_number"`
-	Status      string              `json:"status"`
-	TotalAmount decimal.Decimal     `json:"total_amount"`
-	PaymentInfo PaymentInfoResponse `json:"payment_info"`
-	Items       []ExternalOrderItem `json:"items"`
-	CreatedAt   time.Time           `json:"created_at"`
+	OrderID           string              `json:"order_id"`
+	OrderNumber       string              `json:"order_number"`
+	Status            string              `json:"status"`
+	TotalAmount       decimal.Decimal     `json:"total_amount"`
+	PaymentInfo       PaymentInfoResponse `json:"payment_info"`
+	Items             []ExternalOrderItem `json:"items"`
+	Website           string              `json:"website"`
+	ExternalOrderID   string              `json:"external_order_id,omitempty"`
+	TrackingNumber    string              `json:"tracking_number,omitempty"`
+	EstimatedDelivery *time.Time          `json:"estimated_delivery,omitempty"`
+	DeliveredAt       *time.Time          `json:"delivered_at,omitempty"`
+	DeliveryAddress   *Address            `json:"delivery_address,omitempty"`
+	FailureReason     string              `json:"failure_reason,omitempty"`
+	CreatedAt         time.Time           `json:"created_at"`
 }
 
 type PaymentInfoResponse struct {
@@ -34,15 +34,23 @@ type AISpendingLimitsResponse struct {
 	AIDailyLimit              decimal.Decimal `json:"ai_daily_limit"`
 	AIPerTransactionLimit     decimal.Decimal `json:"ai_per_transaction_limit"`
 	AIWeeklyLimit             decimal.Decimal `json:"ai_weekly_limit"`
+	AIMonthlyLimit            decimal.Decimal `json:"ai_monthly_limit"`
 	DailySpent                decimal.Decimal `json:"daily_spent"`
 	WeeklySpent               decimal.Decimal `json:"weekly_spent"`
+	MonthlySpent              decimal.Decimal `json:"monthly_spent"`
 	DailyRemaining            decimal.Decimal `json:"daily_remaining"`
 	WeeklyRemaining           decimal.Decimal `json:"weekly_remaining"`
+	MonthlyRemaining          decimal.Decimal `json:"monthly_remaining"`
 	TransactionsToday         int             `json:"transactions_today"`
 	TransactionsThisWeek      int             `json:"transactions_this_week"`
-	SpendingDate              string          `json:"spending_date"`
+	TransactionsThisMonth     int             `json:"transactions_this_month"`
+	Timezone                  string          `json:"timezone"`
+	SpendingDate              string          `json:"spending_date"` // Today in the user's time zone, YYYY-MM-DD
+	DailyResetsAt             string          `json:"daily_resets_at"`
+	WeeklyResetsAt            string          `json:"weekly_resets_at"`
+	MonthlyResetsAt           string          `json:"monthly_resets_at"`
 	LastTransactionTime       string          `json:"last_transaction_time,omitempty"`
-	RecommendedTransactionLimit decimal.Decimal `json:"recommended_transaction_limit"`
+	RecommendedTransactionLimit decimal.Decimal `json:"recommended_transaction_limit"` // Largest payment every limit and the balance allow
 }
 
 // AI Agent Configuration Request
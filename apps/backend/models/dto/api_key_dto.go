@@ -7,6 +7,7 @@ type CreateAPIKeyRequest struct {
 	Label    string `json:"label" binding:"required,max=100"`
 	Password string `json:"password" binding:"required,min=6,max=50"` // Password to protect the API key
 	TTLHours int    `json:"ttl_hours" binding:"min=0,max=8760"`       // Max 1 year
+	AgentID  string `json:"agent_id" binding:"max=100"`               // AI agent the key acts as
 }
 
 // CreateAPIKeyResponse represents the response after creating an API key
@@ -14,6 +15,7 @@ type CreateAPIKeyResponse struct {
 	APIKey   string `json:"api_key"`
 	Label    string `json:"label"`
 	TTLHours int    `json:"ttl_hours"`
+	AgentID  string `json:"agent_id,omitempty"`
 	Message  string `json:"message"`
 }
 
@@ -24,6 +26,7 @@ type CreateBotAPIKeyRequest struct {
 	WorkspaceID string `json:"workspace_id" binding:"required"`
 	BotUserID   string `json:"bot_user_id" binding:"required"`
 	TTLHours    int    `json:"ttl_hours" binding:"min=0,max=8760"`
+	AgentID     string `json:"agent_id" binding:"max=100"` // AI agent the key acts as
 }
 
 // CreateBotAPIKeyResponse represents the response after creating a bot API key
@@ -33,6 +36,7 @@ type CreateBotAPIKeyResponse struct {
 	WorkspaceID string   `json:"workspace_id"`
 	BotUserID   string   `json:"bot_user_id"`
 	TTLHours    int      `json:"ttl_hours"`
+	AgentID     string   `json:"agent_id,omitempty"`
 	Scopes      []string `json:"scopes"`
 	Message     string   `json:"message"`
 }
@@ -0,0 +1,38 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/pkg/ask"
+)
+
+// AskRequest is a question about the user's own wallet history
+type AskRequest struct {
+	Question string `json:"question" binding:"required,max=500"`
+	Limit    int    `json:"limit,omitempty" binding:"omitempty,min=1,max=100"` // Records to return, 20 by default
+}
+
+// AskResponse answers a question and shows the records behind the answer
+type AskResponse struct {
+	Question string          `json:"question"`
+	Answer   string          `json:"answer"`
+	Query    *ask.Query      `json:"query"`
+	Total    decimal.Decimal `json:"total"`
+	Count    int64           `json:"count"`
+	Records  []AskRecord     `json:"records"`
+}
+
+// AskRecord is one transaction or external transfer behind an answer
+type AskRecord struct {
+	ID           uuid.UUID       `json:"id"`
+	Source       string          `json:"source"` // "transaction" or "external_transfer"
+	Type         string          `json:"type"`
+	Amount       decimal.Decimal `json:"amount"`
+	Counterparty string          `json:"counterparty,omitempty"`
+	Description  string          `json:"description,omitempty"`
+	Status       string          `json:"status"`
+	ReferenceID  string          `json:"reference_id"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
@@ -43,4 +43,15 @@ type UserResponse struct {
 	KYCStatus   string `json:"kyc_status"`
 	IsActive    bool   `json:"is_active"`
 	CreatedAt   string `json:"created_at"`
-}
\ No newline at end of file
+}
+
+// TOTP Setup Response
+type TOTPSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"` // Render as a QR code for authenticator apps
+}
+
+// TOTP Code Request
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required,len=6"`
+}
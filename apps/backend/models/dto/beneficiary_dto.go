@@ -0,0 +1,15 @@
+package dto
+
+// CreateBeneficiaryRequest represents the request to save a transfer recipient
+type CreateBeneficiaryRequest struct {
+	Nickname       string `json:"nickname" binding:"required,max=50" example:"mom"`
+	RecipientType  string `json:"recipient_type" binding:"required,oneof=upi phone ifsc" example:"upi"`
+	RecipientValue string `json:"recipient_value" binding:"required" example:"mom@okicici"`
+	RecipientIFSC  string `json:"recipient_ifsc,omitempty" binding:"required_if=RecipientType ifsc" example:"HDFC0000123"` // Branch of a bank account
+	Name           string `json:"name" binding:"required,max=100" example:"Sunita Sharma"`
+}
+
+// RenameBeneficiaryRequest represents the request to change a beneficiary's nickname
+type RenameBeneficiaryRequest struct {
+	Nickname string `json:"nickname" binding:"required,max=50" example:"amma"`
+}
@@ -21,9 +21,30 @@ type CreateExternalTransferRequest struct {
 	Amount         decimal.Decimal `json:"amount" binding:"required,gt=0" example:"100.00"`
 	Currency       string          `json:"currency,omitempty" example:"INR"`
 	Description    string          `json:"description,omitempty" binding:"max=500" example:"Payment for services"`
-	RecipientType  string          `json:"recipient_type" binding:"required,oneof=upi phone" example:"upi"`
-	RecipientValue string          `json:"recipient_value" binding:"required" example:"user@paytm"`
+	RecipientType  string          `json:"recipient_type,omitempty" binding:"required_without=BeneficiaryID,omitempty,oneof=upi phone ifsc" example:"upi"`
+	RecipientValue string          `json:"recipient_value,omitempty" binding:"required_without=BeneficiaryID" example:"user@paytm"`
+	RecipientIFSC  string          `json:"recipient_ifsc,omitempty" binding:"required_if=RecipientType ifsc" example:"HDFC0000123"` // Branch of a bank account recipient
 	RecipientName  string          `json:"recipient_name,omitempty" binding:"max=100" example:"John Doe"`
+	BeneficiaryID  string          `json:"beneficiary_id,omitempty" example:"9b2f6c1e-8d4a-4f6b-9a51-3c2d7e0f1a2b"` // Pay a saved beneficiary instead of a raw recipient
+
+	// Recipient of a phone number as returned by validation. The transfer is
+	// refused if the number resolves to anyone else by the time it is created.
+	ResolvedVia string `json:"resolved_via,omitempty" binding:"required_if=RecipientType phone,omitempty,oneof=tranza saved upi_lookup beneficiary" example:"saved"`
+	ResolvedVPA string `json:"resolved_vpa,omitempty" binding:"max=255" example:"john@okhdfc"`
+
+	// Go ahead with a transfer the risk checks asked to confirm, by sending
+	// back the assessment_id they returned. It is used once, and only for the
+	// same amount and recipient within 15 minutes.
+	ConfirmAssessmentID string `json:"confirm_assessment_id,omitempty" binding:"omitempty,uuid" example:"5f0c2a9e-3b7d-4e21-9c4f-8a6b1d2e3f40"`
+
+	// Filled in by the controller
+	InitiatedBy string `json:"-"`
+	IPAddress   string `json:"-"`
+	UserAgent   string `json:"-"`
+
+	// Hold the transfer is paid from, such as that of the bulk payout it
+	// belongs to; the transfer's share moves from it to the transfer's own hold
+	FundingHold string `json:"-"`
 }
 
 // ExternalTransferResponse represents the response after creating an external transfer
@@ -33,13 +54,27 @@ type ExternalTransferResponse struct {
 	Amount         decimal.Decimal `json:"amount"`
 	Currency       string          `json:"currency"`
 	TransferFee    decimal.Decimal `json:"transfer_fee"`
+	FeeQuote       *FeeQuote       `json:"fee_quote,omitempty"` // How the fee was worked out
 	TotalAmount    decimal.Decimal `json:"total_amount"`
 	RecipientType  string          `json:"recipient_type"`
 	RecipientValue string          `json:"recipient_value"`
+	RecipientIFSC  string          `json:"recipient_ifsc,omitempty"`
 	RecipientName  string          `json:"recipient_name,omitempty"`
+	ResolvedVPA    string          `json:"resolved_vpa,omitempty"` // UPI ID a phone number is paid at
+	ResolvedVia    string          `json:"resolved_via,omitempty"` // Where the phone number was found: tranza, saved, upi_lookup or beneficiary
+	BeneficiaryID  string          `json:"beneficiary_id,omitempty"`
+	TransferMode   string          `json:"transfer_mode,omitempty"` // UPI, IMPS, NEFT, RTGS or WALLET
 	Status         string          `json:"status"`
 	CreatedAt      time.Time       `json:"created_at"`
 	EstimatedTime  string          `json:"estimated_time,omitempty"`
+	RiskLevel      string          `json:"risk_level,omitempty"`
+
+	// A phone number of a Tranza user is paid straight into their wallet; the
+	// transfer is then a wallet transaction, with no ID of its own
+	WalletTransactionID string `json:"wallet_transaction_id,omitempty"`
+
+	// A queued transfer can be cancelled until it is sent to Razorpay at this time
+	CancellableUntil *time.Time `json:"cancellable_until,omitempty"`
 }
 
 // ExternalTransferStatusResponse represents transfer status information
@@ -89,17 +124,28 @@ type ExternalTransferSummary struct {
 // ValidateTransferRequest represents request to validate transfer before processing
 type ValidateTransferRequest struct {
 	Amount         decimal.Decimal `json:"amount" binding:"required,gt=0"`
-	RecipientType  string          `json:"recipient_type" binding:"required,oneof=upi phone"`
-	RecipientValue string          `json:"recipient_value" binding:"required"`
+	RecipientType  string          `json:"recipient_type,omitempty" binding:"required_without=BeneficiaryID,omitempty,oneof=upi phone ifsc"`
+	RecipientValue string          `json:"recipient_value,omitempty" binding:"required_without=BeneficiaryID"`
+	RecipientIFSC  string          `json:"recipient_ifsc,omitempty" binding:"required_if=RecipientType ifsc"`
+	BeneficiaryID  string          `json:"beneficiary_id,omitempty"`
+
+	// Filled in by the controller
+	InitiatedBy string `json:"-"`
+	FundingHold string `json:"-"` // Funds on this hold count as available
 }
 
 // ValidateTransferResponse represents transfer validation response
 type ValidateTransferResponse struct {
 	Valid         bool            `json:"valid"`
 	TransferFee   decimal.Decimal `json:"transfer_fee"`
+	FeeQuote      *FeeQuote       `json:"fee_quote,omitempty"` // Fee plan, GST and free transfers behind the fee
 	TotalAmount   decimal.Decimal `json:"total_amount"`
 	EstimatedTime string          `json:"estimated_time"`
-	RecipientName string          `json:"recipient_name,omitempty"`
+	TransferMode  string          `json:"transfer_mode,omitempty"`  // UPI, IMPS, NEFT, RTGS or WALLET
+	Bank          string          `json:"bank,omitempty"`           // Bank and branch of a bank account recipient
+	RecipientName string          `json:"recipient_name,omitempty"` // Confirm with the user before sending
+	ResolvedVPA   string          `json:"resolved_vpa,omitempty"`   // UPI ID a phone number will be paid at
+	ResolvedVia   string          `json:"resolved_via,omitempty"`   // tranza, saved, upi_lookup or beneficiary
 	Warnings      []string        `json:"warnings,omitempty"`
 	Errors        []string        `json:"errors,omitempty"`
 }
@@ -152,25 +198,45 @@ type BotTransferResponse struct {
 	Message       string          `json:"message"`
 	Recipient     string          `json:"recipient"`
 	EstimatedTime string          `json:"estimated_time,omitempty"`
+
+	CancellableUntil *time.Time `json:"cancellable_until,omitempty"` // Cancel with POST /api/bot/transfers/:id/cancel until then
 }
 
-// TransferFeesResponse represents transfer fees information
+// TransferFeesResponse represents transfer fees information. The per-type
+// fees are for a ₹1,000 transfer (₹2,00,000 by RTGS), including GST.
 type TransferFeesResponse struct {
-	UPIFee       decimal.Decimal `json:"upi_fee"`
-	PhoneFee     decimal.Decimal `json:"phone_fee"`
-	MinAmount    decimal.Decimal `json:"min_amount"`
-	MaxAmount    decimal.Decimal `json:"max_amount"`
-	DailyLimit   decimal.Decimal `json:"daily_limit"`
-	MonthlyLimit decimal.Decimal `json:"monthly_limit"`
-	FeeStructure []FeeRange      `json:"fee_structure"`
+	UPIFee        decimal.Decimal `json:"upi_fee"`
+	PhoneFee      decimal.Decimal `json:"phone_fee"`
+	IMPSFee       decimal.Decimal `json:"imps_fee"`
+	NEFTFee       decimal.Decimal `json:"neft_fee"`
+	RTGSFee       decimal.Decimal `json:"rtgs_fee"`
+	MinAmount     decimal.Decimal `json:"min_amount"`
+	MaxAmount     decimal.Decimal `json:"max_amount"`      // To a UPI ID or phone number
+	MaxBankAmount decimal.Decimal `json:"max_bank_amount"` // To a bank account
+	DailyLimit    decimal.Decimal `json:"daily_limit"`     // The wallet's own limits
+	MonthlyLimit  decimal.Decimal `json:"monthly_limit"`
+	FeeStructure  []FeeRange      `json:"fee_structure"`
+
+	// Fee plan the user is on
+	PlanCode              string          `json:"plan_code"`
+	PlanName              string          `json:"plan_name"`
+	PlanVersion           int             `json:"plan_version"`
+	GSTRate               decimal.Decimal `json:"gst_rate"`
+	FreeTransfersPerMonth int             `json:"free_transfers_per_month"`
+	FreeTransfersLeft     int             `json:"free_transfers_left"`
 }
 
 // FeeRange represents fee structure for different amount ranges
 type FeeRange struct {
-	MinAmount decimal.Decimal `json:"min_amount"`
-	MaxAmount decimal.Decimal `json:"max_amount"`
-	Fee       decimal.Decimal `json:"fee"`
-	FeeType   string          `json:"fee_type"` // "fixed" or "percentage"
+	RecipientType string          `json:"recipient_type,omitempty"` // Empty for any
+	Mode          string          `json:"mode,omitempty"`           // Empty for any
+	MinAmount     decimal.Decimal `json:"min_amount"`
+	MaxAmount     decimal.Decimal `json:"max_amount"` // Zero for no upper bound
+	Fee           decimal.Decimal `json:"fee"`
+	Percent       decimal.Decimal `json:"percent"`
+	MinFee        decimal.Decimal `json:"min_fee"`
+	MaxFee        decimal.Decimal `json:"max_fee"`
+	FeeType       string          `json:"fee_type"` // "fixed", "percentage" or "mixed"
 }
 
 // PaginatedExternalTransferResponse represents paginated external transfer response
@@ -185,8 +251,10 @@ type PaginatedExternalTransferResponse struct {
 // Bot-specific DTOs for Slack integration
 type BotValidateTransferRequest struct {
 	Amount         decimal.Decimal `json:"amount" binding:"required"`
-	RecipientType  string          `json:"recipient_type" binding:"required"`  // "upi" or "phone"
-	RecipientValue string          `json:"recipient_value" binding:"required"` // UPI ID or phone number
+	RecipientType  string          `json:"recipient_type,omitempty" binding:"required_without=Beneficiary"`  // "upi", "phone" or "ifsc"
+	RecipientValue string          `json:"recipient_value,omitempty" binding:"required_without=Beneficiary"` // UPI ID, phone number or bank account number
+	RecipientIFSC  string          `json:"recipient_ifsc,omitempty" binding:"required_if=RecipientType ifsc"`
+	Beneficiary    string          `json:"beneficiary,omitempty"` // Saved beneficiary ID or nickname
 }
 
 type BotValidateTransferResponse struct {
@@ -196,14 +264,23 @@ type BotValidateTransferResponse struct {
 	TransferFee   decimal.Decimal `json:"transfer_fee"`
 	TotalAmount   decimal.Decimal `json:"total_amount"`
 	EstimatedTime string          `json:"estimated_time"`
+	TransferMode  string          `json:"transfer_mode,omitempty"`
+	Bank          string          `json:"bank,omitempty"`
+	RecipientName string          `json:"recipient_name,omitempty"` // Who a phone number belongs to; confirm it before sending
+	ResolvedVPA   string          `json:"resolved_vpa,omitempty"`
+	ResolvedVia   string          `json:"resolved_via,omitempty"` // Send back with resolved_vpa when creating the transfer
 }
 
 type BotCreateTransferRequest struct {
 	Amount         decimal.Decimal `json:"amount" binding:"required"`
-	RecipientType  string          `json:"recipient_type" binding:"required"`  // "upi" or "phone"
-	RecipientValue string          `json:"recipient_value" binding:"required"` // UPI ID or phone number
+	RecipientType  string          `json:"recipient_type,omitempty" binding:"required_without=Beneficiary"`  // "upi", "phone" or "ifsc"
+	RecipientValue string          `json:"recipient_value,omitempty" binding:"required_without=Beneficiary"` // UPI ID, phone number or bank account number
+	RecipientIFSC  string          `json:"recipient_ifsc,omitempty" binding:"required_if=RecipientType ifsc"`
+	Beneficiary    string          `json:"beneficiary,omitempty"` // Saved beneficiary ID or nickname, e.g. "mom"
 	RecipientName  string          `json:"recipient_name,omitempty"`
 	Description    string          `json:"description,omitempty"`
+	ResolvedVia    string          `json:"resolved_via,omitempty" binding:"required_if=RecipientType phone"` // From validating a phone number
+	ResolvedVPA    string          `json:"resolved_vpa,omitempty"`
 }
 
 type BotTransferStatusResponse struct {
@@ -213,4 +290,6 @@ type BotTransferStatusResponse struct {
 	Amount        decimal.Decimal `json:"amount"`
 	Recipient     string          `json:"recipient"`
 	EstimatedTime string          `json:"estimated_time,omitempty"`
+
+	CancellableUntil *time.Time `json:"cancellable_until,omitempty"` // Cancel with POST /api/bot/transfers/:id/cancel until then
 }
@@ -0,0 +1,63 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// FeeSlab is one row of a fee plan. Empty recipient_type or mode match any
+// transfer; a zero max_amount or max_fee means no upper bound.
+type FeeSlab struct {
+	RecipientType string          `json:"recipient_type" binding:"omitempty,oneof=upi phone ifsc"`
+	Mode          string          `json:"mode" binding:"omitempty,oneof=UPI IMPS NEFT RTGS"`
+	MinAmount     decimal.Decimal `json:"min_amount"`
+	MaxAmount     decimal.Decimal `json:"max_amount"`
+	FlatFee       decimal.Decimal `json:"flat_fee"`
+	Percent       decimal.Decimal `json:"percent"`
+	MinFee        decimal.Decimal `json:"min_fee"`
+	MaxFee        decimal.Decimal `json:"max_fee"`
+}
+
+// CreateFeePlanRequest adds a fee plan
+type CreateFeePlanRequest struct {
+	Code                  string          `json:"code" binding:"required,max=50"`
+	Name                  string          `json:"name" binding:"required,max=100"`
+	Description           string          `json:"description" binding:"max=500"`
+	Slabs                 []FeeSlab       `json:"slabs" binding:"required,min=1,max=50,dive"`
+	GSTRate               decimal.Decimal `json:"gst_rate"`
+	FreeTransfersPerMonth int             `json:"free_transfers_per_month" binding:"min=0"`
+	FreeTransfersUntil    *time.Time      `json:"free_transfers_until"`
+	IsDefault             bool            `json:"is_default"`
+}
+
+// UpdateFeePlanRequest changes the fields that are set and bumps the plan version
+type UpdateFeePlanRequest struct {
+	Name                  *string          `json:"name" binding:"omitempty,max=100"`
+	Description           *string          `json:"description" binding:"omitempty,max=500"`
+	Slabs                 []FeeSlab        `json:"slabs" binding:"omitempty,min=1,max=50,dive"`
+	GSTRate               *decimal.Decimal `json:"gst_rate"`
+	FreeTransfersPerMonth *int             `json:"free_transfers_per_month" binding:"omitempty,min=0"`
+	FreeTransfersUntil    *time.Time       `json:"free_transfers_until"`
+	IsDefault             *bool            `json:"is_default"`
+	IsActive              *bool            `json:"is_active"`
+}
+
+// AssignFeePlanRequest puts a user on a fee plan; an empty plan moves them
+// back to the default
+type AssignFeePlanRequest struct {
+	FeePlanID string `json:"fee_plan_id"`
+}
+
+// FeeQuote is the fee a transfer will be charged and the plan it comes from
+type FeeQuote struct {
+	PlanID            string          `json:"plan_id,omitempty"` // Empty for the built-in plan
+	PlanCode          string          `json:"plan_code"`
+	PlanVersion       int             `json:"plan_version"`
+	BaseFee           decimal.Decimal `json:"base_fee"` // Before GST
+	GSTRate           decimal.Decimal `json:"gst_rate"`
+	GST               decimal.Decimal `json:"gst"`
+	Fee               decimal.Decimal `json:"fee"`                 // Base fee + GST; zero when waived
+	Waived            bool            `json:"waived"`              // Free under the plan's monthly quota
+	FreeTransfersLeft int             `json:"free_transfers_left"` // Free transfers left this month after this one
+}
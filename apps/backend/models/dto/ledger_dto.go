@@ -0,0 +1,14 @@
+package dto
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// Ledger Reconciliation Response
+type LedgerReconciliationResponse struct {
+	WalletID      string          `json:"wallet_id"`
+	WalletBalance decimal.Decimal `json:"wallet_balance"`
+	LedgerBalance decimal.Decimal `json:"ledger_balance"`
+	Difference    decimal.Decimal `json:"difference"`
+	Reconciled    bool            `json:"reconciled"`
+}
@@ -0,0 +1,18 @@
+package dto
+
+// CreateMerchantRequest adds a merchant to the registry
+type CreateMerchantRequest struct {
+	Name     string   `json:"name" binding:"required,max=255"`
+	UPIID    string   `json:"upi_id" binding:"required,max=255"`
+	Category string   `json:"category" binding:"max=100"`
+	Aliases  []string `json:"aliases" binding:"max=20,dive,max=255"`
+}
+
+// UpdateMerchantRequest changes the fields that are set
+type UpdateMerchantRequest struct {
+	Name     *string  `json:"name" binding:"omitempty,max=255"`
+	UPIID    *string  `json:"upi_id" binding:"omitempty,max=255"`
+	Category *string  `json:"category" binding:"omitempty,max=100"`
+	Aliases  []string `json:"aliases" binding:"omitempty,max=20,dive,max=255"`
+	IsActive *bool    `json:"is_active"`
+}
@@ -0,0 +1,21 @@
+package dto
+
+import "github.com/shopspring/decimal"
+
+// PayoutBatchRowInput is one recipient of a bulk payout upload. Give either a
+// saved beneficiary or a recipient type and value.
+type PayoutBatchRowInput struct {
+	RecipientType  string          `json:"recipient_type,omitempty" example:"ifsc"` // "upi", "phone" or "ifsc"
+	RecipientValue string          `json:"recipient_value,omitempty" example:"50100123456789"`
+	RecipientIFSC  string          `json:"recipient_ifsc,omitempty" example:"HDFC0000123"`
+	RecipientName  string          `json:"recipient_name,omitempty" example:"Sharma Traders"`
+	Beneficiary    string          `json:"beneficiary,omitempty" example:"printer"` // Saved beneficiary ID or nickname
+	Amount         decimal.Decimal `json:"amount" example:"12500.00"`
+	Description    string          `json:"description,omitempty" example:"Invoice 2291"`
+}
+
+// CreatePayoutBatchRequest represents a bulk payout sent as JSON rather than a file
+type CreatePayoutBatchRequest struct {
+	Name string                `json:"name" binding:"max=100" example:"October vendors"`
+	Rows []PayoutBatchRowInput `json:"rows" binding:"required,min=1"`
+}
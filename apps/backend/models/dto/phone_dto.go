@@ -0,0 +1,25 @@
+package dto
+
+import "time"
+
+// Phone Verification Request
+type PhoneVerificationRequest struct {
+	Phone string `json:"phone" binding:"required" example:"9876543210"`
+}
+
+// Phone Verification Response
+type PhoneVerificationResponse struct {
+	Phone     string    `json:"phone"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Verify Phone Request
+type VerifyPhoneRequest struct {
+	Code string `json:"code" binding:"required,len=6"`
+}
+
+// Save Phone UPI ID Request
+type SavePhoneVPARequest struct {
+	Phone string `json:"phone" binding:"required" example:"9876543210"`
+	VPA   string `json:"vpa" binding:"required" example:"9876543210@ybl"`
+}
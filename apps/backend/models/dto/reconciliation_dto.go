@@ -0,0 +1,14 @@
+package dto
+
+// RunReconciliationRequest selects the period to reconcile: either a single
+// day, or an explicit range of RFC 3339 timestamps
+type RunReconciliationRequest struct {
+	Date string `json:"date,omitempty" example:"2025-01-31"`
+	From string `json:"from,omitempty" example:"2025-01-31T00:00:00+05:30"`
+	To   string `json:"to,omitempty" example:"2025-01-31T23:59:59+05:30"`
+}
+
+// ResolveMismatchRequest records how a reconciliation mismatch was handled
+type ResolveMismatchRequest struct {
+	Note string `json:"note" binding:"required,max=1000"`
+}
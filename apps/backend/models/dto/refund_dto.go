@@ -0,0 +1,12 @@
+package dto
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// CreateRefundRequest represents the request to refund a wallet load or AI payment
+type CreateRefundRequest struct {
+	TransactionID string          `json:"transaction_id" binding:"required"`
+	Amount        decimal.Decimal `json:"amount,omitempty"` // Omit to refund the remaining amount
+	Reason        string          `json:"reason,omitempty" binding:"max=500"`
+}
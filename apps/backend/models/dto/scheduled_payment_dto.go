@@ -0,0 +1,31 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CreateScheduledPaymentRequest represents the request to schedule a one-off or recurring payment
+type CreateScheduledPaymentRequest struct {
+	Name           string          `json:"name" binding:"required,max=100" example:"Rent"`
+	PaymentType    string          `json:"payment_type" binding:"required,oneof=external wallet" example:"external"`
+	RecipientType  string          `json:"recipient_type,omitempty" binding:"omitempty,oneof=upi phone" example:"upi"` // Required for external payments
+	RecipientValue string          `json:"recipient_value" binding:"required" example:"landlord@okhdfc"`               // UPI ID or phone, or a username, email or wallet ID for wallet payments
+	RecipientName  string          `json:"recipient_name,omitempty" binding:"max=100"`
+	Amount         decimal.Decimal `json:"amount" binding:"required" example:"15000.00"`
+	Description    string          `json:"description,omitempty" binding:"max=500"`
+
+	Frequency  string     `json:"frequency" binding:"required,oneof=once daily weekly monthly cron" example:"monthly"`
+	TimeOfDay  string     `json:"time_of_day,omitempty" example:"09:00"` // HH:MM, defaults to 09:00
+	DayOfWeek  int        `json:"day_of_week,omitempty" example:"1"`     // 0 (Sunday) to 6, for weekly schedules
+	DayOfMonth int        `json:"day_of_month,omitempty" example:"1"`    // 1 to 31, for monthly schedules
+	CronExpr   string     `json:"cron_expr,omitempty" example:"0 9 1 * *"`
+	Timezone   string     `json:"timezone,omitempty" example:"Asia/Kolkata"`
+	StartAt    *time.Time `json:"start_at,omitempty"` // Run time of one-off payments; no run is due before it otherwise
+	EndAt      *time.Time `json:"end_at,omitempty"`
+	MaxRuns    int        `json:"max_runs,omitempty" binding:"min=0"`
+
+	InsufficientFunds string `json:"insufficient_funds,omitempty" binding:"omitempty,oneof=retry skip" example:"retry"`
+	MaxRetries        *int   `json:"max_retries,omitempty" binding:"omitempty,min=0,max=10"`
+}
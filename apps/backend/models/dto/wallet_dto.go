@@ -38,6 +38,8 @@ type PaymentVerificationResponse struct {
 type WalletResponse struct {
 	ID                    string          `json:"id"`
 	Balance               decimal.Decimal `json:"balance"`
+	AvailableBalance      decimal.Decimal `json:"available_balance"`
+	HeldAmount            decimal.Decimal `json:"held_amount"`
 	Currency              string          `json:"currency"`
 	Status                string          `json:"status"`
 	DailyLimit            decimal.Decimal `json:"daily_limit"`
@@ -86,4 +88,56 @@ type UpdateWalletSettingsAdvancedRequest struct {
 	AIAllowedMerchants             []string         `json:"ai_allowed_merchants"`
 	AIBlockedCategories            []string         `json:"ai_blocked_categories"`
 	AIWeeklySpendingLimit          *decimal.Decimal `json:"ai_weekly_spending_limit"`
-}
\ No newline at end of file
+}
+
+// Wallet Transfer Request
+type WalletTransferRequest struct {
+	Recipient   string          `json:"recipient" validate:"required" binding:"required"` // Username, email or wallet ID
+	Amount      decimal.Decimal `json:"amount" validate:"required,gt=0" binding:"required"`
+	Description string          `json:"description,omitempty"`
+
+	// Go ahead with a transfer the risk checks asked to confirm, by sending
+	// back the assessment_id they returned. It is used once, and only for the
+	// same amount and recipient within 15 minutes.
+	ConfirmAssessmentID string `json:"confirm_assessment_id,omitempty" binding:"omitempty,uuid"`
+
+	// Filled in by the controller
+	InitiatedBy string `json:"-"`
+	IPAddress   string `json:"-"`
+	UserAgent   string `json:"-"`
+
+	// Hold the transfer is paid from, such as that of the bulk payout it
+	// belongs to; it is reduced by the amount sent along with the debit
+	FundingHold string `json:"-"`
+}
+
+// Wallet Transfer Response
+type WalletTransferResponse struct {
+	ReferenceID       string          `json:"reference_id"`
+	TransactionID     string          `json:"transaction_id"`
+	Amount            decimal.Decimal `json:"amount"`
+	Currency          string          `json:"currency"`
+	RecipientWalletID string          `json:"recipient_wallet_id"`
+	RecipientName     string          `json:"recipient_name"`
+	NewBalance        decimal.Decimal `json:"new_balance"`
+	Status            string          `json:"status"`
+	Message           string          `json:"message"`
+	CreatedAt         string          `json:"created_at"`
+}
+
+// Wallet Limit Usage
+type WalletLimitUsage struct {
+	Limit       decimal.Decimal `json:"limit"`
+	Used        decimal.Decimal `json:"used"`
+	Remaining   decimal.Decimal `json:"remaining"`
+	WindowStart string          `json:"window_start"`
+}
+
+// Wallet Limits Response
+type WalletLimitsResponse struct {
+	Daily                 WalletLimitUsage `json:"daily"`
+	Monthly               WalletLimitUsage `json:"monthly"`
+	AIDaily               WalletLimitUsage `json:"ai_daily"`
+	AIPerTransactionLimit decimal.Decimal  `json:"ai_per_transaction_limit"`
+	AIAccessEnabled       bool             `json:"ai_access_enabled"`
+}
@@ -20,13 +20,19 @@ type ExternalTransfer struct {
 	Description string          `json:"description" gorm:"size:500"`
 
 	// Recipient Details
-	RecipientType  string `json:"recipient_type" gorm:"not null"`  // "upi" or "phone"
-	RecipientValue string `json:"recipient_value" gorm:"not null"` // UPI ID or Phone Number
+	RecipientType  string `json:"recipient_type" gorm:"not null"`          // "upi", "phone" or "ifsc"
+	RecipientValue string `json:"recipient_value" gorm:"not null"`         // UPI ID, Phone Number or bank account number
+	RecipientIFSC  string `json:"recipient_ifsc,omitempty" gorm:"size:11"` // Branch of a bank account recipient
 	RecipientName  string `json:"recipient_name,omitempty" gorm:"size:100"`
+	ResolvedVPA    string `json:"resolved_vpa,omitempty" gorm:"size:255"` // UPI ID a phone number recipient is paid at
+
+	// Saved recipient the transfer was made to, if any
+	BeneficiaryID *uuid.UUID `json:"beneficiary_id,omitempty" gorm:"type:uuid;index"`
 
 	// Transfer Status & References
-	Status         string `json:"status" gorm:"default:'pending';not null"` // pending, processing, success, failed, cancelled
-	TransferMethod string `json:"transfer_method" gorm:"not null"`          // "razorpay_payout", "upi_direct"
+	Status         string `json:"status" gorm:"default:'pending';not null"` // queued, pending, processing, success, failed, cancelled, refunded
+	TransferMethod string `json:"transfer_method" gorm:"not null"`          // "razorpay_payout", "upi_direct", "bank_transfer"
+	TransferMode   string `json:"transfer_mode,omitempty" gorm:"size:10"`   // Payout mode: UPI, IMPS, NEFT or RTGS
 
 	// External Payment Gateway References
 	RazorpayPayoutID  string `json:"razorpay_payout_id,omitempty" gorm:"size:50;index"`
@@ -34,10 +40,16 @@ type ExternalTransfer struct {
 	RazorpayFundID    string `json:"razorpay_fund_id,omitempty" gorm:"size:50"`
 
 	// Financial Details
-	TransferFee  decimal.Decimal `json:"transfer_fee" gorm:"type:decimal(10,2);default:0"`
-	TotalAmount  decimal.Decimal `json:"total_amount" gorm:"type:decimal(15,2);not null"` // Amount + Fee
+	TransferFee  decimal.Decimal `json:"transfer_fee" gorm:"type:decimal(10,2);default:0"` // Including GST
+	TotalAmount  decimal.Decimal `json:"total_amount" gorm:"type:decimal(15,2);not null"`  // Amount + Fee
 	ExchangeRate decimal.Decimal `json:"exchange_rate,omitempty" gorm:"type:decimal(10,6);default:1"`
 
+	// Fee plan the fee was quoted from
+	FeePlanID      *uuid.UUID      `json:"fee_plan_id,omitempty" gorm:"type:uuid"` // Empty for the built-in plan
+	FeePlanVersion int             `json:"fee_plan_version,omitempty"`
+	FeeGST         decimal.Decimal `json:"fee_gst" gorm:"type:decimal(10,2);default:0"` // GST part of the fee
+	FeeWaived      bool            `json:"fee_waived" gorm:"default:false"`             // Free under the plan's monthly quota
+
 	// Tracking & Audit
 	ReferenceID   string     `json:"reference_id" gorm:"unique;not null;size:50"`     // Internal tracking
 	TransactionID *uuid.UUID `json:"transaction_id,omitempty" gorm:"type:uuid;index"` // Link to main transaction
@@ -48,12 +60,17 @@ type ExternalTransfer struct {
 	InitiatedBy string `json:"initiated_by" gorm:"default:'user';not null"` // "user", "bot", "api"
 
 	// Status Tracking
+	SubmitAfter   *time.Time `json:"submit_after,omitempty"` // A queued transfer is sent to Razorpay at this time and can be cancelled until then
 	ProcessedAt   *time.Time `json:"processed_at,omitempty"`
 	CompletedAt   *time.Time `json:"completed_at,omitempty"`
 	FailureReason string     `json:"failure_reason,omitempty" gorm:"size:500"`
 	RetryCount    int        `json:"retry_count" gorm:"default:0"`
 	MaxRetries    int        `json:"max_retries" gorm:"default:3"`
 
+	// Set when a payout is still processing after PayoutReviewAfter; ops
+	// should check it with Razorpay. Polling goes on regardless.
+	ReviewFlaggedAt *time.Time `json:"review_flagged_at,omitempty"`
+
 	// Wallet Balance Tracking
 	BalanceBefore decimal.Decimal `json:"balance_before" gorm:"type:decimal(15,2)"`
 	BalanceAfter  decimal.Decimal `json:"balance_after" gorm:"type:decimal(15,2)"`
@@ -76,6 +93,7 @@ func (ExternalTransfer) TableName() string {
 
 // External Transfer Status Constants
 const (
+	ExternalTransferStatusQueued     = "queued" // Waiting out the cancel window, not yet sent to Razorpay
 	ExternalTransferStatusPending    = "pending"
 	ExternalTransferStatusProcessing = "processing"
 	ExternalTransferStatusSuccess    = "success"
@@ -88,7 +106,7 @@ const (
 const (
 	RecipientTypeUPI   = "upi"
 	RecipientTypePhone = "phone"
-	RecipientTypeIFSC  = "ifsc" // For future bank transfer support
+	RecipientTypeIFSC  = "ifsc" // Bank account number with its branch's IFSC code
 )
 
 // Transfer Method Constants
@@ -100,9 +118,12 @@ const (
 
 // Initiated By Constants
 const (
-	InitiatedByUser = "user"
-	InitiatedByBot  = "bot"
-	InitiatedByAPI  = "api"
+	InitiatedByUser     = "user"
+	InitiatedByBot      = "bot"
+	InitiatedByAPI      = "api"
+	InitiatedByAI       = "ai"       // Settles a confirmed AI payment
+	InitiatedBySchedule = "schedule" // Run of a scheduled payment
+	InitiatedByBatch    = "batch"    // Row of a confirmed bulk payout batch
 )
 
 // BeforeCreate hook to set UUID and reference ID
@@ -136,6 +157,8 @@ func (et *ExternalTransfer) CanRetry() bool {
 // GetDisplayStatus returns user-friendly status message
 func (et *ExternalTransfer) GetDisplayStatus() string {
 	switch et.Status {
+	case ExternalTransferStatusQueued:
+		return "Transfer Scheduled"
 	case ExternalTransferStatusPending:
 		return "Transfer Initiated"
 	case ExternalTransferStatusProcessing:
@@ -164,6 +187,12 @@ func (et *ExternalTransfer) GetRecipientDisplay() string {
 			return "****" + et.RecipientValue[len(et.RecipientValue)-4:]
 		}
 		return et.RecipientValue
+	case RecipientTypeIFSC:
+		// Show only the last digits of the account number
+		if len(et.RecipientValue) >= 4 {
+			return "XXXX" + et.RecipientValue[len(et.RecipientValue)-4:] + " (" + et.RecipientIFSC + ")"
+		}
+		return et.RecipientValue
 	default:
 		return et.RecipientValue
 	}
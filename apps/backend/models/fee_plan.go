@@ -0,0 +1,103 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// FeePlan prices external transfers. The fee of a transfer comes from the
+// first slab matching its recipient type, payout mode and amount, plus GST on
+// that fee. Every change to a plan bumps its version, which transfers record;
+// the terms of each version are kept as a FeePlanVersion.
+type FeePlan struct {
+	ID          uuid.UUID       `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Code        string          `json:"code" gorm:"type:varchar(50);not null;uniqueIndex"`
+	Name        string          `json:"name" gorm:"type:varchar(100);not null"`
+	Version     int             `json:"version" gorm:"not null;default:1"`
+	Slabs       []FeeSlab       `json:"slabs" gorm:"type:jsonb;serializer:json"`
+	GSTRate     decimal.Decimal `json:"gst_rate" gorm:"type:decimal(5,2);default:0"` // Percent charged on the fee
+	IsDefault   bool            `json:"is_default" gorm:"not null;default:false"`    // Plan of users without one assigned
+	IsActive    bool            `json:"is_active" gorm:"not null;default:true"`
+	Description string          `json:"description,omitempty" gorm:"type:varchar(500)"`
+
+	// Promotional quota: this many transfers a calendar month are free, until
+	// FreeTransfersUntil if set
+	FreeTransfersPerMonth int        `json:"free_transfers_per_month" gorm:"not null;default:0"`
+	FreeTransfersUntil    *time.Time `json:"free_transfers_until,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FeeSlab is one row of a fee plan. Empty RecipientType or Mode match any; a
+// zero MaxAmount or MaxFee means no upper bound.
+type FeeSlab struct {
+	RecipientType string          `json:"recipient_type,omitempty"` // upi, phone or ifsc
+	Mode          string          `json:"mode,omitempty"`           // UPI, IMPS, NEFT or RTGS
+	MinAmount     decimal.Decimal `json:"min_amount"`
+	MaxAmount     decimal.Decimal `json:"max_amount"`
+	FlatFee       decimal.Decimal `json:"flat_fee"`
+	Percent       decimal.Decimal `json:"percent"` // Percent of the amount, added to the flat fee
+	MinFee        decimal.Decimal `json:"min_fee"`
+	MaxFee        decimal.Decimal `json:"max_fee"` // Cap before GST
+}
+
+// FeePlanVersion is the terms of a fee plan as they stood at one version, so
+// the fee of any transfer can be traced to the slabs it was priced with
+type FeePlanVersion struct {
+	ID          uuid.UUID       `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	FeePlanID   uuid.UUID       `json:"fee_plan_id" gorm:"type:uuid;not null;uniqueIndex:idx_fee_plan_versions_version,priority:1"`
+	Version     int             `json:"version" gorm:"not null;uniqueIndex:idx_fee_plan_versions_version,priority:2"`
+	Name        string          `json:"name" gorm:"type:varchar(100);not null"`
+	Slabs       []FeeSlab       `json:"slabs" gorm:"type:jsonb;serializer:json"`
+	GSTRate     decimal.Decimal `json:"gst_rate" gorm:"type:decimal(5,2);default:0"`
+	IsDefault   bool            `json:"is_default" gorm:"not null;default:false"`
+	IsActive    bool            `json:"is_active" gorm:"not null;default:true"`
+	Description string          `json:"description,omitempty" gorm:"type:varchar(500)"`
+
+	FreeTransfersPerMonth int        `json:"free_transfers_per_month" gorm:"not null;default:0"`
+	FreeTransfersUntil    *time.Time `json:"free_transfers_until,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"` // When the version took effect
+}
+
+// NewFeePlanVersion snapshots the current terms of a plan
+func NewFeePlanVersion(plan *FeePlan) *FeePlanVersion {
+	return &FeePlanVersion{
+		FeePlanID:             plan.ID,
+		Version:               plan.Version,
+		Name:                  plan.Name,
+		Slabs:                 plan.Slabs,
+		GSTRate:               plan.GSTRate,
+		IsDefault:             plan.IsDefault,
+		IsActive:              plan.IsActive,
+		Description:           plan.Description,
+		FreeTransfersPerMonth: plan.FreeTransfersPerMonth,
+		FreeTransfersUntil:    plan.FreeTransfersUntil,
+	}
+}
+
+// FeePlanAssignment puts a user on a fee plan other than the default
+type FeePlanAssignment struct {
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;primary_key"`
+	FeePlanID uuid.UUID `json:"fee_plan_id" gorm:"type:uuid;not null;index"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (p *FeePlan) BeforeCreate(tx *gorm.DB) (err error) {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return
+}
+
+func (v *FeePlanVersion) BeforeCreate(tx *gorm.DB) (err error) {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// IdempotencyKey remembers the outcome of a money-moving request so a client
+// retrying with the same Idempotency-Key gets the original response back
+// instead of repeating the operation.
+type IdempotencyKey struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Scope       string    `json:"scope" gorm:"type:varchar(100);not null;uniqueIndex:idx_idempotency_scope_key"` // user:<id> or api_key:<id>
+	Key         string    `json:"key" gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_scope_key"`
+	Method      string    `json:"method" gorm:"type:varchar(10);not null"`
+	Path        string    `json:"path" gorm:"type:varchar(255);not null"`
+	RequestHash string    `json:"request_hash" gorm:"type:varchar(64);not null"`
+
+	Status       string `json:"status" gorm:"type:varchar(20);default:'in_progress';not null"`
+	ResponseCode int    `json:"response_code"`
+	ResponseBody string `json:"response_body" gorm:"type:text"`
+
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Idempotency Key Status Constants
+const (
+	IdempotencyStatusInProgress = "in_progress"
+	IdempotencyStatusCompleted  = "completed"
+	IdempotencyStatusFailed     = "failed" // Ended in a server error; it may or may not have taken effect
+)
+
+// TableName returns the table name for IdempotencyKey
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
+
+// BeforeCreate hook to set UUID
+func (k *IdempotencyKey) BeforeCreate(tx *gorm.DB) error {
+	if k.ID == uuid.Nil {
+		k.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Job is a unit of background work stored in Postgres so it survives restarts.
+// Workers claim jobs with SKIP LOCKED and hold them for a visibility timeout;
+// a job whose worker disappears becomes claimable again once the lock lapses.
+type Job struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Type        string    `json:"type" gorm:"type:varchar(50);not null;index"`
+	Payload     string    `json:"payload" gorm:"type:jsonb;not null;default:'{}'"`
+	Status      string    `json:"status" gorm:"type:varchar(20);default:'queued';not null;index:idx_jobs_status_run_at,priority:1"`
+	Attempts    int       `json:"attempts" gorm:"default:0;not null"`
+	MaxAttempts int       `json:"max_attempts" gorm:"default:3;not null"`
+	RunAt       time.Time `json:"run_at" gorm:"not null;index:idx_jobs_status_run_at,priority:2"`
+
+	// Only one queued or running job may exist per dedupe key
+	DedupeKey *string `json:"dedupe_key,omitempty" gorm:"type:varchar(255);uniqueIndex:idx_jobs_active_dedupe_key,where:completed_at IS NULL"`
+
+	LockedBy    string     `json:"locked_by,omitempty" gorm:"type:varchar(100)"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	LastError   string     `json:"last_error,omitempty" gorm:"type:text"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Job Status Constants
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusDead      = "dead" // Out of attempts, kept for inspection
+)
+
+// Job Type Constants
+const (
+	JobTypePayoutSubmit = "payout_submit"
+	JobTypePayoutPoll   = "payout_poll"
+	JobTypeRefundSubmit = "refund_submit"
+	JobTypeReconcile    = "reconcile"
+	JobTypeScheduledRun = "scheduled_payment_run"
+	JobTypePayoutBatch  = "payout_batch"
+)
+
+// TableName returns the table name for Job
+func (Job) TableName() string {
+	return "jobs"
+}
+
+// BeforeCreate hook to set UUID and schedule the job
+func (j *Job) BeforeCreate(tx *gorm.DB) error {
+	if j.ID == uuid.Nil {
+		j.ID = uuid.New()
+	}
+	if j.RunAt.IsZero() {
+		j.RunAt = time.Now()
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// LedgerAccount is a book of account in the double-entry ledger. Every wallet
+// owns exactly one liability account; everything else is a system account
+// identified by its code.
+type LedgerAccount struct {
+	ID       uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Code     string     `json:"code" gorm:"type:varchar(100);uniqueIndex;not null"`
+	Name     string     `json:"name" gorm:"type:varchar(255);not null"`
+	Type     string     `json:"type" gorm:"type:varchar(20);not null"` // asset, liability, equity, revenue, expense
+	WalletID *uuid.UUID `json:"wallet_id,omitempty" gorm:"type:uuid;uniqueIndex"`
+	Currency string     `json:"currency" gorm:"type:varchar(3);default:'INR';not null"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// JournalEntry groups the postings of a single money movement. The postings of
+// an entry always sum to zero.
+type JournalEntry struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ReferenceID   string     `json:"reference_id" gorm:"type:varchar(255);uniqueIndex;not null"`
+	EntryType     string     `json:"entry_type" gorm:"type:varchar(50);not null;index"`
+	Description   string     `json:"description" gorm:"type:text"`
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty" gorm:"type:uuid;index"`
+
+	Postings []Posting `json:"postings,omitempty" gorm:"foreignKey:JournalEntryID"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Posting is one leg of a journal entry. Positive amounts are debits and
+// negative amounts are credits.
+type Posting struct {
+	ID             uuid.UUID       `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	JournalEntryID uuid.UUID       `json:"journal_entry_id" gorm:"type:uuid;not null;index"`
+	AccountID      uuid.UUID       `json:"account_id" gorm:"type:uuid;not null;index"`
+	Amount         decimal.Decimal `json:"amount" gorm:"type:decimal(15,2);not null"`
+	Currency       string          `json:"currency" gorm:"type:varchar(3);default:'INR';not null"`
+
+	Account *LedgerAccount `json:"account,omitempty" gorm:"foreignKey:AccountID"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Ledger Account Type Constants
+const (
+	LedgerAccountTypeAsset     = "asset"
+	LedgerAccountTypeLiability = "liability"
+	LedgerAccountTypeEquity    = "equity"
+	LedgerAccountTypeRevenue   = "revenue"
+	LedgerAccountTypeExpense   = "expense"
+)
+
+// System Ledger Account Codes
+const (
+	LedgerAccountGatewaySettlement = "system:gateway_settlement" // Funds held with Razorpay / the bank
+	LedgerAccountPayoutClearing    = "system:payout_clearing"    // Payouts submitted but not yet settled
+	LedgerAccountMerchantPayable   = "system:merchant_payable"   // AI payments owed to merchants
+	LedgerAccountFeeRevenue        = "system:fee_revenue"        // Transfer fees earned, less GST
+	LedgerAccountGSTPayable        = "system:gst_payable"        // GST collected on fees, owed to the government
+	LedgerAccountOpeningBalance    = "system:opening_balance"    // Balances that predate the ledger
+)
+
+// Journal Entry Type Constants
+const (
+	JournalEntryTypeOpeningBalance   = "opening_balance"
+	JournalEntryTypeWalletLoad       = "wallet_load"
+	JournalEntryTypeExternalTransfer = "external_transfer"
+	JournalEntryTypePayoutSettlement = "payout_settlement"
+	JournalEntryTypePayoutReversal   = "payout_reversal"
+	JournalEntryTypeAIPayment        = "ai_payment"
+	JournalEntryTypeWalletTransfer   = "wallet_transfer"
+	JournalEntryTypeRefund           = "refund"
+	JournalEntryTypeRefundReversal   = "refund_reversal"
+)
+
+// TableName returns the table name for LedgerAccount
+func (LedgerAccount) TableName() string {
+	return "ledger_accounts"
+}
+
+// TableName returns the table name for JournalEntry
+func (JournalEntry) TableName() string {
+	return "journal_entries"
+}
+
+// TableName returns the table name for Posting
+func (Posting) TableName() string {
+	return "ledger_postings"
+}
+
+// IsCreditNormal returns true for accounts whose balance grows with credits
+func (a *LedgerAccount) IsCreditNormal() bool {
+	return a.Type == LedgerAccountTypeLiability ||
+		a.Type == LedgerAccountTypeEquity ||
+		a.Type == LedgerAccountTypeRevenue
+}
+
+// NormalBalance converts the raw sum of postings into the account's natural balance
+func (a *LedgerAccount) NormalBalance(postingSum decimal.Decimal) decimal.Decimal {
+	if a.IsCreditNormal() {
+		return postingSum.Neg()
+	}
+	return postingSum
+}
+
+// IsBalanced returns true if the postings of the entry sum to zero
+func (je *JournalEntry) IsBalanced() bool {
+	sum := decimal.Zero
+	for _, p := range je.Postings {
+		sum = sum.Add(p.Amount)
+	}
+	return sum.IsZero()
+}
+
+// BeforeCreate hook to set UUID
+func (a *LedgerAccount) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate hook to set UUID
+func (je *JournalEntry) BeforeCreate(tx *gorm.DB) error {
+	if je.ID == uuid.Nil {
+		je.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate hook to set UUID
+func (p *Posting) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
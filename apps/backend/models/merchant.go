@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Merchant is a business that AI payments can be paid out to. A merchant
+// named in a prompt is matched against the name and aliases, ignoring case.
+type Merchant struct {
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Name           string    `json:"name" gorm:"type:varchar(255);not null"`
+	NormalizedName string    `json:"-" gorm:"type:varchar(255);not null;uniqueIndex"`
+	Aliases        []string  `json:"aliases" gorm:"type:jsonb;serializer:json"` // Stored lowercased
+	UPIID          string    `json:"upi_id" gorm:"type:varchar(255);not null"`
+	Category       string    `json:"category,omitempty" gorm:"type:varchar(100)"`
+	IsActive       bool      `json:"is_active" gorm:"not null;default:true"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Where the UPI ID of an AI payment came from
+const (
+	MerchantUPISourceRequest  = "request"  // Sent with the payment request
+	MerchantUPISourcePrompt   = "prompt"   // Written in the prompt
+	MerchantUPISourceRegistry = "registry" // Merchant registry
+	MerchantUPISourceHistory  = "history"  // The user's earlier payment to the same merchant
+)
+
+func (m *Merchant) BeforeCreate(tx *gorm.DB) (err error) {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return
+}
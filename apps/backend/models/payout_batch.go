@@ -0,0 +1,118 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// PayoutBatch is a set of external transfers uploaded together, e.g. the
+// month-end vendor payments. Every row is validated on upload and the batch
+// is paid only once the user confirms it.
+type PayoutBatch struct {
+	ID       uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID   uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	Name     string    `json:"name" gorm:"size:100;not null"`
+	FileName string    `json:"file_name,omitempty" gorm:"size:255"`
+
+	// Validation, as of upload
+	TotalRows        int             `json:"total_rows" gorm:"not null"`
+	ValidRows        int             `json:"valid_rows" gorm:"not null"`
+	InvalidRows      int             `json:"invalid_rows" gorm:"not null"`
+	TotalAmount      decimal.Decimal `json:"total_amount" gorm:"type:decimal(15,2);not null"` // Sum of the valid rows
+	TotalFees        decimal.Decimal `json:"total_fees" gorm:"type:decimal(15,2);not null"`
+	GrandTotal       decimal.Decimal `json:"grand_total" gorm:"type:decimal(15,2);not null"` // Amount + Fees, debited from the wallet
+	AvailableBalance decimal.Decimal `json:"available_balance" gorm:"type:decimal(15,2)"`
+	BalanceAfter     decimal.Decimal `json:"balance_after" gorm:"type:decimal(15,2)"`
+	Warnings         string          `json:"warnings,omitempty" gorm:"size:1000"`
+
+	// Progress
+	Status        string          `json:"status" gorm:"size:30;not null;index"` // pending_confirmation, processing, completed, cancelled, expired
+	SucceededRows int             `json:"succeeded_rows" gorm:"default:0"`
+	FailedRows    int             `json:"failed_rows" gorm:"default:0"`
+	PendingRows   int             `json:"pending_rows" gorm:"default:0"` // Valid rows whose transfer has not finished
+	PaidAmount    decimal.Decimal `json:"paid_amount" gorm:"type:decimal(15,2);default:0"`
+
+	ExpiresAt   time.Time  `json:"expires_at"` // Confirm before this, or upload again
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName returns the table name for PayoutBatch
+func (PayoutBatch) TableName() string {
+	return "payout_batches"
+}
+
+// BeforeCreate hook to set UUID
+func (b *PayoutBatch) BeforeCreate(tx *gorm.DB) error {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	return nil
+}
+
+// PayoutBatchRow is one recipient of a payout batch and the transfer made to it
+type PayoutBatchRow struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	BatchID   uuid.UUID `json:"batch_id" gorm:"type:uuid;not null;uniqueIndex:idx_payout_batch_rows_row,priority:1"`
+	RowNumber int       `json:"row_number" gorm:"not null;uniqueIndex:idx_payout_batch_rows_row,priority:2"` // Line in the uploaded file
+
+	// Recipient and amount, as resolved from the upload
+	RecipientType  string          `json:"recipient_type,omitempty" gorm:"size:20"`
+	RecipientValue string          `json:"recipient_value,omitempty" gorm:"size:255"`
+	RecipientIFSC  string          `json:"recipient_ifsc,omitempty" gorm:"size:11"`
+	RecipientName  string          `json:"recipient_name,omitempty" gorm:"size:100"`
+	Beneficiary    string          `json:"beneficiary,omitempty" gorm:"size:50"` // Saved beneficiary ID or nickname from the upload
+	Amount         decimal.Decimal `json:"amount" gorm:"type:decimal(15,2);not null"`
+	Description    string          `json:"description,omitempty" gorm:"size:500"`
+	TransferFee    decimal.Decimal `json:"transfer_fee" gorm:"type:decimal(10,2);default:0"`
+	TransferMode   string          `json:"transfer_mode,omitempty" gorm:"size:10"`
+	ResolvedVia    string          `json:"resolved_via,omitempty" gorm:"size:20"` // Who a phone number belonged to when the user reviewed the batch
+	ResolvedVPA    string          `json:"resolved_vpa,omitempty" gorm:"size:255"`
+
+	Status      string     `json:"status" gorm:"size:20;not null;index"` // invalid, pending, processing, submitted, success, failed, skipped
+	Errors      string     `json:"errors,omitempty" gorm:"size:1000"`    // Why the row failed validation or its transfer failed
+	TransferID  *uuid.UUID `json:"transfer_id,omitempty" gorm:"type:uuid;index"`
+	ReferenceID string     `json:"reference_id,omitempty" gorm:"size:50"`
+
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName returns the table name for PayoutBatchRow
+func (PayoutBatchRow) TableName() string {
+	return "payout_batch_rows"
+}
+
+// BeforeCreate hook to set UUID
+func (r *PayoutBatchRow) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// Payout Batch Status Constants
+const (
+	PayoutBatchStatusPendingConfirmation = "pending_confirmation"
+	PayoutBatchStatusProcessing          = "processing"
+	PayoutBatchStatusCompleted           = "completed" // Every valid row has succeeded or failed
+	PayoutBatchStatusCancelled           = "cancelled"
+	PayoutBatchStatusExpired             = "expired" // Not confirmed in time
+)
+
+// Payout Batch Row Status Constants
+const (
+	PayoutBatchRowStatusInvalid    = "invalid" // Failed validation, never paid
+	PayoutBatchRowStatusPending    = "pending"
+	PayoutBatchRowStatusProcessing = "processing" // Its transfer is being created
+	PayoutBatchRowStatusSubmitted  = "submitted"  // Transfer created, payout in flight
+	PayoutBatchRowStatusSuccess    = "success"
+	PayoutBatchRowStatusFailed     = "failed"
+	PayoutBatchRowStatusSkipped    = "skipped" // The batch was cancelled or expired
+)
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PhoneVerification is a pending check that a user owns a mobile number. The
+// number is only stored on the user once the code sent to it is entered.
+type PhoneVerification struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;unique;not null" json:"user_id"`
+	Phone     string    `gorm:"size:10;not null" json:"phone"`
+	Code      string    `gorm:"not null" json:"-"` // Verification code (hashed)
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	Attempts  int       `gorm:"default:0" json:"attempts"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for PhoneVerification
+func (PhoneVerification) TableName() string {
+	return "phone_verifications"
+}
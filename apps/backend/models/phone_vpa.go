@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PhoneVPA is a UPI ID a user has saved for a phone number. Phone transfers
+// to the number are paid to this UPI ID instead of one looked up with Razorpay.
+type PhoneVPA struct {
+	ID     uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID uuid.UUID `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_phone_vpas_user_phone,priority:1"`
+	Phone  string    `json:"phone" gorm:"size:10;not null;uniqueIndex:idx_phone_vpas_user_phone,priority:2"`
+	VPA    string    `json:"vpa" gorm:"size:255;not null"`
+	Name   string    `json:"name,omitempty" gorm:"size:100"` // Name Razorpay has for the UPI ID
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for PhoneVPA
+func (PhoneVPA) TableName() string {
+	return "phone_vpas"
+}
+
+// BeforeCreate hook to set UUID
+func (p *PhoneVPA) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
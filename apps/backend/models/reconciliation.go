@@ -0,0 +1,115 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ReconciliationRun is one comparison of Razorpay's payments, refunds and
+// payouts against Tranza's records for a period
+type ReconciliationRun struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	PeriodStart time.Time `json:"period_start" gorm:"not null;index"`
+	PeriodEnd   time.Time `json:"period_end" gorm:"not null"`
+	Trigger     string    `json:"trigger" gorm:"type:varchar(20);not null"` // job, cli, api
+	Status      string    `json:"status" gorm:"type:varchar(20);default:'running';not null;index"`
+
+	RemotePayments int `json:"remote_payments"`
+	RemoteRefunds  int `json:"remote_refunds"`
+	RemotePayouts  int `json:"remote_payouts"`
+	Matched        int `json:"matched"`
+	MismatchCount  int `json:"mismatch_count"`
+
+	Error       string     `json:"error,omitempty" gorm:"type:text"`
+	StartedAt   time.Time  `json:"started_at" gorm:"not null"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+
+	Mismatches []ReconciliationMismatch `json:"mismatches,omitempty" gorm:"foreignKey:RunID"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ReconciliationMismatch is one discrepancy found by a run, kept until ops
+// review and resolve it
+type ReconciliationMismatch struct {
+	ID     uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	RunID  uuid.UUID `json:"run_id" gorm:"type:uuid;not null;index"`
+	Source string    `json:"source" gorm:"type:varchar(20);not null"` // payment, refund, payout
+	Kind   string    `json:"kind" gorm:"type:varchar(30);not null;index"`
+
+	RazorpayID   string          `json:"razorpay_id,omitempty" gorm:"type:varchar(255);index"`
+	RemoteAmount decimal.Decimal `json:"remote_amount" gorm:"type:decimal(15,2)"`
+	RemoteStatus string          `json:"remote_status,omitempty" gorm:"type:varchar(30)"`
+
+	LocalType   string          `json:"local_type,omitempty" gorm:"type:varchar(30)"` // transaction, refund, external_transfer
+	LocalID     *uuid.UUID      `json:"local_id,omitempty" gorm:"type:uuid"`
+	LocalAmount decimal.Decimal `json:"local_amount" gorm:"type:decimal(15,2)"`
+	LocalStatus string          `json:"local_status,omitempty" gorm:"type:varchar(30)"`
+
+	Details        string     `json:"details" gorm:"type:text"`
+	Resolved       bool       `json:"resolved" gorm:"default:false;index"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+	ResolutionNote string     `json:"resolution_note,omitempty" gorm:"type:text"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Reconciliation Run Status Constants
+const (
+	ReconciliationStatusRunning   = "running"
+	ReconciliationStatusCompleted = "completed"
+	ReconciliationStatusFailed    = "failed"
+)
+
+// Reconciliation Trigger Constants
+const (
+	ReconciliationTriggerJob = "job"
+	ReconciliationTriggerCLI = "cli"
+	ReconciliationTriggerAPI = "api"
+)
+
+// Reconciliation Mismatch Kind Constants
+const (
+	MismatchKindMissingLocally  = "missing_locally"  // On Razorpay, no Tranza record
+	MismatchKindMissingRemotely = "missing_remotely" // Tranza record, not on Razorpay
+	MismatchKindAmount          = "amount_mismatch"
+	MismatchKindStatus          = "status_mismatch"
+)
+
+// Reconciliation Mismatch Source Constants
+const (
+	MismatchSourcePayment = "payment"
+	MismatchSourceRefund  = "refund"
+	MismatchSourcePayout  = "payout"
+)
+
+// TableName returns the table name for ReconciliationRun
+func (ReconciliationRun) TableName() string {
+	return "reconciliation_runs"
+}
+
+// TableName returns the table name for ReconciliationMismatch
+func (ReconciliationMismatch) TableName() string {
+	return "reconciliation_mismatches"
+}
+
+// BeforeCreate hook to set UUID
+func (r *ReconciliationRun) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate hook to set UUID
+func (m *ReconciliationMismatch) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Refund returns all or part of a wallet load or AI payment. A load refund
+// debits the wallet and sends the money back to the original card or UPI
+// account through Razorpay; an AI payment refund credits the wallet.
+type Refund struct {
+	ID                  uuid.UUID       `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID              uuid.UUID       `json:"user_id" gorm:"type:uuid;not null;index"`
+	WalletID            uuid.UUID       `json:"wallet_id" gorm:"type:uuid;not null;index"`
+	TransactionID       uuid.UUID       `json:"transaction_id" gorm:"type:uuid;not null;index"` // The transaction being refunded
+	RefundTransactionID *uuid.UUID      `json:"refund_transaction_id,omitempty" gorm:"type:uuid"`
+	Kind                string          `json:"kind" gorm:"type:varchar(20);not null"`
+	Amount              decimal.Decimal `json:"amount" gorm:"type:decimal(15,2);not null"`
+	Currency            string          `json:"currency" gorm:"type:varchar(3);default:'INR';not null"`
+	Status              string          `json:"status" gorm:"type:varchar(20);default:'pending';not null;index"`
+	Reason              string          `json:"reason" gorm:"type:text"`
+	ReferenceID         string          `json:"reference_id" gorm:"type:varchar(255);uniqueIndex;not null"`
+
+	// Razorpay details, for load refunds
+	RazorpayPaymentID string `json:"razorpay_payment_id,omitempty" gorm:"type:varchar(255)"`
+	RazorpayRefundID  string `json:"razorpay_refund_id,omitempty" gorm:"type:varchar(255);index"`
+
+	FailureReason string     `json:"failure_reason,omitempty" gorm:"type:text"`
+	ProcessedAt   *time.Time `json:"processed_at,omitempty"`
+
+	History []RefundStatusEvent `json:"history,omitempty" gorm:"foreignKey:RefundID"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RefundStatusEvent records one status change of a refund
+type RefundStatusEvent struct {
+	ID         uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	RefundID   uuid.UUID `json:"refund_id" gorm:"type:uuid;not null;index"`
+	FromStatus string    `json:"from_status,omitempty" gorm:"type:varchar(20)"`
+	ToStatus   string    `json:"to_status" gorm:"type:varchar(20);not null"`
+	Source     string    `json:"source" gorm:"type:varchar(20);not null"` // api, webhook, job
+	Note       string    `json:"note,omitempty" gorm:"type:text"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Refund Status Constants
+const (
+	RefundStatusPending   = "pending"
+	RefundStatusProcessed = "processed"
+	RefundStatusFailed    = "failed"
+)
+
+// Refund Kind Constants
+const (
+	RefundKindWalletLoad = "wallet_load"
+	RefundKindAIPayment  = "ai_payment"
+)
+
+// Refund Status Event Source Constants
+const (
+	RefundEventSourceAPI     = "api"
+	RefundEventSourceWebhook = "webhook"
+	RefundEventSourceJob     = "job"
+)
+
+// TableName returns the table name for Refund
+func (Refund) TableName() string {
+	return "refunds"
+}
+
+// TableName returns the table name for RefundStatusEvent
+func (RefundStatusEvent) TableName() string {
+	return "refund_status_history"
+}
+
+// IsFinal returns true once the refund can no longer change
+func (r *Refund) IsFinal() bool {
+	return r.Status == RefundStatusProcessed || r.Status == RefundStatusFailed
+}
+
+// BeforeCreate hook to set UUID
+func (r *Refund) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate hook to set UUID
+func (e *RefundStatusEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
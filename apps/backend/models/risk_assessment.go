@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/pkg/risk"
+	"gorm.io/gorm"
+)
+
+// RiskAssessment records how an outgoing payment was scored and why. Blocked
+// payments are recorded too; they have no subject because nothing was created.
+type RiskAssessment struct {
+	ID        uuid.UUID       `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    uuid.UUID       `json:"user_id" gorm:"type:uuid;not null;index"`
+	Kind      string          `json:"kind" gorm:"type:varchar(30);not null;index"` // ai_payment, external_transfer, wallet_transfer, bot_transfer
+	SubjectID *uuid.UUID      `json:"subject_id,omitempty" gorm:"type:uuid;index"` // AI payment request, external transfer or wallet transfer debit
+	Amount    decimal.Decimal `json:"amount" gorm:"type:decimal(15,2);not null"`
+	Recipient string          `json:"recipient,omitempty" gorm:"type:varchar(255)"`
+	IPAddress string          `json:"ip_address,omitempty" gorm:"size:45"`
+	Score     int             `json:"score" gorm:"not null"`
+	Level     string          `json:"level" gorm:"type:varchar(10);not null"`
+	Action    string          `json:"action" gorm:"type:varchar(30);not null;index"` // allow, require_confirmation, block
+	Reasons   []risk.Reason   `json:"reasons" gorm:"type:jsonb;serializer:json"`
+	Confirmed bool            `json:"confirmed" gorm:"not null;default:false"` // The user went ahead after being asked to confirm
+	UsedAt    *time.Time      `json:"used_at,omitempty"`                       // When a payment went ahead on the user's confirmation of this assessment
+	CreatedAt time.Time       `json:"created_at" gorm:"index"`
+}
+
+// Messages returns the reasons as plain sentences
+func (ra *RiskAssessment) Messages() []string {
+	messages := make([]string, 0, len(ra.Reasons))
+	for _, reason := range ra.Reasons {
+		messages = append(messages, reason.Message)
+	}
+	return messages
+}
+
+func (ra *RiskAssessment) BeforeCreate(tx *gorm.DB) (err error) {
+	if ra.ID == uuid.Nil {
+		ra.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ScheduledPayment is a transfer the user has set up to run later, once or on
+// a repeating rule such as rent on the 1st of every month
+type ScheduledPayment struct {
+	ID     uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	Name   string    `json:"name" gorm:"size:100;not null"`
+
+	// What to pay
+	PaymentType    string          `json:"payment_type" gorm:"size:20;not null"`    // "external" or "wallet"
+	RecipientType  string          `json:"recipient_type,omitempty" gorm:"size:20"` // "upi" or "phone" for external payments
+	RecipientValue string          `json:"recipient_value" gorm:"size:255;not null"`
+	RecipientName  string          `json:"recipient_name,omitempty" gorm:"size:100"`
+	Amount         decimal.Decimal `json:"amount" gorm:"type:decimal(15,2);not null"`
+	Description    string          `json:"description,omitempty" gorm:"size:500"`
+
+	// When to pay, see pkg/schedule
+	Frequency  string     `json:"frequency" gorm:"size:20;not null"` // once, daily, weekly, monthly, cron
+	TimeOfDay  string     `json:"time_of_day,omitempty" gorm:"size:5"`
+	DayOfWeek  int        `json:"day_of_week,omitempty"`
+	DayOfMonth int        `json:"day_of_month,omitempty"`
+	CronExpr   string     `json:"cron_expr,omitempty" gorm:"size:100"`
+	Timezone   string     `json:"timezone" gorm:"size:50;default:'Asia/Kolkata'"`
+	StartAt    time.Time  `json:"start_at" gorm:"not null"` // No run is due before this; the run time of one-off payments
+	EndAt      *time.Time `json:"end_at,omitempty"`
+	MaxRuns    int        `json:"max_runs,omitempty"` // 0 for no limit
+
+	// What to do when the wallet is short on a run
+	InsufficientFunds string `json:"insufficient_funds" gorm:"size:10;default:'retry'"` // "retry" or "skip"
+	MaxRetries        int    `json:"max_retries" gorm:"default:3"`
+
+	// Progress
+	Status        string     `json:"status" gorm:"size:20;default:'active';not null;index"` // active, paused, cancelled, completed
+	NextRunAt     *time.Time `json:"next_run_at,omitempty" gorm:"index"`
+	LastRunAt     *time.Time `json:"last_run_at,omitempty"`
+	LastRunStatus string     `json:"last_run_status,omitempty" gorm:"size:20"`
+	RunCount      int        `json:"run_count" gorm:"default:0"` // Occurrences that have come due, whatever their outcome
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// TableName returns the table name for ScheduledPayment
+func (ScheduledPayment) TableName() string {
+	return "scheduled_payments"
+}
+
+// BeforeCreate hook to set UUID
+func (sp *ScheduledPayment) BeforeCreate(tx *gorm.DB) error {
+	if sp.ID == uuid.Nil {
+		sp.ID = uuid.New()
+	}
+	return nil
+}
+
+// ScheduledPaymentRun records one occurrence of a scheduled payment
+type ScheduledPaymentRun struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ScheduleID   uuid.UUID `json:"schedule_id" gorm:"type:uuid;not null;uniqueIndex:idx_scheduled_payment_runs_occurrence,priority:1"`
+	UserID       uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	ScheduledFor time.Time `json:"scheduled_for" gorm:"not null;uniqueIndex:idx_scheduled_payment_runs_occurrence,priority:2"`
+
+	Status      string          `json:"status" gorm:"size:20;not null"` // pending, processing, retrying, submitted, success, skipped, failed
+	Attempts    int             `json:"attempts" gorm:"default:0"`
+	Amount      decimal.Decimal `json:"amount" gorm:"type:decimal(15,2);not null"`
+	ReferenceID string          `json:"reference_id,omitempty" gorm:"size:50"` // Reference of the transfer the run made
+	TransferID  *uuid.UUID      `json:"transfer_id,omitempty" gorm:"type:uuid;index"`
+	Reason      string          `json:"reason,omitempty" gorm:"size:500"`
+
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName returns the table name for ScheduledPaymentRun
+func (ScheduledPaymentRun) TableName() string {
+	return "scheduled_payment_runs"
+}
+
+// BeforeCreate hook to set UUID
+func (r *ScheduledPaymentRun) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// Scheduled Payment Type Constants
+const (
+	ScheduledPaymentTypeExternal = "external" // UPI or phone payout
+	ScheduledPaymentTypeWallet   = "wallet"   // Another Tranza wallet
+)
+
+// Scheduled Payment Status Constants
+const (
+	ScheduledPaymentStatusActive    = "active"
+	ScheduledPaymentStatusPaused    = "paused"
+	ScheduledPaymentStatusCancelled = "cancelled"
+	ScheduledPaymentStatusCompleted = "completed" // No more runs are due
+)
+
+// Insufficient Funds Policy Constants
+const (
+	InsufficientFundsRetry = "retry" // Try again later, up to MaxRetries times
+	InsufficientFundsSkip  = "skip"  // Skip the run and wait for the next one
+)
+
+// Scheduled Payment Run Status Constants
+const (
+	ScheduledRunStatusPending    = "pending"
+	ScheduledRunStatusProcessing = "processing"
+	ScheduledRunStatusRetrying   = "retrying"  // Waiting for funds
+	ScheduledRunStatusSubmitted  = "submitted" // Transfer created, payout in flight
+	ScheduledRunStatusSuccess    = "success"
+	ScheduledRunStatusSkipped    = "skipped"
+	ScheduledRunStatusFailed     = "failed"
+)
@@ -7,20 +7,35 @@ import (
 )
 
 type User struct {
-	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	Email      string    `gorm:"unique;not null" json:"email"`
-	Username   string    `gorm:"unique;not null" json:"username"`
-	Password   string    `json:"-"` // Never expose password
-	Avatar     string    `json:"avatar"`
-	Provider   string    `json:"provider"` // "local", "google", "github"
-	ProviderID string    `json:"provider_id"`
-	IsActive   bool      `gorm:"default:true" json:"is_active"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Email       string    `gorm:"unique;not null" json:"email"`
+	Username    string    `gorm:"unique;not null" json:"username"`
+	Password    string    `json:"-"` // Never expose password
+	Avatar      string    `json:"avatar"`
+	Provider    string    `json:"provider"` // "local", "google", "github"
+	ProviderID  string    `json:"provider_id"`
+	IsActive    bool      `gorm:"default:true" json:"is_active"`
+	TOTPSecret  string    `json:"-"` // Authenticator secret, set while enrolling
+	TOTPEnabled bool      `gorm:"default:false" json:"totp_enabled"`
+	Phone       *string   `gorm:"uniqueIndex;size:10" json:"phone,omitempty"`  // Verified mobile number; phone transfers to it credit this user's wallet
+	Role        string    `gorm:"size:20;not null;default:'user'" json:"role"` // user or admin; admins can use /api/v1/admin
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
 
 	// Relations will be loaded separately to avoid circular dependencies
 }
 
+// User roles
+const (
+	UserRoleUser  = "user"
+	UserRoleAdmin = "admin"
+)
+
+// IsAdmin reports whether the user may use the admin API
+func (u *User) IsAdmin() bool {
+	return u.Role == UserRoleAdmin && u.IsActive
+}
+
 type AuthResponse struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
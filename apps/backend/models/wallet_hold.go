@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// WalletHold reserves part of a wallet balance for a pending debit. Held funds
+// stay in the ledger balance but are no longer available to spend until the
+// hold is captured, released or expires.
+type WalletHold struct {
+	ID          uuid.UUID       `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	WalletID    uuid.UUID       `json:"wallet_id" gorm:"type:uuid;not null;index"`
+	UserID      uuid.UUID       `json:"user_id" gorm:"type:uuid;not null;index"`
+	Amount      decimal.Decimal `json:"amount" gorm:"type:decimal(15,2);not null"`
+	Currency    string          `json:"currency" gorm:"type:varchar(3);default:'INR';not null"`
+	Status      string          `json:"status" gorm:"type:varchar(20);default:'active';not null;index"`
+	Purpose     string          `json:"purpose" gorm:"type:varchar(50);not null"`
+	ReferenceID string          `json:"reference_id" gorm:"type:varchar(255);uniqueIndex;not null"`
+	Description string          `json:"description" gorm:"type:text"`
+
+	ExpiresAt     time.Time  `json:"expires_at" gorm:"not null;index"`
+	CapturedAt    *time.Time `json:"captured_at,omitempty"`
+	ReleasedAt    *time.Time `json:"released_at,omitempty"`
+	ReleaseReason string     `json:"release_reason,omitempty" gorm:"type:text"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Wallet Hold Status Constants
+const (
+	WalletHoldStatusActive   = "active"
+	WalletHoldStatusCaptured = "captured"
+	WalletHoldStatusReleased = "released"
+	WalletHoldStatusExpired  = "expired"
+)
+
+// Wallet Hold Purpose Constants
+const (
+	WalletHoldPurposeExternalTransfer = "external_transfer"
+	WalletHoldPurposeAIPayment        = "ai_payment"
+	WalletHoldPurposeAIShopping       = "ai_shopping"
+	WalletHoldPurposePayoutBatch      = "payout_batch" // Rows of a confirmed bulk payout not yet paid
+)
+
+// TableName returns the table name for WalletHold
+func (WalletHold) TableName() string {
+	return "wallet_holds"
+}
+
+// IsActive returns true if the hold still reserves funds
+func (h *WalletHold) IsActive() bool {
+	return h.Status == WalletHoldStatusActive
+}
+
+// BeforeCreate hook to set UUID
+func (h *WalletHold) BeforeCreate(tx *gorm.DB) error {
+	if h.ID == uuid.Nil {
+		h.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+// Package ask turns questions about a user's own wallet history, such as "how
+// much did I spend on Swiggy last month", into structured queries. A Query only
+// names a metric, a source, a counterparty and a period; callers answer it with
+// fixed, parameterized repository methods, so no part of the question is ever
+// used as SQL.
+package ask
+
+import (
+	"errors"
+	"time"
+)
+
+// Metrics
+const (
+	MetricTotal    = "total"
+	MetricCount    = "count"
+	MetricLargest  = "largest"
+	MetricSmallest = "smallest"
+	MetricList     = "list"
+)
+
+// Sources
+const (
+	SourceSpending  = "spending"  // Every debit from the wallet
+	SourceTransfers = "transfers" // UPI and phone transfers
+)
+
+// MaxCounterpartyLength caps the payee text taken from a question
+const MaxCounterpartyLength = 50
+
+// ErrUnsupported is returned for questions that are not about spending or transfers
+var ErrUnsupported = errors.New("only questions about your spending and transfers are supported")
+
+// Query is what a question asks for. To is exclusive.
+type Query struct {
+	Metric       string    `json:"metric"`
+	Source       string    `json:"source"`
+	Counterparty string    `json:"counterparty,omitempty"`
+	Period       string    `json:"period"`
+	From         time.Time `json:"from"`
+	To           time.Time `json:"to"`
+}
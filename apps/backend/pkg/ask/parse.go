@@ -0,0 +1,151 @@
+package ask
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zeusnotfound04/Tranza/pkg/period"
+)
+
+// DefaultPeriodDays is how far back questions without a period look
+const DefaultPeriodDays = 30
+
+// maxPeriodDays caps "last N days" questions
+const maxPeriodDays = 366
+
+var (
+	metricPatterns = []struct {
+		metric  string
+		pattern *regexp.Regexp
+	}{
+		{MetricCount, regexp.MustCompile(`\bhow many\b|\bnumber of\b|\bcount\b`)},
+		{MetricLargest, regexp.MustCompile(`\b(largest|biggest|highest|maximum|max|most expensive)\b`)},
+		{MetricSmallest, regexp.MustCompile(`\b(smallest|lowest|minimum|min|cheapest)\b`)},
+		{MetricTotal, regexp.MustCompile(`\bhow much\b|\btotal\b|\bsum\b`)},
+		{MetricList, regexp.MustCompile(`\b(show|list|which|what were)\b`)},
+	}
+
+	transferWords = regexp.MustCompile(`\b(transfers?|transferred|sent|send|upi)\b`)
+	spendingWords = regexp.MustCompile(`\b(spen[dt]|spending|paid|pay|payments?|purchases?|transactions?|bought|debits?)\b`)
+
+	counterpartyPattern = regexp.MustCompile(`\b(?:on|at|to|from|with)\s+(.+?)(?:\s+(?:for|in|during|over|between)\b.*)?$`)
+	leadingArticle      = regexp.MustCompile(`^(?:the|my)\s+`)
+	trailingPunctuation = regexp.MustCompile(`[\s?.!,]+$`)
+	spaces              = regexp.MustCompile(`\s+`)
+
+	periodPatterns = []struct {
+		pattern *regexp.Regexp
+		resolve func(match []string, now time.Time) (string, time.Time, time.Time)
+	}{
+		{regexp.MustCompile(`\b(?:in the |over the |during the )?(?:last|past) (\d{1,3}) days?\b`), lastDays},
+		{regexp.MustCompile(`\btoday\b`), func(_ []string, now time.Time) (string, time.Time, time.Time) {
+			start := period.StartOfDay(now)
+			return "today", start, start.AddDate(0, 0, 1)
+		}},
+		{regexp.MustCompile(`\byesterday\b`), func(_ []string, now time.Time) (string, time.Time, time.Time) {
+			start := period.StartOfDay(now)
+			return "yesterday", start.AddDate(0, 0, -1), start
+		}},
+		{regexp.MustCompile(`\b(?:this|current) week\b`), func(_ []string, now time.Time) (string, time.Time, time.Time) {
+			start := period.StartOfWeek(now)
+			return "this week", start, start.AddDate(0, 0, 7)
+		}},
+		{regexp.MustCompile(`\b(?:last|previous) week\b`), func(_ []string, now time.Time) (string, time.Time, time.Time) {
+			start := period.StartOfWeek(now)
+			return "last week", start.AddDate(0, 0, -7), start
+		}},
+		{regexp.MustCompile(`\b(?:this|current) month\b`), func(_ []string, now time.Time) (string, time.Time, time.Time) {
+			start := period.StartOfMonth(now)
+			return "this month", start, start.AddDate(0, 1, 0)
+		}},
+		{regexp.MustCompile(`\b(?:last|previous) month\b`), func(_ []string, now time.Time) (string, time.Time, time.Time) {
+			start := period.StartOfMonth(now)
+			return "last month", start.AddDate(0, -1, 0), start
+		}},
+		{regexp.MustCompile(`\b(?:this|current) year\b`), func(_ []string, now time.Time) (string, time.Time, time.Time) {
+			start := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+			return "this year", start, start.AddDate(1, 0, 0)
+		}},
+		{regexp.MustCompile(`\b(?:last|previous) year\b`), func(_ []string, now time.Time) (string, time.Time, time.Time) {
+			start := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
+			return "last year", start.AddDate(-1, 0, 0), start
+		}},
+	}
+)
+
+// Parse reads a question asked at now. Periods are resolved in now's location.
+func Parse(question string, now time.Time) (*Query, error) {
+	text := strings.ToLower(spaces.ReplaceAllString(strings.TrimSpace(question), " "))
+	text = trailingPunctuation.ReplaceAllString(text, "")
+
+	query := &Query{Metric: MetricList, Source: SourceSpending}
+	understood := false
+
+	for _, candidate := range metricPatterns {
+		if candidate.pattern.MatchString(text) {
+			query.Metric = candidate.metric
+			understood = true
+			break
+		}
+	}
+
+	switch {
+	case transferWords.MatchString(text):
+		query.Source = SourceTransfers
+		understood = true
+	case spendingWords.MatchString(text):
+		understood = true
+	}
+
+	if !understood {
+		return nil, ErrUnsupported
+	}
+
+	// Resolve the period and take it out so it is not read as a payee
+	query.Period, query.From, query.To = lastDays([]string{"", strconv.Itoa(DefaultPeriodDays)}, now)
+	for _, candidate := range periodPatterns {
+		if match := candidate.pattern.FindStringSubmatch(text); match != nil {
+			query.Period, query.From, query.To = candidate.resolve(match, now)
+			text = strings.TrimSpace(candidate.pattern.ReplaceAllString(text, " "))
+			break
+		}
+	}
+
+	query.Counterparty = counterparty(text)
+	return query, nil
+}
+
+// counterparty finds who the question is about, e.g. "swiggy" in "how much did
+// i spend on swiggy"
+func counterparty(text string) string {
+	text = trailingPunctuation.ReplaceAllString(spaces.ReplaceAllString(text, " "), "")
+	match := counterpartyPattern.FindStringSubmatch(text)
+	if match == nil {
+		return ""
+	}
+
+	name := strings.TrimSpace(leadingArticle.ReplaceAllString(match[1], ""))
+	if len(name) > MaxCounterpartyLength {
+		name = strings.TrimSpace(name[:MaxCounterpartyLength])
+	}
+	return name
+}
+
+func lastDays(match []string, now time.Time) (string, time.Time, time.Time) {
+	days, err := strconv.Atoi(match[1])
+	if err != nil || days < 1 {
+		days = 1
+	}
+	if days > maxPeriodDays {
+		days = maxPeriodDays
+	}
+
+	end := period.StartOfDay(now).AddDate(0, 0, 1)
+	label := "in the last " + strconv.Itoa(days) + " days"
+	if days == 1 {
+		label = "in the last day"
+	}
+	return label, end.AddDate(0, 0, -days), end
+}
@@ -0,0 +1,132 @@
+package ask_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zeusnotfound04/Tranza/pkg/ask"
+)
+
+var ist = time.FixedZone("IST", 5*60*60+30*60)
+
+// now is Thursday 14 May 2026, 2:30pm in India
+var now = time.Date(2026, 5, 14, 14, 30, 0, 0, ist)
+
+func day(month time.Month, d int) time.Time {
+	return time.Date(2026, month, d, 0, 0, 0, 0, ist)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		question string
+		want     ask.Query
+	}{
+		{"how much did I spend on Swiggy last month", ask.Query{
+			Metric: ask.MetricTotal, Source: ask.SourceSpending, Counterparty: "swiggy",
+			Period: "last month", From: day(4, 1), To: day(5, 1),
+		}},
+		{"What was my largest transfer this week?", ask.Query{
+			Metric: ask.MetricLargest, Source: ask.SourceTransfers,
+			Period: "this week", From: day(5, 11), To: day(5, 18),
+		}},
+		{"how many payments in the last 10 days", ask.Query{
+			Metric: ask.MetricCount, Source: ask.SourceSpending,
+			Period: "in the last 10 days", From: day(5, 5), To: day(5, 15),
+		}},
+		{"show my transactions with Zomato yesterday", ask.Query{
+			Metric: ask.MetricList, Source: ask.SourceSpending, Counterparty: "zomato",
+			Period: "yesterday", From: day(5, 13), To: day(5, 14),
+		}},
+		{"smallest   payment   today", ask.Query{
+			Metric: ask.MetricSmallest, Source: ask.SourceSpending,
+			Period: "today", From: day(5, 14), To: day(5, 15),
+		}},
+		{"total sent last week", ask.Query{
+			Metric: ask.MetricTotal, Source: ask.SourceTransfers,
+			Period: "last week", From: day(5, 4), To: day(5, 11),
+		}},
+		{"how much did I spend this year", ask.Query{
+			Metric: ask.MetricTotal, Source: ask.SourceSpending,
+			Period: "this year", From: day(1, 1), To: time.Date(2027, 1, 1, 0, 0, 0, 0, ist),
+		}},
+		// Without a period the last 30 days are read
+		{"total spent at Amazon", ask.Query{
+			Metric: ask.MetricTotal, Source: ask.SourceSpending, Counterparty: "amazon",
+			Period: "in the last 30 days", From: day(4, 15), To: day(5, 15),
+		}},
+		// "last N days" is capped at a year and a day
+		{"how much did I spend in the last 999 days", ask.Query{
+			Metric: ask.MetricTotal, Source: ask.SourceSpending,
+			Period: "in the last 366 days", From: day(5, 15).AddDate(0, 0, -366), To: day(5, 15),
+		}},
+		{"payments over the past 0 days", ask.Query{
+			Metric: ask.MetricList, Source: ask.SourceSpending,
+			Period: "in the last day", From: day(5, 14), To: day(5, 15),
+		}},
+	}
+	for _, tt := range tests {
+		got, err := ask.Parse(tt.question, now)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.question, err)
+			continue
+		}
+		if got.Metric != tt.want.Metric || got.Source != tt.want.Source || got.Counterparty != tt.want.Counterparty ||
+			got.Period != tt.want.Period || !got.From.Equal(tt.want.From) || !got.To.Equal(tt.want.To) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.question, got, tt.want)
+		}
+	}
+}
+
+func TestParseCounterparty(t *testing.T) {
+	tests := map[string]string{
+		"how much did I send to Ravi Kumar":                "ravi kumar",
+		"how much did I send to my sister for rent":        "sister",
+		"total paid to the landlord in March":              "landlord",
+		"how much did I spend at Starbucks last month?!":   "starbucks",
+		"what were my payments from HDFC during this week": "hdfc",
+		"how much did I spend":                             "",
+	}
+	for question, want := range tests {
+		got, err := ask.Parse(question, now)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", question, err)
+			continue
+		}
+		if got.Counterparty != want {
+			t.Errorf("Parse(%q).Counterparty = %q, want %q", question, got.Counterparty, want)
+		}
+	}
+
+	// Long payees are cut short
+	got, err := ask.Parse("how much did I pay to "+strings.Repeat("a", 80), now)
+	if err != nil || len(got.Counterparty) != ask.MaxCounterpartyLength {
+		t.Errorf("Parse of a long payee = %+v, %v; want %d characters", got, err, ask.MaxCounterpartyLength)
+	}
+}
+
+func TestParseResolvesPeriodsInNowsLocation(t *testing.T) {
+	// 8pm UTC on the 13th is the 14th in India, but today is read in UTC
+	got, err := ask.Parse("how much did I spend today", time.Date(2026, 5, 13, 20, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := time.Date(2026, 5, 13, 0, 0, 0, 0, time.UTC)
+	if !got.From.Equal(want) {
+		t.Errorf("From = %s, want %s", got.From, want)
+	}
+}
+
+func TestParseUnsupported(t *testing.T) {
+	for _, question := range []string{
+		"what is the weather today",
+		"delete my account",
+		"",
+		"   ?  ",
+	} {
+		if got, err := ask.Parse(question, now); !errors.Is(err, ask.ErrUnsupported) {
+			t.Errorf("Parse(%q) = %+v, %v; want ErrUnsupported", question, got, err)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Package catalog is the interface to the stores AI shopping orders are bought
+// from. A Catalog lists products, takes orders and reports their status; the
+// local provider serves a JSON or CSV file and is used for development and
+// tests. Rank and ParseCriteria turn a shopping prompt into ranked suggestions.
+package catalog
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Provider names
+const (
+	ProviderLocal = "local"
+)
+
+// Order statuses reported by catalogs
+const (
+	StatusConfirmed = "confirmed"
+	StatusShipped   = "shipped"
+	StatusDelivered = "delivered"
+	StatusCancelled = "cancelled"
+)
+
+var (
+	ErrProductNotFound = errors.New("product not found")
+	ErrOrderNotFound   = errors.New("order not found")
+	ErrOutOfStock      = errors.New("product is out of stock")
+	ErrNotCancellable  = errors.New("order can no longer be cancelled")
+)
+
+// Product is an item a store sells. Each variant is a size and colour with its own stock.
+type Product struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Brand       string          `json:"brand"`
+	Category    string          `json:"category"`
+	Price       decimal.Decimal `json:"price"`
+	Currency    string          `json:"currency"`
+	Rating      float64         `json:"rating"`
+	Occasions   []string        `json:"occasions,omitempty"`
+	Description string          `json:"description,omitempty"`
+	ImageURL    string          `json:"image_url,omitempty"`
+	URL         string          `json:"url"`
+	Website     string          `json:"website"`
+	Variants    []Variant       `json:"variants"`
+}
+
+// Variant is one size and colour of a product
+type Variant struct {
+	ID    string `json:"id"`
+	Size  string `json:"size"`
+	Color string `json:"color"`
+	Stock int    `json:"stock"`
+}
+
+// Variant returns the product's variant with the given ID
+func (p *Product) Variant(id string) (*Variant, bool) {
+	for i := range p.Variants {
+		if p.Variants[i].ID == id {
+			return &p.Variants[i], true
+		}
+	}
+	return nil, false
+}
+
+// FindVariant returns the in-stock variant closest to the size and colour
+// asked for: both matching, then size, then colour, then any. Empty values
+// match anything.
+func (p *Product) FindVariant(size, color string) (*Variant, bool) {
+	var best *Variant
+	bestScore := -1
+	for i := range p.Variants {
+		variant := &p.Variants[i]
+		if variant.Stock <= 0 {
+			continue
+		}
+		score := 0
+		if size != "" && strings.EqualFold(variant.Size, size) {
+			score += 2
+		}
+		if color != "" && strings.EqualFold(variant.Color, color) {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = variant, score
+		}
+	}
+	return best, best != nil
+}
+
+// Criteria is what a shopper asked for. Empty fields and zero prices mean no preference.
+type Criteria struct {
+	Category string
+	Size     string
+	Color    string
+	Brand    string
+	Occasion string
+	MinPrice float64
+	MaxPrice float64
+}
+
+// OrderLine is one variant and quantity in an order
+type OrderLine struct {
+	ProductID string
+	VariantID string
+	Quantity  int
+}
+
+// Address is where an order is delivered
+type Address struct {
+	Name        string
+	Phone       string
+	AddressLine string
+	Landmark    string
+	City        string
+	State       string
+	PinCode     string
+	Country     string
+}
+
+// Order is sent to a catalog to buy its lines. Reference is Tranza's order
+// number; catalogs treat a repeated reference as the same order.
+type Order struct {
+	Reference string
+	Lines     []OrderLine
+	Address   Address
+}
+
+// Placement is a catalog's view of an order
+type Placement struct {
+	OrderID           string     `json:"order_id"`
+	Status            string     `json:"status"`
+	TrackingNumber    string     `json:"tracking_number,omitempty"`
+	EstimatedDelivery *time.Time `json:"estimated_delivery,omitempty"`
+	DeliveredAt       *time.Time `json:"delivered_at,omitempty"`
+}
+
+// Catalog is a store AI shopping orders can be placed with
+type Catalog interface {
+	Name() string
+	// Brands lists the brands the catalog sells, for spotting them in prompts
+	Brands(ctx context.Context) ([]string, error)
+	// Search returns in-stock products in the criteria's category and price range
+	Search(ctx context.Context, criteria Criteria) ([]Product, error)
+	Product(ctx context.Context, id string) (*Product, error)
+	PlaceOrder(ctx context.Context, order *Order) (*Placement, error)
+	OrderStatus(ctx context.Context, orderID string) (*Placement, error)
+	// CancelOrder cancels an order that has not shipped, or returns ErrNotCancellable
+	CancelOrder(ctx context.Context, orderID string) (*Placement, error)
+}
@@ -0,0 +1,339 @@
+package catalog
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// LocalDeliveryTime is the delivery estimate the local catalog gives new orders
+const LocalDeliveryTime = 5 * 24 * time.Hour
+
+// csvColumns is the header a CSV catalog must have. Each row is one variant;
+// rows sharing a product_id make up one product. Occasions are separated by "|".
+var csvColumns = []string{
+	"product_id", "name", "brand", "category", "price", "rating", "occasions",
+	"description", "image_url", "url", "website", "variant_id", "size", "color", "stock",
+}
+
+// LocalCatalog serves products from memory and keeps orders there. Stock is
+// taken when an order is placed and returned when it is cancelled.
+type LocalCatalog struct {
+	mu       sync.Mutex
+	products []Product
+	byID     map[string]int
+	orders   map[string]*localOrder
+	byRef    map[string]string
+	sequence int
+}
+
+type localOrder struct {
+	placement Placement
+	lines     []OrderLine
+}
+
+// NewLocalCatalog creates a catalog holding the given products
+func NewLocalCatalog(products []Product) *LocalCatalog {
+	c := &LocalCatalog{
+		products: make([]Product, 0, len(products)),
+		byID:     make(map[string]int, len(products)),
+		orders:   make(map[string]*localOrder),
+		byRef:    make(map[string]string),
+	}
+	for _, product := range products {
+		if product.Currency == "" {
+			product.Currency = "INR"
+		}
+		c.byID[product.ID] = len(c.products)
+		c.products = append(c.products, product)
+	}
+	return c
+}
+
+// LoadLocalCatalog reads products from a .json file (an array of Product) or a
+// .csv file with the columns in csvColumns
+func LoadLocalCatalog(path string) (*LocalCatalog, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open catalog: %w", err)
+	}
+	defer file.Close()
+
+	var products []Product
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := json.NewDecoder(file).Decode(&products); err != nil {
+			return nil, fmt.Errorf("failed to read catalog %s: %w", path, err)
+		}
+	case ".csv":
+		if products, err = readCSV(file); err != nil {
+			return nil, fmt.Errorf("failed to read catalog %s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported catalog file %s, use .json or .csv", path)
+	}
+
+	return NewLocalCatalog(products), nil
+}
+
+func readCSV(r io.Reader) ([]Product, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	column := make(map[string]int, len(header))
+	for i, name := range header {
+		column[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range csvColumns {
+		if _, ok := column[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	var products []Product
+	index := make(map[string]int)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(name string) string {
+			return strings.TrimSpace(record[column[name]])
+		}
+
+		stock, err := strconv.Atoi(field("stock"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid stock %q", line, field("stock"))
+		}
+		variant := Variant{ID: field("variant_id"), Size: field("size"), Color: field("color"), Stock: stock}
+
+		id := field("product_id")
+		if i, ok := index[id]; ok {
+			products[i].Variants = append(products[i].Variants, variant)
+			continue
+		}
+
+		price, err := decimal.NewFromString(field("price"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid price %q", line, field("price"))
+		}
+		rating, _ := strconv.ParseFloat(field("rating"), 64)
+		var occasions []string
+		for _, occasion := range strings.Split(field("occasions"), "|") {
+			if occasion = strings.TrimSpace(occasion); occasion != "" {
+				occasions = append(occasions, occasion)
+			}
+		}
+
+		index[id] = len(products)
+		products = append(products, Product{
+			ID:          id,
+			Name:        field("name"),
+			Brand:       field("brand"),
+			Category:    field("category"),
+			Price:       price,
+			Rating:      rating,
+			Occasions:   occasions,
+			Description: field("description"),
+			ImageURL:    field("image_url"),
+			URL:         field("url"),
+			Website:     field("website"),
+			Variants:    []Variant{variant},
+		})
+	}
+	return products, nil
+}
+
+// Name returns the provider name
+func (c *LocalCatalog) Name() string {
+	return ProviderLocal
+}
+
+// Brands lists the catalog's brands
+func (c *LocalCatalog) Brands(ctx context.Context) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var brands []string
+	for _, product := range c.products {
+		key := strings.ToLower(product.Brand)
+		if product.Brand != "" && !seen[key] {
+			seen[key] = true
+			brands = append(brands, product.Brand)
+		}
+	}
+	sort.Strings(brands)
+	return brands, nil
+}
+
+// Search returns in-stock products in the criteria's category and price range
+func (c *LocalCatalog) Search(ctx context.Context, criteria Criteria) ([]Product, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var results []Product
+	for _, product := range c.products {
+		if criteria.Category != "" && !strings.EqualFold(product.Category, criteria.Category) {
+			continue
+		}
+		if criteria.MaxPrice > 0 && product.Price.GreaterThan(decimal.NewFromFloat(criteria.MaxPrice)) {
+			continue
+		}
+		if criteria.MinPrice > 0 && product.Price.LessThan(decimal.NewFromFloat(criteria.MinPrice)) {
+			continue
+		}
+		if _, ok := product.FindVariant("", ""); !ok {
+			continue
+		}
+		results = append(results, copyProduct(product))
+	}
+	return results, nil
+}
+
+// Product returns one product
+func (c *LocalCatalog) Product(ctx context.Context, id string) (*Product, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i, ok := c.byID[id]
+	if !ok {
+		return nil, ErrProductNotFound
+	}
+	product := copyProduct(c.products[i])
+	return &product, nil
+}
+
+// PlaceOrder takes stock for every line and confirms the order. Nothing is
+// taken if any line cannot be filled.
+func (c *LocalCatalog) PlaceOrder(ctx context.Context, order *Order) (*Placement, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if id, ok := c.byRef[order.Reference]; ok {
+		placement := c.orders[id].placement
+		return &placement, nil
+	}
+
+	for _, line := range order.Lines {
+		variant, err := c.variant(line.ProductID, line.VariantID)
+		if err != nil {
+			return nil, err
+		}
+		if line.Quantity <= 0 || variant.Stock < line.Quantity {
+			return nil, fmt.Errorf("%w: %s", ErrOutOfStock, line.ProductID)
+		}
+	}
+	for _, line := range order.Lines {
+		variant, _ := c.variant(line.ProductID, line.VariantID)
+		variant.Stock -= line.Quantity
+	}
+
+	c.sequence++
+	eta := time.Now().Add(LocalDeliveryTime)
+	placement := Placement{
+		OrderID:           fmt.Sprintf("LOCAL-%06d", c.sequence),
+		Status:            StatusConfirmed,
+		EstimatedDelivery: &eta,
+	}
+	c.orders[placement.OrderID] = &localOrder{placement: placement, lines: append([]OrderLine(nil), order.Lines...)}
+	if order.Reference != "" {
+		c.byRef[order.Reference] = placement.OrderID
+	}
+	return &placement, nil
+}
+
+// OrderStatus returns an order's current status
+func (c *LocalCatalog) OrderStatus(ctx context.Context, orderID string) (*Placement, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	order, ok := c.orders[orderID]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+	placement := order.placement
+	return &placement, nil
+}
+
+// CancelOrder cancels a confirmed order and returns its stock
+func (c *LocalCatalog) CancelOrder(ctx context.Context, orderID string) (*Placement, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	order, ok := c.orders[orderID]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+	switch order.placement.Status {
+	case StatusCancelled:
+	case StatusConfirmed:
+		for _, line := range order.lines {
+			if variant, err := c.variant(line.ProductID, line.VariantID); err == nil {
+				variant.Stock += line.Quantity
+			}
+		}
+		order.placement.Status = StatusCancelled
+	default:
+		return nil, ErrNotCancellable
+	}
+	placement := order.placement
+	return &placement, nil
+}
+
+// UpdateOrder moves an order along, standing in for the store shipping and
+// delivering it
+func (c *LocalCatalog) UpdateOrder(orderID, status, trackingNumber string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	order, ok := c.orders[orderID]
+	if !ok {
+		return ErrOrderNotFound
+	}
+	order.placement.Status = status
+	if trackingNumber != "" {
+		order.placement.TrackingNumber = trackingNumber
+	}
+	if status == StatusDelivered {
+		now := time.Now()
+		order.placement.DeliveredAt = &now
+	}
+	return nil
+}
+
+func (c *LocalCatalog) variant(productID, variantID string) (*Variant, error) {
+	i, ok := c.byID[productID]
+	if !ok {
+		return nil, ErrProductNotFound
+	}
+	variant, ok := c.products[i].Variant(variantID)
+	if !ok {
+		return nil, fmt.Errorf("%w: no variant %s of %s", ErrProductNotFound, variantID, productID)
+	}
+	return variant, nil
+}
+
+func copyProduct(product Product) Product {
+	product.Variants = append([]Variant(nil), product.Variants...)
+	product.Occasions = append([]string(nil), product.Occasions...)
+	return product
+}
@@ -0,0 +1,102 @@
+package catalog
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Categories, most specific first so "t-shirt" is not read as "shirt"
+var categoryPatterns = []struct {
+	category string
+	pattern  *regexp.Regexp
+}{
+	{"t-shirts", regexp.MustCompile(`\bt-?shirts?\b|\btees?\b`)},
+	{"shirts", regexp.MustCompile(`\bshirts?\b`)},
+	{"jeans", regexp.MustCompile(`\bjeans?\b|\bdenims?\b`)},
+	{"pants", regexp.MustCompile(`\bpants?\b|\btrousers?\b|\bchinos?\b`)},
+	{"dresses", regexp.MustCompile(`\bdress(es)?\b|\bgowns?\b`)},
+	{"kurtas", regexp.MustCompile(`\bkurtas?\b|\bkurtis?\b`)},
+	{"sarees", regexp.MustCompile(`\bsarees?\b|\bsaris?\b`)},
+	{"jackets", regexp.MustCompile(`\bjackets?\b|\bhoodies?\b|\bblazers?\b`)},
+	{"shoes", regexp.MustCompile(`\bshoes?\b|\bsneakers?\b|\bfootwear\b`)},
+}
+
+var (
+	sizeWords = map[string]string{
+		"extra small": "XS", "small": "S", "medium": "M", "large": "L",
+		"extra large": "XL", "double xl": "XXL",
+	}
+	sizePattern     = regexp.MustCompile(`\bsize\s+(xxxl|xxl|xl|xs|s|m|l|\d{1,2})\b|\b(xxxl|xxl|xl|xs)\b|\b(extra small|extra large|double xl|small|medium|large)\b`)
+	colorPattern    = regexp.MustCompile(`\b(black|white|blue|navy|red|green|yellow|pink|purple|grey|gray|brown|beige|maroon|orange|olive)\b`)
+	occasionPattern = regexp.MustCompile(`\b(party|wedding|office|casual|formal|gym|festive|travel|beach)\b`)
+
+	maxPricePattern     = regexp.MustCompile(`(?:under|below|less than|within|upto|up to|max(?:imum)?|budget(?: of)?)\s*(?:rs\.?|₹|inr)?\s*(\d+(?:,\d{3})*)`)
+	minPricePattern     = regexp.MustCompile(`(?:above|over|more than|at least|min(?:imum)?)\s*(?:rs\.?|₹|inr)?\s*(\d+(?:,\d{3})*)`)
+	betweenPricePattern = regexp.MustCompile(`between\s*(?:rs\.?|₹|inr)?\s*(\d+(?:,\d{3})*)\s*(?:and|-|to)\s*(?:rs\.?|₹|inr)?\s*(\d+(?:,\d{3})*)`)
+)
+
+// ParseCriteria reads a shopping prompt such as "a blue formal shirt in size M
+// under 1500". Brands are matched against the given list, which is usually the
+// catalog's own.
+func ParseCriteria(prompt string, brands []string) Criteria {
+	text := strings.ToLower(prompt)
+	var criteria Criteria
+
+	for _, candidate := range categoryPatterns {
+		if candidate.pattern.MatchString(text) {
+			criteria.Category = candidate.category
+			break
+		}
+	}
+
+	if match := sizePattern.FindStringSubmatch(text); match != nil {
+		switch {
+		case match[1] != "":
+			criteria.Size = strings.ToUpper(match[1])
+		case match[2] != "":
+			criteria.Size = strings.ToUpper(match[2])
+		default:
+			criteria.Size = sizeWords[match[3]]
+		}
+	}
+
+	if match := colorPattern.FindString(text); match != "" {
+		if match == "gray" {
+			match = "grey"
+		}
+		criteria.Color = match
+	}
+
+	if match := occasionPattern.FindString(text); match != "" {
+		criteria.Occasion = match
+	}
+
+	for _, brand := range brands {
+		if brand != "" && regexp.MustCompile(`\b`+regexp.QuoteMeta(strings.ToLower(brand))+`\b`).MatchString(text) {
+			criteria.Brand = brand
+			break
+		}
+	}
+
+	if match := betweenPricePattern.FindStringSubmatch(text); match != nil {
+		criteria.MinPrice, criteria.MaxPrice = parsePrice(match[1]), parsePrice(match[2])
+		if criteria.MinPrice > criteria.MaxPrice {
+			criteria.MinPrice, criteria.MaxPrice = criteria.MaxPrice, criteria.MinPrice
+		}
+	} else {
+		if match := maxPricePattern.FindStringSubmatch(text); match != nil {
+			criteria.MaxPrice = parsePrice(match[1])
+		}
+		if match := minPricePattern.FindStringSubmatch(text); match != nil {
+			criteria.MinPrice = parsePrice(match[1])
+		}
+	}
+
+	return criteria
+}
+
+func parsePrice(text string) float64 {
+	price, _ := strconv.ParseFloat(strings.ReplaceAll(text, ",", ""), 64)
+	return price
+}
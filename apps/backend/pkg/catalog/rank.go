@@ -0,0 +1,107 @@
+package catalog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Points each matching preference adds to a suggestion's score
+const (
+	categoryPoints = 3.0
+	sizePoints     = 2.0
+	colorPoints    = 2.0
+	brandPoints    = 2.0
+	occasionPoints = 1.0
+	pricePoints    = 1.0
+	ratingWeight   = 0.5 // Per rating star
+)
+
+// Suggestion is a product variant ranked against what the shopper asked for
+type Suggestion struct {
+	Product Product
+	Variant Variant
+	Score   float64
+	Reasons []string
+}
+
+// Rank scores products against the criteria and returns the best in-stock
+// variant of each, highest score first, cheapest first on ties. Products
+// priced over budget are left out; a zero budget means no budget.
+func Rank(products []Product, criteria Criteria, budget decimal.Decimal) []Suggestion {
+	suggestions := make([]Suggestion, 0, len(products))
+	for _, product := range products {
+		if budget.IsPositive() && product.Price.GreaterThan(budget) {
+			continue
+		}
+		variant, ok := product.FindVariant(criteria.Size, criteria.Color)
+		if !ok {
+			continue
+		}
+
+		suggestion := Suggestion{Product: product, Variant: *variant}
+		add := func(points float64, reason string) {
+			suggestion.Score += points
+			suggestion.Reasons = append(suggestion.Reasons, reason)
+		}
+
+		if criteria.Category != "" && strings.EqualFold(product.Category, criteria.Category) {
+			add(categoryPoints, product.Category)
+		}
+		if criteria.Size != "" && strings.EqualFold(variant.Size, criteria.Size) {
+			add(sizePoints, "size "+variant.Size)
+		}
+		if criteria.Color != "" && strings.EqualFold(variant.Color, criteria.Color) {
+			add(colorPoints, "in "+strings.ToLower(variant.Color))
+		}
+		if criteria.Brand != "" && strings.EqualFold(product.Brand, criteria.Brand) {
+			add(brandPoints, "by "+product.Brand)
+		}
+		if criteria.Occasion != "" && hasOccasion(product, criteria.Occasion) {
+			add(occasionPoints, "for "+strings.ToLower(criteria.Occasion))
+		}
+		if (criteria.MinPrice > 0 || criteria.MaxPrice > 0) && inPriceRange(product.Price, criteria) {
+			add(pricePoints, "in your price range")
+		}
+		if product.Rating > 0 {
+			suggestion.Score += product.Rating * ratingWeight
+			suggestion.Reasons = append(suggestion.Reasons, fmt.Sprintf("rated %.1f", product.Rating))
+		}
+
+		suggestions = append(suggestions, suggestion)
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].Product.Price.LessThan(suggestions[j].Product.Price)
+	})
+	return suggestions
+}
+
+// Reason joins the suggestion's reasons into a phrase, e.g. "shirts, size M, rated 4.2"
+func (s *Suggestion) Reason() string {
+	return strings.Join(s.Reasons, ", ")
+}
+
+func hasOccasion(product Product, occasion string) bool {
+	for _, candidate := range product.Occasions {
+		if strings.EqualFold(candidate, occasion) {
+			return true
+		}
+	}
+	return false
+}
+
+func inPriceRange(price decimal.Decimal, criteria Criteria) bool {
+	if criteria.MinPrice > 0 && price.LessThan(decimal.NewFromFloat(criteria.MinPrice)) {
+		return false
+	}
+	if criteria.MaxPrice > 0 && price.GreaterThan(decimal.NewFromFloat(criteria.MaxPrice)) {
+		return false
+	}
+	return true
+}
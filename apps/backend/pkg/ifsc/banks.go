@@ -0,0 +1,55 @@
+package ifsc
+
+// bankNames maps the bank code that starts every IFSC code to the bank's name.
+// It covers the banks most transfers go to; load a branch list with
+// LoadDirectory for full coverage.
+var bankNames = map[string]string{
+	"ABHY": "Abhyudaya Co-operative Bank",
+	"AIRP": "Airtel Payments Bank",
+	"AUBL": "AU Small Finance Bank",
+	"BARB": "Bank of Baroda",
+	"BDBL": "Bandhan Bank",
+	"BKID": "Bank of India",
+	"CBIN": "Central Bank of India",
+	"CITI": "Citibank",
+	"CIUB": "City Union Bank",
+	"CNRB": "Canara Bank",
+	"CSBK": "CSB Bank",
+	"DBSS": "DBS Bank India",
+	"DCBL": "DCB Bank",
+	"DLXB": "Dhanlaxmi Bank",
+	"ESFB": "Equitas Small Finance Bank",
+	"FDRL": "Federal Bank",
+	"FINO": "Fino Payments Bank",
+	"HDFC": "HDFC Bank",
+	"HSBC": "HSBC",
+	"IBKL": "IDBI Bank",
+	"ICIC": "ICICI Bank",
+	"IDFB": "IDFC FIRST Bank",
+	"IDIB": "Indian Bank",
+	"INDB": "IndusInd Bank",
+	"IOBA": "Indian Overseas Bank",
+	"IPOS": "India Post Payments Bank",
+	"JAKA": "Jammu & Kashmir Bank",
+	"JSFB": "Jana Small Finance Bank",
+	"KARB": "Karnataka Bank",
+	"KKBK": "Kotak Mahindra Bank",
+	"KVBL": "Karur Vysya Bank",
+	"MAHB": "Bank of Maharashtra",
+	"NSPB": "NSDL Payments Bank",
+	"PSIB": "Punjab & Sind Bank",
+	"PUNB": "Punjab National Bank",
+	"PYTM": "Paytm Payments Bank",
+	"RATN": "RBL Bank",
+	"SBIN": "State Bank of India",
+	"SCBL": "Standard Chartered Bank",
+	"SIBL": "South Indian Bank",
+	"SVCB": "SVC Co-operative Bank",
+	"TMBL": "Tamilnad Mercantile Bank",
+	"UBIN": "Union Bank of India",
+	"UCBA": "UCO Bank",
+	"UJVN": "Ujjivan Small Finance Bank",
+	"USFB": "Unity Small Finance Bank",
+	"UTIB": "Axis Bank",
+	"YESB": "Yes Bank",
+}
@@ -0,0 +1,163 @@
+package ifsc_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zeusnotfound04/Tranza/pkg/ifsc"
+)
+
+func TestValidFormat(t *testing.T) {
+	tests := map[string]bool{
+		"HDFC0001234":  true,
+		"SBIN0ABC123":  true,
+		"HDFC1001234":  false, // Fifth character must be 0
+		"HDFC000123":   false, // Too short
+		"HDFC00012345": false,
+		"HDF00001234":  false, // Bank code is four letters
+		"hdfc0001234":  false, // Callers normalize first
+		"":             false,
+	}
+	for code, want := range tests {
+		if got := ifsc.ValidFormat(code); got != want {
+			t.Errorf("ValidFormat(%q) = %t, want %t", code, got, want)
+		}
+	}
+
+	if got := ifsc.Normalize("  hdfc0001234 "); got != "HDFC0001234" {
+		t.Errorf("Normalize = %q, want HDFC0001234", got)
+	}
+}
+
+func TestValidateAccountNumber(t *testing.T) {
+	tests := map[string]bool{
+		"123456789":           true,
+		"123456789012345678":  true,
+		"12345678":            false, // Fewer than 9 digits
+		"1234567890123456789": false,
+		"12345678A":           false,
+		"1234 56789":          false,
+		"000000000":           false, // No account is all zeros
+		"":                    false,
+	}
+	for account, valid := range tests {
+		err := ifsc.ValidateAccountNumber(account)
+		if valid && err != nil {
+			t.Errorf("ValidateAccountNumber(%q) = %v, want nil", account, err)
+		}
+		if !valid && !errors.Is(err, ifsc.ErrInvalidAccount) {
+			t.Errorf("ValidateAccountNumber(%q) = %v, want ErrInvalidAccount", account, err)
+		}
+	}
+}
+
+func TestLookupWithoutBranchList(t *testing.T) {
+	directory := ifsc.NewDirectory(nil)
+
+	branch, err := directory.Lookup(" icic0000104 ")
+	if err != nil {
+		t.Fatalf("Lookup of a known bank: %v", err)
+	}
+	if branch.IFSC != "ICIC0000104" || branch.Bank != "ICICI Bank" || !branch.IMPS || !branch.NEFT || !branch.RTGS {
+		t.Errorf("Lookup = %+v, want an ICICI Bank branch taking every mode", branch)
+	}
+
+	if _, err := directory.Lookup("ZZZZ0000001"); !errors.Is(err, ifsc.ErrUnknownBank) {
+		t.Errorf("Lookup of an unknown bank = %v, want ErrUnknownBank", err)
+	}
+	if _, err := directory.Lookup("ICIC1000104"); !errors.Is(err, ifsc.ErrInvalidFormat) {
+		t.Errorf("Lookup of a malformed code = %v, want ErrInvalidFormat", err)
+	}
+}
+
+func TestLookupWithBranchList(t *testing.T) {
+	directory := ifsc.NewDirectory([]ifsc.Branch{
+		{IFSC: "hdfc0000060", Branch: "Fort", City: "Mumbai", IMPS: true, NEFT: true},
+		{IFSC: "ABCD0000001", Bank: "Example Co-operative Bank", Branch: "Main", NEFT: true},
+		{IFSC: "not-a-code", Bank: "Dropped"},
+	})
+	if directory.Size() != 2 {
+		t.Fatalf("Size = %d, want 2", directory.Size())
+	}
+
+	// The bank name of a branch without one comes from the built-in list
+	branch, err := directory.Lookup("HDFC0000060")
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+	if branch.Bank != "HDFC Bank" || branch.RTGS || branch.Display() != "HDFC Bank, Fort, Mumbai" {
+		t.Errorf("Lookup = %+v (%q)", branch, branch.Display())
+	}
+
+	// Banks outside the built-in list are learned from the branch list
+	if branch, err := directory.Lookup("ABCD0000001"); err != nil || branch.Bank != "Example Co-operative Bank" {
+		t.Errorf("Lookup of a listed branch = %+v, %v", branch, err)
+	}
+
+	// With a branch list, a known bank is not enough
+	if _, err := directory.Lookup("HDFC0000061"); !errors.Is(err, ifsc.ErrUnknownBranch) {
+		t.Errorf("Lookup of an unlisted branch = %v, want ErrUnknownBranch", err)
+	}
+}
+
+func writeCSV(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ifsc.csv")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDirectory(t *testing.T) {
+	path := writeCSV(t, `BANK,IFSC,BRANCH,CITY,STATE,IMPS,NEFT,RTGS
+State Bank of India, sbin0000300 ,Mumbai Main,Mumbai,Maharashtra,true,true,true
+Example Bank,EXMP0000001,Rural,Nowhere,Goa,false,yes,N
+`)
+	directory, err := ifsc.LoadDirectory(path)
+	if err != nil {
+		t.Fatalf("LoadDirectory: %v", err)
+	}
+	if directory.Size() != 2 {
+		t.Fatalf("Size = %d, want 2", directory.Size())
+	}
+
+	sbi, err := directory.Lookup("SBIN0000300")
+	if err != nil || sbi.State != "Maharashtra" || !sbi.IMPS || !sbi.NEFT || !sbi.RTGS {
+		t.Errorf("Lookup(SBIN0000300) = %+v, %v", sbi, err)
+	}
+	rural, err := directory.Lookup("EXMP0000001")
+	if err != nil || rural.IMPS || !rural.NEFT || rural.RTGS {
+		t.Errorf("Lookup(EXMP0000001) = %+v, %v; want NEFT only", rural, err)
+	}
+}
+
+func TestLoadDirectoryWithoutModeColumns(t *testing.T) {
+	directory, err := ifsc.LoadDirectory(writeCSV(t, "IFSC,BANK,BRANCH\nHDFC0000060,HDFC Bank,Fort\n"))
+	if err != nil {
+		t.Fatalf("LoadDirectory: %v", err)
+	}
+	branch, err := directory.Lookup("HDFC0000060")
+	if err != nil || !branch.IMPS || !branch.NEFT || !branch.RTGS {
+		t.Errorf("Lookup = %+v, %v; want every mode", branch, err)
+	}
+}
+
+func TestLoadDirectoryErrors(t *testing.T) {
+	tests := map[string]string{
+		"missing branch column": "IFSC,BANK\nHDFC0000060,HDFC Bank\n",
+		"bad code":              "IFSC,BANK,BRANCH\nHDFC0000060,HDFC Bank,Fort\nHDFC9,HDFC Bank,Typo\n",
+		"empty file":            "",
+	}
+	for name, content := range tests {
+		if _, err := ifsc.LoadDirectory(writeCSV(t, content)); err == nil {
+			t.Errorf("LoadDirectory with %s: want an error", name)
+		}
+	}
+
+	if _, err := ifsc.LoadDirectory(filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Error("LoadDirectory of a missing file: want an error")
+	}
+}
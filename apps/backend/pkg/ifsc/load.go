@@ -0,0 +1,91 @@
+package ifsc
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// LoadDirectory reads a branch list from a CSV file with at least the columns
+// IFSC, BANK and BRANCH, as in the RBI and Razorpay IFSC datasets. CITY,
+// STATE, IMPS, NEFT and RTGS are read when present; a branch without the
+// payment system columns is assumed to take all three.
+func LoadDirectory(path string) (*Directory, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open IFSC directory: %w", err)
+	}
+	defer file.Close()
+
+	branches, err := readCSV(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IFSC directory %s: %w", path, err)
+	}
+	return NewDirectory(branches), nil
+}
+
+func readCSV(r io.Reader) ([]Branch, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	column := make(map[string]int, len(header))
+	for i, name := range header {
+		column[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"ifsc", "bank", "branch"} {
+		if _, ok := column[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	var branches []Branch
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(name string) string {
+			i, ok := column[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		supports := func(name string) bool {
+			if _, ok := column[name]; !ok {
+				return true
+			}
+			switch strings.ToLower(field(name)) {
+			case "true", "yes", "y", "1":
+				return true
+			}
+			return false
+		}
+
+		code := Normalize(field("ifsc"))
+		if !ValidFormat(code) {
+			return nil, fmt.Errorf("line %d: invalid IFSC code %q", line, field("ifsc"))
+		}
+		branches = append(branches, Branch{
+			IFSC:   code,
+			Bank:   field("bank"),
+			Branch: field("branch"),
+			City:   field("city"),
+			State:  field("state"),
+			IMPS:   supports("imps"),
+			NEFT:   supports("neft"),
+			RTGS:   supports("rtgs"),
+		})
+	}
+	return branches, nil
+}
@@ -0,0 +1,36 @@
+package intent
+
+import "context"
+
+// FallbackProvider tries the primary provider and uses the fallback when it
+// fails, so an unreachable or misbehaving model never blocks a payment request
+type FallbackProvider struct {
+	primary  Provider
+	fallback Provider
+	onError  func(provider string, err error)
+}
+
+// WithFallback wraps primary so errors are answered by fallback. onError, if
+// not nil, is called with each primary failure, e.g. for logging.
+func WithFallback(primary, fallback Provider, onError func(provider string, err error)) *FallbackProvider {
+	return &FallbackProvider{primary: primary, fallback: fallback, onError: onError}
+}
+
+// Name returns the primary provider's name
+func (p *FallbackProvider) Name() string {
+	return p.primary.Name()
+}
+
+// Extract returns the primary provider's intent, or the fallback's if the
+// primary fails. Intent.Provider records which one answered.
+func (p *FallbackProvider) Extract(ctx context.Context, prompt string) (*Intent, error) {
+	intent, err := p.primary.Extract(ctx, prompt)
+	if err == nil {
+		return intent, nil
+	}
+
+	if p.onError != nil {
+		p.onError(p.primary.Name(), err)
+	}
+	return p.fallback.Extract(ctx, prompt)
+}
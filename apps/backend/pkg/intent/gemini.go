@@ -0,0 +1,89 @@
+package intent
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Gemini defaults
+const (
+	DefaultGeminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+	DefaultGeminiModel   = "gemini-1.5-flash"
+)
+
+// GeminiCompleter calls Google's Gemini generateContent API
+type GeminiCompleter struct {
+	APIKey     string
+	Model      string
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewGeminiCompleter creates a Gemini client. Empty model and base URL fall
+// back to the defaults.
+func NewGeminiCompleter(apiKey, model, baseURL string, timeout time.Duration) *GeminiCompleter {
+	if model == "" {
+		model = DefaultGeminiModel
+	}
+	if baseURL == "" {
+		baseURL = DefaultGeminiBaseURL
+	}
+	return &GeminiCompleter{
+		APIKey:     apiKey,
+		Model:      model,
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: timeout},
+	}
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiRequest struct {
+	SystemInstruction geminiContent          `json:"systemInstruction"`
+	Contents          []geminiContent        `json:"contents"`
+	GenerationConfig  map[string]interface{} `json:"generationConfig"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+}
+
+// Complete sends the prompt and returns the text of the first candidate
+func (c *GeminiCompleter) Complete(ctx context.Context, system, prompt string) (string, error) {
+	req := geminiRequest{
+		SystemInstruction: geminiContent{Parts: []geminiPart{{Text: system}}},
+		Contents:          []geminiContent{{Role: "user", Parts: []geminiPart{{Text: prompt}}}},
+		GenerationConfig: map[string]interface{}{
+			"temperature":      0,
+			"responseMimeType": "application/json",
+		},
+	}
+
+	var resp geminiResponse
+	url := c.BaseURL + "/models/" + c.Model + ":generateContent"
+	if err := postJSON(ctx, c.HTTPClient, url, map[string]string{"x-goog-api-key": c.APIKey}, req, &resp); err != nil {
+		return "", err
+	}
+
+	if len(resp.Candidates) == 0 {
+		return "", errors.New("gemini returned no candidates")
+	}
+	var text strings.Builder
+	for _, part := range resp.Candidates[0].Content.Parts {
+		text.WriteString(part.Text)
+	}
+	return text.String(), nil
+}
@@ -0,0 +1,93 @@
+// Package intent extracts structured payment details (amount, merchant,
+// recipient, purpose, currency) from natural language prompts. LLM backends
+// do the extraction when configured; the regex parser is the deterministic
+// fallback.
+package intent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Provider names
+const (
+	ProviderGemini = "gemini"
+	ProviderOpenAI = "openai"
+	ProviderRegex  = "regex"
+)
+
+// DefaultCurrency is assumed when a prompt does not mention one
+const DefaultCurrency = "INR"
+
+// ErrUnparseable is returned when a model response does not contain a usable intent
+var ErrUnparseable = errors.New("could not parse payment intent from model response")
+
+// Intent is the payment a prompt asks for. Fields the prompt does not mention
+// are left empty.
+type Intent struct {
+	Amount     float64 `json:"amount"`
+	Currency   string  `json:"currency"`
+	Merchant   string  `json:"merchant"`
+	Recipient  string  `json:"recipient"`
+	Purpose    string  `json:"purpose"`
+	Confidence float64 `json:"confidence"`
+	Reasoning  string  `json:"reasoning"`
+	Provider   string  `json:"provider"`
+}
+
+// Provider turns a prompt into an Intent
+type Provider interface {
+	Name() string
+	Extract(ctx context.Context, prompt string) (*Intent, error)
+}
+
+// Payee returns who the payment goes to, preferring the merchant
+func (i *Intent) Payee() string {
+	if i.Merchant != "" {
+		return i.Merchant
+	}
+	return i.Recipient
+}
+
+// Describe builds a one-line description of the payment
+func (i *Intent) Describe() string {
+	payee := i.Payee()
+	switch {
+	case payee != "" && i.Amount > 0 && i.Purpose != "":
+		return fmt.Sprintf("Payment of Rs. %.2f to %s for %s", i.Amount, payee, i.Purpose)
+	case payee != "" && i.Amount > 0:
+		return fmt.Sprintf("Payment of Rs. %.2f to %s", i.Amount, payee)
+	case i.Amount > 0:
+		return fmt.Sprintf("Payment of Rs. %.2f", i.Amount)
+	case payee != "":
+		return fmt.Sprintf("Payment to %s", payee)
+	default:
+		return "Payment request"
+	}
+}
+
+// normalize cleans up values coming from any provider so callers see the same
+// shape regardless of which one produced the intent
+func (i *Intent) normalize() {
+	i.Merchant = strings.TrimSpace(i.Merchant)
+	i.Recipient = strings.TrimSpace(i.Recipient)
+	i.Purpose = strings.TrimSpace(i.Purpose)
+	i.Reasoning = strings.TrimSpace(i.Reasoning)
+
+	i.Currency = strings.ToUpper(strings.TrimSpace(i.Currency))
+	if i.Currency == "" || i.Currency == "RS" || i.Currency == "₹" {
+		i.Currency = DefaultCurrency
+	}
+
+	if i.Amount < 0 {
+		i.Amount = 0
+	}
+	if i.Confidence < 0 {
+		i.Confidence = 0
+	}
+	if i.Confidence > 1 {
+		i.Confidence = 1
+	}
+}
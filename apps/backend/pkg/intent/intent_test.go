@@ -0,0 +1,303 @@
+package intent_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zeusnotfound04/Tranza/pkg/intent"
+)
+
+func TestRegexProvider(t *testing.T) {
+	tests := []struct {
+		prompt string
+		want   intent.Intent
+	}{
+		{
+			prompt: "Pay Rs. 1,500 to Swiggy",
+			want:   intent.Intent{Amount: 1500, Currency: "INR", Merchant: "Swiggy"},
+		},
+		{
+			prompt: "send 250 rupees to alice@okhdfc",
+			want:   intent.Intent{Amount: 250, Currency: "INR", Recipient: "alice@okhdfc"},
+		},
+		{
+			// Digits in the phone number are not taken for the amount
+			prompt: "transfer ₹300 to 9876543210",
+			want:   intent.Intent{Amount: 300, Currency: "INR", Recipient: "9876543210"},
+		},
+		{
+			prompt: "pay $20 to @bob_99",
+			want:   intent.Intent{Amount: 20, Currency: "USD", Recipient: "bob_99"},
+		},
+	}
+	for _, tt := range tests {
+		got, err := intent.NewRegexProvider().Extract(context.Background(), tt.prompt)
+		if err != nil {
+			t.Fatalf("Extract(%q): %v", tt.prompt, err)
+		}
+		if got.Amount != tt.want.Amount || got.Currency != tt.want.Currency || got.Recipient != tt.want.Recipient ||
+			(tt.want.Merchant != "" && got.Merchant != tt.want.Merchant) {
+			t.Errorf("Extract(%q) = %+v, want %+v", tt.prompt, got, tt.want)
+		}
+		if got.Provider != intent.ProviderRegex {
+			t.Errorf("Extract(%q).Provider = %q, want %q", tt.prompt, got.Provider, intent.ProviderRegex)
+		}
+	}
+
+	// A prompt with nothing to pay gets low confidence instead of an error
+	got, err := intent.NewRegexProvider().Extract(context.Background(), "hello there")
+	if err != nil || got.Amount != 0 || got.Payee() != "" || got.Confidence != 0.3 {
+		t.Fatalf("Extract of an unclear prompt = %+v, %v", got, err)
+	}
+}
+
+func TestParseResponse(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		want  intent.Intent
+	}{
+		{
+			name:  "plain JSON",
+			reply: `{"amount": 99.5, "currency": "INR", "merchant": "Netflix", "purpose": "subscription", "confidence": 0.8}`,
+			want:  intent.Intent{Amount: 99.5, Currency: "INR", Merchant: "Netflix", Purpose: "subscription", Confidence: 0.8},
+		},
+		{
+			name:  "code fence and numbers as strings",
+			reply: "Here you go:\n```json\n{\"amount\": \"Rs. 2,000\", \"recipient\": \"mom\", \"confidence\": \"0.7\"}\n```",
+			want:  intent.Intent{Amount: 2000, Currency: "INR", Recipient: "mom", Confidence: 0.7},
+		},
+		{
+			name:  "values cleaned up",
+			reply: `{"amount": -5, "currency": " usd ", "merchant": "  Uber ", "confidence": 3}`,
+			want:  intent.Intent{Currency: "USD", Merchant: "Uber", Confidence: 1},
+		},
+		{
+			name:  "rupee symbol as currency",
+			reply: `{"amount": null, "currency": "₹", "confidence": -1}`,
+			want:  intent.Intent{Currency: "INR"},
+		},
+	}
+	for _, tt := range tests {
+		got, err := intent.ParseResponse(tt.reply)
+		if err != nil {
+			t.Fatalf("%s: ParseResponse: %v", tt.name, err)
+		}
+		if *got != tt.want {
+			t.Errorf("%s: ParseResponse = %+v, want %+v", tt.name, *got, tt.want)
+		}
+	}
+
+	for _, reply := range []string{"", "no JSON here", "} {", `{"amount": "lots"}`, `{"amount": true}`} {
+		if _, err := intent.ParseResponse(reply); !errors.Is(err, intent.ErrUnparseable) {
+			t.Errorf("ParseResponse(%q) = %v, want ErrUnparseable", reply, err)
+		}
+	}
+}
+
+func TestRecordedGeminiReplies(t *testing.T) {
+	recorded, err := intent.LoadRecorded(filepath.Join("testdata", "gemini.json"))
+	if err != nil {
+		t.Fatalf("LoadRecorded: %v", err)
+	}
+	provider := intent.NewLLMProvider(intent.ProviderGemini, recorded)
+
+	tests := []struct {
+		prompt string
+		want   intent.Intent
+	}{
+		{
+			prompt: "Pay 450 to Swiggy for dinner",
+			want:   intent.Intent{Amount: 450, Currency: "INR", Merchant: "Swiggy", Purpose: "dinner", Confidence: 0.95},
+		},
+		{
+			prompt: "send rahul@okaxis ₹1,200 for rent",
+			want:   intent.Intent{Amount: 1200, Currency: "INR", Recipient: "rahul@okaxis", Purpose: "rent", Confidence: 0.9},
+		},
+		{
+			prompt: "recharge airtel",
+			want:   intent.Intent{Currency: "INR", Merchant: "Airtel", Purpose: "mobile recharge", Confidence: 1},
+		},
+	}
+	for _, tt := range tests {
+		got, err := provider.Extract(context.Background(), tt.prompt)
+		if err != nil {
+			t.Fatalf("Extract(%q): %v", tt.prompt, err)
+		}
+		got.Reasoning = ""
+		tt.want.Provider = intent.ProviderGemini
+		if *got != tt.want {
+			t.Errorf("Extract(%q) = %+v, want %+v", tt.prompt, *got, tt.want)
+		}
+	}
+
+	if _, err := provider.Extract(context.Background(), "what's the weather like"); !errors.Is(err, intent.ErrUnparseable) {
+		t.Fatalf("Extract of a reply without JSON = %v, want ErrUnparseable", err)
+	}
+	if _, err := provider.Extract(context.Background(), "a prompt nobody recorded"); err == nil {
+		t.Fatal("Extract of an unrecorded prompt succeeded")
+	}
+}
+
+func TestWithFallback(t *testing.T) {
+	recorded := intent.NewRecorded(map[string]string{
+		"pay 100 to zomato": `{"amount": 100, "merchant": "Zomato", "confidence": 0.9}`,
+		"pay 200 to uber":   "sorry, I can't help with that",
+	})
+
+	var failures []string
+	provider := intent.WithFallback(intent.NewLLMProvider(intent.ProviderOpenAI, recorded), intent.NewRegexProvider(), func(provider string, err error) {
+		failures = append(failures, provider)
+	})
+	if provider.Name() != intent.ProviderOpenAI {
+		t.Fatalf("Name = %q, want the primary's", provider.Name())
+	}
+
+	got, err := provider.Extract(context.Background(), "pay 100 to zomato")
+	if err != nil || got.Provider != intent.ProviderOpenAI || got.Amount != 100 {
+		t.Fatalf("Extract answered by the model = %+v, %v", got, err)
+	}
+
+	// An unusable reply and an unknown prompt both fall back to the regex parser
+	for _, prompt := range []string{"pay 200 to uber", "pay 300 to ola"} {
+		got, err := provider.Extract(context.Background(), prompt)
+		if err != nil || got.Provider != intent.ProviderRegex || got.Amount == 0 {
+			t.Fatalf("Extract(%q) = %+v, %v; want the regex parser's intent", prompt, got, err)
+		}
+	}
+	if len(failures) != 2 || failures[0] != intent.ProviderOpenAI {
+		t.Fatalf("onError calls = %v, want two for %s", failures, intent.ProviderOpenAI)
+	}
+
+	// onError is optional
+	quiet := intent.WithFallback(intent.NewLLMProvider(intent.ProviderOpenAI, recorded), intent.NewRegexProvider(), nil)
+	if got, err := quiet.Extract(context.Background(), "pay 200 to uber"); err != nil || got.Provider != intent.ProviderRegex {
+		t.Fatalf("Extract without onError = %+v, %v", got, err)
+	}
+}
+
+func TestRecorderReplaysSavedSession(t *testing.T) {
+	live := intent.NewRecorded(map[string]string{"pay 50 to chai wala": `{"amount": 50, "merchant": "Chai Wala"}`})
+	recorder := intent.NewRecorder(live)
+	if _, err := intent.NewLLMProvider(intent.ProviderGemini, recorder).Extract(context.Background(), "pay 50 to chai wala"); err != nil {
+		t.Fatalf("Extract through the recorder: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	if err := recorder.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	replayed, err := intent.LoadRecorded(path)
+	if err != nil {
+		t.Fatalf("LoadRecorded: %v", err)
+	}
+	got, err := intent.NewLLMProvider(intent.ProviderGemini, replayed).Extract(context.Background(), "pay 50 to chai wala")
+	if err != nil || got.Amount != 50 || got.Merchant != "Chai Wala" {
+		t.Fatalf("replayed Extract = %+v, %v", got, err)
+	}
+}
+
+func TestGeminiCompleter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-test:generateContent" || r.Header.Get("x-goog-api-key") != "key" {
+			http.Error(w, `{"error": "not found"}`, http.StatusNotFound)
+			return
+		}
+		var req struct {
+			SystemInstruction struct {
+				Parts []struct{ Text string } `json:"parts"`
+			} `json:"systemInstruction"`
+			Contents []struct {
+				Parts []struct{ Text string } `json:"parts"`
+			} `json:"contents"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Contents) != 1 ||
+			req.SystemInstruction.Parts[0].Text != intent.SystemPrompt {
+			http.Error(w, `{"error": "bad request"}`, http.StatusBadRequest)
+			return
+		}
+		if req.Contents[0].Parts[0].Text == "empty" {
+			w.Write([]byte(`{"candidates": []}`))
+			return
+		}
+		w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "{\"amount\": "}, {"text": "75}"}]}}]}`))
+	}))
+	defer server.Close()
+
+	completer := intent.NewGeminiCompleter("key", "gemini-test", server.URL+"/", time.Second)
+	got, err := intent.NewLLMProvider(intent.ProviderGemini, completer).Extract(context.Background(), "pay 75")
+	if err != nil || got.Amount != 75 {
+		t.Fatalf("Extract = %+v, %v", got, err)
+	}
+	if _, err := completer.Complete(context.Background(), intent.SystemPrompt, "empty"); err == nil {
+		t.Fatal("Complete with no candidates succeeded")
+	}
+
+	wrongKey := intent.NewGeminiCompleter("other", "gemini-test", server.URL, time.Second)
+	if _, err := wrongKey.Complete(context.Background(), intent.SystemPrompt, "pay 75"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("Complete with a wrong key = %v, want the API error", err)
+	}
+}
+
+func TestOpenAICompleter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		var req struct {
+			Model    string `json:"model"`
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Messages) != 2 || req.Messages[0].Role != "system" {
+			http.Error(w, `{"error": "bad request"}`, http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("Authorization") != "" || req.Model != intent.DefaultOpenAIModel {
+			http.Error(w, `{"error": "unexpected auth or model"}`, http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "{\"amount\": 120, \"recipient\": \"@sam\"}"}}]}`))
+	}))
+	defer server.Close()
+
+	// A local server needs no key, and the default model is sent
+	completer := intent.NewOpenAICompleter("", "", server.URL, time.Second)
+	got, err := intent.NewLLMProvider(intent.ProviderOpenAI, completer).Extract(context.Background(), "pay sam 120")
+	if err != nil || got.Amount != 120 || got.Recipient != "@sam" || got.Provider != intent.ProviderOpenAI {
+		t.Fatalf("Extract = %+v, %v", got, err)
+	}
+
+	server.Close()
+	if _, err := completer.Complete(context.Background(), intent.SystemPrompt, "pay sam 120"); err == nil {
+		t.Fatal("Complete against a closed server succeeded")
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		intent intent.Intent
+		want   string
+	}{
+		{intent.Intent{Amount: 500, Merchant: "Swiggy", Recipient: "bob", Purpose: "lunch"}, "Payment of Rs. 500.00 to Swiggy for lunch"},
+		{intent.Intent{Amount: 80, Recipient: "bob"}, "Payment of Rs. 80.00 to bob"},
+		{intent.Intent{Amount: 12.5}, "Payment of Rs. 12.50"},
+		{intent.Intent{Recipient: "bob"}, "Payment to bob"},
+		{intent.Intent{}, "Payment request"},
+	}
+	for _, tt := range tests {
+		if got := tt.intent.Describe(); got != tt.want {
+			t.Errorf("Describe(%+v) = %q, want %q", tt.intent, got, tt.want)
+		}
+	}
+}
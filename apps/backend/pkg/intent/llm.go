@@ -0,0 +1,170 @@
+package intent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// SystemPrompt tells the model what to extract and the JSON shape to reply with
+const SystemPrompt = `You extract payment instructions for Tranza, an Indian digital wallet.
+Read the user's message and reply with a single JSON object and nothing else:
+{"amount": number, "currency": string, "merchant": string, "recipient": string, "purpose": string, "confidence": number, "reasoning": string}
+
+- amount: the amount to pay as a number in major units (500.50, not "Rs 500.50"); 0 if not stated.
+- currency: ISO 4217 code; use "INR" for rupees, Rs or ₹, and when no currency is mentioned.
+- merchant: the business or service being paid, e.g. "Swiggy" or "Airtel"; empty if the payee is a person.
+- recipient: the person being paid, or a UPI ID, phone number, email or @username exactly as written; empty if none.
+- purpose: a short phrase for what the payment is for; empty if not stated.
+- confidence: 0 to 1, how sure you are that the message is a payment instruction and the fields are right.
+- reasoning: one sentence explaining what you extracted.
+
+Never invent values that are not in the message.`
+
+// Completer sends a system and user prompt to a chat model and returns the
+// text of its reply
+type Completer interface {
+	Complete(ctx context.Context, system, prompt string) (string, error)
+}
+
+// LLMProvider extracts intents by asking a model for the JSON described in
+// SystemPrompt
+type LLMProvider struct {
+	name      string
+	completer Completer
+}
+
+// NewLLMProvider creates a provider backed by the given model
+func NewLLMProvider(name string, completer Completer) *LLMProvider {
+	return &LLMProvider{name: name, completer: completer}
+}
+
+// Name returns the provider name
+func (p *LLMProvider) Name() string {
+	return p.name
+}
+
+// Extract asks the model for the intent and parses its reply
+func (p *LLMProvider) Extract(ctx context.Context, prompt string) (*Intent, error) {
+	reply, err := p.completer.Complete(ctx, SystemPrompt, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("%s request failed: %w", p.name, err)
+	}
+
+	intent, err := ParseResponse(reply)
+	if err != nil {
+		return nil, err
+	}
+	intent.Provider = p.name
+	return intent, nil
+}
+
+// llmIntent mirrors Intent but accepts numbers sent as strings, which models
+// do often enough to matter
+type llmIntent struct {
+	Amount     flexibleNumber `json:"amount"`
+	Currency   string         `json:"currency"`
+	Merchant   string         `json:"merchant"`
+	Recipient  string         `json:"recipient"`
+	Purpose    string         `json:"purpose"`
+	Confidence flexibleNumber `json:"confidence"`
+	Reasoning  string         `json:"reasoning"`
+}
+
+type flexibleNumber float64
+
+func (n *flexibleNumber) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var number float64
+	if err := json.Unmarshal(data, &number); err == nil {
+		*n = flexibleNumber(number)
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("expected a number, got %s", string(data))
+	}
+	text = strings.NewReplacer(",", "", "₹", "", "Rs.", "", "Rs", "", " ", "").Replace(text)
+	if text == "" {
+		return nil
+	}
+	number, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return fmt.Errorf("expected a number, got %q", text)
+	}
+	*n = flexibleNumber(number)
+	return nil
+}
+
+// ParseResponse reads the JSON object out of a model reply. Markdown code
+// fences and text around the object are ignored.
+func ParseResponse(reply string) (*Intent, error) {
+	start := strings.Index(reply, "{")
+	end := strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return nil, ErrUnparseable
+	}
+
+	var raw llmIntent
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnparseable, err)
+	}
+
+	intent := &Intent{
+		Amount:     float64(raw.Amount),
+		Currency:   raw.Currency,
+		Merchant:   raw.Merchant,
+		Recipient:  raw.Recipient,
+		Purpose:    raw.Purpose,
+		Confidence: float64(raw.Confidence),
+		Reasoning:  raw.Reasoning,
+	}
+	intent.normalize()
+	return intent, nil
+}
+
+// postJSON sends body to url and decodes the JSON reply into result
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body, result interface{}) error {
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("API error %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	if err := json.Unmarshal(respBody, result); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
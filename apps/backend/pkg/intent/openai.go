@@ -0,0 +1,86 @@
+package intent
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAI defaults. Any server implementing the chat completions API (Azure
+// OpenAI, Ollama, vLLM, OpenRouter, ...) can be used by changing the base URL.
+const (
+	DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+	DefaultOpenAIModel   = "gpt-4o-mini"
+)
+
+// OpenAICompleter calls an OpenAI-compatible chat completions endpoint
+type OpenAICompleter struct {
+	APIKey     string
+	Model      string
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewOpenAICompleter creates an OpenAI-compatible client. Empty model and base
+// URL fall back to the defaults.
+func NewOpenAICompleter(apiKey, model, baseURL string, timeout time.Duration) *OpenAICompleter {
+	if model == "" {
+		model = DefaultOpenAIModel
+	}
+	if baseURL == "" {
+		baseURL = DefaultOpenAIBaseURL
+	}
+	return &OpenAICompleter{
+		APIKey:     apiKey,
+		Model:      model,
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: timeout},
+	}
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIRequest struct {
+	Model          string            `json:"model"`
+	Messages       []openAIMessage   `json:"messages"`
+	Temperature    float64           `json:"temperature"`
+	ResponseFormat map[string]string `json:"response_format"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// Complete sends the prompt and returns the content of the first choice
+func (c *OpenAICompleter) Complete(ctx context.Context, system, prompt string) (string, error) {
+	req := openAIRequest{
+		Model: c.Model,
+		Messages: []openAIMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: prompt},
+		},
+		ResponseFormat: map[string]string{"type": "json_object"},
+	}
+
+	headers := map[string]string{}
+	if c.APIKey != "" {
+		headers["Authorization"] = "Bearer " + c.APIKey
+	}
+
+	var resp openAIResponse
+	if err := postJSON(ctx, c.HTTPClient, c.BaseURL+"/chat/completions", headers, req, &resp); err != nil {
+		return "", err
+	}
+
+	if len(resp.Choices) == 0 {
+		return "", errors.New("model returned no choices")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
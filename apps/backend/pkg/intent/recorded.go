@@ -0,0 +1,102 @@
+package intent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Recorded is a Completer that replays model replies captured earlier, keyed
+// by prompt. Paired with NewLLMProvider it runs the full extraction pipeline
+// (reply parsing, normalization, fallback) offline and deterministically.
+//
+//	recorded, _ := intent.LoadRecorded("testdata/gemini.json")
+//	provider := intent.NewLLMProvider(intent.ProviderGemini, recorded)
+type Recorded struct {
+	mu      sync.RWMutex
+	replies map[string]string
+}
+
+// NewRecorded creates a replaying Completer from prompt to reply pairs
+func NewRecorded(replies map[string]string) *Recorded {
+	copied := make(map[string]string, len(replies))
+	for prompt, reply := range replies {
+		copied[prompt] = reply
+	}
+	return &Recorded{replies: copied}
+}
+
+// LoadRecorded reads replies saved by Recorder.Save
+func LoadRecorded(path string) (*Recorded, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recorded replies: %w", err)
+	}
+
+	var replies map[string]string
+	if err := json.Unmarshal(data, &replies); err != nil {
+		return nil, fmt.Errorf("failed to parse recorded replies: %w", err)
+	}
+	return NewRecorded(replies), nil
+}
+
+// Add records the reply to return for prompt
+func (r *Recorded) Add(prompt, reply string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.replies[prompt] = reply
+}
+
+// Complete returns the recorded reply for prompt, or an error if there is none
+func (r *Recorded) Complete(ctx context.Context, system, prompt string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	reply, ok := r.replies[prompt]
+	if !ok {
+		return "", fmt.Errorf("no recorded reply for prompt %q", prompt)
+	}
+	return reply, nil
+}
+
+// Recorder wraps a live Completer and keeps every reply so a session against
+// a real model can be saved and replayed later with LoadRecorded
+type Recorder struct {
+	Recorded
+	completer Completer
+}
+
+// NewRecorder creates a Recorder around completer
+func NewRecorder(completer Completer) *Recorder {
+	return &Recorder{
+		Recorded:  Recorded{replies: map[string]string{}},
+		completer: completer,
+	}
+}
+
+// Complete forwards the prompt and records the reply
+func (r *Recorder) Complete(ctx context.Context, system, prompt string) (string, error) {
+	reply, err := r.completer.Complete(ctx, system, prompt)
+	if err != nil {
+		return "", err
+	}
+	r.Add(prompt, reply)
+	return reply, nil
+}
+
+// Save writes the recorded replies as JSON
+func (r *Recorder) Save(path string) error {
+	r.mu.RLock()
+	data, err := json.MarshalIndent(r.replies, "", "  ")
+	r.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal recorded replies: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write recorded replies: %w", err)
+	}
+	return nil
+}
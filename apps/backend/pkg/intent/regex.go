@@ -0,0 +1,162 @@
+package intent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	amountPatterns = compile(
+		`(?:rs\.?|rupees?|₹|inr)\s*(\d+(?:,\d{2,3})*(?:\.\d{1,2})?)`, // Rs. 1,000.00
+		`(\d+(?:,\d{2,3})*(?:\.\d{1,2})?)\s*(?:rs\.?|rupees?|₹|inr)`, // 1000 rs
+		`(\d+(?:,\d{3})*(?:\.\d{2})?)\s*(?:only|/-)?`,                // 1000 only
+	)
+
+	merchantPatterns = compile(
+		`(?:pay|send|transfer)\s+(?:to\s+)?([a-z][a-z0-9\s]{1,30})(?:\s+for|\s+rs|\s*₹|\s*$)`,
+		`(?:for|at|from)\s+([a-z][a-z0-9\s]{1,30})(?:\s+for|\s+rs|\s*₹|\s*$)`,
+		`(?:to|towards)\s+([a-z][a-z0-9\s]{1,30})(?:\s+for|\s+rs|\s*₹|\s*$)`,
+	)
+
+	purposePatterns = compile(
+		`for\s+([a-z\s]{3,30})(?:\s|$)`,
+		`(?:buying|purchasing)\s+([a-z\s]{3,30})(?:\s|$)`,
+		`(?:payment for|bill for)\s+([a-z\s]{3,30})(?:\s|$)`,
+	)
+
+	// Recipients written as a UPI ID, email, @username or phone number. These
+	// are removed from the prompt before looking for an amount so digits in
+	// them are not mistaken for one.
+	recipientPatterns = compile(
+		`\b[a-z0-9._-]{2,}@[a-z][a-z0-9.-]*\b`,
+		`(?:^|\s)@([a-z0-9_]{3,})`,
+		`(?:\+91[\s-]?)?\b[6-9]\d{9}\b`,
+	)
+
+	foreignCurrency = regexp.MustCompile(`\$|\busd\b|\bdollars?\b|€|\beur\b|\beuros?\b|£|\bgbp\b|\bpounds?\b`)
+	fillerWords     = regexp.MustCompile(`\b(the|and|for|of|in|on|at|to|from)\b`)
+)
+
+func compile(patterns ...string) []*regexp.Regexp {
+	compiled := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		compiled[i] = regexp.MustCompile(pattern)
+	}
+	return compiled
+}
+
+// RegexProvider extracts intents with pattern matching. It never fails and
+// needs no network access, which makes it the fallback for the LLM providers.
+type RegexProvider struct{}
+
+// NewRegexProvider creates a regex based provider
+func NewRegexProvider() *RegexProvider {
+	return &RegexProvider{}
+}
+
+// Name returns the provider name
+func (p *RegexProvider) Name() string {
+	return ProviderRegex
+}
+
+// Extract parses the prompt. Confidence starts low and grows with each field
+// that could be found.
+func (p *RegexProvider) Extract(ctx context.Context, prompt string) (*Intent, error) {
+	prompt = strings.ToLower(prompt)
+
+	intent := &Intent{
+		Currency:   DefaultCurrency,
+		Confidence: 0.6,
+		Provider:   ProviderRegex,
+	}
+
+	// Recipient identifiers
+	remaining := prompt
+	for _, pattern := range recipientPatterns {
+		match := pattern.FindStringSubmatch(remaining)
+		if match == nil {
+			continue
+		}
+		if intent.Recipient == "" {
+			intent.Recipient = strings.TrimSpace(match[len(match)-1])
+		}
+		remaining = pattern.ReplaceAllString(remaining, " ")
+	}
+
+	if foreignCurrency.MatchString(remaining) {
+		switch match := foreignCurrency.FindString(remaining); {
+		case match == "$" || strings.HasPrefix(match, "usd") || strings.HasPrefix(match, "dollar"):
+			intent.Currency = "USD"
+		case match == "€" || strings.HasPrefix(match, "eur"):
+			intent.Currency = "EUR"
+		default:
+			intent.Currency = "GBP"
+		}
+	}
+
+	// Amount
+	for _, pattern := range amountPatterns {
+		if matches := pattern.FindStringSubmatch(remaining); len(matches) > 1 {
+			amountStr := strings.ReplaceAll(matches[1], ",", "")
+			if amount, err := strconv.ParseFloat(amountStr, 64); err == nil && amount > 0 {
+				intent.Amount = amount
+				intent.Confidence += 0.3
+				break
+			}
+		}
+	}
+
+	// Merchant
+	for _, pattern := range merchantPatterns {
+		if matches := pattern.FindStringSubmatch(remaining); len(matches) > 1 {
+			merchant := strings.TrimSpace(fillerWords.ReplaceAllString(matches[1], ""))
+			if len(merchant) > 2 {
+				intent.Merchant = strings.Title(merchant)
+				intent.Confidence += 0.2
+				break
+			}
+		}
+	}
+
+	// Purpose
+	for _, pattern := range purposePatterns {
+		if matches := pattern.FindStringSubmatch(remaining); len(matches) > 1 {
+			purpose := strings.TrimSpace(matches[1])
+			if len(purpose) > 2 {
+				intent.Purpose = purpose
+				intent.Confidence += 0.1
+				break
+			}
+		}
+	}
+
+	if intent.Amount == 0 && intent.Payee() == "" {
+		intent.Confidence = 0.3 // Low confidence for unclear requests
+	}
+
+	reasoningParts := []string{}
+	if intent.Amount > 0 {
+		reasoningParts = append(reasoningParts, fmt.Sprintf("extracted amount: Rs. %.2f", intent.Amount))
+	}
+	if intent.Merchant != "" {
+		reasoningParts = append(reasoningParts, fmt.Sprintf("identified merchant: %s", intent.Merchant))
+	}
+	if intent.Recipient != "" {
+		reasoningParts = append(reasoningParts, fmt.Sprintf("identified recipient: %s", intent.Recipient))
+	}
+	if intent.Purpose != "" {
+		reasoningParts = append(reasoningParts, fmt.Sprintf("payment purpose: %s", intent.Purpose))
+	}
+
+	if len(reasoningParts) > 0 {
+		intent.Reasoning = "Successfully " + strings.Join(reasoningParts, ", ")
+	} else {
+		intent.Reasoning = "Could not extract clear payment details from prompt"
+	}
+
+	intent.normalize()
+	return intent, nil
+}
@@ -0,0 +1,72 @@
+// Package period works out calendar periods (days, Monday-start weeks and
+// months) in a given time zone, for limits and reports that reset at local
+// midnight rather than on a rolling window.
+package period
+
+import (
+	"time"
+	_ "time/tzdata" // Time zones resolve even where the OS has no zoneinfo
+)
+
+// Calendar periods
+const (
+	Day   = "day"
+	Week  = "week"
+	Month = "month"
+)
+
+// DefaultTimezone is used when a user has not set one
+const DefaultTimezone = "Asia/Kolkata"
+
+// IST is India Standard Time, the fallback when a time zone cannot be loaded
+var IST = time.FixedZone("IST", 5*60*60+30*60)
+
+// Location returns the named time zone, or IST if the name is empty or unknown
+func Location(name string) *time.Location {
+	if name == "" {
+		name = DefaultTimezone
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return IST
+	}
+	return location
+}
+
+// Valid reports whether name is a time zone Location can load
+func Valid(name string) bool {
+	_, err := time.LoadLocation(name)
+	return err == nil && name != ""
+}
+
+// StartOfDay returns midnight at the start of t's day, in t's location
+func StartOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// StartOfWeek returns midnight on the Monday of t's week
+func StartOfWeek(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return StartOfDay(t).AddDate(0, 0, -offset)
+}
+
+// StartOfMonth returns midnight on the first of t's month
+func StartOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// Bounds returns the start and end of the period of the given kind that holds
+// t. The end is the start of the next period. Unknown kinds are read as Day.
+func Bounds(kind string, t time.Time) (time.Time, time.Time) {
+	switch kind {
+	case Week:
+		start := StartOfWeek(t)
+		return start, start.AddDate(0, 0, 7)
+	case Month:
+		start := StartOfMonth(t)
+		return start, start.AddDate(0, 1, 0)
+	default:
+		start := StartOfDay(t)
+		return start, start.AddDate(0, 0, 1)
+	}
+}
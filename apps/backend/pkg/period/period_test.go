@@ -0,0 +1,91 @@
+package period_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zeusnotfound04/Tranza/pkg/period"
+)
+
+var kolkata = period.Location("Asia/Kolkata")
+
+func date(year int, month time.Month, day, hour, minute int, location *time.Location) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, location)
+}
+
+func TestBounds(t *testing.T) {
+	tests := []struct {
+		name  string
+		kind  string
+		at    time.Time
+		start time.Time
+		end   time.Time
+	}{
+		{"day", period.Day, date(2026, 5, 14, 14, 30, kolkata), date(2026, 5, 14, 0, 0, kolkata), date(2026, 5, 15, 0, 0, kolkata)},
+		{"day at midnight", period.Day, date(2026, 5, 14, 0, 0, kolkata), date(2026, 5, 14, 0, 0, kolkata), date(2026, 5, 15, 0, 0, kolkata)},
+		{"unknown kind", "fortnight", date(2026, 5, 14, 14, 30, kolkata), date(2026, 5, 14, 0, 0, kolkata), date(2026, 5, 15, 0, 0, kolkata)},
+		// Weeks start on Monday
+		{"week from Thursday", period.Week, date(2026, 5, 14, 14, 30, kolkata), date(2026, 5, 11, 0, 0, kolkata), date(2026, 5, 18, 0, 0, kolkata)},
+		{"week from Monday", period.Week, date(2026, 5, 11, 0, 0, kolkata), date(2026, 5, 11, 0, 0, kolkata), date(2026, 5, 18, 0, 0, kolkata)},
+		{"week from Sunday", period.Week, date(2026, 5, 17, 23, 59, kolkata), date(2026, 5, 11, 0, 0, kolkata), date(2026, 5, 18, 0, 0, kolkata)},
+		{"week across months", period.Week, date(2026, 7, 2, 9, 0, kolkata), date(2026, 6, 29, 0, 0, kolkata), date(2026, 7, 6, 0, 0, kolkata)},
+		// Months roll over into the next, and the next year
+		{"month", period.Month, date(2026, 5, 14, 14, 30, kolkata), date(2026, 5, 1, 0, 0, kolkata), date(2026, 6, 1, 0, 0, kolkata)},
+		{"month on the 31st", period.Month, date(2026, 1, 31, 23, 59, kolkata), date(2026, 1, 1, 0, 0, kolkata), date(2026, 2, 1, 0, 0, kolkata)},
+		{"February", period.Month, date(2028, 2, 29, 12, 0, kolkata), date(2028, 2, 1, 0, 0, kolkata), date(2028, 3, 1, 0, 0, kolkata)},
+		{"December", period.Month, date(2026, 12, 31, 23, 59, kolkata), date(2026, 12, 1, 0, 0, kolkata), date(2027, 1, 1, 0, 0, kolkata)},
+	}
+	for _, tt := range tests {
+		start, end := period.Bounds(tt.kind, tt.at)
+		if !start.Equal(tt.start) || !end.Equal(tt.end) {
+			t.Errorf("Bounds %s = %s to %s, want %s to %s", tt.name, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+func TestBoundsFollowTheLocalMidnight(t *testing.T) {
+	// 7pm UTC on the 13th is 00:30 on the 14th in India
+	at := date(2026, 5, 13, 19, 0, time.UTC)
+
+	start, end := period.Bounds(period.Day, at.In(kolkata))
+	if want := date(2026, 5, 13, 18, 30, time.UTC); !start.Equal(want) || !end.Equal(want.AddDate(0, 0, 1)) {
+		t.Errorf("IST day = %s to %s, want from %s", start, end, want)
+	}
+
+	start, _ = period.Bounds(period.Day, at)
+	if want := date(2026, 5, 13, 0, 0, time.UTC); !start.Equal(want) {
+		t.Errorf("UTC day starts %s, want %s", start, want)
+	}
+
+	// The last day of a month in UTC is already the next month in India
+	start, _ = period.Bounds(period.Month, date(2026, 5, 31, 19, 0, time.UTC).In(kolkata))
+	if want := date(2026, 6, 1, 0, 0, kolkata); !start.Equal(want) {
+		t.Errorf("IST month starts %s, want %s", start, want)
+	}
+}
+
+func TestLocation(t *testing.T) {
+	at := date(2026, 5, 14, 12, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"Asia/Kolkata":     5*time.Hour + 30*time.Minute,
+		"Europe/London":    time.Hour, // Summer time
+		"America/New_York": -4 * time.Hour,
+		"":                 5*time.Hour + 30*time.Minute, // The default
+		"Mars/Olympus":     5*time.Hour + 30*time.Minute, // Unknown, so IST
+	}
+	for name, want := range tests {
+		_, offset := at.In(period.Location(name)).Zone()
+		if got := time.Duration(offset) * time.Second; got != want {
+			t.Errorf("Location(%q) is %s from UTC, want %s", name, got, want)
+		}
+	}
+
+	if period.Location("Mars/Olympus") != period.IST {
+		t.Error("Location of an unknown zone is not IST")
+	}
+	for name, valid := range map[string]bool{"Asia/Kolkata": true, "UTC": true, "Mars/Olympus": false, "": false} {
+		if period.Valid(name) != valid {
+			t.Errorf("Valid(%q) = %t, want %t", name, !valid, valid)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package razorpay
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// MaxListCount is the largest page Razorpay returns from list endpoints
+const MaxListCount = 100
+
+// ListOptions filters and pages a list request by creation time
+type ListOptions struct {
+	From  time.Time
+	To    time.Time
+	Count int
+	Skip  int
+}
+
+func (o ListOptions) values() url.Values {
+	values := url.Values{}
+	if !o.From.IsZero() {
+		values.Set("from", strconv.FormatInt(o.From.Unix(), 10))
+	}
+	if !o.To.IsZero() {
+		values.Set("to", strconv.FormatInt(o.To.Unix(), 10))
+	}
+	if o.Count > 0 {
+		values.Set("count", strconv.Itoa(o.Count))
+	}
+	if o.Skip > 0 {
+		values.Set("skip", strconv.Itoa(o.Skip))
+	}
+	return values
+}
+
+// PaymentCollection is a page of payments
+type PaymentCollection struct {
+	Entity string    `json:"entity"`
+	Count  int       `json:"count"`
+	Items  []Payment `json:"items"`
+}
+
+// RefundCollection is a page of refunds
+type RefundCollection struct {
+	Entity string   `json:"entity"`
+	Count  int      `json:"count"`
+	Items  []Refund `json:"items"`
+}
+
+// PayoutCollection is a page of payouts
+type PayoutCollection struct {
+	Entity string   `json:"entity"`
+	Count  int      `json:"count"`
+	Items  []Payout `json:"items"`
+}
+
+// ListPayments fetches a page of payments
+func (c *Client) ListPayments(opts ListOptions) (*PaymentCollection, error) {
+	url := fmt.Sprintf("%s/payments?%s", c.BaseURL, opts.values().Encode())
+
+	var collection PaymentCollection
+	if err := c.makeRequest("GET", url, nil, &collection); err != nil {
+		return nil, fmt.Errorf("failed to list payments: %w", err)
+	}
+
+	return &collection, nil
+}
+
+// ListRefunds fetches a page of refunds
+func (c *Client) ListRefunds(opts ListOptions) (*RefundCollection, error) {
+	url := fmt.Sprintf("%s/refunds?%s", c.BaseURL, opts.values().Encode())
+
+	var collection RefundCollection
+	if err := c.makeRequest("GET", url, nil, &collection); err != nil {
+		return nil, fmt.Errorf("failed to list refunds: %w", err)
+	}
+
+	return &collection, nil
+}
+
+// ListPayouts fetches a page of payouts from the RazorpayX account
+func (c *Client) ListPayouts(opts ListOptions) (*PayoutCollection, error) {
+	values := opts.values()
+	if c.AccountNumber != "" {
+		values.Set("account_number", c.AccountNumber)
+	}
+	url := fmt.Sprintf("%s/payouts?%s", c.BaseURL, values.Encode())
+
+	var collection PayoutCollection
+	if err := c.makeRequest("GET", url, nil, &collection); err != nil {
+		return nil, fmt.Errorf("failed to list payouts: %w", err)
+	}
+
+	return &collection, nil
+}
@@ -0,0 +1,1057 @@
+// Package fake provides an in-memory Razorpay API for tests and local
+// development. Point razorpay.Client.BaseURL at the server's URL and the real
+// client code paths run against it: orders, payments, captures, refunds,
+// contacts, fund accounts, payouts and UPI ID validation. Payout state
+// changes follow a script and every change is delivered as a signed webhook.
+package fake
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zeusnotfound04/Tranza/pkg/razorpay"
+)
+
+// Config controls the fake server
+type Config struct {
+	KeyID     string // When set, requests must authenticate with these credentials
+	KeySecret string
+
+	WebhookURL    string // Where webhooks are delivered; empty disables delivery
+	WebhookSecret string // Secret used to sign webhooks
+
+	// AdvanceOnFetch moves a payout one step along its script each time it is
+	// fetched, so status polling drives payouts to completion
+	AdvanceOnFetch bool
+
+	// PendingRefunds creates refunds as pending instead of processed; settle
+	// them with SetRefundStatus
+	PendingRefunds bool
+}
+
+// PayoutStep is one state in a payout's scripted lifecycle
+type PayoutStep struct {
+	Status        string `json:"status"`
+	FailureReason string `json:"failure_reason,omitempty"`
+}
+
+// Webhook is a webhook the server sent, kept for inspection
+type Webhook struct {
+	Event      string
+	Body       []byte
+	Signature  string
+	StatusCode int
+	Err        error
+}
+
+// DefaultVPAName is the name valid UPI IDs not set with SetVPA are registered to
+const DefaultVPAName = "Razorpay Test Customer"
+
+// DefaultPayoutScript completes a payout after one status check
+var DefaultPayoutScript = []PayoutStep{
+	{Status: razorpay.PayoutStatusProcessing},
+	{Status: razorpay.PayoutStatusProcessed},
+}
+
+type payoutState struct {
+	payout *razorpay.Payout
+	steps  []PayoutStep
+	step   int
+}
+
+// Server is an in-memory Razorpay API
+type Server struct {
+	cfg Config
+	mux *http.ServeMux
+
+	mu           sync.Mutex
+	seq          int
+	orders       map[string]*razorpay.Order
+	payments     map[string]*razorpay.Payment
+	refunds      map[string]*razorpay.Refund
+	refundKeys   map[string]string // X-Refund-Idempotency key to refund ID
+	payoutKeys   map[string]string // X-Payout-Idempotency key to payout ID
+	contacts     map[string]*razorpay.ContactResponse
+	fundAccounts map[string]*razorpay.FundAccountResponse
+	payouts      map[string]*payoutState
+	scripts      map[string][]PayoutStep // Keyed by VPA; "" is the default
+	vpas         map[string]string       // UPI ID to the name it is registered to; "" marks it unknown
+	failNext     int
+	webhooks     []Webhook
+
+	httpServer    *httptest.Server
+	webhookClient *http.Client
+}
+
+// NewServer creates a fake server. Use Start to listen on a local port, or
+// mount the server as an http.Handler.
+func NewServer(cfg Config) *Server {
+	s := &Server{
+		cfg:           cfg,
+		mux:           http.NewServeMux(),
+		orders:        make(map[string]*razorpay.Order),
+		payments:      make(map[string]*razorpay.Payment),
+		refunds:       make(map[string]*razorpay.Refund),
+		refundKeys:    make(map[string]string),
+		payoutKeys:    make(map[string]string),
+		contacts:      make(map[string]*razorpay.ContactResponse),
+		fundAccounts:  make(map[string]*razorpay.FundAccountResponse),
+		payouts:       make(map[string]*payoutState),
+		scripts:       map[string][]PayoutStep{"": DefaultPayoutScript},
+		vpas:          make(map[string]string),
+		webhookClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	s.routes()
+	return s
+}
+
+// Start listens on a random local port
+func (s *Server) Start() *Server {
+	s.httpServer = httptest.NewServer(s)
+	return s
+}
+
+// URL returns the base URL to use as razorpay.Client.BaseURL
+func (s *Server) URL() string {
+	if s.httpServer == nil {
+		return ""
+	}
+	return s.httpServer.URL
+}
+
+// Close stops the server started with Start
+func (s *Server) Close() {
+	if s.httpServer != nil {
+		s.httpServer.Close()
+	}
+}
+
+// Client returns a razorpay.Client pointed at the server
+func (s *Server) Client() *razorpay.Client {
+	client := razorpay.NewClientWithAccount(s.cfg.KeyID, s.cfg.KeySecret, "")
+	client.BaseURL = s.URL()
+	return client
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, "/_sim/") {
+		if s.cfg.KeyID != "" {
+			keyID, keySecret, ok := r.BasicAuth()
+			if !ok || keyID != s.cfg.KeyID || keySecret != s.cfg.KeySecret {
+				writeError(w, http.StatusUnauthorized, "BAD_REQUEST_ERROR", "Authentication failed")
+				return
+			}
+		}
+
+		s.mu.Lock()
+		fail := s.failNext > 0
+		if fail {
+			s.failNext--
+		}
+		s.mu.Unlock()
+		if fail {
+			writeError(w, http.StatusInternalServerError, "SERVER_ERROR", "Simulated server error")
+			return
+		}
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) routes() {
+	s.mux.HandleFunc("POST /orders", s.handleCreateOrder)
+	s.mux.HandleFunc("GET /orders/{id}", s.handleGetOrder)
+	s.mux.HandleFunc("GET /payments", s.handleListPayments)
+	s.mux.HandleFunc("GET /refunds", s.handleListRefunds)
+	s.mux.HandleFunc("GET /payouts", s.handleListPayouts)
+	s.mux.HandleFunc("GET /payments/{id}", s.handleGetPayment)
+	s.mux.HandleFunc("POST /payments/{id}/capture", s.handleCapturePayment)
+	s.mux.HandleFunc("POST /payments/{id}/refund", s.handleRefundPayment)
+	s.mux.HandleFunc("GET /refunds/{id}", s.handleGetRefund)
+	s.mux.HandleFunc("POST /contacts", s.handleCreateContact)
+	s.mux.HandleFunc("POST /fund_accounts", s.handleCreateFundAccount)
+	s.mux.HandleFunc("POST /payouts", s.handleCreatePayout)
+	s.mux.HandleFunc("GET /payouts/{id}", s.handleGetPayout)
+	s.mux.HandleFunc("POST /payouts/{id}/cancel", s.handleCancelPayout)
+	s.mux.HandleFunc("POST /payments/validate/vpa", s.handleValidateVPA)
+
+	// Simulation controls
+	s.mux.HandleFunc("POST /_sim/orders/{id}/pay", s.handleSimPayOrder)
+	s.mux.HandleFunc("POST /_sim/payouts/{id}/advance", s.handleSimAdvancePayout)
+	s.mux.HandleFunc("POST /_sim/payouts/{id}/status", s.handleSimSetPayoutStatus)
+	s.mux.HandleFunc("POST /_sim/payouts/script", s.handleSimScript)
+	s.mux.HandleFunc("POST /_sim/refunds/{id}/status", s.handleSimSetRefundStatus)
+	s.mux.HandleFunc("POST /_sim/vpas", s.handleSimVPA)
+	s.mux.HandleFunc("POST /_sim/fail", s.handleSimFail)
+}
+
+// SetPayoutScript sets the lifecycle of new payouts to vpa. An empty vpa sets
+// the default script. The first step is the status the payout is created in.
+func (s *Server) SetPayoutScript(vpa string, steps ...PayoutStep) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts[vpa] = steps
+}
+
+// SetVPA registers a UPI ID to name for VPA validation. An empty name makes
+// the UPI ID unknown. UPI IDs not set here are valid and registered to
+// DefaultVPAName, except failure@ ones, which are unknown.
+func (s *Server) SetVPA(vpa, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.vpas[strings.ToLower(vpa)] = name
+}
+
+// FailNext makes the next n API requests fail with a 500
+func (s *Server) FailNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failNext = n
+}
+
+// Webhooks returns the webhooks sent so far
+func (s *Server) Webhooks() []Webhook {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Webhook(nil), s.webhooks...)
+}
+
+// Payout returns a copy of a payout
+func (s *Server) Payout(id string) (*razorpay.Payout, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.payouts[id]
+	if !ok {
+		return nil, false
+	}
+	payout := *state.payout
+	return &payout, true
+}
+
+// PayOrder simulates a customer paying an order. The payment is captured
+// immediately and payment.captured and order.paid webhooks are sent.
+func (s *Server) PayOrder(orderID, method string) (*razorpay.Payment, error) {
+	s.mu.Lock()
+	order, ok := s.orders[orderID]
+	if !ok {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("order %s not found", orderID)
+	}
+	if order.Status == "paid" {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("order %s is already paid", orderID)
+	}
+	if method == "" {
+		method = "upi"
+	}
+
+	payment := &razorpay.Payment{
+		ID:        s.newID("pay"),
+		Entity:    "payment",
+		Amount:    order.AmountDue,
+		Currency:  order.Currency,
+		Status:    "captured",
+		OrderID:   order.ID,
+		Method:    method,
+		Notes:     razorpay.FlexibleNotes{},
+		CreatedAt: time.Now().Unix(),
+	}
+	s.payments[payment.ID] = payment
+
+	order.AmountPaid += payment.Amount
+	order.AmountDue = 0
+	order.Attempts++
+	order.Status = "paid"
+
+	paymentCopy, orderCopy := *payment, *order
+	s.mu.Unlock()
+
+	s.emit("payment.captured", map[string]interface{}{"payment": entity(paymentCopy)})
+	s.emit("order.paid", map[string]interface{}{"payment": entity(paymentCopy), "order": entity(orderCopy)})
+
+	return &paymentCopy, nil
+}
+
+// AdvancePayout moves a payout to the next step of its script
+func (s *Server) AdvancePayout(id string) (*razorpay.Payout, error) {
+	s.mu.Lock()
+	state, ok := s.payouts[id]
+	if !ok {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("payout %s not found", id)
+	}
+	if state.step+1 >= len(state.steps) {
+		payout := *state.payout
+		s.mu.Unlock()
+		return &payout, nil
+	}
+	state.step++
+	s.mu.Unlock()
+
+	return s.applyPayoutStep(id, state.steps[state.step])
+}
+
+// SetPayoutStatus moves a payout to any status, outside its script
+func (s *Server) SetPayoutStatus(id, status, failureReason string) (*razorpay.Payout, error) {
+	s.mu.Lock()
+	_, ok := s.payouts[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("payout %s not found", id)
+	}
+
+	return s.applyPayoutStep(id, PayoutStep{Status: status, FailureReason: failureReason})
+}
+
+// SetRefundStatus settles a refund as processed or failed
+func (s *Server) SetRefundStatus(id, status string) (*razorpay.Refund, error) {
+	s.mu.Lock()
+	refund, ok := s.refunds[id]
+	if !ok {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("refund %s not found", id)
+	}
+	if status == razorpay.RefundStatusFailed && refund.Status != razorpay.RefundStatusFailed {
+		if payment, ok := s.payments[refund.PaymentID]; ok {
+			payment.AmountRefunded -= refund.Amount
+			payment.Status = "captured"
+			payment.RefundStatus = "partial"
+			if payment.AmountRefunded == 0 {
+				payment.RefundStatus = ""
+			}
+		}
+	}
+	refund.Status = status
+	refundCopy := *refund
+	paymentCopy := *s.payments[refund.PaymentID]
+	s.mu.Unlock()
+
+	s.emit("refund."+status, map[string]interface{}{"refund": entity(refundCopy), "payment": entity(paymentCopy)})
+	return &refundCopy, nil
+}
+
+func (s *Server) applyPayoutStep(id string, step PayoutStep) (*razorpay.Payout, error) {
+	s.mu.Lock()
+	state := s.payouts[id]
+	state.payout.Status = step.Status
+	state.payout.FailureReason = step.FailureReason
+	if step.Status == razorpay.PayoutStatusProcessed {
+		state.payout.ProcessedAt = time.Now().Unix()
+		state.payout.UTR = fmt.Sprintf("UTR%012d", s.nextSeq())
+	}
+	payout := *state.payout
+	s.mu.Unlock()
+
+	s.emit(payoutEvent(payout.Status), map[string]interface{}{"payout": entity(payout)})
+	return &payout, nil
+}
+
+// Order handlers
+
+func (s *Server) handleCreateOrder(w http.ResponseWriter, r *http.Request) {
+	var req razorpay.CreateOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "Invalid request body")
+		return
+	}
+	if req.Amount < 100 {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "Order amount less than minimum amount allowed")
+		return
+	}
+	if req.Currency == "" {
+		req.Currency = "INR"
+	}
+
+	s.mu.Lock()
+	order := &razorpay.Order{
+		ID:        s.newID("order"),
+		Entity:    "order",
+		Amount:    req.Amount,
+		AmountDue: req.Amount,
+		Currency:  req.Currency,
+		Receipt:   req.Receipt,
+		Status:    "created",
+		Notes:     razorpay.FlexibleNotes(req.Notes),
+		CreatedAt: time.Now().Unix(),
+	}
+	s.orders[order.ID] = order
+	response := *order
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleGetOrder(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	order, ok := s.orders[r.PathValue("id")]
+	var response razorpay.Order
+	if ok {
+		response = *order
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "The id provided does not exist")
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// Payment handlers
+
+func (s *Server) handleGetPayment(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	payment, ok := s.payments[r.PathValue("id")]
+	var response razorpay.Payment
+	if ok {
+		response = *payment
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "The id provided does not exist")
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleCapturePayment(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Amount int64 `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "Invalid request body")
+		return
+	}
+
+	s.mu.Lock()
+	payment, ok := s.payments[r.PathValue("id")]
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "The id provided does not exist")
+		return
+	}
+	if payment.Status != "authorized" {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "This payment has already been captured")
+		return
+	}
+	if req.Amount != payment.Amount {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "Capture amount must be equal to the amount authorized")
+		return
+	}
+	payment.Status = "captured"
+	response := *payment
+	s.mu.Unlock()
+
+	s.emit("payment.captured", map[string]interface{}{"payment": entity(response)})
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleRefundPayment(w http.ResponseWriter, r *http.Request) {
+	var req razorpay.CreateRefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "Invalid request body")
+		return
+	}
+	idempotencyKey := r.Header.Get("X-Refund-Idempotency")
+
+	s.mu.Lock()
+	if id, ok := s.refundKeys[idempotencyKey]; ok && idempotencyKey != "" {
+		response := *s.refunds[id]
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, response)
+		return
+	}
+
+	payment, ok := s.payments[r.PathValue("id")]
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "The id provided does not exist")
+		return
+	}
+	if payment.Status != "captured" {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "The payment has been fully refunded already")
+		return
+	}
+
+	refunded := int64(0)
+	for _, refund := range s.refunds {
+		if refund.PaymentID == payment.ID && refund.Status != razorpay.RefundStatusFailed {
+			refunded += refund.Amount
+		}
+	}
+	if req.Amount == 0 {
+		req.Amount = payment.Amount - refunded
+	}
+	if req.Amount <= 0 || refunded+req.Amount > payment.Amount {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "The refund amount provided is greater than amount captured")
+		return
+	}
+
+	notes := razorpay.FlexibleNotes{}
+	for key, value := range req.Notes {
+		notes[key] = value
+	}
+
+	refund := &razorpay.Refund{
+		ID:             s.newID("rfnd"),
+		Entity:         "refund",
+		Amount:         req.Amount,
+		Currency:       payment.Currency,
+		PaymentID:      payment.ID,
+		Receipt:        req.Receipt,
+		Status:         razorpay.RefundStatusProcessed,
+		SpeedRequested: "normal",
+		SpeedProcessed: "normal",
+		Notes:          notes,
+		CreatedAt:      time.Now().Unix(),
+	}
+	if s.cfg.PendingRefunds {
+		refund.Status = razorpay.RefundStatusPending
+	}
+	s.refunds[refund.ID] = refund
+	if idempotencyKey != "" {
+		s.refundKeys[idempotencyKey] = refund.ID
+	}
+	payment.AmountRefunded = refunded + req.Amount
+	payment.RefundStatus = "partial"
+	if payment.AmountRefunded == payment.Amount {
+		payment.Status = "refunded"
+		payment.RefundStatus = "full"
+	}
+	refundCopy, paymentCopy := *refund, *payment
+	s.mu.Unlock()
+
+	s.emit("refund.created", map[string]interface{}{"refund": entity(refundCopy), "payment": entity(paymentCopy)})
+	if refundCopy.Status == razorpay.RefundStatusProcessed {
+		s.emit("refund.processed", map[string]interface{}{"refund": entity(refundCopy), "payment": entity(paymentCopy)})
+	}
+	writeJSON(w, http.StatusOK, refundCopy)
+}
+
+func (s *Server) handleGetRefund(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	refund, ok := s.refunds[r.PathValue("id")]
+	var response razorpay.Refund
+	if ok {
+		response = *refund
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "The id provided does not exist")
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// Payout handlers
+
+func (s *Server) handleCreateContact(w http.ResponseWriter, r *http.Request) {
+	var req razorpay.PayoutContact
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "Invalid request body")
+		return
+	}
+	if req.Name == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "The name field is required.")
+		return
+	}
+
+	s.mu.Lock()
+	contact := &razorpay.ContactResponse{
+		ID:          s.newID("cont"),
+		Entity:      "contact",
+		Name:        req.Name,
+		Contact:     req.Contact,
+		Email:       req.Email,
+		Type:        req.Type,
+		ReferenceID: req.ReferenceID,
+		Active:      true,
+		Notes:       req.Notes,
+		CreatedAt:   time.Now().Unix(),
+	}
+	s.contacts[contact.ID] = contact
+	response := *contact
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleCreateFundAccount(w http.ResponseWriter, r *http.Request) {
+	var req razorpay.FundAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "Invalid request body")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.contacts[req.ContactID]; !ok {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "The contact id provided does not exist")
+		return
+	}
+	switch req.AccountType {
+	case razorpay.AccountTypeVPA:
+		if req.VPA == nil || !strings.Contains(req.VPA.Address, "@") {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "Invalid VPA. Please enter a valid Virtual Payment Address")
+			return
+		}
+	case razorpay.AccountTypeBank:
+		if req.BankAccount == nil || req.BankAccount.IFSC == "" || req.BankAccount.AccountNumber == "" {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "The bank account details are invalid")
+			return
+		}
+	default:
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "The account type field is invalid")
+		return
+	}
+
+	fundAccount := &razorpay.FundAccountResponse{
+		ID:          s.newID("fa"),
+		Entity:      "fund_account",
+		ContactID:   req.ContactID,
+		AccountType: req.AccountType,
+		VPA:         req.VPA,
+		BankAccount: req.BankAccount,
+		Active:      true,
+		CreatedAt:   time.Now().Unix(),
+	}
+	s.fundAccounts[fundAccount.ID] = fundAccount
+
+	writeJSON(w, http.StatusOK, *fundAccount)
+}
+
+func (s *Server) handleCreatePayout(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		FundAccountID string                 `json:"fund_account_id"`
+		Amount        int64                  `json:"amount"`
+		Currency      string                 `json:"currency"`
+		Mode          string                 `json:"mode"`
+		Purpose       string                 `json:"purpose"`
+		ReferenceID   string                 `json:"reference_id"`
+		Narration     string                 `json:"narration"`
+		Notes         map[string]interface{} `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "Invalid request body")
+		return
+	}
+	if req.Amount < 100 {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "Minimum transaction amount should be 100 paise")
+		return
+	}
+
+	idempotencyKey := r.Header.Get("X-Payout-Idempotency")
+
+	s.mu.Lock()
+	if id, ok := s.payoutKeys[idempotencyKey]; ok && idempotencyKey != "" {
+		response := *s.payouts[id].payout
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, response)
+		return
+	}
+
+	fundAccount, ok := s.fundAccounts[req.FundAccountID]
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "The fund account id provided does not exist")
+		return
+	}
+
+	steps := s.scripts[""]
+	if fundAccount.VPA != nil {
+		if script, ok := s.scripts[fundAccount.VPA.Address]; ok {
+			steps = script
+		}
+	}
+	if len(steps) == 0 {
+		steps = DefaultPayoutScript
+	}
+
+	payout := &razorpay.Payout{
+		ID:            s.newID("pout"),
+		Entity:        "payout",
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		Status:        steps[0].Status,
+		FailureReason: steps[0].FailureReason,
+		Purpose:       req.Purpose,
+		Mode:          req.Mode,
+		Reference:     req.ReferenceID,
+		Narration:     req.Narration,
+		Notes:         razorpay.FlexibleNotes(req.Notes),
+		CreatedAt:     time.Now().Unix(),
+		FundAccountID: fundAccount.ID,
+	}
+	s.payouts[payout.ID] = &payoutState{payout: payout, steps: steps}
+	if idempotencyKey != "" {
+		s.payoutKeys[idempotencyKey] = payout.ID
+	}
+	response := *payout
+	s.mu.Unlock()
+
+	s.emit(payoutEvent(response.Status), map[string]interface{}{"payout": entity(response)})
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleGetPayout(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	s.mu.Lock()
+	state, ok := s.payouts[id]
+	var response razorpay.Payout
+	if ok {
+		response = *state.payout
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "The id provided does not exist")
+		return
+	}
+
+	if s.cfg.AdvanceOnFetch {
+		payout, err := s.AdvancePayout(id)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "SERVER_ERROR", err.Error())
+			return
+		}
+		response = *payout
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleCancelPayout cancels a queued payout, ending its script. Like
+// Razorpay, payouts in any other status are not cancelled.
+func (s *Server) handleCancelPayout(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	s.mu.Lock()
+	state, ok := s.payouts[id]
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "The id provided does not exist")
+		return
+	}
+	if state.payout.Status != razorpay.PayoutStatusQueued {
+		s.mu.Unlock()
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "Only queued payouts can be cancelled")
+		return
+	}
+	state.step = len(state.steps) - 1
+	s.mu.Unlock()
+
+	payout, err := s.applyPayoutStep(id, PayoutStep{Status: razorpay.PayoutStatusCancelled, FailureReason: "Payout cancelled"})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "SERVER_ERROR", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, payout)
+}
+
+// List handlers
+
+func (s *Server) handleValidateVPA(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		VPA string `json:"vpa"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.VPA == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "The vpa field is required.")
+		return
+	}
+	vpa := strings.ToLower(req.VPA)
+
+	s.mu.Lock()
+	name, ok := s.vpas[vpa]
+	s.mu.Unlock()
+	if !ok && strings.Contains(vpa, "@") && !strings.HasPrefix(vpa, "failure@") {
+		name = DefaultVPAName
+	}
+
+	writeJSON(w, http.StatusOK, razorpay.VPAValidation{VPA: req.VPA, Success: name != "", CustomerName: name})
+}
+
+func (s *Server) handleListPayments(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	items := make([]razorpay.Payment, 0, len(s.payments))
+	for _, payment := range s.payments {
+		items = append(items, *payment)
+	}
+	s.mu.Unlock()
+
+	items = page(r, items, func(p razorpay.Payment) (int64, string) { return p.CreatedAt, p.ID })
+	writeJSON(w, http.StatusOK, razorpay.PaymentCollection{Entity: "collection", Count: len(items), Items: items})
+}
+
+func (s *Server) handleListRefunds(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	items := make([]razorpay.Refund, 0, len(s.refunds))
+	for _, refund := range s.refunds {
+		items = append(items, *refund)
+	}
+	s.mu.Unlock()
+
+	items = page(r, items, func(rf razorpay.Refund) (int64, string) { return rf.CreatedAt, rf.ID })
+	writeJSON(w, http.StatusOK, razorpay.RefundCollection{Entity: "collection", Count: len(items), Items: items})
+}
+
+func (s *Server) handleListPayouts(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	items := make([]razorpay.Payout, 0, len(s.payouts))
+	for _, state := range s.payouts {
+		items = append(items, *state.payout)
+	}
+	s.mu.Unlock()
+
+	items = page(r, items, func(p razorpay.Payout) (int64, string) { return p.CreatedAt, p.ID })
+	writeJSON(w, http.StatusOK, razorpay.PayoutCollection{Entity: "collection", Count: len(items), Items: items})
+}
+
+// page applies Razorpay's from/to/count/skip parameters, newest first
+func page[T any](r *http.Request, items []T, key func(T) (int64, string)) []T {
+	query := r.URL.Query()
+	from, _ := strconv.ParseInt(query.Get("from"), 10, 64)
+	to, _ := strconv.ParseInt(query.Get("to"), 10, 64)
+	skip, _ := strconv.Atoi(query.Get("skip"))
+	count, err := strconv.Atoi(query.Get("count"))
+	if err != nil || count <= 0 {
+		count = 10
+	}
+	if count > razorpay.MaxListCount {
+		count = razorpay.MaxListCount
+	}
+
+	filtered := items[:0]
+	for _, item := range items {
+		createdAt, _ := key(item)
+		if (from == 0 || createdAt >= from) && (to == 0 || createdAt <= to) {
+			filtered = append(filtered, item)
+		}
+	}
+
+	sort.Slice(filtered, func(i, j int) bool {
+		ci, idi := key(filtered[i])
+		cj, idj := key(filtered[j])
+		if ci != cj {
+			return ci > cj
+		}
+		return idi > idj
+	})
+
+	if skip >= len(filtered) {
+		return []T{}
+	}
+	filtered = filtered[skip:]
+	if len(filtered) > count {
+		filtered = filtered[:count]
+	}
+	return filtered
+}
+
+// Simulation handlers
+
+func (s *Server) handleSimPayOrder(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Method string `json:"method"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	payment, err := s.PayOrder(r.PathValue("id"), req.Method)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, payment)
+}
+
+func (s *Server) handleSimAdvancePayout(w http.ResponseWriter, r *http.Request) {
+	payout, err := s.AdvancePayout(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, payout)
+}
+
+func (s *Server) handleSimSetPayoutStatus(w http.ResponseWriter, r *http.Request) {
+	var req PayoutStep
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Status == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "status is required")
+		return
+	}
+
+	payout, err := s.SetPayoutStatus(r.PathValue("id"), req.Status, req.FailureReason)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, payout)
+}
+
+func (s *Server) handleSimSetRefundStatus(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Status == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "status is required")
+		return
+	}
+
+	refund, err := s.SetRefundStatus(r.PathValue("id"), req.Status)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, refund)
+}
+
+func (s *Server) handleSimScript(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		VPA   string       `json:"vpa"`
+		Steps []PayoutStep `json:"steps"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Steps) == 0 {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "steps are required")
+		return
+	}
+
+	s.SetPayoutScript(req.VPA, req.Steps...)
+	writeJSON(w, http.StatusOK, req)
+}
+
+func (s *Server) handleSimVPA(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		VPA  string `json:"vpa"`
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.VPA == "" {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "vpa is required")
+		return
+	}
+
+	s.SetVPA(req.VPA, req.Name)
+	writeJSON(w, http.StatusOK, req)
+}
+
+func (s *Server) handleSimFail(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Count int `json:"count"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST_ERROR", "count is required")
+		return
+	}
+
+	s.FailNext(req.Count)
+	writeJSON(w, http.StatusOK, req)
+}
+
+// Webhooks
+
+// emit sends a signed webhook in Razorpay's format. It must be called without
+// holding the lock, since the receiver may call back into the server.
+func (s *Server) emit(event string, payload map[string]interface{}) {
+	if s.cfg.WebhookURL == "" {
+		return
+	}
+
+	contains := make([]string, 0, len(payload))
+	for name := range payload {
+		contains = append(contains, name)
+	}
+
+	body, _ := json.Marshal(razorpay.WebhookEvent{
+		Account:   "acc_fake",
+		Event:     event,
+		Contains:  contains,
+		Payload:   payload,
+		CreatedAt: time.Now().Unix(),
+	})
+	signature := sign(body, s.cfg.WebhookSecret)
+
+	delivery := Webhook{Event: event, Body: body, Signature: signature}
+
+	req, err := http.NewRequest(http.MethodPost, s.cfg.WebhookURL, bytes.NewReader(body))
+	if err == nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Razorpay-Signature", signature)
+		req.Header.Set("X-Razorpay-Event-Id", fmt.Sprintf("evt_fake_%d", time.Now().UnixNano()))
+
+		var resp *http.Response
+		resp, err = s.webhookClient.Do(req)
+		if err == nil {
+			delivery.StatusCode = resp.StatusCode
+			resp.Body.Close()
+		}
+	}
+	delivery.Err = err
+
+	s.mu.Lock()
+	s.webhooks = append(s.webhooks, delivery)
+	s.mu.Unlock()
+}
+
+// payoutEvent maps a payout status to the webhook Razorpay sends for it
+func payoutEvent(status string) string {
+	switch status {
+	case razorpay.PayoutStatusProcessing:
+		return "payout.initiated"
+	case razorpay.PayoutStatusCancelled:
+		return "payout.updated"
+	default:
+		return "payout." + status
+	}
+}
+
+// Helpers
+
+// newID returns an ID in Razorpay's prefix_xxxx style. Callers hold the lock.
+func (s *Server) newID(prefix string) string {
+	return fmt.Sprintf("%s_fake%010d", prefix, s.nextSeq())
+}
+
+func (s *Server) nextSeq() int {
+	s.seq++
+	return s.seq
+}
+
+func entity(v interface{}) map[string]interface{} {
+	return map[string]interface{}{"entity": v}
+}
+
+func sign(body []byte, secret string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, description string) {
+	var body razorpay.RazorpayError
+	body.RazorpayError.Code = code
+	body.RazorpayError.Description = description
+	writeJSON(w, status, body)
+}
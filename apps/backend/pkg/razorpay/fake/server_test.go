@@ -0,0 +1,370 @@
+package fake_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/zeusnotfound04/Tranza/pkg/razorpay"
+	"github.com/zeusnotfound04/Tranza/pkg/razorpay/fake"
+)
+
+const webhookSecret = "whsec_test"
+
+// delivery is a webhook as the receiver saw it
+type delivery struct {
+	body      []byte
+	signature string
+}
+
+// receiver collects the webhooks the fake server delivers
+type receiver struct {
+	mu         sync.Mutex
+	deliveries []delivery
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	r.deliveries = append(r.deliveries, delivery{body: body, signature: req.Header.Get("X-Razorpay-Signature")})
+	r.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+// events returns the webhooks received so far, checking each signature
+func (r *receiver) events(t *testing.T, client *razorpay.Client) []*razorpay.WebhookEvent {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := make([]*razorpay.WebhookEvent, 0, len(r.deliveries))
+	for _, d := range r.deliveries {
+		if !client.VerifyWebhookSignature(d.body, d.signature, webhookSecret) {
+			t.Fatalf("webhook signature does not verify: %s", d.body)
+		}
+		event, err := client.ParseWebhookEvent(d.body)
+		if err != nil {
+			t.Fatalf("ParseWebhookEvent: %v", err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// startServer runs a fake server that delivers webhooks to a local receiver
+func startServer(t *testing.T, cfg fake.Config) (*fake.Server, *razorpay.Client, *receiver) {
+	t.Helper()
+
+	recv := &receiver{}
+	hooks := httptest.NewServer(recv)
+	t.Cleanup(hooks.Close)
+
+	cfg.WebhookURL = hooks.URL
+	cfg.WebhookSecret = webhookSecret
+	server := fake.NewServer(cfg).Start()
+	t.Cleanup(server.Close)
+
+	return server, server.Client(), recv
+}
+
+func eventNames(events []*razorpay.WebhookEvent) []string {
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i] = event.Event
+	}
+	return names
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestUPIPayoutCompletesWhenPolled(t *testing.T) {
+	_, client, recv := startServer(t, fake.Config{AdvanceOnFetch: true})
+
+	payout, err := client.CreateUPIPayout("alice@upi", 25000, "INR", razorpay.PurposePayout, "Rent", "Alice", "", "TXN001")
+	if err != nil {
+		t.Fatalf("CreateUPIPayout: %v", err)
+	}
+	if payout.Status != razorpay.PayoutStatusProcessing {
+		t.Fatalf("new payout status = %q, want %q", payout.Status, razorpay.PayoutStatusProcessing)
+	}
+	if payout.Reference != "TXN001" || payout.Amount != 25000 {
+		t.Fatalf("payout = %+v, want reference TXN001 for 25000 paise", payout)
+	}
+
+	polled, err := client.GetPayout(payout.ID)
+	if err != nil {
+		t.Fatalf("GetPayout: %v", err)
+	}
+	if polled.Status != razorpay.PayoutStatusProcessed {
+		t.Fatalf("polled payout status = %q, want %q", polled.Status, razorpay.PayoutStatusProcessed)
+	}
+	if polled.UTR == "" {
+		t.Fatal("processed payout has no UTR")
+	}
+
+	// The script has ended, so further polls leave the payout where it is
+	if again, err := client.GetPayout(payout.ID); err != nil || again.Status != razorpay.PayoutStatusProcessed {
+		t.Fatalf("GetPayout after completion = %+v, %v", again, err)
+	}
+
+	want := []string{"payout.initiated", "payout.processed"}
+	if got := eventNames(recv.events(t, client)); !equalStrings(got, want) {
+		t.Fatalf("webhooks = %v, want %v", got, want)
+	}
+}
+
+func TestPayoutScriptPerVPA(t *testing.T) {
+	server, client, recv := startServer(t, fake.Config{})
+	server.SetPayoutScript("bob@upi",
+		fake.PayoutStep{Status: razorpay.PayoutStatusQueued},
+		fake.PayoutStep{Status: razorpay.PayoutStatusFailed, FailureReason: "Beneficiary bank down"},
+	)
+
+	payout, err := client.CreateUPIPayout("bob@upi", 10000, "INR", razorpay.PurposePayout, "", "Bob", "", "TXN002")
+	if err != nil {
+		t.Fatalf("CreateUPIPayout: %v", err)
+	}
+	if payout.Status != razorpay.PayoutStatusQueued {
+		t.Fatalf("new payout status = %q, want %q", payout.Status, razorpay.PayoutStatusQueued)
+	}
+
+	// Without AdvanceOnFetch a poll does not move the payout
+	if polled, err := client.GetPayout(payout.ID); err != nil || polled.Status != razorpay.PayoutStatusQueued {
+		t.Fatalf("GetPayout = %+v, %v; want it still queued", polled, err)
+	}
+
+	failed, err := server.AdvancePayout(payout.ID)
+	if err != nil {
+		t.Fatalf("AdvancePayout: %v", err)
+	}
+	if failed.Status != razorpay.PayoutStatusFailed || failed.FailureReason != "Beneficiary bank down" {
+		t.Fatalf("advanced payout = %+v, want failed with the scripted reason", failed)
+	}
+
+	// Other UPI IDs keep the default script
+	other, err := client.CreateUPIPayout("carol@upi", 10000, "INR", razorpay.PurposePayout, "", "Carol", "", "TXN003")
+	if err != nil {
+		t.Fatalf("CreateUPIPayout: %v", err)
+	}
+	if other.Status != razorpay.PayoutStatusProcessing {
+		t.Fatalf("default script payout status = %q, want %q", other.Status, razorpay.PayoutStatusProcessing)
+	}
+
+	events := recv.events(t, client)
+	want := []string{"payout.queued", "payout.failed", "payout.initiated"}
+	if got := eventNames(events); !equalStrings(got, want) {
+		t.Fatalf("webhooks = %v, want %v", got, want)
+	}
+	entity := events[1].Payload["payout"].(map[string]interface{})["entity"].(map[string]interface{})
+	if entity["id"] != payout.ID || entity["failure_reason"] != "Beneficiary bank down" {
+		t.Fatalf("payout.failed payload = %v", entity)
+	}
+}
+
+func TestPayoutIsIdempotentOnReference(t *testing.T) {
+	server, client, _ := startServer(t, fake.Config{})
+
+	first, err := client.CreateUPIPayout("alice@upi", 5000, "INR", razorpay.PurposePayout, "", "Alice", "", "TXN004")
+	if err != nil {
+		t.Fatalf("CreateUPIPayout: %v", err)
+	}
+
+	// A submit retried after a timeout gets the payout already made
+	second, err := client.CreateUPIPayout("alice@upi", 5000, "INR", razorpay.PurposePayout, "", "Alice", "", "TXN004")
+	if err != nil {
+		t.Fatalf("CreateUPIPayout retry: %v", err)
+	}
+	if second.ID != first.ID {
+		t.Fatalf("retried payout ID = %q, want %q", second.ID, first.ID)
+	}
+
+	// The payout lands on the fund account it was created with
+	if _, ok := server.Payout(first.ID); !ok {
+		t.Fatalf("payout %s not found on the server", first.ID)
+	}
+	again, err := client.CreateFundAccountPayout(first.FundAccountID, 5000, "INR", razorpay.PayoutModeUPI, razorpay.PurposePayout, "", "TXN005")
+	if err != nil {
+		t.Fatalf("CreateFundAccountPayout: %v", err)
+	}
+	if again.ID == first.ID || again.FundAccountID != first.FundAccountID {
+		t.Fatalf("fund account payout = %+v, want a new payout to %s", again, first.FundAccountID)
+	}
+}
+
+func TestPayoutRequiresRecipientName(t *testing.T) {
+	_, client, _ := startServer(t, fake.Config{})
+
+	_, err := client.CreateUPIPayout("alice@upi", 5000, "INR", razorpay.PurposePayout, "", "", "", "TXN006")
+	var rzpErr *razorpay.RazorpayError
+	if !errors.As(err, &rzpErr) || rzpErr.RazorpayError.Code != "BAD_REQUEST_ERROR" {
+		t.Fatalf("CreateUPIPayout without a name: err = %v, want a BAD_REQUEST_ERROR", err)
+	}
+
+	_, err = client.CreateBankAccountPayout("1234567890", "HDFC0000001", "", 5000, "INR", razorpay.PayoutModeIMPS, razorpay.PurposePayout, "", "TXN007")
+	if !errors.As(err, &rzpErr) {
+		t.Fatalf("CreateBankAccountPayout without a name: err = %v, want a Razorpay error", err)
+	}
+}
+
+func TestCancelPayout(t *testing.T) {
+	server, client, _ := startServer(t, fake.Config{})
+	server.SetPayoutScript("",
+		fake.PayoutStep{Status: razorpay.PayoutStatusQueued},
+		fake.PayoutStep{Status: razorpay.PayoutStatusProcessed},
+	)
+
+	payout, err := client.CreateUPIPayout("alice@upi", 5000, "INR", razorpay.PurposePayout, "", "Alice", "", "TXN008")
+	if err != nil {
+		t.Fatalf("CreateUPIPayout: %v", err)
+	}
+	cancelled, err := client.CancelPayout(payout.ID)
+	if err != nil {
+		t.Fatalf("CancelPayout: %v", err)
+	}
+	if cancelled.Status != razorpay.PayoutStatusCancelled {
+		t.Fatalf("cancelled payout status = %q, want %q", cancelled.Status, razorpay.PayoutStatusCancelled)
+	}
+
+	// Cancelling ends the script
+	if advanced, err := server.AdvancePayout(payout.ID); err != nil || advanced.Status != razorpay.PayoutStatusCancelled {
+		t.Fatalf("AdvancePayout after cancel = %+v, %v", advanced, err)
+	}
+	if _, err := client.CancelPayout(payout.ID); err == nil {
+		t.Fatal("cancelling a cancelled payout succeeded")
+	}
+}
+
+func TestRefunds(t *testing.T) {
+	server, client, recv := startServer(t, fake.Config{PendingRefunds: true})
+
+	order, err := client.CreateOrder(100000, "INR", "load_1")
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	payment, err := server.PayOrder(order.ID, "upi")
+	if err != nil {
+		t.Fatalf("PayOrder: %v", err)
+	}
+
+	refund, err := client.CreateRefund(payment.ID, &razorpay.CreateRefundRequest{Amount: 40000, Receipt: "RFD001"}, "refund-1")
+	if err != nil {
+		t.Fatalf("CreateRefund: %v", err)
+	}
+	if refund.Status != razorpay.RefundStatusPending || refund.Amount != 40000 || refund.Receipt != "RFD001" {
+		t.Fatalf("refund = %+v, want a pending 40000 paise refund with receipt RFD001", refund)
+	}
+
+	// The same idempotency key returns the same refund
+	retried, err := client.CreateRefund(payment.ID, &razorpay.CreateRefundRequest{Amount: 40000, Receipt: "RFD001"}, "refund-1")
+	if err != nil {
+		t.Fatalf("CreateRefund retry: %v", err)
+	}
+	if retried.ID != refund.ID {
+		t.Fatalf("retried refund ID = %q, want %q", retried.ID, refund.ID)
+	}
+
+	// Only what is left of the payment can be refunded
+	_, err = client.CreateRefund(payment.ID, &razorpay.CreateRefundRequest{Amount: 70000}, "refund-2")
+	var rzpErr *razorpay.RazorpayError
+	if !errors.As(err, &rzpErr) || rzpErr.RazorpayError.Code != "BAD_REQUEST_ERROR" {
+		t.Fatalf("refund over the captured amount: err = %v, want a BAD_REQUEST_ERROR", err)
+	}
+
+	// A failed refund frees its amount again
+	if _, err := server.SetRefundStatus(refund.ID, razorpay.RefundStatusFailed); err != nil {
+		t.Fatalf("SetRefundStatus: %v", err)
+	}
+	full, err := client.CreateRefund(payment.ID, &razorpay.CreateRefundRequest{}, "refund-3")
+	if err != nil {
+		t.Fatalf("full CreateRefund: %v", err)
+	}
+	if full.Amount != 100000 {
+		t.Fatalf("full refund amount = %d, want 100000", full.Amount)
+	}
+	if _, err := server.SetRefundStatus(full.ID, razorpay.RefundStatusProcessed); err != nil {
+		t.Fatalf("SetRefundStatus: %v", err)
+	}
+
+	fetched, err := client.GetRefund(full.ID)
+	if err != nil {
+		t.Fatalf("GetRefund: %v", err)
+	}
+	if fetched.Status != razorpay.RefundStatusProcessed {
+		t.Fatalf("refund status = %q, want %q", fetched.Status, razorpay.RefundStatusProcessed)
+	}
+	paid, err := client.GetPayment(payment.ID)
+	if err != nil {
+		t.Fatalf("GetPayment: %v", err)
+	}
+	if paid.Status != "refunded" || paid.AmountRefunded != 100000 {
+		t.Fatalf("payment = %+v, want it fully refunded", paid)
+	}
+
+	want := []string{"payment.captured", "order.paid", "refund.created", "refund.failed", "refund.created", "refund.processed"}
+	if got := eventNames(recv.events(t, client)); !equalStrings(got, want) {
+		t.Fatalf("webhooks = %v, want %v", got, want)
+	}
+}
+
+func TestWebhookSignatureUsesSecret(t *testing.T) {
+	server, client, recv := startServer(t, fake.Config{})
+
+	order, err := client.CreateOrder(50000, "INR", "load_2")
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if _, err := server.PayOrder(order.ID, ""); err != nil {
+		t.Fatalf("PayOrder: %v", err)
+	}
+
+	recv.mu.Lock()
+	defer recv.mu.Unlock()
+	if len(recv.deliveries) == 0 {
+		t.Fatal("no webhooks delivered")
+	}
+	for _, d := range recv.deliveries {
+		if client.VerifyWebhookSignature(d.body, d.signature, "wrong_secret") {
+			t.Fatal("webhook verified with the wrong secret")
+		}
+	}
+	for _, hook := range server.Webhooks() {
+		if hook.Err != nil || hook.StatusCode != http.StatusOK {
+			t.Fatalf("webhook %s delivery: status %d, err %v", hook.Event, hook.StatusCode, hook.Err)
+		}
+	}
+}
+
+func TestAuthAndSimulatedFailures(t *testing.T) {
+	server, client, _ := startServer(t, fake.Config{KeyID: "rzp_test_key", KeySecret: "secret"})
+
+	if _, err := client.CreateOrder(10000, "INR", "r1"); err != nil {
+		t.Fatalf("CreateOrder with the server's keys: %v", err)
+	}
+
+	wrong := razorpay.NewClientWithAccount("rzp_test_key", "not-the-secret", "")
+	wrong.BaseURL = server.URL()
+	if _, err := wrong.CreateOrder(10000, "INR", "r2"); err == nil {
+		t.Fatal("CreateOrder with the wrong secret succeeded")
+	}
+
+	server.FailNext(1)
+	if _, err := client.CreateOrder(10000, "INR", "r3"); err == nil {
+		t.Fatal("CreateOrder succeeded while a failure was simulated")
+	}
+	if _, err := client.CreateOrder(10000, "INR", "r4"); err != nil {
+		t.Fatalf("CreateOrder after the simulated failure: %v", err)
+	}
+}
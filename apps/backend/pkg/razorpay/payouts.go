@@ -103,12 +103,22 @@ type FundAccountResponse struct {
 	CreatedAt   int64              `json:"created_at"`
 }
 
-// CreatePayout creates a new payout
+// payoutHeaders keys a payout by its reference ID, so a retried request
+// returns the payout Razorpay already created instead of paying twice
+func payoutHeaders(referenceID string) map[string]string {
+	if referenceID == "" {
+		return nil
+	}
+	return map[string]string{"X-Payout-Idempotency": referenceID}
+}
+
+// CreatePayout creates a new payout. Payouts with a reference ID are
+// idempotent on it.
 func (c *Client) CreatePayout(req *PayoutRequest) (*Payout, error) {
 	url := fmt.Sprintf("%s/payouts", c.BaseURL)
 
 	var payout Payout
-	if err := c.makeRequest("POST", url, req, &payout); err != nil {
+	if err := c.makeRequestWithHeaders("POST", url, payoutHeaders(req.Reference), req, &payout); err != nil {
 		return nil, fmt.Errorf("failed to create payout: %w", err)
 	}
 
@@ -127,6 +137,39 @@ func (c *Client) GetPayout(payoutID string) (*Payout, error) {
 	return &payout, nil
 }
 
+// CancelPayout cancels a payout. Razorpay only cancels payouts still queued,
+// e.g. waiting for the account to be funded.
+func (c *Client) CancelPayout(payoutID string) (*Payout, error) {
+	url := fmt.Sprintf("%s/payouts/%s/cancel", c.BaseURL, payoutID)
+
+	var payout Payout
+	if err := c.makeRequest("POST", url, nil, &payout); err != nil {
+		return nil, fmt.Errorf("failed to cancel payout: %w", err)
+	}
+
+	return &payout, nil
+}
+
+// VPAValidation is the result of checking a UPI ID with Razorpay
+type VPAValidation struct {
+	VPA          string `json:"vpa"`
+	Success      bool   `json:"success"`
+	CustomerName string `json:"customer_name,omitempty"` // Name the UPI ID is registered to
+}
+
+// ValidateVPA checks that a UPI ID exists and returns the name registered to
+// it. An unknown UPI ID is not an error; Success is false.
+func (c *Client) ValidateVPA(vpa string) (*VPAValidation, error) {
+	url := fmt.Sprintf("%s/payments/validate/vpa", c.BaseURL)
+
+	var result VPAValidation
+	if err := c.makeRequest("POST", url, map[string]string{"vpa": vpa}, &result); err != nil {
+		return nil, fmt.Errorf("failed to validate VPA: %w", err)
+	}
+
+	return &result, nil
+}
+
 // CreateContact creates a contact for payouts
 func (c *Client) CreateContact(contact *PayoutContact) (*ContactResponse, error) {
 	url := fmt.Sprintf("%s/contacts", c.BaseURL)
@@ -151,7 +194,8 @@ func (c *Client) CreateFundAccount(fundAccount *FundAccountRequest) (*FundAccoun
 	return &result, nil
 }
 
-// CreateUPIPayout creates a UPI payout (convenience method)
+// CreateUPIPayout creates a UPI payout (convenience method). The reference ID
+// is the payout's idempotency key.
 func (c *Client) CreateUPIPayout(upiID string, amount int64, currency, purpose, narration, contactName, phone, referenceID string) (*Payout, error) {
 	fmt.Printf("🚀 Starting UPI Payout: UPI=%s, Amount=%d, Contact=%s\n", upiID, amount, contactName)
 
@@ -211,7 +255,7 @@ func (c *Client) CreateUPIPayout(upiID string, amount int64, currency, purpose,
 	fmt.Printf("🌐 Payout URL: %s\n", url)
 
 	var payout Payout
-	if err := c.makeRequest("POST", url, payoutData, &payout); err != nil {
+	if err := c.makeRequestWithHeaders("POST", url, payoutHeaders(referenceID), payoutData, &payout); err != nil {
 		return nil, fmt.Errorf("failed to create payout: %w", err)
 	}
 
@@ -219,6 +263,62 @@ func (c *Client) CreateUPIPayout(upiID string, amount int64, currency, purpose,
 	return &payout, nil
 }
 
+// CreateFundAccountPayout pays an existing fund account, so a saved recipient
+// does not need a new contact and fund account for every payout. The
+// reference ID is the payout's idempotency key.
+func (c *Client) CreateFundAccountPayout(fundAccountID string, amount int64, currency, mode, purpose, narration, referenceID string) (*Payout, error) {
+	payoutData := map[string]interface{}{
+		"fund_account_id":      fundAccountID,
+		"amount":               amount,
+		"currency":             currency,
+		"mode":                 mode,
+		"purpose":              purpose,
+		"queue_if_low_balance": true,
+		"reference_id":         referenceID,
+		"narration":            narration,
+	}
+	if c.AccountNumber != "" {
+		payoutData["account_number"] = c.AccountNumber
+	}
+
+	url := fmt.Sprintf("%s/payouts", c.BaseURL)
+
+	var payout Payout
+	if err := c.makeRequestWithHeaders("POST", url, payoutHeaders(referenceID), payoutData, &payout); err != nil {
+		return nil, fmt.Errorf("failed to create payout: %w", err)
+	}
+
+	return &payout, nil
+}
+
+// CreateBankAccountPayout pays a bank account by IMPS, NEFT or RTGS, creating
+// a contact and bank fund account for it first
+func (c *Client) CreateBankAccountPayout(accountNumber, ifsc, accountName string, amount int64, currency, mode, purpose, narration, referenceID string) (*Payout, error) {
+	contactResp, err := c.CreateContact(&PayoutContact{
+		Name:        accountName,
+		Type:        ContactTypeCustomer,
+		ReferenceID: referenceID + "_contact",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create contact: %w", err)
+	}
+
+	fundAccountResp, err := c.CreateFundAccount(&FundAccountRequest{
+		ContactID:   contactResp.ID,
+		AccountType: AccountTypeBank,
+		BankAccount: &PayoutBankAccount{
+			Name:          accountName,
+			IFSC:          ifsc,
+			AccountNumber: accountNumber,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fund account: %w", err)
+	}
+
+	return c.CreateFundAccountPayout(fundAccountResp.ID, amount, currency, mode, purpose, narration, referenceID)
+}
+
 // Payout Status Constants
 const (
 	PayoutStatusQueued     = "queued"
@@ -47,6 +47,19 @@ type Client struct {
 	AccountNumber string // Required for payouts in live mode
 }
 
+// DefaultBaseURL is Razorpay's API endpoint. Test and live mode share it; the
+// mode is determined by the API keys.
+const DefaultBaseURL = "https://api.razorpay.com/v1"
+
+// baseURL returns RAZORPAY_BASE_URL if set, so the client can be pointed at a
+// local simulator, and DefaultBaseURL otherwise
+func baseURL() string {
+	if url := strings.TrimRight(os.Getenv("RAZORPAY_BASE_URL"), "/"); url != "" {
+		return url
+	}
+	return DefaultBaseURL
+}
+
 // NewClient creates a new Razorpay client
 func NewClient(keyID, keySecret string) *Client {
 	accountNumber := os.Getenv("RAZORPAY_ACCOUNT_NUMBER")
@@ -58,7 +71,7 @@ func NewClient(keyID, keySecret string) *Client {
 	return &Client{
 		KeyID:         keyID,
 		KeySecret:     keySecret,
-		BaseURL:       "https://api.razorpay.com/v1",
+		BaseURL:       baseURL(),
 		AccountNumber: accountNumber,
 		HTTPClient: &http.Client{
 			Timeout: 30 * time.Second,
@@ -71,7 +84,7 @@ func NewClientWithAccount(keyID, keySecret, accountNumber string) *Client {
 	return &Client{
 		KeyID:         keyID,
 		KeySecret:     keySecret,
-		BaseURL:       "https://api.razorpay.com/v1",
+		BaseURL:       baseURL(),
 		AccountNumber: accountNumber,
 		HTTPClient: &http.Client{
 			Timeout: 30 * time.Second,
@@ -108,6 +121,9 @@ type Payment struct {
 	Contact     string        `json:"contact"`
 	Notes       FlexibleNotes `json:"notes"`
 	CreatedAt   int64         `json:"created_at"`
+
+	AmountRefunded int64  `json:"amount_refunded"`
+	RefundStatus   string `json:"refund_status,omitempty"` // partial or full
 }
 
 // CreateOrderRequest represents order creation request
@@ -179,6 +195,11 @@ func (c *Client) CapturePayment(paymentID string, amount int64) (*Payment, error
 
 // makeRequest makes HTTP request to Razorpay API
 func (c *Client) makeRequest(method, url string, body interface{}, result interface{}) error {
+	return c.makeRequestWithHeaders(method, url, nil, body, result)
+}
+
+// makeRequestWithHeaders makes HTTP request to Razorpay API with extra headers
+func (c *Client) makeRequestWithHeaders(method, url string, headers map[string]string, body interface{}, result interface{}) error {
 	var reqBody io.Reader
 
 	if body != nil {
@@ -202,6 +223,9 @@ func (c *Client) makeRequest(method, url string, body interface{}, result interf
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
 	req.SetBasicAuth(c.KeyID, c.KeySecret)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
 
 	// Add X-Razorpay-Account header for payouts if account number is available
 	if strings.Contains(url, "payouts") || strings.Contains(url, "contacts") || strings.Contains(url, "fund_accounts") {
@@ -0,0 +1,66 @@
+package razorpay
+
+import (
+	"fmt"
+)
+
+// Refund Status Constants
+const (
+	RefundStatusPending   = "pending"
+	RefundStatusProcessed = "processed"
+	RefundStatusFailed    = "failed"
+)
+
+// Refund represents a Razorpay refund
+type Refund struct {
+	ID             string        `json:"id"`
+	Entity         string        `json:"entity"`
+	Amount         int64         `json:"amount"`
+	Currency       string        `json:"currency"`
+	PaymentID      string        `json:"payment_id"`
+	Receipt        string        `json:"receipt"`
+	Status         string        `json:"status"`
+	SpeedRequested string        `json:"speed_requested"`
+	SpeedProcessed string        `json:"speed_processed"`
+	Notes          FlexibleNotes `json:"notes"`
+	CreatedAt      int64         `json:"created_at"`
+}
+
+// CreateRefundRequest represents refund creation request
+type CreateRefundRequest struct {
+	Amount  int64             `json:"amount,omitempty"` // In paise; zero refunds the full payment
+	Speed   string            `json:"speed,omitempty"`  // normal or optimum
+	Receipt string            `json:"receipt,omitempty"`
+	Notes   map[string]string `json:"notes,omitempty"`
+}
+
+// CreateRefund refunds a captured payment. The idempotency key makes retries
+// of the same refund safe; Razorpay returns the original refund for a key it
+// has already seen.
+func (c *Client) CreateRefund(paymentID string, req *CreateRefundRequest, idempotencyKey string) (*Refund, error) {
+	url := fmt.Sprintf("%s/payments/%s/refund", c.BaseURL, paymentID)
+
+	var headers map[string]string
+	if idempotencyKey != "" {
+		headers = map[string]string{"X-Refund-Idempotency": idempotencyKey}
+	}
+
+	var refund Refund
+	if err := c.makeRequestWithHeaders("POST", url, headers, req, &refund); err != nil {
+		return nil, fmt.Errorf("failed to create refund: %w", err)
+	}
+
+	return &refund, nil
+}
+
+// GetRefund fetches refund details
+func (c *Client) GetRefund(refundID string) (*Refund, error) {
+	url := fmt.Sprintf("%s/refunds/%s", c.BaseURL, refundID)
+
+	var refund Refund
+	if err := c.makeRequest("GET", url, nil, &refund); err != nil {
+		return nil, fmt.Errorf("failed to get refund: %w", err)
+	}
+
+	return &refund, nil
+}
@@ -0,0 +1,99 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Thresholds are the scores at which a payment moves up a level
+type Thresholds struct {
+	Medium  int // Allowed, but reported as medium risk
+	Confirm int // The user has to confirm the payment
+	Block   int // The payment is refused
+}
+
+// DefaultThresholds keep a single strong signal, or a couple of weaker ones,
+// below the block line
+var DefaultThresholds = Thresholds{
+	Medium:  20,
+	Confirm: 50,
+	Block:   100,
+}
+
+// Engine runs every rule against a payment and adds up the score
+type Engine struct {
+	thresholds Thresholds
+	rules      []Rule
+}
+
+func NewEngine(thresholds Thresholds, rules ...Rule) *Engine {
+	return &Engine{
+		thresholds: thresholds,
+		rules:      rules,
+	}
+}
+
+// NewDefaultEngine builds the engine with DefaultThresholds and DefaultRules
+func NewDefaultEngine(loc *time.Location) *Engine {
+	return NewEngine(DefaultThresholds, DefaultRules(loc)...)
+}
+
+// Rules returns the rule names in evaluation order
+func (e *Engine) Rules() []string {
+	names := make([]string, 0, len(e.rules))
+	for _, rule := range e.rules {
+		names = append(names, rule.Name())
+	}
+	return names
+}
+
+// Evaluate scores the payment. A rule that cannot read the history fails the
+// whole evaluation rather than letting the payment through unchecked.
+func (e *Engine) Evaluate(ctx context.Context, payment *Payment, history History) (*Decision, error) {
+	if payment.At.IsZero() {
+		payment.At = time.Now()
+	}
+
+	decision := &Decision{Reasons: []Reason{}}
+	forced := ActionAllow
+	for _, rule := range e.rules {
+		reason, err := rule.Evaluate(ctx, payment, history)
+		if err != nil {
+			return nil, fmt.Errorf("risk rule %s failed: %w", rule.Name(), err)
+		}
+		if reason == nil {
+			continue
+		}
+
+		reason.Rule = rule.Name()
+		decision.Score += reason.Score
+		decision.Reasons = append(decision.Reasons, *reason)
+		if severity(reason.Action) > severity(forced) {
+			forced = reason.Action
+		}
+	}
+
+	switch {
+	case decision.Score >= e.thresholds.Block:
+		decision.Action = ActionBlock
+	case decision.Score >= e.thresholds.Confirm:
+		decision.Action = ActionConfirm
+	default:
+		decision.Action = ActionAllow
+	}
+	if severity(forced) > severity(decision.Action) {
+		decision.Action = forced
+	}
+
+	switch {
+	case decision.Action != ActionAllow:
+		decision.Level = LevelHigh
+	case decision.Score >= e.thresholds.Medium:
+		decision.Level = LevelMedium
+	default:
+		decision.Level = LevelLow
+	}
+
+	return decision, nil
+}
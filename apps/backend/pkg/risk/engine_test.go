@@ -0,0 +1,152 @@
+package risk_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zeusnotfound04/Tranza/pkg/risk"
+)
+
+// fixedRule always gives the same reason
+type fixedRule struct {
+	name   string
+	score  int
+	action string
+}
+
+func (r *fixedRule) Name() string { return r.name }
+
+func (r *fixedRule) Evaluate(ctx context.Context, payment *risk.Payment, history risk.History) (*risk.Reason, error) {
+	if r.score == 0 && r.action == "" {
+		return nil, nil
+	}
+	return &risk.Reason{Score: r.score, Message: r.name + " fired", Action: r.action}, nil
+}
+
+func TestEngineThresholds(t *testing.T) {
+	tests := []struct {
+		scores []int
+		action string
+		level  string
+	}{
+		{nil, risk.ActionAllow, risk.LevelLow},
+		{[]int{19}, risk.ActionAllow, risk.LevelLow},
+		{[]int{10, 10}, risk.ActionAllow, risk.LevelMedium},
+		{[]int{49}, risk.ActionAllow, risk.LevelMedium},
+		{[]int{30, 20}, risk.ActionConfirm, risk.LevelHigh},
+		{[]int{99}, risk.ActionConfirm, risk.LevelHigh},
+		{[]int{50, 50}, risk.ActionBlock, risk.LevelHigh},
+	}
+	for _, tt := range tests {
+		rules := make([]risk.Rule, len(tt.scores))
+		total := 0
+		for i, score := range tt.scores {
+			rules[i] = &fixedRule{name: "rule", score: score}
+			total += score
+		}
+
+		decision, err := risk.NewEngine(risk.DefaultThresholds, rules...).Evaluate(context.Background(), payment("100"), &fakeHistory{})
+		if err != nil {
+			t.Fatalf("Evaluate(%v): %v", tt.scores, err)
+		}
+		if decision.Score != total || decision.Action != tt.action || decision.Level != tt.level || len(decision.Reasons) != len(tt.scores) {
+			t.Errorf("Evaluate(%v) = %+v, want score %d, %s at %s", tt.scores, decision, total, tt.action, tt.level)
+		}
+		if decision.Allowed() != (tt.action == risk.ActionAllow) {
+			t.Errorf("Evaluate(%v).Allowed() = %t", tt.scores, decision.Allowed())
+		}
+	}
+}
+
+func TestEngineMinimumAction(t *testing.T) {
+	tests := []struct {
+		rules  []risk.Rule
+		action string
+	}{
+		// A rule's action holds however low the score
+		{[]risk.Rule{&fixedRule{name: "velocity_24h", score: 10, action: risk.ActionBlock}}, risk.ActionBlock},
+		{[]risk.Rule{&fixedRule{name: "flag", action: risk.ActionConfirm}}, risk.ActionConfirm},
+		// The most severe of the rules' actions and the score's wins
+		{[]risk.Rule{
+			&fixedRule{name: "flag", action: risk.ActionConfirm},
+			&fixedRule{name: "velocity_24h", action: risk.ActionBlock},
+		}, risk.ActionBlock},
+		{[]risk.Rule{
+			&fixedRule{name: "flag", score: 100, action: risk.ActionConfirm},
+		}, risk.ActionBlock},
+	}
+	for _, tt := range tests {
+		decision, err := risk.NewEngine(risk.DefaultThresholds, tt.rules...).Evaluate(context.Background(), payment("100"), &fakeHistory{})
+		if err != nil {
+			t.Fatalf("Evaluate: %v", err)
+		}
+		if decision.Action != tt.action || decision.Level != risk.LevelHigh {
+			t.Errorf("Evaluate = %+v, want %s at high", decision, tt.action)
+		}
+	}
+}
+
+func TestEngineExplainsItsDecision(t *testing.T) {
+	engine := risk.NewEngine(risk.DefaultThresholds,
+		&fixedRule{name: "first", score: 20},
+		&fixedRule{name: "quiet"},
+		&fixedRule{name: "second", score: 35},
+	)
+	if names := engine.Rules(); len(names) != 3 || names[0] != "first" || names[2] != "second" {
+		t.Errorf("Rules = %v", names)
+	}
+
+	decision, err := engine.Evaluate(context.Background(), payment("100"), &fakeHistory{})
+	if err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	if len(decision.Reasons) != 2 || decision.Reasons[0].Rule != "first" || decision.Reasons[1].Rule != "second" {
+		t.Fatalf("Reasons = %+v, want first and second", decision.Reasons)
+	}
+	if decision.Summary() != "first fired; second fired" {
+		t.Errorf("Summary = %q", decision.Summary())
+	}
+}
+
+func TestEngineFailsClosed(t *testing.T) {
+	// The history error stops the evaluation instead of letting the payment through
+	engine := risk.NewDefaultEngine(ist)
+	decision, err := engine.Evaluate(context.Background(), payment("100"), &fakeHistory{err: errors.New("database is down")})
+	if err == nil || decision != nil {
+		t.Fatalf("Evaluate with a failing history = %+v, %v; want an error", decision, err)
+	}
+}
+
+func TestDefaultEngine(t *testing.T) {
+	engine := risk.NewDefaultEngine(ist)
+	history := &fakeHistory{paid: map[string]bool{"alice@okhdfc": true}, lastIP: "203.0.113.7"}
+
+	// A usual payment to a known recipient in the afternoon
+	decision, err := engine.Evaluate(context.Background(), payment("500"), history)
+	if err != nil || decision.Action != risk.ActionAllow || decision.Score != 0 {
+		t.Fatalf("usual payment = %+v, %v; want allowed with no score", decision, err)
+	}
+
+	// A large first payment at 2am from a new IP address adds up to a block
+	risky := payment("6000")
+	risky.Recipient = "mallory@okaxis"
+	risky.IPAddress = "198.51.100.2"
+	risky.At = time.Date(2026, 5, 14, 2, 0, 0, 0, ist)
+	decision, err = engine.Evaluate(context.Background(), risky, history)
+	if err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	// amount 50 + first large payment 35 + unusual hour 15 + new IP 15
+	if decision.Score != 115 || decision.Action != risk.ActionBlock {
+		t.Fatalf("risky payment = %+v, want score 115 and blocked", decision)
+	}
+
+	// The payment's time is filled in when missing
+	undated := payment("100")
+	undated.At = time.Time{}
+	if _, err := engine.Evaluate(context.Background(), undated, history); err != nil || undated.At.IsZero() {
+		t.Fatalf("Evaluate of an undated payment = %v, at %s", err, undated.At)
+	}
+}
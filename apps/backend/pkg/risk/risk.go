@@ -0,0 +1,113 @@
+// Package risk scores outgoing payments with a set of composable rules. Each
+// rule that fires adds points and a human readable reason; the total decides
+// whether the payment is allowed, needs the user's confirmation or is blocked.
+package risk
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Payment kinds
+const (
+	KindAIPayment        = "ai_payment"
+	KindExternalTransfer = "external_transfer"
+	KindWalletTransfer   = "wallet_transfer"
+	KindBotTransfer      = "bot_transfer"
+)
+
+// Actions, in increasing order of severity
+const (
+	ActionAllow   = "allow"
+	ActionConfirm = "require_confirmation"
+	ActionBlock   = "block"
+)
+
+// Levels
+const (
+	LevelLow    = "low"
+	LevelMedium = "medium"
+	LevelHigh   = "high"
+)
+
+// Payment is the outgoing payment being scored
+type Payment struct {
+	Kind      string
+	UserID    uuid.UUID
+	Amount    decimal.Decimal
+	Recipient string // UPI ID, phone number, merchant name or @username of a Tranza user
+	IPAddress string
+	UserAgent string
+	At        time.Time
+}
+
+// History is what rules know about the user's past payments. Only payments
+// that left, or are leaving, the wallet count.
+type History interface {
+	// Activity returns how many payments the user made since the given time and their total
+	Activity(ctx context.Context, userID uuid.UUID, since time.Time) (int, decimal.Decimal, error)
+	// HasPaid reports whether the user has successfully paid the recipient before
+	HasPaid(ctx context.Context, userID uuid.UUID, recipient string) (bool, error)
+	// RecentAmounts returns the amounts of the user's latest successful payments
+	RecentAmounts(ctx context.Context, userID uuid.UUID, limit int) ([]decimal.Decimal, error)
+	// LastIPAddress returns the IP address of the user's latest recorded payment
+	LastIPAddress(ctx context.Context, userID uuid.UUID) (string, error)
+}
+
+// Rule looks at one aspect of a payment. It returns nil when it has nothing
+// to say about the payment.
+type Rule interface {
+	Name() string
+	Evaluate(ctx context.Context, payment *Payment, history History) (*Reason, error)
+}
+
+// Reason explains why a rule added to the score. Action, when set, is the
+// least severe action the payment can get regardless of its score.
+type Reason struct {
+	Rule    string `json:"rule"`
+	Score   int    `json:"score"`
+	Message string `json:"message"`
+	Action  string `json:"action,omitempty"`
+}
+
+// Decision is the outcome of scoring a payment
+type Decision struct {
+	Score   int      `json:"score"`
+	Level   string   `json:"level"`
+	Action  string   `json:"action"`
+	Reasons []Reason `json:"reasons"`
+}
+
+// Allowed reports whether the payment can go ahead without the user
+func (d *Decision) Allowed() bool {
+	return d.Action == ActionAllow
+}
+
+// Messages returns the reasons as plain sentences
+func (d *Decision) Messages() []string {
+	messages := make([]string, 0, len(d.Reasons))
+	for _, reason := range d.Reasons {
+		messages = append(messages, reason.Message)
+	}
+	return messages
+}
+
+// Summary joins the reasons into one line
+func (d *Decision) Summary() string {
+	return strings.Join(d.Messages(), "; ")
+}
+
+func severity(action string) int {
+	switch action {
+	case ActionBlock:
+		return 2
+	case ActionConfirm:
+		return 1
+	default:
+		return 0
+	}
+}
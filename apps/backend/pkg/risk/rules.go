@@ -0,0 +1,219 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// DefaultRules is the rule set used in production. Times of day are read in loc.
+func DefaultRules(loc *time.Location) []Rule {
+	return []Rule{
+		&AmountRule{
+			Medium:      decimal.NewFromInt(1000),
+			MediumScore: 20,
+			High:        decimal.NewFromInt(5000),
+			HighScore:   50,
+		},
+		&VelocityRule{Window: 10 * time.Minute, MaxCount: 3, Score: 30},
+		&VelocityRule{Window: time.Hour, MaxAmount: decimal.NewFromInt(25000), Score: 40},
+		&VelocityRule{Window: 24 * time.Hour, MaxCount: 25, Score: 50, Action: ActionBlock},
+		&NewRecipientRule{Score: 15, LargeAmount: decimal.NewFromInt(2000), LargeScore: 35},
+		&UnusualHourRule{From: 0, To: 5, Location: loc, Score: 15},
+		&AmountOutlierRule{Samples: 50, MinSamples: 5, Deviations: 3, Score: 30},
+		&IPChangeRule{Score: 15},
+	}
+}
+
+// AmountRule flags large payments
+type AmountRule struct {
+	Medium      decimal.Decimal
+	MediumScore int
+	High        decimal.Decimal
+	HighScore   int
+}
+
+func (r *AmountRule) Name() string { return "amount" }
+
+func (r *AmountRule) Evaluate(ctx context.Context, payment *Payment, history History) (*Reason, error) {
+	switch {
+	case payment.Amount.GreaterThan(r.High):
+		return &Reason{Score: r.HighScore, Message: fmt.Sprintf("amount is above ₹%s", r.High.StringFixed(0))}, nil
+	case payment.Amount.GreaterThan(r.Medium):
+		return &Reason{Score: r.MediumScore, Message: fmt.Sprintf("amount is above ₹%s", r.Medium.StringFixed(0))}, nil
+	}
+	return nil, nil
+}
+
+// VelocityRule flags users paying too often, or too much, within a window.
+// The payment being scored counts towards both limits; a zero limit is off.
+type VelocityRule struct {
+	Window    time.Duration
+	MaxCount  int
+	MaxAmount decimal.Decimal
+	Score     int
+	Action    string
+}
+
+func (r *VelocityRule) Name() string {
+	switch {
+	case r.Window%time.Hour == 0:
+		return fmt.Sprintf("velocity_%dh", int(r.Window/time.Hour))
+	case r.Window%time.Minute == 0:
+		return fmt.Sprintf("velocity_%dm", int(r.Window/time.Minute))
+	default:
+		return "velocity_" + r.Window.String()
+	}
+}
+
+func (r *VelocityRule) Evaluate(ctx context.Context, payment *Payment, history History) (*Reason, error) {
+	count, total, err := history.Activity(ctx, payment.UserID, payment.At.Add(-r.Window))
+	if err != nil {
+		return nil, err
+	}
+	count++
+	total = total.Add(payment.Amount)
+
+	var messages []string
+	if r.MaxCount > 0 && count > r.MaxCount {
+		messages = append(messages, fmt.Sprintf("%d payments in the last %s", count, describeWindow(r.Window)))
+	}
+	if r.MaxAmount.IsPositive() && total.GreaterThan(r.MaxAmount) {
+		messages = append(messages, fmt.Sprintf("₹%s paid in the last %s", total.StringFixed(2), describeWindow(r.Window)))
+	}
+	if len(messages) == 0 {
+		return nil, nil
+	}
+
+	return &Reason{Score: r.Score, Message: strings.Join(messages, " and "), Action: r.Action}, nil
+}
+
+// NewRecipientRule flags the first payment to a recipient, more so when it is large
+type NewRecipientRule struct {
+	Score       int
+	LargeAmount decimal.Decimal
+	LargeScore  int
+}
+
+func (r *NewRecipientRule) Name() string { return "new_recipient" }
+
+func (r *NewRecipientRule) Evaluate(ctx context.Context, payment *Payment, history History) (*Reason, error) {
+	if payment.Recipient == "" {
+		return nil, nil
+	}
+
+	paid, err := history.HasPaid(ctx, payment.UserID, payment.Recipient)
+	if err != nil || paid {
+		return nil, err
+	}
+
+	if r.LargeAmount.IsPositive() && payment.Amount.GreaterThanOrEqual(r.LargeAmount) {
+		return &Reason{
+			Score:   r.LargeScore,
+			Message: fmt.Sprintf("first payment to %s is ₹%s", payment.Recipient, payment.Amount.StringFixed(2)),
+		}, nil
+	}
+	return &Reason{Score: r.Score, Message: fmt.Sprintf("first payment to %s", payment.Recipient)}, nil
+}
+
+// UnusualHourRule flags payments made between From and To o'clock
+type UnusualHourRule struct {
+	From     int
+	To       int
+	Location *time.Location
+	Score    int
+}
+
+func (r *UnusualHourRule) Name() string { return "unusual_hour" }
+
+func (r *UnusualHourRule) Evaluate(ctx context.Context, payment *Payment, history History) (*Reason, error) {
+	at := payment.At
+	if r.Location != nil {
+		at = at.In(r.Location)
+	}
+
+	hour := at.Hour()
+	if hour < r.From || hour >= r.To {
+		return nil, nil
+	}
+	return &Reason{Score: r.Score, Message: fmt.Sprintf("made at %s, outside usual hours", at.Format("15:04"))}, nil
+}
+
+// AmountOutlierRule flags payments far above what the user normally pays. It
+// needs MinSamples past payments before it says anything.
+type AmountOutlierRule struct {
+	Samples    int
+	MinSamples int
+	Deviations float64
+	Score      int
+}
+
+func (r *AmountOutlierRule) Name() string { return "amount_outlier" }
+
+func (r *AmountOutlierRule) Evaluate(ctx context.Context, payment *Payment, history History) (*Reason, error) {
+	amounts, err := history.RecentAmounts(ctx, payment.UserID, r.Samples)
+	if err != nil || len(amounts) < r.MinSamples {
+		return nil, err
+	}
+
+	var sum float64
+	values := make([]float64, len(amounts))
+	for i, amount := range amounts {
+		values[i] = amount.InexactFloat64()
+		sum += values[i]
+	}
+	mean := sum / float64(len(values))
+
+	var variance float64
+	for _, value := range values {
+		variance += (value - mean) * (value - mean)
+	}
+	stddev := math.Sqrt(variance / float64(len(values)))
+
+	// Users who always pay the same amount would otherwise trip on any change,
+	// so a payment has to be at least twice the average as well
+	amount := payment.Amount.InexactFloat64()
+	if amount <= mean+r.Deviations*stddev || amount < 2*mean {
+		return nil, nil
+	}
+	return &Reason{
+		Score:   r.Score,
+		Message: fmt.Sprintf("amount is %.1fx the usual ₹%.2f", amount/mean, mean),
+	}, nil
+}
+
+// IPChangeRule flags payments from a different IP address than the last one
+type IPChangeRule struct {
+	Score int
+}
+
+func (r *IPChangeRule) Name() string { return "ip_change" }
+
+func (r *IPChangeRule) Evaluate(ctx context.Context, payment *Payment, history History) (*Reason, error) {
+	if payment.IPAddress == "" {
+		return nil, nil
+	}
+
+	last, err := history.LastIPAddress(ctx, payment.UserID)
+	if err != nil || last == "" || last == payment.IPAddress {
+		return nil, err
+	}
+	return &Reason{Score: r.Score, Message: fmt.Sprintf("made from a new IP address %s", payment.IPAddress)}, nil
+}
+
+func describeWindow(window time.Duration) string {
+	switch {
+	case window == time.Hour:
+		return "hour"
+	case window%time.Hour == 0:
+		return fmt.Sprintf("%d hours", int(window/time.Hour))
+	case window%time.Minute == 0:
+		return fmt.Sprintf("%d minutes", int(window/time.Minute))
+	default:
+		return window.String()
+	}
+}
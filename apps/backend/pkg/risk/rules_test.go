@@ -0,0 +1,248 @@
+package risk_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/pkg/risk"
+)
+
+// fakeHistory answers the rules' questions from fixed data. Payments made at
+// a time count towards Activity from any earlier start.
+type fakeHistory struct {
+	payments []fakePayment
+	paid     map[string]bool
+	amounts  []decimal.Decimal
+	lastIP   string
+	err      error
+}
+
+type fakePayment struct {
+	at     time.Time
+	amount decimal.Decimal
+}
+
+func (h *fakeHistory) Activity(ctx context.Context, userID uuid.UUID, since time.Time) (int, decimal.Decimal, error) {
+	if h.err != nil {
+		return 0, decimal.Zero, h.err
+	}
+	count, total := 0, decimal.Zero
+	for _, payment := range h.payments {
+		if !payment.at.Before(since) {
+			count++
+			total = total.Add(payment.amount)
+		}
+	}
+	return count, total, nil
+}
+
+func (h *fakeHistory) HasPaid(ctx context.Context, userID uuid.UUID, recipient string) (bool, error) {
+	return h.paid[recipient], h.err
+}
+
+func (h *fakeHistory) RecentAmounts(ctx context.Context, userID uuid.UUID, limit int) ([]decimal.Decimal, error) {
+	if len(h.amounts) > limit {
+		return h.amounts[:limit], h.err
+	}
+	return h.amounts, h.err
+}
+
+func (h *fakeHistory) LastIPAddress(ctx context.Context, userID uuid.UUID) (string, error) {
+	return h.lastIP, h.err
+}
+
+func d(value string) decimal.Decimal {
+	return decimal.RequireFromString(value)
+}
+
+// now is 2:30pm in India
+var now = time.Date(2026, 5, 14, 9, 0, 0, 0, time.UTC)
+
+var ist = time.FixedZone("IST", 5*60*60+30*60)
+
+func payment(amount string) *risk.Payment {
+	return &risk.Payment{
+		Kind:      risk.KindExternalTransfer,
+		UserID:    uuid.New(),
+		Amount:    d(amount),
+		Recipient: "alice@okhdfc",
+		IPAddress: "203.0.113.7",
+		At:        now,
+	}
+}
+
+// paymentsAgo makes count payments of amount, the latest one ago before now
+// and each a minute before the next
+func paymentsAgo(count int, amount string, ago time.Duration) []fakePayment {
+	payments := make([]fakePayment, count)
+	for i := range payments {
+		payments[i] = fakePayment{at: now.Add(-ago - time.Duration(i)*time.Minute), amount: d(amount)}
+	}
+	return payments
+}
+
+type ruleTest struct {
+	name    string
+	payment *risk.Payment
+	history *fakeHistory
+	score   int    // Zero when the rule has nothing to say
+	message string // Part of the reason's message
+}
+
+func runRuleTests(t *testing.T, rule risk.Rule, tests []ruleTest) {
+	t.Helper()
+	for _, tt := range tests {
+		if tt.history == nil {
+			tt.history = &fakeHistory{}
+		}
+		reason, err := rule.Evaluate(context.Background(), tt.payment, tt.history)
+		if err != nil {
+			t.Errorf("%s %s: %v", rule.Name(), tt.name, err)
+			continue
+		}
+		if tt.score == 0 {
+			if reason != nil {
+				t.Errorf("%s %s = %+v, want nothing", rule.Name(), tt.name, reason)
+			}
+			continue
+		}
+		if reason == nil || reason.Score != tt.score || !strings.Contains(reason.Message, tt.message) {
+			t.Errorf("%s %s = %+v, want score %d saying %q", rule.Name(), tt.name, reason, tt.score, tt.message)
+		}
+	}
+}
+
+func TestAmountRule(t *testing.T) {
+	rule := &risk.AmountRule{Medium: d("1000"), MediumScore: 20, High: d("5000"), HighScore: 50}
+	runRuleTests(t, rule, []ruleTest{
+		{name: "small", payment: payment("999")},
+		{name: "at the medium line", payment: payment("1000")},
+		{name: "medium", payment: payment("1000.01"), score: 20, message: "above ₹1000"},
+		{name: "at the high line", payment: payment("5000"), score: 20},
+		{name: "high", payment: payment("5000.01"), score: 50, message: "above ₹5000"},
+	})
+}
+
+func TestVelocityRule(t *testing.T) {
+	byCount := &risk.VelocityRule{Window: 10 * time.Minute, MaxCount: 3, Score: 30}
+	if byCount.Name() != "velocity_10m" {
+		t.Errorf("Name = %q, want velocity_10m", byCount.Name())
+	}
+	runRuleTests(t, byCount, []ruleTest{
+		{name: "quiet", payment: payment("100")},
+		// The payment being scored is the third
+		{name: "two before", payment: payment("100"), history: &fakeHistory{payments: paymentsAgo(2, "100", time.Minute)}},
+		{name: "three before", payment: payment("100"), history: &fakeHistory{payments: paymentsAgo(3, "100", time.Minute)},
+			score: 30, message: "4 payments in the last 10 minutes"},
+		// Older payments are outside the window
+		{name: "three before the window", payment: payment("100"), history: &fakeHistory{payments: paymentsAgo(3, "100", 11*time.Minute)}},
+	})
+
+	byAmount := &risk.VelocityRule{Window: time.Hour, MaxAmount: d("25000"), Score: 40}
+	if byAmount.Name() != "velocity_1h" {
+		t.Errorf("Name = %q, want velocity_1h", byAmount.Name())
+	}
+	runRuleTests(t, byAmount, []ruleTest{
+		{name: "up to the amount", payment: payment("5000"), history: &fakeHistory{payments: paymentsAgo(2, "10000", 30*time.Minute)}},
+		{name: "past the amount", payment: payment("5000.01"), history: &fakeHistory{payments: paymentsAgo(2, "10000", 30*time.Minute)},
+			score: 40, message: "₹25000.01 paid in the last hour"},
+		{name: "past the amount an hour ago", payment: payment("5000.01"), history: &fakeHistory{payments: paymentsAgo(2, "10000", 61*time.Minute)}},
+	})
+
+	// A rule over both limits names both, and carries its action
+	both := &risk.VelocityRule{Window: 24 * time.Hour, MaxCount: 1, MaxAmount: d("100"), Score: 50, Action: risk.ActionBlock}
+	reason, err := both.Evaluate(context.Background(), payment("100"), &fakeHistory{payments: paymentsAgo(1, "50", time.Hour)})
+	if err != nil || reason == nil || reason.Action != risk.ActionBlock ||
+		reason.Message != "2 payments in the last 24 hours and ₹150.00 paid in the last 24 hours" {
+		t.Errorf("velocity_24h = %+v, %v", reason, err)
+	}
+}
+
+func TestNewRecipientRule(t *testing.T) {
+	rule := &risk.NewRecipientRule{Score: 15, LargeAmount: d("2000"), LargeScore: 35}
+	noRecipient := payment("5000")
+	noRecipient.Recipient = ""
+	runRuleTests(t, rule, []ruleTest{
+		{name: "paid before", payment: payment("5000"), history: &fakeHistory{paid: map[string]bool{"alice@okhdfc": true}}},
+		{name: "first", payment: payment("1999.99"), score: 15, message: "first payment to alice@okhdfc"},
+		{name: "first and large", payment: payment("2000"), score: 35, message: "is ₹2000.00"},
+		{name: "no recipient", payment: noRecipient},
+	})
+}
+
+func TestUnusualHourRule(t *testing.T) {
+	rule := &risk.UnusualHourRule{From: 0, To: 5, Location: ist, Score: 15}
+	at := func(hour, minute int) *risk.Payment {
+		p := payment("100")
+		p.At = time.Date(2026, 5, 14, hour, minute, 0, 0, ist).UTC()
+		return p
+	}
+	runRuleTests(t, rule, []ruleTest{
+		{name: "midnight", payment: at(0, 0), score: 15, message: "made at 00:00"},
+		{name: "4:59am", payment: at(4, 59), score: 15, message: "made at 04:59"},
+		{name: "5am", payment: at(5, 0)},
+		{name: "11:59pm", payment: at(23, 59)},
+		// 8pm UTC is 1:30am in India
+		{name: "8pm UTC", payment: &risk.Payment{Amount: d("100"), At: time.Date(2026, 5, 14, 20, 0, 0, 0, time.UTC)}, score: 15, message: "made at 01:30"},
+	})
+
+	// Without a location the payment's own time is read
+	utcRule := &risk.UnusualHourRule{From: 0, To: 5, Score: 15}
+	runRuleTests(t, utcRule, []ruleTest{
+		{name: "8pm UTC", payment: &risk.Payment{Amount: d("100"), At: time.Date(2026, 5, 14, 20, 0, 0, 0, time.UTC)}},
+	})
+}
+
+func TestAmountOutlierRule(t *testing.T) {
+	rule := &risk.AmountOutlierRule{Samples: 50, MinSamples: 5, Deviations: 3, Score: 30}
+	amounts := func(values ...string) []decimal.Decimal {
+		result := make([]decimal.Decimal, len(values))
+		for i, value := range values {
+			result[i] = d(value)
+		}
+		return result
+	}
+	usual := amounts("100", "120", "80", "100", "110", "90")
+	runRuleTests(t, rule, []ruleTest{
+		{name: "usual", payment: payment("130"), history: &fakeHistory{amounts: usual}},
+		{name: "far above", payment: payment("1000"), history: &fakeHistory{amounts: usual}, score: 30, message: "10.0x the usual ₹100.00"},
+		// Too few payments to know what is usual
+		{name: "four samples", payment: payment("1000"), history: &fakeHistory{amounts: usual[:4]}},
+		{name: "no samples", payment: payment("1000")},
+		// Always the same amount: any change is many deviations off, but not twice the average
+		{name: "same amount, a bit more", payment: payment("150"), history: &fakeHistory{amounts: amounts("100", "100", "100", "100", "100")}},
+		{name: "same amount, twice", payment: payment("200"), history: &fakeHistory{amounts: amounts("100", "100", "100", "100", "100")}, score: 30, message: "2.0x"},
+	})
+}
+
+func TestIPChangeRule(t *testing.T) {
+	rule := &risk.IPChangeRule{Score: 15}
+	noIP := payment("100")
+	noIP.IPAddress = ""
+	runRuleTests(t, rule, []ruleTest{
+		{name: "same IP", payment: payment("100"), history: &fakeHistory{lastIP: "203.0.113.7"}},
+		{name: "new IP", payment: payment("100"), history: &fakeHistory{lastIP: "198.51.100.2"}, score: 15, message: "new IP address 203.0.113.7"},
+		{name: "no earlier IP", payment: payment("100")},
+		{name: "no IP on the payment", payment: noIP, history: &fakeHistory{lastIP: "198.51.100.2"}},
+	})
+}
+
+func TestRulesPassHistoryErrorsOn(t *testing.T) {
+	history := &fakeHistory{err: errors.New("database is down"), amounts: make([]decimal.Decimal, 10), lastIP: "198.51.100.2"}
+	for _, rule := range risk.DefaultRules(ist) {
+		if _, ok := rule.(*risk.AmountRule); ok {
+			continue // Reads no history
+		}
+		if _, ok := rule.(*risk.UnusualHourRule); ok {
+			continue
+		}
+		if _, err := rule.Evaluate(context.Background(), payment("100"), history); err == nil {
+			t.Errorf("%s with a failing history: want an error", rule.Name())
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSearchDays bounds how far ahead Next looks for a matching day, enough
+// to find the next 29th of February
+const cronSearchDays = 366 * 5
+
+// CronExpr is a parsed five field cron expression. Each field accepts "*",
+// single values, ranges ("1-5"), steps ("*/15", "1-20/5") and comma lists.
+// Day of week runs 0-6 from Sunday; 7 is also Sunday.
+type CronExpr struct {
+	minutes  uint64
+	hours    uint64
+	days     uint64
+	months   uint64
+	weekdays uint64
+
+	// When both day fields are restricted a day matching either is due, as
+	// in standard cron; otherwise a day must match both. A field starting
+	// with "*", such as "*/2", counts as unrestricted here.
+	anyDay     bool
+	anyWeekday bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// ParseCron parses "minute hour day-of-month month day-of-week"
+func ParseCron(expr string) (*CronExpr, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields", expr)
+	}
+
+	sets := make([]uint64, len(fields))
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+
+	// Sunday may be written as 7
+	weekdays := sets[4]
+	if weekdays&(1<<7) != 0 {
+		weekdays = weekdays&^(1<<7) | 1
+	}
+
+	return &CronExpr{
+		minutes:    sets[0],
+		hours:      sets[1],
+		days:       sets[2],
+		months:     sets[3],
+		weekdays:   weekdays,
+		anyDay:     strings.HasPrefix(fields[2], "*"),
+		anyWeekday: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// Next returns the first matching minute strictly after after, in after's
+// location. It returns false if nothing matches within five years.
+func (c *CronExpr) Next(after time.Time) (time.Time, bool) {
+	loc := after.Location()
+	start := after.Truncate(time.Minute).Add(time.Minute)
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+
+	for i := 0; i < cronSearchDays; i++ {
+		if c.matchesDay(day) {
+			for hour := 0; hour < 24; hour++ {
+				if c.hours&(1<<uint(hour)) == 0 {
+					continue
+				}
+				for minute := 0; minute < 60; minute++ {
+					if c.minutes&(1<<uint(minute)) == 0 {
+						continue
+					}
+					next := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, loc)
+					// Skip times the clocks jump over when DST starts; a time
+					// repeated when it ends is due once
+					if next.Hour() != hour || next.Minute() != minute {
+						continue
+					}
+					if !next.Before(start) {
+						return next, true
+					}
+				}
+			}
+		}
+		day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc)
+	}
+
+	return time.Time{}, false
+}
+
+func (c *CronExpr) matchesDay(day time.Time) bool {
+	if c.months&(1<<uint(day.Month())) == 0 {
+		return false
+	}
+
+	dayMatch := c.days&(1<<uint(day.Day())) != 0
+	weekdayMatch := c.weekdays&(1<<uint(day.Weekday())) != 0
+	if c.anyDay || c.anyWeekday {
+		return dayMatch && weekdayMatch
+	}
+	return dayMatch || weekdayMatch
+}
+
+func parseCronField(value string, field cronField) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(value, ",") {
+		step := 1
+		if base, stepValue, ok := strings.Cut(part, "/"); ok {
+			n, err := strconv.Atoi(stepValue)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q in cron %s field", stepValue, field.name)
+			}
+			part, step = base, n
+		}
+
+		low, high := field.min, field.max
+		if part != "*" {
+			from, to, isRange := strings.Cut(part, "-")
+			var err error
+			if low, err = parseCronValue(from, field); err != nil {
+				return 0, err
+			}
+			high = low
+			if isRange {
+				if high, err = parseCronValue(to, field); err != nil {
+					return 0, err
+				}
+				if high < low {
+					return 0, fmt.Errorf("invalid range %q in cron %s field", part, field.name)
+				}
+			} else if step > 1 {
+				// "5/15" means every 15 starting at 5
+				high = field.max
+			}
+		}
+
+		for v := low; v <= high; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+func parseCronValue(value string, field cronField) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < field.min || n > field.max {
+		return 0, fmt.Errorf("cron %s must be between %d and %d, got %q", field.name, field.min, field.max, value)
+	}
+	return n, nil
+}
@@ -0,0 +1,168 @@
+// Package schedule works out when a recurring payment is next due. Rules are
+// either simple calendar rules (once, daily, weekly, monthly) or a five field
+// cron expression, and are always evaluated in the schedule's time zone so
+// "the 1st at 9am" means 9am where the user lives.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequencies
+const (
+	Once    = "once"
+	Daily   = "daily"
+	Weekly  = "weekly"
+	Monthly = "monthly"
+	Cron    = "cron"
+)
+
+// DefaultTimeOfDay is used when a calendar rule does not give a time
+const DefaultTimeOfDay = "09:00"
+
+// Rule describes when a payment repeats
+type Rule struct {
+	Frequency  string
+	TimeOfDay  string       // "HH:MM", for daily, weekly and monthly rules
+	DayOfWeek  time.Weekday // For weekly rules
+	DayOfMonth int          // For monthly rules; days past the end of a short month run on its last day
+	Cron       string       // For cron rules: "minute hour day-of-month month day-of-week"
+	At         time.Time    // For once rules
+}
+
+// Validate checks the rule is complete and well formed
+func (r Rule) Validate() error {
+	switch r.Frequency {
+	case Once:
+		if r.At.IsZero() {
+			return errors.New("a one-off schedule needs a time to run at")
+		}
+		return nil
+	case Daily:
+	case Weekly:
+		if r.DayOfWeek < time.Sunday || r.DayOfWeek > time.Saturday {
+			return errors.New("day of week must be between 0 (Sunday) and 6 (Saturday)")
+		}
+	case Monthly:
+		if r.DayOfMonth < 1 || r.DayOfMonth > 31 {
+			return errors.New("day of month must be between 1 and 31")
+		}
+	case Cron:
+		_, err := ParseCron(r.Cron)
+		return err
+	default:
+		return fmt.Errorf("unknown frequency %q", r.Frequency)
+	}
+
+	_, _, err := parseTimeOfDay(r.TimeOfDay)
+	return err
+}
+
+// Next returns the first time the rule is due strictly after after, in loc.
+// It returns false when the rule will never be due again.
+func (r Rule) Next(after time.Time, loc *time.Location) (time.Time, bool) {
+	after = after.In(loc)
+
+	switch r.Frequency {
+	case Once:
+		if r.At.After(after) {
+			return r.At.In(loc), true
+		}
+		return time.Time{}, false
+	case Cron:
+		cron, err := ParseCron(r.Cron)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return cron.Next(after)
+	}
+
+	hour, minute, err := parseTimeOfDay(r.TimeOfDay)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	switch r.Frequency {
+	case Daily:
+		next := time.Date(after.Year(), after.Month(), after.Day(), hour, minute, 0, 0, loc)
+		if !next.After(after) {
+			next = time.Date(after.Year(), after.Month(), after.Day()+1, hour, minute, 0, 0, loc)
+		}
+		return next, true
+	case Weekly:
+		days := (int(r.DayOfWeek) - int(after.Weekday()) + 7) % 7
+		next := time.Date(after.Year(), after.Month(), after.Day()+days, hour, minute, 0, 0, loc)
+		if !next.After(after) {
+			next = time.Date(after.Year(), after.Month(), after.Day()+days+7, hour, minute, 0, 0, loc)
+		}
+		return next, true
+	case Monthly:
+		for months := 0; months <= 1; months++ {
+			first := time.Date(after.Year(), after.Month()+time.Month(months), 1, 0, 0, 0, 0, loc)
+			day := r.DayOfMonth
+			if last := daysIn(first); day > last {
+				day = last
+			}
+			next := time.Date(first.Year(), first.Month(), day, hour, minute, 0, 0, loc)
+			if next.After(after) {
+				return next, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// Describe returns the rule in words, e.g. "monthly on day 5 at 09:00"
+func (r Rule) Describe() string {
+	switch r.Frequency {
+	case Once:
+		return "once at " + r.At.Format("2006-01-02 15:04")
+	case Daily:
+		return "daily at " + r.timeOfDay()
+	case Weekly:
+		return fmt.Sprintf("weekly on %s at %s", r.DayOfWeek, r.timeOfDay())
+	case Monthly:
+		return fmt.Sprintf("monthly on day %d at %s", r.DayOfMonth, r.timeOfDay())
+	case Cron:
+		return "cron " + r.Cron
+	}
+	return r.Frequency
+}
+
+func (r Rule) timeOfDay() string {
+	if r.TimeOfDay == "" {
+		return DefaultTimeOfDay
+	}
+	return r.TimeOfDay
+}
+
+// parseTimeOfDay reads "HH:MM", defaulting to DefaultTimeOfDay when empty
+func parseTimeOfDay(value string) (int, int, error) {
+	if value == "" {
+		value = DefaultTimeOfDay
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("time of day %q must be HH:MM", value)
+	}
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, 0, fmt.Errorf("time of day %q must be HH:MM", value)
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, 0, fmt.Errorf("time of day %q must be HH:MM", value)
+	}
+	return hour, minute, nil
+}
+
+// daysIn returns the number of days in t's month
+func daysIn(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+}
@@ -0,0 +1,241 @@
+package schedule_test
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/zeusnotfound04/Tranza/pkg/schedule"
+)
+
+var ist = time.FixedZone("IST", 5*60*60+30*60)
+
+func at(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, ist)
+}
+
+func TestParseCron(t *testing.T) {
+	valid := []string{
+		"* * * * *",
+		"0 9 1 * *",
+		"*/15 9-17 * * 1-5",
+		"0,30 8,20 1,15 1-12/3 0,6",
+		"5/20 * * * *",
+		"0 0 * * 7",
+		"  0   9  *  *  *  ",
+	}
+	for _, expr := range valid {
+		if _, err := schedule.ParseCron(expr); err != nil {
+			t.Errorf("ParseCron(%q) = %v, want it parsed", expr, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1,,2 * * * *",
+		"-1 * * * *",
+	}
+	for _, expr := range invalid {
+		if _, err := schedule.ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		// Strictly after, to the minute
+		{"* * * * *", at(2026, 3, 10, 9, 0), at(2026, 3, 10, 9, 1)},
+		{"0 9 * * *", at(2026, 3, 10, 8, 59).Add(30 * time.Second), at(2026, 3, 10, 9, 0)},
+		{"0 9 * * *", at(2026, 3, 10, 9, 0), at(2026, 3, 11, 9, 0)},
+
+		// Steps, ranges and lists
+		{"*/15 * * * *", at(2026, 3, 10, 9, 16), at(2026, 3, 10, 9, 30)},
+		{"*/15 * * * *", at(2026, 3, 10, 9, 45), at(2026, 3, 10, 10, 0)},
+		{"5/20 * * * *", at(2026, 3, 10, 9, 26), at(2026, 3, 10, 9, 45)},
+		{"0 9-17/4 * * *", at(2026, 3, 10, 13, 0), at(2026, 3, 10, 17, 0)},
+		{"0 9-17/4 * * *", at(2026, 3, 10, 17, 0), at(2026, 3, 11, 9, 0)},
+		{"0,30 8,20 * * *", at(2026, 3, 10, 8, 30), at(2026, 3, 10, 20, 0)},
+		{"0 9 * 1-12/3 *", at(2026, 2, 1, 0, 0), at(2026, 4, 1, 9, 0)},
+
+		// Weekdays, with Sunday as 0 or 7; 10 March 2026 is a Tuesday
+		{"0 9 * * 1-5", at(2026, 3, 13, 9, 0), at(2026, 3, 16, 9, 0)},
+		{"0 9 * * 0", at(2026, 3, 10, 0, 0), at(2026, 3, 15, 9, 0)},
+		{"0 9 * * 7", at(2026, 3, 10, 0, 0), at(2026, 3, 15, 9, 0)},
+
+		// Month ends and the 29th of February
+		{"0 9 31 * *", at(2026, 4, 1, 0, 0), at(2026, 5, 31, 9, 0)},
+		{"0 9 30 * *", at(2026, 1, 31, 0, 0), at(2026, 3, 30, 9, 0)},
+		{"0 0 29 2 *", at(2026, 3, 1, 0, 0), at(2028, 2, 29, 0, 0)},
+		{"59 23 31 12 *", at(2026, 12, 31, 23, 58), at(2026, 12, 31, 23, 59)},
+		{"0 0 1 1 *", at(2026, 12, 31, 23, 59), at(2027, 1, 1, 0, 0)},
+
+		// Both day fields restricted: either one makes the day due. The 13th
+		// of March 2026 is a Friday; the next Monday is the 16th.
+		{"0 9 13 * 1", at(2026, 3, 12, 10, 0), at(2026, 3, 13, 9, 0)},
+		{"0 9 13 * 1", at(2026, 3, 13, 10, 0), at(2026, 3, 16, 9, 0)},
+		// A field starting with "*" does not restrict the day, so the day must
+		// match both: odd days that are Mondays, skipping Monday the 16th
+		{"0 9 */2 * 1", at(2026, 3, 14, 0, 0), at(2026, 3, 23, 9, 0)},
+		// The 1st on a Sunday, Tuesday, Thursday or Saturday; 1 April 2026 is
+		// a Wednesday and 1 August the next that fits
+		{"0 9 1 * */2", at(2026, 3, 2, 0, 0), at(2026, 8, 1, 9, 0)},
+	}
+	for _, tt := range tests {
+		cron, err := schedule.ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", tt.expr, err)
+		}
+		got, ok := cron.Next(tt.after)
+		if !ok || !got.Equal(tt.want) {
+			t.Errorf("%q.Next(%s) = %s, %t; want %s", tt.expr, tt.after.Format(time.RFC3339), got.Format(time.RFC3339), ok, tt.want.Format(time.RFC3339))
+		}
+	}
+
+	// 31 February never comes
+	cron, err := schedule.ParseCron("0 0 31 2 *")
+	if err != nil {
+		t.Fatalf("ParseCron: %v", err)
+	}
+	if got, ok := cron.Next(at(2026, 1, 1, 0, 0)); ok {
+		t.Errorf("Next of 31 February = %s, want none", got)
+	}
+}
+
+func TestCronNextAcrossDST(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+	cron, err := schedule.ParseCron("30 2 * * *")
+	if err != nil {
+		t.Fatalf("ParseCron: %v", err)
+	}
+
+	// 2:30am does not exist on 8 March 2026, when the clocks go forward
+	got, ok := cron.Next(time.Date(2026, 3, 7, 3, 0, 0, 0, newYork))
+	if want := time.Date(2026, 3, 9, 2, 30, 0, 0, newYork); !ok || !got.Equal(want) {
+		t.Errorf("Next over the spring change = %s, want %s", got, want)
+	}
+
+	// 1:30am happens twice on 1 November 2026 and is due once
+	cron, err = schedule.ParseCron("30 1 * * *")
+	if err != nil {
+		t.Fatalf("ParseCron: %v", err)
+	}
+	first, ok := cron.Next(time.Date(2026, 11, 1, 0, 0, 0, 0, newYork))
+	if !ok || first.Hour() != 1 || first.Minute() != 30 || first.Day() != 1 {
+		t.Fatalf("Next over the autumn change = %s, want 1:30 on 1 November", first)
+	}
+	second, ok := cron.Next(first)
+	if want := time.Date(2026, 11, 2, 1, 30, 0, 0, newYork); !ok || !second.Equal(want) {
+		t.Errorf("Next after %s = %s, want %s", first, second, want)
+	}
+}
+
+func TestRuleNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  schedule.Rule
+		after time.Time
+		want  time.Time // Zero when never due again
+	}{
+		{"daily later today", schedule.Rule{Frequency: schedule.Daily, TimeOfDay: "18:30"}, at(2026, 3, 10, 9, 0), at(2026, 3, 10, 18, 30)},
+		{"daily at the time", schedule.Rule{Frequency: schedule.Daily, TimeOfDay: "09:00"}, at(2026, 3, 10, 9, 0), at(2026, 3, 11, 9, 0)},
+		{"daily default time", schedule.Rule{Frequency: schedule.Daily}, at(2026, 3, 10, 10, 0), at(2026, 3, 11, 9, 0)},
+
+		{"weekly this week", schedule.Rule{Frequency: schedule.Weekly, DayOfWeek: time.Friday}, at(2026, 3, 10, 9, 0), at(2026, 3, 13, 9, 0)},
+		{"weekly same day, earlier", schedule.Rule{Frequency: schedule.Weekly, DayOfWeek: time.Tuesday, TimeOfDay: "10:00"}, at(2026, 3, 10, 9, 0), at(2026, 3, 10, 10, 0)},
+		{"weekly same day, passed", schedule.Rule{Frequency: schedule.Weekly, DayOfWeek: time.Tuesday}, at(2026, 3, 10, 9, 0), at(2026, 3, 17, 9, 0)},
+
+		{"monthly this month", schedule.Rule{Frequency: schedule.Monthly, DayOfMonth: 15}, at(2026, 3, 10, 9, 0), at(2026, 3, 15, 9, 0)},
+		{"monthly next month", schedule.Rule{Frequency: schedule.Monthly, DayOfMonth: 5}, at(2026, 3, 10, 9, 0), at(2026, 4, 5, 9, 0)},
+		{"monthly across the year", schedule.Rule{Frequency: schedule.Monthly, DayOfMonth: 5}, at(2026, 12, 10, 9, 0), at(2027, 1, 5, 9, 0)},
+		// Days past the end of a short month run on its last day
+		{"31st in February", schedule.Rule{Frequency: schedule.Monthly, DayOfMonth: 31}, at(2026, 2, 1, 0, 0), at(2026, 2, 28, 9, 0)},
+		{"31st in a leap February", schedule.Rule{Frequency: schedule.Monthly, DayOfMonth: 31}, at(2028, 2, 1, 0, 0), at(2028, 2, 29, 9, 0)},
+		{"31st after February", schedule.Rule{Frequency: schedule.Monthly, DayOfMonth: 31}, at(2026, 2, 28, 9, 0), at(2026, 3, 31, 9, 0)},
+		{"31st in April", schedule.Rule{Frequency: schedule.Monthly, DayOfMonth: 31}, at(2026, 3, 31, 9, 0), at(2026, 4, 30, 9, 0)},
+
+		{"once ahead", schedule.Rule{Frequency: schedule.Once, At: at(2026, 6, 1, 12, 0)}, at(2026, 3, 10, 9, 0), at(2026, 6, 1, 12, 0)},
+		{"once passed", schedule.Rule{Frequency: schedule.Once, At: at(2026, 3, 10, 9, 0)}, at(2026, 3, 10, 9, 0), time.Time{}},
+
+		{"cron", schedule.Rule{Frequency: schedule.Cron, Cron: "0 18 * * 5"}, at(2026, 3, 10, 9, 0), at(2026, 3, 13, 18, 0)},
+		{"bad cron", schedule.Rule{Frequency: schedule.Cron, Cron: "0 18 * *"}, at(2026, 3, 10, 9, 0), time.Time{}},
+		{"bad time", schedule.Rule{Frequency: schedule.Daily, TimeOfDay: "25:00"}, at(2026, 3, 10, 9, 0), time.Time{}},
+	}
+	for _, tt := range tests {
+		got, ok := tt.rule.Next(tt.after, ist)
+		if tt.want.IsZero() {
+			if ok {
+				t.Errorf("%s: Next = %s, want none", tt.name, got)
+			}
+			continue
+		}
+		if !ok || !got.Equal(tt.want) {
+			t.Errorf("%s: Next = %s, %t; want %s", tt.name, got, ok, tt.want)
+		}
+	}
+}
+
+func TestRuleNextReadsTheScheduleTimeZone(t *testing.T) {
+	// 5am UTC on 1 April is already past 9am in India
+	after := time.Date(2026, 4, 1, 5, 0, 0, 0, time.UTC)
+	rule := schedule.Rule{Frequency: schedule.Monthly, DayOfMonth: 1}
+
+	got, ok := rule.Next(after, ist)
+	if want := at(2026, 5, 1, 9, 0); !ok || !got.Equal(want) {
+		t.Errorf("Next in India = %s, want %s", got, want)
+	}
+	got, ok = rule.Next(after, time.UTC)
+	if want := time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC); !ok || !got.Equal(want) {
+		t.Errorf("Next in UTC = %s, want %s", got, want)
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	valid := []schedule.Rule{
+		{Frequency: schedule.Once, At: at(2026, 6, 1, 12, 0)},
+		{Frequency: schedule.Daily},
+		{Frequency: schedule.Weekly, DayOfWeek: time.Saturday, TimeOfDay: "23:59"},
+		{Frequency: schedule.Monthly, DayOfMonth: 31, TimeOfDay: "00:00"},
+		{Frequency: schedule.Cron, Cron: "0 9 * * 1-5"},
+	}
+	for _, rule := range valid {
+		if err := rule.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v", rule, err)
+		}
+	}
+
+	invalid := []schedule.Rule{
+		{Frequency: schedule.Once},
+		{Frequency: schedule.Weekly, DayOfWeek: 7},
+		{Frequency: schedule.Monthly},
+		{Frequency: schedule.Monthly, DayOfMonth: 32},
+		{Frequency: schedule.Daily, TimeOfDay: "9"},
+		{Frequency: schedule.Daily, TimeOfDay: "09:60"},
+		{Frequency: schedule.Cron, Cron: "* * *"},
+		{Frequency: "yearly"},
+	}
+	for _, rule := range invalid {
+		if err := rule.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded, want an error", rule)
+		}
+	}
+}
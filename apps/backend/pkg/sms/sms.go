@@ -0,0 +1,60 @@
+// Package sms sends text messages, such as phone verification codes, through
+// an SMS provider.
+package sms
+
+import (
+	"context"
+	"errors"
+	"log"
+)
+
+// Provider names accepted in configuration
+const (
+	ProviderTwilio = "twilio"
+	ProviderLog    = "log"
+)
+
+// ErrNotDelivered is returned by senders that do not deliver messages
+var ErrNotDelivered = errors.New("SMS is not configured; the message was not delivered")
+
+// Sender delivers a text message to a phone number in E.164 format
+type Sender interface {
+	Name() string
+	Send(ctx context.Context, to, message string) error
+}
+
+// LogSender stands in for a provider in development. It records that a
+// message was due without its content, which can hold secrets such as
+// verification codes, and reports it undelivered.
+type LogSender struct{}
+
+// NewLogSender creates a sender that delivers nothing
+func NewLogSender() *LogSender {
+	return &LogSender{}
+}
+
+func (s *LogSender) Name() string {
+	return ProviderLog
+}
+
+// Send logs the masked recipient and returns ErrNotDelivered
+func (s *LogSender) Send(ctx context.Context, to, message string) error {
+	log.Printf("SMS to %s not sent (%d characters): no SMS provider configured", MaskPhone(to), len(message))
+	return ErrNotDelivered
+}
+
+// MaskPhone hides all but the last four digits of a phone number for logs
+func MaskPhone(phone string) string {
+	if len(phone) <= 4 {
+		return "****"
+	}
+	masked := make([]byte, len(phone))
+	for i := range phone {
+		if i < len(phone)-4 && phone[i] >= '0' && phone[i] <= '9' {
+			masked[i] = '*'
+		} else {
+			masked[i] = phone[i]
+		}
+	}
+	return string(masked)
+}
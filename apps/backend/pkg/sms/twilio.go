@@ -0,0 +1,82 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultTwilioBaseURL is Twilio's REST API
+const DefaultTwilioBaseURL = "https://api.twilio.com/2010-04-01"
+
+// TwilioSender sends messages with Twilio's Messages API
+type TwilioSender struct {
+	AccountSID string
+	AuthToken  string
+	From       string // Sending number or messaging service SID
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewTwilioSender creates a Twilio client. An empty base URL uses Twilio's API.
+func NewTwilioSender(accountSID, authToken, from, baseURL string, timeout time.Duration) *TwilioSender {
+	if baseURL == "" {
+		baseURL = DefaultTwilioBaseURL
+	}
+	return &TwilioSender{
+		AccountSID: accountSID,
+		AuthToken:  authToken,
+		From:       from,
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: timeout},
+	}
+}
+
+func (s *TwilioSender) Name() string {
+	return ProviderTwilio
+}
+
+// Send queues the message with Twilio
+func (s *TwilioSender) Send(ctx context.Context, to, message string) error {
+	form := url.Values{}
+	form.Set("To", to)
+	form.Set("Body", message)
+	if strings.HasPrefix(s.From, "MG") {
+		form.Set("MessagingServiceSid", s.From)
+	} else {
+		form.Set("From", s.From)
+	}
+
+	endpoint := fmt.Sprintf("%s/Accounts/%s/Messages.json", s.BaseURL, url.PathEscape(s.AccountSID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to build SMS request: %w", err)
+	}
+	req.SetBasicAuth(s.AccountSID, s.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send SMS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		// Twilio's error body names the problem but never echoes the message
+		var apiErr struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("SMS provider error %d: %s", apiErr.Code, apiErr.Message)
+		}
+		return fmt.Errorf("SMS provider returned status %d", resp.StatusCode)
+	}
+	return nil
+}
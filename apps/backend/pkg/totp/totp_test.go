@@ -0,0 +1,94 @@
+package totp_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zeusnotfound04/Tranza/pkg/totp"
+)
+
+// secret is the RFC 6238 SHA-1 test key "12345678901234567890", base32 encoded
+const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// The RFC's 8 digit codes cut to their last 6 digits
+	tests := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range tests {
+		got, err := totp.Code(secret, time.Unix(unix, 0))
+		if err != nil {
+			t.Fatalf("Code at %d: %v", unix, err)
+		}
+		if got != want {
+			t.Errorf("Code at %d = %s, want %s", unix, got, want)
+		}
+	}
+
+	// Secrets are read case-insensitively and with padding
+	if got, err := totp.Code(strings.ToLower(secret)+"====", time.Unix(59, 0)); err != nil || got != "287082" {
+		t.Errorf("Code with a lower case, padded secret = %s, %v", got, err)
+	}
+	if _, err := totp.Code("not base32!", time.Unix(59, 0)); err == nil {
+		t.Error("Code with an invalid secret: want an error")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	// 1111111111 is the third second of its period
+	at := time.Unix(1111111111, 0)
+	codeAt := func(offset time.Duration) string {
+		code, err := totp.Code(secret, at.Add(offset))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name  string
+		code  string
+		valid bool
+	}{
+		{"current", "050471", true},
+		{"surrounded by spaces", " 050471 ", true},
+		{"one period back", codeAt(-totp.Period), true},
+		{"one period ahead", codeAt(totp.Period), true},
+		{"two periods back", codeAt(-2 * totp.Period), false},
+		{"two periods ahead", codeAt(2 * totp.Period), false},
+		{"wrong", "050472", false},
+		{"too short", "50471", false},
+		{"too long", "0050471", false},
+		{"the RFC's 8 digits", "14050471", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		if got := totp.Validate(secret, tt.code, at); got != tt.valid {
+			t.Errorf("Validate %s (%q) = %t, want %t", tt.name, tt.code, got, tt.valid)
+		}
+	}
+
+	if totp.Validate("not base32!", "050471", at) {
+		t.Error("Validate with an invalid secret = true")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	first, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	second, _ := totp.GenerateSecret()
+	if len(first) != 32 || first == second {
+		t.Errorf("GenerateSecret = %q then %q, want two different 32 character secrets", first, second)
+	}
+	if _, err := totp.Code(first, time.Now()); err != nil {
+		t.Errorf("Code with a generated secret: %v", err)
+	}
+}
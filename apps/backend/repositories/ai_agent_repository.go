@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"gorm.io/gorm"
+)
+
+// ErrAgentConfigNotFound is returned when a user has no configuration for an agent
+var ErrAgentConfigNotFound = errors.New("agent configuration not found")
+
+type AIAgentRepository struct {
+	db *gorm.DB
+}
+
+func NewAIAgentRepository(db *gorm.DB) *AIAgentRepository {
+	return &AIAgentRepository{
+		db: db,
+	}
+}
+
+// Save creates or updates an agent configuration
+func (r *AIAgentRepository) Save(config *models.AIAgentConfig) error {
+	if err := r.db.Save(config).Error; err != nil {
+		return fmt.Errorf("failed to save agent configuration: %w", err)
+	}
+	return nil
+}
+
+// GetByUserAndAgentID retrieves the configuration of one of a user's agents
+func (r *AIAgentRepository) GetByUserAndAgentID(userID uuid.UUID, agentID string) (*models.AIAgentConfig, error) {
+	var config models.AIAgentConfig
+	if err := r.db.Where("user_id = ? AND agent_id = ?", userID, agentID).First(&config).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAgentConfigNotFound
+		}
+		return nil, fmt.Errorf("failed to get agent configuration: %w", err)
+	}
+	return &config, nil
+}
+
+// GetByUserID lists a user's agent configurations
+func (r *AIAgentRepository) GetByUserID(userID uuid.UUID) ([]*models.AIAgentConfig, error) {
+	var configs []*models.AIAgentConfig
+	if err := r.db.Where("user_id = ?", userID).Order("agent_id ASC").Find(&configs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get agent configurations: %w", err)
+	}
+	return configs, nil
+}
+
+// CountByUserID counts a user's agent configurations
+func (r *AIAgentRepository) CountByUserID(userID uuid.UUID) (int64, error) {
+	var count int64
+	if err := r.db.Model(&models.AIAgentConfig{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count agent configurations: %w", err)
+	}
+	return count, nil
+}
+
+// Delete removes one of a user's agent configurations
+func (r *AIAgentRepository) Delete(userID uuid.UUID, agentID string) error {
+	result := r.db.Where("user_id = ? AND agent_id = ?", userID, agentID).Delete(&models.AIAgentConfig{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete agent configuration: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrAgentConfigNotFound
+	}
+	return nil
+}
+
+// GetSpentSince returns how much an agent has spent on payments made since
+// the given time, including ones still being paid out
+func (r *AIAgentRepository) GetSpentSince(userID uuid.UUID, agentID string, since time.Time) (decimal.Decimal, error) {
+	return r.GetSpentSinceWithTx(nil, userID, agentID, since, uuid.Nil)
+}
+
+// GetSpentSinceWithTx is GetSpentSince inside the caller's transaction,
+// leaving out the payment request being paid, if any
+func (r *AIAgentRepository) GetSpentSinceWithTx(tx *gorm.DB, userID uuid.UUID, agentID string, since time.Time, exceptRequestID uuid.UUID) (decimal.Decimal, error) {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+	query := db.Model(&models.AIPaymentRequest{}).
+		Where("user_id = ? AND agent_id = ? AND status IN ('processing', 'processed') AND created_at >= ?", userID, agentID, since)
+	if exceptRequestID != uuid.Nil {
+		query = query.Where("id <> ?", exceptRequestID)
+	}
+
+	var spent float64
+	if err := query.Select("COALESCE(SUM(amount), 0)").Scan(&spent).Error; err != nil {
+		return decimal.Zero, fmt.Errorf("failed to calculate agent spending: %w", err)
+	}
+	return decimal.NewFromFloat(spent), nil
+}
@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models"
+	"gorm.io/gorm"
+)
+
+type BeneficiaryRepository struct {
+	db *gorm.DB
+}
+
+func NewBeneficiaryRepository(db *gorm.DB) *BeneficiaryRepository {
+	return &BeneficiaryRepository{
+		db: db,
+	}
+}
+
+// Create creates a new beneficiary
+func (r *BeneficiaryRepository) Create(beneficiary *models.Beneficiary) error {
+	if err := r.db.Create(beneficiary).Error; err != nil {
+		return fmt.Errorf("failed to create beneficiary: %w", err)
+	}
+	return nil
+}
+
+// Update saves all fields of a beneficiary
+func (r *BeneficiaryRepository) Update(beneficiary *models.Beneficiary) error {
+	if err := r.db.Save(beneficiary).Error; err != nil {
+		return fmt.Errorf("failed to update beneficiary: %w", err)
+	}
+	return nil
+}
+
+// Delete removes one of a user's beneficiaries
+func (r *BeneficiaryRepository) Delete(id, userID uuid.UUID) error {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Beneficiary{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete beneficiary: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("beneficiary not found")
+	}
+	return nil
+}
+
+// GetByIDAndUserID retrieves one of a user's beneficiaries
+func (r *BeneficiaryRepository) GetByIDAndUserID(id, userID uuid.UUID) (*models.Beneficiary, error) {
+	return r.first("id = ? AND user_id = ?", id, userID)
+}
+
+// GetByNickname retrieves a user's beneficiary by its nickname
+func (r *BeneficiaryRepository) GetByNickname(userID uuid.UUID, nickname string) (*models.Beneficiary, error) {
+	return r.first("user_id = ? AND nickname = ?", userID, nickname)
+}
+
+// GetByRecipient retrieves a user's beneficiary for a UPI ID, phone number or
+// bank account; recipientIFSC is empty for all but bank accounts
+func (r *BeneficiaryRepository) GetByRecipient(userID uuid.UUID, recipientType, recipientValue, recipientIFSC string) (*models.Beneficiary, error) {
+	return r.first("user_id = ? AND recipient_type = ? AND recipient_value = ? AND COALESCE(recipient_ifsc, '') = ?", userID, recipientType, recipientValue, recipientIFSC)
+}
+
+// GetByUserID retrieves all of a user's beneficiaries, most recently used first
+func (r *BeneficiaryRepository) GetByUserID(userID uuid.UUID) ([]*models.Beneficiary, error) {
+	var beneficiaries []*models.Beneficiary
+	if err := r.db.Where("user_id = ?", userID).
+		Order("last_used_at DESC NULLS LAST, nickname ASC").
+		Find(&beneficiaries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get beneficiaries: %w", err)
+	}
+	return beneficiaries, nil
+}
+
+// MarkUsed records when a transfer was last made to a beneficiary
+func (r *BeneficiaryRepository) MarkUsed(id uuid.UUID, at time.Time) error {
+	if err := r.db.Model(&models.Beneficiary{}).Where("id = ?", id).Update("last_used_at", at).Error; err != nil {
+		return fmt.Errorf("failed to update beneficiary: %w", err)
+	}
+	return nil
+}
+
+func (r *BeneficiaryRepository) first(query string, args ...interface{}) (*models.Beneficiary, error) {
+	var beneficiary models.Beneficiary
+	if err := r.db.Where(query, args...).First(&beneficiary).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("beneficiary not found")
+		}
+		return nil, fmt.Errorf("failed to get beneficiary: %w", err)
+	}
+	return &beneficiary, nil
+}
@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models"
+	"gorm.io/gorm"
+)
+
+// ErrOrderStatusChanged is returned when an order is no longer in the status a
+// transition expected, e.g. because a concurrent request confirmed it
+var ErrOrderStatusChanged = errors.New("order status has changed")
+
+type ExternalOrderRepository struct {
+	db *gorm.DB
+}
+
+func NewExternalOrderRepository(db *gorm.DB) *ExternalOrderRepository {
+	return &ExternalOrderRepository{
+		db: db,
+	}
+}
+
+// Create creates a new external order
+func (r *ExternalOrderRepository) Create(order *models.ExternalOrder) error {
+	if err := r.db.Create(order).Error; err != nil {
+		return fmt.Errorf("failed to create order: %w", err)
+	}
+	return nil
+}
+
+// Update saves all fields of an order
+func (r *ExternalOrderRepository) Update(tx *gorm.DB, order *models.ExternalOrder) error {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	if err := db.Save(order).Error; err != nil {
+		return fmt.Errorf("failed to update order: %w", err)
+	}
+	return nil
+}
+
+// Transition moves an order to a new status if it is still in the expected one
+func (r *ExternalOrderRepository) Transition(tx *gorm.DB, id uuid.UUID, from, to string) error {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	result := db.Model(&models.ExternalOrder{}).Where("id = ? AND status = ?", id, from).Update("status", to)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update order status: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrOrderStatusChanged
+	}
+	return nil
+}
+
+// GetByIDAndUserID retrieves one of a user's orders
+func (r *ExternalOrderRepository) GetByIDAndUserID(id, userID uuid.UUID) (*models.ExternalOrder, error) {
+	var order models.ExternalOrder
+	if err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("order not found")
+		}
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+	return &order, nil
+}
+
+// GetByUserID retrieves a user's orders newest first, optionally with one status
+func (r *ExternalOrderRepository) GetByUserID(userID uuid.UUID, status string, limit, offset int) ([]*models.ExternalOrder, int64, error) {
+	var orders []*models.ExternalOrder
+	var total int64
+
+	query := r.db.Model(&models.ExternalOrder{}).Where("user_id = ?", userID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count orders: %w", err)
+	}
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&orders).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get orders: %w", err)
+	}
+
+	return orders, total, nil
+}
+
+// GetOpen retrieves orders the store may still ship or deliver, oldest update first
+func (r *ExternalOrderRepository) GetOpen(limit int) ([]*models.ExternalOrder, error) {
+	var orders []*models.ExternalOrder
+	if err := r.db.Where("status IN ?", []string{models.ExternalOrderStatusConfirmed, models.ExternalOrderStatusShipped}).
+		Order("updated_at ASC").
+		Limit(limit).
+		Find(&orders).Error; err != nil {
+		return nil, fmt.Errorf("failed to get open orders: %w", err)
+	}
+	return orders, nil
+}
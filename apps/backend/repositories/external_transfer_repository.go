@@ -1,6 +1,8 @@
 package repositories
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,6 +11,10 @@ import (
 	"gorm.io/gorm"
 )
 
+// ErrTransferStatusChanged is returned when a transfer is no longer in a
+// status a transition expected, e.g. because it was cancelled or submitted
+var ErrTransferStatusChanged = errors.New("transfer status has changed")
+
 type ExternalTransferRepository struct {
 	db *gorm.DB
 }
@@ -25,6 +31,14 @@ func (r *ExternalTransferRepository) Create(transfer *models.ExternalTransfer) (
 	return transfer, nil
 }
 
+// CreateWithTx creates a new external transfer within a database transaction
+func (r *ExternalTransferRepository) CreateWithTx(tx *gorm.DB, transfer *models.ExternalTransfer) (*models.ExternalTransfer, error) {
+	if err := tx.Create(transfer).Error; err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
 // GetByID retrieves an external transfer by ID
 func (r *ExternalTransferRepository) GetByID(id uuid.UUID) (*models.ExternalTransfer, error) {
 	var transfer models.ExternalTransfer
@@ -117,23 +131,24 @@ func (r *ExternalTransferRepository) GetByStatus(status string) ([]*models.Exter
 	return transfers, nil
 }
 
-// GetPendingTransfers retrieves all pending transfers
-func (r *ExternalTransferRepository) GetPendingTransfers() ([]*models.ExternalTransfer, error) {
+// GetByDateRange retrieves all transfers created in a period
+func (r *ExternalTransferRepository) GetByDateRange(startDate, endDate time.Time) ([]*models.ExternalTransfer, error) {
 	var transfers []*models.ExternalTransfer
-	if err := r.db.Where("status IN ?", []string{
-		models.ExternalTransferStatusPending,
-		models.ExternalTransferStatusProcessing,
-	}).Order("created_at ASC").Find(&transfers).Error; err != nil {
+	if err := r.db.Where("created_at BETWEEN ? AND ?", startDate, endDate).
+		Order("created_at ASC").Find(&transfers).Error; err != nil {
 		return nil, err
 	}
 	return transfers, nil
 }
 
-// GetFailedTransfersForRetry retrieves failed transfers that can be retried
-func (r *ExternalTransferRepository) GetFailedTransfersForRetry() ([]*models.ExternalTransfer, error) {
+// GetPendingTransfers retrieves all pending transfers
+func (r *ExternalTransferRepository) GetPendingTransfers() ([]*models.ExternalTransfer, error) {
 	var transfers []*models.ExternalTransfer
-	if err := r.db.Where("status = ? AND retry_count < max_retries",
-		models.ExternalTransferStatusFailed).Order("created_at ASC").Find(&transfers).Error; err != nil {
+	if err := r.db.Where("status IN ?", []string{
+		models.ExternalTransferStatusQueued,
+		models.ExternalTransferStatusPending,
+		models.ExternalTransferStatusProcessing,
+	}).Order("created_at ASC").Find(&transfers).Error; err != nil {
 		return nil, err
 	}
 	return transfers, nil
@@ -150,6 +165,34 @@ func (r *ExternalTransferRepository) Update(tx *gorm.DB, transfer *models.Extern
 
 // UpdateStatus updates the status of an external transfer
 func (r *ExternalTransferRepository) UpdateStatus(transferID uuid.UUID, status string, failureReason string) error {
+	return r.db.Model(&models.ExternalTransfer{}).Where("id = ?", transferID).Updates(statusUpdates(status, failureReason)).Error
+}
+
+// TransitionStatus moves a transfer to a new status if it is still in one of
+// the from statuses
+func (r *ExternalTransferRepository) TransitionStatus(transferID uuid.UUID, from []string, to string, failureReason string) error {
+	return r.TransitionStatusWithTx(nil, transferID, from, to, failureReason)
+}
+
+// TransitionStatusWithTx is TransitionStatus inside the caller's transaction
+func (r *ExternalTransferRepository) TransitionStatusWithTx(tx *gorm.DB, transferID uuid.UUID, from []string, to string, failureReason string) error {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+	result := db.Model(&models.ExternalTransfer{}).
+		Where("id = ? AND status IN ?", transferID, from).
+		Updates(statusUpdates(to, failureReason))
+	if result.Error != nil {
+		return fmt.Errorf("failed to update transfer status: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrTransferStatusChanged
+	}
+	return nil
+}
+
+func statusUpdates(status, failureReason string) map[string]interface{} {
 	updates := map[string]interface{}{
 		"status":     status,
 		"updated_at": time.Now(),
@@ -169,17 +212,38 @@ func (r *ExternalTransferRepository) UpdateStatus(transferID uuid.UUID, status s
 		updates["failure_reason"] = failureReason
 	}
 
-	return r.db.Model(&models.ExternalTransfer{}).Where("id = ?", transferID).Updates(updates).Error
+	return updates
 }
 
-// UpdateRazorpayPayoutID updates the Razorpay payout ID
-func (r *ExternalTransferRepository) UpdateRazorpayPayoutID(transferID uuid.UUID, payoutID string) error {
-	return r.db.Model(&models.ExternalTransfer{}).Where("id = ?", transferID).Updates(map[string]interface{}{
-		"razorpay_payout_id": payoutID,
-		"status":             models.ExternalTransferStatusProcessing,
-		"processed_at":       time.Now(),
-		"updated_at":         time.Now(),
-	}).Error
+// UpdateRazorpayPayoutID records the Razorpay payout of a transfer and marks
+// it processing, if it has no payout yet and is still in one of the from
+// statuses. A transfer a webhook got to first is left as it is.
+func (r *ExternalTransferRepository) UpdateRazorpayPayoutID(transferID uuid.UUID, payoutID string, from []string) error {
+	result := r.db.Model(&models.ExternalTransfer{}).
+		Where("id = ? AND razorpay_payout_id = ? AND status IN ?", transferID, "", from).
+		Updates(map[string]interface{}{
+			"razorpay_payout_id": payoutID,
+			"status":             models.ExternalTransferStatusProcessing,
+			"processed_at":       time.Now(),
+			"updated_at":         time.Now(),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update transfer with payout ID: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrTransferStatusChanged
+	}
+	return nil
+}
+
+// FlagForReview marks a transfer whose payout has been processing for too long
+func (r *ExternalTransferRepository) FlagForReview(transferID uuid.UUID) error {
+	return r.db.Model(&models.ExternalTransfer{}).
+		Where("id = ? AND review_flagged_at IS NULL", transferID).
+		Updates(map[string]interface{}{
+			"review_flagged_at": time.Now(),
+			"updated_at":        time.Now(),
+		}).Error
 }
 
 // IncrementRetryCount increments the retry count for a transfer
@@ -223,8 +287,8 @@ func (r *ExternalTransferRepository) GetTransferSummary(userID uuid.UUID, dateFr
 			summary.SuccessfulCount = sc.Count
 		case models.ExternalTransferStatusFailed:
 			summary.FailedCount = sc.Count
-		case models.ExternalTransferStatusPending:
-			summary.PendingCount = sc.Count
+		case models.ExternalTransferStatusQueued, models.ExternalTransferStatusPending:
+			summary.PendingCount += sc.Count
 		case models.ExternalTransferStatusProcessing:
 			summary.ProcessingCount = sc.Count
 		}
@@ -238,42 +302,71 @@ func (r *ExternalTransferRepository) GetTransferSummary(userID uuid.UUID, dateFr
 	return &summary, nil
 }
 
-// GetDailyTransferLimitUsed calculates the daily transfer amount used
-func (r *ExternalTransferRepository) GetDailyTransferLimitUsed(userID uuid.UUID, date time.Time) (decimal.Decimal, error) {
-	startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
-	endOfDay := startOfDay.Add(24 * time.Hour)
-
-	var totalAmount decimal.Decimal
-	row := r.db.Model(&models.ExternalTransfer{}).
-		Where("user_id = ? AND created_at >= ? AND created_at < ? AND status IN ?",
-			userID, startOfDay, endOfDay,
-			[]string{models.ExternalTransferStatusSuccess, models.ExternalTransferStatusPending, models.ExternalTransferStatusProcessing}).
-		Select("COALESCE(SUM(total_amount), 0)").Row()
+// CountWaivedFeeTransfers counts the transfers made in [from, to) whose fee was
+// waived under a free-transfer quota. Failed and cancelled transfers give
+// theirs back.
+func (r *ExternalTransferRepository) CountWaivedFeeTransfers(userID uuid.UUID, from, to time.Time) (int64, error) {
+	return r.CountWaivedFeeTransfersWithTx(nil, userID, from, to)
+}
 
-	if err := row.Scan(&totalAmount); err != nil {
-		return decimal.Zero, err
+// CountWaivedFeeTransfersWithTx counts waived transfers within a transaction
+func (r *ExternalTransferRepository) CountWaivedFeeTransfersWithTx(tx *gorm.DB, userID uuid.UUID, from, to time.Time) (int64, error) {
+	db := r.db
+	if tx != nil {
+		db = tx
 	}
 
-	return totalAmount, nil
+	var count int64
+	err := db.Model(&models.ExternalTransfer{}).
+		Where("user_id = ? AND fee_waived = ? AND created_at >= ? AND created_at < ? AND status IN ?",
+			userID, true, from, to,
+			[]string{models.ExternalTransferStatusSuccess, models.ExternalTransferStatusQueued, models.ExternalTransferStatusPending, models.ExternalTransferStatusProcessing}).
+		Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count free transfers: %w", err)
+	}
+	return count, nil
 }
 
-// GetMonthlyTransferLimitUsed calculates the monthly transfer amount used
-func (r *ExternalTransferRepository) GetMonthlyTransferLimitUsed(userID uuid.UUID, date time.Time) (decimal.Decimal, error) {
-	startOfMonth := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
-	endOfMonth := startOfMonth.AddDate(0, 1, 0)
+// SearchTransfers returns a user's external transfers matching the query.
+// Failed, cancelled and refunded transfers are left out.
+func (r *ExternalTransferRepository) SearchTransfers(q HistoryQuery) ([]*models.ExternalTransfer, error) {
+	var transfers []*models.ExternalTransfer
+	if err := r.sent(q).Order(q.order()).Limit(q.Limit).Find(&transfers).Error; err != nil {
+		return nil, fmt.Errorf("failed to search transfers: %w", err)
+	}
+	return transfers, nil
+}
 
-	var totalAmount decimal.Decimal
-	row := r.db.Model(&models.ExternalTransfer{}).
-		Where("user_id = ? AND created_at >= ? AND created_at < ? AND status IN ?",
-			userID, startOfMonth, endOfMonth,
-			[]string{models.ExternalTransferStatusSuccess, models.ExternalTransferStatusPending, models.ExternalTransferStatusProcessing}).
-		Select("COALESCE(SUM(total_amount), 0)").Row()
+// SumTransfers totals and counts a user's external transfers matching the query
+func (r *ExternalTransferRepository) SumTransfers(q HistoryQuery) (decimal.Decimal, int64, error) {
+	var result struct {
+		Total decimal.Decimal
+		Count int64
+	}
 
-	if err := row.Scan(&totalAmount); err != nil {
-		return decimal.Zero, err
+	if err := r.sent(q).
+		Select("COALESCE(SUM(amount), 0) as total, COUNT(*) as count").
+		Scan(&result).Error; err != nil {
+		return decimal.Zero, 0, fmt.Errorf("failed to sum transfers: %w", err)
 	}
 
-	return totalAmount, nil
+	return result.Total, result.Count, nil
+}
+
+func (r *ExternalTransferRepository) sent(q HistoryQuery) *gorm.DB {
+	query := r.db.Model(&models.ExternalTransfer{}).
+		Where("user_id = ? AND status NOT IN ? AND created_at >= ? AND created_at < ?",
+			q.UserID,
+			[]string{models.ExternalTransferStatusFailed, models.ExternalTransferStatusCancelled, models.ExternalTransferStatusRefunded},
+			q.From, q.To)
+
+	if q.Counterparty != "" {
+		pattern := likePattern(q.Counterparty)
+		query = query.Where("(LOWER(recipient_value) LIKE ? OR LOWER(recipient_name) LIKE ? OR LOWER(description) LIKE ?)",
+			pattern, pattern, pattern)
+	}
+	return query
 }
 
 // Delete soft deletes an external transfer
@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrFeePlanNotFound is returned when no fee plan matches
+var ErrFeePlanNotFound = errors.New("fee plan not found")
+
+type FeePlanRepository struct {
+	db *gorm.DB
+}
+
+func NewFeePlanRepository(db *gorm.DB) *FeePlanRepository {
+	return &FeePlanRepository{
+		db: db,
+	}
+}
+
+// Save creates or updates a fee plan and records its current version. A
+// default plan takes over from the previous default. Saving a version that
+// was already recorded fails, so two admins editing the same plan at once
+// cannot both write the next version.
+func (r *FeePlanRepository) Save(plan *models.FeePlan) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if plan.IsDefault {
+			if err := tx.Model(&models.FeePlan{}).
+				Where("is_default = ? AND id <> ?", true, plan.ID).
+				Update("is_default", false).Error; err != nil {
+				return fmt.Errorf("failed to clear default fee plan: %w", err)
+			}
+		}
+		if err := tx.Save(plan).Error; err != nil {
+			return fmt.Errorf("failed to save fee plan: %w", err)
+		}
+		if err := tx.Create(models.NewFeePlanVersion(plan)).Error; err != nil {
+			return fmt.Errorf("failed to save fee plan version: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetByID retrieves a fee plan
+func (r *FeePlanRepository) GetByID(id uuid.UUID) (*models.FeePlan, error) {
+	var plan models.FeePlan
+	if err := r.db.Where("id = ?", id).First(&plan).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFeePlanNotFound
+		}
+		return nil, fmt.Errorf("failed to get fee plan: %w", err)
+	}
+	return &plan, nil
+}
+
+// GetByCode retrieves a fee plan by its code
+func (r *FeePlanRepository) GetByCode(code string) (*models.FeePlan, error) {
+	var plan models.FeePlan
+	if err := r.db.Where("code = ?", code).First(&plan).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFeePlanNotFound
+		}
+		return nil, fmt.Errorf("failed to get fee plan: %w", err)
+	}
+	return &plan, nil
+}
+
+// GetDefault retrieves the active default fee plan
+func (r *FeePlanRepository) GetDefault() (*models.FeePlan, error) {
+	var plan models.FeePlan
+	if err := r.db.Where("is_default = ? AND is_active = ?", true, true).First(&plan).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFeePlanNotFound
+		}
+		return nil, fmt.Errorf("failed to get default fee plan: %w", err)
+	}
+	return &plan, nil
+}
+
+// GetVersions lists the recorded versions of a fee plan, newest first
+func (r *FeePlanRepository) GetVersions(planID uuid.UUID) ([]*models.FeePlanVersion, error) {
+	var versions []*models.FeePlanVersion
+	if err := r.db.Where("fee_plan_id = ?", planID).Order("version DESC").Find(&versions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get fee plan versions: %w", err)
+	}
+	return versions, nil
+}
+
+// GetAll lists fee plans
+func (r *FeePlanRepository) GetAll() ([]*models.FeePlan, error) {
+	var plans []*models.FeePlan
+	if err := r.db.Order("code ASC").Find(&plans).Error; err != nil {
+		return nil, fmt.Errorf("failed to get fee plans: %w", err)
+	}
+	return plans, nil
+}
+
+// GetUserPlan retrieves the active fee plan assigned to a user
+func (r *FeePlanRepository) GetUserPlan(userID uuid.UUID) (*models.FeePlan, error) {
+	var plan models.FeePlan
+	err := r.db.Joins("JOIN fee_plan_assignments ON fee_plan_assignments.fee_plan_id = fee_plans.id").
+		Where("fee_plan_assignments.user_id = ? AND fee_plans.is_active = ?", userID, true).
+		First(&plan).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFeePlanNotFound
+		}
+		return nil, fmt.Errorf("failed to get user fee plan: %w", err)
+	}
+	return &plan, nil
+}
+
+// Assign puts a user on a fee plan, replacing any plan they had
+func (r *FeePlanRepository) Assign(userID, planID uuid.UUID) error {
+	assignment := &models.FeePlanAssignment{UserID: userID, FeePlanID: planID}
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"fee_plan_id", "updated_at"}),
+	}).Create(assignment).Error
+	if err != nil {
+		return fmt.Errorf("failed to assign fee plan: %w", err)
+	}
+	return nil
+}
+
+// Unassign moves a user back to the default fee plan
+func (r *FeePlanRepository) Unassign(userID uuid.UUID) error {
+	if err := r.db.Where("user_id = ?", userID).Delete(&models.FeePlanAssignment{}).Error; err != nil {
+		return fmt.Errorf("failed to remove fee plan assignment: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		db: db,
+	}
+}
+
+// Create stores a new idempotency key. It returns false when a record for the
+// same scope and key already exists.
+func (r *IdempotencyRepository) Create(key *models.IdempotencyKey) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to create idempotency key: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetByScopeAndKey retrieves an idempotency key
+func (r *IdempotencyRepository) GetByScopeAndKey(scope, key string) (*models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	if err := r.db.Where("scope = ? AND key = ?", scope, key).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("idempotency key not found")
+		}
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	return &record, nil
+}
+
+// Complete stores the response for an idempotency key
+func (r *IdempotencyRepository) Complete(id uuid.UUID, responseCode int, responseBody string) error {
+	return r.finish(id, models.IdempotencyStatusCompleted, responseCode, responseBody)
+}
+
+// Fail marks an idempotency key as ended in a server error, keeping the
+// response for the record
+func (r *IdempotencyRepository) Fail(id uuid.UUID, responseCode int, responseBody string) error {
+	return r.finish(id, models.IdempotencyStatusFailed, responseCode, responseBody)
+}
+
+func (r *IdempotencyRepository) finish(id uuid.UUID, status string, responseCode int, responseBody string) error {
+	return r.db.Model(&models.IdempotencyKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":        status,
+		"response_code": responseCode,
+		"response_body": responseBody,
+		"updated_at":    time.Now(),
+	}).Error
+}
+
+// Delete removes an idempotency key so the request can be tried again
+func (r *IdempotencyRepository) Delete(id uuid.UUID) error {
+	return r.db.Delete(&models.IdempotencyKey{}, "id = ?", id).Error
+}
+
+// DeleteStale removes an idempotency key still in progress since before
+// startedBefore. It returns false when the key was completed or renewed in
+// the meantime.
+func (r *IdempotencyRepository) DeleteStale(id uuid.UUID, startedBefore time.Time) (bool, error) {
+	result := r.db.Where("id = ? AND status = ? AND created_at <= ?", id, models.IdempotencyStatusInProgress, startedBefore).
+		Delete(&models.IdempotencyKey{})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to delete stale idempotency key: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteExpired removes idempotency keys whose retention window has passed
+func (r *IdempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= ?", now).Delete(&models.IdempotencyKey{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
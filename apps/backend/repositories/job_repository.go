@@ -0,0 +1,157 @@
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type JobRepository struct {
+	db *gorm.DB
+}
+
+func NewJobRepository(db *gorm.DB) *JobRepository {
+	return &JobRepository{
+		db: db,
+	}
+}
+
+// Enqueue stores a job. A job whose dedupe key matches a queued or running job
+// is dropped; the returned bool reports whether the job was stored.
+func (r *JobRepository) Enqueue(tx *gorm.DB, job *models.Job) (bool, error) {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(job)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to enqueue job: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Claim locks up to limit runnable jobs for a worker. Jobs that are due, and
+// running jobs whose visibility timeout lapsed, are both runnable. Rows locked
+// by other workers are skipped rather than waited on.
+func (r *JobRepository) Claim(workerID string, types []string, visibilityTimeout time.Duration, limit int) ([]*models.Job, error) {
+	var jobs []*models.Job
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("type IN ?", types).
+			Where("(status = ? AND run_at <= ?) OR (status = ? AND locked_until <= ?)",
+				models.JobStatusQueued, now, models.JobStatusRunning, now).
+			Order("run_at ASC").
+			Limit(limit).
+			Find(&jobs).Error; err != nil {
+			return err
+		}
+		if len(jobs) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, len(jobs))
+		for i, job := range jobs {
+			ids[i] = job.ID
+		}
+
+		lockedUntil := now.Add(visibilityTimeout)
+		if err := tx.Model(&models.Job{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"status":       models.JobStatusRunning,
+			"attempts":     gorm.Expr("attempts + 1"),
+			"locked_by":    workerID,
+			"locked_until": lockedUntil,
+			"updated_at":   now,
+		}).Error; err != nil {
+			return err
+		}
+
+		for _, job := range jobs {
+			job.Status = models.JobStatusRunning
+			job.Attempts++
+			job.LockedBy = workerID
+			job.LockedUntil = &lockedUntil
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// Complete marks a claimed job as succeeded
+func (r *JobRepository) Complete(job *models.Job) error {
+	now := time.Now()
+	return r.release(job, map[string]interface{}{
+		"status":       models.JobStatusSucceeded,
+		"completed_at": now,
+		"last_error":   "",
+	})
+}
+
+// Retry puts a claimed job back in the queue to run again at runAt
+func (r *JobRepository) Retry(job *models.Job, runAt time.Time, lastError string) error {
+	return r.release(job, map[string]interface{}{
+		"status":     models.JobStatusQueued,
+		"run_at":     runAt,
+		"last_error": lastError,
+	})
+}
+
+// Reschedule puts a claimed job back in the queue without spending an attempt
+func (r *JobRepository) Reschedule(job *models.Job, runAt time.Time) error {
+	return r.release(job, map[string]interface{}{
+		"status":   models.JobStatusQueued,
+		"run_at":   runAt,
+		"attempts": gorm.Expr("attempts - 1"),
+	})
+}
+
+// Kill moves a claimed job to the dead-letter state
+func (r *JobRepository) Kill(job *models.Job, lastError string) error {
+	now := time.Now()
+	return r.release(job, map[string]interface{}{
+		"status":       models.JobStatusDead,
+		"completed_at": now,
+		"last_error":   lastError,
+	})
+}
+
+// release applies updates to a job and drops the worker's lock. Updates from a
+// worker whose lock was taken over after the visibility timeout are ignored.
+func (r *JobRepository) release(job *models.Job, updates map[string]interface{}) error {
+	updates["locked_by"] = ""
+	updates["locked_until"] = nil
+	updates["updated_at"] = time.Now()
+
+	result := r.db.Model(&models.Job{}).
+		Where("id = ? AND status = ? AND locked_by = ?", job.ID, models.JobStatusRunning, job.LockedBy).
+		Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update job: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("job %s is no longer held by %s", job.ID, job.LockedBy)
+	}
+	return nil
+}
+
+// GetDeadJobs retrieves dead-lettered jobs, newest first
+func (r *JobRepository) GetDeadJobs(limit int) ([]*models.Job, error) {
+	var jobs []*models.Job
+	if err := r.db.Where("status = ?", models.JobStatusDead).
+		Order("completed_at DESC").
+		Limit(limit).
+		Find(&jobs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get dead jobs: %w", err)
+	}
+	return jobs, nil
+}
@@ -0,0 +1,181 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LedgerRepository struct {
+	db *gorm.DB
+}
+
+func NewLedgerRepository(db *gorm.DB) *LedgerRepository {
+	return &LedgerRepository{
+		db: db,
+	}
+}
+
+// CreateAccount creates a ledger account, ignoring conflicts on the account code
+func (r *LedgerRepository) CreateAccount(tx *gorm.DB, account *models.LedgerAccount) error {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(account).Error; err != nil {
+		return fmt.Errorf("failed to create ledger account: %w", err)
+	}
+	return nil
+}
+
+// GetAccountByCode retrieves a ledger account by its code
+func (r *LedgerRepository) GetAccountByCode(tx *gorm.DB, code string) (*models.LedgerAccount, error) {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	var account models.LedgerAccount
+	if err := db.Where("code = ?", code).First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("ledger account not found")
+		}
+		return nil, fmt.Errorf("failed to get ledger account: %w", err)
+	}
+	return &account, nil
+}
+
+// GetAccountByWalletID retrieves the ledger account backing a wallet
+func (r *LedgerRepository) GetAccountByWalletID(tx *gorm.DB, walletID uuid.UUID) (*models.LedgerAccount, error) {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	var account models.LedgerAccount
+	if err := db.Where("wallet_id = ?", walletID).First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("ledger account not found")
+		}
+		return nil, fmt.Errorf("failed to get wallet ledger account: %w", err)
+	}
+	return &account, nil
+}
+
+// GetAccountsByIDs retrieves ledger accounts keyed by ID
+func (r *LedgerRepository) GetAccountsByIDs(tx *gorm.DB, ids []uuid.UUID) (map[uuid.UUID]*models.LedgerAccount, error) {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	var accounts []*models.LedgerAccount
+	if err := db.Where("id IN ?", ids).Find(&accounts).Error; err != nil {
+		return nil, fmt.Errorf("failed to get ledger accounts: %w", err)
+	}
+
+	result := make(map[uuid.UUID]*models.LedgerAccount, len(accounts))
+	for _, account := range accounts {
+		result[account.ID] = account
+	}
+	return result, nil
+}
+
+// GetWalletAccounts retrieves all wallet-backed ledger accounts
+func (r *LedgerRepository) GetWalletAccounts() ([]*models.LedgerAccount, error) {
+	var accounts []*models.LedgerAccount
+	if err := r.db.Where("wallet_id IS NOT NULL").Find(&accounts).Error; err != nil {
+		return nil, fmt.Errorf("failed to get wallet ledger accounts: %w", err)
+	}
+	return accounts, nil
+}
+
+// CreateEntry creates a journal entry together with its postings
+func (r *LedgerRepository) CreateEntry(tx *gorm.DB, entry *models.JournalEntry) error {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	if err := db.Create(entry).Error; err != nil {
+		return fmt.Errorf("failed to create journal entry: %w", err)
+	}
+	return nil
+}
+
+// GetEntryByReferenceID retrieves a journal entry by reference ID
+func (r *LedgerRepository) GetEntryByReferenceID(tx *gorm.DB, referenceID string) (*models.JournalEntry, error) {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	var entry models.JournalEntry
+	if err := db.Preload("Postings").Where("reference_id = ?", referenceID).First(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("journal entry not found")
+		}
+		return nil, fmt.Errorf("failed to get journal entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// GetAccountPostingSum returns the raw sum of all postings against an account
+func (r *LedgerRepository) GetAccountPostingSum(tx *gorm.DB, accountID uuid.UUID) (decimal.Decimal, error) {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	var result struct {
+		Total decimal.Decimal
+	}
+	err := db.Model(&models.Posting{}).
+		Select("COALESCE(SUM(amount), 0) as total").
+		Where("account_id = ?", accountID).
+		Scan(&result).Error
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to sum ledger postings: %w", err)
+	}
+	return result.Total, nil
+}
+
+// GetEntriesByAccount retrieves the journal entries that touch an account
+func (r *LedgerRepository) GetEntriesByAccount(accountID uuid.UUID, limit, offset int) ([]*models.JournalEntry, int64, error) {
+	var entries []*models.JournalEntry
+	var total int64
+
+	query := r.db.Model(&models.JournalEntry{}).
+		Where("id IN (?)", r.db.Model(&models.Posting{}).Select("journal_entry_id").Where("account_id = ?", accountID))
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count journal entries: %w", err)
+	}
+
+	err := query.Preload("Postings").Preload("Postings.Account").
+		Order("created_at DESC").
+		Limit(limit).Offset(offset).
+		Find(&entries).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get journal entries: %w", err)
+	}
+
+	return entries, total, nil
+}
+
+// GetTrialBalance returns the raw posting sum of every account, which must net to zero
+func (r *LedgerRepository) GetTrialBalance() (decimal.Decimal, error) {
+	var result struct {
+		Total decimal.Decimal
+	}
+	if err := r.db.Model(&models.Posting{}).Select("COALESCE(SUM(amount), 0) as total").Scan(&result).Error; err != nil {
+		return decimal.Zero, fmt.Errorf("failed to compute trial balance: %w", err)
+	}
+	return result.Total, nil
+}
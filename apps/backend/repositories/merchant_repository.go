@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models"
+	"gorm.io/gorm"
+)
+
+type MerchantRepository struct {
+	db *gorm.DB
+}
+
+func NewMerchantRepository(db *gorm.DB) *MerchantRepository {
+	return &MerchantRepository{
+		db: db,
+	}
+}
+
+// Save creates or updates a merchant
+func (r *MerchantRepository) Save(merchant *models.Merchant) error {
+	if err := r.db.Save(merchant).Error; err != nil {
+		return fmt.Errorf("failed to save merchant: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a merchant
+func (r *MerchantRepository) GetByID(id uuid.UUID) (*models.Merchant, error) {
+	var merchant models.Merchant
+	if err := r.db.Where("id = ?", id).First(&merchant).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("merchant not found")
+		}
+		return nil, fmt.Errorf("failed to get merchant: %w", err)
+	}
+	return &merchant, nil
+}
+
+// FindActiveByName finds an active merchant whose name or one of whose
+// aliases matches, ignoring case
+func (r *MerchantRepository) FindActiveByName(name string) (*models.Merchant, error) {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	alias, _ := json.Marshal([]string{normalized})
+
+	var merchant models.Merchant
+	err := r.db.Where("is_active = ? AND (normalized_name = ? OR aliases @> ?::jsonb)", true, normalized, string(alias)).
+		Order("created_at ASC").
+		First(&merchant).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("merchant not found")
+		}
+		return nil, fmt.Errorf("failed to find merchant: %w", err)
+	}
+	return &merchant, nil
+}
+
+// GetAll lists merchants
+func (r *MerchantRepository) GetAll(limit, offset int) ([]*models.Merchant, int64, error) {
+	var merchants []*models.Merchant
+	var total int64
+
+	if err := r.db.Model(&models.Merchant{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count merchants: %w", err)
+	}
+
+	if err := r.db.Order("name ASC").Limit(limit).Offset(offset).Find(&merchants).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get merchants: %w", err)
+	}
+	return merchants, total, nil
+}
@@ -0,0 +1,197 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrBatchRowStatusChanged is returned when a payout batch row is no longer in
+// a status a transition expected
+var ErrBatchRowStatusChanged = errors.New("payout batch row status has changed")
+
+// BatchRowTotal is the number and amount of a batch's rows in one status
+type BatchRowTotal struct {
+	Status string
+	Count  int
+	Amount decimal.Decimal
+}
+
+type PayoutBatchRepository struct {
+	db *gorm.DB
+}
+
+func NewPayoutBatchRepository(db *gorm.DB) *PayoutBatchRepository {
+	return &PayoutBatchRepository{
+		db: db,
+	}
+}
+
+// Create creates a batch with its rows
+func (r *PayoutBatchRepository) Create(batch *models.PayoutBatch, rows []*models.PayoutBatchRow) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(batch).Error; err != nil {
+			return fmt.Errorf("failed to create payout batch: %w", err)
+		}
+		for _, row := range rows {
+			row.BatchID = batch.ID
+		}
+		if err := tx.CreateInBatches(rows, 100).Error; err != nil {
+			return fmt.Errorf("failed to create payout batch rows: %w", err)
+		}
+		return nil
+	})
+}
+
+// Update saves all fields of a batch
+func (r *PayoutBatchRepository) Update(tx *gorm.DB, batch *models.PayoutBatch) error {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	if err := db.Save(batch).Error; err != nil {
+		return fmt.Errorf("failed to update payout batch: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a batch
+func (r *PayoutBatchRepository) GetByID(id uuid.UUID) (*models.PayoutBatch, error) {
+	var batch models.PayoutBatch
+	if err := r.db.Where("id = ?", id).First(&batch).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("payout batch not found")
+		}
+		return nil, fmt.Errorf("failed to get payout batch: %w", err)
+	}
+	return &batch, nil
+}
+
+// GetByIDForUpdate retrieves a batch and locks its row until tx ends
+func (r *PayoutBatchRepository) GetByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*models.PayoutBatch, error) {
+	var batch models.PayoutBatch
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&batch).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("payout batch not found")
+		}
+		return nil, fmt.Errorf("failed to get payout batch: %w", err)
+	}
+	return &batch, nil
+}
+
+// GetByIDAndUserID retrieves one of a user's batches
+func (r *PayoutBatchRepository) GetByIDAndUserID(id, userID uuid.UUID) (*models.PayoutBatch, error) {
+	var batch models.PayoutBatch
+	if err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&batch).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("payout batch not found")
+		}
+		return nil, fmt.Errorf("failed to get payout batch: %w", err)
+	}
+	return &batch, nil
+}
+
+// GetByUserID retrieves a user's batches newest first
+func (r *PayoutBatchRepository) GetByUserID(userID uuid.UUID, limit, offset int) ([]*models.PayoutBatch, int64, error) {
+	var batches []*models.PayoutBatch
+	var total int64
+
+	query := r.db.Model(&models.PayoutBatch{}).Where("user_id = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count payout batches: %w", err)
+	}
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&batches).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get payout batches: %w", err)
+	}
+
+	return batches, total, nil
+}
+
+// GetRows retrieves a batch's rows in file order, optionally only those in
+// one status. A limit of 0 returns every row.
+func (r *PayoutBatchRepository) GetRows(batchID uuid.UUID, status string, limit, offset int) ([]*models.PayoutBatchRow, int64, error) {
+	var rows []*models.PayoutBatchRow
+	var total int64
+
+	query := r.db.Model(&models.PayoutBatchRow{}).Where("batch_id = ?", batchID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count payout batch rows: %w", err)
+	}
+
+	query = query.Order("row_number ASC")
+	if limit > 0 {
+		query = query.Limit(limit).Offset(offset)
+	}
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get payout batch rows: %w", err)
+	}
+
+	return rows, total, nil
+}
+
+// GetRowByTransferID retrieves the batch row that made a transfer
+func (r *PayoutBatchRepository) GetRowByTransferID(transferID uuid.UUID) (*models.PayoutBatchRow, error) {
+	var row models.PayoutBatchRow
+	if err := r.db.Where("transfer_id = ?", transferID).First(&row).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("payout batch row not found")
+		}
+		return nil, fmt.Errorf("failed to get payout batch row: %w", err)
+	}
+	return &row, nil
+}
+
+// TransitionRow moves a row to a new status if it is still in one of the from statuses
+func (r *PayoutBatchRepository) TransitionRow(id uuid.UUID, from []string, to string) error {
+	result := r.db.Model(&models.PayoutBatchRow{}).Where("id = ? AND status IN ?", id, from).Update("status", to)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update payout batch row status: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrBatchRowStatusChanged
+	}
+	return nil
+}
+
+// TransitionRows moves all of a batch's rows in one status to another
+func (r *PayoutBatchRepository) TransitionRows(tx *gorm.DB, batchID uuid.UUID, from, to string) error {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	if err := db.Model(&models.PayoutBatchRow{}).Where("batch_id = ? AND status = ?", batchID, from).Update("status", to).Error; err != nil {
+		return fmt.Errorf("failed to update payout batch rows: %w", err)
+	}
+	return nil
+}
+
+// UpdateRow saves all fields of a row
+func (r *PayoutBatchRepository) UpdateRow(row *models.PayoutBatchRow) error {
+	if err := r.db.Save(row).Error; err != nil {
+		return fmt.Errorf("failed to update payout batch row: %w", err)
+	}
+	return nil
+}
+
+// GetRowTotals counts a batch's rows and sums their amounts by status
+func (r *PayoutBatchRepository) GetRowTotals(batchID uuid.UUID) ([]BatchRowTotal, error) {
+	var totals []BatchRowTotal
+	if err := r.db.Model(&models.PayoutBatchRow{}).
+		Select("status, COUNT(*) AS count, COALESCE(SUM(amount), 0) AS amount").
+		Where("batch_id = ?", batchID).
+		Group("status").
+		Scan(&totals).Error; err != nil {
+		return nil, fmt.Errorf("failed to get payout batch totals: %w", err)
+	}
+	return totals, nil
+}
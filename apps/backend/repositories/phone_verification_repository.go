@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models"
+	"gorm.io/gorm"
+)
+
+// PhoneVerificationRepository handles phone verification database operations
+type PhoneVerificationRepository struct {
+	db *gorm.DB
+}
+
+// NewPhoneVerificationRepository creates a new phone verification repository
+func NewPhoneVerificationRepository(db *gorm.DB) *PhoneVerificationRepository {
+	return &PhoneVerificationRepository{db: db}
+}
+
+// CreateVerification creates a new phone verification record
+func (r *PhoneVerificationRepository) CreateVerification(ctx context.Context, verification *models.PhoneVerification) error {
+	return r.db.WithContext(ctx).Create(verification).Error
+}
+
+// GetVerificationByUserID retrieves a user's pending phone verification
+func (r *PhoneVerificationRepository) GetVerificationByUserID(ctx context.Context, userID uuid.UUID) (*models.PhoneVerification, error) {
+	var verification models.PhoneVerification
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&verification).Error; err != nil {
+		return nil, err
+	}
+	return &verification, nil
+}
+
+// DeleteVerification removes a user's pending phone verification
+func (r *PhoneVerificationRepository) DeleteVerification(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.PhoneVerification{}).Error
+}
+
+// IncrementAttempts counts a wrong code against a user's phone verification
+func (r *PhoneVerificationRepository) IncrementAttempts(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&models.PhoneVerification{}).
+		Where("user_id = ?", userID).
+		UpdateColumn("attempts", gorm.Expr("attempts + ?", 1)).Error
+}
@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models"
+	"gorm.io/gorm"
+)
+
+// ErrPhoneVPANotFound is returned when a user has no UPI ID saved for a phone number
+var ErrPhoneVPANotFound = errors.New("phone UPI ID not found")
+
+type PhoneVPARepository struct {
+	db *gorm.DB
+}
+
+func NewPhoneVPARepository(db *gorm.DB) *PhoneVPARepository {
+	return &PhoneVPARepository{
+		db: db,
+	}
+}
+
+// Create saves a UPI ID for a phone number
+func (r *PhoneVPARepository) Create(mapping *models.PhoneVPA) error {
+	if err := r.db.Create(mapping).Error; err != nil {
+		return fmt.Errorf("failed to create phone UPI ID: %w", err)
+	}
+	return nil
+}
+
+// Update saves all fields of a phone UPI ID
+func (r *PhoneVPARepository) Update(mapping *models.PhoneVPA) error {
+	if err := r.db.Save(mapping).Error; err != nil {
+		return fmt.Errorf("failed to update phone UPI ID: %w", err)
+	}
+	return nil
+}
+
+// GetByPhone retrieves the UPI ID a user saved for a phone number
+func (r *PhoneVPARepository) GetByPhone(userID uuid.UUID, phone string) (*models.PhoneVPA, error) {
+	var mapping models.PhoneVPA
+	if err := r.db.Where("user_id = ? AND phone = ?", userID, phone).First(&mapping).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPhoneVPANotFound
+		}
+		return nil, fmt.Errorf("failed to get phone UPI ID: %w", err)
+	}
+	return &mapping, nil
+}
+
+// GetByUserID retrieves all of a user's saved phone UPI IDs
+func (r *PhoneVPARepository) GetByUserID(userID uuid.UUID) ([]*models.PhoneVPA, error) {
+	var mappings []*models.PhoneVPA
+	if err := r.db.Where("user_id = ?", userID).Order("phone ASC").Find(&mappings).Error; err != nil {
+		return nil, fmt.Errorf("failed to get phone UPI IDs: %w", err)
+	}
+	return mappings, nil
+}
+
+// Delete removes one of a user's saved phone UPI IDs
+func (r *PhoneVPARepository) Delete(id, userID uuid.UUID) error {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.PhoneVPA{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete phone UPI ID: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrPhoneVPANotFound
+	}
+	return nil
+}
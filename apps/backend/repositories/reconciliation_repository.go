@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models"
+	"gorm.io/gorm"
+)
+
+type ReconciliationRepository struct {
+	db *gorm.DB
+}
+
+func NewReconciliationRepository(db *gorm.DB) *ReconciliationRepository {
+	return &ReconciliationRepository{
+		db: db,
+	}
+}
+
+// CreateRun creates a reconciliation run
+func (r *ReconciliationRepository) CreateRun(run *models.ReconciliationRun) error {
+	if err := r.db.Omit("Mismatches").Create(run).Error; err != nil {
+		return fmt.Errorf("failed to create reconciliation run: %w", err)
+	}
+	return nil
+}
+
+// CompleteRun stores the results of a run and its mismatches together
+func (r *ReconciliationRepository) CompleteRun(run *models.ReconciliationRun, mismatches []*models.ReconciliationMismatch) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, mismatch := range mismatches {
+			mismatch.RunID = run.ID
+		}
+		if len(mismatches) > 0 {
+			if err := tx.CreateInBatches(mismatches, 100).Error; err != nil {
+				return fmt.Errorf("failed to save reconciliation mismatches: %w", err)
+			}
+		}
+
+		if err := tx.Omit("Mismatches").Save(run).Error; err != nil {
+			return fmt.Errorf("failed to update reconciliation run: %w", err)
+		}
+		return nil
+	})
+}
+
+// UpdateRun saves a run without touching its mismatches
+func (r *ReconciliationRepository) UpdateRun(run *models.ReconciliationRun) error {
+	if err := r.db.Omit("Mismatches").Save(run).Error; err != nil {
+		return fmt.Errorf("failed to update reconciliation run: %w", err)
+	}
+	return nil
+}
+
+// GetRun retrieves a run with its mismatches
+func (r *ReconciliationRepository) GetRun(id uuid.UUID) (*models.ReconciliationRun, error) {
+	var run models.ReconciliationRun
+	if err := r.db.Preload("Mismatches", func(db *gorm.DB) *gorm.DB {
+		return db.Order("source ASC, kind ASC, created_at ASC")
+	}).Where("id = ?", id).First(&run).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("reconciliation run not found")
+		}
+		return nil, fmt.Errorf("failed to get reconciliation run: %w", err)
+	}
+	return &run, nil
+}
+
+// GetRuns retrieves runs, newest first
+func (r *ReconciliationRepository) GetRuns(limit, offset int) ([]*models.ReconciliationRun, int64, error) {
+	var runs []*models.ReconciliationRun
+	var total int64
+
+	if err := r.db.Model(&models.ReconciliationRun{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count reconciliation runs: %w", err)
+	}
+
+	if err := r.db.Order("started_at DESC").Limit(limit).Offset(offset).Find(&runs).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get reconciliation runs: %w", err)
+	}
+	return runs, total, nil
+}
+
+// HasCompletedRun reports whether a period has already been reconciled successfully
+func (r *ReconciliationRepository) HasCompletedRun(periodStart, periodEnd time.Time) (bool, error) {
+	var count int64
+	if err := r.db.Model(&models.ReconciliationRun{}).
+		Where("period_start = ? AND period_end = ? AND status = ?", periodStart, periodEnd, models.ReconciliationStatusCompleted).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check reconciliation runs: %w", err)
+	}
+	return count > 0, nil
+}
+
+// GetUnresolvedMismatches retrieves mismatches still awaiting review, oldest first
+func (r *ReconciliationRepository) GetUnresolvedMismatches(limit, offset int) ([]*models.ReconciliationMismatch, int64, error) {
+	var mismatches []*models.ReconciliationMismatch
+	var total int64
+
+	query := r.db.Model(&models.ReconciliationMismatch{}).Where("resolved = ?", false)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count reconciliation mismatches: %w", err)
+	}
+
+	if err := query.Order("created_at ASC").Limit(limit).Offset(offset).Find(&mismatches).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get reconciliation mismatches: %w", err)
+	}
+	return mismatches, total, nil
+}
+
+// ResolveMismatch marks a mismatch as reviewed
+func (r *ReconciliationRepository) ResolveMismatch(id uuid.UUID, note string) error {
+	now := time.Now()
+	result := r.db.Model(&models.ReconciliationMismatch{}).
+		Where("id = ? AND resolved = ?", id, false).
+		Updates(map[string]interface{}{
+			"resolved":        true,
+			"resolved_at":     now,
+			"resolution_note": note,
+			"updated_at":      now,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to resolve reconciliation mismatch: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("reconciliation mismatch not found or already resolved")
+	}
+	return nil
+}
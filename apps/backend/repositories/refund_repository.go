@@ -0,0 +1,186 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"gorm.io/gorm"
+)
+
+type RefundRepository struct {
+	db *gorm.DB
+}
+
+func NewRefundRepository(db *gorm.DB) *RefundRepository {
+	return &RefundRepository{
+		db: db,
+	}
+}
+
+// Create creates a refund and records its first status
+func (r *RefundRepository) Create(tx *gorm.DB, refund *models.Refund, source, note string) error {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	if err := db.Omit("History").Create(refund).Error; err != nil {
+		return fmt.Errorf("failed to create refund: %w", err)
+	}
+	return r.addEvent(db, refund.ID, "", refund.Status, source, note)
+}
+
+// GetByID retrieves a refund with its status history
+func (r *RefundRepository) GetByID(id uuid.UUID) (*models.Refund, error) {
+	var refund models.Refund
+	if err := r.db.Preload("History", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Where("id = ?", id).First(&refund).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("refund not found")
+		}
+		return nil, fmt.Errorf("failed to get refund: %w", err)
+	}
+	return &refund, nil
+}
+
+// GetByIDAndUserID retrieves a user's refund with its status history
+func (r *RefundRepository) GetByIDAndUserID(id, userID uuid.UUID) (*models.Refund, error) {
+	refund, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if refund.UserID != userID {
+		return nil, errors.New("refund not found")
+	}
+	return refund, nil
+}
+
+// GetByRazorpayRefundID retrieves a refund by its Razorpay refund ID
+func (r *RefundRepository) GetByRazorpayRefundID(razorpayRefundID string) (*models.Refund, error) {
+	var refund models.Refund
+	if err := r.db.Where("razorpay_refund_id = ?", razorpayRefundID).First(&refund).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("refund not found")
+		}
+		return nil, fmt.Errorf("failed to get refund: %w", err)
+	}
+	return &refund, nil
+}
+
+// GetByReferenceID retrieves a refund by reference ID
+func (r *RefundRepository) GetByReferenceID(referenceID string) (*models.Refund, error) {
+	var refund models.Refund
+	if err := r.db.Where("reference_id = ?", referenceID).First(&refund).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("refund not found")
+		}
+		return nil, fmt.Errorf("failed to get refund: %w", err)
+	}
+	return &refund, nil
+}
+
+// GetByUserID retrieves a user's refunds, newest first
+func (r *RefundRepository) GetByUserID(userID uuid.UUID, limit, offset int) ([]*models.Refund, int64, error) {
+	var refunds []*models.Refund
+	var total int64
+
+	query := r.db.Model(&models.Refund{}).Where("user_id = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count refunds: %w", err)
+	}
+
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&refunds).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get refunds: %w", err)
+	}
+	return refunds, total, nil
+}
+
+// GetByKindAndDateRange retrieves all refunds of a kind created in a period
+func (r *RefundRepository) GetByKindAndDateRange(kind string, startDate, endDate time.Time) ([]*models.Refund, error) {
+	var refunds []*models.Refund
+	if err := r.db.Where("kind = ? AND created_at BETWEEN ? AND ?", kind, startDate, endDate).
+		Order("created_at ASC").
+		Find(&refunds).Error; err != nil {
+		return nil, fmt.Errorf("failed to get refunds by date range: %w", err)
+	}
+	return refunds, nil
+}
+
+// GetRefundedTotal sums the refunds of a transaction that have not failed
+func (r *RefundRepository) GetRefundedTotal(tx *gorm.DB, transactionID uuid.UUID) (decimal.Decimal, error) {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	var result struct {
+		Total decimal.Decimal
+	}
+	if err := db.Model(&models.Refund{}).
+		Select("COALESCE(SUM(amount), 0) as total").
+		Where("transaction_id = ? AND status <> ?", transactionID, models.RefundStatusFailed).
+		Scan(&result).Error; err != nil {
+		return decimal.Zero, fmt.Errorf("failed to sum refunds: %w", err)
+	}
+	return result.Total, nil
+}
+
+// SetRazorpayRefundID records the Razorpay refund created for a refund
+func (r *RefundRepository) SetRazorpayRefundID(id uuid.UUID, razorpayRefundID string) error {
+	if err := r.db.Model(&models.Refund{}).Where("id = ?", id).
+		Update("razorpay_refund_id", razorpayRefundID).Error; err != nil {
+		return fmt.Errorf("failed to update refund: %w", err)
+	}
+	return nil
+}
+
+// Transition moves a pending refund to a final status and records the change,
+// failing if the refund has already left the pending status
+func (r *RefundRepository) Transition(tx *gorm.DB, id uuid.UUID, to, source, note string) error {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":     to,
+		"updated_at": now,
+	}
+	switch to {
+	case models.RefundStatusProcessed:
+		updates["processed_at"] = now
+	case models.RefundStatusFailed:
+		updates["failure_reason"] = note
+	}
+
+	result := db.Model(&models.Refund{}).
+		Where("id = ? AND status = ?", id, models.RefundStatusPending).
+		Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update refund: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("refund is no longer pending")
+	}
+	return r.addEvent(db, id, models.RefundStatusPending, to, source, note)
+}
+
+func (r *RefundRepository) addEvent(db *gorm.DB, refundID uuid.UUID, from, to, source, note string) error {
+	event := &models.RefundStatusEvent{
+		RefundID:   refundID,
+		FromStatus: from,
+		ToStatus:   to,
+		Source:     source,
+		Note:       note,
+	}
+	if err := db.Create(event).Error; err != nil {
+		return fmt.Errorf("failed to record refund status: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/pkg/risk"
+	"github.com/zeusnotfound04/Tranza/utils"
+	"gorm.io/gorm"
+)
+
+// outgoingTransactionTypes are the transactions that take money out of a wallet
+var outgoingTransactionTypes = []string{
+	utils.TransactionTypeAIPayment,
+	utils.TransactionTypeExternalTransfer,
+	utils.TransactionTypeWalletTransferOut,
+}
+
+// RiskRepository stores risk assessments and answers the risk engine's
+// questions about a user's payment history
+type RiskRepository struct {
+	db *gorm.DB
+}
+
+func NewRiskRepository(db *gorm.DB) *RiskRepository {
+	return &RiskRepository{
+		db: db,
+	}
+}
+
+// Create records an assessment
+func (r *RiskRepository) Create(assessment *models.RiskAssessment) error {
+	if err := r.db.Create(assessment).Error; err != nil {
+		return fmt.Errorf("failed to create risk assessment: %w", err)
+	}
+	return nil
+}
+
+// LinkSubject points an assessment at the payment that was created after it
+func (r *RiskRepository) LinkSubject(id, subjectID uuid.UUID) error {
+	if err := r.db.Model(&models.RiskAssessment{}).Where("id = ?", id).Update("subject_id", subjectID).Error; err != nil {
+		return fmt.Errorf("failed to link risk assessment: %w", err)
+	}
+	return nil
+}
+
+// MarkConfirmed records that the user confirmed the payment an assessment asked about
+func (r *RiskRepository) MarkConfirmed(tx *gorm.DB, subjectID uuid.UUID) error {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	if err := db.Model(&models.RiskAssessment{}).
+		Where("subject_id = ? AND action = ?", subjectID, risk.ActionConfirm).
+		Update("confirmed", true).Error; err != nil {
+		return fmt.Errorf("failed to confirm risk assessment: %w", err)
+	}
+	return nil
+}
+
+// UseConfirmation marks the assessment that asked the user to confirm a
+// payment as used by that payment. It returns false unless the assessment is
+// the user's, asked about this kind of payment of this amount to this
+// recipient, was made after since, and was not used before.
+func (r *RiskRepository) UseConfirmation(id, userID uuid.UUID, kind string, amount decimal.Decimal, recipient string, since time.Time) (bool, error) {
+	result := r.db.Model(&models.RiskAssessment{}).
+		Where("id = ? AND user_id = ? AND kind = ? AND action = ? AND amount = ? AND recipient = ? AND created_at >= ? AND used_at IS NULL",
+			id, userID, kind, risk.ActionConfirm, amount, recipient, since).
+		Updates(map[string]interface{}{
+			"confirmed": true,
+			"used_at":   time.Now(),
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to use risk confirmation: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetBySubjectID retrieves the assessment of a payment
+func (r *RiskRepository) GetBySubjectID(subjectID uuid.UUID) (*models.RiskAssessment, error) {
+	var assessment models.RiskAssessment
+	if err := r.db.Where("subject_id = ?", subjectID).Order("created_at DESC").First(&assessment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("risk assessment not found")
+		}
+		return nil, fmt.Errorf("failed to get risk assessment: %w", err)
+	}
+	return &assessment, nil
+}
+
+// GetAll retrieves assessments newest first, optionally for one user or action
+func (r *RiskRepository) GetAll(userID *uuid.UUID, action string, limit, offset int) ([]*models.RiskAssessment, int64, error) {
+	var assessments []*models.RiskAssessment
+	var total int64
+
+	query := r.db.Model(&models.RiskAssessment{})
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+	if action != "" {
+		query = query.Where("action = ?", action)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count risk assessments: %w", err)
+	}
+
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&assessments).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get risk assessments: %w", err)
+	}
+	return assessments, total, nil
+}
+
+// Activity counts the user's outgoing payments since the given time, leaving
+// out ones that failed or were cancelled
+func (r *RiskRepository) Activity(ctx context.Context, userID uuid.UUID, since time.Time) (int, decimal.Decimal, error) {
+	var result struct {
+		Count int64
+		Total decimal.Decimal
+	}
+
+	err := r.db.WithContext(ctx).Model(&models.Transaction{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(amount), 0) AS total").
+		Where("user_id = ? AND type IN ? AND created_at >= ?", userID, outgoingTransactionTypes, since).
+		Where("LOWER(status) NOT IN ?", []string{utils.TransactionStatusFailed, utils.TransactionStatusCancelled}).
+		Scan(&result).Error
+	if err != nil {
+		return 0, decimal.Zero, fmt.Errorf("failed to get payment activity: %w", err)
+	}
+	return int(result.Count), result.Total, nil
+}
+
+// HasPaid reports whether a transfer to the recipient, or an AI payment to a
+// merchant of that name, has completed before. A recipient written as
+// @username is a Tranza user paid by wallet transfer.
+func (r *RiskRepository) HasPaid(ctx context.Context, userID uuid.UUID, recipient string) (bool, error) {
+	recipient = strings.ToLower(strings.TrimSpace(recipient))
+
+	var count int64
+	if username, ok := strings.CutPrefix(recipient, "@"); ok {
+		// The credit of a wallet transfer has its debit's reference plus "_IN"
+		if err := r.db.WithContext(ctx).Table("transactions AS sent").
+			Joins("JOIN transactions AS received ON received.reference_id = sent.reference_id || '_IN'").
+			Joins("JOIN users ON users.id = received.user_id").
+			Where("sent.user_id = ? AND sent.type = ? AND LOWER(sent.status) = ? AND LOWER(users.username) = ?",
+				userID, utils.TransactionTypeWalletTransferOut, utils.TransactionStatusSuccess, username).
+			Count(&count).Error; err != nil {
+			return false, fmt.Errorf("failed to check past wallet transfers: %w", err)
+		}
+		return count > 0, nil
+	}
+
+	if err := r.db.WithContext(ctx).Model(&models.ExternalTransfer{}).
+		Where("user_id = ? AND LOWER(recipient_value) = ? AND status = ?", userID, recipient, models.ExternalTransferStatusSuccess).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check past transfers: %w", err)
+	}
+	if count > 0 {
+		return true, nil
+	}
+
+	if err := r.db.WithContext(ctx).Model(&models.AIPaymentRequest{}).
+		Where("user_id = ? AND status = ? AND (LOWER(merchant_name) = ? OR LOWER(merchant_upi_id) = ?)", userID, "processed", recipient, recipient).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check past AI payments: %w", err)
+	}
+	return count > 0, nil
+}
+
+// RecentAmounts returns the amounts of the user's latest successful outgoing payments
+func (r *RiskRepository) RecentAmounts(ctx context.Context, userID uuid.UUID, limit int) ([]decimal.Decimal, error) {
+	var amounts []decimal.Decimal
+	err := r.db.WithContext(ctx).Model(&models.Transaction{}).
+		Where("user_id = ? AND type IN ? AND LOWER(status) = ?", userID, outgoingTransactionTypes, utils.TransactionStatusSuccess).
+		Order("created_at DESC").
+		Limit(limit).
+		Pluck("amount", &amounts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent payment amounts: %w", err)
+	}
+	return amounts, nil
+}
+
+// LastIPAddress returns the IP address recorded on the user's latest transaction
+func (r *RiskRepository) LastIPAddress(ctx context.Context, userID uuid.UUID) (string, error) {
+	var ipAddresses []string
+	err := r.db.WithContext(ctx).Model(&models.Transaction{}).
+		Where("user_id = ? AND ip_address <> ''", userID).
+		Order("created_at DESC").
+		Limit(1).
+		Pluck("ip_address", &ipAddresses).Error
+	if err != nil {
+		return "", fmt.Errorf("failed to get last IP address: %w", err)
+	}
+	if len(ipAddresses) == 0 {
+		return "", nil
+	}
+	return ipAddresses[0], nil
+}
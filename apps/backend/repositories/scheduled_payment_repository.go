@@ -0,0 +1,214 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrRunStatusChanged is returned when a scheduled payment run is no longer in
+// a status a transition expected, e.g. because another worker picked it up
+var ErrRunStatusChanged = errors.New("scheduled payment run status has changed")
+
+// ErrRunNotFound is returned when an occurrence has no run yet
+var ErrRunNotFound = errors.New("scheduled payment run not found")
+
+type ScheduledPaymentRepository struct {
+	db *gorm.DB
+}
+
+func NewScheduledPaymentRepository(db *gorm.DB) *ScheduledPaymentRepository {
+	return &ScheduledPaymentRepository{
+		db: db,
+	}
+}
+
+// Create creates a new scheduled payment
+func (r *ScheduledPaymentRepository) Create(tx *gorm.DB, schedule *models.ScheduledPayment) error {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	if err := db.Create(schedule).Error; err != nil {
+		return fmt.Errorf("failed to create scheduled payment: %w", err)
+	}
+	return nil
+}
+
+// Update saves all fields of a scheduled payment
+func (r *ScheduledPaymentRepository) Update(tx *gorm.DB, schedule *models.ScheduledPayment) error {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	if err := db.Save(schedule).Error; err != nil {
+		return fmt.Errorf("failed to update scheduled payment: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a scheduled payment
+func (r *ScheduledPaymentRepository) GetByID(id uuid.UUID) (*models.ScheduledPayment, error) {
+	var schedule models.ScheduledPayment
+	if err := r.db.Where("id = ?", id).First(&schedule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("scheduled payment not found")
+		}
+		return nil, fmt.Errorf("failed to get scheduled payment: %w", err)
+	}
+	return &schedule, nil
+}
+
+// GetByIDForUpdate retrieves a scheduled payment and locks its row until tx ends
+func (r *ScheduledPaymentRepository) GetByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*models.ScheduledPayment, error) {
+	var schedule models.ScheduledPayment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&schedule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("scheduled payment not found")
+		}
+		return nil, fmt.Errorf("failed to get scheduled payment: %w", err)
+	}
+	return &schedule, nil
+}
+
+// RecordRun stores the outcome of a schedule's latest run
+func (r *ScheduledPaymentRepository) RecordRun(id uuid.UUID, at time.Time, status string) error {
+	if err := r.db.Model(&models.ScheduledPayment{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_run_at":     at,
+		"last_run_status": status,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to record scheduled payment run: %w", err)
+	}
+	return nil
+}
+
+// GetByIDAndUserID retrieves one of a user's scheduled payments
+func (r *ScheduledPaymentRepository) GetByIDAndUserID(id, userID uuid.UUID) (*models.ScheduledPayment, error) {
+	var schedule models.ScheduledPayment
+	if err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&schedule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("scheduled payment not found")
+		}
+		return nil, fmt.Errorf("failed to get scheduled payment: %w", err)
+	}
+	return &schedule, nil
+}
+
+// GetByUserID retrieves a user's scheduled payments by next run, optionally with one status
+func (r *ScheduledPaymentRepository) GetByUserID(userID uuid.UUID, status string, limit, offset int) ([]*models.ScheduledPayment, int64, error) {
+	var schedules []*models.ScheduledPayment
+	var total int64
+
+	query := r.db.Model(&models.ScheduledPayment{}).Where("user_id = ?", userID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count scheduled payments: %w", err)
+	}
+	if err := query.Order("next_run_at ASC NULLS LAST, created_at DESC").Limit(limit).Offset(offset).Find(&schedules).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get scheduled payments: %w", err)
+	}
+
+	return schedules, total, nil
+}
+
+// GetActive retrieves every active scheduled payment
+func (r *ScheduledPaymentRepository) GetActive() ([]*models.ScheduledPayment, error) {
+	var schedules []*models.ScheduledPayment
+	if err := r.db.Where("status = ?", models.ScheduledPaymentStatusActive).Find(&schedules).Error; err != nil {
+		return nil, fmt.Errorf("failed to get active scheduled payments: %w", err)
+	}
+	return schedules, nil
+}
+
+// CreateRun creates the run for an occurrence
+func (r *ScheduledPaymentRepository) CreateRun(tx *gorm.DB, run *models.ScheduledPaymentRun) error {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	if err := db.Create(run).Error; err != nil {
+		return fmt.Errorf("failed to create scheduled payment run: %w", err)
+	}
+	return nil
+}
+
+// GetRun retrieves the run for an occurrence
+func (r *ScheduledPaymentRepository) GetRun(tx *gorm.DB, scheduleID uuid.UUID, scheduledFor time.Time) (*models.ScheduledPaymentRun, error) {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	var run models.ScheduledPaymentRun
+	if err := db.Where("schedule_id = ? AND scheduled_for = ?", scheduleID, scheduledFor).First(&run).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRunNotFound
+		}
+		return nil, fmt.Errorf("failed to get scheduled payment run: %w", err)
+	}
+	return &run, nil
+}
+
+// GetRunByTransferID retrieves the run that made a transfer
+func (r *ScheduledPaymentRepository) GetRunByTransferID(transferID uuid.UUID) (*models.ScheduledPaymentRun, error) {
+	var run models.ScheduledPaymentRun
+	if err := r.db.Where("transfer_id = ?", transferID).First(&run).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRunNotFound
+		}
+		return nil, fmt.Errorf("failed to get scheduled payment run: %w", err)
+	}
+	return &run, nil
+}
+
+// TransitionRun moves a run to a new status if it is still in one of the expected ones
+func (r *ScheduledPaymentRepository) TransitionRun(id uuid.UUID, from []string, to string) error {
+	result := r.db.Model(&models.ScheduledPaymentRun{}).Where("id = ? AND status IN ?", id, from).Update("status", to)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update scheduled payment run status: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrRunStatusChanged
+	}
+	return nil
+}
+
+// UpdateRun saves all fields of a run
+func (r *ScheduledPaymentRepository) UpdateRun(tx *gorm.DB, run *models.ScheduledPaymentRun) error {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	if err := db.Save(run).Error; err != nil {
+		return fmt.Errorf("failed to update scheduled payment run: %w", err)
+	}
+	return nil
+}
+
+// GetRuns retrieves a scheduled payment's runs newest first
+func (r *ScheduledPaymentRepository) GetRuns(scheduleID uuid.UUID, limit, offset int) ([]*models.ScheduledPaymentRun, int64, error) {
+	var runs []*models.ScheduledPaymentRun
+	var total int64
+
+	query := r.db.Model(&models.ScheduledPaymentRun{}).Where("schedule_id = ?", scheduleID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count scheduled payment runs: %w", err)
+	}
+	if err := query.Order("scheduled_for DESC").Limit(limit).Offset(offset).Find(&runs).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get scheduled payment runs: %w", err)
+	}
+
+	return runs, total, nil
+}
@@ -3,12 +3,14 @@ package repositories
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"github.com/zeusnotfound04/Tranza/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TransactionRepository struct {
@@ -61,6 +63,23 @@ func (r *TransactionRepository) GetByIDAndUserID(id, userID uuid.UUID) (*models.
 	return &transaction, nil
 }
 
+// GetByIDForUpdate retrieves a transaction by ID and locks the row for the rest of the transaction
+func (r *TransactionRepository) GetByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*models.Transaction, error) {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	var transaction models.Transaction
+	if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&transaction).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("transaction not found")
+		}
+		return nil, fmt.Errorf("failed to lock transaction: %w", err)
+	}
+	return &transaction, nil
+}
+
 // GetByOrderID retrieves a transaction by Razorpay order ID
 func (r *TransactionRepository) GetByOrderID(orderID string) (*models.Transaction, error) {
 	var transaction models.Transaction
@@ -209,6 +228,17 @@ func (r *TransactionRepository) GetByWalletIDWithDateRange(walletID uuid.UUID, s
 	return transactions, nil
 }
 
+// GetByTypeAndDateRange retrieves all transactions of a type created in a period
+func (r *TransactionRepository) GetByTypeAndDateRange(transactionType string, startDate, endDate time.Time) ([]*models.Transaction, error) {
+	var transactions []*models.Transaction
+	if err := r.db.Where("type = ? AND created_at BETWEEN ? AND ?", transactionType, startDate, endDate).
+		Order("created_at ASC").
+		Find(&transactions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get transactions by date range: %w", err)
+	}
+	return transactions, nil
+}
+
 // GetSuccessfulTransactionsByWalletID retrieves only successful transactions
 func (r *TransactionRepository) GetSuccessfulTransactionsByWalletID(walletID uuid.UUID) ([]*models.Transaction, error) {
 	var transactions []*models.Transaction
@@ -301,6 +331,34 @@ func (r *TransactionRepository) GetAITransactionCount(walletID uuid.UUID, date s
 	return count, nil
 }
 
+// GetDebitTotalSince sums outgoing transactions of the given types since a point in time,
+// ignoring failed and cancelled ones
+func (r *TransactionRepository) GetDebitTotalSince(walletID uuid.UUID, types []string, since time.Time) (decimal.Decimal, error) {
+	return r.GetDebitTotalSinceWithTx(nil, walletID, types, since)
+}
+
+// GetDebitTotalSinceWithTx is GetDebitTotalSince inside the caller's transaction
+func (r *TransactionRepository) GetDebitTotalSinceWithTx(tx *gorm.DB, walletID uuid.UUID, types []string, since time.Time) (decimal.Decimal, error) {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	var result struct {
+		Total decimal.Decimal
+	}
+
+	if err := db.Model(&models.Transaction{}).
+		Select("COALESCE(SUM(amount), 0) as total").
+		Where("wallet_id = ? AND type IN ? AND LOWER(status) NOT IN ? AND created_at >= ?",
+			walletID, types, []string{"failed", "cancelled"}, since).
+		Scan(&result).Error; err != nil {
+		return decimal.Zero, fmt.Errorf("failed to calculate debit total: %w", err)
+	}
+
+	return result.Total, nil
+}
+
 // GetTransactionStats retrieves transaction statistics for a user
 func (r *TransactionRepository) GetTransactionStats(userID uuid.UUID) (*TransactionStats, error) {
 	var stats TransactionStats
@@ -408,6 +466,15 @@ func (r *TransactionRepository) GetPendingTransactions(olderThan time.Duration)
 
 // UpdateStatus updates transaction status
 func (r *TransactionRepository) UpdateStatus(id uuid.UUID, status string, failureReason string) error {
+	return r.UpdateStatusWithTx(nil, id, status, failureReason)
+}
+
+// UpdateStatusWithTx is UpdateStatus inside the caller's transaction
+func (r *TransactionRepository) UpdateStatusWithTx(tx *gorm.DB, id uuid.UUID, status string, failureReason string) error {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
 	updates := map[string]interface{}{
 		"status":     status,
 		"updated_at": time.Now(),
@@ -417,7 +484,7 @@ func (r *TransactionRepository) UpdateStatus(id uuid.UUID, status string, failur
 		updates["failure_reason"] = failureReason
 	}
 
-	if err := r.db.Model(&models.Transaction{}).
+	if err := db.Model(&models.Transaction{}).
 		Where("id = ?", id).
 		Updates(updates).Error; err != nil {
 		return fmt.Errorf("failed to update transaction status: %w", err)
@@ -500,6 +567,82 @@ func (r *TransactionRepository) GetTransactionSummaryByDateRange(walletID uuid.U
 	return &summary, nil
 }
 
+// SearchDebits returns a user's outgoing transactions matching the query.
+// Failed and cancelled transactions are left out.
+func (r *TransactionRepository) SearchDebits(q HistoryQuery) ([]*models.Transaction, error) {
+	var transactions []*models.Transaction
+	if err := r.debits(q).Order(q.order()).Limit(q.Limit).Find(&transactions).Error; err != nil {
+		return nil, fmt.Errorf("failed to search transactions: %w", err)
+	}
+	return transactions, nil
+}
+
+// SumDebits totals and counts a user's outgoing transactions matching the query
+func (r *TransactionRepository) SumDebits(q HistoryQuery) (decimal.Decimal, int64, error) {
+	var result struct {
+		Total decimal.Decimal
+		Count int64
+	}
+
+	if err := r.debits(q).
+		Select("COALESCE(SUM(amount), 0) as total, COUNT(*) as count").
+		Scan(&result).Error; err != nil {
+		return decimal.Zero, 0, fmt.Errorf("failed to sum transactions: %w", err)
+	}
+
+	return result.Total, result.Count, nil
+}
+
+func (r *TransactionRepository) debits(q HistoryQuery) *gorm.DB {
+	query := r.db.Model(&models.Transaction{}).
+		Where("user_id = ? AND type IN ? AND LOWER(status) NOT IN ? AND created_at >= ? AND created_at < ?",
+			q.UserID, q.Types, []string{"failed", "cancelled"}, q.From, q.To)
+
+	if q.Counterparty != "" {
+		pattern := likePattern(q.Counterparty)
+		query = query.Where("(LOWER(merchant_name) LIKE ? OR LOWER(merchant_upi_id) LIKE ? OR LOWER(description) LIKE ?)",
+			pattern, pattern, pattern)
+	}
+	return query
+}
+
+// History sort orders
+const (
+	HistorySortNewest   = "newest"
+	HistorySortLargest  = "largest"
+	HistorySortSmallest = "smallest"
+)
+
+// HistoryQuery selects part of a user's outgoing payment history. Every field
+// is bound as a query parameter; Sort only picks between fixed orderings.
+type HistoryQuery struct {
+	UserID       uuid.UUID
+	Types        []string // Transaction types, ignored for external transfers
+	Counterparty string   // Matched case-insensitively against the payee and description
+	From         time.Time
+	To           time.Time // Exclusive
+	Sort         string
+	Limit        int
+}
+
+func (q HistoryQuery) order() string {
+	switch q.Sort {
+	case HistorySortLargest:
+		return "amount DESC, created_at DESC"
+	case HistorySortSmallest:
+		return "amount ASC, created_at DESC"
+	default:
+		return "created_at DESC"
+	}
+}
+
+// likePattern matches text anywhere in a lower-cased column, treating LIKE
+// wildcards in it literally
+func likePattern(text string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(strings.ToLower(text))
+	return "%" + escaped + "%"
+}
+
 // Helper structs for statistics
 type TransactionStats struct {
 	TotalTransactions   int64           `json:"total_transactions"`
@@ -13,9 +13,13 @@ var db *gorm.DB
 // UserRepository interface defines the contract for user data operations
 type UserRepository interface {
 	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	FindByUsername(ctx context.Context, username string) (*models.User, error)
 	FindByID(ctx context.Context, id uuid.UUID) (*models.User, error)
 	FindByProviderID(ctx context.Context, provider, providerID string) (*models.User, error)
 	Create(ctx context.Context, user *models.User) error
+	UpdateTOTP(ctx context.Context, id uuid.UUID, secret string, enabled bool) error
+	FindByPhone(ctx context.Context, phone string) (*models.User, error)
+	UpdatePhone(ctx context.Context, id uuid.UUID, phone *string) error
 }
 
 // userRepository struct implements UserRepository interface
@@ -55,6 +59,14 @@ func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models
 	return &user, nil
 }
 
+func (r *userRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (r *userRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	var user models.User
 	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&user).Error; err != nil {
@@ -74,3 +86,23 @@ func (r *userRepository) FindByProviderID(ctx context.Context, provider, provide
 func (r *userRepository) Create(ctx context.Context, user *models.User) error {
 	return r.db.WithContext(ctx).Create(user).Error
 }
+
+// UpdateTOTP stores the user's authenticator secret and whether it is in use
+func (r *userRepository) UpdateTOTP(ctx context.Context, id uuid.UUID, secret string, enabled bool) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"totp_secret": secret, "totp_enabled": enabled}).Error
+}
+
+// FindByPhone finds the user who verified a mobile number
+func (r *userRepository) FindByPhone(ctx context.Context, phone string) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Where("phone = ?", phone).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdatePhone stores the user's verified mobile number; nil removes it
+func (r *userRepository) UpdatePhone(ctx context.Context, id uuid.UUID, phone *string) error {
+	return r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("phone", phone).Error
+}
@@ -0,0 +1,215 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrHoldNotFound is returned when no hold exists for a reference
+var ErrHoldNotFound = errors.New("wallet hold not found")
+
+type WalletHoldRepository struct {
+	db *gorm.DB
+}
+
+func NewWalletHoldRepository(db *gorm.DB) *WalletHoldRepository {
+	return &WalletHoldRepository{
+		db: db,
+	}
+}
+
+// Create creates a new wallet hold
+func (r *WalletHoldRepository) Create(tx *gorm.DB, hold *models.WalletHold) error {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	if err := db.Create(hold).Error; err != nil {
+		return fmt.Errorf("failed to create wallet hold: %w", err)
+	}
+	return nil
+}
+
+// GetByReferenceID retrieves a wallet hold by reference ID
+func (r *WalletHoldRepository) GetByReferenceID(tx *gorm.DB, referenceID string) (*models.WalletHold, error) {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	var hold models.WalletHold
+	if err := db.Where("reference_id = ?", referenceID).First(&hold).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrHoldNotFound
+		}
+		return nil, fmt.Errorf("failed to get wallet hold: %w", err)
+	}
+	return &hold, nil
+}
+
+// GetByReferenceIDForUpdate retrieves a wallet hold by reference ID and locks
+// the row for the rest of the transaction
+func (r *WalletHoldRepository) GetByReferenceIDForUpdate(tx *gorm.DB, referenceID string) (*models.WalletHold, error) {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	var hold models.WalletHold
+	if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("reference_id = ?", referenceID).First(&hold).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrHoldNotFound
+		}
+		return nil, fmt.Errorf("failed to lock wallet hold: %w", err)
+	}
+	return &hold, nil
+}
+
+// GetActiveByWalletID retrieves all active holds on a wallet
+func (r *WalletHoldRepository) GetActiveByWalletID(walletID uuid.UUID) ([]*models.WalletHold, error) {
+	var holds []*models.WalletHold
+	if err := r.db.Where("wallet_id = ? AND status = ?", walletID, models.WalletHoldStatusActive).
+		Order("created_at DESC").
+		Find(&holds).Error; err != nil {
+		return nil, fmt.Errorf("failed to get active wallet holds: %w", err)
+	}
+	return holds, nil
+}
+
+// GetActiveTotal sums the active holds on a wallet
+func (r *WalletHoldRepository) GetActiveTotal(tx *gorm.DB, walletID uuid.UUID) (decimal.Decimal, error) {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	var result struct {
+		Total decimal.Decimal
+	}
+	if err := db.Model(&models.WalletHold{}).
+		Select("COALESCE(SUM(amount), 0) as total").
+		Where("wallet_id = ? AND status = ?", walletID, models.WalletHoldStatusActive).
+		Scan(&result).Error; err != nil {
+		return decimal.Zero, fmt.Errorf("failed to sum wallet holds: %w", err)
+	}
+	return result.Total, nil
+}
+
+// GetUnrecordedActiveTotal sums the active holds on a wallet placed since a
+// point in time that have no transaction record yet, such as AI payment
+// requests waiting for confirmation. No purposes means holds of any purpose;
+// a non-empty excludeReference leaves that hold out of the total.
+func (r *WalletHoldRepository) GetUnrecordedActiveTotal(tx *gorm.DB, walletID uuid.UUID, purposes []string, since time.Time, excludeReference string) (decimal.Decimal, error) {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	recorded := r.db.Model(&models.Transaction{}).
+		Select("1").
+		Where("transactions.reference_id = wallet_holds.reference_id")
+
+	query := db.Model(&models.WalletHold{}).
+		Select("COALESCE(SUM(amount), 0) as total").
+		Where("wallet_id = ? AND status = ? AND created_at >= ?", walletID, models.WalletHoldStatusActive, since).
+		Where("NOT EXISTS (?)", recorded)
+	if len(purposes) > 0 {
+		query = query.Where("purpose IN ?", purposes)
+	}
+	if excludeReference != "" {
+		query = query.Where("reference_id <> ?", excludeReference)
+	}
+
+	var result struct {
+		Total decimal.Decimal
+	}
+	if err := query.Scan(&result).Error; err != nil {
+		return decimal.Zero, fmt.Errorf("failed to sum wallet holds: %w", err)
+	}
+	return result.Total, nil
+}
+
+// GetExpired retrieves active holds whose expiry has passed
+func (r *WalletHoldRepository) GetExpired(now time.Time, limit int) ([]*models.WalletHold, error) {
+	var holds []*models.WalletHold
+	// A hold backing a transfer or merchant payout still in flight stays until
+	// the payout settles, however long the bank takes
+	inFlight := r.db.Model(&models.ExternalTransfer{}).
+		Select("1").
+		Where("external_transfers.reference_id = wallet_holds.reference_id AND external_transfers.status IN ?", []string{
+			models.ExternalTransferStatusQueued,
+			models.ExternalTransferStatusPending,
+			models.ExternalTransferStatusProcessing,
+		})
+
+	if err := r.db.Where("status = ? AND expires_at <= ?", models.WalletHoldStatusActive, now).
+		Where("NOT EXISTS (?)", inFlight).
+		Order("expires_at ASC").
+		Limit(limit).
+		Find(&holds).Error; err != nil {
+		return nil, fmt.Errorf("failed to get expired wallet holds: %w", err)
+	}
+	return holds, nil
+}
+
+// SetAmount changes the amount of an active hold
+func (r *WalletHoldRepository) SetAmount(tx *gorm.DB, holdID uuid.UUID, amount decimal.Decimal) error {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	result := db.Model(&models.WalletHold{}).
+		Where("id = ? AND status = ?", holdID, models.WalletHoldStatusActive).
+		Updates(map[string]interface{}{
+			"amount":     amount,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update wallet hold: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("wallet hold is no longer active")
+	}
+	return nil
+}
+
+// Transition moves a hold between statuses, failing if it is no longer in one of the expected statuses
+func (r *WalletHoldRepository) Transition(tx *gorm.DB, holdID uuid.UUID, from []string, to string, reason string) error {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":     to,
+		"updated_at": now,
+	}
+	switch to {
+	case models.WalletHoldStatusCaptured:
+		updates["captured_at"] = now
+	case models.WalletHoldStatusReleased, models.WalletHoldStatusExpired:
+		updates["released_at"] = now
+		updates["release_reason"] = reason
+	}
+
+	result := db.Model(&models.WalletHold{}).
+		Where("id = ? AND status IN ?", holdID, from).
+		Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update wallet hold: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("wallet hold is no longer active")
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/shopspring/decimal"
 	"github.com/zeusnotfound04/Tranza/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type WalletRepository struct {
@@ -64,6 +65,23 @@ func (r *WalletRepository) GetByID(id uuid.UUID) (*models.Wallet, error) {
 	return &wallet, nil
 }
 
+// GetByIDForUpdate retrieves a wallet by ID and locks the row for the rest of the transaction
+func (r *WalletRepository) GetByIDForUpdate(tx *gorm.DB, id uuid.UUID) (*models.Wallet, error) {
+	db := r.db
+	if tx != nil {
+		db = tx
+	}
+
+	var wallet models.Wallet
+	if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&wallet).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("wallet not found")
+		}
+		return nil, fmt.Errorf("failed to lock wallet: %w", err)
+	}
+	return &wallet, nil
+}
+
 // GetByUserID retrieves a wallet by user ID
 func (r *WalletRepository) GetByUserID(userID uuid.UUID) (*models.Wallet, error) {
 	var wallet models.Wallet
@@ -145,8 +163,12 @@ func (r *WalletRepository) IncrementBalance(tx *gorm.DB, walletID uuid.UUID, amo
 		return nil, errors.New("wallet not found")
 	}
 
-	// Return updated wallet
-	return r.GetByID(walletID)
+	// Return updated wallet (read through db so callers inside a transaction see the new balance)
+	var wallet models.Wallet
+	if err := db.Where("id = ?", walletID).First(&wallet).Error; err != nil {
+		return nil, fmt.Errorf("failed to get wallet: %w", err)
+	}
+	return &wallet, nil
 }
 
 // DecrementBalance subtracts amount from wallet balance (atomic operation)
@@ -182,8 +204,12 @@ func (r *WalletRepository) DecrementBalance(tx *gorm.DB, walletID uuid.UUID, amo
 		return nil, errors.New("insufficient balance or wallet not found")
 	}
 
-	// Return updated wallet
-	return r.GetByID(walletID)
+	// Return updated wallet (read through db so callers inside a transaction see the new balance)
+	var wallet models.Wallet
+	if err := db.Where("id = ?", walletID).First(&wallet).Error; err != nil {
+		return nil, fmt.Errorf("failed to get wallet: %w", err)
+	}
+	return &wallet, nil
 }
 
 // UpdateAISettings updates AI-related settings
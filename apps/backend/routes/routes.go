@@ -3,11 +3,14 @@ package routes
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/zeusnotfound04/Tranza/config"
 	"github.com/zeusnotfound04/Tranza/controllers"
 	middlewares "github.com/zeusnotfound04/Tranza/middleware"
 	"github.com/zeusnotfound04/Tranza/pkg/razorpay"
+	"github.com/zeusnotfound04/Tranza/pkg/risk"
 	"github.com/zeusnotfound04/Tranza/repositories"
 	"github.com/zeusnotfound04/Tranza/services"
 	"github.com/zeusnotfound04/Tranza/utils"
@@ -25,6 +28,22 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 	apiUsageLogRepo := repositories.NewAPIUsageLogRepository(db)
 	addressRepo := repositories.NewAddressRepository(db)
 	externalTransferRepo := repositories.NewExternalTransferRepository(db)
+	ledgerRepo := repositories.NewLedgerRepository(db)
+	walletHoldRepo := repositories.NewWalletHoldRepository(db)
+	externalOrderRepo := repositories.NewExternalOrderRepository(db)
+	jobRepo := repositories.NewJobRepository(db)
+	idempotencyRepo := repositories.NewIdempotencyRepository(db)
+	refundRepo := repositories.NewRefundRepository(db)
+	reconciliationRepo := repositories.NewReconciliationRepository(db)
+	aiAgentRepo := repositories.NewAIAgentRepository(db)
+	merchantRepo := repositories.NewMerchantRepository(db)
+	riskRepo := repositories.NewRiskRepository(db)
+	scheduledPaymentRepo := repositories.NewScheduledPaymentRepository(db)
+	beneficiaryRepo := repositories.NewBeneficiaryRepository(db)
+	payoutBatchRepo := repositories.NewPayoutBatchRepository(db)
+	phoneVPARepo := repositories.NewPhoneVPARepository(db)
+	phoneVerificationRepo := repositories.NewPhoneVerificationRepository(db)
+	feePlanRepo := repositories.NewFeePlanRepository(db)
 
 	// Initialize external clients
 	razorpayClient := razorpay.NewClient(
@@ -36,34 +55,107 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 	jwtService := utils.NewJWTService(os.Getenv("JWT_SECRET"))
 	emailService := services.NewEmailService()
 	oauthService := services.NewOAuthServiceFromEnv()
-	notificationService := services.NewNotificationService()
+	notificationService := services.NewNotificationService(services.NewSMSSender(config.LoadSMSConfig()))
 
 	// Initialize main services
-	walletService := services.NewWalletService(walletRepo, txnRepo, razorpayClient, notificationService, db)
+	ledgerService := services.NewLedgerService(db, ledgerRepo, walletRepo, walletHoldRepo)
+	holdService := services.NewHoldService(db, walletHoldRepo, walletRepo)
+	jobQueue := services.NewJobQueue(jobRepo)
+	idempotencyService := services.NewIdempotencyService(idempotencyRepo, config.LoadIdempotencyKeyTTL())
+	limitsService := services.NewLimitsService(walletRepo, txnRepo, walletHoldRepo)
+	walletService := services.NewWalletService(walletRepo, txnRepo, ledgerService, razorpayClient, notificationService, db)
 	authService := services.NewAuthService(userRepo, jwtService, oauthService, walletService)
 	emailVerificationService := services.NewEmailVerificationService(emailVerificationRepo, userRepo, emailService)
 	cardService := services.NewCardService(cardRepo)
-	paymentService := services.NewPaymentService(razorpayClient, walletRepo, txnRepo, notificationService, db, os.Getenv("RAZORPAY_WEBHOOK_SECRET"))
+	riskService := services.NewRiskService(riskRepo, risk.NewDefaultEngine(services.RiskLocation))
+	walletTransferService := services.NewWalletTransferService(db, walletRepo, userRepo, txnRepo, ledgerService, limitsService, holdService, riskService, notificationService)
+	phoneResolver := services.NewPhoneResolver(
+		services.NewSavedVPAResolver(phoneVPARepo),
+		services.NewTranzaUserResolver(userRepo, walletRepo),
+		services.NewUPILookupResolver(razorpayClient, config.LoadPhoneUPIHandles()),
+	)
+	feeService := services.NewFeeService(db, feePlanRepo, externalTransferRepo, walletRepo, userRepo)
+	externalTransferService := services.NewExternalTransferService(db, externalTransferRepo, beneficiaryRepo, walletRepo, txnRepo, ledgerService, limitsService, holdService, riskService, jobQueue, razorpayClient, services.NewIFSCDirectory(config.LoadIFSCDirectoryFile()), config.LoadTransferCancelWindow(), phoneResolver, walletTransferService, feeService, notificationService)
+	refundService := services.NewRefundService(db, refundRepo, txnRepo, ledgerService, jobQueue, razorpayClient)
+	reconciliationService := services.NewReconciliationService(reconciliationRepo, txnRepo, externalTransferRepo, refundRepo, jobQueue, razorpayClient)
+	paymentService := services.NewPaymentService(razorpayClient, walletRepo, txnRepo, ledgerService, externalTransferService, refundService, notificationService, db, os.Getenv("RAZORPAY_WEBHOOK_SECRET"))
 	transactionService := services.NewTransactionService(txnRepo, walletRepo, paymentService)
 	razorpayService := services.NewRazorpayService()
 	apiKeyService := services.NewAPIKeyService(apiKeyRepo)
 	apiUsageLogService := services.NewAPIUsageLogService(apiUsageLogRepo, apiKeyRepo)
-	aiService := services.NewAIService(db, os.Getenv("GEMINI_API_KEY"))
+	aiAgentService := services.NewAIAgentService(aiAgentRepo)
+	merchantService := services.NewMerchantService(merchantRepo)
+	stepUpService := services.NewStepUpService(userRepo)
+	aiService := services.NewAIService(db, ledgerService, limitsService, holdService, aiAgentService, merchantService, externalTransferService, riskService, stepUpService, services.NewIntentProvider(config.LoadAIConfig()))
+	assistantService := services.NewAssistantService(txnRepo, externalTransferRepo)
 	addressService := services.NewAddressService(addressRepo)
-	externalTransferService := services.NewExternalTransferService(db, externalTransferRepo, walletRepo, txnRepo, razorpayClient, notificationService)
-	// clothingService := services.NewClothingService(addressRepo, walletRepo, txnRepo, db)
+	phoneService := services.NewPhoneService(phoneVerificationRepo, phoneVPARepo, userRepo, emailService, notificationService, razorpayClient)
+	beneficiaryService := services.NewBeneficiaryService(beneficiaryRepo, externalTransferService, razorpayClient, config.LoadBeneficiaryCoolingOff())
+	scheduledPaymentService := services.NewScheduledPaymentService(db, scheduledPaymentRepo, walletRepo, externalTransferRepo, holdService, externalTransferService, walletTransferService, jobQueue, notificationService)
+	payoutBatchService := services.NewPayoutBatchService(db, payoutBatchRepo, walletRepo, externalTransferRepo, holdService, externalTransferService, jobQueue, notificationService)
+	clothingService := services.NewClothingService(db, externalOrderRepo, walletRepo, txnRepo, addressService, ledgerService, holdService, limitsService, aiService, refundService, services.NewCatalog(config.LoadCatalogConfig()))
+
+	// Expire wallet holds that were never captured or released
+	go holdService.StartExpirySweeper(5 * time.Minute)
+
+	// Drop idempotency keys past their replay window
+	go idempotencyService.StartCleanup(time.Hour)
+
+	// Expire AI payment requests that were never confirmed
+	go aiService.StartConfirmationSweeper(time.Minute)
+
+	// Pick up shipping and delivery updates for AI clothing orders
+	go clothingService.StartOrderTracking(10 * time.Minute)
+
+	// Start background job workers and pick up transfers left in flight
+	externalTransferService.RegisterJobHandlers()
+	refundService.RegisterJobHandlers()
+	aiService.RegisterTransferHandlers()
+	reconciliationService.RegisterJobHandlers()
+	scheduledPaymentService.RegisterJobHandlers()
+	scheduledPaymentService.RegisterTransferHandlers()
+	payoutBatchService.RegisterJobHandlers()
+	payoutBatchService.RegisterTransferHandlers()
+	if err := reconciliationService.ScheduleDaily(); err != nil {
+		utils.LogError(err, map[string]interface{}{"action": "schedule_reconciliation"})
+	}
+	if err := externalTransferService.ResumePendingTransfers(); err != nil {
+		utils.LogError(err, map[string]interface{}{"action": "resume_pending_transfers"})
+	}
+	if err := scheduledPaymentService.ResumeSchedules(); err != nil {
+		utils.LogError(err, map[string]interface{}{"action": "resume_scheduled_payments"})
+	}
+	jobQueue.Start(2)
 
     // Initialize controllers
 	authController := controllers.NewAuthController(authService, emailVerificationService)
 	cardController := controllers.NewCardController(cardService)
-	walletController := controllers.NewWalletHandler(walletService)
+	walletController := controllers.NewWalletHandler(walletService, holdService)
 	transactionController := controllers.NewTransactionController(transactionService, paymentService)
 	paymentController := controllers.NewPaymentController(razorpayService)
 	apiKeyController := controllers.NewAPIKeyController(apiKeyService, apiUsageLogService)
-	aiController := controllers.NewAIController(aiService, walletService, paymentService)
+	aiController := controllers.NewAIController(aiService, walletService, paymentService, assistantService)
+	aiAgentController := controllers.NewAIAgentController(aiAgentService)
+	merchantController := controllers.NewMerchantController(merchantService)
+	feePlanController := controllers.NewFeePlanController(feeService)
 	addressController := controllers.NewAddressController(addressService)
 	externalTransferController := controllers.NewExternalTransferController(externalTransferService, walletService)
-	// clothingController := controllers.NewClothingController(clothingService)
+	ledgerController := controllers.NewLedgerController(ledgerService)
+	walletTransferController := controllers.NewWalletTransferController(walletTransferService)
+	limitsController := controllers.NewLimitsController(limitsService)
+	webhookController := controllers.NewWebhookController(paymentService)
+	refundController := controllers.NewRefundController(refundService)
+	reconciliationController := controllers.NewReconciliationController(reconciliationService)
+	riskController := controllers.NewRiskController(riskService)
+	clothingController := controllers.NewClothingController(clothingService)
+	stepUpController := controllers.NewStepUpController(stepUpService)
+	scheduledPaymentController := controllers.NewScheduledPaymentController(scheduledPaymentService)
+	beneficiaryController := controllers.NewBeneficiaryController(beneficiaryService)
+	payoutBatchController := controllers.NewPayoutBatchController(payoutBatchService)
+	phoneController := controllers.NewPhoneController(phoneService)
+
+	// Idempotency-Key support for money-moving endpoints
+	idempotent := middlewares.IdempotencyMiddleware(idempotencyService)
 
 	fmt.Printf("DEBUG: All controllers initialized successfully\n")
 	fmt.Printf("DEBUG: Wallet controller: %+v\n", walletController)
@@ -136,6 +228,16 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 			// Update user profile
 			ctx.JSON(200, gin.H{"message": "Profile updated successfully"})
 		})
+
+		// Authenticator app for step-up confirmation
+		profile.POST("/totp/setup", stepUpController.SetupTOTP)
+		profile.POST("/totp/enable", stepUpController.EnableTOTP)
+		profile.POST("/totp/disable", stepUpController.DisableTOTP)
+
+		// Verified mobile number; phone transfers to it credit the wallet
+		profile.POST("/phone", phoneController.StartPhoneVerification)
+		profile.POST("/phone/verify", phoneController.VerifyPhone)
+		profile.DELETE("/phone", phoneController.RemovePhone)
 	}
 
 	// ======================
@@ -144,10 +246,15 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 	wallet := api.Group("/wallet")
 	{
 		fmt.Printf("DEBUG: Registering wallet routes\n")
-		wallet.GET("", walletController.GetWallet)                     // Get wallet details
-		wallet.PUT("/settings", walletController.UpdateWalletSettings) // Update wallet settings
-		wallet.POST("/load", walletController.CreateLoadMoneyOrder)    // Create load money order
-		wallet.POST("/verify-payment", walletController.VerifyPayment) // Verify payment and credit wallet
+		wallet.GET("", walletController.GetWallet)                                      // Get wallet details
+		wallet.PUT("/settings", walletController.UpdateWalletSettings)                  // Update wallet settings
+		wallet.POST("/load", idempotent, walletController.CreateLoadMoneyOrder)         // Create load money order
+		wallet.POST("/verify-payment", walletController.VerifyPayment)                  // Verify payment and credit wallet
+		wallet.POST("/transfer", idempotent, walletTransferController.TransferToWallet) // Send money to another Tranza wallet
+		wallet.GET("/limits", limitsController.GetWalletLimits)                         // Usage against wallet limits
+		wallet.GET("/holds", walletController.GetWalletHolds)                           // Funds reserved for pending debits
+		wallet.GET("/ledger", ledgerController.GetWalletLedger)                         // Journal entries behind the balance
+		wallet.GET("/ledger/reconcile", ledgerController.ReconcileWallet)               // Compare balance with the ledger
 		fmt.Printf("DEBUG: Wallet routes registered successfully\n")
 	}
 
@@ -200,10 +307,57 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 		payments.GET("/payments/:id", paymentController.GetPayment) // Get payment details
 	}
 
+	// ======================
+	// Refund Routes
+	// ======================
+	refunds := api.Group("/refunds")
+	{
+		refunds.POST("", idempotent, refundController.CreateRefund) // Refund a wallet load or AI payment
+		refunds.GET("", refundController.GetRefunds)                // List refunds
+		refunds.GET("/:id", refundController.GetRefund)             // Get refund with status history
+	}
+
 	// Payment webhooks (public, but secured with signature verification)
 	webhooks := r.Group("/webhooks")
 	{
-		webhooks.POST("/razorpay", paymentController.HandleWebhook) // Razorpay webhook handler
+		webhooks.POST("/razorpay", webhookController.HandleRazorpayWebhook) // Razorpay payment, refund and payout webhooks
+	}
+
+	// ======================
+	// Beneficiary Routes (Saved Transfer Recipients)
+	// ======================
+	beneficiaries := api.Group("/beneficiaries")
+	{
+		beneficiaries.POST("", beneficiaryController.AddBeneficiary)               // Save a recipient under a nickname
+		beneficiaries.GET("", beneficiaryController.GetBeneficiaries)              // List saved recipients
+		beneficiaries.GET("/:id", beneficiaryController.GetBeneficiary)            // Get by ID or nickname
+		beneficiaries.PUT("/:id", beneficiaryController.RenameBeneficiary)         // Change the nickname
+		beneficiaries.POST("/:id/verify", beneficiaryController.VerifyBeneficiary) // Retry Razorpay registration
+		beneficiaries.DELETE("/:id", beneficiaryController.DeleteBeneficiary)      // Remove a saved recipient
+	}
+
+	// ======================
+	// Phone UPI ID Routes (UPI IDs Phone Transfers Are Paid At)
+	// ======================
+	phoneVPAs := api.Group("/phone-vpas")
+	{
+		phoneVPAs.POST("", phoneController.SavePhoneVPA)         // Save or replace the UPI ID for a number
+		phoneVPAs.GET("", phoneController.GetPhoneVPAs)          // List saved UPI IDs
+		phoneVPAs.DELETE("/:id", phoneController.DeletePhoneVPA) // Remove a saved UPI ID
+	}
+
+	// ======================
+	// Scheduled Payment Routes (One-off and Recurring Transfers)
+	// ======================
+	schedules := api.Group("/schedules")
+	{
+		schedules.POST("", idempotent, scheduledPaymentController.CreateSchedule) // Schedule a one-off or recurring payment
+		schedules.GET("", scheduledPaymentController.GetSchedules)                // List scheduled payments
+		schedules.GET("/:id", scheduledPaymentController.GetSchedule)             // Get specific scheduled payment
+		schedules.GET("/:id/runs", scheduledPaymentController.GetScheduleRuns)    // Run history with outcomes
+		schedules.POST("/:id/pause", scheduledPaymentController.PauseSchedule)    // Pause until resumed
+		schedules.POST("/:id/resume", scheduledPaymentController.ResumeSchedule)  // Resume from the next run
+		schedules.POST("/:id/cancel", scheduledPaymentController.CancelSchedule)  // Cancel for good
 	}
 
 	// ======================
@@ -225,7 +379,7 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 		// These would be used by third-party integrations
 		apiKeyRoutes.GET("/transactions", transactionController.GetTransactionHistory)
 		apiKeyRoutes.GET("/wallet/balance", walletController.GetWallet)
-		apiKeyRoutes.POST("/payments/create", paymentController.CreateOrder)
+		apiKeyRoutes.POST("/payments/create", idempotent, paymentController.CreateOrder)
 	}
 
 	// ======================
@@ -234,22 +388,34 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 	ai := api.Group("/ai")
 	{
 		// AI Payment Processing
-		ai.POST("/payment/request", aiController.ProcessPaymentRequest) // Process natural language payment request
-		ai.POST("/payment/confirm", aiController.ConfirmPayment)        // Confirm or cancel payment request
-		ai.GET("/payment/:id", aiController.GetPaymentRequest)          // Get specific payment request details
-		ai.DELETE("/payment/:id", aiController.CancelPaymentRequest)    // Cancel pending payment request
+		ai.POST("/payment/request", aiController.ProcessPaymentRequest)            // Process natural language payment request
+		ai.POST("/payment/confirm", idempotent, aiController.ConfirmPayment)       // Confirm or cancel payment request
+		ai.GET("/payment/:id", aiController.GetPaymentRequest)                     // Get specific payment request details
+		ai.GET("/payment/:id/confirmations", aiController.GetPaymentConfirmations) // Who confirmed or cancelled, from where
+		ai.DELETE("/payment/:id", aiController.CancelPaymentRequest)               // Cancel pending payment request
 
 		// AI Payment History and Analytics
 		ai.GET("/payments", aiController.GetPaymentHistory)     // Get AI payment history with pagination
 		ai.GET("/analytics", aiController.GetSpendingAnalytics) // Get AI spending analytics and insights
+		ai.POST("/ask", aiController.AskQuestion)               // Answer a question about spending and transfers
 
 		// AI Spending Limits Management
-		ai.GET("/limits", aiController.GetSpendingLimits)    // Get user's AI spending limits
-		ai.PUT("/limits", aiController.UpdateSpendingLimits) // Update user's AI spending limits
+		ai.GET("/limits", aiController.GetSpendingLimits)            // Get user's AI spending limits
+		ai.PUT("/limits", aiController.UpdateSpendingLimits)         // Update user's AI spending limits
+		ai.GET("/limits/remaining", aiController.GetRemainingBudget) // Spent and remaining per day, week and month
+
+		// Per-agent rules
+		ai.POST("/agents", aiAgentController.SaveAgentConfig)               // Create or update an agent's configuration
+		ai.GET("/agents", aiAgentController.GetAgentConfigs)                // List agent configurations
+		ai.GET("/agents/:agent_id", aiAgentController.GetAgentConfig)       // Get an agent's configuration
+		ai.DELETE("/agents/:agent_id", aiAgentController.DeleteAgentConfig) // Delete an agent's configuration
 
 		// AI Clothing Order Processing
-		// ai.POST("/clothing/order", clothingController.ProcessAIClothingOrder)   // Process AI clothing order request
-		// ai.POST("/clothing/confirm", clothingController.ConfirmAIClothingOrder) // Confirm AI clothing order
+		ai.POST("/clothing/order", clothingController.ProcessAIClothingOrder)               // Suggest products and draft an order
+		ai.POST("/clothing/confirm", idempotent, clothingController.ConfirmAIClothingOrder) // Pay for a drafted order and place it
+		ai.GET("/clothing/orders", clothingController.GetClothingOrders)                    // List clothing orders
+		ai.GET("/clothing/orders/:id", clothingController.GetClothingOrder)                 // Get a clothing order with delivery status
+		ai.POST("/clothing/orders/:id/cancel", clothingController.CancelClothingOrder)      // Cancel and refund an unshipped order
 	}
 
 	// ======================
@@ -271,12 +437,22 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 	// ======================
 	transfers := api.Group("/transfers")
 	{
-		transfers.POST("/validate", externalTransferController.ValidateTransfer) // Validate transfer before processing
-		transfers.POST("", externalTransferController.CreateTransfer)            // Create new external transfer
-		transfers.GET("", externalTransferController.GetUserTransfers)           // Get user's transfer history
-		transfers.GET("/:id", externalTransferController.GetTransfer)            // Get specific transfer
-		transfers.GET("/fees", externalTransferController.GetTransferFees)       // Get transfer fee structure
-		transfers.GET("/health", externalTransferController.HealthCheck)         // Health check
+		transfers.POST("/validate", externalTransferController.ValidateTransfer)  // Validate transfer before processing
+		transfers.POST("", idempotent, externalTransferController.CreateTransfer) // Create new external transfer
+		transfers.GET("", externalTransferController.GetUserTransfers)            // Get user's transfer history
+		transfers.GET("/:id", externalTransferController.GetTransfer)             // Get specific transfer
+		transfers.POST("/:id/cancel", externalTransferController.CancelTransfer)  // Cancel a transfer not yet paid out
+		transfers.GET("/fees", externalTransferController.GetTransferFees)        // Get transfer fee structure
+		transfers.GET("/health", externalTransferController.HealthCheck)          // Health check
+
+		// Bulk payouts: upload, review, confirm once, then track each row
+		transfers.POST("/batches", payoutBatchController.CreateBatch)                          // Validate a CSV or JSON upload
+		transfers.GET("/batches", payoutBatchController.GetBatches)                            // List payout batches
+		transfers.GET("/batches/:id", payoutBatchController.GetBatch)                          // Batch summary and progress
+		transfers.GET("/batches/:id/rows", payoutBatchController.GetBatchRows)                 // Per-row status
+		transfers.GET("/batches/:id/results", payoutBatchController.DownloadResults)           // Download results as CSV
+		transfers.POST("/batches/:id/confirm", idempotent, payoutBatchController.ConfirmBatch) // Pay the valid rows
+		transfers.POST("/batches/:id/cancel", payoutBatchController.CancelBatch)               // Drop an unconfirmed batch
 	}
 
 	// ======================
@@ -307,20 +483,17 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 		bot.GET("/wallet/balance", externalTransferController.BotGetWalletBalance)
 
 		// Transfer operations for bots (with appropriate scope requirements)
-		bot.POST("/transfers/validate", externalTransferController.BotValidateTransfer)   // Requires bot:transfer:validate
-		bot.POST("/transfers", externalTransferController.BotCreateTransfer)              // Requires bot:transfer:create
-		bot.GET("/transfers/:id/status", externalTransferController.BotGetTransferStatus) // Requires bot:transfer:status
+		bot.POST("/transfers/validate", externalTransferController.BotValidateTransfer)        // Requires bot:transfer:validate
+		bot.POST("/transfers", idempotent, externalTransferController.BotCreateTransfer)       // Requires bot:transfer:create
+		bot.GET("/transfers/:id/status", externalTransferController.BotGetTransferStatus)      // Requires bot:transfer:status
+		bot.POST("/transfers/:id/cancel", externalTransferController.BotCancelTransfer)        // Requires bot:transfer:create
+		bot.GET("/beneficiaries", beneficiaryController.GetBeneficiaries)                      // Saved recipients the bot can pay by nickname
+		bot.POST("/wallet/transfer", idempotent, walletTransferController.BotTransferToWallet) // Requires bot:transfer:create
+		bot.POST("/ai/ask", aiController.AskQuestion)                                          // Answer a question about spending and transfers
+		bot.POST("/ai/payment/request", aiController.ProcessPaymentRequest)                    // Draft an AI payment from a Slack prompt
+		bot.POST("/ai/payment/confirm", idempotent, aiController.ConfirmPayment)               // Confirm from Slack with the confirmation token
 	}
 
-	// ======================
-	// AI Clothing Shopping - External E-commerce Integration
-	// ======================
-	// Note: This system searches external e-commerce sites and processes payments via Tranza wallet
-
-	// These routes are already defined in the AI group above:
-	// ai.POST("/clothing/order", clothingController.ProcessAIClothingOrder)
-	// ai.POST("/clothing/confirm", clothingController.ConfirmAIClothingOrder)
-
 	// ======================
 	// Debug Routes (Temporary)
 	// ======================
@@ -348,10 +521,10 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 	}
 
 	// ======================
-	// Admin Routes (Future Implementation)
+	// Admin Routes
 	// ======================
 	admin := api.Group("/admin")
-	// admin.Use(middlewares.AdminAuthMiddleware()) // Future: Admin-only middleware
+	admin.Use(middlewares.AdminAuthMiddleware(userRepo)) // Users whose role is admin
 	{
 		admin.GET("/users", func(ctx *gin.Context) {
 			ctx.JSON(501, gin.H{"message": "Admin routes not implemented yet"})
@@ -362,5 +535,28 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 		admin.GET("/analytics", func(ctx *gin.Context) {
 			ctx.JSON(501, gin.H{"message": "Admin routes not implemented yet"})
 		})
+
+		// Razorpay reconciliation reports
+		admin.POST("/reconciliation/runs", reconciliationController.RunReconciliation)                 // Reconcile a day or range on demand
+		admin.GET("/reconciliation/runs", reconciliationController.GetRuns)                            // List reconciliation runs
+		admin.GET("/reconciliation/runs/:id", reconciliationController.GetRun)                         // Run with its mismatch report
+		admin.GET("/reconciliation/mismatches", reconciliationController.GetUnresolvedMismatches)      // Mismatches awaiting review
+		admin.POST("/reconciliation/mismatches/:id/resolve", reconciliationController.ResolveMismatch) // Mark a mismatch reviewed
+
+		// Merchant registry for AI payment payouts
+		admin.POST("/merchants", merchantController.CreateMerchant)    // Register a merchant and its UPI ID
+		admin.GET("/merchants", merchantController.GetMerchants)       // List registered merchants
+		admin.PUT("/merchants/:id", merchantController.UpdateMerchant) // Update or deactivate a merchant
+
+		// Transfer fee plans
+		admin.POST("/fee-plans", feePlanController.CreateFeePlan)                  // Add a fee plan
+		admin.GET("/fee-plans", feePlanController.GetFeePlans)                     // List fee plans
+		admin.PUT("/fee-plans/:id", feePlanController.UpdateFeePlan)               // Change a plan as a new version
+		admin.GET("/fee-plans/:id/versions", feePlanController.GetFeePlanVersions) // Terms of every version
+		admin.PUT("/users/:id/fee-plan", feePlanController.AssignFeePlan)          // Put a user on a plan
+
+		// Risk engine decisions
+		admin.GET("/risk/assessments", riskController.GetAssessments)        // Scored payments with their reasons
+		admin.GET("/risk/payments/:id", riskController.GetPaymentAssessment) // How one payment was scored
 	}
 }
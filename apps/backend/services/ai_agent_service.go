@@ -0,0 +1,423 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/pkg/period"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"gorm.io/gorm"
+)
+
+// Agent rules reported in AgentPolicyError
+const (
+	AgentRuleDisabled        = "agent_disabled"
+	AgentRuleMerchantBlocked = "merchant_blocked"
+	AgentRuleMerchantAllowed = "merchant_not_allowed"
+	AgentRuleCategoryBlocked = "category_blocked"
+	AgentRuleCategoryAllowed = "category_not_allowed"
+	AgentRuleUnknownAgent    = "unknown_agent"
+	AgentRuleKeyNotBound     = "key_not_bound"
+)
+
+// Constants for agent configurations
+const (
+	MaxAgentIDLength    = 100
+	MaxAgentListEntries = 100 // Per merchant or category list
+)
+
+// AgentPolicyError is returned when a payment breaks one of the agent's rules
+type AgentPolicyError struct {
+	AgentID string `json:"agent_id"`
+	Rule    string `json:"rule"`
+	Value   string `json:"value,omitempty"`
+}
+
+func (e *AgentPolicyError) Error() string {
+	switch e.Rule {
+	case AgentRuleDisabled:
+		return fmt.Sprintf("agent %s is disabled", e.AgentID)
+	case AgentRuleMerchantBlocked:
+		return fmt.Sprintf("agent %s may not pay %s", e.AgentID, e.Value)
+	case AgentRuleMerchantAllowed:
+		if e.Value == "" {
+			return fmt.Sprintf("agent %s may only pay approved merchants and no merchant was given", e.AgentID)
+		}
+		return fmt.Sprintf("%s is not an approved merchant for agent %s", e.Value, e.AgentID)
+	case AgentRuleCategoryBlocked:
+		return fmt.Sprintf("agent %s may not make %s payments", e.AgentID, e.Value)
+	case AgentRuleCategoryAllowed:
+		if e.Value == "" {
+			return fmt.Sprintf("agent %s may only make payments in approved categories and no category was given", e.AgentID)
+		}
+		return fmt.Sprintf("%s is not an approved category for agent %s", e.Value, e.AgentID)
+	case AgentRuleUnknownAgent:
+		if e.AgentID == "" {
+			return "payments made with an API key must name one of your configured agents"
+		}
+		return fmt.Sprintf("agent %s is not configured; add it before it makes payments", e.AgentID)
+	case AgentRuleKeyNotBound:
+		return fmt.Sprintf("this API key is not bound to agent %s; create a key for the agent to pay as it", e.AgentID)
+	default:
+		return fmt.Sprintf("payment not allowed for agent %s", e.AgentID)
+	}
+}
+
+// AgentPayment is a payment checked against an agent's rules. Merchant lists
+// are matched against the registered merchant and the UPI ID paid, never the
+// free-text payee alone, which only counts towards blocked merchants.
+type AgentPayment struct {
+	AgentID  string
+	Amount   decimal.Decimal
+	Merchant string // Registered merchant name; empty when the payee is not registered
+	UPIID    string // UPI ID the money goes to, if known yet
+	Payee    string // Payee as written in the prompt or request
+	Category string
+	Timezone string // User's time zone; the agent's daily limit resets at midnight there
+
+	// FromAPIKey is set for payments made with an API key rather than by the
+	// user in the app; those must come from a configured agent once the user
+	// has any. KeyAgentID is the agent the key is bound to, empty for a key
+	// bound to none, which may not act as an agent by naming it.
+	FromAPIKey bool
+	KeyAgentID string
+}
+
+// AgentDecision is the outcome of a payment that passed an agent's rules
+type AgentDecision struct {
+	Config *models.AIAgentConfig // nil when the agent has no configuration
+
+	// RequiresApproval reports whether the agent's approval settings call for
+	// user confirmation; only meaningful when Config is set
+	RequiresApproval bool
+}
+
+// AIAgentService manages per-agent AI payment rules
+type AIAgentService struct {
+	agentRepo *repositories.AIAgentRepository
+}
+
+func NewAIAgentService(agentRepo *repositories.AIAgentRepository) *AIAgentService {
+	return &AIAgentService{
+		agentRepo: agentRepo,
+	}
+}
+
+// SaveConfig creates an agent configuration or updates the fields set in the
+// request. New agents start enabled, requiring approval for every payment and
+// with no limits beyond the user's.
+func (s *AIAgentService) SaveConfig(userID string, req *dto.AIAgentConfigRequest) (*dto.AIAgentConfigResponse, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	agentID := strings.TrimSpace(req.AgentID)
+	if agentID == "" {
+		return nil, errors.New("agent ID is required")
+	}
+	if len(agentID) > MaxAgentIDLength {
+		return nil, fmt.Errorf("agent ID must be at most %d characters", MaxAgentIDLength)
+	}
+
+	config, err := s.agentRepo.GetByUserAndAgentID(uid, agentID)
+	if err != nil {
+		config = &models.AIAgentConfig{
+			UserID:          uid,
+			AgentID:         agentID,
+			Enabled:         true,
+			RequireApproval: true,
+		}
+	}
+
+	if req.Enabled != nil {
+		config.Enabled = *req.Enabled
+	}
+	if req.DailyLimit != nil {
+		if req.DailyLimit.IsNegative() {
+			return nil, errors.New("daily limit cannot be negative")
+		}
+		config.DailyLimit = *req.DailyLimit
+	}
+	if req.PerTransactionLimit != nil {
+		if req.PerTransactionLimit.IsNegative() {
+			return nil, errors.New("per transaction limit cannot be negative")
+		}
+		config.PerTransactionLimit = *req.PerTransactionLimit
+	}
+	if req.RequireApproval != nil {
+		config.RequireApproval = *req.RequireApproval
+	}
+	if req.AutoApprovalThreshold != nil {
+		if req.AutoApprovalThreshold.IsNegative() {
+			return nil, errors.New("auto approval threshold cannot be negative")
+		}
+		config.AutoApprovalThreshold = *req.AutoApprovalThreshold
+	}
+	if req.NotificationSettings != nil {
+		config.NotificationSettings = req.NotificationSettings
+	}
+
+	lists := []struct {
+		name   string
+		values []string
+		field  *[]string
+	}{
+		{"allowed merchants", req.AllowedMerchants, &config.AllowedMerchants},
+		{"blocked merchants", req.BlockedMerchants, &config.BlockedMerchants},
+		{"allowed categories", req.AllowedCategories, &config.AllowedCategories},
+		{"blocked categories", req.BlockedCategories, &config.BlockedCategories},
+	}
+	for _, list := range lists {
+		if list.values == nil {
+			continue
+		}
+		if len(list.values) > MaxAgentListEntries {
+			return nil, fmt.Errorf("%s can have at most %d entries", list.name, MaxAgentListEntries)
+		}
+		*list.field = cleanAgentList(list.values)
+	}
+
+	if !config.PerTransactionLimit.IsZero() && !config.DailyLimit.IsZero() &&
+		config.PerTransactionLimit.GreaterThan(config.DailyLimit) {
+		return nil, errors.New("per transaction limit cannot exceed the daily limit")
+	}
+
+	if err := s.agentRepo.Save(config); err != nil {
+		return nil, err
+	}
+
+	return s.toResponse(config), nil
+}
+
+// GetConfig returns one of the user's agent configurations
+func (s *AIAgentService) GetConfig(userID, agentID string) (*dto.AIAgentConfigResponse, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	config, err := s.agentRepo.GetByUserAndAgentID(uid, agentID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toResponse(config), nil
+}
+
+// GetConfigs lists the user's agent configurations
+func (s *AIAgentService) GetConfigs(userID string) ([]*dto.AIAgentConfigResponse, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	configs, err := s.agentRepo.GetByUserID(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*dto.AIAgentConfigResponse, len(configs))
+	for i, config := range configs {
+		responses[i] = s.toResponse(config)
+	}
+	return responses, nil
+}
+
+// DeleteConfig removes an agent configuration. The agent's later payments are
+// only held to the user's AI spending limits.
+func (s *AIAgentService) DeleteConfig(userID, agentID string) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return errors.New("invalid user ID")
+	}
+	return s.agentRepo.Delete(uid, agentID)
+}
+
+// CheckPayment checks a payment against the agent's rules. Once a user has
+// configured any agent, payments from an unknown agent, or made with an API
+// key not bound to the agent paying, are refused. Users without agent
+// configurations are unaffected, as are their own payments from the app.
+func (s *AIAgentService) CheckPayment(userID uuid.UUID, payment AgentPayment) (*AgentDecision, error) {
+	if payment.AgentID == "" && !payment.FromAPIKey {
+		return &AgentDecision{}, nil
+	}
+
+	// An API key acts only as the agent it is bound to; the agent named in the
+	// request is not taken on the key's word
+	if payment.FromAPIKey && payment.AgentID != payment.KeyAgentID {
+		configured, err := s.agentRepo.CountByUserID(userID)
+		if err != nil {
+			return nil, err
+		}
+		if configured > 0 {
+			return nil, &AgentPolicyError{AgentID: payment.AgentID, Rule: AgentRuleKeyNotBound}
+		}
+		return &AgentDecision{}, nil
+	}
+
+	var config *models.AIAgentConfig
+	var err error
+	if payment.AgentID != "" {
+		config, err = s.agentRepo.GetByUserAndAgentID(userID, payment.AgentID)
+		if err != nil && !errors.Is(err, repositories.ErrAgentConfigNotFound) {
+			return nil, err
+		}
+	}
+	if config == nil {
+		configured, err := s.agentRepo.CountByUserID(userID)
+		if err != nil {
+			return nil, err
+		}
+		if configured > 0 {
+			return nil, &AgentPolicyError{AgentID: payment.AgentID, Rule: AgentRuleUnknownAgent}
+		}
+		return &AgentDecision{}, nil
+	}
+
+	if !config.Enabled {
+		return nil, &AgentPolicyError{AgentID: config.AgentID, Rule: AgentRuleDisabled}
+	}
+
+	if matchesAgentList(config.BlockedMerchants, payment.Merchant) ||
+		matchesAgentList(config.BlockedMerchants, payment.UPIID) ||
+		matchesAgentList(config.BlockedMerchants, payment.Payee) {
+		return nil, &AgentPolicyError{AgentID: config.AgentID, Rule: AgentRuleMerchantBlocked, Value: payment.describePayee()}
+	}
+	if len(config.AllowedMerchants) > 0 &&
+		!matchesAgentList(config.AllowedMerchants, payment.Merchant) &&
+		!matchesAgentList(config.AllowedMerchants, payment.UPIID) {
+		return nil, &AgentPolicyError{AgentID: config.AgentID, Rule: AgentRuleMerchantAllowed, Value: payment.describePayee()}
+	}
+	if matchesAgentList(config.BlockedCategories, payment.Category) {
+		return nil, &AgentPolicyError{AgentID: config.AgentID, Rule: AgentRuleCategoryBlocked, Value: payment.Category}
+	}
+	if len(config.AllowedCategories) > 0 && !matchesAgentList(config.AllowedCategories, payment.Category) {
+		return nil, &AgentPolicyError{AgentID: config.AgentID, Rule: AgentRuleCategoryAllowed, Value: payment.Category}
+	}
+
+	if !config.PerTransactionLimit.IsZero() && payment.Amount.GreaterThan(config.PerTransactionLimit) {
+		return nil, &LimitExceededError{
+			Limit:     LimitAgentPerTransaction,
+			Allowed:   config.PerTransactionLimit,
+			Used:      decimal.Zero,
+			Remaining: config.PerTransactionLimit,
+			Requested: payment.Amount,
+		}
+	}
+
+	if err := s.CheckDailyLimitWithTx(nil, userID, config, payment.Amount, payment.Timezone, uuid.Nil); err != nil {
+		return nil, err
+	}
+
+	return &AgentDecision{
+		Config:           config,
+		RequiresApproval: config.RequireApproval || payment.Amount.GreaterThan(config.AutoApprovalThreshold),
+	}, nil
+}
+
+// CheckDailyLimitWithTx checks a payment against what is left of the agent's
+// daily limit inside the caller's transaction. A payment request already
+// claimed for paying is left out of the agent's spending. Payments not made by
+// an agent, or by one without a daily limit, pass.
+func (s *AIAgentService) CheckDailyLimitWithTx(tx *gorm.DB, userID uuid.UUID, config *models.AIAgentConfig, amount decimal.Decimal, timezone string, paymentRequestID uuid.UUID) error {
+	if config == nil || config.DailyLimit.IsZero() {
+		return nil
+	}
+
+	dayStart := period.StartOfDay(time.Now().In(period.Location(timezone)))
+	spent, err := s.agentRepo.GetSpentSinceWithTx(tx, userID, config.AgentID, dayStart, paymentRequestID)
+	if err != nil {
+		return err
+	}
+	if spent.Add(amount).GreaterThan(config.DailyLimit) {
+		return &LimitExceededError{
+			Limit:     LimitAgentDaily,
+			Allowed:   config.DailyLimit,
+			Used:      spent,
+			Remaining: decimal.Max(config.DailyLimit.Sub(spent), decimal.Zero),
+			Requested: amount,
+		}
+	}
+	return nil
+}
+
+// describePayee names the payee in policy errors
+func (p *AgentPayment) describePayee() string {
+	switch {
+	case p.Merchant != "":
+		return p.Merchant
+	case p.UPIID != "":
+		return p.UPIID
+	default:
+		return p.Payee
+	}
+}
+
+func (s *AIAgentService) toResponse(config *models.AIAgentConfig) *dto.AIAgentConfigResponse {
+	orEmpty := func(values []string) []string {
+		if values == nil {
+			return []string{}
+		}
+		return values
+	}
+
+	settings := config.NotificationSettings
+	if settings == nil {
+		settings = map[string]bool{}
+	}
+
+	return &dto.AIAgentConfigResponse{
+		AgentID:               config.AgentID,
+		Enabled:               config.Enabled,
+		DailyLimit:            config.DailyLimit,
+		PerTransactionLimit:   config.PerTransactionLimit,
+		AllowedMerchants:      orEmpty(config.AllowedMerchants),
+		BlockedMerchants:      orEmpty(config.BlockedMerchants),
+		AllowedCategories:     orEmpty(config.AllowedCategories),
+		BlockedCategories:     orEmpty(config.BlockedCategories),
+		RequireApproval:       config.RequireApproval,
+		AutoApprovalThreshold: config.AutoApprovalThreshold,
+		NotificationSettings:  settings,
+		CreatedAt:             config.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:             config.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// cleanAgentList trims entries and drops blanks and case-insensitive duplicates
+func cleanAgentList(values []string) []string {
+	cleaned := make([]string, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		key := strings.ToLower(value)
+		if value == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		cleaned = append(cleaned, value)
+	}
+	return cleaned
+}
+
+// matchesAgentList reports whether value matches an entry, ignoring case. An
+// entry also matches values that start with it as a whole word, so "Swiggy"
+// covers "Swiggy Instamart".
+func matchesAgentList(list []string, value string) bool {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return false
+	}
+	for _, entry := range list {
+		entry = strings.ToLower(entry)
+		if value == entry || strings.HasPrefix(value, entry+" ") {
+			return true
+		}
+	}
+	return false
+}
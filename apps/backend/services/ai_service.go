@@ -1,30 +1,138 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/config"
 	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/pkg/intent"
+	"github.com/zeusnotfound04/Tranza/pkg/period"
+	"github.com/zeusnotfound04/Tranza/pkg/risk"
+	"github.com/zeusnotfound04/Tranza/utils"
 	"gorm.io/gorm"
 )
 
+const (
+	// AIPaymentConfirmationTTL is how long a payment request's confirmation
+	// token is valid. Unconfirmed requests expire after it.
+	AIPaymentConfirmationTTL = 15 * time.Minute
+	// MaxConfirmationAttempts is how many wrong tokens or failed step-ups a
+	// payment request takes before it is cancelled
+	MaxConfirmationAttempts = 5
+)
+
+var (
+	ErrConfirmationTokenInvalid    = errors.New("invalid confirmation token")
+	ErrConfirmationExpired         = errors.New("payment request has expired; send it again")
+	ErrTooManyConfirmationAttempts = errors.New("too many failed confirmation attempts; payment request cancelled")
+)
+
+// ConfirmationSource is who acted on a payment request and where from
+type ConfirmationSource struct {
+	Channel   string // web, slack, api
+	Actor     string
+	IPAddress string
+	UserAgent string
+}
+
+// PaymentConfirmation is an attempt to confirm or cancel a payment request
+type PaymentConfirmation struct {
+	ConfirmationSource
+	Confirmed bool
+	Token     string
+	Password  string // Step-up
+	TOTPCode  string // Step-up
+}
+
 type AIService struct {
-	db           *gorm.DB
-	geminiAPIKey string
+	db              *gorm.DB
+	ledgerService   *LedgerService
+	limitsService   *LimitsService
+	holdService     *HoldService
+	agentService    *AIAgentService
+	merchantService *MerchantService
+	transferService *ExternalTransferService
+	riskService     *RiskService
+	stepUpService   *StepUpService
+	intentProvider  intent.Provider
 }
 
-func NewAIService(db *gorm.DB, geminiAPIKey string) *AIService {
+func NewAIService(
+	db *gorm.DB,
+	ledgerService *LedgerService,
+	limitsService *LimitsService,
+	holdService *HoldService,
+	agentService *AIAgentService,
+	merchantService *MerchantService,
+	transferService *ExternalTransferService,
+	riskService *RiskService,
+	stepUpService *StepUpService,
+	intentProvider intent.Provider,
+) *AIService {
 	return &AIService{
-		db:           db,
-		geminiAPIKey: geminiAPIKey,
+		db:              db,
+		ledgerService:   ledgerService,
+		limitsService:   limitsService,
+		holdService:     holdService,
+		agentService:    agentService,
+		merchantService: merchantService,
+		transferService: transferService,
+		riskService:     riskService,
+		stepUpService:   stepUpService,
+		intentProvider:  intentProvider,
 	}
 }
 
+// RegisterTransferHandlers has the payouts of AI payments report back to
+// their payment requests. Call before the job queue starts.
+func (s *AIService) RegisterTransferHandlers() {
+	s.transferService.OnTransferComplete(s.handleMerchantPayoutComplete)
+}
+
+// NewIntentProvider builds the prompt parser selected by cfg. LLM providers
+// fall back to pattern matching when a request fails; a provider that is
+// missing its credentials is replaced by pattern matching outright.
+func NewIntentProvider(cfg *config.AIConfig) intent.Provider {
+	regex := intent.NewRegexProvider()
+
+	var completer intent.Completer
+	switch cfg.Provider {
+	case intent.ProviderRegex:
+		return regex
+	case intent.ProviderGemini:
+		if cfg.APIKey != "" {
+			completer = intent.NewGeminiCompleter(cfg.APIKey, cfg.Model, cfg.BaseURL, cfg.Timeout)
+		}
+	case intent.ProviderOpenAI:
+		// Self-hosted OpenAI-compatible servers often need no key
+		if cfg.APIKey != "" || cfg.BaseURL != "" {
+			completer = intent.NewOpenAICompleter(cfg.APIKey, cfg.Model, cfg.BaseURL, cfg.Timeout)
+		}
+	}
+
+	if completer == nil {
+		utils.LogWarning("AI provider not usable, falling back to pattern matching", map[string]interface{}{
+			"provider": cfg.Provider,
+		})
+		return regex
+	}
+
+	return intent.WithFallback(intent.NewLLMProvider(cfg.Provider, completer), regex, func(provider string, err error) {
+		utils.LogWarning("AI prompt extraction failed, using pattern matching", map[string]interface{}{
+			"provider": provider,
+			"error":    err.Error(),
+		})
+	})
+}
+
 // ProcessPaymentRequest analyzes natural language and creates payment request
 func (s *AIService) ProcessPaymentRequest(userID uuid.UUID, req models.AIPaymentRequestDTO) (*models.AIPaymentResponse, error) {
 	// Check if user has AI access enabled
@@ -37,9 +145,14 @@ func (s *AIService) ProcessPaymentRequest(userID uuid.UUID, req models.AIPayment
 		return nil, fmt.Errorf("AI payment processing is not enabled for this user")
 	}
 
-	// Parse the natural language prompt using simple pattern matching
-	// Note: Gemini AI integration can be added later
-	analysisResult := s.analyzePaymentPrompt(req.Prompt)
+	// Extract the payment details from the natural language prompt
+	analysisResult, err := s.analyzePaymentPrompt(req.Prompt)
+	if err != nil {
+		return nil, err
+	}
+	if analysisResult.Currency != intent.DefaultCurrency {
+		return nil, fmt.Errorf("only %s payments are supported, prompt asks for %s", intent.DefaultCurrency, analysisResult.Currency)
+	}
 
 	// Override with explicit values if provided
 	if req.Amount > 0 {
@@ -52,30 +165,104 @@ func (s *AIService) ProcessPaymentRequest(userID uuid.UUID, req models.AIPayment
 		analysisResult.Description = req.Description
 	}
 
+	// Find where the money should go
+	upiID, upiSource, err := s.resolveMerchantUPI(userID, analysisResult, strings.TrimSpace(req.MerchantUPI))
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply the agent's own rules before the user-wide ones, to the merchant
+	// and UPI ID actually paid
+	agentID := strings.TrimSpace(req.AgentID)
+	category := strings.TrimSpace(req.Category)
+	agentDecision, err := s.agentService.CheckPayment(userID, AgentPayment{
+		AgentID:    agentID,
+		Amount:     models.DecimalFromFloat64(analysisResult.Amount),
+		Merchant:   registeredMerchant(upiSource, analysisResult.Merchant),
+		UPIID:      upiID,
+		Payee:      analysisResult.payee(),
+		Category:   category,
+		Timezone:   limits.Timezone,
+		FromAPIKey: req.FromAPIKey,
+		KeyAgentID: req.APIKeyAgentID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// An unbound API key naming an agent passes only while the user has no
+	// agents, and its payment is not recorded as that agent's
+	if req.FromAPIKey && agentID != req.APIKeyAgentID {
+		agentID = ""
+	}
+
 	// Validate against spending limits
 	if err := s.validateSpendingLimits(userID, analysisResult.Amount); err != nil {
 		return nil, err
 	}
 
-	// Assess risk level
-	riskLevel := s.assessRiskLevel(analysisResult.Amount, analysisResult.Merchant)
+	// Score the payment. The risk checks can refuse it outright; asking for
+	// confirmation is handled below with the user's and agent's own settings.
+	riskRecipient := upiID
+	if riskRecipient == "" {
+		riskRecipient = analysisResult.payee()
+	}
+	assessment, err := s.riskService.Assess(&risk.Payment{
+		Kind:      risk.KindAIPayment,
+		UserID:    userID,
+		Amount:    models.DecimalFromFloat64(analysisResult.Amount),
+		Recipient: riskRecipient,
+		IPAddress: req.IPAddress,
+	}, "")
+	var riskErr *RiskDecisionError
+	if err != nil && !(errors.As(err, &riskErr) && riskErr.NeedsConfirmation()) {
+		return nil, err
+	}
+	riskNeedsConfirmation := riskErr != nil
+
+	// The request is paid only when confirmed with this token, and large
+	// payments also need the user's password or authenticator code
+	token, err := utils.GenerateSecureKey()
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(AIPaymentConfirmationTTL)
+	stepUpRequired := limits.RequireStepUp && analysisResult.Amount >= limits.ConfirmationThreshold
+	var stepUpMethods []string
+	if stepUpRequired {
+		if stepUpMethods, err = s.stepUpService.Methods(context.Background(), userID); err != nil {
+			return nil, err
+		}
+	}
 
 	// Create AI payment request record
 	paymentRequest := &models.AIPaymentRequest{
-		UserID:       userID,
-		Amount:       analysisResult.Amount,
-		Description:  analysisResult.Description,
-		MerchantName: analysisResult.Merchant,
-		AIPrompt:     req.Prompt,
-		Status:       "pending",
-		AIResponse:   analysisResult.AIReasoning,
-		RiskLevel:    riskLevel,
-		Confidence:   analysisResult.Confidence,
+		UserID:        userID,
+		AgentID:       agentID,
+		Amount:        analysisResult.Amount,
+		Description:   analysisResult.Description,
+		MerchantName:  analysisResult.Merchant,
+		MerchantUPIID: upiID,
+		UPISource:     upiSource,
+		Recipient:     analysisResult.Recipient,
+		Category:      category,
+		AIPrompt:      req.Prompt,
+		Status:        "pending",
+		AIResponse:    analysisResult.AIReasoning,
+		RiskLevel:     assessment.Level,
+		RiskScore:     assessment.Score,
+		IPAddress:     req.IPAddress,
+		Confidence:    analysisResult.Confidence,
+
+		ConfirmationTokenHash: utils.HashKey(token),
+		ConfirmationExpiresAt: &expiresAt,
+		StepUpRequired:        stepUpRequired,
 	}
 
 	if err := s.db.Create(paymentRequest).Error; err != nil {
 		return nil, fmt.Errorf("failed to create payment request: %v", err)
 	}
+	s.riskService.LinkAssessment(assessment, paymentRequest.ID)
 
 	// Get user's wallet balance
 	var wallet models.Wallet
@@ -83,8 +270,25 @@ func (s *AIService) ProcessPaymentRequest(userID uuid.UUID, req models.AIPayment
 		return nil, fmt.Errorf("failed to get wallet balance: %v", err)
 	}
 
-	// Calculate remaining daily limit
-	remainingLimit, err := s.calculateRemainingDailyLimit(userID)
+	// Reserve the amount until the request is confirmed or cancelled. A request
+	// the wallet cannot cover is still created so the user sees the suggestion.
+	if paymentRequest.Amount > 0 {
+		_, err := s.holdService.PlaceHold(
+			nil,
+			wallet.ID,
+			models.DecimalFromFloat64(paymentRequest.Amount),
+			models.WalletHoldPurposeAIPayment,
+			paymentRequest.ID.String(),
+			fmt.Sprintf("AI Payment: %s", paymentRequest.Description),
+			AIPaymentHoldTTL,
+		)
+		if err != nil && !errors.Is(err, ErrInsufficientAvailableBalance) {
+			return nil, fmt.Errorf("failed to reserve wallet balance: %v", err)
+		}
+	}
+
+	// Calculate remaining limit
+	remainingLimit, err := s.calculateRemainingLimit(userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate remaining limit: %v", err)
 	}
@@ -92,30 +296,48 @@ func (s *AIService) ProcessPaymentRequest(userID uuid.UUID, req models.AIPayment
 	// Determine if confirmation is required
 	requiresConfirmation := analysisResult.Amount >= limits.ConfirmationThreshold ||
 		limits.RequireConfirmation ||
-		riskLevel == "high"
+		riskNeedsConfirmation
+	if agentDecision.Config != nil {
+		// A configured agent's approval settings replace the user's, but
+		// payments the risk checks flag are always confirmed
+		requiresConfirmation = agentDecision.RequiresApproval || riskNeedsConfirmation
+	}
+	requiresConfirmation = requiresConfirmation || stepUpRequired
 
 	// Convert decimal to float64 for response
 	walletBalance, _ := wallet.Balance.Float64()
 
 	response := &models.AIPaymentResponse{
 		ID:                   paymentRequest.ID.String(),
+		AgentID:              agentID,
 		Amount:               analysisResult.Amount,
 		Merchant:             analysisResult.Merchant,
+		MerchantUPIID:        upiID,
+		Recipient:            analysisResult.Recipient,
 		Description:          analysisResult.Description,
 		Confidence:           analysisResult.Confidence,
-		RiskLevel:            riskLevel,
+		RiskLevel:            assessment.Level,
+		RiskScore:            assessment.Score,
+		RiskReasons:          assessment.Messages(),
 		RequiresConfirmation: requiresConfirmation,
 		AIReasoning:          analysisResult.AIReasoning,
 		WalletBalance:        walletBalance,
 		RemainingLimit:       remainingLimit,
-		Suggestions:          s.generateSuggestions(analysisResult, walletBalance, remainingLimit),
+		Suggestions:          s.generateSuggestions(analysisResult, upiID, walletBalance, remainingLimit),
+
+		ConfirmationToken:     token,
+		ConfirmationExpiresAt: &expiresAt,
+		StepUpRequired:        stepUpRequired,
+		StepUpMethods:         stepUpMethods,
 	}
 
 	return response, nil
 }
 
-// ConfirmPayment processes confirmed payment requests
-func (s *AIService) ConfirmPayment(userID, paymentID uuid.UUID, confirmed bool) (interface{}, error) {
+// ConfirmPayment confirms or cancels a payment request with its confirmation
+// token. The token works once and only until it expires; requests above the
+// user's confirmation threshold may also need a password or authenticator code.
+func (s *AIService) ConfirmPayment(userID, paymentID uuid.UUID, attempt *PaymentConfirmation) (interface{}, error) {
 	// Get the payment request
 	var paymentRequest models.AIPaymentRequest
 	if err := s.db.Where("id = ? AND user_id = ?", paymentID, userID).First(&paymentRequest).Error; err != nil {
@@ -126,82 +348,215 @@ func (s *AIService) ConfirmPayment(userID, paymentID uuid.UUID, confirmed bool)
 		return nil, fmt.Errorf("payment request is not in pending status")
 	}
 
-	if !confirmed {
-		// Mark as cancelled
-		paymentRequest.Status = "cancelled"
-		if err := s.db.Save(&paymentRequest).Error; err != nil {
-			return nil, fmt.Errorf("failed to cancel payment: %v", err)
+	if paymentRequest.ConfirmationExpiresAt != nil && time.Now().After(*paymentRequest.ConfirmationExpiresAt) {
+		s.expirePaymentRequest(&paymentRequest)
+		return nil, ErrConfirmationExpired
+	}
+
+	if paymentRequest.ConfirmationTokenHash == "" ||
+		!utils.SecureCompare(utils.HashKey(attempt.Token), paymentRequest.ConfirmationTokenHash) {
+		return nil, s.rejectConfirmation(&paymentRequest, &attempt.ConfirmationSource, "", ErrConfirmationTokenInvalid)
+	}
+
+	if !attempt.Confirmed {
+		if err := s.cancelPaymentRequest(&paymentRequest, &attempt.ConfirmationSource, "Payment request cancelled"); err != nil {
+			return nil, err
 		}
 		return gin.H{"status": "cancelled", "message": "Payment request cancelled"}, nil
 	}
 
+	var stepUpMethod string
+	if paymentRequest.StepUpRequired {
+		method, err := s.stepUpService.Verify(context.Background(), userID, attempt.Password, attempt.TOTPCode)
+		if errors.Is(err, ErrStepUpFailed) {
+			return nil, s.rejectConfirmation(&paymentRequest, &attempt.ConfirmationSource, method, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		stepUpMethod = method
+	}
+
+	if paymentRequest.MerchantUPIID == "" {
+		return nil, fmt.Errorf("no UPI ID is known for %s; send the request again with merchant_upi_id", describePayee(&paymentRequest))
+	}
+
 	// Get wallet for transaction
 	var wallet models.Wallet
 	if err := s.db.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
 		return nil, fmt.Errorf("failed to get wallet: %v", err)
 	}
 
-	// Process the payment - create actual transaction
-	transaction := &models.Transaction{
-		WalletID:     wallet.ID,
-		UserID:       userID,
-		Type:         "debit",
-		Amount:       models.DecimalFromFloat64(paymentRequest.Amount),
-		Description:  fmt.Sprintf("AI Payment: %s", paymentRequest.Description),
-		Status:       models.StatusSuccess,
-		BalanceAfter: wallet.Balance.Sub(models.DecimalFromFloat64(paymentRequest.Amount)),
+	// The agent's rules may have changed, or its daily limit been used up,
+	// since the request was made
+	limits, err := s.GetSpendingLimits(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spending limits: %v", err)
+	}
+	agentDecision, err := s.agentService.CheckPayment(userID, AgentPayment{
+		AgentID:  paymentRequest.AgentID,
+		Amount:   models.DecimalFromFloat64(paymentRequest.Amount),
+		Merchant: registeredMerchant(paymentRequest.UPISource, paymentRequest.MerchantName),
+		UPIID:    paymentRequest.MerchantUPIID,
+		Payee:    paymentRequestPayee(&paymentRequest),
+		Category: paymentRequest.Category,
+		Timezone: limits.Timezone,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Other payments may have used up the user's AI limits since the request
+	if err := s.validateSpendingLimits(userID, paymentRequest.Amount); err != nil {
+		return nil, err
 	}
 
-	// Start database transaction
-	tx := s.db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	// Pay the merchant through a UPI payout. The request's hold is swapped for
+	// the payout's, and the request completes when the payout does.
+	var transfer *models.ExternalTransfer
+	var transaction *models.Transaction
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Claiming the request also spends the token
+		now := time.Now()
+		result := tx.Model(&models.AIPaymentRequest{}).
+			Where("id = ? AND status = ? AND confirmation_token_hash = ?", paymentRequest.ID, "pending", paymentRequest.ConfirmationTokenHash).
+			Updates(map[string]interface{}{
+				"status":                  "processing",
+				"confirmation_token_hash": "",
+				"confirmed_at":            now,
+				"confirmed_via":           attempt.Channel,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to update payment request: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("payment request is not in pending status")
+		}
+		if err := s.recordConfirmation(tx, &paymentRequest, models.AIConfirmationActionConfirmed, &attempt.ConfirmationSource, stepUpMethod, ""); err != nil {
+			return err
 		}
-	}()
 
-	// Create transaction record
-	if err := tx.Create(transaction).Error; err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("failed to create transaction: %v", err)
-	}
+		// Nothing to release if the hold already expired
+		s.holdService.ReleaseHold(tx, paymentRequest.ID.String(), "Moved to merchant payout")
+
+		// Enforce wallet limits with the wallet locked, now the request's own
+		// hold no longer counts towards them
+		if err := s.limitsService.CheckDebitWithTx(tx, wallet.ID, DebitKindAIPayment, models.DecimalFromFloat64(paymentRequest.Amount)); err != nil {
+			return err
+		}
+
+		// The checks above were made without the lock; payments confirmed
+		// since then are counted now
+		if err := s.validateSpendingLimitsWithTx(tx, userID, paymentRequest.Amount); err != nil {
+			return err
+		}
+		if err := s.agentService.CheckDailyLimitWithTx(tx, userID, agentDecision.Config, models.DecimalFromFloat64(paymentRequest.Amount), limits.Timezone, paymentRequest.ID); err != nil {
+			return err
+		}
+
+		var err error
+		transfer, transaction, err = s.transferService.CreateMerchantPayout(tx, &MerchantPayout{
+			UserID:       userID,
+			WalletID:     wallet.ID,
+			Amount:       models.DecimalFromFloat64(paymentRequest.Amount),
+			MerchantName: describePayee(&paymentRequest),
+			UPIID:        paymentRequest.MerchantUPIID,
+			Description:  fmt.Sprintf("AI Payment: %s", paymentRequest.Description),
+			IPAddress:    paymentRequest.IPAddress,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := s.riskService.ConfirmAssessment(tx, paymentRequest.ID); err != nil {
+			return err
+		}
 
-	// Update wallet balance
-	wallet.Balance = wallet.Balance.Sub(models.DecimalFromFloat64(paymentRequest.Amount))
-	if err := tx.Save(&wallet).Error; err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("failed to update wallet: %v", err)
+		return tx.Model(&models.AIPaymentRequest{}).
+			Where("id = ?", paymentRequest.ID).
+			Updates(map[string]interface{}{
+				"transfer_id":    transfer.ID,
+				"transaction_id": transaction.ID.String(),
+			}).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrInsufficientAvailableBalance) {
+			return nil, errors.New("insufficient wallet balance")
+		}
+		return nil, fmt.Errorf("failed to process payment: %w", err)
 	}
 
-	// Update payment request
-	paymentRequest.Status = "processed"
-	now := time.Now()
-	paymentRequest.ProcessedAt = &now
-	paymentRequest.TransactionID = transaction.ID.String()
+	return gin.H{
+		"status":          "processing",
+		"transaction_id":  transaction.ID.String(),
+		"transfer_id":     transfer.ID.String(),
+		"reference_id":    transfer.ReferenceID,
+		"amount":          paymentRequest.Amount,
+		"merchant":        paymentRequest.MerchantName,
+		"merchant_upi_id": paymentRequest.MerchantUPIID,
+		"message":         "Payment confirmed, paying the merchant by UPI",
+	}, nil
+}
 
-	if err := tx.Save(&paymentRequest).Error; err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("failed to update payment request: %v", err)
+// handleMerchantPayoutComplete moves an AI payment request to the final state
+// of the payout that settled it
+func (s *AIService) handleMerchantPayoutComplete(transfer *models.ExternalTransfer) {
+	if transfer.InitiatedBy != models.InitiatedByAI {
+		return
 	}
 
-	// Update spending tracker
-	if err := s.updateSpendingTracker(tx, userID, paymentRequest.Amount); err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("failed to update spending tracker: %v", err)
+	var paymentRequest models.AIPaymentRequest
+	if err := s.db.Where("transfer_id = ?", transfer.ID).First(&paymentRequest).Error; err != nil {
+		utils.LogError(err, map[string]interface{}{"transfer_id": transfer.ID.String(), "action": "get_ai_payment_for_transfer"})
+		return
 	}
 
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	var err error
+	switch transfer.Status {
+	case models.ExternalTransferStatusSuccess:
+		if paymentRequest.Status != "processing" {
+			return
+		}
+		err = s.db.Transaction(func(tx *gorm.DB) error {
+			now := time.Now()
+			if err := tx.Model(&paymentRequest).Updates(map[string]interface{}{
+				"status":       "processed",
+				"processed_at": &now,
+			}).Error; err != nil {
+				return err
+			}
+			return s.updateSpendingTracker(tx, paymentRequest.UserID)
+		})
+	case models.ExternalTransferStatusFailed, models.ExternalTransferStatusCancelled, models.ExternalTransferStatusRefunded:
+		if paymentRequest.Status == "failed" {
+			return
+		}
+		reason := transfer.FailureReason
+		if reason == "" {
+			reason = "Payout to merchant " + transfer.Status
+		}
+		err = s.db.Model(&paymentRequest).Updates(map[string]interface{}{
+			"status":         "failed",
+			"failure_reason": reason,
+		}).Error
+	default:
+		return
 	}
 
-	return gin.H{
-		"status":         "processed",
-		"transaction_id": transaction.ID.String(),
-		"amount":         paymentRequest.Amount,
-		"merchant":       paymentRequest.MerchantName,
-		"message":        "Payment processed successfully",
-	}, nil
+	if err != nil {
+		utils.LogError(err, map[string]interface{}{
+			"payment_id":  paymentRequest.ID.String(),
+			"transfer_id": transfer.ID.String(),
+			"action":      "update_ai_payment_for_transfer",
+		})
+		return
+	}
+
+	utils.LogInfo("AI payment settled", map[string]interface{}{
+		"payment_id":      paymentRequest.ID.String(),
+		"transfer_id":     transfer.ID.String(),
+		"transfer_status": transfer.Status,
+	})
 }
 
 // GetPaymentHistory retrieves AI payment history for a user
@@ -239,7 +594,9 @@ func (s *AIService) GetSpendingLimits(userID uuid.UUID) (*models.AISpendingLimit
 				UserID:                userID,
 				DailyLimit:            10000,
 				TransactionLimit:      2000,
+				WeeklyLimit:           50000,
 				MonthlyLimit:          100000,
+				Timezone:              period.DefaultTimezone,
 				AIAccessEnabled:       false,
 				RequireConfirmation:   true,
 				ConfirmationThreshold: 1000,
@@ -261,13 +618,24 @@ func (s *AIService) UpdateSpendingLimits(userID uuid.UUID, req models.AISpending
 		return nil, err
 	}
 
+	if req.Timezone != "" && !period.Valid(req.Timezone) {
+		return nil, fmt.Errorf("unknown timezone %q, use an IANA name such as Asia/Kolkata", req.Timezone)
+	}
+
 	// Update limits
 	limits.DailyLimit = req.DailyLimit
 	limits.TransactionLimit = req.TransactionLimit
 	limits.MonthlyLimit = req.MonthlyLimit
+	if req.WeeklyLimit != nil {
+		limits.WeeklyLimit = *req.WeeklyLimit
+	}
+	if req.Timezone != "" {
+		limits.Timezone = req.Timezone
+	}
 	limits.AIAccessEnabled = req.AIAccessEnabled
 	limits.RequireConfirmation = req.RequireConfirmation
 	limits.ConfirmationThreshold = req.ConfirmationThreshold
+	limits.RequireStepUp = req.RequireStepUp
 
 	if err := s.db.Save(limits).Error; err != nil {
 		return nil, fmt.Errorf("failed to update spending limits: %v", err)
@@ -277,13 +645,18 @@ func (s *AIService) UpdateSpendingLimits(userID uuid.UUID, req models.AISpending
 }
 
 // GetSpendingAnalytics provides spending insights
-func (s *AIService) GetSpendingAnalytics(userID uuid.UUID, period string) (interface{}, error) {
+func (s *AIService) GetSpendingAnalytics(userID uuid.UUID, span string) (interface{}, error) {
+	limits, err := s.GetSpendingLimits(userID)
+	if err != nil {
+		return nil, err
+	}
+
 	var startDate time.Time
-	now := time.Now()
+	now := time.Now().In(period.Location(limits.Timezone))
 
-	switch period {
+	switch span {
 	case "day":
-		startDate = now.Truncate(24 * time.Hour)
+		startDate = period.StartOfDay(now)
 	case "week":
 		startDate = now.AddDate(0, 0, -7)
 	case "month":
@@ -329,7 +702,7 @@ func (s *AIService) GetSpendingAnalytics(userID uuid.UUID, period string) (inter
 	}
 
 	return gin.H{
-		"period":            period,
+		"period":            span,
 		"total_spent":       totalSpent,
 		"transaction_count": transactionCount,
 		"average_amount": func() float64 {
@@ -340,7 +713,7 @@ func (s *AIService) GetSpendingAnalytics(userID uuid.UUID, period string) (inter
 		}(),
 		"top_merchants": topMerchants,
 		"insights": []string{
-			fmt.Sprintf("You've spent %.2f in the last %s", totalSpent, period),
+			fmt.Sprintf("You've spent %.2f in the last %s", totalSpent, span),
 			fmt.Sprintf("Average transaction amount: %.2f", func() float64 {
 				if transactionCount > 0 {
 					return totalSpent / float64(transactionCount)
@@ -360,8 +733,9 @@ func (s *AIService) GetPaymentRequest(userID, paymentID uuid.UUID) (*models.AIPa
 	return &payment, nil
 }
 
-// CancelPaymentRequest cancels a pending payment request
-func (s *AIService) CancelPaymentRequest(userID, paymentID uuid.UUID) error {
+// CancelPaymentRequest cancels a pending payment request. Cancelling needs no
+// confirmation token since it cannot move money.
+func (s *AIService) CancelPaymentRequest(userID, paymentID uuid.UUID, source *ConfirmationSource) error {
 	var payment models.AIPaymentRequest
 	if err := s.db.Where("id = ? AND user_id = ?", paymentID, userID).First(&payment).Error; err != nil {
 		return fmt.Errorf("payment request not found: %v", err)
@@ -371,236 +745,568 @@ func (s *AIService) CancelPaymentRequest(userID, paymentID uuid.UUID) error {
 		return fmt.Errorf("can only cancel pending payment requests")
 	}
 
-	payment.Status = "cancelled"
-	if err := s.db.Save(&payment).Error; err != nil {
-		return fmt.Errorf("failed to cancel payment: %v", err)
+	return s.cancelPaymentRequest(&payment, source, "Payment request cancelled")
+}
+
+// GetPaymentConfirmations returns a payment request's confirmation audit trail,
+// oldest first
+func (s *AIService) GetPaymentConfirmations(userID, paymentID uuid.UUID) ([]models.AIPaymentConfirmation, error) {
+	if _, err := s.GetPaymentRequest(userID, paymentID); err != nil {
+		return nil, err
+	}
+
+	var confirmations []models.AIPaymentConfirmation
+	if err := s.db.Where("payment_request_id = ?", paymentID).Order("created_at ASC").Find(&confirmations).Error; err != nil {
+		return nil, fmt.Errorf("failed to get confirmations: %w", err)
+	}
+	return confirmations, nil
+}
+
+// ExpirePaymentRequests expires pending requests whose confirmation token ran
+// out. Requests from before tokens were issued expire by age.
+func (s *AIService) ExpirePaymentRequests() (int, error) {
+	now := time.Now()
+	var requests []models.AIPaymentRequest
+	if err := s.db.Where("status = ?", "pending").
+		Where("confirmation_expires_at < ? OR (confirmation_expires_at IS NULL AND created_at < ?)", now, now.Add(-AIPaymentConfirmationTTL)).
+		Limit(100).
+		Find(&requests).Error; err != nil {
+		return 0, fmt.Errorf("failed to get stale payment requests: %w", err)
+	}
+
+	expired := 0
+	for i := range requests {
+		if s.expirePaymentRequest(&requests[i]) {
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+// StartConfirmationSweeper expires stale payment requests on a fixed interval
+func (s *AIService) StartConfirmationSweeper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		expired, err := s.ExpirePaymentRequests()
+		if err != nil {
+			utils.LogError(err, map[string]interface{}{"action": "expire_ai_payment_requests"})
+			continue
+		}
+		if expired > 0 {
+			utils.LogInfo("Expired AI payment requests", map[string]interface{}{"count": expired})
+		}
+	}
+}
+
+// cancelPaymentRequest moves a pending request to cancelled and frees its hold
+func (s *AIService) cancelPaymentRequest(payment *models.AIPaymentRequest, source *ConfirmationSource, reason string) error {
+	if !s.closePaymentRequest(payment, "cancelled", models.AIConfirmationActionCancelled, source, reason) {
+		return fmt.Errorf("can only cancel pending payment requests")
+	}
+	return nil
+}
+
+// expirePaymentRequest moves a pending request to expired and frees its hold.
+// Returns false if the request was no longer pending.
+func (s *AIService) expirePaymentRequest(payment *models.AIPaymentRequest) bool {
+	source := &ConfirmationSource{Channel: models.AIConfirmationChannelSystem}
+	return s.closePaymentRequest(payment, "expired", models.AIConfirmationActionExpired, source, "Confirmation token expired")
+}
+
+// closePaymentRequest ends a pending request without paying it, recording who
+// did so. Returns false if the request was no longer pending.
+func (s *AIService) closePaymentRequest(payment *models.AIPaymentRequest, status, action string, source *ConfirmationSource, reason string) bool {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.AIPaymentRequest{}).
+			Where("id = ? AND status = ?", payment.ID, "pending").
+			Updates(map[string]interface{}{
+				"status":                  status,
+				"confirmation_token_hash": "",
+				"failure_reason":          reason,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("payment request is not in pending status")
+		}
+		return s.recordConfirmation(tx, payment, action, source, "", reason)
+	})
+	if err != nil {
+		return false
 	}
 
+	payment.Status = status
+	s.releasePaymentHold(payment.ID, reason)
+	return true
+}
+
+// rejectConfirmation records a failed confirmation attempt and returns cause.
+// The request is cancelled once it has had too many.
+func (s *AIService) rejectConfirmation(payment *models.AIPaymentRequest, source *ConfirmationSource, stepUpMethod string, cause error) error {
+	if err := s.recordConfirmation(nil, payment, models.AIConfirmationActionRejected, source, stepUpMethod, cause.Error()); err != nil {
+		utils.LogError(err, map[string]interface{}{"payment_id": payment.ID.String(), "action": "record_ai_payment_confirmation"})
+	}
+
+	if err := s.db.Model(&models.AIPaymentRequest{}).
+		Where("id = ?", payment.ID).
+		Update("confirmation_attempts", gorm.Expr("confirmation_attempts + 1")).Error; err != nil {
+		return fmt.Errorf("failed to record confirmation attempt: %w", err)
+	}
+	var attempts int
+	if err := s.db.Model(&models.AIPaymentRequest{}).
+		Where("id = ?", payment.ID).
+		Select("confirmation_attempts").
+		Scan(&attempts).Error; err != nil {
+		return fmt.Errorf("failed to read confirmation attempts: %w", err)
+	}
+
+	if attempts >= MaxConfirmationAttempts {
+		system := &ConfirmationSource{Channel: models.AIConfirmationChannelSystem}
+		s.closePaymentRequest(payment, "cancelled", models.AIConfirmationActionCancelled, system, "Too many failed confirmation attempts")
+		return ErrTooManyConfirmationAttempts
+	}
+	return cause
+}
+
+// recordConfirmation adds an entry to a payment request's audit trail
+func (s *AIService) recordConfirmation(tx *gorm.DB, payment *models.AIPaymentRequest, action string, source *ConfirmationSource, stepUpMethod, reason string) error {
+	db := s.db
+	if tx != nil {
+		db = tx
+	}
+
+	confirmation := &models.AIPaymentConfirmation{
+		PaymentRequestID: payment.ID,
+		UserID:           payment.UserID,
+		Action:           action,
+		Channel:          source.Channel,
+		Actor:            source.Actor,
+		StepUpMethod:     stepUpMethod,
+		Reason:           reason,
+		IPAddress:        source.IPAddress,
+		UserAgent:        source.UserAgent,
+	}
+	if err := db.Create(confirmation).Error; err != nil {
+		return fmt.Errorf("failed to record confirmation: %w", err)
+	}
 	return nil
 }
 
+// releasePaymentHold frees the funds reserved for a payment request. Requests
+// that never got a hold, or whose hold already expired, have nothing to release.
+func (s *AIService) releasePaymentHold(paymentID uuid.UUID, reason string) {
+	s.holdService.ReleaseHold(nil, paymentID.String(), reason)
+}
+
 // Private helper methods
 
 type PaymentAnalysis struct {
 	Amount      float64
+	Currency    string
 	Merchant    string
+	Recipient   string
 	Description string
 	Confidence  float64
 	AIReasoning string
+	Provider    string
 }
 
-func (s *AIService) analyzePaymentPrompt(prompt string) *PaymentAnalysis {
-	// Enhanced pattern matching - fallback when Gemini is not available
-	prompt = strings.ToLower(prompt)
-
-	analysis := &PaymentAnalysis{
-		Confidence:  0.6, // Start with lower confidence
-		AIReasoning: "Pattern matching analysis",
+// payee is the merchant, or the recipient when no merchant was named
+func (a *PaymentAnalysis) payee() string {
+	if a.Merchant != "" {
+		return a.Merchant
 	}
+	return a.Recipient
+}
 
-	// Enhanced amount extraction with multiple patterns
-	amountPatterns := []string{
-		`(?:rs\.?|rupees?|₹)\s*(\d+(?:,\d{3})*(?:\.\d{2})?)`, // Rs. 1,000.00
-		`(\d+(?:,\d{3})*(?:\.\d{2})?)\s*(?:rs\.?|rupees?|₹)`, // 1000 rs
-		`(\d+(?:,\d{3})*(?:\.\d{2})?)\s*(?:only|/-)?`,        // 1000 only
+// resolveMerchantUPI finds the UPI ID to pay. A registered merchant is always
+// paid at its registered UPI ID, under its registered name, and a different
+// UPI ID sent with the request or written in the prompt is refused. Otherwise
+// it tries in turn the UPI ID sent with the request, one written in the prompt
+// and the user's last settled payment to the same merchant. No match is not
+// an error; the request is created and can only be confirmed once a UPI ID is
+// given.
+func (s *AIService) resolveMerchantUPI(userID uuid.UUID, analysis *PaymentAnalysis, explicit string) (string, string, error) {
+	if explicit != "" && !upiIDRegex.MatchString(explicit) {
+		return "", "", errors.New("invalid merchant UPI ID format")
+	}
+	fromPrompt := ""
+	if upiIDRegex.MatchString(analysis.Recipient) {
+		fromPrompt = analysis.Recipient
 	}
 
-	for _, pattern := range amountPatterns {
-		amountRegex := regexp.MustCompile(pattern)
-		if matches := amountRegex.FindStringSubmatch(prompt); len(matches) > 1 {
-			amountStr := strings.ReplaceAll(matches[1], ",", "") // Remove commas
-			if amount, err := strconv.ParseFloat(amountStr, 64); err == nil && amount > 0 {
-				analysis.Amount = amount
-				analysis.Confidence += 0.3
-				break
+	if analysis.Merchant != "" {
+		if merchant, err := s.merchantService.FindMerchant(analysis.Merchant); err == nil {
+			for _, given := range []string{explicit, fromPrompt} {
+				if given != "" && !strings.EqualFold(given, merchant.UPIID) {
+					return "", "", fmt.Errorf("%s is not the registered UPI ID of %s", given, merchant.Name)
+				}
 			}
+			analysis.Merchant = merchant.Name
+			return merchant.UPIID, models.MerchantUPISourceRegistry, nil
 		}
 	}
 
-	// Enhanced merchant extraction with better patterns
-	merchantPatterns := []string{
-		`(?:pay|send|transfer)\s+(?:to\s+)?([a-z][a-z0-9\s]{1,30})(?:\s+for|\s+rs|\s*₹|\s*$)`,
-		`(?:for|at|from)\s+([a-z][a-z0-9\s]{1,30})(?:\s+for|\s+rs|\s*₹|\s*$)`,
-		`(?:to|towards)\s+([a-z][a-z0-9\s]{1,30})(?:\s+for|\s+rs|\s*₹|\s*$)`,
-	}
-
-	for _, pattern := range merchantPatterns {
-		merchantRegex := regexp.MustCompile(pattern)
-		if matches := merchantRegex.FindStringSubmatch(prompt); len(matches) > 1 {
-			merchant := strings.TrimSpace(matches[1])
-			// Clean up common words
-			merchant = regexp.MustCompile(`\b(the|and|for|of|in|on|at|to|from)\b`).ReplaceAllString(merchant, "")
-			merchant = strings.TrimSpace(merchant)
-			if len(merchant) > 2 {
-				analysis.Merchant = strings.Title(merchant)
-				analysis.Confidence += 0.2
-				break
-			}
-		}
+	if explicit != "" {
+		return explicit, models.MerchantUPISourceRequest, nil
+	}
+	if fromPrompt != "" {
+		return fromPrompt, models.MerchantUPISourcePrompt, nil
 	}
 
-	// Extract purpose/description
-	purposePatterns := []string{
-		`for\s+([a-z\s]{3,30})(?:\s|$)`,
-		`(?:buying|purchasing)\s+([a-z\s]{3,30})(?:\s|$)`,
-		`(?:payment for|bill for)\s+([a-z\s]{3,30})(?:\s|$)`,
+	if analysis.Merchant == "" {
+		return "", "", nil
 	}
 
-	var purpose string
-	for _, pattern := range purposePatterns {
-		purposeRegex := regexp.MustCompile(pattern)
-		if matches := purposeRegex.FindStringSubmatch(prompt); len(matches) > 1 {
-			purpose = strings.TrimSpace(matches[1])
-			if len(purpose) > 2 {
-				analysis.Confidence += 0.1
-				break
-			}
-		}
+	var previous models.AIPaymentRequest
+	err := s.db.Where("user_id = ? AND LOWER(merchant_name) = ? AND status = 'processed' AND merchant_upi_id <> ''",
+		userID, strings.ToLower(analysis.Merchant)).
+		Order("processed_at DESC").
+		First(&previous).Error
+	if err == nil {
+		return previous.MerchantUPIID, models.MerchantUPISourceHistory, nil
 	}
 
-	// Generate intelligent description
-	if analysis.Merchant != "" && analysis.Amount > 0 {
-		if purpose != "" {
-			analysis.Description = fmt.Sprintf("Payment of Rs. %.2f to %s for %s", analysis.Amount, analysis.Merchant, purpose)
-		} else {
-			analysis.Description = fmt.Sprintf("Payment of Rs. %.2f to %s", analysis.Amount, analysis.Merchant)
-		}
-	} else if analysis.Amount > 0 {
-		analysis.Description = fmt.Sprintf("Payment of Rs. %.2f", analysis.Amount)
-	} else if analysis.Merchant != "" {
-		analysis.Description = fmt.Sprintf("Payment to %s", analysis.Merchant)
-	} else {
-		analysis.Description = "Payment request"
-		analysis.Confidence = 0.3 // Low confidence for unclear requests
+	return "", "", nil
+}
+
+// registeredMerchant returns the merchant name of a payment whose UPI ID came
+// from the merchant registry, and nothing for payees the registry does not know
+func registeredMerchant(upiSource, merchantName string) string {
+	if upiSource != models.MerchantUPISourceRegistry {
+		return ""
 	}
+	return merchantName
+}
 
-	// Update reasoning based on what was extracted
-	reasoningParts := []string{}
-	if analysis.Amount > 0 {
-		reasoningParts = append(reasoningParts, fmt.Sprintf("extracted amount: Rs. %.2f", analysis.Amount))
+// describePayee names who an AI payment goes to, for transfer records
+func describePayee(paymentRequest *models.AIPaymentRequest) string {
+	if payee := paymentRequestPayee(paymentRequest); payee != "" {
+		return payee
 	}
-	if analysis.Merchant != "" {
-		reasoningParts = append(reasoningParts, fmt.Sprintf("identified merchant: %s", analysis.Merchant))
+	return paymentRequest.MerchantUPIID
+}
+
+func paymentRequestPayee(paymentRequest *models.AIPaymentRequest) string {
+	if paymentRequest.MerchantName != "" {
+		return paymentRequest.MerchantName
 	}
-	if purpose != "" {
-		reasoningParts = append(reasoningParts, fmt.Sprintf("payment purpose: %s", purpose))
+	return paymentRequest.Recipient
+}
+
+// analyzePaymentPrompt runs the prompt through the configured intent provider
+func (s *AIService) analyzePaymentPrompt(prompt string) (*PaymentAnalysis, error) {
+	extracted, err := s.intentProvider.Extract(context.Background(), prompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze payment prompt: %w", err)
 	}
 
-	if len(reasoningParts) > 0 {
-		analysis.AIReasoning = "Successfully " + strings.Join(reasoningParts, ", ")
-	} else {
-		analysis.AIReasoning = "Could not extract clear payment details from prompt"
+	reasoning := extracted.Reasoning
+	if reasoning == "" {
+		reasoning = "No reasoning provided"
 	}
 
-	return analysis
+	return &PaymentAnalysis{
+		Amount:      extracted.Amount,
+		Currency:    extracted.Currency,
+		Merchant:    extracted.Merchant,
+		Recipient:   extracted.Recipient,
+		Description: extracted.Describe(),
+		Confidence:  extracted.Confidence,
+		AIReasoning: fmt.Sprintf("[%s] %s", extracted.Provider, reasoning),
+		Provider:    extracted.Provider,
+	}, nil
 }
 
+// aiSpending is what a user has spent on AI payments and AI shopping in each
+// calendar period, read in their time zone
+type aiSpending struct {
+	now          time.Time
+	dailySpent   decimal.Decimal
+	weeklySpent  decimal.Decimal
+	monthlySpent decimal.Decimal
+	dailyCount   int
+	weeklyCount  int
+	monthlyCount int
+	lastAt       *time.Time
+}
+
+// aiSpendingWindow is one of the user's AI limits with what it has left
+type aiSpendingWindow struct {
+	name    string
+	allowed decimal.Decimal
+	used    decimal.Decimal
+}
+
+func (w aiSpendingWindow) remaining() decimal.Decimal {
+	return decimal.Max(w.allowed.Sub(w.used), decimal.Zero)
+}
+
+// spendingWindows pairs the user's day, week and month limits with spending
+func spendingWindows(limits *models.AISpendingLimit, spending *aiSpending) []aiSpendingWindow {
+	return []aiSpendingWindow{
+		{LimitAIDay, models.DecimalFromFloat64(limits.DailyLimit), spending.dailySpent},
+		{LimitAIWeek, models.DecimalFromFloat64(limits.WeeklyLimit), spending.weeklySpent},
+		{LimitAIMonth, models.DecimalFromFloat64(limits.MonthlyLimit), spending.monthlySpent},
+	}
+}
+
+// getAISpending totals the user's AI debits since the start of the current
+// day, week and month in their time zone. Debits still being paid out count,
+// as do shopping orders held on the wallet while the store takes them; failed
+// and cancelled ones do not.
+func (s *AIService) getAISpending(db *gorm.DB, userID uuid.UUID, limits *models.AISpendingLimit) (*aiSpending, error) {
+	now := time.Now().In(period.Location(limits.Timezone))
+	dayStart := period.StartOfDay(now)
+	weekStart := period.StartOfWeek(now)
+	monthStart := period.StartOfMonth(now)
+
+	// A week can start in the previous month
+	since := monthStart
+	if weekStart.Before(since) {
+		since = weekStart
+	}
+
+	var result struct {
+		DailySpent   decimal.Decimal
+		WeeklySpent  decimal.Decimal
+		MonthlySpent decimal.Decimal
+		DailyCount   int
+		WeeklyCount  int
+		MonthlyCount int
+		LastAt       *time.Time
+	}
+	if err := db.Model(&models.Transaction{}).
+		Select(`COALESCE(SUM(CASE WHEN created_at >= ? THEN amount END), 0) AS daily_spent,
+			COALESCE(SUM(CASE WHEN created_at >= ? THEN amount END), 0) AS weekly_spent,
+			COALESCE(SUM(CASE WHEN created_at >= ? THEN amount END), 0) AS monthly_spent,
+			COUNT(CASE WHEN created_at >= ? THEN 1 END) AS daily_count,
+			COUNT(CASE WHEN created_at >= ? THEN 1 END) AS weekly_count,
+			COUNT(CASE WHEN created_at >= ? THEN 1 END) AS monthly_count,
+			MAX(created_at) AS last_at`,
+			dayStart, weekStart, monthStart, dayStart, weekStart, monthStart).
+		Where("user_id = ? AND type IN ? AND LOWER(status) NOT IN ? AND created_at >= ?",
+			userID, aiDebitTransactionTypes, []string{utils.TransactionStatusFailed, utils.TransactionStatusCancelled}, since).
+		Scan(&result).Error; err != nil {
+		return nil, fmt.Errorf("failed to calculate AI spending: %w", err)
+	}
+
+	// A shopping order's debit is only recorded once the store accepts it
+	var held struct {
+		DailySpent   decimal.Decimal
+		WeeklySpent  decimal.Decimal
+		MonthlySpent decimal.Decimal
+	}
+	if err := db.Model(&models.WalletHold{}).
+		Select(`COALESCE(SUM(CASE WHEN created_at >= ? THEN amount END), 0) AS daily_spent,
+			COALESCE(SUM(CASE WHEN created_at >= ? THEN amount END), 0) AS weekly_spent,
+			COALESCE(SUM(CASE WHEN created_at >= ? THEN amount END), 0) AS monthly_spent`,
+			dayStart, weekStart, monthStart).
+		Where("user_id = ? AND purpose = ? AND status = ? AND created_at >= ?",
+			userID, models.WalletHoldPurposeAIShopping, models.WalletHoldStatusActive, since).
+		Scan(&held).Error; err != nil {
+		return nil, fmt.Errorf("failed to calculate held AI spending: %w", err)
+	}
+
+	return &aiSpending{
+		now:          now,
+		dailySpent:   result.DailySpent.Add(held.DailySpent),
+		weeklySpent:  result.WeeklySpent.Add(held.WeeklySpent),
+		monthlySpent: result.MonthlySpent.Add(held.MonthlySpent),
+		dailyCount:   result.DailyCount,
+		weeklyCount:  result.WeeklyCount,
+		monthlyCount: result.MonthlyCount,
+		lastAt:       result.LastAt,
+	}, nil
+}
+
+// validateSpendingLimits checks a payment against the user's per-transaction
+// limit and what is left of their daily, weekly and monthly AI limits
 func (s *AIService) validateSpendingLimits(userID uuid.UUID, amount float64) error {
+	return s.validateSpendingLimitsWithTx(nil, userID, amount)
+}
+
+// validateSpendingLimitsWithTx is validateSpendingLimits inside the caller's
+// transaction. Called with the wallet locked, it sees every AI debit committed
+// before the lock was taken.
+func (s *AIService) validateSpendingLimitsWithTx(tx *gorm.DB, userID uuid.UUID, amount float64) error {
+	db := s.db
+	if tx != nil {
+		db = tx
+	}
+
 	limits, err := s.GetSpendingLimits(userID)
 	if err != nil {
 		return err
 	}
 
-	// Check transaction limit
-	if amount > limits.TransactionLimit {
-		return fmt.Errorf("amount exceeds transaction limit of Rs. %.2f", limits.TransactionLimit)
+	requested := models.DecimalFromFloat64(amount)
+	perTransaction := models.DecimalFromFloat64(limits.TransactionLimit)
+	if requested.GreaterThan(perTransaction) {
+		return &LimitExceededError{
+			Limit:     LimitAITransaction,
+			Allowed:   perTransaction,
+			Used:      decimal.Zero,
+			Remaining: perTransaction,
+			Requested: requested,
+		}
 	}
 
-	// Check daily limit
-	remainingDaily, err := s.calculateRemainingDailyLimit(userID)
+	spending, err := s.getAISpending(db, userID, limits)
 	if err != nil {
 		return err
 	}
-	if amount > remainingDaily {
-		return fmt.Errorf("amount exceeds remaining daily limit of Rs. %.2f", remainingDaily)
+	// Unlike wallet limits, a zero AI limit allows nothing
+	for _, window := range spendingWindows(limits, spending) {
+		if window.used.Add(requested).GreaterThan(window.allowed) {
+			return &LimitExceededError{
+				Limit:     window.name,
+				Allowed:   window.allowed,
+				Used:      window.used,
+				Remaining: window.remaining(),
+				Requested: requested,
+			}
+		}
 	}
 
 	return nil
 }
 
-func (s *AIService) assessRiskLevel(amount float64, merchant string) string {
-	if amount > 5000 {
-		return "high"
+// calculateRemainingLimit returns the most the user can still spend through AI
+// today, the tightest of their daily, weekly and monthly limits
+func (s *AIService) calculateRemainingLimit(userID uuid.UUID) (float64, error) {
+	limits, err := s.GetSpendingLimits(userID)
+	if err != nil {
+		return 0, err
 	}
-	if amount > 1000 {
-		return "medium"
+
+	spending, err := s.getAISpending(s.db, userID, limits)
+	if err != nil {
+		return 0, err
+	}
+
+	remaining := tightestRemaining(limits, spending)
+	value, _ := remaining.Float64()
+	return value, nil
+}
+
+func tightestRemaining(limits *models.AISpendingLimit, spending *aiSpending) decimal.Decimal {
+	windows := spendingWindows(limits, spending)
+	remaining := windows[0].remaining()
+	for _, window := range windows[1:] {
+		remaining = decimal.Min(remaining, window.remaining())
 	}
-	return "low"
+	return remaining
 }
 
-func (s *AIService) calculateRemainingDailyLimit(userID uuid.UUID) (float64, error) {
+// GetRemainingBudget breaks down the user's AI spending and what is left in
+// each period. Periods reset at midnight in the user's time zone; weeks start
+// on Monday.
+func (s *AIService) GetRemainingBudget(userID uuid.UUID) (*dto.AISpendingLimitsResponse, error) {
 	limits, err := s.GetSpendingLimits(userID)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	today := time.Now().Truncate(24 * time.Hour)
-	var dailySpent float64
+	var wallet models.Wallet
+	if err := s.db.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		return nil, fmt.Errorf("failed to get wallet: %v", err)
+	}
 
-	if err := s.db.Model(&models.AIPaymentRequest{}).
-		Where("user_id = ? AND status = 'processed' AND created_at >= ?", userID, today).
-		Select("COALESCE(SUM(amount), 0)").
-		Scan(&dailySpent).Error; err != nil {
-		return 0, fmt.Errorf("failed to calculate daily spending: %v", err)
+	spending, err := s.getAISpending(s.db, userID, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	windows := spendingWindows(limits, spending)
+	perTransaction := models.DecimalFromFloat64(limits.TransactionLimit)
+	recommended := decimal.Min(perTransaction, tightestRemaining(limits, spending), decimal.Max(wallet.Balance, decimal.Zero))
+	if !limits.AIAccessEnabled {
+		recommended = decimal.Zero
+	}
+
+	_, dayEnd := period.Bounds(period.Day, spending.now)
+	_, weekEnd := period.Bounds(period.Week, spending.now)
+	_, monthEnd := period.Bounds(period.Month, spending.now)
+
+	response := &dto.AISpendingLimitsResponse{
+		Balance:                     wallet.Balance,
+		AIAccessEnabled:             limits.AIAccessEnabled,
+		AIDailyLimit:                windows[0].allowed,
+		AIPerTransactionLimit:       perTransaction,
+		AIWeeklyLimit:               windows[1].allowed,
+		AIMonthlyLimit:              windows[2].allowed,
+		DailySpent:                  spending.dailySpent,
+		WeeklySpent:                 spending.weeklySpent,
+		MonthlySpent:                spending.monthlySpent,
+		DailyRemaining:              windows[0].remaining(),
+		WeeklyRemaining:             windows[1].remaining(),
+		MonthlyRemaining:            windows[2].remaining(),
+		TransactionsToday:           spending.dailyCount,
+		TransactionsThisWeek:        spending.weeklyCount,
+		TransactionsThisMonth:       spending.monthlyCount,
+		Timezone:                    spending.now.Location().String(),
+		SpendingDate:                spending.now.Format("2006-01-02"),
+		DailyResetsAt:               dayEnd.Format(time.RFC3339),
+		WeeklyResetsAt:              weekEnd.Format(time.RFC3339),
+		MonthlyResetsAt:             monthEnd.Format(time.RFC3339),
+		RecommendedTransactionLimit: recommended,
+	}
+	if spending.lastAt != nil {
+		response.LastTransactionTime = spending.lastAt.In(spending.now.Location()).Format(time.RFC3339)
 	}
 
-	return limits.DailyLimit - dailySpent, nil
+	return response, nil
 }
 
-func (s *AIService) updateSpendingTracker(tx *gorm.DB, userID uuid.UUID, amount float64) error {
-	today := time.Now().Truncate(24 * time.Hour)
-	firstOfMonth := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+// updateSpendingTracker snapshots the user's AI spending for today, in their
+// time zone, after a payment. The debit itself must already be recorded in tx.
+func (s *AIService) updateSpendingTracker(tx *gorm.DB, userID uuid.UUID) error {
+	limits, err := s.GetSpendingLimits(userID)
+	if err != nil {
+		return err
+	}
+	spending, err := s.getAISpending(tx, userID, limits)
+	if err != nil {
+		return err
+	}
+
+	// The date column holds the local calendar day
+	local := spending.now
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
 
 	var tracker models.AISpendingTracker
-	err := tx.Where("user_id = ? AND date = ?", userID, today).First(&tracker).Error
-
-	if err == gorm.ErrRecordNotFound {
-		// Calculate monthly spending properly
-		var monthlySpent float64
-		tx.Model(&models.AIPaymentRequest{}).
-			Where("user_id = ? AND status = 'processed' AND created_at >= ? AND created_at < ?",
-				userID, firstOfMonth, firstOfMonth.AddDate(0, 1, 0)).
-			Select("COALESCE(SUM(amount), 0)").
-			Scan(&monthlySpent)
-
-		// Create new tracker for today
-		tracker = models.AISpendingTracker{
-			UserID:           userID,
-			Date:             today,
-			DailySpent:       amount,
-			TransactionCount: 1,
-			MonthlySpent:     monthlySpent + amount,
-		}
-		return tx.Create(&tracker).Error
-	} else if err != nil {
+	err = tx.Where("user_id = ? AND date = ?", userID, today).First(&tracker).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		return err
 	}
 
-	// Update existing tracker
-	tracker.DailySpent += amount
-	tracker.TransactionCount++
-
-	// Recalculate monthly spending properly
-	var monthlySpent float64
-	tx.Model(&models.AIPaymentRequest{}).
-		Where("user_id = ? AND status = 'processed' AND created_at >= ? AND created_at < ?",
-			userID, firstOfMonth, firstOfMonth.AddDate(0, 1, 0)).
-		Select("COALESCE(SUM(amount), 0)").
-		Scan(&monthlySpent)
-	tracker.MonthlySpent = monthlySpent + amount
+	tracker.UserID = userID
+	tracker.Date = today
+	tracker.DailySpent, _ = spending.dailySpent.Float64()
+	tracker.WeeklySpent, _ = spending.weeklySpent.Float64()
+	tracker.MonthlySpent, _ = spending.monthlySpent.Float64()
+	tracker.TransactionCount = spending.dailyCount
 
 	return tx.Save(&tracker).Error
 }
 
-func (s *AIService) generateSuggestions(analysis *PaymentAnalysis, walletBalance, remainingLimit float64) string {
+func (s *AIService) generateSuggestions(analysis *PaymentAnalysis, upiID string, walletBalance, remainingLimit float64) string {
 	suggestions := []string{}
 
+	if upiID == "" {
+		suggestions = append(suggestions, "No UPI ID is known for this payee. Include merchant_upi_id so the payment can be made.")
+	}
+
 	if analysis.Amount > walletBalance {
 		suggestions = append(suggestions, "Insufficient wallet balance. Consider adding funds.")
 	}
 
 	if analysis.Amount > remainingLimit*0.8 {
-		suggestions = append(suggestions, "This transaction will use most of your daily limit.")
+		suggestions = append(suggestions, "This transaction will use most of your remaining AI limit.")
 	}
 
 	if analysis.Confidence < 0.8 {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,8 +21,9 @@ func NewAPIKeyService(repo *repositories.APIKeyRepository) *APIKeyService {
 	return &APIKeyService{Repo: repo}
 }
 
-// Generate creates a universal API key that works with everything
-func (s *APIKeyService) Generate(ctx context.Context, userID uuid.UUID, label string, password string, ttlHours int) (string, error) {
+// Generate creates a universal API key that works with everything. A key
+// given an agent ID makes AI payments as that agent.
+func (s *APIKeyService) Generate(ctx context.Context, userID uuid.UUID, label string, password string, ttlHours int, agentID string) (string, error) {
 	fmt.Printf("DEBUG APIKeyService.Generate: Starting generation for user %s\n", userID)
 
 	// Universal scopes that allow access to all features
@@ -29,7 +31,7 @@ func (s *APIKeyService) Generate(ctx context.Context, userID uuid.UUID, label st
 		"*", // Wildcard scope for all permissions
 	}
 
-	result, err := s.GenerateWithScopes(ctx, userID, label, password, ttlHours, universalScopes, "universal", "", "")
+	result, err := s.GenerateWithScopes(ctx, userID, label, password, ttlHours, universalScopes, "universal", "", "", agentID)
 	if err != nil {
 		fmt.Printf("DEBUG APIKeyService.Generate: Error in GenerateWithScopes: %v\n", err)
 		return "", err
@@ -48,11 +50,11 @@ func (s *APIKeyService) GenerateBotKey(ctx context.Context, userID uuid.UUID, la
 		"*", // Wildcard scope for all permissions
 	}
 
-	return s.GenerateWithScopes(ctx, userID, label, password, ttlHours, universalScopes, "universal", workspaceID, botUserID)
+	return s.GenerateWithScopes(ctx, userID, label, password, ttlHours, universalScopes, "universal", workspaceID, botUserID, "")
 }
 
 // GenerateWithScopes creates an API key with specific scopes and type
-func (s *APIKeyService) GenerateWithScopes(ctx context.Context, userID uuid.UUID, label string, password string, ttlHours int, scopes []string, keyType, workspaceID, botUserID string) (string, error) {
+func (s *APIKeyService) GenerateWithScopes(ctx context.Context, userID uuid.UUID, label string, password string, ttlHours int, scopes []string, keyType, workspaceID, botUserID, agentID string) (string, error) {
 	fmt.Printf("DEBUG GenerateWithScopes: Starting for user %s, label '%s', keyType '%s'\n", userID, label, keyType)
 
 	rawKey, err := utils.GenerateSecureKey()
@@ -91,6 +93,7 @@ func (s *APIKeyService) GenerateWithScopes(ctx context.Context, userID uuid.UUID
 		ExpiresAt:    expiresAt,
 		BotWorkspace: workspaceID,
 		BotUserID:    botUserID,
+		AgentID:      strings.TrimSpace(agentID),
 		RateLimit:    1000, // Default rate limit
 	}
 
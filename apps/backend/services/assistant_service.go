@@ -0,0 +1,182 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/pkg/ask"
+	"github.com/zeusnotfound04/Tranza/repositories"
+)
+
+// AssistantLocation is the time zone periods like "last month" are read in
+var AssistantLocation = RiskLocation
+
+const defaultAskRecordLimit = 20
+
+// AssistantService answers questions about a user's own spending and transfers
+type AssistantService struct {
+	txnRepo      *repositories.TransactionRepository
+	transferRepo *repositories.ExternalTransferRepository
+}
+
+func NewAssistantService(
+	txnRepo *repositories.TransactionRepository,
+	transferRepo *repositories.ExternalTransferRepository,
+) *AssistantService {
+	return &AssistantService{
+		txnRepo:      txnRepo,
+		transferRepo: transferRepo,
+	}
+}
+
+// Ask parses the question and answers it from the user's history. Returns
+// ask.ErrUnsupported for questions it cannot turn into a query.
+func (s *AssistantService) Ask(userID uuid.UUID, req *dto.AskRequest) (*dto.AskResponse, error) {
+	query, err := ask.Parse(req.Question, time.Now().In(AssistantLocation))
+	if err != nil {
+		return nil, err
+	}
+
+	history := repositories.HistoryQuery{
+		UserID:       userID,
+		Types:        walletDebitTransactionTypes,
+		Counterparty: query.Counterparty,
+		From:         query.From,
+		To:           query.To,
+		Sort:         repositories.HistorySortNewest,
+		Limit:        req.Limit,
+	}
+	if history.Limit == 0 {
+		history.Limit = defaultAskRecordLimit
+	}
+	switch query.Metric {
+	case ask.MetricLargest:
+		history.Sort, history.Limit = repositories.HistorySortLargest, 1
+	case ask.MetricSmallest:
+		history.Sort, history.Limit = repositories.HistorySortSmallest, 1
+	}
+
+	response := &dto.AskResponse{Question: req.Question, Query: query}
+	if query.Source == ask.SourceTransfers {
+		err = s.answerFromTransfers(history, response)
+	} else {
+		err = s.answerFromTransactions(history, response)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	response.Answer = answer(query, response)
+	return response, nil
+}
+
+func (s *AssistantService) answerFromTransactions(history repositories.HistoryQuery, response *dto.AskResponse) error {
+	total, count, err := s.txnRepo.SumDebits(history)
+	if err != nil {
+		return err
+	}
+	transactions, err := s.txnRepo.SearchDebits(history)
+	if err != nil {
+		return err
+	}
+
+	response.Total, response.Count = total, count
+	response.Records = make([]dto.AskRecord, 0, len(transactions))
+	for _, txn := range transactions {
+		counterparty := txn.MerchantName
+		if counterparty == "" {
+			counterparty = txn.MerchantUPIID
+		}
+		response.Records = append(response.Records, dto.AskRecord{
+			ID:           txn.ID,
+			Source:       "transaction",
+			Type:         txn.Type,
+			Amount:       txn.Amount,
+			Counterparty: counterparty,
+			Description:  txn.Description,
+			Status:       string(txn.Status),
+			ReferenceID:  txn.ReferenceID,
+			CreatedAt:    txn.CreatedAt,
+		})
+	}
+	return nil
+}
+
+func (s *AssistantService) answerFromTransfers(history repositories.HistoryQuery, response *dto.AskResponse) error {
+	total, count, err := s.transferRepo.SumTransfers(history)
+	if err != nil {
+		return err
+	}
+	transfers, err := s.transferRepo.SearchTransfers(history)
+	if err != nil {
+		return err
+	}
+
+	response.Total, response.Count = total, count
+	response.Records = make([]dto.AskRecord, 0, len(transfers))
+	for _, transfer := range transfers {
+		counterparty := transfer.RecipientName
+		if counterparty == "" {
+			counterparty = transfer.RecipientValue
+		}
+		response.Records = append(response.Records, dto.AskRecord{
+			ID:           transfer.ID,
+			Source:       "external_transfer",
+			Type:         transfer.RecipientType,
+			Amount:       transfer.Amount,
+			Counterparty: counterparty,
+			Description:  transfer.Description,
+			Status:       transfer.Status,
+			ReferenceID:  transfer.ReferenceID,
+			CreatedAt:    transfer.CreatedAt,
+		})
+	}
+	return nil
+}
+
+// answer writes the result as a sentence, e.g. "You spent ₹1250.00 on swiggy
+// last month across 4 payments."
+func answer(query *ask.Query, response *dto.AskResponse) string {
+	noun, verb, preposition := "payment", "spent", "on"
+	if query.Source == ask.SourceTransfers {
+		noun, verb, preposition = "transfer", "sent", "to"
+	}
+
+	scope := query.Period
+	if query.Counterparty != "" {
+		scope = preposition + " " + query.Counterparty + " " + query.Period
+	}
+
+	if response.Count == 0 {
+		return fmt.Sprintf("You have no %ss %s.", noun, scope)
+	}
+
+	switch query.Metric {
+	case ask.MetricTotal:
+		return fmt.Sprintf("You %s ₹%s %s across %s.", verb, response.Total.StringFixed(2), scope, plural(response.Count, noun))
+	case ask.MetricCount:
+		return fmt.Sprintf("You made %s %s, ₹%s in total.", plural(response.Count, noun), scope, response.Total.StringFixed(2))
+	case ask.MetricLargest, ask.MetricSmallest:
+		record := response.Records[0]
+		sentence := fmt.Sprintf("Your %s %s %s was ₹%s", query.Metric, noun, scope, record.Amount.StringFixed(2))
+		if record.Counterparty != "" {
+			sentence += " to " + record.Counterparty
+		}
+		return sentence + " on " + record.CreatedAt.In(AssistantLocation).Format("2 Jan 2006") + "."
+	default:
+		sentence := fmt.Sprintf("You have %s %s", plural(response.Count, noun), scope)
+		if int64(len(response.Records)) < response.Count {
+			sentence += fmt.Sprintf(", showing the latest %d", len(response.Records))
+		}
+		return sentence + "."
+	}
+}
+
+func plural(count int64, noun string) string {
+	if count == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
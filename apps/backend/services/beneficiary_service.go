@@ -0,0 +1,236 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/pkg/ifsc"
+	"github.com/zeusnotfound04/Tranza/pkg/razorpay"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"github.com/zeusnotfound04/Tranza/utils"
+)
+
+// BeneficiaryCoolingOffMaxAmount caps each transfer to a beneficiary still in
+// its cooling-off period
+const BeneficiaryCoolingOffMaxAmount = 5000 // ₹5,000
+
+var (
+	ErrBeneficiaryExists      = errors.New("a beneficiary with this nickname already exists")
+	ErrBeneficiaryNotVerified = errors.New("beneficiary is not verified yet; verify it before sending money")
+)
+
+// Nicknames are what users type in Slack, e.g. "send 500 to mom"
+var nicknameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9 ._-]*$`)
+
+// BeneficiaryService manages saved transfer recipients. Each beneficiary is
+// registered with Razorpay once, as a contact and a UPI or bank fund account,
+// and payouts to it reuse them.
+type BeneficiaryService struct {
+	beneficiaryRepo         *repositories.BeneficiaryRepository
+	externalTransferService *ExternalTransferService
+	razorpayClient          *razorpay.Client
+	coolingOff              time.Duration
+}
+
+func NewBeneficiaryService(
+	beneficiaryRepo *repositories.BeneficiaryRepository,
+	externalTransferService *ExternalTransferService,
+	razorpayClient *razorpay.Client,
+	coolingOff time.Duration,
+) *BeneficiaryService {
+	return &BeneficiaryService{
+		beneficiaryRepo:         beneficiaryRepo,
+		externalTransferService: externalTransferService,
+		razorpayClient:          razorpayClient,
+		coolingOff:              coolingOff,
+	}
+}
+
+// AddBeneficiary saves a recipient and registers it with Razorpay. Transfers
+// to it are limited until its cooling-off period ends.
+func (s *BeneficiaryService) AddBeneficiary(userID uuid.UUID, req *dto.CreateBeneficiaryRequest) (*models.Beneficiary, error) {
+	nickname, err := normalizeNickname(req.Nickname)
+	if err != nil {
+		return nil, err
+	}
+	recipientValue := strings.TrimSpace(req.RecipientValue)
+	recipientIFSC := ""
+	if req.RecipientType == models.RecipientTypeIFSC {
+		recipientIFSC = ifsc.Normalize(req.RecipientIFSC)
+	}
+	if err := s.externalTransferService.validateRecipient(req.RecipientType, recipientValue, recipientIFSC); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.beneficiaryRepo.GetByNickname(userID, nickname); err == nil {
+		return nil, ErrBeneficiaryExists
+	}
+	if existing, err := s.beneficiaryRepo.GetByRecipient(userID, req.RecipientType, recipientValue, recipientIFSC); err == nil {
+		return nil, fmt.Errorf("this recipient is already saved as %q", existing.Nickname)
+	}
+
+	beneficiary := &models.Beneficiary{
+		UserID:             userID,
+		Nickname:           nickname,
+		RecipientType:      req.RecipientType,
+		RecipientValue:     recipientValue,
+		RecipientIFSC:      recipientIFSC,
+		Name:               strings.TrimSpace(req.Name),
+		VerificationStatus: models.BeneficiaryStatusPending,
+		CoolingOffUntil:    time.Now().Add(s.coolingOff),
+	}
+	if err := s.beneficiaryRepo.Create(beneficiary); err != nil {
+		return nil, err
+	}
+
+	s.register(beneficiary)
+	return beneficiary, nil
+}
+
+// VerifyBeneficiary retries registering a beneficiary with Razorpay
+func (s *BeneficiaryService) VerifyBeneficiary(userID, beneficiaryID uuid.UUID) (*models.Beneficiary, error) {
+	beneficiary, err := s.beneficiaryRepo.GetByIDAndUserID(beneficiaryID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !beneficiary.IsVerified() {
+		s.register(beneficiary)
+	}
+	return beneficiary, nil
+}
+
+// GetBeneficiaries lists the user's beneficiaries
+func (s *BeneficiaryService) GetBeneficiaries(userID uuid.UUID) ([]*models.Beneficiary, error) {
+	return s.beneficiaryRepo.GetByUserID(userID)
+}
+
+// GetBeneficiary returns one of the user's beneficiaries by ID or nickname
+func (s *BeneficiaryService) GetBeneficiary(userID uuid.UUID, ref string) (*models.Beneficiary, error) {
+	return findBeneficiary(s.beneficiaryRepo, userID, ref)
+}
+
+// RenameBeneficiary changes a beneficiary's nickname. The recipient itself
+// cannot be changed, since that would skip the cooling-off period; add a new
+// beneficiary instead.
+func (s *BeneficiaryService) RenameBeneficiary(userID, beneficiaryID uuid.UUID, req *dto.RenameBeneficiaryRequest) (*models.Beneficiary, error) {
+	nickname, err := normalizeNickname(req.Nickname)
+	if err != nil {
+		return nil, err
+	}
+
+	beneficiary, err := s.beneficiaryRepo.GetByIDAndUserID(beneficiaryID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if existing, err := s.beneficiaryRepo.GetByNickname(userID, nickname); err == nil && existing.ID != beneficiary.ID {
+		return nil, ErrBeneficiaryExists
+	}
+
+	beneficiary.Nickname = nickname
+	if err := s.beneficiaryRepo.Update(beneficiary); err != nil {
+		return nil, err
+	}
+	return beneficiary, nil
+}
+
+// DeleteBeneficiary removes one of the user's beneficiaries
+func (s *BeneficiaryService) DeleteBeneficiary(userID, beneficiaryID uuid.UUID) error {
+	return s.beneficiaryRepo.Delete(beneficiaryID, userID)
+}
+
+// register creates the beneficiary's Razorpay contact and fund account and
+// records whether that worked. Razorpay rejects malformed UPI IDs here, so a
+// bad recipient shows up when it is saved rather than on the first transfer.
+func (s *BeneficiaryService) register(beneficiary *models.Beneficiary) {
+	err := s.createFundAccount(beneficiary)
+	if err != nil {
+		beneficiary.VerificationStatus = models.BeneficiaryStatusFailed
+		beneficiary.VerificationError = err.Error()
+		utils.LogError(err, map[string]interface{}{"beneficiary_id": beneficiary.ID.String(), "action": "register_beneficiary"})
+	} else {
+		now := time.Now()
+		beneficiary.VerificationStatus = models.BeneficiaryStatusVerified
+		beneficiary.VerificationError = ""
+		beneficiary.VerifiedAt = &now
+	}
+
+	if err := s.beneficiaryRepo.Update(beneficiary); err != nil {
+		utils.LogError(err, map[string]interface{}{"beneficiary_id": beneficiary.ID.String(), "action": "save_beneficiary_verification"})
+	}
+}
+
+func (s *BeneficiaryService) createFundAccount(beneficiary *models.Beneficiary) error {
+	if beneficiary.RazorpayContactID == "" {
+		contact := &razorpay.PayoutContact{
+			Name:        beneficiary.Name,
+			Type:        razorpay.ContactTypeCustomer,
+			ReferenceID: "ben_" + beneficiary.ID.String(),
+		}
+		if beneficiary.RecipientType == models.RecipientTypePhone {
+			contact.Contact = beneficiary.RecipientValue
+		}
+
+		contactResp, err := s.razorpayClient.CreateContact(contact)
+		if err != nil {
+			return err
+		}
+		beneficiary.RazorpayContactID = contactResp.ID
+	}
+
+	request := &razorpay.FundAccountRequest{ContactID: beneficiary.RazorpayContactID}
+	switch beneficiary.RecipientType {
+	case models.RecipientTypeIFSC:
+		request.AccountType = razorpay.AccountTypeBank
+		request.BankAccount = &razorpay.PayoutBankAccount{
+			Name:          beneficiary.Name,
+			IFSC:          beneficiary.RecipientIFSC,
+			AccountNumber: beneficiary.RecipientValue,
+		}
+	case models.RecipientTypePhone:
+		// Beneficiaries are paid by payout, so a number is registered at its
+		// UPI ID even when it belongs to a Tranza user
+		recipient, err := s.externalTransferService.phoneResolver.ResolveVPA(beneficiary.UserID, beneficiary.RecipientValue)
+		if err != nil {
+			return err
+		}
+		request.AccountType = razorpay.AccountTypeVPA
+		request.VPA = &razorpay.PayoutVPA{Address: recipient.VPA}
+	default:
+		request.AccountType = razorpay.AccountTypeVPA
+		request.VPA = &razorpay.PayoutVPA{Address: beneficiary.RecipientValue}
+	}
+
+	fundAccount, err := s.razorpayClient.CreateFundAccount(request)
+	if err != nil {
+		return err
+	}
+	beneficiary.RazorpayFundID = fundAccount.ID
+	return nil
+}
+
+// findBeneficiary looks a beneficiary up by ID, or by nickname if ref is not an ID
+func findBeneficiary(repo *repositories.BeneficiaryRepository, userID uuid.UUID, ref string) (*models.Beneficiary, error) {
+	ref = strings.TrimSpace(ref)
+	if id, err := uuid.Parse(ref); err == nil {
+		return repo.GetByIDAndUserID(id, userID)
+	}
+	return repo.GetByNickname(userID, strings.ToLower(ref))
+}
+
+// normalizeNickname lower-cases a nickname and checks it can be typed in a command
+func normalizeNickname(nickname string) (string, error) {
+	nickname = strings.ToLower(strings.Join(strings.Fields(nickname), " "))
+	if nickname == "" || len(nickname) > 50 || !nicknameRegex.MatchString(nickname) {
+		return "", errors.New("nickname must start with a letter or digit and use only letters, digits, spaces, dots, dashes and underscores")
+	}
+	if _, err := uuid.Parse(nickname); err == nil {
+		return "", errors.New("nickname cannot be an ID")
+	}
+	return nickname, nil
+}
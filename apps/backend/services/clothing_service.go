@@ -0,0 +1,784 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/config"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/pkg/catalog"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"github.com/zeusnotfound04/Tranza/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxClothingSuggestions caps the products suggested for one prompt
+	maxClothingSuggestions = 5
+	// catalogTimeout bounds each call to the catalog
+	catalogTimeout = 15 * time.Second
+)
+
+var (
+	ErrClothingOverBudget     = errors.New("order is over budget")
+	ErrClothingAddressMissing = errors.New("a delivery address is required; add one or pass address_id")
+)
+
+// ClothingService runs AI shopping orders: a prompt is matched against the
+// catalog and drafted as an order, and confirming it pays from the wallet and
+// places the order with the store. Store-side status changes are picked up by
+// StartOrderTracking and when an order is read.
+type ClothingService struct {
+	db             *gorm.DB
+	orderRepo      *repositories.ExternalOrderRepository
+	walletRepo     *repositories.WalletRepository
+	txnRepo        *repositories.TransactionRepository
+	addressService *AddressService
+	ledgerService  *LedgerService
+	holdService    *HoldService
+	limitsService  *LimitsService
+	aiService      *AIService
+	refundService  *RefundService
+	catalog        catalog.Catalog
+}
+
+func NewClothingService(
+	db *gorm.DB,
+	orderRepo *repositories.ExternalOrderRepository,
+	walletRepo *repositories.WalletRepository,
+	txnRepo *repositories.TransactionRepository,
+	addressService *AddressService,
+	ledgerService *LedgerService,
+	holdService *HoldService,
+	limitsService *LimitsService,
+	aiService *AIService,
+	refundService *RefundService,
+	catalog catalog.Catalog,
+) *ClothingService {
+	return &ClothingService{
+		db:             db,
+		orderRepo:      orderRepo,
+		walletRepo:     walletRepo,
+		txnRepo:        txnRepo,
+		addressService: addressService,
+		ledgerService:  ledgerService,
+		holdService:    holdService,
+		limitsService:  limitsService,
+		aiService:      aiService,
+		refundService:  refundService,
+		catalog:        catalog,
+	}
+}
+
+// NewCatalog builds the catalog selected by cfg. A local catalog whose file
+// cannot be read is replaced by an empty one so the server still starts.
+func NewCatalog(cfg *config.CatalogConfig) catalog.Catalog {
+	if cfg.Provider != catalog.ProviderLocal {
+		utils.LogWarning("Unknown catalog provider, using the local catalog", map[string]interface{}{
+			"provider": cfg.Provider,
+		})
+	}
+
+	local, err := catalog.LoadLocalCatalog(cfg.File)
+	if err != nil {
+		utils.LogWarning("Local catalog not loaded, AI shopping has no products", map[string]interface{}{
+			"file":  cfg.File,
+			"error": err.Error(),
+		})
+		return catalog.NewLocalCatalog(nil)
+	}
+	return local
+}
+
+// ProcessAIClothingOrder suggests products for the prompt and drafts an order
+// with the best one. The draft is bought only when confirmed.
+func (s *ClothingService) ProcessAIClothingOrder(userID uuid.UUID, req *models.AIClothingOrderRequest) (*models.AIClothingOrderResponse, error) {
+	limits, err := s.aiService.GetSpendingLimits(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spending limits: %v", err)
+	}
+	if !limits.AIAccessEnabled {
+		return nil, fmt.Errorf("AI payment processing is not enabled for this user")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), catalogTimeout)
+	defer cancel()
+
+	brands, err := s.catalog.Brands(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog: %w", err)
+	}
+
+	criteria := catalog.ParseCriteria(req.Prompt, brands)
+	if category := strings.ToLower(strings.TrimSpace(req.Category)); category != "" {
+		criteria.Category = category
+	}
+	budget := decimal.Zero
+	switch {
+	case req.Budget > 0:
+		budget = decimal.NewFromFloat(req.Budget)
+	case criteria.MaxPrice > 0:
+		budget = decimal.NewFromFloat(criteria.MaxPrice)
+	}
+
+	analysis := &models.ClothingOrderAnalysis{
+		Category: criteria.Category,
+		Size:     criteria.Size,
+		Color:    criteria.Color,
+		Brand:    criteria.Brand,
+		Occasion: criteria.Occasion,
+		MinPrice: criteria.MinPrice,
+		MaxPrice: criteria.MaxPrice,
+	}
+
+	address, err := s.selectAddress(userID, req.AddressID)
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := s.catalog.Search(ctx, catalog.Criteria{Category: criteria.Category})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search catalog: %w", err)
+	}
+	ranked := catalog.Rank(products, criteria, budget)
+	if len(ranked) == 0 {
+		return &models.AIClothingOrderResponse{
+			OrderCreated:  false,
+			Message:       "No products in the catalog match your request" + describeBudget(budget),
+			OrderAnalysis: analysis,
+		}, nil
+	}
+	if len(ranked) > maxClothingSuggestions {
+		ranked = ranked[:maxClothingSuggestions]
+	}
+
+	suggestions := make([]models.ExternalOrderItem, len(ranked))
+	for i := range ranked {
+		suggestions[i] = orderItem(&ranked[i].Product, &ranked[i].Variant, 1, ranked[i].Reason())
+	}
+	best := suggestions[0]
+
+	order := &models.ExternalOrder{
+		UserID:        userID,
+		Catalog:       s.catalog.Name(),
+		Website:       best.Website,
+		TotalAmount:   best.Price,
+		Budget:        budget,
+		Currency:      "INR",
+		Status:        models.ExternalOrderStatusAwaitingConfirmation,
+		PaymentStatus: models.ExternalOrderPaymentPending,
+		Items:         []models.ExternalOrderItem{best},
+		Suggestions:   suggestions,
+		IsAIOrder:     true,
+		AIPrompt:      req.Prompt,
+		Analysis:      analysis,
+	}
+	if address != nil {
+		order.DeliveryAddress = *address
+	}
+	if err := s.orderRepo.Create(order); err != nil {
+		return nil, err
+	}
+
+	response := &models.AIClothingOrderResponse{
+		OrderID:              order.ID.String(),
+		OrderCreated:         true,
+		TotalEstimate:        best.Price,
+		RequiresConfirmation: true,
+		ConfirmationID:       order.ID.String(),
+		OrderAnalysis:        analysis,
+		SelectedAddress:      address,
+		Message:              fmt.Sprintf("Found %d matching products. Top pick: %s by %s for ₹%s", len(ranked), best.Name, best.Brand, best.Price.StringFixed(2)),
+	}
+	if address == nil {
+		response.RequiredInfo = []string{"address_id"}
+	}
+	for _, suggestion := range ranked {
+		response.SuggestedItems = append(response.SuggestedItems, productSuggestion(&suggestion))
+		response.SuggestedProducts = append(response.SuggestedProducts, suggestedProduct(&suggestion.Product))
+	}
+
+	return response, nil
+}
+
+// ConfirmAIClothingOrder pays for a drafted order and places it with the store.
+// The price is held on the wallet while the store takes the order and captured
+// once it has; a store that refuses the order releases the hold.
+func (s *ClothingService) ConfirmAIClothingOrder(userID uuid.UUID, req *models.ConfirmAIClothingOrderRequest) (*models.ConfirmAIClothingOrderResponse, error) {
+	orderID, err := uuid.Parse(req.ConfirmationID)
+	if err != nil {
+		return nil, errors.New("invalid confirmation ID")
+	}
+
+	order, err := s.orderRepo.GetByIDAndUserID(orderID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.ExternalOrderStatusAwaitingConfirmation {
+		return nil, fmt.Errorf("order is %s, not awaiting confirmation", order.Status)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), catalogTimeout)
+	defer cancel()
+
+	items, err := s.selectItems(ctx, order, req.SelectedProducts)
+	if err != nil {
+		return nil, err
+	}
+	total := decimal.Zero
+	for _, item := range items {
+		total = total.Add(item.Price.Mul(decimal.NewFromInt(int64(item.Quantity))))
+	}
+	totalFloat, _ := total.Float64()
+
+	if order.Budget.IsPositive() && total.GreaterThan(order.Budget) {
+		return nil, fmt.Errorf("%w: total ₹%s is more than your budget of ₹%s", ErrClothingOverBudget, total.StringFixed(2), order.Budget.StringFixed(2))
+	}
+
+	address := &order.DeliveryAddress
+	if req.AddressID != "" {
+		if address, err = s.selectAddress(userID, req.AddressID); err != nil {
+			return nil, err
+		}
+	}
+	if address == nil || address.ID == uuid.Nil {
+		return nil, ErrClothingAddressMissing
+	}
+
+	wallet, err := s.walletRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Claim the draft so it cannot be confirmed twice
+	if err := s.orderRepo.Transition(nil, order.ID, models.ExternalOrderStatusAwaitingConfirmation, models.ExternalOrderStatusPlacing); err != nil {
+		return nil, fmt.Errorf("order is no longer awaiting confirmation: %w", err)
+	}
+
+	// Apply the wallet and AI limits with the wallet locked, so other payments
+	// cannot spend the same allowance; the hold counts towards them from then on
+	description := fmt.Sprintf("AI Shopping: %s order %s", order.Website, order.OrderNumber)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.limitsService.CheckDebitWithTx(tx, wallet.ID, DebitKindAIPayment, total); err != nil {
+			return err
+		}
+		if err := s.aiService.validateSpendingLimitsWithTx(tx, userID, totalFloat); err != nil {
+			return err
+		}
+		_, err := s.holdService.PlaceHold(tx, wallet.ID, total, models.WalletHoldPurposeAIShopping, order.OrderNumber, description, AIPaymentHoldTTL)
+		return err
+	})
+	if err != nil {
+		s.orderRepo.Transition(nil, order.ID, models.ExternalOrderStatusPlacing, models.ExternalOrderStatusAwaitingConfirmation)
+		var limitErr *LimitExceededError
+		if errors.As(err, &limitErr) {
+			return nil, err
+		}
+		if errors.Is(err, ErrInsufficientAvailableBalance) {
+			balance, _ := wallet.Balance.Float64()
+			return &models.ConfirmAIClothingOrderResponse{
+				Success:        false,
+				OrderID:        order.ID.String(),
+				Message:        "Insufficient wallet balance",
+				TotalAmount:    totalFloat,
+				RequiredAmount: totalFloat,
+				CurrentBalance: balance,
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to reserve wallet balance: %w", err)
+	}
+
+	placement, err := s.catalog.PlaceOrder(ctx, &catalog.Order{
+		Reference: order.OrderNumber,
+		Lines:     orderLines(items),
+		Address:   catalogAddress(address),
+	})
+	if err != nil {
+		s.failOrder(order, "Store did not accept the order: "+err.Error())
+		return nil, fmt.Errorf("store did not accept the order: %w", err)
+	}
+
+	var remaining decimal.Decimal
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := s.holdService.CaptureHold(tx, order.OrderNumber); err != nil {
+			return err
+		}
+
+		transaction := &models.Transaction{
+			ID:            uuid.New(),
+			WalletID:      wallet.ID,
+			UserID:        userID,
+			Type:          utils.TransactionTypeAIPayment,
+			Amount:        total,
+			Currency:      "INR",
+			Description:   description,
+			Status:        utils.TransactionStatusSuccess,
+			PaymentMethod: "wallet",
+			ReferenceID:   order.OrderNumber,
+			MerchantName:  order.Website,
+		}
+		updated, err := s.ledgerService.RecordAIPayment(tx, wallet.ID, total, order.OrderNumber, &transaction.ID)
+		if err != nil {
+			return err
+		}
+		transaction.BalanceAfter = updated.Balance
+		remaining = updated.Balance
+		if _, err := s.txnRepo.CreateWithTx(tx, transaction); err != nil {
+			return err
+		}
+
+		if err := s.aiService.updateSpendingTracker(tx, userID); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		order.Items = items
+		order.TotalAmount = total
+		order.Website = items[0].Website
+		order.DeliveryAddress = *address
+		order.TransactionID = transaction.ID.String()
+		order.PaymentStatus = models.ExternalOrderPaymentPaid
+		order.ConfirmedAt = &now
+		order.Status = models.ExternalOrderStatusPlacing
+		applyPlacement(order, placement)
+		return s.orderRepo.Update(tx, order)
+	})
+	if err != nil {
+		// The store has the order but it could not be paid for, so take it back
+		if _, cancelErr := s.catalog.CancelOrder(ctx, placement.OrderID); cancelErr != nil {
+			utils.LogError(cancelErr, map[string]interface{}{
+				"order_id":          order.ID.String(),
+				"external_order_id": placement.OrderID,
+				"action":            "cancel_unpaid_clothing_order",
+			})
+		}
+		s.failOrder(order, "Payment failed: "+err.Error())
+		if errors.Is(err, ErrInsufficientAvailableBalance) {
+			return nil, errors.New("insufficient wallet balance")
+		}
+		return nil, fmt.Errorf("failed to pay for order: %w", err)
+	}
+
+	utils.LogInfo("AI clothing order placed", map[string]interface{}{
+		"order_id":          order.ID.String(),
+		"external_order_id": order.ExternalOrderID,
+		"amount":            total.String(),
+	})
+
+	remainingFloat, _ := remaining.Float64()
+	response := &models.ConfirmAIClothingOrderResponse{
+		Success:          true,
+		OrderID:          order.ID.String(),
+		Message:          fmt.Sprintf("Order %s placed with %s", order.OrderNumber, order.Website),
+		TotalAmount:      totalFloat,
+		RemainingBalance: remainingFloat,
+	}
+	for _, item := range items {
+		price, _ := item.Price.Float64()
+		response.SelectedProducts = append(response.SelectedProducts, models.SelectedProduct{
+			ID:       item.ProductID,
+			Name:     item.Name,
+			Price:    price,
+			Quantity: item.Quantity,
+			Size:     item.Size,
+			Color:    item.Color,
+			Website:  item.Website,
+			URL:      item.ProductURL,
+		})
+	}
+	return response, nil
+}
+
+// CancelOrder cancels a draft, or a paid order the store has not shipped. Paid
+// orders are refunded to the wallet.
+func (s *ClothingService) CancelOrder(userID, orderID uuid.UUID) (*models.ClothingOrderResponse, error) {
+	order, err := s.orderRepo.GetByIDAndUserID(orderID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch order.Status {
+	case models.ExternalOrderStatusAwaitingConfirmation:
+		if err := s.orderRepo.Transition(nil, order.ID, order.Status, models.ExternalOrderStatusCancelled); err != nil {
+			return nil, fmt.Errorf("failed to cancel order: %w", err)
+		}
+		now := time.Now()
+		order.Status = models.ExternalOrderStatusCancelled
+		order.CancelledAt = &now
+		if err := s.orderRepo.Update(nil, order); err != nil {
+			return nil, err
+		}
+	case models.ExternalOrderStatusConfirmed:
+		ctx, cancel := context.WithTimeout(context.Background(), catalogTimeout)
+		defer cancel()
+
+		placement, err := s.catalog.CancelOrder(ctx, order.ExternalOrderID)
+		if err != nil {
+			if errors.Is(err, catalog.ErrNotCancellable) {
+				return nil, errors.New("order has shipped and can no longer be cancelled")
+			}
+			return nil, fmt.Errorf("store did not cancel the order: %w", err)
+		}
+		applyPlacement(order, placement)
+		if err := s.settleCancelledOrder(order, "Order cancelled by user"); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("order is %s and cannot be cancelled", order.Status)
+	}
+
+	return clothingOrderResponse(order), nil
+}
+
+// GetOrder returns one of the user's orders, with its status refreshed from
+// the store while it is open
+func (s *ClothingService) GetOrder(userID, orderID uuid.UUID) (*models.ClothingOrderResponse, error) {
+	order, err := s.orderRepo.GetByIDAndUserID(orderID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.IsOpen() {
+		if err := s.syncOrder(order); err != nil {
+			utils.LogError(err, map[string]interface{}{"order_id": order.ID.String(), "action": "sync_clothing_order"})
+		}
+	}
+	return clothingOrderResponse(order), nil
+}
+
+// GetOrders returns the user's orders newest first, optionally with one status
+func (s *ClothingService) GetOrders(userID uuid.UUID, status string, page, limit int) ([]*models.ClothingOrderResponse, int64, error) {
+	orders, total, err := s.orderRepo.GetByUserID(userID, status, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	responses := make([]*models.ClothingOrderResponse, len(orders))
+	for i, order := range orders {
+		responses[i] = clothingOrderResponse(order)
+	}
+	return responses, total, nil
+}
+
+// SyncOpenOrders refreshes the status of orders the store has not finished
+func (s *ClothingService) SyncOpenOrders() (int, error) {
+	orders, err := s.orderRepo.GetOpen(100)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, order := range orders {
+		previous := order.Status
+		if err := s.syncOrder(order); err != nil {
+			utils.LogError(err, map[string]interface{}{"order_id": order.ID.String(), "action": "sync_clothing_order"})
+			continue
+		}
+		if order.Status != previous {
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+// StartOrderTracking syncs open orders with the store on a fixed interval
+func (s *ClothingService) StartOrderTracking(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		updated, err := s.SyncOpenOrders()
+		if err != nil {
+			utils.LogError(err, map[string]interface{}{"action": "sync_clothing_orders"})
+			continue
+		}
+		if updated > 0 {
+			utils.LogInfo("Updated clothing orders", map[string]interface{}{"count": updated})
+		}
+	}
+}
+
+// syncOrder applies the store's view of an order. An order the store
+// cancelled is refunded.
+func (s *ClothingService) syncOrder(order *models.ExternalOrder) error {
+	ctx, cancel := context.WithTimeout(context.Background(), catalogTimeout)
+	defer cancel()
+
+	placement, err := s.catalog.OrderStatus(ctx, order.ExternalOrderID)
+	if err != nil {
+		return err
+	}
+
+	previous := order.Status
+	if !applyPlacement(order, placement) {
+		return nil
+	}
+	if order.Status == models.ExternalOrderStatusCancelled && previous != order.Status {
+		return s.settleCancelledOrder(order, "Order cancelled by "+order.Website)
+	}
+	return s.orderRepo.Update(nil, order)
+}
+
+// settleCancelledOrder records a store-side cancellation and refunds the payment
+func (s *ClothingService) settleCancelledOrder(order *models.ExternalOrder, reason string) error {
+	now := time.Now()
+	order.Status = models.ExternalOrderStatusCancelled
+	order.CancelledAt = &now
+
+	if order.PaymentStatus == models.ExternalOrderPaymentPaid && order.TransactionID != "" {
+		if _, err := s.refundService.RefundOrderPayment(order.UserID.String(), &dto.CreateRefundRequest{
+			TransactionID: order.TransactionID,
+			Reason:        reason,
+		}); err != nil && !errors.Is(err, ErrAlreadyRefunded) {
+			order.FailureReason = "Refund failed: " + err.Error()
+			if updateErr := s.orderRepo.Update(nil, order); updateErr != nil {
+				return updateErr
+			}
+			return fmt.Errorf("order cancelled but not refunded: %w", err)
+		}
+		order.PaymentStatus = models.ExternalOrderPaymentRefunded
+	}
+
+	return s.orderRepo.Update(nil, order)
+}
+
+// failOrder marks an order that could not be placed and returns its held funds
+func (s *ClothingService) failOrder(order *models.ExternalOrder, reason string) {
+	if err := s.holdService.ReleaseHold(nil, order.OrderNumber, reason); err != nil {
+		utils.LogError(err, map[string]interface{}{"order_id": order.ID.String(), "action": "release_clothing_order_hold"})
+	}
+
+	order.Status = models.ExternalOrderStatusFailed
+	order.FailureReason = reason
+	if err := s.orderRepo.Update(nil, order); err != nil {
+		utils.LogError(err, map[string]interface{}{"order_id": order.ID.String(), "action": "fail_clothing_order"})
+	}
+}
+
+// selectAddress returns the user's address with the given ID, or their default
+// address when no ID is given. Returns nil if the user has no default address.
+func (s *ClothingService) selectAddress(userID uuid.UUID, addressID string) (*models.Address, error) {
+	var response *models.AddressResponse
+	var err error
+	if addressID != "" {
+		if response, err = s.addressService.GetAddress(userID.String(), addressID); err != nil {
+			return nil, err
+		}
+	} else if response, err = s.addressService.GetDefaultAddress(userID.String()); err != nil {
+		return nil, nil
+	}
+
+	id, err := uuid.Parse(response.ID)
+	if err != nil {
+		return nil, errors.New("invalid address ID")
+	}
+	return &models.Address{
+		ID:          id,
+		UserID:      userID,
+		Name:        response.Name,
+		Phone:       response.Phone,
+		AddressLine: response.AddressLine,
+		City:        response.City,
+		State:       response.State,
+		PinCode:     response.PinCode,
+		Country:     response.Country,
+		Landmark:    response.Landmark,
+		IsDefault:   response.IsDefault,
+		AddressType: response.AddressType,
+	}, nil
+}
+
+// selectItems prices the products picked from an order's suggestions with the
+// catalog's current prices. Without a selection the drafted items are bought.
+func (s *ClothingService) selectItems(ctx context.Context, order *models.ExternalOrder, selected []models.SelectedProduct) ([]models.ExternalOrderItem, error) {
+	if len(selected) == 0 {
+		for _, item := range order.Items {
+			selected = append(selected, models.SelectedProduct{ID: item.ProductID, Quantity: item.Quantity, Size: item.Size, Color: item.Color})
+		}
+	}
+
+	suggested := make(map[string]models.ExternalOrderItem, len(order.Suggestions))
+	for _, suggestion := range order.Suggestions {
+		suggested[suggestion.ProductID] = suggestion
+	}
+
+	items := make([]models.ExternalOrderItem, 0, len(selected))
+	for _, choice := range selected {
+		suggestion, ok := suggested[choice.ID]
+		if !ok {
+			return nil, fmt.Errorf("product %s was not suggested for this order", choice.ID)
+		}
+
+		product, err := s.catalog.Product(ctx, choice.ID)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", suggestion.Name, err)
+		}
+
+		size, color := choice.Size, choice.Color
+		if size == "" && color == "" {
+			size, color = suggestion.Size, suggestion.Color
+		}
+		variant, ok := product.FindVariant(size, color)
+		if !ok || (size != "" && !strings.EqualFold(variant.Size, size)) {
+			return nil, fmt.Errorf("%s is out of stock in the size you chose", product.Name)
+		}
+		if variant.Stock < choice.Quantity {
+			return nil, fmt.Errorf("only %d of %s left in stock", variant.Stock, product.Name)
+		}
+
+		items = append(items, orderItem(product, variant, choice.Quantity, suggestion.Reason))
+	}
+	return items, nil
+}
+
+// applyPlacement copies the store's view of an order onto it and reports
+// whether anything changed
+func applyPlacement(order *models.ExternalOrder, placement *catalog.Placement) bool {
+	status := order.Status
+	switch placement.Status {
+	case catalog.StatusConfirmed:
+		status = models.ExternalOrderStatusConfirmed
+	case catalog.StatusShipped:
+		status = models.ExternalOrderStatusShipped
+	case catalog.StatusDelivered:
+		status = models.ExternalOrderStatusDelivered
+	case catalog.StatusCancelled:
+		status = models.ExternalOrderStatusCancelled
+	}
+
+	changed := status != order.Status ||
+		placement.OrderID != order.ExternalOrderID ||
+		placement.TrackingNumber != order.TrackingNumber ||
+		(placement.DeliveredAt != nil && order.DeliveredAt == nil)
+
+	order.Status = status
+	order.ExternalOrderID = placement.OrderID
+	order.TrackingNumber = placement.TrackingNumber
+	if placement.EstimatedDelivery != nil {
+		order.EstimatedDelivery = placement.EstimatedDelivery
+	}
+	if placement.DeliveredAt != nil {
+		order.DeliveredAt = placement.DeliveredAt
+	}
+	return changed
+}
+
+func orderItem(product *catalog.Product, variant *catalog.Variant, quantity int, reason string) models.ExternalOrderItem {
+	return models.ExternalOrderItem{
+		ProductID:  product.ID,
+		VariantID:  variant.ID,
+		Name:       product.Name,
+		Brand:      product.Brand,
+		Category:   product.Category,
+		Size:       variant.Size,
+		Color:      variant.Color,
+		Quantity:   quantity,
+		Price:      product.Price,
+		ImageURL:   product.ImageURL,
+		ProductURL: product.URL,
+		Website:    product.Website,
+		Reason:     reason,
+	}
+}
+
+func orderLines(items []models.ExternalOrderItem) []catalog.OrderLine {
+	lines := make([]catalog.OrderLine, len(items))
+	for i, item := range items {
+		lines[i] = catalog.OrderLine{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity}
+	}
+	return lines
+}
+
+func catalogAddress(address *models.Address) catalog.Address {
+	return catalog.Address{
+		Name:        address.Name,
+		Phone:       address.Phone,
+		AddressLine: address.AddressLine,
+		Landmark:    address.Landmark,
+		City:        address.City,
+		State:       address.State,
+		PinCode:     address.PinCode,
+		Country:     address.Country,
+	}
+}
+
+func productSuggestion(suggestion *catalog.Suggestion) models.ProductSuggestion {
+	product := &suggestion.Product
+	return models.ProductSuggestion{
+		Product: models.ProductResponse{
+			ID:       product.ID,
+			Name:     product.Name,
+			Brand:    product.Brand,
+			Category: product.Category,
+			Price:    product.Price,
+			ImageURL: product.ImageURL,
+			Rating:   product.Rating,
+			Website:  product.Website,
+			URL:      product.URL,
+		},
+		Variant: models.VariantResponse{
+			ID:    suggestion.Variant.ID,
+			Size:  suggestion.Variant.Size,
+			Color: suggestion.Variant.Color,
+			Stock: suggestion.Variant.Stock,
+		},
+		Quantity: 1,
+		Reason:   suggestion.Reason(),
+		Price:    product.Price,
+	}
+}
+
+func suggestedProduct(product *catalog.Product) models.SuggestedProduct {
+	price, _ := product.Price.Float64()
+	return models.SuggestedProduct{
+		ID:          product.ID,
+		Name:        product.Name,
+		Brand:       product.Brand,
+		Price:       price,
+		Currency:    product.Currency,
+		ImageURL:    product.ImageURL,
+		Rating:      product.Rating,
+		Description: product.Description,
+		URL:         product.URL,
+		Website:     product.Website,
+	}
+}
+
+func clothingOrderResponse(order *models.ExternalOrder) *models.ClothingOrderResponse {
+	response := &models.ClothingOrderResponse{
+		OrderID:     order.ID.String(),
+		OrderNumber: order.OrderNumber,
+		Status:      order.Status,
+		TotalAmount: order.TotalAmount,
+		PaymentInfo: models.PaymentInfoResponse{
+			Status:        order.PaymentStatus,
+			TransactionID: order.TransactionID,
+		},
+		Items:             order.Items,
+		Website:           order.Website,
+		ExternalOrderID:   order.ExternalOrderID,
+		TrackingNumber:    order.TrackingNumber,
+		EstimatedDelivery: order.EstimatedDelivery,
+		DeliveredAt:       order.DeliveredAt,
+		FailureReason:     order.FailureReason,
+		CreatedAt:         order.CreatedAt,
+	}
+	if order.DeliveryAddress.ID != uuid.Nil {
+		address := order.DeliveryAddress
+		response.DeliveryAddress = &address
+	}
+	return response
+}
+
+func describeBudget(budget decimal.Decimal) string {
+	if !budget.IsPositive() {
+		return ""
+	}
+	return " within ₹" + budget.StringFixed(2)
+}
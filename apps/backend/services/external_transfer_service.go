@@ -3,61 +3,201 @@ package services
 import (
 	"errors"
 	"fmt"
-	"log"
-	"regexp"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
-	"github.com/zeusnotfound04/Tranza/config"
 	"github.com/zeusnotfound04/Tranza/models"
 	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/pkg/ifsc"
 	"github.com/zeusnotfound04/Tranza/pkg/razorpay"
+	"github.com/zeusnotfound04/Tranza/pkg/risk"
 	"github.com/zeusnotfound04/Tranza/repositories"
 	"github.com/zeusnotfound04/Tranza/utils"
 	"gorm.io/gorm"
 )
 
+// ErrRecipientNameRequired is returned when a payout has no name to create
+// the recipient's Razorpay contact under
+var ErrRecipientNameRequired = errors.New("recipient name is required")
+
 type ExternalTransferService struct {
 	db                   *gorm.DB
 	externalTransferRepo *repositories.ExternalTransferRepository
+	beneficiaryRepo      *repositories.BeneficiaryRepository
 	walletRepo           *repositories.WalletRepository
 	transactionRepo      *repositories.TransactionRepository
+	ledgerService        *LedgerService
+	limitsService        *LimitsService
+	holdService          *HoldService
+	riskService          *RiskService
+	jobQueue             *JobQueue
 	razorpayClient       *razorpay.Client
+	ifscDirectory        *ifsc.Directory
+	cancelWindow         time.Duration
+	phoneResolver        *PhoneResolver
+	walletTransfers      *WalletTransferService
+	feeService           *FeeService
 	notificationService  *NotificationService
+	completionHandlers   []TransferCompletionHandler
+}
+
+// TransferCompletionHandler is called when a transfer reaches a final state
+type TransferCompletionHandler func(transfer *models.ExternalTransfer)
+
+// MerchantPayout describes a payout settling a confirmed AI payment
+type MerchantPayout struct {
+	UserID       uuid.UUID
+	WalletID     uuid.UUID
+	Amount       decimal.Decimal
+	MerchantName string
+	UPIID        string
+	Description  string
+	IPAddress    string // Where the payment was requested from
 }
 
 func NewExternalTransferService(
 	db *gorm.DB,
 	externalTransferRepo *repositories.ExternalTransferRepository,
+	beneficiaryRepo *repositories.BeneficiaryRepository,
 	walletRepo *repositories.WalletRepository,
 	transactionRepo *repositories.TransactionRepository,
+	ledgerService *LedgerService,
+	limitsService *LimitsService,
+	holdService *HoldService,
+	riskService *RiskService,
+	jobQueue *JobQueue,
 	razorpayClient *razorpay.Client,
+	ifscDirectory *ifsc.Directory,
+	cancelWindow time.Duration,
+	phoneResolver *PhoneResolver,
+	walletTransfers *WalletTransferService,
+	feeService *FeeService,
 	notificationService *NotificationService,
 ) *ExternalTransferService {
 	return &ExternalTransferService{
 		db:                   db,
 		externalTransferRepo: externalTransferRepo,
+		beneficiaryRepo:      beneficiaryRepo,
 		walletRepo:           walletRepo,
 		transactionRepo:      transactionRepo,
+		ledgerService:        ledgerService,
+		limitsService:        limitsService,
+		holdService:          holdService,
+		riskService:          riskService,
+		jobQueue:             jobQueue,
 		razorpayClient:       razorpayClient,
+		ifscDirectory:        ifscDirectory,
+		cancelWindow:         cancelWindow,
+		phoneResolver:        phoneResolver,
+		walletTransfers:      walletTransfers,
+		feeService:           feeService,
 		notificationService:  notificationService,
 	}
 }
 
-// Constants for transfer limits and fees
+// NewIFSCDirectory loads the bank branch list bank transfers are checked
+// against. Without one, IFSC codes are checked against the built-in bank list.
+func NewIFSCDirectory(path string) *ifsc.Directory {
+	if path == "" {
+		return ifsc.NewDirectory(nil)
+	}
+
+	directory, err := ifsc.LoadDirectory(path)
+	if err != nil {
+		utils.LogWarning("IFSC directory not loaded, checking bank codes only", map[string]interface{}{
+			"file":  path,
+			"error": err.Error(),
+		})
+		return ifsc.NewDirectory(nil)
+	}
+	return directory
+}
+
+// Constants for single transfer amounts; fees come from fee plans, and what
+// may be sent in a day or month from the wallet's limits
 const (
-	MinTransferAmount    = 1.0    // ₹1
-	MaxTransferAmount    = 100000 // ₹1,00,000
-	DailyTransferLimit   = 50000  // ₹50,000
-	MonthlyTransferLimit = 200000 // ₹2,00,000
-	UPITransferFee       = 2.0    // ₹2 per transfer
-	PhoneTransferFee     = 5.0    // ₹5 per transfer (if phone to UPI conversion)
+	MinTransferAmount     = 1.0     // ₹1
+	MaxTransferAmount     = 100000  // ₹1,00,000 to a UPI ID or phone number
+	MaxBankTransferAmount = 1000000 // ₹10,00,000 to a bank account, enough for RTGS
+)
+
+// maxTransferAmount returns the most a single transfer to the recipient type
+// may carry. Bank accounts take RTGS amounts, which UPI does not.
+func maxTransferAmount(recipientType string) decimal.Decimal {
+	if recipientType == models.RecipientTypeIFSC {
+		return decimal.NewFromInt(MaxBankTransferAmount)
+	}
+	return decimal.NewFromInt(MaxTransferAmount)
+}
+
+var (
+	ErrTransferNotFound       = errors.New("transfer not found")
+	ErrTransferNotCancellable = errors.New("transfer is already being paid out and can no longer be cancelled")
 )
 
-// ValidateTransferRequest validates a transfer request before processing
+// TransferModeWallet is the mode of a phone transfer to a Tranza user, which
+// credits their wallet instead of going out by UPI
+const TransferModeWallet = "WALLET"
+
+// Bank transfer mode thresholds
+const (
+	IMPSMaxAmount = 500000 // ₹5,00,000, the most IMPS carries in one transfer
+	RTGSMinAmount = 200000 // ₹2,00,000, the least RTGS accepts
+)
+
+// Payout job settings
+const (
+	PayoutPollInterval    = 30 * time.Second // How often a new payout's status is checked
+	PayoutPollMaxInterval = 30 * time.Minute // Longest wait between checks of an older payout
+	PayoutReviewAfter     = 24 * time.Hour   // When a payout still processing is flagged for ops
+	PayoutPollAttempts    = 5                // Consecutive status check failures before giving up
+)
+
+// payoutJobPayload identifies the transfer a payout job works on
+type payoutJobPayload struct {
+	TransferID string `json:"transfer_id"`
+}
+
+// OnTransferComplete registers a handler run after every transfer succeeds,
+// fails or is reversed. Handlers must be registered before the job queue starts.
+func (s *ExternalTransferService) OnTransferComplete(handler TransferCompletionHandler) {
+	s.completionHandlers = append(s.completionHandlers, handler)
+}
+
+// notifyTransferComplete passes the transfer's final state to the registered handlers
+func (s *ExternalTransferService) notifyTransferComplete(transferID uuid.UUID) {
+	if len(s.completionHandlers) == 0 {
+		return
+	}
+
+	transfer, err := s.externalTransferRepo.GetByID(transferID)
+	if err != nil {
+		utils.LogError(err, map[string]interface{}{"transfer_id": transferID.String(), "action": "get_transfer_for_completion"})
+		return
+	}
+	for _, handler := range s.completionHandlers {
+		handler(transfer)
+	}
+}
+
+// QuoteFee quotes the fee the user's fee plan charges for a transfer; an
+// empty mode prices the recipient type's default mode
+func (s *ExternalTransferService) QuoteFee(userID uuid.UUID, recipientType, mode string, amount decimal.Decimal) (*FeeQuote, error) {
+	return s.feeService.Quote(userID, recipientType, mode, amount)
+}
+
+// ValidateTransferRequest validates a transfer request before processing. A
+// phone number is resolved to the Tranza user or UPI ID it belongs to and the
+// name returned, so the user can check it before any money moves.
 func (s *ExternalTransferService) ValidateTransferRequest(userID string, req *dto.ValidateTransferRequest) (*dto.ValidateTransferResponse, error) {
+	return s.validateTransfer(userID, req, nil)
+}
+
+// validateTransfer validates a transfer whose phone number recipient, if the
+// caller already resolved it, is recipient
+func (s *ExternalTransferService) validateTransfer(userID string, req *dto.ValidateTransferRequest, recipient *ResolvedRecipient) (*dto.ValidateTransferResponse, error) {
 	uid, err := uuid.Parse(userID)
 	if err != nil {
 		return nil, errors.New("invalid user ID")
@@ -69,21 +209,70 @@ func (s *ExternalTransferService) ValidateTransferRequest(userID string, req *dt
 		Errors:   []string{},
 	}
 
+	// Fill in the recipient of a saved beneficiary
+	if req.BeneficiaryID != "" {
+		initiatedBy := req.InitiatedBy
+		if initiatedBy == "" {
+			initiatedBy = models.InitiatedByUser
+		}
+		beneficiary, err := s.applyBeneficiary(uid, req.BeneficiaryID, req.Amount, initiatedBy)
+		if err != nil {
+			response.Valid = false
+			response.Errors = append(response.Errors, err.Error())
+			return response, nil
+		}
+		req.RecipientType = beneficiary.RecipientType
+		req.RecipientValue = beneficiary.RecipientValue
+		req.RecipientIFSC = beneficiary.RecipientIFSC
+		response.RecipientName = beneficiary.Name
+		if beneficiary.RecipientType == models.RecipientTypePhone {
+			recipient = beneficiaryRecipient(beneficiary)
+		}
+	}
+
+	// Find out who a phone number belongs to
+	if req.RecipientType == models.RecipientTypePhone && recipient == nil && s.validatePhoneNumber(req.RecipientValue) == nil {
+		recipient, err = s.phoneResolver.Resolve(uid, req.RecipientValue)
+		if err != nil {
+			response.Valid = false
+			response.Errors = append(response.Errors, err.Error())
+		}
+	}
+	walletCredit := recipient != nil && recipient.IsTranzaUser()
+	if recipient != nil {
+		response.RecipientName = recipient.Name
+		response.ResolvedVPA = recipient.VPA
+		response.ResolvedVia = recipient.Source
+	}
+
 	// Validate amount
 	if req.Amount.LessThan(decimal.NewFromFloat(MinTransferAmount)) {
 		response.Valid = false
 		response.Errors = append(response.Errors, fmt.Sprintf("Minimum transfer amount is ₹%.0f", MinTransferAmount))
 	}
 
-	if req.Amount.GreaterThan(decimal.NewFromFloat(MaxTransferAmount)) {
+	if maxAmount := maxTransferAmount(req.RecipientType); req.Amount.GreaterThan(maxAmount) {
 		response.Valid = false
-		response.Errors = append(response.Errors, fmt.Sprintf("Maximum transfer amount is ₹%.0f", float64(MaxTransferAmount)))
+		response.Errors = append(response.Errors, fmt.Sprintf("Maximum transfer amount is ₹%s", maxAmount.String()))
 	}
 
-	// Validate recipient
-	if err := s.validateRecipient(req.RecipientType, req.RecipientValue); err != nil {
+	// Validate recipient and pick the payout mode
+	var transferMode string
+	if err := s.validateRecipient(req.RecipientType, req.RecipientValue, req.RecipientIFSC); err != nil {
 		response.Valid = false
 		response.Errors = append(response.Errors, err.Error())
+	} else if walletCredit {
+		transferMode = TransferModeWallet
+		response.TransferMode = transferMode
+	} else if mode, branch, err := s.transferMode(req.RecipientType, req.RecipientIFSC, req.Amount); err != nil {
+		response.Valid = false
+		response.Errors = append(response.Errors, err.Error())
+	} else {
+		transferMode = mode
+		response.TransferMode = mode
+		if branch != nil {
+			response.Bank = branch.Display()
+		}
 	}
 
 	// Check wallet balance
@@ -94,42 +283,56 @@ func (s *ExternalTransferService) ValidateTransferRequest(userID string, req *dt
 		return response, nil
 	}
 
-	// Calculate transfer fee
-	transferFee := s.calculateTransferFee(req.RecipientType, req.Amount)
+	// Quote the transfer fee from the user's fee plan; wallet credits are free
+	transferFee := decimal.Zero
+	if !walletCredit {
+		quote, err := s.feeService.Quote(uid, req.RecipientType, transferMode, req.Amount)
+		if err != nil {
+			response.Valid = false
+			response.Errors = append(response.Errors, err.Error())
+		} else {
+			transferFee = quote.Fee
+			response.FeeQuote = quote.Response()
+		}
+	}
 	totalAmount := req.Amount.Add(transferFee)
 
 	response.TransferFee = transferFee
 	response.TotalAmount = totalAmount
 
-	// Check sufficient balance
-	if wallet.Balance.LessThan(totalAmount) {
+	// Check sufficient balance, excluding funds already on hold other than
+	// those held to pay this transfer
+	available, _, err := s.holdService.GetAvailableBalance(wallet)
+	if err != nil {
+		available = wallet.Balance
+	}
+	if req.FundingHold != "" {
+		if hold, err := s.holdService.GetHold(req.FundingHold); err == nil && hold.IsActive() {
+			available = available.Add(decimal.Min(hold.Amount, totalAmount))
+		}
+	}
+	if available.LessThan(totalAmount) {
 		response.Valid = false
 		response.Errors = append(response.Errors, "Insufficient wallet balance")
 	}
 
-	// Check daily limit
-	today := time.Now()
-	dailyUsed, err := s.externalTransferRepo.GetDailyTransferLimitUsed(uid, today)
-	if err == nil {
-		if dailyUsed.Add(totalAmount).GreaterThan(decimal.NewFromFloat(DailyTransferLimit)) {
-			response.Valid = false
-			response.Errors = append(response.Errors, "Daily transfer limit exceeded")
-		} else if dailyUsed.Add(totalAmount).GreaterThan(decimal.NewFromFloat(DailyTransferLimit * 0.8)) {
-			response.Warnings = append(response.Warnings, "Approaching daily transfer limit")
-		}
+	// Check wallet limits; a wallet credit counts as a wallet to wallet transfer
+	// and not against the external transfer limits
+	debitKind := DebitKindExternalTransfer
+	if walletCredit {
+		debitKind = DebitKindWalletTransfer
 	}
-
-	// Check monthly limit
-	monthlyUsed, err := s.externalTransferRepo.GetMonthlyTransferLimitUsed(uid, today)
-	if err == nil {
-		if monthlyUsed.Add(totalAmount).GreaterThan(decimal.NewFromFloat(MonthlyTransferLimit)) {
-			response.Valid = false
-			response.Errors = append(response.Errors, "Monthly transfer limit exceeded")
-		}
+	if err := s.limitsService.CheckDebitFromHold(wallet, debitKind, totalAmount, req.FundingHold); err != nil {
+		response.Valid = false
+		response.Errors = append(response.Errors, err.Error())
+	}
+	if walletCredit {
+		response.EstimatedTime = "Instant"
+		return response, nil
 	}
 
 	// Set estimated time
-	response.EstimatedTime = s.getEstimatedTransferTime(req.RecipientType)
+	response.EstimatedTime = s.getEstimatedTransferTime(req.RecipientType, transferMode)
 
 	return response, nil
 }
@@ -141,14 +344,104 @@ func (s *ExternalTransferService) CreateExternalTransfer(userID string, req *dto
 		return nil, errors.New("invalid user ID")
 	}
 
-	// Validate the request first
+	// Get wallet
+	wallet, err := s.walletRepo.GetByUserID(uid)
+	if err != nil {
+		return nil, errors.New("wallet not found")
+	}
+
+	initiatedBy := req.InitiatedBy
+	if initiatedBy == "" {
+		initiatedBy = models.InitiatedByUser
+	}
+
+	// Pay a saved beneficiary, or reuse the Razorpay fund account of one that
+	// matches the raw recipient
+	var beneficiary *models.Beneficiary
+	if req.BeneficiaryID != "" {
+		beneficiary, err = s.applyBeneficiary(uid, req.BeneficiaryID, req.Amount, initiatedBy)
+		if err != nil {
+			return nil, err
+		}
+		req.RecipientType = beneficiary.RecipientType
+		req.RecipientValue = beneficiary.RecipientValue
+		req.RecipientIFSC = beneficiary.RecipientIFSC
+		if req.RecipientName == "" {
+			req.RecipientName = beneficiary.Name
+		}
+	} else if saved, err := s.beneficiaryRepo.GetByRecipient(uid, req.RecipientType, req.RecipientValue, ifsc.Normalize(req.RecipientIFSC)); err == nil && saved.IsVerified() {
+		beneficiary = saved
+	}
+
+	// Banks need the account holder's name to credit the account
+	if req.RecipientType == models.RecipientTypeIFSC && strings.TrimSpace(req.RecipientName) == "" {
+		return nil, errors.New("recipient name is required for bank transfers")
+	}
+	req.RecipientIFSC = ifsc.Normalize(req.RecipientIFSC)
+
+	// A phone number is paid at the UPI ID it resolves to, or straight into the
+	// wallet of the Tranza user it belongs to
+	var recipient *ResolvedRecipient
+	if req.RecipientType == models.RecipientTypePhone {
+		if beneficiary != nil {
+			recipient = beneficiaryRecipient(beneficiary)
+		} else if s.validatePhoneNumber(req.RecipientValue) == nil {
+			recipient, err = s.phoneResolver.Resolve(uid, req.RecipientValue)
+			if err != nil {
+				return nil, err
+			}
+			// Pay only the recipient the user confirmed from validation
+			if req.ResolvedVia != "" && !recipient.Matches(req.ResolvedVia, req.ResolvedVPA) {
+				return nil, ErrRecipientChanged
+			}
+			if recipient.IsTranzaUser() {
+				return s.payTranzaUser(userID, req, recipient, initiatedBy)
+			}
+			if strings.TrimSpace(req.RecipientName) == "" {
+				req.RecipientName = recipient.Name
+			}
+		}
+	}
+
+	// Razorpay needs the recipient's name for the contact it pays, unless a
+	// saved beneficiary already has a fund account
+	if (beneficiary == nil || beneficiary.RazorpayFundID == "") && strings.TrimSpace(req.RecipientName) == "" {
+		return nil, ErrRecipientNameRequired
+	}
+
+	// Pick the payout mode; bank transfers go by IMPS, NEFT or RTGS
+	transferMode, _, err := s.transferMode(req.RecipientType, req.RecipientIFSC, req.Amount)
+	if err != nil {
+		return nil, err
+	}
+	transferMethod := models.TransferMethodRazorpayPayout
+	if req.RecipientType == models.RecipientTypeIFSC {
+		transferMethod = models.TransferMethodBankTransfer
+	}
+
+	// Quote the fee from the user's fee plan
+	quote, err := s.feeService.Quote(uid, req.RecipientType, transferMode, req.Amount)
+	if err != nil {
+		return nil, err
+	}
+	transferFee := quote.Fee
+	totalAmount := req.Amount.Add(transferFee)
+
+	// Enforce wallet limits before validation so callers get the structured limit error
+	if err := s.limitsService.CheckDebitFromHold(wallet, DebitKindExternalTransfer, totalAmount, req.FundingHold); err != nil {
+		return nil, err
+	}
+
+	// Validate the request
 	validateReq := &dto.ValidateTransferRequest{
 		Amount:         req.Amount,
 		RecipientType:  req.RecipientType,
 		RecipientValue: req.RecipientValue,
+		RecipientIFSC:  req.RecipientIFSC,
+		FundingHold:    req.FundingHold,
 	}
 
-	validation, err := s.ValidateTransferRequest(userID, validateReq)
+	validation, err := s.validateTransfer(userID, validateReq, recipient)
 	if err != nil {
 		return nil, err
 	}
@@ -157,15 +450,23 @@ func (s *ExternalTransferService) CreateExternalTransfer(userID string, req *dto
 		return nil, errors.New(strings.Join(validation.Errors, "; "))
 	}
 
-	// Get wallet
-	wallet, err := s.walletRepo.GetByUserID(uid)
-	if err != nil {
-		return nil, errors.New("wallet not found")
+	riskKind := risk.KindExternalTransfer
+	if initiatedBy == models.InitiatedByBot {
+		riskKind = risk.KindBotTransfer
 	}
 
-	// Calculate fees
-	transferFee := s.calculateTransferFee(req.RecipientType, req.Amount)
-	totalAmount := req.Amount.Add(transferFee)
+	// Score the transfer before any money is reserved
+	assessment, err := s.riskService.Assess(&risk.Payment{
+		Kind:      riskKind,
+		UserID:    uid,
+		Amount:    req.Amount,
+		Recipient: req.RecipientValue,
+		IPAddress: req.IPAddress,
+		UserAgent: req.UserAgent,
+	}, riskConfirmation(req, initiatedBy))
+	if err != nil {
+		return nil, err
+	}
 
 	// Start database transaction
 	tx := s.db.Begin()
@@ -175,6 +476,48 @@ func (s *ExternalTransferService) CreateExternalTransfer(userID string, req *dto
 		}
 	}()
 
+	// Move the transfer's share off the hold it is paid from, so the hold
+	// below can reserve it and the limits do not count it twice
+	if req.FundingHold != "" {
+		if err := s.holdService.ReduceHold(tx, req.FundingHold, totalAmount, "paid by transfer"); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to update funding hold: %w", err)
+		}
+	}
+
+	// Check the limits again with the wallet locked, so concurrent debits
+	// cannot both fit under them
+	if err := s.limitsService.CheckDebitWithTx(tx, wallet.ID, DebitKindExternalTransfer, totalAmount); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// Claim the free transfer with the wallet locked; if a concurrent
+	// transfer took the last one, this one pays the plan's fee
+	if quote.Waived {
+		if err := s.feeService.ClaimFreeTransferWithTx(tx, uid, quote); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if !quote.Waived {
+			transferFee = quote.Fee
+			totalAmount = req.Amount.Add(transferFee)
+			if req.FundingHold != "" {
+				if err := s.holdService.ReduceHold(tx, req.FundingHold, transferFee, "paid by transfer"); err != nil {
+					tx.Rollback()
+					return nil, fmt.Errorf("failed to update funding hold: %w", err)
+				}
+			}
+			if err := s.limitsService.CheckDebitWithTx(tx, wallet.ID, DebitKindExternalTransfer, totalAmount); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+	}
+
+	// The transfer waits out the cancel window before it is sent to Razorpay
+	submitAfter := time.Now().Add(s.cancelWindow)
+
 	// Create external transfer record
 	transfer := &models.ExternalTransfer{
 		UserID:         uid,
@@ -184,18 +527,33 @@ func (s *ExternalTransferService) CreateExternalTransfer(userID string, req *dto
 		Description:    req.Description,
 		RecipientType:  req.RecipientType,
 		RecipientValue: req.RecipientValue,
+		RecipientIFSC:  req.RecipientIFSC,
 		RecipientName:  req.RecipientName,
-		Status:         models.ExternalTransferStatusPending,
-		TransferMethod: models.TransferMethodRazorpayPayout,
+		ResolvedVPA:    validation.ResolvedVPA,
+		Status:         models.ExternalTransferStatusQueued,
+		TransferMethod: transferMethod,
+		TransferMode:   transferMode,
 		TransferFee:    transferFee,
+		FeePlanID:      quote.PlanID(),
+		FeePlanVersion: quote.Plan.Version,
+		FeeGST:         quote.GST,
+		FeeWaived:      quote.Waived,
 		TotalAmount:    totalAmount,
-		InitiatedBy:    models.InitiatedByUser,
+		InitiatedBy:    initiatedBy,
+		SubmitAfter:    &submitAfter,
 		BalanceBefore:  wallet.Balance,
 		BalanceAfter:   wallet.Balance.Sub(totalAmount),
 		MaxRetries:     3,
+		IPAddress:      req.IPAddress,
+		UserAgent:      req.UserAgent,
+	}
+	if beneficiary != nil {
+		transfer.BeneficiaryID = &beneficiary.ID
+		transfer.RazorpayContactID = beneficiary.RazorpayContactID
+		transfer.RazorpayFundID = beneficiary.RazorpayFundID
 	}
 
-	createdTransfer, err := s.externalTransferRepo.Create(transfer)
+	createdTransfer, err := s.externalTransferRepo.CreateWithTx(tx, transfer)
 	if err != nil {
 		tx.Rollback()
 		return nil, fmt.Errorf("failed to create transfer record: %w", err)
@@ -212,14 +570,31 @@ func (s *ExternalTransferService) CreateExternalTransfer(userID string, req *dto
 		Status:       models.StatusPending,
 		ReferenceID:  createdTransfer.ReferenceID,
 		BalanceAfter: wallet.Balance.Sub(totalAmount),
+		IPAddress:    req.IPAddress,
+		UserAgent:    req.UserAgent,
 	}
 
-	createdTransaction, err := s.transactionRepo.Create(transaction)
+	createdTransaction, err := s.transactionRepo.CreateWithTx(tx, transaction)
 	if err != nil {
 		tx.Rollback()
 		return nil, fmt.Errorf("failed to create transaction record: %w", err)
 	}
 
+	// Reserve funds with a hold; the wallet is only debited once the payout succeeds
+	_, err = s.holdService.PlaceHold(
+		tx,
+		wallet.ID,
+		totalAmount,
+		models.WalletHoldPurposeExternalTransfer,
+		createdTransfer.ReferenceID,
+		fmt.Sprintf("External transfer to %s", req.RecipientValue),
+		ExternalTransferHoldTTL,
+	)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to reserve wallet balance: %w", err)
+	}
+
 	// Update transfer with transaction ID
 	createdTransfer.TransactionID = &createdTransaction.ID
 	if err := s.externalTransferRepo.Update(tx, createdTransfer); err != nil {
@@ -227,66 +602,206 @@ func (s *ExternalTransferService) CreateExternalTransfer(userID string, req *dto
 		return nil, fmt.Errorf("failed to update transfer with transaction ID: %w", err)
 	}
 
-	// Deduct amount from wallet (reserve funds)
-	newBalance := wallet.Balance.Sub(totalAmount)
-	if err := s.walletRepo.UpdateBalance(tx, wallet.ID, newBalance); err != nil {
+	// Queue the payout with the transfer so it is submitted even if we crash after commit
+	if err := s.enqueuePayoutSubmit(tx, createdTransfer); err != nil {
 		tx.Rollback()
-		return nil, fmt.Errorf("failed to update wallet balance: %w", err)
+		return nil, fmt.Errorf("failed to queue payout: %w", err)
 	}
 
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		return nil, fmt.Errorf("failed to commit transfer transaction: %w", err)
 	}
-
-	// Check if we're in test mode - process synchronously for immediate completion
-	cfg := config.LoadConfig()
-	if cfg.Environment == "test" {
-		// Process synchronously in test mode
-		s.processTransferAsync(createdTransfer.ID.String())
-
-		// Fetch updated transfer to get the new status
-		updatedTransfer, err := s.externalTransferRepo.GetByID(createdTransfer.ID)
-		if err == nil {
-			return &dto.ExternalTransferResponse{
-				ID:             updatedTransfer.ID.String(),
-				ReferenceID:    updatedTransfer.ReferenceID,
-				Amount:         req.Amount,
-				Currency:       "INR",
-				TransferFee:    transferFee,
-				TotalAmount:    totalAmount,
-				RecipientType:  req.RecipientType,
-				RecipientValue: req.RecipientValue,
-				RecipientName:  req.RecipientName,
-				Status:         updatedTransfer.Status,
-				CreatedAt:      updatedTransfer.CreatedAt,
-				EstimatedTime:  s.getEstimatedTransferTimeForStatus(req.RecipientType, updatedTransfer.Status),
-			}, nil
+	s.riskService.LinkAssessment(assessment, createdTransfer.ID)
+	if beneficiary != nil {
+		if err := s.beneficiaryRepo.MarkUsed(beneficiary.ID, time.Now()); err != nil {
+			utils.LogError(err, map[string]interface{}{"beneficiary_id": beneficiary.ID.String(), "action": "mark_beneficiary_used"})
 		}
-	} else {
-		// Process the transfer asynchronously in production
-		go s.processTransferAsync(createdTransfer.ID.String())
 	}
 
 	// Send notification
 	// go s.notificationService.SendExternalTransferInitiatedNotification(userID, req.Amount, req.RecipientValue)
 
 	return &dto.ExternalTransferResponse{
-		ID:             createdTransfer.ID.String(),
-		ReferenceID:    createdTransfer.ReferenceID,
+		ID:               createdTransfer.ID.String(),
+		ReferenceID:      createdTransfer.ReferenceID,
+		Amount:           req.Amount,
+		Currency:         "INR",
+		TransferFee:      transferFee,
+		FeeQuote:         quote.Response(),
+		TotalAmount:      totalAmount,
+		RecipientType:    req.RecipientType,
+		RecipientValue:   req.RecipientValue,
+		RecipientIFSC:    req.RecipientIFSC,
+		RecipientName:    req.RecipientName,
+		ResolvedVPA:      createdTransfer.ResolvedVPA,
+		ResolvedVia:      validation.ResolvedVia,
+		BeneficiaryID:    beneficiaryIDString(createdTransfer.BeneficiaryID),
+		TransferMode:     transferMode,
+		Status:           models.ExternalTransferStatusQueued,
+		CreatedAt:        createdTransfer.CreatedAt,
+		EstimatedTime:    s.getEstimatedTransferTime(req.RecipientType, transferMode),
+		CancellableUntil: cancellableUntil(createdTransfer),
+		RiskLevel:        assessment.Level,
+	}, nil
+}
+
+// payTranzaUser pays a phone number that belongs to a Tranza user straight
+// into their wallet. No payout is made and no fee is charged; the transfer
+// goes through the same validation and risk checks and is done at once.
+func (s *ExternalTransferService) payTranzaUser(userID string, req *dto.CreateExternalTransferRequest, recipient *ResolvedRecipient, initiatedBy string) (*dto.ExternalTransferResponse, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	validation, err := s.validateTransfer(userID, &dto.ValidateTransferRequest{
 		Amount:         req.Amount,
-		Currency:       "INR",
-		TransferFee:    transferFee,
-		TotalAmount:    totalAmount,
 		RecipientType:  req.RecipientType,
 		RecipientValue: req.RecipientValue,
-		RecipientName:  req.RecipientName,
-		Status:         models.ExternalTransferStatusPending,
-		CreatedAt:      createdTransfer.CreatedAt,
-		EstimatedTime:  s.getEstimatedTransferTime(req.RecipientType),
+		FundingHold:    req.FundingHold,
+	}, recipient)
+	if err != nil {
+		return nil, err
+	}
+	if !validation.Valid {
+		return nil, errors.New(strings.Join(validation.Errors, "; "))
+	}
+
+	riskKind := risk.KindExternalTransfer
+	if initiatedBy == models.InitiatedByBot {
+		riskKind = risk.KindBotTransfer
+	}
+	assessment, err := s.riskService.Assess(&risk.Payment{
+		Kind:      riskKind,
+		UserID:    uid,
+		Amount:    req.Amount,
+		Recipient: req.RecipientValue,
+		IPAddress: req.IPAddress,
+		UserAgent: req.UserAgent,
+	}, riskConfirmation(req, initiatedBy))
+	if err != nil {
+		return nil, err
+	}
+
+	// The wallet transfer checks the available balance on its own, and
+	// reduces the funding hold in the transaction that debits the wallet. The
+	// transfer was scored above, as the phone number it was sent to.
+	result, err := s.walletTransfers.transfer(userID, &dto.WalletTransferRequest{
+		Recipient:   recipient.WalletID.String(),
+		Amount:      req.Amount,
+		Description: req.Description,
+		InitiatedBy: initiatedBy,
+		IPAddress:   req.IPAddress,
+		UserAgent:   req.UserAgent,
+		FundingHold: req.FundingHold,
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+	if transactionID, err := uuid.Parse(result.TransactionID); err == nil {
+		s.riskService.LinkAssessment(assessment, transactionID)
+	}
+
+	return &dto.ExternalTransferResponse{
+		ReferenceID:         result.ReferenceID,
+		WalletTransactionID: result.TransactionID,
+		Amount:              req.Amount,
+		Currency:            "INR",
+		TransferFee:         decimal.Zero,
+		TotalAmount:         req.Amount,
+		RecipientType:       req.RecipientType,
+		RecipientValue:      req.RecipientValue,
+		RecipientName:       recipient.Name,
+		ResolvedVia:         recipient.Source,
+		TransferMode:        TransferModeWallet,
+		Status:              models.ExternalTransferStatusSuccess,
+		CreatedAt:           time.Now(),
+		EstimatedTime:       validation.EstimatedTime,
+		RiskLevel:           assessment.Level,
 	}, nil
 }
 
+// CreateMerchantPayout queues the UPI payout that settles a confirmed AI
+// payment, inside the caller's database transaction. The payment is recorded
+// as a pending AI payment transaction that completes with the payout. No
+// transfer fee is charged and the wallet limits are left to the caller.
+func (s *ExternalTransferService) CreateMerchantPayout(tx *gorm.DB, payout *MerchantPayout) (*models.ExternalTransfer, *models.Transaction, error) {
+	if err := s.validateUPIID(payout.UPIID); err != nil {
+		return nil, nil, err
+	}
+
+	wallet, err := s.walletRepo.GetByIDForUpdate(tx, payout.WalletID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	transfer := &models.ExternalTransfer{
+		UserID:         payout.UserID,
+		WalletID:       wallet.ID,
+		Amount:         payout.Amount,
+		Currency:       "INR",
+		Description:    payout.Description,
+		RecipientType:  models.RecipientTypeUPI,
+		RecipientValue: payout.UPIID,
+		RecipientName:  payout.MerchantName,
+		Status:         models.ExternalTransferStatusPending,
+		TransferMethod: models.TransferMethodRazorpayPayout,
+		TransferMode:   razorpay.PayoutModeUPI,
+		TransferFee:    decimal.Zero,
+		TotalAmount:    payout.Amount,
+		InitiatedBy:    models.InitiatedByAI,
+		BalanceBefore:  wallet.Balance,
+		BalanceAfter:   wallet.Balance.Sub(payout.Amount),
+		MaxRetries:     3,
+		IPAddress:      payout.IPAddress,
+	}
+	if _, err := s.externalTransferRepo.CreateWithTx(tx, transfer); err != nil {
+		return nil, nil, fmt.Errorf("failed to create transfer record: %w", err)
+	}
+
+	transaction := &models.Transaction{
+		WalletID:      wallet.ID,
+		UserID:        payout.UserID,
+		Type:          utils.TransactionTypeAIPayment,
+		Amount:        payout.Amount,
+		Currency:      "INR",
+		Description:   payout.Description,
+		Status:        models.StatusPending,
+		ReferenceID:   transfer.ReferenceID,
+		MerchantName:  payout.MerchantName,
+		MerchantUPIID: payout.UPIID,
+		BalanceAfter:  wallet.Balance.Sub(payout.Amount),
+		IPAddress:     payout.IPAddress,
+	}
+	if _, err := s.transactionRepo.CreateWithTx(tx, transaction); err != nil {
+		return nil, nil, fmt.Errorf("failed to create transaction record: %w", err)
+	}
+
+	if _, err := s.holdService.PlaceHold(
+		tx,
+		wallet.ID,
+		payout.Amount,
+		models.WalletHoldPurposeAIPayment,
+		transfer.ReferenceID,
+		payout.Description,
+		ExternalTransferHoldTTL,
+	); err != nil {
+		return nil, nil, fmt.Errorf("failed to reserve wallet balance: %w", err)
+	}
+
+	transfer.TransactionID = &transaction.ID
+	if err := s.externalTransferRepo.Update(tx, transfer); err != nil {
+		return nil, nil, fmt.Errorf("failed to update transfer with transaction ID: %w", err)
+	}
+
+	if err := s.enqueuePayoutSubmit(tx, transfer); err != nil {
+		return nil, nil, fmt.Errorf("failed to queue payout: %w", err)
+	}
+
+	return transfer, transaction, nil
+}
+
 // GetExternalTransfer retrieves an external transfer by ID
 func (s *ExternalTransferService) GetExternalTransfer(transferID string) (*dto.ExternalTransferResponse, error) {
 	id, err := uuid.Parse(transferID)
@@ -300,18 +815,23 @@ func (s *ExternalTransferService) GetExternalTransfer(transferID string) (*dto.E
 	}
 
 	return &dto.ExternalTransferResponse{
-		ID:             transfer.ID.String(),
-		ReferenceID:    transfer.ReferenceID,
-		Amount:         transfer.Amount,
-		Currency:       transfer.Currency,
-		TransferFee:    transfer.TransferFee,
-		TotalAmount:    transfer.TotalAmount,
-		RecipientType:  transfer.RecipientType,
-		RecipientValue: transfer.RecipientValue,
-		RecipientName:  transfer.RecipientName,
-		Status:         transfer.Status,
-		CreatedAt:      transfer.CreatedAt,
-		EstimatedTime:  s.getEstimatedTransferTimeForStatus(transfer.RecipientType, transfer.Status),
+		ID:               transfer.ID.String(),
+		ReferenceID:      transfer.ReferenceID,
+		Amount:           transfer.Amount,
+		Currency:         transfer.Currency,
+		TransferFee:      transfer.TransferFee,
+		TotalAmount:      transfer.TotalAmount,
+		RecipientType:    transfer.RecipientType,
+		RecipientValue:   transfer.RecipientValue,
+		RecipientIFSC:    transfer.RecipientIFSC,
+		RecipientName:    transfer.RecipientName,
+		ResolvedVPA:      transfer.ResolvedVPA,
+		BeneficiaryID:    beneficiaryIDString(transfer.BeneficiaryID),
+		TransferMode:     transfer.TransferMode,
+		Status:           transfer.Status,
+		CreatedAt:        transfer.CreatedAt,
+		EstimatedTime:    s.getEstimatedTransferTimeForStatus(transfer.RecipientType, transfer.TransferMode, transfer.Status),
+		CancellableUntil: cancellableUntil(transfer),
 	}, nil
 }
 
@@ -331,17 +851,22 @@ func (s *ExternalTransferService) GetExternalTransfersByUser(userID string, page
 	var transferResponses []dto.ExternalTransferResponse
 	for _, transfer := range transfers {
 		transferResponses = append(transferResponses, dto.ExternalTransferResponse{
-			ID:             transfer.ID.String(),
-			ReferenceID:    transfer.ReferenceID,
-			Amount:         transfer.Amount,
-			Currency:       transfer.Currency,
-			TransferFee:    transfer.TransferFee,
-			TotalAmount:    transfer.TotalAmount,
-			RecipientType:  transfer.RecipientType,
-			RecipientValue: transfer.RecipientValue,
-			RecipientName:  transfer.RecipientName,
-			Status:         transfer.Status,
-			CreatedAt:      transfer.CreatedAt,
+			ID:               transfer.ID.String(),
+			ReferenceID:      transfer.ReferenceID,
+			Amount:           transfer.Amount,
+			Currency:         transfer.Currency,
+			TransferFee:      transfer.TransferFee,
+			TotalAmount:      transfer.TotalAmount,
+			RecipientType:    transfer.RecipientType,
+			RecipientValue:   transfer.RecipientValue,
+			RecipientIFSC:    transfer.RecipientIFSC,
+			RecipientName:    transfer.RecipientName,
+			ResolvedVPA:      transfer.ResolvedVPA,
+			BeneficiaryID:    beneficiaryIDString(transfer.BeneficiaryID),
+			TransferMode:     transfer.TransferMode,
+			Status:           transfer.Status,
+			CreatedAt:        transfer.CreatedAt,
+			CancellableUntil: cancellableUntil(transfer),
 		})
 	}
 
@@ -354,82 +879,259 @@ func (s *ExternalTransferService) GetExternalTransfersByUser(userID string, page
 	}, nil
 }
 
-// processTransferAsync processes the transfer with Razorpay asynchronously
-func (s *ExternalTransferService) processTransferAsync(transferID string) {
+// CancelExternalTransfer cancels one of the user's transfers that has not been
+// paid out and releases its reserved funds. A queued transfer is cancelled
+// before it reaches Razorpay; a submitted one only while Razorpay still has
+// the payout queued.
+func (s *ExternalTransferService) CancelExternalTransfer(userID, transferID string) (*dto.ExternalTransferResponse, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
 	id, err := uuid.Parse(transferID)
 	if err != nil {
-		utils.LogError(err, map[string]interface{}{"transfer_id": transferID, "action": "parse_transfer_id"})
-		return
+		return nil, errors.New("invalid transfer ID")
 	}
 
 	transfer, err := s.externalTransferRepo.GetByID(id)
+	if err != nil || transfer.UserID != uid {
+		return nil, ErrTransferNotFound
+	}
+
+	reason := "Cancelled by user"
+	switch transfer.Status {
+	case models.ExternalTransferStatusQueued:
+		_, err = s.giveUpTransfer(transfer.ID, []string{models.ExternalTransferStatusQueued}, models.ExternalTransferStatusCancelled, reason)
+	case models.ExternalTransferStatusProcessing:
+		err = s.cancelRazorpayPayout(transfer, reason)
+	default:
+		err = ErrTransferNotCancellable
+	}
+	if errors.Is(err, repositories.ErrTransferStatusChanged) {
+		// Submitted or settled since it was read
+		err = ErrTransferNotCancellable
+	}
 	if err != nil {
-		utils.LogError(err, map[string]interface{}{"transfer_id": transferID, "action": "get_transfer"})
-		return
+		return nil, err
 	}
 
-	// Process with Razorpay
-	if err := s.processWithRazorpay(transfer); err != nil {
-		utils.LogError(err, map[string]interface{}{"transfer_id": transferID, "action": "process_with_razorpay"})
+	transfer.Status = models.ExternalTransferStatusCancelled
+	s.notifyTransferComplete(transfer.ID)
 
-		// Update status to failed
-		s.externalTransferRepo.UpdateStatus(id, models.ExternalTransferStatusFailed, err.Error())
+	utils.LogInfo("External transfer cancelled", map[string]interface{}{
+		"transfer_id": transfer.ID.String(),
+		"payout_id":   transfer.RazorpayPayoutID,
+		"amount":      transfer.Amount.String(),
+	})
 
-		// Refund wallet balance
-		s.refundWalletBalance(transfer)
+	return s.GetExternalTransfer(transfer.ID.String())
+}
 
-		// Send failure notification
-		// go s.notificationService.SendExternalTransferFailedNotification(
-		// 	transfer.UserID.String(), transfer.Amount, transfer.RecipientValue, err.Error())
+// cancelRazorpayPayout cancels a submitted transfer's payout if Razorpay has
+// not started paying it out yet
+func (s *ExternalTransferService) cancelRazorpayPayout(transfer *models.ExternalTransfer, reason string) error {
+	if transfer.RazorpayPayoutID == "" {
+		return ErrTransferNotCancellable
 	}
-}
 
-// processWithRazorpay processes the transfer using Razorpay Payouts
-func (s *ExternalTransferService) processWithRazorpay(transfer *models.ExternalTransfer) error {
-	// Convert amount to paise
-	amountInPaise := transfer.Amount.Mul(decimal.NewFromInt(100)).IntPart()
+	payout, err := s.razorpayClient.GetPayout(transfer.RazorpayPayoutID)
+	if err != nil {
+		return fmt.Errorf("failed to get payout status: %w", err)
+	}
+	if payout.Status != razorpay.PayoutStatusQueued {
+		return ErrTransferNotCancellable
+	}
 
-	// Ensure recipient name is not empty (required by Razorpay)
-	recipientName := transfer.RecipientName
-	if recipientName == "" {
-		// Use a default name for testing/when name is not provided
-		recipientName = "Test User"
+	if _, err := s.razorpayClient.CancelPayout(transfer.RazorpayPayoutID); err != nil {
+		// The payout most likely left the queue in the meantime
+		utils.LogWarning("Razorpay payout not cancelled", map[string]interface{}{
+			"transfer_id": transfer.ID.String(),
+			"payout_id":   transfer.RazorpayPayoutID,
+			"error":       err.Error(),
+		})
+		return ErrTransferNotCancellable
 	}
 
-	// Check if we're in test mode and should simulate success
-	cfg := config.LoadConfig()
-	if cfg.Environment == "test" {
-		// Simulate successful payout for testing
-		log.Printf("Simulating successful payout in test mode, transfer_id: %s, amount: %s, recipient: %s",
-			transfer.ID.String(),
-			transfer.Amount.String(),
-			transfer.RecipientValue)
+	_, err = s.giveUpTransfer(transfer.ID, []string{models.ExternalTransferStatusProcessing}, models.ExternalTransferStatusCancelled, reason)
+	return err
+}
 
-		// Generate a fake payout ID
-		fakePayoutID := fmt.Sprintf("pout_test_%s", transfer.ReferenceID)
+// RegisterJobHandlers registers the payout jobs with the job queue
+func (s *ExternalTransferService) RegisterJobHandlers() {
+	s.jobQueue.Register(models.JobTypePayoutSubmit, utils.GetMaxRetryAttempts(), s.handlePayoutSubmitJob, s.handlePayoutSubmitDead)
+	s.jobQueue.Register(models.JobTypePayoutPoll, PayoutPollAttempts, s.handlePayoutPollJob, s.handlePayoutPollDead)
+}
 
-		// Update transfer with fake Razorpay payout ID
-		if err := s.externalTransferRepo.UpdateRazorpayPayoutID(transfer.ID, fakePayoutID); err != nil {
-			return fmt.Errorf("failed to update transfer with payout ID: %w", err)
+// ResumePendingTransfers queues jobs for transfers left in flight, e.g. by a
+// restart before the job queue existed. Transfers that already have a queued
+// or running job are skipped by the dedupe key.
+func (s *ExternalTransferService) ResumePendingTransfers() error {
+	transfers, err := s.externalTransferRepo.GetPendingTransfers()
+	if err != nil {
+		return fmt.Errorf("failed to get pending transfers: %w", err)
+	}
+
+	for _, transfer := range transfers {
+		if transfer.RazorpayPayoutID == "" {
+			err = s.enqueuePayoutSubmit(nil, transfer)
+		} else {
+			err = s.enqueuePayoutPoll(transfer.ID, time.Now())
 		}
+		if err != nil {
+			utils.LogError(err, map[string]interface{}{"transfer_id": transfer.ID.String(), "action": "resume_transfer"})
+		}
+	}
+
+	return nil
+}
+
+func (s *ExternalTransferService) enqueuePayoutSubmit(tx *gorm.DB, transfer *models.ExternalTransfer) error {
+	runAt := time.Now()
+	if transfer.SubmitAfter != nil && transfer.SubmitAfter.After(runAt) {
+		runAt = *transfer.SubmitAfter
+	}
+
+	return s.jobQueue.Enqueue(
+		tx,
+		models.JobTypePayoutSubmit,
+		payoutJobPayload{TransferID: transfer.ID.String()},
+		"payout_submit:"+transfer.ID.String(),
+		runAt,
+	)
+}
+
+func (s *ExternalTransferService) enqueuePayoutPoll(transferID uuid.UUID, runAt time.Time) error {
+	return s.jobQueue.Enqueue(
+		nil,
+		models.JobTypePayoutPoll,
+		payoutJobPayload{TransferID: transferID.String()},
+		"payout_poll:"+transferID.String(),
+		runAt,
+	)
+}
+
+func (s *ExternalTransferService) transferFromJob(job *models.Job) (*models.ExternalTransfer, error) {
+	var payload payoutJobPayload
+	if err := DecodeJobPayload(job, &payload); err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.Parse(payload.TransferID)
+	if err != nil {
+		return nil, errors.New("invalid transfer ID")
+	}
+
+	return s.externalTransferRepo.GetByID(id)
+}
+
+// handlePayoutSubmitJob submits a queued transfer to Razorpay
+func (s *ExternalTransferService) handlePayoutSubmitJob(job *models.Job) error {
+	transfer, err := s.transferFromJob(job)
+	if err != nil {
+		return err
+	}
+	return s.submitPayout(transfer.ID)
+}
+
+// handlePayoutSubmitDead fails a transfer whose payout could not be submitted
+func (s *ExternalTransferService) handlePayoutSubmitDead(job *models.Job, err error) {
+	transfer, getErr := s.transferFromJob(job)
+	if getErr != nil {
+		utils.LogError(getErr, map[string]interface{}{"job_id": job.ID.String(), "action": "get_transfer_for_dead_job"})
+		return
+	}
+	if (transfer.Status != models.ExternalTransferStatusQueued && transfer.Status != models.ExternalTransferStatusPending) ||
+		transfer.RazorpayPayoutID != "" {
+		return
+	}
 
-		// Immediately mark as successful
-		if err := s.externalTransferRepo.UpdateStatus(transfer.ID, models.ExternalTransferStatusSuccess, ""); err != nil {
-			return fmt.Errorf("failed to update transfer status: %w", err)
+	from := []string{models.ExternalTransferStatusQueued, models.ExternalTransferStatusPending}
+	if _, giveUpErr := s.giveUpTransfer(transfer.ID, from, models.ExternalTransferStatusFailed, err.Error()); giveUpErr != nil {
+		if !errors.Is(giveUpErr, repositories.ErrTransferStatusChanged) {
+			// Left in flight, so ResumePendingTransfers submits it again
+			utils.LogError(giveUpErr, map[string]interface{}{"transfer_id": transfer.ID.String(), "action": "fail_transfer"})
 		}
+		return
+	}
+	s.notifyTransferComplete(transfer.ID)
 
-		log.Printf("Test transfer completed successfully, transfer_id: %s, payout_id: %s",
-			transfer.ID.String(),
-			fakePayoutID)
+	// Send failure notification
+	// go s.notificationService.SendExternalTransferFailedNotification(
+	// 	transfer.UserID.String(), transfer.Amount, transfer.RecipientValue, err.Error())
+}
 
+// submitPayout sends a pending transfer to Razorpay. Transfers that already
+// have a payout are not submitted again, so a retried job cannot pay twice.
+func (s *ExternalTransferService) submitPayout(transferID uuid.UUID) error {
+	transfer, err := s.externalTransferRepo.GetByID(transferID)
+	if err != nil {
+		return err
+	}
+	if transfer.IsCompleted() {
 		return nil
 	}
+	if transfer.RazorpayPayoutID != "" {
+		// Submitted by an earlier attempt; make sure the payout is being tracked
+		return s.enqueuePayoutPoll(transfer.ID, time.Now().Add(PayoutPollInterval))
+	}
+
+	if transfer.Status == models.ExternalTransferStatusQueued {
+		if transfer.SubmitAfter != nil && time.Now().Before(*transfer.SubmitAfter) {
+			return &JobNotReadyError{RetryAfter: time.Until(*transfer.SubmitAfter)}
+		}
+		// Take the transfer out of the queue so it can no longer be cancelled
+		// locally. A transfer cancelled first is left alone.
+		err := s.externalTransferRepo.TransitionStatus(transfer.ID, []string{models.ExternalTransferStatusQueued}, models.ExternalTransferStatusPending, "")
+		if errors.Is(err, repositories.ErrTransferStatusChanged) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := s.processWithRazorpay(transfer); err != nil {
+		s.externalTransferRepo.IncrementRetryCount(transfer.ID)
+		return err
+	}
+	return nil
+}
+
+// processWithRazorpay processes the transfer using Razorpay Payouts
+func (s *ExternalTransferService) processWithRazorpay(transfer *models.ExternalTransfer) error {
+	// Convert amount to paise
+	amountInPaise := transfer.Amount.Mul(decimal.NewFromInt(100)).IntPart()
+
+	// Razorpay creates the recipient's contact under this name
+	recipientName := strings.TrimSpace(transfer.RecipientName)
+	if recipientName == "" && transfer.RazorpayFundID == "" {
+		return ErrRecipientNameRequired
+	}
 
 	var payout *razorpay.Payout
 	var err error
 
-	switch transfer.RecipientType {
-	case models.RecipientTypeUPI:
+	// Transfers created before bank transfers were added have no mode and are all UPI
+	mode := transfer.TransferMode
+	if mode == "" {
+		mode = razorpay.PayoutModeUPI
+	}
+
+	// Every payout is keyed by the transfer's reference ID, so a submit retried
+	// after a timeout gets back the payout Razorpay already made
+	switch {
+	case transfer.RazorpayFundID != "":
+		// Saved beneficiary: pay its existing fund account
+		payout, err = s.razorpayClient.CreateFundAccountPayout(
+			transfer.RazorpayFundID,
+			amountInPaise,
+			"INR",
+			mode,
+			razorpay.PurposePayout,
+			transfer.Description,
+			transfer.ReferenceID,
+		)
+	case transfer.RecipientType == models.RecipientTypeUPI:
 		payout, err = s.razorpayClient.CreateUPIPayout(
 			transfer.RecipientValue,
 			amountInPaise,
@@ -440,10 +1142,17 @@ func (s *ExternalTransferService) processWithRazorpay(transfer *models.ExternalT
 			"",
 			transfer.ReferenceID,
 		)
-	case models.RecipientTypePhone:
-		// For phone numbers, we might need to convert to UPI ID
-		// For now, treat as UPI with @paytm suffix
-		upiID := s.convertPhoneToUPI(transfer.RecipientValue)
+	case transfer.RecipientType == models.RecipientTypePhone:
+		// Pay the UPI ID the number resolved to when the transfer was made;
+		// transfers from before phone numbers were resolved look it up now
+		upiID := transfer.ResolvedVPA
+		if upiID == "" {
+			recipient, err := s.phoneResolver.ResolveVPA(transfer.UserID, transfer.RecipientValue)
+			if err != nil {
+				return fmt.Errorf("failed to resolve phone number: %w", err)
+			}
+			upiID = recipient.VPA
+		}
 		payout, err = s.razorpayClient.CreateUPIPayout(
 			upiID,
 			amountInPaise,
@@ -454,6 +1163,18 @@ func (s *ExternalTransferService) processWithRazorpay(transfer *models.ExternalT
 			transfer.RecipientValue,
 			transfer.ReferenceID,
 		)
+	case transfer.RecipientType == models.RecipientTypeIFSC:
+		payout, err = s.razorpayClient.CreateBankAccountPayout(
+			transfer.RecipientValue,
+			transfer.RecipientIFSC,
+			recipientName,
+			amountInPaise,
+			"INR",
+			mode,
+			razorpay.PurposePayout,
+			transfer.Description,
+			transfer.ReferenceID,
+		)
 	default:
 		return errors.New("unsupported recipient type")
 	}
@@ -462,80 +1183,238 @@ func (s *ExternalTransferService) processWithRazorpay(transfer *models.ExternalT
 		return fmt.Errorf("failed to create Razorpay payout: %w", err)
 	}
 
-	// Update transfer with Razorpay payout ID
-	if err := s.externalTransferRepo.UpdateRazorpayPayoutID(transfer.ID, payout.ID); err != nil {
-		return fmt.Errorf("failed to update transfer with payout ID: %w", err)
+	// Update transfer with Razorpay payout ID. A webhook for the payout can
+	// beat this and may already have settled the transfer, which must not be
+	// put back to processing or polled again.
+	err = s.externalTransferRepo.UpdateRazorpayPayoutID(transfer.ID, payout.ID, payoutInFlightStatuses)
+	if errors.Is(err, repositories.ErrTransferStatusChanged) {
+		current, getErr := s.externalTransferRepo.GetByID(transfer.ID)
+		if getErr != nil {
+			return fmt.Errorf("failed to get transfer: %w", getErr)
+		}
+		if current.IsCompleted() {
+			return nil
+		}
+	} else if err != nil {
+		return err
 	}
 
 	// Start monitoring the payout status
-	go s.monitorPayoutStatus(transfer.ID.String(), payout.ID)
-
-	return nil
+	return s.enqueuePayoutPoll(transfer.ID, time.Now().Add(PayoutPollInterval))
 }
 
-// monitorPayoutStatus monitors the Razorpay payout status
-func (s *ExternalTransferService) monitorPayoutStatus(transferID, payoutID string) {
-	id, err := uuid.Parse(transferID)
+// handlePayoutPollJob checks a processing payout with Razorpay and settles
+// the transfer once the payout reaches a final state. A payout is never given
+// up on while Razorpay still has it in flight; NEFT in particular can take
+// hours. Checks back off as the payout ages, and one still processing after
+// PayoutReviewAfter is flagged for ops.
+func (s *ExternalTransferService) handlePayoutPollJob(job *models.Job) error {
+	transfer, err := s.transferFromJob(job)
 	if err != nil {
-		utils.LogError(err, map[string]interface{}{"transfer_id": transferID, "action": "parse_transfer_id"})
-		return
+		return err
+	}
+	if transfer.IsCompleted() || transfer.RazorpayPayoutID == "" {
+		return nil
 	}
 
-	// Check status every 30 seconds for up to 10 minutes
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
+	payout, err := s.razorpayClient.GetPayout(transfer.RazorpayPayoutID)
+	if err != nil {
+		return fmt.Errorf("failed to get payout status: %w", err)
+	}
 
-	timeout := time.After(10 * time.Minute)
+	switch payoutStatusOutcome(payout.Status) {
+	case payoutSucceeded:
+		return s.handlePayoutSuccess(transfer.ID, payout)
+	case payoutFailed:
+		return s.handlePayoutFailure(transfer.ID, payout)
+	case payoutReversed:
+		return s.handlePayoutReversal(transfer.ID, payout)
+	}
 
-	for {
-		select {
-		case <-ticker.C:
-			payout, err := s.razorpayClient.GetPayout(payoutID)
-			if err != nil {
-				utils.LogError(err, map[string]interface{}{"transfer_id": transferID, "payout_id": payoutID, "action": "get_payout_status"})
-				continue
-			}
+	age := time.Since(transfer.CreatedAt)
+	if transfer.ProcessedAt != nil {
+		age = time.Since(*transfer.ProcessedAt)
+	}
+	if age > PayoutReviewAfter && transfer.ReviewFlaggedAt == nil {
+		if err := s.externalTransferRepo.FlagForReview(transfer.ID); err != nil {
+			utils.LogError(err, map[string]interface{}{"transfer_id": transfer.ID.String(), "action": "flag_transfer_for_review"})
+		} else {
+			utils.LogWarning("Payout still processing; flagged for review", map[string]interface{}{
+				"transfer_id": transfer.ID.String(),
+				"payout_id":   transfer.RazorpayPayoutID,
+				"status":      payout.Status,
+				"age":         age.String(),
+			})
+		}
+	}
 
-			switch payout.Status {
-			case razorpay.PayoutStatusProcessed:
-				s.handlePayoutSuccess(id, payout)
-				return
-			case razorpay.PayoutStatusFailed, razorpay.PayoutStatusCancelled:
-				s.handlePayoutFailure(id, payout)
-				return
-			case razorpay.PayoutStatusReversed:
-				s.handlePayoutReversal(id, payout)
-				return
-			}
+	return &JobNotReadyError{RetryAfter: payoutPollDelay(age)}
+}
 
-		case <-timeout:
-			// Timeout - mark as failed
-			s.externalTransferRepo.UpdateStatus(id, models.ExternalTransferStatusFailed, "Transfer timeout")
-			transfer, _ := s.externalTransferRepo.GetByID(id)
-			if transfer != nil {
-				s.refundWalletBalance(transfer)
-			}
-			return
-		}
+// payoutPollDelay is how long to wait before checking a payout of the given
+// age again: often at first, then less often up to PayoutPollMaxInterval
+func payoutPollDelay(age time.Duration) time.Duration {
+	delay := age / 10
+	if delay < PayoutPollInterval {
+		return PayoutPollInterval
+	}
+	if delay > PayoutPollMaxInterval {
+		return PayoutPollMaxInterval
 	}
+	return delay
 }
 
-// handlePayoutSuccess handles successful payout
-func (s *ExternalTransferService) handlePayoutSuccess(transferID uuid.UUID, payout *razorpay.Payout) {
-	if err := s.externalTransferRepo.UpdateStatus(transferID, models.ExternalTransferStatusSuccess, ""); err != nil {
-		utils.LogError(err, map[string]interface{}{"transfer_id": transferID.String(), "action": "update_success_status"})
-		return
+// payoutOutcome is what a payout's state at Razorpay means for its transfer
+type payoutOutcome int
+
+const (
+	payoutInFlight  payoutOutcome = iota // Still with Razorpay or the bank
+	payoutSucceeded                      // Settle the transfer
+	payoutFailed                         // Fail the transfer and release its funds
+	payoutReversed                       // Refund the transfer, even after success
+	payoutIgnored                        // Nothing to apply
+)
+
+// payoutStatusOutcome maps the status of a polled payout to its outcome
+func payoutStatusOutcome(status string) payoutOutcome {
+	switch status {
+	case razorpay.PayoutStatusProcessed:
+		return payoutSucceeded
+	case razorpay.PayoutStatusFailed, razorpay.PayoutStatusCancelled:
+		return payoutFailed
+	case razorpay.PayoutStatusReversed:
+		return payoutReversed
+	}
+	return payoutInFlight
+}
+
+// payoutEventOutcome maps a payout webhook to its outcome. A processed or
+// reversed payout is final at Razorpay and applies whatever the transfer says
+// locally; other events for completed transfers are ignored.
+func payoutEventOutcome(event string, transferCompleted bool) payoutOutcome {
+	switch event {
+	case "payout.reversed":
+		return payoutReversed
+	case "payout.processed":
+		return payoutSucceeded
+	}
+	if transferCompleted {
+		return payoutIgnored
+	}
+
+	switch event {
+	case "payout.queued", "payout.pending", "payout.initiated":
+		return payoutInFlight
+	case "payout.failed", "payout.rejected":
+		return payoutFailed
+	}
+	return payoutIgnored
+}
+
+// handlePayoutPollDead leaves the transfer processing when Razorpay could not
+// be reached; it is polled again on the next start
+func (s *ExternalTransferService) handlePayoutPollDead(job *models.Job, err error) {
+	utils.LogWarning("Stopped polling payout status", map[string]interface{}{
+		"job_id": job.ID.String(),
+		"error":  err.Error(),
+	})
+}
+
+// HandlePayoutEvent applies a Razorpay payout webhook to the matching transfer.
+// A processed or reversed payout is final at Razorpay and overrides whatever
+// the transfer says locally; other events for completed transfers are ignored.
+// An error means the event was not applied and should be delivered again.
+func (s *ExternalTransferService) HandlePayoutEvent(event string, payout *razorpay.Payout) error {
+	transfer, err := s.externalTransferRepo.GetByRazorpayPayoutID(payout.ID)
+	if err != nil && payout.Reference != "" {
+		// The webhook can beat the payout ID being saved after submission
+		transfer, err = s.externalTransferRepo.GetByReferenceID(payout.Reference)
+	}
+	if err != nil {
+		return fmt.Errorf("transfer not found for payout %s: %w", payout.ID, err)
+	}
+
+	if transfer.RazorpayPayoutID == "" {
+		// A transfer already given up on keeps its status; the event decides
+		// below what happens to it
+		err := s.externalTransferRepo.UpdateRazorpayPayoutID(transfer.ID, payout.ID, payoutInFlightStatuses)
+		if err == nil {
+			transfer.Status = models.ExternalTransferStatusProcessing
+		} else if !errors.Is(err, repositories.ErrTransferStatusChanged) {
+			return err
+		}
 	}
 
-	// Update corresponding transaction
+	switch payoutEventOutcome(event, transfer.IsCompleted()) {
+	case payoutReversed:
+		return s.handlePayoutReversal(transfer.ID, payout)
+	case payoutSucceeded:
+		return s.handlePayoutSuccess(transfer.ID, payout)
+	case payoutFailed:
+		if payout.FailureReason == "" {
+			payout.FailureReason = fmt.Sprintf("Payout %s", payout.Status)
+		}
+		return s.handlePayoutFailure(transfer.ID, payout)
+	case payoutInFlight:
+		utils.LogInfo("Payout in progress", map[string]interface{}{
+			"transfer_id": transfer.ID.String(),
+			"payout_id":   payout.ID,
+			"status":      payout.Status,
+		})
+	default:
+		if !transfer.IsCompleted() {
+			utils.LogInfo("Unhandled payout event", map[string]interface{}{
+				"event":     event,
+				"payout_id": payout.ID,
+			})
+		}
+	}
+
+	return nil
+}
+
+// Statuses a transfer can be settled from once Razorpay reports the payout
+// processed. Failed and cancelled are included: a payout can go through after
+// it was given up on locally, and the money has left either way.
+var payoutSettleableStatuses = []string{
+	models.ExternalTransferStatusQueued,
+	models.ExternalTransferStatusPending,
+	models.ExternalTransferStatusProcessing,
+	models.ExternalTransferStatusFailed,
+	models.ExternalTransferStatusCancelled,
+}
+
+// Statuses of a transfer whose payout is still in flight
+var payoutInFlightStatuses = []string{
+	models.ExternalTransferStatusQueued,
+	models.ExternalTransferStatusPending,
+	models.ExternalTransferStatusProcessing,
+}
+
+// handlePayoutSuccess handles successful payout
+func (s *ExternalTransferService) handlePayoutSuccess(transferID uuid.UUID, payout *razorpay.Payout) error {
 	transfer, err := s.externalTransferRepo.GetByID(transferID)
 	if err != nil {
-		utils.LogError(err, map[string]interface{}{"transfer_id": transferID.String(), "action": "get_transfer_for_success"})
-		return
+		return fmt.Errorf("failed to get transfer: %w", err)
+	}
+	if transfer.Status == models.ExternalTransferStatusSuccess || transfer.Status == models.ExternalTransferStatusRefunded {
+		return nil
 	}
 
-	if transfer.TransactionID != nil {
-		s.transactionRepo.UpdateStatus(*transfer.TransactionID, utils.TransactionStatusSuccess, "")
+	// Turn the hold into a wallet debit, move the payout out of clearing and
+	// mark the transfer successful together
+	err = s.captureTransferFunds(transfer)
+	if errors.Is(err, repositories.ErrTransferStatusChanged) {
+		// Settled by another notice of the same payout
+		return nil
+	}
+	if err != nil {
+		// The money has left; ops need to look at a wallet that cannot cover it
+		utils.LogError(err, map[string]interface{}{"transfer_id": transferID.String(), "action": "capture_transfer_funds"})
+		if flagErr := s.externalTransferRepo.FlagForReview(transferID); flagErr != nil {
+			utils.LogError(flagErr, map[string]interface{}{"transfer_id": transferID.String(), "action": "flag_transfer_for_review"})
+		}
+		return fmt.Errorf("failed to settle transfer: %w", err)
 	}
 
 	// Send success notification
@@ -543,32 +1422,27 @@ func (s *ExternalTransferService) handlePayoutSuccess(transferID uuid.UUID, payo
 	// 	transfer.UserID.String(), transfer.Amount, transfer.RecipientValue, payout.UTR)
 
 	utils.LogInfo("External transfer completed successfully", map[string]interface{}{
-		"transfer_id": transferID.String(),
-		"payout_id":   payout.ID,
-		"utr":         payout.UTR,
-		"amount":      transfer.Amount.String(),
+		"transfer_id":     transferID.String(),
+		"payout_id":       payout.ID,
+		"utr":             payout.UTR,
+		"amount":          transfer.Amount.String(),
+		"previous_status": transfer.Status,
 	})
+
+	s.notifyTransferComplete(transferID)
+	return nil
 }
 
 // handlePayoutFailure handles failed payout
-func (s *ExternalTransferService) handlePayoutFailure(transferID uuid.UUID, payout *razorpay.Payout) {
-	if err := s.externalTransferRepo.UpdateStatus(transferID, models.ExternalTransferStatusFailed, payout.FailureReason); err != nil {
-		utils.LogError(err, map[string]interface{}{"transfer_id": transferID.String(), "action": "update_failure_status"})
-		return
+func (s *ExternalTransferService) handlePayoutFailure(transferID uuid.UUID, payout *razorpay.Payout) error {
+	// Fail the transfer and release the reserved funds together
+	transfer, err := s.giveUpTransfer(transferID, payoutInFlightStatuses, models.ExternalTransferStatusFailed, payout.FailureReason)
+	if errors.Is(err, repositories.ErrTransferStatusChanged) {
+		// Already settled one way or the other
+		return nil
 	}
-
-	// Refund wallet balance
-	transfer, err := s.externalTransferRepo.GetByID(transferID)
 	if err != nil {
-		utils.LogError(err, map[string]interface{}{"transfer_id": transferID.String(), "action": "get_transfer_for_refund"})
-		return
-	}
-
-	s.refundWalletBalance(transfer)
-
-	// Update corresponding transaction
-	if transfer.TransactionID != nil {
-		s.transactionRepo.UpdateStatus(*transfer.TransactionID, utils.TransactionStatusFailed, payout.FailureReason)
+		return fmt.Errorf("failed to fail transfer: %w", err)
 	}
 
 	// Send failure notification
@@ -581,47 +1455,187 @@ func (s *ExternalTransferService) handlePayoutFailure(transferID uuid.UUID, payo
 		"failure_reason": payout.FailureReason,
 		"amount":         transfer.Amount.String(),
 	})
+
+	s.notifyTransferComplete(transferID)
+	return nil
 }
 
 // handlePayoutReversal handles reversed payout
-func (s *ExternalTransferService) handlePayoutReversal(transferID uuid.UUID, payout *razorpay.Payout) {
-	if err := s.externalTransferRepo.UpdateStatus(transferID, models.ExternalTransferStatusRefunded, "Payout reversed by bank"); err != nil {
-		utils.LogError(err, map[string]interface{}{"transfer_id": transferID.String(), "action": "update_reversal_status"})
-		return
+func (s *ExternalTransferService) handlePayoutReversal(transferID uuid.UUID, payout *razorpay.Payout) error {
+	// Release the hold, or refund the wallet if the payout had already been
+	// captured, together with marking the transfer refunded
+	from := append([]string{models.ExternalTransferStatusSuccess}, payoutInFlightStatuses...)
+	_, err := s.giveUpTransfer(transferID, from, models.ExternalTransferStatusRefunded, "Payout reversed by bank")
+	if errors.Is(err, repositories.ErrTransferStatusChanged) {
+		// Already refunded, or failed or cancelled with the funds handed back
+		return nil
 	}
-
-	// Refund wallet balance
-	transfer, err := s.externalTransferRepo.GetByID(transferID)
 	if err != nil {
-		utils.LogError(err, map[string]interface{}{"transfer_id": transferID.String(), "action": "get_transfer_for_reversal"})
-		return
+		return fmt.Errorf("failed to refund transfer: %w", err)
 	}
 
-	s.refundWalletBalance(transfer)
+	s.notifyTransferComplete(transferID)
 
 	// Send reversal notification
 	// go s.notificationService.SendExternalTransferReversalNotification(
 	// 	transfer.UserID.String(), transfer.Amount, transfer.RecipientValue)
+	return nil
 }
 
-// refundWalletBalance refunds the wallet balance for failed/reversed transfers
-func (s *ExternalTransferService) refundWalletBalance(transfer *models.ExternalTransfer) {
-	// Get current wallet
-	wallet, err := s.walletRepo.GetByID(transfer.WalletID)
+// captureTransferFunds captures the transfer's hold, debits the wallet through
+// the ledger, settles the payout and marks the transfer successful in a single
+// database transaction. A hold released when the transfer was given up on is
+// taken back, as the money has left after all.
+func (s *ExternalTransferService) captureTransferFunds(transfer *models.ExternalTransfer) error {
+	hold, err := s.holdService.GetHold(transfer.ReferenceID)
+	if errors.Is(err, repositories.ErrHoldNotFound) {
+		// Transfers created before holds were debited up front and only need settling
+		err := s.ledgerService.RecordPayoutSettlement(nil, transfer.Amount, transfer.ReferenceID)
+		if err != nil && !errors.Is(err, ErrDuplicateJournalEntry) {
+			return err
+		}
+		if err := s.externalTransferRepo.TransitionStatus(transfer.ID, payoutInFlightStatuses, models.ExternalTransferStatusSuccess, ""); err != nil {
+			return err
+		}
+		if transfer.TransactionID != nil {
+			s.transactionRepo.UpdateStatus(*transfer.TransactionID, utils.TransactionStatusSuccess, "")
+		}
+		return nil
+	}
 	if err != nil {
-		utils.LogError(err, map[string]interface{}{"wallet_id": transfer.WalletID.String(), "action": "get_wallet_for_refund"})
-		return
+		return err
+	}
+	if hold.Status == models.WalletHoldStatusCaptured {
+		// The wallet was already debited; only the status is behind
+		return s.externalTransferRepo.TransitionStatus(transfer.ID, payoutSettleableStatuses, models.ExternalTransferStatusSuccess, "")
 	}
 
-	// Refund the amount
-	newBalance := wallet.Balance.Add(transfer.TotalAmount)
-	if err := s.walletRepo.UpdateBalance(nil, wallet.ID, newBalance); err != nil {
-		utils.LogError(err, map[string]interface{}{"wallet_id": transfer.WalletID.String(), "action": "refund_wallet_balance"})
-		return
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.externalTransferRepo.TransitionStatusWithTx(tx, transfer.ID, payoutSettleableStatuses, models.ExternalTransferStatusSuccess, ""); err != nil {
+			return err
+		}
+
+		if hold.Status == models.WalletHoldStatusReleased {
+			_, err = s.holdService.ReclaimHold(tx, transfer.ReferenceID)
+		} else {
+			_, err = s.holdService.CaptureHold(tx, transfer.ReferenceID)
+		}
+		if err != nil {
+			return err
+		}
+
+		wallet, err := s.ledgerService.RecordExternalTransfer(tx, transfer.WalletID, transfer.Amount, transfer.TransferFee, transfer.FeeGST, transfer.ReferenceID, transfer.TransactionID)
+		if err != nil {
+			return err
+		}
+
+		if err := s.ledgerService.RecordPayoutSettlement(tx, transfer.Amount, transfer.ReferenceID); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.ExternalTransfer{}).
+			Where("id = ?", transfer.ID).
+			Updates(map[string]interface{}{
+				"balance_after":  wallet.Balance,
+				"failure_reason": "",
+			}).Error; err != nil {
+			return err
+		}
+
+		if transfer.TransactionID != nil {
+			return tx.Model(&models.Transaction{}).
+				Where("id = ?", *transfer.TransactionID).
+				Updates(map[string]interface{}{
+					"status":        utils.TransactionStatusSuccess,
+					"balance_after": wallet.Balance,
+					"updated_at":    time.Now(),
+				}).Error
+		}
+		return nil
+	})
+}
+
+// giveUpTransfer moves a transfer whose payout did not go through to status
+// and hands its funds back in one database transaction, so a transfer is never
+// left failed or refunded with its funds still taken. ErrTransferStatusChanged
+// means the transfer was no longer in one of the from statuses.
+func (s *ExternalTransferService) giveUpTransfer(transferID uuid.UUID, from []string, status, reason string) (*models.ExternalTransfer, error) {
+	var transfer models.ExternalTransfer
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.externalTransferRepo.TransitionStatusWithTx(tx, transferID, from, status, reason); err != nil {
+			return err
+		}
+		if err := tx.Where("id = ?", transferID).First(&transfer).Error; err != nil {
+			return fmt.Errorf("failed to get transfer: %w", err)
+		}
+		return s.releaseTransferFunds(tx, &transfer, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// releaseTransferFunds hands back the funds of a transfer that did not go
+// through. An active hold is simply released; a transfer whose funds were
+// already debited is refunded through the ledger.
+func (s *ExternalTransferService) releaseTransferFunds(tx *gorm.DB, transfer *models.ExternalTransfer, reason string) error {
+	hold, err := s.holdService.GetHold(transfer.ReferenceID)
+	if err != nil && !errors.Is(err, repositories.ErrHoldNotFound) {
+		return err
+	}
+	if err == nil && hold.Status == models.WalletHoldStatusCaptured {
+		// The payout completed before it was reversed
+		return s.refundWalletBalance(tx, transfer)
+	}
+
+	if transfer.TransactionID != nil {
+		status := utils.TransactionStatusFailed
+		if transfer.Status == models.ExternalTransferStatusCancelled {
+			status = utils.TransactionStatusCancelled
+		}
+		if err := s.transactionRepo.UpdateStatusWithTx(tx, *transfer.TransactionID, status, reason); err != nil {
+			return err
+		}
+	}
+	if hold == nil {
+		// Transfers created before holds were debited up front
+		return s.refundWalletBalance(tx, transfer)
+	}
+	if hold.Status != models.WalletHoldStatusActive {
+		// Released or expired already, nothing was debited
+		return nil
+	}
+
+	if err := s.holdService.ReleaseHold(tx, transfer.ReferenceID, reason); err != nil {
+		return err
+	}
+
+	utils.LogInfo("Wallet hold released", map[string]interface{}{
+		"transfer_id": transfer.ID.String(),
+		"wallet_id":   transfer.WalletID.String(),
+		"amount":      hold.Amount.String(),
+	})
+	return nil
+}
+
+// refundWalletBalance refunds the wallet balance for reversed transfers whose funds were already captured
+func (s *ExternalTransferService) refundWalletBalance(tx *gorm.DB, transfer *models.ExternalTransfer) error {
+	refundTransactionID := uuid.New()
+
+	// Return the amount and fee through the ledger
+	wallet, err := s.ledgerService.RecordPayoutReversal(tx, transfer.WalletID, transfer.Amount, transfer.TransferFee, transfer.FeeGST, transfer.ReferenceID, &refundTransactionID)
+	if errors.Is(err, ErrDuplicateJournalEntry) {
+		// Already refunded by an earlier failure or reversal notice
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to refund wallet: %w", err)
 	}
 
 	// Create refund transaction
 	refundTransaction := &models.Transaction{
+		ID:           refundTransactionID,
 		WalletID:     wallet.ID,
 		UserID:       transfer.UserID,
 		Type:         utils.TransactionTypeRefund,
@@ -630,80 +1644,183 @@ func (s *ExternalTransferService) refundWalletBalance(transfer *models.ExternalT
 		Description:  fmt.Sprintf("Refund for failed transfer %s", transfer.ReferenceID),
 		Status:       models.StatusSuccess,
 		ReferenceID:  "REFUND_" + transfer.ReferenceID,
-		BalanceAfter: newBalance,
+		BalanceAfter: wallet.Balance,
+	}
+	if _, err := s.transactionRepo.CreateWithTx(tx, refundTransaction); err != nil {
+		return fmt.Errorf("failed to record refund: %w", err)
 	}
-
-	s.transactionRepo.Create(refundTransaction)
 
 	utils.LogInfo("Wallet balance refunded", map[string]interface{}{
 		"transfer_id":   transfer.ID.String(),
-		"wallet_id":     wallet.ID.String(),
+		"wallet_id":     transfer.WalletID.String(),
 		"refund_amount": transfer.TotalAmount.String(),
-		"new_balance":   newBalance.String(),
+		"new_balance":   wallet.Balance.String(),
 	})
+	return nil
 }
 
 // Helper functions
 
-func (s *ExternalTransferService) validateRecipient(recipientType, recipientValue string) error {
+// applyBeneficiary looks up a saved beneficiary by ID or nickname and checks
+// it can be paid. Beneficiaries still in their cooling-off period can only be
+// paid from the app, up to BeneficiaryCoolingOffMaxAmount per transfer.
+func (s *ExternalTransferService) applyBeneficiary(userID uuid.UUID, ref string, amount decimal.Decimal, initiatedBy string) (*models.Beneficiary, error) {
+	beneficiary, err := findBeneficiary(s.beneficiaryRepo, userID, ref)
+	if err != nil {
+		return nil, err
+	}
+	if !beneficiary.IsVerified() {
+		return nil, ErrBeneficiaryNotVerified
+	}
+
+	if beneficiary.InCoolingOff(time.Now()) {
+		until := beneficiary.CoolingOffUntil.In(RiskLocation).Format("02 Jan 15:04")
+		if !initiatedFromApp(initiatedBy) {
+			return nil, fmt.Errorf("beneficiary %q was added recently and can only be paid from the app until %s", beneficiary.Nickname, until)
+		}
+		if amount.GreaterThan(decimal.NewFromInt(BeneficiaryCoolingOffMaxAmount)) {
+			return nil, fmt.Errorf("beneficiary %q was added recently; transfers to it are limited to ₹%d until %s", beneficiary.Nickname, BeneficiaryCoolingOffMaxAmount, until)
+		}
+	}
+
+	return beneficiary, nil
+}
+
+// riskConfirmation returns the risk assessment the user confirmed for a
+// transfer. Bots cannot confirm a risky transfer, only the user can from the app.
+func riskConfirmation(req *dto.CreateExternalTransferRequest, initiatedBy string) string {
+	if initiatedBy != models.InitiatedByUser {
+		return ""
+	}
+	return req.ConfirmAssessmentID
+}
+
+// initiatedFromApp reports whether the user started a transfer from the app,
+// either on its own or as a row of a bulk payout they confirmed
+func initiatedFromApp(initiatedBy string) bool {
+	return initiatedBy == models.InitiatedByUser || initiatedBy == models.InitiatedByBatch
+}
+
+// cancellableUntil returns when a queued transfer leaves the cancel window
+func cancellableUntil(transfer *models.ExternalTransfer) *time.Time {
+	if transfer.Status != models.ExternalTransferStatusQueued || transfer.SubmitAfter == nil {
+		return nil
+	}
+	return transfer.SubmitAfter
+}
+
+func beneficiaryIDString(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+func (s *ExternalTransferService) validateRecipient(recipientType, recipientValue, ifscCode string) error {
 	switch recipientType {
 	case models.RecipientTypeUPI:
 		return s.validateUPIID(recipientValue)
 	case models.RecipientTypePhone:
 		return s.validatePhoneNumber(recipientValue)
+	case models.RecipientTypeIFSC:
+		return s.validateBankAccount(recipientValue, ifscCode)
 	default:
 		return errors.New("invalid recipient type")
 	}
 }
 
 func (s *ExternalTransferService) validateUPIID(upiID string) error {
-	// UPI ID format: username@provider
-	upiRegex := regexp.MustCompile(`^[a-zA-Z0-9._-]+@[a-zA-Z0-9.-]+$`)
-	if !upiRegex.MatchString(upiID) {
+	if !upiIDRegex.MatchString(upiID) {
 		return errors.New("invalid UPI ID format")
 	}
 	return nil
 }
 
 func (s *ExternalTransferService) validatePhoneNumber(phone string) error {
-	// Indian phone number format: 10 digits starting with 6-9
-	phoneRegex := regexp.MustCompile(`^[6-9]\d{9}$`)
-	if !phoneRegex.MatchString(phone) {
-		return errors.New("invalid phone number format")
+	if !phoneNumberRegex.MatchString(phone) {
+		return ErrInvalidPhoneNumber
+	}
+	return nil
+}
+
+// validateBankAccount checks a bank account number and that its IFSC code is
+// a known branch
+func (s *ExternalTransferService) validateBankAccount(accountNumber, ifscCode string) error {
+	if err := ifsc.ValidateAccountNumber(accountNumber); err != nil {
+		return err
+	}
+	if ifscCode == "" {
+		return errors.New("IFSC code is required for bank transfers")
+	}
+	if _, err := s.ifscDirectory.Lookup(ifscCode); err != nil {
+		return err
 	}
 	return nil
 }
 
-func (s *ExternalTransferService) calculateTransferFee(recipientType string, amount decimal.Decimal) decimal.Decimal {
+// transferMode returns the payout mode for a recipient, along with the
+// branch of a bank account. UPI IDs and phone numbers are paid by UPI.
+func (s *ExternalTransferService) transferMode(recipientType, ifscCode string, amount decimal.Decimal) (string, *ifsc.Branch, error) {
 	switch recipientType {
-	case models.RecipientTypeUPI:
-		return decimal.NewFromFloat(UPITransferFee)
-	case models.RecipientTypePhone:
-		return decimal.NewFromFloat(PhoneTransferFee)
+	case models.RecipientTypeUPI, models.RecipientTypePhone:
+		return razorpay.PayoutModeUPI, nil, nil
+	case models.RecipientTypeIFSC:
+		branch, err := s.ifscDirectory.Lookup(ifscCode)
+		if err != nil {
+			return "", nil, err
+		}
+		mode, err := selectBankTransferMode(branch, amount)
+		if err != nil {
+			return "", nil, err
+		}
+		return mode, branch, nil
 	default:
-		return decimal.NewFromFloat(UPITransferFee)
+		return "", nil, errors.New("invalid recipient type")
 	}
 }
 
-func (s *ExternalTransferService) getEstimatedTransferTime(recipientType string) string {
-	// In test mode, don't show estimated time for pending transfers as they complete instantly
-	cfg := config.LoadConfig()
-	if cfg.Environment == "test" || cfg.Environment == "development" {
-		return ""
+// selectBankTransferMode picks RTGS from its minimum amount up, IMPS below
+// that for its instant settlement, and NEFT when the branch takes neither
+func selectBankTransferMode(branch *ifsc.Branch, amount decimal.Decimal) (string, error) {
+	switch {
+	case amount.GreaterThanOrEqual(decimal.NewFromInt(RTGSMinAmount)) && branch.RTGS:
+		return razorpay.PayoutModeRTGS, nil
+	case amount.LessThanOrEqual(decimal.NewFromInt(IMPSMaxAmount)) && branch.IMPS:
+		return razorpay.PayoutModeIMPS, nil
+	case branch.NEFT:
+		return razorpay.PayoutModeNEFT, nil
+	default:
+		return "", fmt.Errorf("%s does not accept bank transfers of this amount", branch.Display())
 	}
+}
+
+// GetTransferFees describes the fees the user's transfers are charged
+func (s *ExternalTransferService) GetTransferFees(userID uuid.UUID) (*dto.TransferFeesResponse, error) {
+	return s.feeService.GetTransferFees(userID)
+}
 
+func (s *ExternalTransferService) getEstimatedTransferTime(recipientType, mode string) string {
 	switch recipientType {
 	case models.RecipientTypeUPI:
 		return "Instant (within 2 minutes)"
 	case models.RecipientTypePhone:
 		return "2-5 minutes"
+	case models.RecipientTypeIFSC:
+		switch mode {
+		case razorpay.PayoutModeNEFT:
+			return "Within 2 hours (NEFT settles in half-hourly batches)"
+		case razorpay.PayoutModeRTGS:
+			return "Within 30 minutes"
+		default:
+			return "Instant (within 5 minutes)"
+		}
 	default:
 		return "2-5 minutes"
 	}
 }
 
 // getEstimatedTransferTimeForStatus returns estimated time based on status
-func (s *ExternalTransferService) getEstimatedTransferTimeForStatus(recipientType, status string) string {
+func (s *ExternalTransferService) getEstimatedTransferTimeForStatus(recipientType, mode, status string) string {
 	// If transfer is completed, failed, or cancelled, no estimated time needed
 	if status == models.ExternalTransferStatusSuccess ||
 		status == models.ExternalTransferStatusFailed ||
@@ -711,11 +1828,5 @@ func (s *ExternalTransferService) getEstimatedTransferTimeForStatus(recipientTyp
 		return ""
 	}
 
-	return s.getEstimatedTransferTime(recipientType)
-}
-
-func (s *ExternalTransferService) convertPhoneToUPI(phone string) string {
-	// Simple conversion - in production, you might want to use a service
-	// to determine the actual UPI ID associated with the phone number
-	return phone + "@paytm"
+	return s.getEstimatedTransferTime(recipientType, mode)
 }
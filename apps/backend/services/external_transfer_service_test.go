@@ -0,0 +1,530 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/pkg/ifsc"
+	"github.com/zeusnotfound04/Tranza/pkg/razorpay"
+	"github.com/zeusnotfound04/Tranza/pkg/razorpay/fake"
+	"github.com/zeusnotfound04/Tranza/utils"
+	"gorm.io/gorm"
+)
+
+func TestProcessWithRazorpayRequiresRecipientName(t *testing.T) {
+	server := fake.NewServer(fake.Config{}).Start()
+	defer server.Close()
+	s := &ExternalTransferService{razorpayClient: server.Client()}
+
+	for _, recipientType := range []string{models.RecipientTypeUPI, models.RecipientTypePhone, models.RecipientTypeIFSC} {
+		transfer := &models.ExternalTransfer{
+			ID:             uuid.New(),
+			Amount:         decimal.NewFromInt(500),
+			RecipientType:  recipientType,
+			RecipientValue: "alice@upi",
+			RecipientName:  "  ",
+			ResolvedVPA:    "alice@upi",
+			ReferenceID:    "TXN" + recipientType,
+		}
+		if err := s.processWithRazorpay(transfer); !errors.Is(err, ErrRecipientNameRequired) {
+			t.Fatalf("%s transfer without a name: err = %v, want ErrRecipientNameRequired", recipientType, err)
+		}
+	}
+
+	// No payout reached Razorpay
+	if payouts, err := server.Client().ListPayouts(razorpay.ListOptions{}); err != nil || len(payouts.Items) != 0 {
+		t.Fatalf("ListPayouts = %+v, %v; want no payouts", payouts, err)
+	}
+}
+
+func TestPayoutPollDelay(t *testing.T) {
+	tests := []struct {
+		age  time.Duration
+		want time.Duration
+	}{
+		{0, PayoutPollInterval},
+		{2 * time.Minute, PayoutPollInterval},
+		{10 * PayoutPollInterval, PayoutPollInterval},
+		{time.Hour, 6 * time.Minute},
+		{3 * time.Hour, 18 * time.Minute},
+		{10 * PayoutPollMaxInterval, PayoutPollMaxInterval},
+		{48 * time.Hour, PayoutPollMaxInterval},
+	}
+	for _, tt := range tests {
+		if got := payoutPollDelay(tt.age); got != tt.want {
+			t.Errorf("payoutPollDelay(%s) = %s, want %s", tt.age, got, tt.want)
+		}
+	}
+
+	// Checks never get more frequent as a payout ages
+	last := time.Duration(0)
+	for age := time.Duration(0); age < 24*time.Hour; age += 7 * time.Minute {
+		delay := payoutPollDelay(age)
+		if delay < last {
+			t.Fatalf("payoutPollDelay(%s) = %s, shorter than %s for a younger payout", age, delay, last)
+		}
+		last = delay
+	}
+}
+
+func TestSelectBankTransferMode(t *testing.T) {
+	all := &ifsc.Branch{IFSC: "HDFC0000001", Bank: "HDFC Bank", IMPS: true, NEFT: true, RTGS: true}
+	noRTGS := &ifsc.Branch{IFSC: "HDFC0000002", Bank: "HDFC Bank", IMPS: true, NEFT: true}
+	neftOnly := &ifsc.Branch{IFSC: "HDFC0000003", Bank: "HDFC Bank", NEFT: true}
+	rtgsOnly := &ifsc.Branch{IFSC: "HDFC0000004", Bank: "HDFC Bank", RTGS: true}
+
+	tests := []struct {
+		branch *ifsc.Branch
+		amount string
+		want   string // Empty for no mode
+	}{
+		{all, "1000", razorpay.PayoutModeIMPS},
+		{all, "199999.99", razorpay.PayoutModeIMPS},
+		{all, "200000", razorpay.PayoutModeRTGS},
+		{all, "1000000", razorpay.PayoutModeRTGS},
+		{noRTGS, "200000", razorpay.PayoutModeIMPS},
+		{noRTGS, "500000", razorpay.PayoutModeIMPS},
+		{noRTGS, "500000.01", razorpay.PayoutModeNEFT}, // Past what IMPS carries
+		{neftOnly, "1000", razorpay.PayoutModeNEFT},
+		{neftOnly, "1000000", razorpay.PayoutModeNEFT},
+		{rtgsOnly, "250000", razorpay.PayoutModeRTGS},
+		{rtgsOnly, "1000", ""}, // Below the RTGS minimum
+	}
+	for _, tt := range tests {
+		got, err := selectBankTransferMode(tt.branch, d(tt.amount))
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("selectBankTransferMode(%s, ₹%s) = %s, want an error", tt.branch.IFSC, tt.amount, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("selectBankTransferMode(%s, ₹%s) = %s, %v; want %s", tt.branch.IFSC, tt.amount, got, err, tt.want)
+		}
+	}
+
+	// RTGS amounts fit under the most a bank transfer may carry, but not under
+	// the UPI maximum
+	if rtgs := decimal.NewFromInt(RTGSMinAmount); rtgs.GreaterThan(maxTransferAmount(models.RecipientTypeIFSC)) ||
+		!rtgs.GreaterThan(maxTransferAmount(models.RecipientTypeUPI)) {
+		t.Errorf("maxTransferAmount = ₹%s by bank and ₹%s by UPI; RTGS starts at ₹%d",
+			maxTransferAmount(models.RecipientTypeIFSC), maxTransferAmount(models.RecipientTypeUPI), RTGSMinAmount)
+	}
+}
+
+func TestPayoutStatusOutcome(t *testing.T) {
+	tests := map[string]payoutOutcome{
+		razorpay.PayoutStatusQueued:     payoutInFlight,
+		razorpay.PayoutStatusPending:    payoutInFlight,
+		razorpay.PayoutStatusProcessing: payoutInFlight,
+		razorpay.PayoutStatusProcessed:  payoutSucceeded,
+		razorpay.PayoutStatusFailed:     payoutFailed,
+		razorpay.PayoutStatusCancelled:  payoutFailed,
+		razorpay.PayoutStatusReversed:   payoutReversed,
+		"something_new":                 payoutInFlight,
+	}
+	for status, want := range tests {
+		if got := payoutStatusOutcome(status); got != want {
+			t.Errorf("payoutStatusOutcome(%q) = %d, want %d", status, got, want)
+		}
+	}
+}
+
+func TestPayoutEventOutcome(t *testing.T) {
+	tests := []struct {
+		event     string
+		completed bool
+		want      payoutOutcome
+	}{
+		{"payout.queued", false, payoutInFlight},
+		{"payout.pending", false, payoutInFlight},
+		{"payout.initiated", false, payoutInFlight},
+		{"payout.processed", false, payoutSucceeded},
+		{"payout.failed", false, payoutFailed},
+		{"payout.rejected", false, payoutFailed},
+		{"payout.reversed", false, payoutReversed},
+		{"payout.updated", false, payoutIgnored},
+
+		// A transfer given up on locally still settles if the payout went
+		// through, and a reversal refunds even a successful one
+		{"payout.processed", true, payoutSucceeded},
+		{"payout.reversed", true, payoutReversed},
+		{"payout.failed", true, payoutIgnored},
+		{"payout.initiated", true, payoutIgnored},
+	}
+	for _, tt := range tests {
+		if got := payoutEventOutcome(tt.event, tt.completed); got != tt.want {
+			t.Errorf("payoutEventOutcome(%q, completed=%t) = %d, want %d", tt.event, tt.completed, got, tt.want)
+		}
+	}
+}
+
+// webhookRecorder keeps the webhooks a fake Razorpay server delivers
+type webhookRecorder struct {
+	mu     sync.Mutex
+	bodies [][]byte
+	sigs   []string
+}
+
+func (r *webhookRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	r.bodies = append(r.bodies, body)
+	r.sigs = append(r.sigs, req.Header.Get("X-Razorpay-Signature"))
+	r.mu.Unlock()
+}
+
+// events verifies and parses the webhooks received so far
+func (r *webhookRecorder) events(t *testing.T, client *razorpay.Client, secret string) []*razorpay.WebhookEvent {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := make([]*razorpay.WebhookEvent, 0, len(r.bodies))
+	for i, body := range r.bodies {
+		if !client.VerifyWebhookSignature(body, r.sigs[i], secret) {
+			t.Fatalf("webhook signature does not verify: %s", body)
+		}
+		event, err := client.ParseWebhookEvent(body)
+		if err != nil {
+			t.Fatalf("ParseWebhookEvent: %v", err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func startFakeRazorpay(t *testing.T, cfg fake.Config) (*fake.Server, *razorpay.Client, *webhookRecorder) {
+	t.Helper()
+
+	recorder := &webhookRecorder{}
+	hooks := httptest.NewServer(recorder)
+	t.Cleanup(hooks.Close)
+
+	cfg.WebhookURL = hooks.URL
+	cfg.WebhookSecret = "whsec_test"
+	server := fake.NewServer(cfg).Start()
+	t.Cleanup(server.Close)
+	return server, server.Client(), recorder
+}
+
+// The outcomes a transfer goes through as the fake server's webhooks for its
+// payout are applied in order, the transfer completing on the first final one
+func webhookOutcomes(t *testing.T, events []*razorpay.WebhookEvent, payoutID string) []payoutOutcome {
+	t.Helper()
+
+	var outcomes []payoutOutcome
+	completed := false
+	for _, event := range events {
+		payout, err := webhookPayout(event)
+		if err != nil {
+			t.Fatalf("webhookPayout(%s): %v", event.Event, err)
+		}
+		if payout.ID != payoutID {
+			continue
+		}
+		outcome := payoutEventOutcome(event.Event, completed)
+		outcomes = append(outcomes, outcome)
+		if outcome != payoutInFlight && outcome != payoutIgnored {
+			completed = true
+		}
+	}
+	return outcomes
+}
+
+func equalOutcomes(a, b []payoutOutcome) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPayoutLifecycleAgainstFakeRazorpay(t *testing.T) {
+	server, client, recorder := startFakeRazorpay(t, fake.Config{AdvanceOnFetch: true})
+	server.SetPayoutScript("bounce@upi",
+		fake.PayoutStep{Status: razorpay.PayoutStatusQueued},
+		fake.PayoutStep{Status: razorpay.PayoutStatusProcessing},
+		fake.PayoutStep{Status: razorpay.PayoutStatusFailed, FailureReason: "Invalid beneficiary VPA"},
+	)
+
+	// Polling drives each payout until it reaches a final outcome
+	poll := func(payoutID string) (payoutOutcome, *razorpay.Payout) {
+		for i := 0; i < 5; i++ {
+			payout, err := client.GetPayout(payoutID)
+			if err != nil {
+				t.Fatalf("GetPayout: %v", err)
+			}
+			if outcome := payoutStatusOutcome(payout.Status); outcome != payoutInFlight {
+				return outcome, payout
+			}
+		}
+		t.Fatalf("payout %s never left flight", payoutID)
+		return payoutInFlight, nil
+	}
+
+	paid, err := client.CreateUPIPayout("alice@upi", 50000, "INR", razorpay.PurposePayout, "Rent", "Alice", "", "TXNOK")
+	if err != nil {
+		t.Fatalf("CreateUPIPayout: %v", err)
+	}
+	if outcome, payout := poll(paid.ID); outcome != payoutSucceeded || payout.UTR == "" {
+		t.Fatalf("polled outcome = %d (%+v), want success with a UTR", outcome, payout)
+	}
+
+	bounced, err := client.CreateUPIPayout("bounce@upi", 50000, "INR", razorpay.PurposePayout, "Rent", "Bob", "", "TXNFAIL")
+	if err != nil {
+		t.Fatalf("CreateUPIPayout: %v", err)
+	}
+	if outcome, payout := poll(bounced.ID); outcome != payoutFailed || payout.FailureReason != "Invalid beneficiary VPA" {
+		t.Fatalf("polled outcome = %d (%+v), want failure with the bank's reason", outcome, payout)
+	}
+
+	// The bank reverses the successful payout later
+	if _, err := server.SetPayoutStatus(paid.ID, razorpay.PayoutStatusReversed, ""); err != nil {
+		t.Fatalf("SetPayoutStatus: %v", err)
+	}
+
+	events := recorder.events(t, client, "whsec_test")
+	if got, want := webhookOutcomes(t, events, paid.ID), []payoutOutcome{payoutInFlight, payoutSucceeded, payoutReversed}; !equalOutcomes(got, want) {
+		t.Fatalf("webhook outcomes for the paid payout = %v, want %v", got, want)
+	}
+	if got, want := webhookOutcomes(t, events, bounced.ID), []payoutOutcome{payoutInFlight, payoutInFlight, payoutFailed}; !equalOutcomes(got, want) {
+		t.Fatalf("webhook outcomes for the bounced payout = %v, want %v", got, want)
+	}
+}
+
+func TestWebhookPayout(t *testing.T) {
+	nested := &razorpay.WebhookEvent{
+		Event: "payout.processed",
+		Payload: map[string]interface{}{
+			"payout": map[string]interface{}{
+				"entity": map[string]interface{}{"id": "pout_1", "status": "processed", "amount": 1000, "reference_id": "TXN1"},
+			},
+		},
+	}
+	payout, err := webhookPayout(nested)
+	if err != nil {
+		t.Fatalf("webhookPayout: %v", err)
+	}
+	if payout.ID != "pout_1" || payout.Status != "processed" || payout.Amount != 1000 || payout.Reference != "TXN1" {
+		t.Fatalf("payout = %+v", payout)
+	}
+
+	flat := &razorpay.WebhookEvent{Payload: map[string]interface{}{"payout": map[string]interface{}{"id": "pout_2"}}}
+	if payout, err := webhookPayout(flat); err != nil || payout.ID != "pout_2" {
+		t.Fatalf("flat payload: %+v, %v", payout, err)
+	}
+
+	for _, payload := range []map[string]interface{}{
+		{},
+		{"payout": "pout_3"},
+		{"payout": map[string]interface{}{"entity": map[string]interface{}{"status": "processed"}}},
+	} {
+		if _, err := webhookPayout(&razorpay.WebhookEvent{Payload: payload}); err == nil {
+			t.Errorf("webhookPayout(%v) succeeded", payload)
+		}
+	}
+}
+
+// newTestServicesWithRazorpay wires the services to a fake Razorpay server
+// whose payout webhooks are applied as they are sent. The server sends them
+// before answering the request that caused them, so a webhook for a new
+// payout arrives before its creator has saved the payout ID.
+func newTestServicesWithRazorpay(t *testing.T, db *gorm.DB, cfg fake.Config) (*testServices, *fake.Server) {
+	t.Helper()
+
+	var s *testServices
+	hooks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		event, err := s.externalTransfers.razorpayClient.ParseWebhookEvent(body)
+		if err != nil || !strings.HasPrefix(event.Event, "payout.") {
+			return
+		}
+		payout, err := webhookPayout(event)
+		if err == nil {
+			err = s.externalTransfers.HandlePayoutEvent(event.Event, payout)
+		}
+		if err != nil {
+			t.Errorf("%s webhook: %v", event.Event, err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(hooks.Close)
+
+	cfg.WebhookURL = hooks.URL
+	cfg.WebhookSecret = "whsec_test"
+	server := fake.NewServer(cfg).Start()
+	t.Cleanup(server.Close)
+
+	s = newTestServices(db, server.Client())
+	return s, server
+}
+
+// countJobs counts the jobs of a type that are still to run
+func countJobs(t *testing.T, db *gorm.DB, jobType string) int64 {
+	t.Helper()
+
+	var count int64
+	err := db.Model(&models.Job{}).
+		Where("type = ? AND status IN ?", jobType, []string{models.JobStatusQueued, models.JobStatusRunning}).
+		Count(&count).Error
+	if err != nil {
+		t.Fatalf("failed to count jobs: %v", err)
+	}
+	return count
+}
+
+func TestPayoutSettledByWebhookBeforeItsIDIsSaved(t *testing.T) {
+	db := testDB(t)
+	s, server := newTestServicesWithRazorpay(t, db, fake.Config{})
+	server.SetPayoutScript("quick@upi", fake.PayoutStep{Status: razorpay.PayoutStatusProcessed})
+	wallet := createTestWallet(t, db, "5000", "50000")
+
+	var completions []string
+	s.externalTransfers.OnTransferComplete(func(transfer *models.ExternalTransfer) {
+		completions = append(completions, transfer.Status)
+	})
+
+	result, err := s.externalTransfers.CreateExternalTransfer(wallet.UserID.String(), &dto.CreateExternalTransferRequest{
+		Amount:         decimal.NewFromInt(1000),
+		Currency:       "INR",
+		RecipientType:  models.RecipientTypeUPI,
+		RecipientValue: "quick@upi",
+		RecipientName:  "Quick Payee",
+	})
+	if err != nil {
+		t.Fatalf("CreateExternalTransfer: %v", err)
+	}
+
+	// The payout.processed webhook settles the transfer while it is submitted
+	if n := s.runJobs(t, models.JobTypePayoutSubmit); n != 1 {
+		t.Fatalf("ran %d submit jobs, want 1", n)
+	}
+
+	transfer, err := s.transferRepo.GetByReferenceID(result.ReferenceID)
+	if err != nil {
+		t.Fatalf("GetByReferenceID: %v", err)
+	}
+	if transfer.Status != models.ExternalTransferStatusSuccess || transfer.RazorpayPayoutID == "" {
+		t.Fatalf("transfer is %s with payout %q, want success with its payout saved", transfer.Status, transfer.RazorpayPayoutID)
+	}
+	if n := countJobs(t, db, models.JobTypePayoutPoll); n != 0 {
+		t.Fatalf("%d payout polls queued for a settled transfer, want none", n)
+	}
+	if len(completions) != 1 || completions[0] != models.ExternalTransferStatusSuccess {
+		t.Fatalf("completion handlers saw %v, want one success", completions)
+	}
+	requireAmount(t, "balance", walletBalance(t, db, wallet.ID), decimal.NewFromInt(5000).Sub(transfer.TotalAmount).String())
+}
+
+func TestPayoutReversalRefundIsRetried(t *testing.T) {
+	db := testDB(t)
+	s, server := newTestServicesWithRazorpay(t, db, fake.Config{})
+	server.SetPayoutScript("quick@upi", fake.PayoutStep{Status: razorpay.PayoutStatusProcessed})
+	wallet := createTestWallet(t, db, "5000", "50000")
+
+	result, err := s.externalTransfers.CreateExternalTransfer(wallet.UserID.String(), &dto.CreateExternalTransferRequest{
+		Amount:         decimal.NewFromInt(1000),
+		Currency:       "INR",
+		RecipientType:  models.RecipientTypeUPI,
+		RecipientValue: "quick@upi",
+		RecipientName:  "Quick Payee",
+	})
+	if err != nil {
+		t.Fatalf("CreateExternalTransfer: %v", err)
+	}
+	s.runJobs(t, models.JobTypePayoutSubmit)
+
+	transfer, err := s.transferRepo.GetByReferenceID(result.ReferenceID)
+	if err != nil || transfer.Status != models.ExternalTransferStatusSuccess {
+		t.Fatalf("transfer = %+v, %v; want success", transfer, err)
+	}
+	paid, ok := server.Payout(transfer.RazorpayPayoutID)
+	if !ok {
+		t.Fatalf("payout %s not found", transfer.RazorpayPayoutID)
+	}
+	reversed := *paid
+	reversed.Status = razorpay.PayoutStatusReversed
+
+	// A record already holding the refund's reference makes the refund fail
+	blocker := &models.Transaction{
+		ID:          uuid.New(),
+		WalletID:    wallet.ID,
+		UserID:      wallet.UserID,
+		Type:        utils.TransactionTypeRefund,
+		Amount:      decimal.NewFromInt(1),
+		Status:      utils.TransactionStatusSuccess,
+		ReferenceID: "REFUND_" + transfer.ReferenceID,
+	}
+	if err := db.Create(blocker).Error; err != nil {
+		t.Fatalf("failed to create blocking transaction: %v", err)
+	}
+
+	if err := s.externalTransfers.HandlePayoutEvent("payout.reversed", &reversed); err == nil {
+		t.Fatal("reversal with a failing refund succeeded; it must fail so it is redelivered")
+	}
+	if transfer, _ := s.transferRepo.GetByReferenceID(result.ReferenceID); transfer.Status != models.ExternalTransferStatusSuccess {
+		t.Fatalf("transfer is %s after a failed refund, want it left successful", transfer.Status)
+	}
+	requireAmount(t, "balance after the failed refund", walletBalance(t, db, wallet.ID), decimal.NewFromInt(5000).Sub(transfer.TotalAmount).String())
+
+	// The redelivered event refunds the wallet, once
+	if err := db.Delete(blocker).Error; err != nil {
+		t.Fatalf("failed to delete blocking transaction: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.externalTransfers.HandlePayoutEvent("payout.reversed", &reversed); err != nil {
+			t.Fatalf("redelivered reversal: %v", err)
+		}
+	}
+	if transfer, _ := s.transferRepo.GetByReferenceID(result.ReferenceID); transfer.Status != models.ExternalTransferStatusRefunded {
+		t.Fatalf("transfer is %s, want refunded", transfer.Status)
+	}
+	requireAmount(t, "balance after the refund", walletBalance(t, db, wallet.ID), "5000")
+}
+
+func TestPhoneTransferToTranzaUserFromFundingHold(t *testing.T) {
+	db := testDB(t)
+	s := newTestServices(db, nil)
+	sender := createTestWallet(t, db, "1000", "50000")
+	recipient := createTestWallet(t, db, "0", "50000")
+	if err := db.Model(&models.User{}).Where("id = ?", recipient.UserID).Update("phone", "9876543210").Error; err != nil {
+		t.Fatalf("failed to set phone: %v", err)
+	}
+	if _, err := s.holds.PlaceHold(nil, sender.ID, decimal.NewFromInt(1000), models.WalletHoldPurposePayoutBatch, "payout_batch:1", "batch", PayoutBatchHoldTTL); err != nil {
+		t.Fatalf("PlaceHold: %v", err)
+	}
+
+	result, err := s.externalTransfers.CreateExternalTransfer(sender.UserID.String(), &dto.CreateExternalTransferRequest{
+		Amount:         decimal.NewFromInt(300),
+		Currency:       "INR",
+		RecipientType:  models.RecipientTypePhone,
+		RecipientValue: "9876543210",
+		ResolvedVia:    RecipientSourceTranza,
+		InitiatedBy:    models.InitiatedByBatch,
+		FundingHold:    "payout_batch:1",
+	})
+	if err != nil {
+		t.Fatalf("CreateExternalTransfer: %v", err)
+	}
+	if result.WalletTransactionID == "" {
+		t.Fatalf("transfer %+v was not paid into the recipient's wallet", result)
+	}
+	requireHold(t, s, "payout_batch:1", models.WalletHoldStatusActive, "700")
+	requireAmount(t, "sender balance", walletBalance(t, db, sender.ID), "700")
+	requireAmount(t, "recipient balance", walletBalance(t, db, recipient.ID), "300")
+}
@@ -0,0 +1,496 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/pkg/period"
+	"github.com/zeusnotfound04/Tranza/pkg/razorpay"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"gorm.io/gorm"
+)
+
+var ErrNoFeeSlab = errors.New("no transfer fee is configured for this recipient and amount")
+
+// BuiltinFeePlanCode is the plan used until a default fee plan is set up
+const BuiltinFeePlanCode = "builtin"
+
+// builtinFeePlan charges the flat fees transfers had before fee plans: ₹2 to
+// a UPI ID, ₹5 to a phone number and ₹5, ₹3 or ₹25 by IMPS, NEFT or RTGS
+var builtinFeePlan = &models.FeePlan{
+	Code:     BuiltinFeePlanCode,
+	Name:     "Standard",
+	IsActive: true,
+	Slabs: []models.FeeSlab{
+		{RecipientType: models.RecipientTypeUPI, FlatFee: decimal.NewFromInt(2)},
+		{RecipientType: models.RecipientTypePhone, FlatFee: decimal.NewFromInt(5)},
+		{RecipientType: models.RecipientTypeIFSC, Mode: razorpay.PayoutModeIMPS, FlatFee: decimal.NewFromInt(5)},
+		{RecipientType: models.RecipientTypeIFSC, Mode: razorpay.PayoutModeNEFT, FlatFee: decimal.NewFromInt(3)},
+		{RecipientType: models.RecipientTypeIFSC, Mode: razorpay.PayoutModeRTGS, FlatFee: decimal.NewFromInt(25)},
+	},
+}
+
+// FeeQuote is the fee a transfer is charged under a fee plan
+type FeeQuote struct {
+	Plan              *models.FeePlan
+	BaseFee           decimal.Decimal
+	GST               decimal.Decimal
+	Fee               decimal.Decimal // BaseFee + GST
+	Waived            bool
+	FreeTransfersLeft int
+
+	// The fee before a waiver, charged if the free transfer is gone by the
+	// time the transfer is made, and the month whose quota it counts against
+	planBaseFee, planGST decimal.Decimal
+	monthStart, monthEnd time.Time
+}
+
+// PlanID returns the ID stored with a transfer, nil for the built-in plan
+func (q *FeeQuote) PlanID() *uuid.UUID {
+	if q.Plan.ID == uuid.Nil {
+		return nil
+	}
+	id := q.Plan.ID
+	return &id
+}
+
+func (q *FeeQuote) Response() *dto.FeeQuote {
+	response := &dto.FeeQuote{
+		PlanCode:          q.Plan.Code,
+		PlanVersion:       q.Plan.Version,
+		BaseFee:           q.BaseFee,
+		GSTRate:           q.Plan.GSTRate,
+		GST:               q.GST,
+		Fee:               q.Fee,
+		Waived:            q.Waived,
+		FreeTransfersLeft: q.FreeTransfersLeft,
+	}
+	if id := q.PlanID(); id != nil {
+		response.PlanID = id.String()
+	}
+	return response
+}
+
+// FeeService prices external transfers from the fee plan of the user paying:
+// the plan assigned to them, else the default plan, else the built-in one.
+// Free transfers are counted by calendar month in the user's time zone.
+type FeeService struct {
+	db                   *gorm.DB
+	feePlanRepo          *repositories.FeePlanRepository
+	externalTransferRepo *repositories.ExternalTransferRepository
+	walletRepo           *repositories.WalletRepository
+	userRepo             repositories.UserRepository
+}
+
+func NewFeeService(
+	db *gorm.DB,
+	feePlanRepo *repositories.FeePlanRepository,
+	externalTransferRepo *repositories.ExternalTransferRepository,
+	walletRepo *repositories.WalletRepository,
+	userRepo repositories.UserRepository,
+) *FeeService {
+	return &FeeService{
+		db:                   db,
+		feePlanRepo:          feePlanRepo,
+		externalTransferRepo: externalTransferRepo,
+		walletRepo:           walletRepo,
+		userRepo:             userRepo,
+	}
+}
+
+// Quote prices a transfer for the user, using up one of the plan's free
+// transfers this month if any are left. An empty mode matches any slab, for
+// estimates made before the payout mode is known. A transfer made with the
+// quote must claim its free transfer with ClaimFreeTransferWithTx.
+func (s *FeeService) Quote(userID uuid.UUID, recipientType, mode string, amount decimal.Decimal) (*FeeQuote, error) {
+	plan, err := s.PlanForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	quote := &FeeQuote{Plan: plan}
+	quote.planBaseFee, quote.planGST, err = priceTransfer(plan, recipientType, mode, amount)
+	if err != nil {
+		return nil, err
+	}
+	quote.charge()
+
+	// A transfer that costs nothing does not use up a free transfer
+	if quote.Fee.IsPositive() && freeTransfersActive(plan, time.Now()) {
+		quote.monthStart, quote.monthEnd = period.Bounds(period.Month, time.Now().In(s.userLocation(userID)))
+		used, err := s.externalTransferRepo.CountWaivedFeeTransfers(userID, quote.monthStart, quote.monthEnd)
+		if err != nil {
+			return nil, err
+		}
+		quote.waive(plan.FreeTransfersPerMonth - int(used))
+	}
+	return quote, nil
+}
+
+// ClaimFreeTransferWithTx counts the free transfers used again inside the
+// transaction creating the transfer. The caller must hold the lock on the
+// user's wallet, so concurrent transfers cannot both take the last free one.
+// When it is gone the quote goes back to the plan's fee.
+func (s *FeeService) ClaimFreeTransferWithTx(tx *gorm.DB, userID uuid.UUID, quote *FeeQuote) error {
+	if !quote.Waived {
+		return nil
+	}
+
+	used, err := s.externalTransferRepo.CountWaivedFeeTransfersWithTx(tx, userID, quote.monthStart, quote.monthEnd)
+	if err != nil {
+		return err
+	}
+	quote.waive(quote.Plan.FreeTransfersPerMonth - int(used))
+	return nil
+}
+
+// waive makes the quote free if the month has free transfers left, and
+// charges the plan's fee otherwise
+func (q *FeeQuote) waive(left int) {
+	if left <= 0 {
+		q.charge()
+		return
+	}
+	q.BaseFee, q.GST, q.Fee = decimal.Zero, decimal.Zero, decimal.Zero
+	q.Waived = true
+	q.FreeTransfersLeft = left - 1
+}
+
+// charge sets the quote to the plan's fee
+func (q *FeeQuote) charge() {
+	q.BaseFee, q.GST = q.planBaseFee, q.planGST
+	q.Fee = q.BaseFee.Add(q.GST)
+	q.Waived = false
+	q.FreeTransfersLeft = 0
+}
+
+// userLocation returns the time zone the user's months start in, the one set
+// with their spending limits
+func (s *FeeService) userLocation(userID uuid.UUID) *time.Location {
+	var timezones []string
+	err := s.db.Model(&models.AISpendingLimit{}).Where("user_id = ?", userID).Limit(1).Pluck("timezone", &timezones).Error
+	if err != nil || len(timezones) == 0 {
+		return period.Location(period.DefaultTimezone)
+	}
+	return period.Location(timezones[0])
+}
+
+// PlanForUser returns the fee plan the user's transfers are priced with
+func (s *FeeService) PlanForUser(userID uuid.UUID) (*models.FeePlan, error) {
+	plan, err := s.feePlanRepo.GetUserPlan(userID)
+	if err == nil {
+		return plan, nil
+	}
+	if !errors.Is(err, repositories.ErrFeePlanNotFound) {
+		return nil, err
+	}
+
+	plan, err = s.feePlanRepo.GetDefault()
+	if err == nil {
+		return plan, nil
+	}
+	if !errors.Is(err, repositories.ErrFeePlanNotFound) {
+		return nil, err
+	}
+	return builtinFeePlan, nil
+}
+
+// FreeTransfersLeft returns how many free transfers the user has left this month
+func (s *FeeService) FreeTransfersLeft(userID uuid.UUID, plan *models.FeePlan) int {
+	if !freeTransfersActive(plan, time.Now()) {
+		return 0
+	}
+	monthStart, monthEnd := period.Bounds(period.Month, time.Now().In(s.userLocation(userID)))
+	used, err := s.externalTransferRepo.CountWaivedFeeTransfers(userID, monthStart, monthEnd)
+	if err != nil {
+		return 0
+	}
+	return max(plan.FreeTransfersPerMonth-int(used), 0)
+}
+
+// GetTransferFees describes the fee plan the user's transfers are priced with
+func (s *FeeService) GetTransferFees(userID uuid.UUID) (*dto.TransferFeesResponse, error) {
+	plan, err := s.PlanForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sampleFee := func(recipientType, mode string, amount decimal.Decimal) decimal.Decimal {
+		fee, gst, err := priceTransfer(plan, recipientType, mode, amount)
+		if err != nil {
+			return decimal.Zero
+		}
+		return fee.Add(gst)
+	}
+	sample := decimal.NewFromInt(1000)
+
+	response := &dto.TransferFeesResponse{
+		UPIFee:                sampleFee(models.RecipientTypeUPI, razorpay.PayoutModeUPI, sample),
+		PhoneFee:              sampleFee(models.RecipientTypePhone, razorpay.PayoutModeUPI, sample),
+		IMPSFee:               sampleFee(models.RecipientTypeIFSC, razorpay.PayoutModeIMPS, sample),
+		NEFTFee:               sampleFee(models.RecipientTypeIFSC, razorpay.PayoutModeNEFT, sample),
+		RTGSFee:               sampleFee(models.RecipientTypeIFSC, razorpay.PayoutModeRTGS, decimal.NewFromInt(RTGSMinAmount)),
+		MinAmount:             decimal.NewFromFloat(MinTransferAmount),
+		MaxAmount:             decimal.NewFromInt(MaxTransferAmount),
+		MaxBankAmount:         decimal.NewFromInt(MaxBankTransferAmount),
+		FeeStructure:          make([]dto.FeeRange, 0, len(plan.Slabs)),
+		PlanCode:              plan.Code,
+		PlanName:              plan.Name,
+		PlanVersion:           plan.Version,
+		GSTRate:               plan.GSTRate,
+		FreeTransfersPerMonth: plan.FreeTransfersPerMonth,
+		FreeTransfersLeft:     s.FreeTransfersLeft(userID, plan),
+	}
+	// Transfers count against the wallet's own daily and monthly limits
+	if wallet, err := s.walletRepo.GetByUserID(userID); err == nil {
+		response.DailyLimit = wallet.DailyLimit
+		response.MonthlyLimit = wallet.MonthlyLimit
+	}
+	for _, slab := range plan.Slabs {
+		feeType := "fixed"
+		if slab.Percent.IsPositive() {
+			feeType = "percentage"
+			if slab.FlatFee.IsPositive() {
+				feeType = "mixed"
+			}
+		}
+		response.FeeStructure = append(response.FeeStructure, dto.FeeRange{
+			RecipientType: slab.RecipientType,
+			Mode:          slab.Mode,
+			MinAmount:     slab.MinAmount,
+			MaxAmount:     slab.MaxAmount,
+			Fee:           slab.FlatFee,
+			Percent:       slab.Percent,
+			MinFee:        slab.MinFee,
+			MaxFee:        slab.MaxFee,
+			FeeType:       feeType,
+		})
+	}
+	return response, nil
+}
+
+// priceTransfer returns the fee and GST of a transfer from the first slab of
+// the plan that matches it
+func priceTransfer(plan *models.FeePlan, recipientType, mode string, amount decimal.Decimal) (decimal.Decimal, decimal.Decimal, error) {
+	for _, slab := range plan.Slabs {
+		if slab.RecipientType != "" && slab.RecipientType != recipientType {
+			continue
+		}
+		if slab.Mode != "" && mode != "" && slab.Mode != mode {
+			continue
+		}
+		if amount.LessThan(slab.MinAmount) || (slab.MaxAmount.IsPositive() && amount.GreaterThan(slab.MaxAmount)) {
+			continue
+		}
+
+		fee := slab.FlatFee.Add(amount.Mul(slab.Percent).Div(decimal.NewFromInt(100)))
+		if fee.LessThan(slab.MinFee) {
+			fee = slab.MinFee
+		}
+		if slab.MaxFee.IsPositive() && fee.GreaterThan(slab.MaxFee) {
+			fee = slab.MaxFee
+		}
+		fee = fee.Round(2)
+		gst := fee.Mul(plan.GSTRate).Div(decimal.NewFromInt(100)).Round(2)
+		return fee, gst, nil
+	}
+	return decimal.Zero, decimal.Zero, ErrNoFeeSlab
+}
+
+// freeTransfersActive reports whether the plan's free-transfer promotion runs at now
+func freeTransfersActive(plan *models.FeePlan, now time.Time) bool {
+	return plan.FreeTransfersPerMonth > 0 && (plan.FreeTransfersUntil == nil || now.Before(*plan.FreeTransfersUntil))
+}
+
+// CreatePlan adds a fee plan at version 1
+func (s *FeeService) CreatePlan(req *dto.CreateFeePlanRequest) (*models.FeePlan, error) {
+	code := strings.ToLower(strings.TrimSpace(req.Code))
+	if code == "" || code == BuiltinFeePlanCode {
+		return nil, errors.New("invalid fee plan code")
+	}
+	if _, err := s.feePlanRepo.GetByCode(code); err == nil {
+		return nil, errors.New("a fee plan with code " + code + " already exists")
+	}
+
+	plan := &models.FeePlan{
+		Code:                  code,
+		Version:               1,
+		IsDefault:             req.IsDefault,
+		IsActive:              true,
+		FreeTransfersPerMonth: req.FreeTransfersPerMonth,
+		FreeTransfersUntil:    req.FreeTransfersUntil,
+	}
+	if err := s.apply(plan, req.Name, req.Description, req.Slabs, req.GSTRate); err != nil {
+		return nil, err
+	}
+
+	if err := s.feePlanRepo.Save(plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// UpdatePlan changes the fields set in the request as a new version of the
+// plan. Earlier versions are kept, and transfers already made keep the fee and
+// plan version they were quoted with.
+func (s *FeeService) UpdatePlan(planID string, req *dto.UpdateFeePlanRequest) (*models.FeePlan, error) {
+	id, err := uuid.Parse(planID)
+	if err != nil {
+		return nil, errors.New("invalid fee plan ID")
+	}
+
+	plan, err := s.feePlanRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	name, description, gstRate := plan.Name, plan.Description, plan.GSTRate
+	if req.Name != nil {
+		name = *req.Name
+	}
+	if req.Description != nil {
+		description = *req.Description
+	}
+	if req.GSTRate != nil {
+		gstRate = *req.GSTRate
+	}
+	slabs := feeSlabRequests(plan.Slabs)
+	if req.Slabs != nil {
+		slabs = req.Slabs
+	}
+	if err := s.apply(plan, name, description, slabs, gstRate); err != nil {
+		return nil, err
+	}
+	if req.FreeTransfersPerMonth != nil {
+		plan.FreeTransfersPerMonth = *req.FreeTransfersPerMonth
+	}
+	if req.FreeTransfersUntil != nil {
+		plan.FreeTransfersUntil = req.FreeTransfersUntil
+	}
+	if req.IsDefault != nil {
+		plan.IsDefault = *req.IsDefault
+	}
+	if req.IsActive != nil {
+		plan.IsActive = *req.IsActive
+	}
+	plan.Version++
+
+	if err := s.feePlanRepo.Save(plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// GetPlans lists the fee plans
+func (s *FeeService) GetPlans() ([]*models.FeePlan, error) {
+	return s.feePlanRepo.GetAll()
+}
+
+// GetPlanVersions lists every version of a fee plan, newest first
+func (s *FeeService) GetPlanVersions(planID string) ([]*models.FeePlanVersion, error) {
+	id, err := uuid.Parse(planID)
+	if err != nil {
+		return nil, errors.New("invalid fee plan ID")
+	}
+	if _, err := s.feePlanRepo.GetByID(id); err != nil {
+		return nil, err
+	}
+	return s.feePlanRepo.GetVersions(id)
+}
+
+// AssignPlan puts a user on a fee plan, or back on the default one when no
+// plan is given
+func (s *FeeService) AssignPlan(userID string, req *dto.AssignFeePlanRequest) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return errors.New("invalid user ID")
+	}
+	if _, err := s.userRepo.FindByID(context.Background(), uid); err != nil {
+		return errors.New("user not found")
+	}
+
+	if req.FeePlanID == "" {
+		return s.feePlanRepo.Unassign(uid)
+	}
+	planID, err := uuid.Parse(req.FeePlanID)
+	if err != nil {
+		return errors.New("invalid fee plan ID")
+	}
+	plan, err := s.feePlanRepo.GetByID(planID)
+	if err != nil {
+		return err
+	}
+	if !plan.IsActive {
+		return errors.New("fee plan is not active")
+	}
+	return s.feePlanRepo.Assign(uid, plan.ID)
+}
+
+func (s *FeeService) apply(plan *models.FeePlan, name, description string, slabs []dto.FeeSlab, gstRate decimal.Decimal) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("fee plan name is required")
+	}
+	if len(slabs) == 0 {
+		return errors.New("a fee plan needs at least one slab")
+	}
+	if gstRate.IsNegative() || gstRate.GreaterThan(decimal.NewFromInt(100)) {
+		return errors.New("GST rate must be between 0 and 100")
+	}
+
+	plan.Slabs = make([]models.FeeSlab, 0, len(slabs))
+	for i, slab := range slabs {
+		if slab.MinAmount.IsNegative() || slab.MaxAmount.IsNegative() || slab.FlatFee.IsNegative() ||
+			slab.Percent.IsNegative() || slab.MinFee.IsNegative() || slab.MaxFee.IsNegative() {
+			return fmt.Errorf("slab %d: amounts and fees cannot be negative", i+1)
+		}
+		if slab.MaxAmount.IsPositive() && slab.MaxAmount.LessThan(slab.MinAmount) {
+			return fmt.Errorf("slab %d: max_amount is below min_amount", i+1)
+		}
+		if slab.Percent.GreaterThan(decimal.NewFromInt(100)) {
+			return fmt.Errorf("slab %d: percent must be at most 100", i+1)
+		}
+		if slab.MaxFee.IsPositive() && slab.MaxFee.LessThan(slab.MinFee) {
+			return fmt.Errorf("slab %d: max_fee is below min_fee", i+1)
+		}
+		plan.Slabs = append(plan.Slabs, models.FeeSlab{
+			RecipientType: slab.RecipientType,
+			Mode:          slab.Mode,
+			MinAmount:     slab.MinAmount,
+			MaxAmount:     slab.MaxAmount,
+			FlatFee:       slab.FlatFee,
+			Percent:       slab.Percent,
+			MinFee:        slab.MinFee,
+			MaxFee:        slab.MaxFee,
+		})
+	}
+
+	plan.Name = name
+	plan.Description = strings.TrimSpace(description)
+	plan.GSTRate = gstRate
+	return nil
+}
+
+func feeSlabRequests(slabs []models.FeeSlab) []dto.FeeSlab {
+	requests := make([]dto.FeeSlab, 0, len(slabs))
+	for _, slab := range slabs {
+		requests = append(requests, dto.FeeSlab{
+			RecipientType: slab.RecipientType,
+			Mode:          slab.Mode,
+			MinAmount:     slab.MinAmount,
+			MaxAmount:     slab.MaxAmount,
+			FlatFee:       slab.FlatFee,
+			Percent:       slab.Percent,
+			MinFee:        slab.MinFee,
+			MaxFee:        slab.MaxFee,
+		})
+	}
+	return requests
+}
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/pkg/period"
+	"github.com/zeusnotfound04/Tranza/pkg/razorpay"
+)
+
+func d(value string) decimal.Decimal {
+	return decimal.RequireFromString(value)
+}
+
+// slabPlan has free UPI transfers up to ₹1,000, 0.5% above that between ₹5 and
+// ₹20, IMPS at ₹5 + 0.1%, NEFT at a flat ₹3.33 and nothing for phone numbers
+func slabPlan() *models.FeePlan {
+	return &models.FeePlan{
+		ID:      uuid.New(),
+		Code:    "pro",
+		Version: 3,
+		GSTRate: d("18"),
+		Slabs: []models.FeeSlab{
+			{RecipientType: models.RecipientTypeUPI, MaxAmount: d("1000")},
+			{RecipientType: models.RecipientTypeUPI, MinAmount: d("1000.01"), Percent: d("0.5"), MinFee: d("5"), MaxFee: d("20")},
+			{RecipientType: models.RecipientTypeIFSC, Mode: razorpay.PayoutModeIMPS, FlatFee: d("5"), Percent: d("0.1")},
+			{RecipientType: models.RecipientTypeIFSC, Mode: razorpay.PayoutModeNEFT, FlatFee: d("3.33")},
+		},
+	}
+}
+
+func TestPriceTransfer(t *testing.T) {
+	tests := []struct {
+		recipientType, mode, amount string
+		fee, gst                    string
+	}{
+		{models.RecipientTypeUPI, razorpay.PayoutModeUPI, "500", "0", "0"},
+		{models.RecipientTypeUPI, razorpay.PayoutModeUPI, "1000", "0", "0"},
+		{models.RecipientTypeUPI, razorpay.PayoutModeUPI, "1000.01", "5", "0.9"},     // Raised to the minimum fee
+		{models.RecipientTypeUPI, razorpay.PayoutModeUPI, "1500", "7.5", "1.35"},     // 0.5%
+		{models.RecipientTypeUPI, razorpay.PayoutModeUPI, "10000", "20", "3.6"},      // Capped at the maximum fee
+		{models.RecipientTypeUPI, razorpay.PayoutModeUPI, "1234.56", "6.17", "1.11"}, // Both rounded to paise
+		{models.RecipientTypeIFSC, razorpay.PayoutModeIMPS, "10000", "15", "2.7"},
+		{models.RecipientTypeIFSC, razorpay.PayoutModeNEFT, "10000", "3.33", "0.6"},
+		{models.RecipientTypeIFSC, "", "10000", "15", "2.7"}, // Estimates take the first slab for the recipient
+	}
+	for _, tt := range tests {
+		fee, gst, err := priceTransfer(slabPlan(), tt.recipientType, tt.mode, d(tt.amount))
+		if err != nil {
+			t.Fatalf("priceTransfer(%s %s ₹%s): %v", tt.recipientType, tt.mode, tt.amount, err)
+		}
+		if !fee.Equal(d(tt.fee)) || !gst.Equal(d(tt.gst)) {
+			t.Errorf("priceTransfer(%s %s ₹%s) = %s + %s GST, want %s + %s GST", tt.recipientType, tt.mode, tt.amount, fee, gst, tt.fee, tt.gst)
+		}
+	}
+
+	for _, tt := range []struct{ recipientType, mode string }{
+		{models.RecipientTypePhone, razorpay.PayoutModeUPI},
+		{models.RecipientTypeIFSC, razorpay.PayoutModeRTGS},
+	} {
+		if _, _, err := priceTransfer(slabPlan(), tt.recipientType, tt.mode, d("5000")); !errors.Is(err, ErrNoFeeSlab) {
+			t.Errorf("priceTransfer(%s %s) = %v, want ErrNoFeeSlab", tt.recipientType, tt.mode, err)
+		}
+	}
+}
+
+func TestBuiltinFeePlanKeepsFlatFees(t *testing.T) {
+	tests := []struct {
+		recipientType, mode string
+		fee                 string
+	}{
+		{models.RecipientTypeUPI, razorpay.PayoutModeUPI, "2"},
+		{models.RecipientTypePhone, razorpay.PayoutModeUPI, "5"},
+		{models.RecipientTypeIFSC, razorpay.PayoutModeIMPS, "5"},
+		{models.RecipientTypeIFSC, razorpay.PayoutModeNEFT, "3"},
+		{models.RecipientTypeIFSC, razorpay.PayoutModeRTGS, "25"},
+	}
+	for _, tt := range tests {
+		for _, amount := range []string{"1", "250000"} {
+			fee, gst, err := priceTransfer(builtinFeePlan, tt.recipientType, tt.mode, d(amount))
+			if err != nil || !fee.Equal(d(tt.fee)) || !gst.IsZero() {
+				t.Errorf("built-in fee for %s %s ₹%s = %s + %s GST, %v; want %s", tt.recipientType, tt.mode, amount, fee, gst, err, tt.fee)
+			}
+		}
+	}
+}
+
+func TestFreeTransfersActive(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, period.IST)
+	later, earlier := now.Add(time.Hour), now.Add(-time.Hour)
+
+	tests := []struct {
+		name string
+		plan models.FeePlan
+		want bool
+	}{
+		{"no free transfers", models.FeePlan{}, false},
+		{"no free transfers with an end date", models.FeePlan{FreeTransfersUntil: &later}, false},
+		{"free transfers without an end", models.FeePlan{FreeTransfersPerMonth: 3}, true},
+		{"promotion still running", models.FeePlan{FreeTransfersPerMonth: 3, FreeTransfersUntil: &later}, true},
+		{"promotion ending now", models.FeePlan{FreeTransfersPerMonth: 3, FreeTransfersUntil: &now}, false},
+		{"promotion over", models.FeePlan{FreeTransfersPerMonth: 3, FreeTransfersUntil: &earlier}, false},
+	}
+	for _, tt := range tests {
+		if got := freeTransfersActive(&tt.plan, now); got != tt.want {
+			t.Errorf("%s: freeTransfersActive = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFeeQuoteFreeTransferQuota(t *testing.T) {
+	plan := slabPlan()
+	plan.FreeTransfersPerMonth = 2
+
+	// Quote: the plan's fee, then waived while the month has free transfers left
+	quote := &FeeQuote{Plan: plan}
+	var err error
+	quote.planBaseFee, quote.planGST, err = priceTransfer(plan, models.RecipientTypeUPI, razorpay.PayoutModeUPI, d("1500"))
+	if err != nil {
+		t.Fatalf("priceTransfer: %v", err)
+	}
+	quote.charge()
+	if !quote.Fee.Equal(d("8.85")) || quote.Waived {
+		t.Fatalf("charged quote = %s (waived %t), want ₹8.85", quote.Fee, quote.Waived)
+	}
+
+	quote.waive(plan.FreeTransfersPerMonth - 0)
+	if !quote.Waived || !quote.Fee.IsZero() || !quote.BaseFee.IsZero() || !quote.GST.IsZero() || quote.FreeTransfersLeft != 1 {
+		t.Fatalf("first transfer of the month = %+v, want it free with one left", quote)
+	}
+	quote.waive(plan.FreeTransfersPerMonth - 1)
+	if !quote.Waived || quote.FreeTransfersLeft != 0 {
+		t.Fatalf("second transfer of the month = %+v, want it free with none left", quote)
+	}
+
+	// Claiming finds the last free transfer taken by a concurrent one, so the
+	// quote goes back to the plan's fee
+	for _, used := range []int{2, 3} {
+		quote.waive(plan.FreeTransfersPerMonth - used)
+		if quote.Waived || !quote.Fee.Equal(d("8.85")) || !quote.BaseFee.Equal(d("7.5")) || !quote.GST.Equal(d("1.35")) || quote.FreeTransfersLeft != 0 {
+			t.Fatalf("transfer with %d of %d free transfers used = %+v, want the plan's fee", used, plan.FreeTransfersPerMonth, quote)
+		}
+	}
+}
+
+func TestFreeTransferQuotaFollowsTheUsersMonth(t *testing.T) {
+	// 8pm UTC on 31 March is already April in India, so the transfer counts
+	// against April's free transfers there and March's in London
+	at := time.Date(2026, 3, 31, 20, 0, 0, 0, time.UTC)
+
+	start, end := period.Bounds(period.Month, at.In(period.Location(period.DefaultTimezone)))
+	if !start.Equal(time.Date(2026, 4, 1, 0, 0, 0, 0, period.IST)) || !end.Equal(time.Date(2026, 5, 1, 0, 0, 0, 0, period.IST)) {
+		t.Fatalf("month in India = %s to %s, want April", start, end)
+	}
+
+	start, _ = period.Bounds(period.Month, at.In(period.Location("Europe/London")))
+	if start.Month() != time.March {
+		t.Fatalf("month in London starts %s, want March", start)
+	}
+}
+
+func TestFeeQuoteResponse(t *testing.T) {
+	plan := slabPlan()
+	quote := &FeeQuote{Plan: plan, planBaseFee: d("7.5"), planGST: d("1.35")}
+	quote.charge()
+
+	response := quote.Response()
+	want := dto.FeeQuote{
+		PlanID:      plan.ID.String(),
+		PlanCode:    "pro",
+		PlanVersion: 3,
+		BaseFee:     d("7.5"),
+		GSTRate:     d("18"),
+		GST:         d("1.35"),
+		Fee:         d("8.85"),
+	}
+	if response.PlanID != want.PlanID || response.PlanCode != want.PlanCode || response.PlanVersion != want.PlanVersion ||
+		!response.BaseFee.Equal(want.BaseFee) || !response.GSTRate.Equal(want.GSTRate) || !response.GST.Equal(want.GST) ||
+		!response.Fee.Equal(want.Fee) || response.Waived {
+		t.Fatalf("Response = %+v, want %+v", response, want)
+	}
+
+	// Transfers priced with the built-in plan store no plan
+	builtin := &FeeQuote{Plan: builtinFeePlan}
+	if builtin.PlanID() != nil || builtin.Response().PlanID != "" {
+		t.Fatalf("built-in quote has plan ID %v", builtin.PlanID())
+	}
+}
+
+func TestApplyFeePlan(t *testing.T) {
+	s := &FeeService{}
+
+	// A new version made from the plan's own slabs prices transfers the same
+	original := slabPlan()
+	updated := &models.FeePlan{}
+	if err := s.apply(updated, "  Pro  ", " For busy senders ", feeSlabRequests(original.Slabs), original.GSTRate); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if updated.Name != "Pro" || updated.Description != "For busy senders" || len(updated.Slabs) != len(original.Slabs) {
+		t.Fatalf("applied plan = %+v", updated)
+	}
+	for _, amount := range []string{"500", "1500", "10000"} {
+		want, _, _ := priceTransfer(original, models.RecipientTypeUPI, razorpay.PayoutModeUPI, d(amount))
+		got, _, err := priceTransfer(updated, models.RecipientTypeUPI, razorpay.PayoutModeUPI, d(amount))
+		if err != nil || !got.Equal(want) {
+			t.Errorf("fee for ₹%s after apply = %s, %v; want %s", amount, got, err, want)
+		}
+	}
+
+	slab := func(change func(*dto.FeeSlab)) []dto.FeeSlab {
+		s := dto.FeeSlab{RecipientType: models.RecipientTypeUPI, FlatFee: d("2")}
+		change(&s)
+		return []dto.FeeSlab{s}
+	}
+	valid := slab(func(*dto.FeeSlab) {})
+	invalid := []struct {
+		name    string
+		plan    string
+		slabs   []dto.FeeSlab
+		gstRate string
+	}{
+		{"no name", " ", valid, "18"},
+		{"no slabs", "Pro", nil, "18"},
+		{"negative GST", "Pro", valid, "-1"},
+		{"GST over 100%", "Pro", valid, "100.01"},
+		{"negative fee", "Pro", slab(func(s *dto.FeeSlab) { s.FlatFee = d("-2") }), "18"},
+		{"max amount below min", "Pro", slab(func(s *dto.FeeSlab) { s.MinAmount, s.MaxAmount = d("500"), d("100") }), "18"},
+		{"percent over 100", "Pro", slab(func(s *dto.FeeSlab) { s.Percent = d("101") }), "18"},
+		{"max fee below min", "Pro", slab(func(s *dto.FeeSlab) { s.MinFee, s.MaxFee = d("10"), d("5") }), "18"},
+	}
+	for _, tt := range invalid {
+		if err := s.apply(&models.FeePlan{}, tt.plan, "", tt.slabs, d(tt.gstRate)); err == nil {
+			t.Errorf("%s: apply succeeded", tt.name)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"github.com/zeusnotfound04/Tranza/utils"
+	"gorm.io/gorm"
+)
+
+// How long each kind of hold reserves funds before it expires. Holds of
+// transfers whose payout is still in flight do not expire.
+const (
+	ExternalTransferHoldTTL = 24 * time.Hour
+	AIPaymentHoldTTL        = 1 * time.Hour
+	PayoutBatchHoldTTL      = 24 * time.Hour
+)
+
+var ErrInsufficientAvailableBalance = errors.New("insufficient available balance")
+
+// HoldService reserves wallet funds for debits that complete later. A hold
+// reduces the available balance without touching the ledger; the ledger entry
+// is only posted when the hold is captured.
+type HoldService struct {
+	db         *gorm.DB
+	holdRepo   *repositories.WalletHoldRepository
+	walletRepo *repositories.WalletRepository
+}
+
+func NewHoldService(
+	db *gorm.DB,
+	holdRepo *repositories.WalletHoldRepository,
+	walletRepo *repositories.WalletRepository,
+) *HoldService {
+	return &HoldService{
+		db:         db,
+		holdRepo:   holdRepo,
+		walletRepo: walletRepo,
+	}
+}
+
+// PlaceHold reserves amount on the wallet. The wallet row is locked so holds
+// and debits on the same wallet are serialised.
+func (s *HoldService) PlaceHold(tx *gorm.DB, walletID uuid.UUID, amount decimal.Decimal, purpose, referenceID, description string, ttl time.Duration) (*models.WalletHold, error) {
+	if !amount.IsPositive() {
+		return nil, errors.New("hold amount must be positive")
+	}
+
+	if tx == nil {
+		var hold *models.WalletHold
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			hold, err = s.PlaceHold(tx, walletID, amount, purpose, referenceID, description, ttl)
+			return err
+		})
+		return hold, err
+	}
+
+	wallet, err := s.walletRepo.GetByIDForUpdate(tx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if wallet.Status != "active" {
+		return nil, errors.New("wallet is not active")
+	}
+
+	held, err := s.holdRepo.GetActiveTotal(tx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if wallet.Balance.Sub(held).LessThan(amount) {
+		return nil, ErrInsufficientAvailableBalance
+	}
+
+	hold := &models.WalletHold{
+		WalletID:    wallet.ID,
+		UserID:      wallet.UserID,
+		Amount:      amount,
+		Currency:    wallet.Currency,
+		Status:      models.WalletHoldStatusActive,
+		Purpose:     purpose,
+		ReferenceID: referenceID,
+		Description: description,
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+	if err := s.holdRepo.Create(tx, hold); err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// CaptureHold marks a hold as captured. The caller posts the matching ledger
+// entry in the same transaction. A hold that expired can still be captured;
+// the ledger then checks the available balance again.
+func (s *HoldService) CaptureHold(tx *gorm.DB, referenceID string) (*models.WalletHold, error) {
+	hold, err := s.holdRepo.GetByReferenceID(tx, referenceID)
+	if err != nil {
+		return nil, err
+	}
+
+	from := []string{models.WalletHoldStatusActive, models.WalletHoldStatusExpired}
+	if err := s.holdRepo.Transition(tx, hold.ID, from, models.WalletHoldStatusCaptured, ""); err != nil {
+		return nil, err
+	}
+
+	hold.Status = models.WalletHoldStatusCaptured
+	return hold, nil
+}
+
+// ReclaimHold captures a hold that was released because its debit looked
+// like it had failed, once the debit turns out to have gone through after
+// all. The ledger checks the available balance again.
+func (s *HoldService) ReclaimHold(tx *gorm.DB, referenceID string) (*models.WalletHold, error) {
+	hold, err := s.holdRepo.GetByReferenceID(tx, referenceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.holdRepo.Transition(tx, hold.ID, []string{models.WalletHoldStatusReleased}, models.WalletHoldStatusCaptured, ""); err != nil {
+		return nil, err
+	}
+
+	hold.Status = models.WalletHoldStatusCaptured
+	return hold, nil
+}
+
+// ReleaseHold returns the held funds to the available balance
+func (s *HoldService) ReleaseHold(tx *gorm.DB, referenceID, reason string) error {
+	hold, err := s.holdRepo.GetByReferenceID(tx, referenceID)
+	if err != nil {
+		return err
+	}
+
+	return s.holdRepo.Transition(tx, hold.ID, []string{models.WalletHoldStatusActive}, models.WalletHoldStatusReleased, reason)
+}
+
+// ReduceHold takes amount off an active hold, as the debits it reserved funds
+// for take holds of their own. The hold is released once nothing is left. A
+// hold that is gone or no longer active is left alone.
+func (s *HoldService) ReduceHold(tx *gorm.DB, referenceID string, amount decimal.Decimal, reason string) error {
+	return s.reduce(tx, referenceID, func(hold *models.WalletHold) decimal.Decimal {
+		return hold.Amount.Sub(amount)
+	}, reason)
+}
+
+// ReduceHoldTo lowers an active hold to amount, releasing it at zero. A hold
+// already at or below amount is left alone.
+func (s *HoldService) ReduceHoldTo(tx *gorm.DB, referenceID string, amount decimal.Decimal, reason string) error {
+	return s.reduce(tx, referenceID, func(*models.WalletHold) decimal.Decimal {
+		return amount
+	}, reason)
+}
+
+// reduce locks the hold so the new amount is worked out from the latest one;
+// otherwise reductions made at the same time would overwrite each other
+func (s *HoldService) reduce(tx *gorm.DB, referenceID string, newAmount func(*models.WalletHold) decimal.Decimal, reason string) error {
+	if tx == nil {
+		return s.db.Transaction(func(tx *gorm.DB) error {
+			return s.reduce(tx, referenceID, newAmount, reason)
+		})
+	}
+
+	hold, err := s.holdRepo.GetByReferenceIDForUpdate(tx, referenceID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrHoldNotFound) {
+			return nil
+		}
+		return err
+	}
+	return s.reduceTo(tx, hold, newAmount(hold), reason)
+}
+
+func (s *HoldService) reduceTo(tx *gorm.DB, hold *models.WalletHold, amount decimal.Decimal, reason string) error {
+	if hold.Status != models.WalletHoldStatusActive || amount.GreaterThanOrEqual(hold.Amount) {
+		return nil
+	}
+	if !amount.IsPositive() {
+		return s.holdRepo.Transition(tx, hold.ID, []string{models.WalletHoldStatusActive}, models.WalletHoldStatusReleased, reason)
+	}
+	return s.holdRepo.SetAmount(tx, hold.ID, amount)
+}
+
+// GetHold retrieves the hold placed for a reference
+func (s *HoldService) GetHold(referenceID string) (*models.WalletHold, error) {
+	return s.holdRepo.GetByReferenceID(nil, referenceID)
+}
+
+// GetAvailableBalance returns the spendable balance and the amount on hold
+func (s *HoldService) GetAvailableBalance(wallet *models.Wallet) (decimal.Decimal, decimal.Decimal, error) {
+	held, err := s.holdRepo.GetActiveTotal(nil, wallet.ID)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, err
+	}
+	return wallet.Balance.Sub(held), held, nil
+}
+
+// GetActiveHolds lists the active holds on a user's wallet
+func (s *HoldService) GetActiveHolds(userID string) ([]*models.WalletHold, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	wallet, err := s.walletRepo.GetByUserID(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.holdRepo.GetActiveByWalletID(wallet.ID)
+}
+
+// ExpireHolds releases active holds whose expiry has passed, except those of
+// transfers and merchant payouts still waiting on their payout
+func (s *HoldService) ExpireHolds() (int, error) {
+	holds, err := s.holdRepo.GetExpired(time.Now(), 100)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, hold := range holds {
+		err := s.holdRepo.Transition(nil, hold.ID, []string{models.WalletHoldStatusActive}, models.WalletHoldStatusExpired, "Hold expired")
+		if err != nil {
+			// Captured or released since it was read
+			continue
+		}
+		expired++
+	}
+
+	return expired, nil
+}
+
+// StartExpirySweeper expires stale holds on a fixed interval
+func (s *HoldService) StartExpirySweeper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		expired, err := s.ExpireHolds()
+		if err != nil {
+			utils.LogError(err, map[string]interface{}{"action": "expire_wallet_holds"})
+			continue
+		}
+		if expired > 0 {
+			utils.LogInfo("Expired wallet holds", map[string]interface{}{"count": expired})
+		}
+	}
+}
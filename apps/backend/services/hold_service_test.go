@@ -0,0 +1,228 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"gorm.io/gorm/clause"
+)
+
+// requireHold checks a hold's status and amount
+func requireHold(t *testing.T, s *testServices, referenceID, status, amount string) {
+	t.Helper()
+
+	hold, err := s.holds.GetHold(referenceID)
+	if err != nil {
+		t.Fatalf("GetHold(%s): %v", referenceID, err)
+	}
+	if hold.Status != status || !hold.Amount.Equal(decimal.RequireFromString(amount)) {
+		t.Fatalf("hold %s is %s for %s, want %s for %s", referenceID, hold.Status, hold.Amount.StringFixed(2), status, amount)
+	}
+}
+
+func requireAvailable(t *testing.T, s *testServices, wallet *models.Wallet, want string) {
+	t.Helper()
+
+	wallet.Balance = walletBalance(t, s.db, wallet.ID)
+	available, _, err := s.holds.GetAvailableBalance(wallet)
+	if err != nil {
+		t.Fatalf("GetAvailableBalance: %v", err)
+	}
+	requireAmount(t, "available balance", available, want)
+}
+
+func TestHoldArithmetic(t *testing.T) {
+	db := testDB(t)
+	s := newTestServices(db, nil)
+	wallet := createTestWallet(t, db, "1000", "50000")
+
+	place := func(amount, referenceID string) error {
+		_, err := s.holds.PlaceHold(nil, wallet.ID, decimal.RequireFromString(amount), models.WalletHoldPurposeAIPayment, referenceID, "held", AIPaymentHoldTTL)
+		return err
+	}
+
+	if err := place("400", "A"); err != nil {
+		t.Fatalf("PlaceHold: %v", err)
+	}
+	requireAvailable(t, s, wallet, "600")
+	if err := place("600.01", "B"); !errors.Is(err, ErrInsufficientAvailableBalance) {
+		t.Fatalf("hold over the available balance = %v, want ErrInsufficientAvailableBalance", err)
+	}
+	if err := place("0", "B"); err == nil {
+		t.Fatal("zero hold placed")
+	}
+
+	reduce := func(referenceID, amount string) {
+		t.Helper()
+		if err := s.holds.ReduceHold(nil, referenceID, decimal.RequireFromString(amount), "paid"); err != nil {
+			t.Fatalf("ReduceHold(%s, %s): %v", referenceID, amount, err)
+		}
+	}
+	reduceTo := func(referenceID, amount string) {
+		t.Helper()
+		if err := s.holds.ReduceHoldTo(nil, referenceID, decimal.RequireFromString(amount), "paid"); err != nil {
+			t.Fatalf("ReduceHoldTo(%s, %s): %v", referenceID, amount, err)
+		}
+	}
+
+	reduce("A", "150")
+	requireHold(t, s, "A", models.WalletHoldStatusActive, "250")
+	requireAvailable(t, s, wallet, "750")
+
+	// Holds only ever go down
+	reduceTo("A", "300")
+	requireHold(t, s, "A", models.WalletHoldStatusActive, "250")
+	reduceTo("A", "100")
+	requireHold(t, s, "A", models.WalletHoldStatusActive, "100")
+
+	// Taking off more than is left releases the hold
+	reduce("A", "120")
+	requireHold(t, s, "A", models.WalletHoldStatusReleased, "100")
+	requireAvailable(t, s, wallet, "1000")
+
+	// A released or unknown hold is left alone
+	reduce("A", "50")
+	requireHold(t, s, "A", models.WalletHoldStatusReleased, "100")
+	reduce("missing", "50")
+	reduceTo("missing", "0")
+}
+
+func TestReduceHoldConcurrently(t *testing.T) {
+	db := testDB(t)
+	s := newTestServices(db, nil)
+	wallet := createTestWallet(t, db, "1000", "50000")
+
+	if _, err := s.holds.PlaceHold(nil, wallet.ID, decimal.NewFromInt(1000), models.WalletHoldPurposePayoutBatch, "batch", "held", PayoutBatchHoldTTL); err != nil {
+		t.Fatalf("PlaceHold: %v", err)
+	}
+
+	// Every reduction counts, however many land at once
+	reduceAtOnce := func(times int) {
+		var wg sync.WaitGroup
+		for i := 0; i < times; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := s.holds.ReduceHold(nil, "batch", decimal.NewFromInt(50), "row paid"); err != nil {
+					t.Errorf("ReduceHold: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+
+	reduceAtOnce(10)
+	requireHold(t, s, "batch", models.WalletHoldStatusActive, "500")
+
+	// The reductions past the tenth find the hold released and leave it alone
+	reduceAtOnce(12)
+	requireHold(t, s, "batch", models.WalletHoldStatusReleased, "50")
+	requireAvailable(t, s, wallet, "1000")
+}
+
+func TestHoldTransitions(t *testing.T) {
+	db := testDB(t)
+	s := newTestServices(db, nil)
+	wallet := createTestWallet(t, db, "1000", "50000")
+
+	for _, referenceID := range []string{"captured", "released"} {
+		if _, err := s.holds.PlaceHold(nil, wallet.ID, decimal.NewFromInt(300), models.WalletHoldPurposeAIPayment, referenceID, "held", AIPaymentHoldTTL); err != nil {
+			t.Fatalf("PlaceHold: %v", err)
+		}
+	}
+	requireAvailable(t, s, wallet, "400")
+
+	// A captured hold no longer reserves funds; its ledger entry moves them
+	if _, err := s.holds.CaptureHold(nil, "captured"); err != nil {
+		t.Fatalf("CaptureHold: %v", err)
+	}
+	if err := s.holds.ReleaseHold(nil, "captured", "cancelled"); err == nil {
+		t.Fatal("captured hold released")
+	}
+	requireHold(t, s, "captured", models.WalletHoldStatusCaptured, "300")
+
+	if err := s.holds.ReleaseHold(nil, "released", "payout failed"); err != nil {
+		t.Fatalf("ReleaseHold: %v", err)
+	}
+	if _, err := s.holds.CaptureHold(nil, "released"); err == nil {
+		t.Fatal("released hold captured")
+	}
+	requireAvailable(t, s, wallet, "1000")
+
+	// The payout went through after all
+	if _, err := s.holds.ReclaimHold(nil, "released"); err != nil {
+		t.Fatalf("ReclaimHold: %v", err)
+	}
+	requireHold(t, s, "released", models.WalletHoldStatusCaptured, "300")
+	if _, err := s.holds.ReclaimHold(nil, "released"); err == nil {
+		t.Fatal("hold reclaimed twice")
+	}
+}
+
+func TestExpireHoldsSkipsTransfersInFlight(t *testing.T) {
+	db := testDB(t)
+	s := newTestServices(db, nil)
+	wallet := createTestWallet(t, db, "10000", "50000")
+
+	transferStatuses := map[string]string{
+		"TRF_QUEUED":     models.ExternalTransferStatusQueued,
+		"TRF_PENDING":    models.ExternalTransferStatusPending,
+		"TRF_PROCESSING": models.ExternalTransferStatusProcessing,
+		"TRF_FAILED":     models.ExternalTransferStatusFailed,
+	}
+	for referenceID, status := range transferStatuses {
+		if _, err := s.holds.PlaceHold(nil, wallet.ID, decimal.NewFromInt(100), models.WalletHoldPurposeExternalTransfer, referenceID, "held", ExternalTransferHoldTTL); err != nil {
+			t.Fatalf("PlaceHold: %v", err)
+		}
+		transfer := &models.ExternalTransfer{
+			ID:             uuid.New(),
+			UserID:         wallet.UserID,
+			WalletID:       wallet.ID,
+			Amount:         decimal.NewFromInt(100),
+			Currency:       "INR",
+			RecipientType:  models.RecipientTypeUPI,
+			RecipientValue: "someone@upi",
+			Status:         status,
+			TransferMethod: models.TransferMethodRazorpayPayout,
+			TotalAmount:    decimal.NewFromInt(100),
+			ReferenceID:    referenceID,
+			InitiatedBy:    models.InitiatedByUser,
+		}
+		if err := db.Omit(clause.Associations).Create(transfer).Error; err != nil {
+			t.Fatalf("failed to create transfer: %v", err)
+		}
+	}
+	for _, referenceID := range []string{"AI_EXPIRED", "AI_CURRENT"} {
+		if _, err := s.holds.PlaceHold(nil, wallet.ID, decimal.NewFromInt(100), models.WalletHoldPurposeAIPayment, referenceID, "held", AIPaymentHoldTTL); err != nil {
+			t.Fatalf("PlaceHold: %v", err)
+		}
+	}
+	if err := db.Model(&models.WalletHold{}).Where("reference_id <> ?", "AI_CURRENT").Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatalf("failed to backdate holds: %v", err)
+	}
+
+	expired, err := s.holds.ExpireHolds()
+	if err != nil {
+		t.Fatalf("ExpireHolds: %v", err)
+	}
+	if expired != 2 {
+		t.Fatalf("ExpireHolds expired %d holds, want 2", expired)
+	}
+	for _, referenceID := range []string{"TRF_QUEUED", "TRF_PENDING", "TRF_PROCESSING", "AI_CURRENT"} {
+		requireHold(t, s, referenceID, models.WalletHoldStatusActive, "100")
+	}
+	for _, referenceID := range []string{"TRF_FAILED", "AI_EXPIRED"} {
+		requireHold(t, s, referenceID, models.WalletHoldStatusExpired, "100")
+	}
+
+	// A debit that completes after its hold expired still captures it
+	if _, err := s.holds.CaptureHold(nil, "AI_EXPIRED"); err != nil {
+		t.Fatalf("CaptureHold of an expired hold: %v", err)
+	}
+	requireHold(t, s, "AI_EXPIRED", models.WalletHoldStatusCaptured, "100")
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"github.com/zeusnotfound04/Tranza/utils"
+)
+
+var (
+	ErrIdempotencyKeyMismatch   = errors.New("idempotency key was already used with a different request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still being processed")
+	ErrIdempotencyKeyFailed     = errors.New("a request with this idempotency key failed and may have taken effect")
+)
+
+// IdempotencyLease is how long a request may hold its key in progress. A key
+// left in progress longer belongs to a request that crashed the server or
+// never finished, and the next retry takes it over.
+const IdempotencyLease = 5 * time.Minute
+
+// IdempotencyService stores request fingerprints and responses so retried
+// requests carrying the same Idempotency-Key are not executed twice
+type IdempotencyService struct {
+	idempotencyRepo *repositories.IdempotencyRepository
+	ttl             time.Duration
+}
+
+func NewIdempotencyService(idempotencyRepo *repositories.IdempotencyRepository, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{
+		idempotencyRepo: idempotencyRepo,
+		ttl:             ttl,
+	}
+}
+
+// Begin claims an idempotency key for a request. When the key was already used
+// for the same request and completed, the stored record is returned for replay
+// and claimed is false. A key whose request ended in a server error is not
+// claimed again until it expires, since the request may have gone through. A
+// key left in progress past IdempotencyLease is claimed afresh.
+func (s *IdempotencyService) Begin(scope, key, method, path string, body []byte) (*models.IdempotencyKey, bool, error) {
+	requestHash := s.fingerprint(method, path, body)
+
+	for attempt := 0; attempt < 2; attempt++ {
+		record := &models.IdempotencyKey{
+			Scope:       scope,
+			Key:         key,
+			Method:      method,
+			Path:        path,
+			RequestHash: requestHash,
+			Status:      models.IdempotencyStatusInProgress,
+			ExpiresAt:   time.Now().Add(s.ttl),
+		}
+
+		created, err := s.idempotencyRepo.Create(record)
+		if err != nil {
+			return nil, false, err
+		}
+		if created {
+			return record, true, nil
+		}
+
+		existing, err := s.idempotencyRepo.GetByScopeAndKey(scope, key)
+		if err != nil {
+			// Deleted between the insert and the read; try again
+			continue
+		}
+
+		if existing.ExpiresAt.Before(time.Now()) {
+			// Past its window, the key can be used afresh
+			s.idempotencyRepo.Delete(existing.ID)
+			continue
+		}
+		if existing.RequestHash != requestHash {
+			return nil, false, ErrIdempotencyKeyMismatch
+		}
+		if existing.Status == models.IdempotencyStatusFailed {
+			return nil, false, ErrIdempotencyKeyFailed
+		}
+		if existing.Status != models.IdempotencyStatusCompleted {
+			if existing.CreatedAt.After(time.Now().Add(-IdempotencyLease)) {
+				return nil, false, ErrIdempotencyKeyInProgress
+			}
+			// The request holding the key never finished; take it over
+			taken, err := s.idempotencyRepo.DeleteStale(existing.ID, time.Now().Add(-IdempotencyLease))
+			if err != nil {
+				return nil, false, err
+			}
+			if !taken {
+				return nil, false, ErrIdempotencyKeyInProgress
+			}
+			continue
+		}
+		return existing, false, nil
+	}
+
+	return nil, false, ErrIdempotencyKeyInProgress
+}
+
+// Complete stores the response to replay for a claimed key
+func (s *IdempotencyService) Complete(record *models.IdempotencyKey, responseCode int, responseBody string) error {
+	return s.idempotencyRepo.Complete(record.ID, responseCode, responseBody)
+}
+
+// Fail records that the request of a claimed key ended in a server error.
+// Whatever it committed before failing stays committed, so the key is kept and
+// retries with it are refused rather than run again.
+func (s *IdempotencyService) Fail(record *models.IdempotencyKey, responseCode int, responseBody string) error {
+	return s.idempotencyRepo.Fail(record.ID, responseCode, responseBody)
+}
+
+// StartCleanup deletes expired idempotency keys on a fixed interval
+func (s *IdempotencyService) StartCleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := s.idempotencyRepo.DeleteExpired(time.Now()); err != nil {
+			utils.LogError(err, map[string]interface{}{"action": "delete_expired_idempotency_keys"})
+		}
+	}
+}
+
+// fingerprint hashes the parts of a request that must match on a retry
+func (s *IdempotencyService) fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
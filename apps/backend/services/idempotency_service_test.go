@@ -0,0 +1,40 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/zeusnotfound04/Tranza/repositories"
+)
+
+func TestIdempotencyKeyFailedIsNotRetried(t *testing.T) {
+	db := testDB(t)
+	s := NewIdempotencyService(repositories.NewIdempotencyRepository(db), time.Hour)
+	body := []byte(`{"amount":500}`)
+
+	record, claimed, err := s.Begin("user:1", "key", http.MethodPost, "/transfers", body)
+	if err != nil || !claimed {
+		t.Fatalf("Begin = %t, %v; want the key claimed", claimed, err)
+	}
+	if err := s.Fail(record, http.StatusBadGateway, `{"success":false}`); err != nil {
+		t.Fatalf("Fail: %v", err)
+	}
+
+	// The failed request may have moved money, so a retry is refused
+	if _, _, err := s.Begin("user:1", "key", http.MethodPost, "/transfers", body); !errors.Is(err, ErrIdempotencyKeyFailed) {
+		t.Fatalf("retry after a server error = %v, want ErrIdempotencyKeyFailed", err)
+	}
+	if _, _, err := s.Begin("user:1", "key", http.MethodPost, "/transfers", []byte(`{"amount":600}`)); !errors.Is(err, ErrIdempotencyKeyMismatch) {
+		t.Fatalf("different request with a failed key = %v, want ErrIdempotencyKeyMismatch", err)
+	}
+
+	// Another key, or the same one for another user, is free
+	if _, claimed, err := s.Begin("user:1", "other", http.MethodPost, "/transfers", body); err != nil || !claimed {
+		t.Fatalf("Begin with another key = %t, %v; want the key claimed", claimed, err)
+	}
+	if _, claimed, err := s.Begin("user:2", "key", http.MethodPost, "/transfers", body); err != nil || !claimed {
+		t.Fatalf("Begin for another user = %t, %v; want the key claimed", claimed, err)
+	}
+}
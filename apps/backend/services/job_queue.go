@@ -0,0 +1,193 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"github.com/zeusnotfound04/Tranza/utils"
+	"gorm.io/gorm"
+)
+
+// Job queue timings
+const (
+	JobVisibilityTimeout = 2 * time.Minute // How long a claimed job stays hidden from other workers
+	JobPollInterval      = 2 * time.Second // How often idle workers look for due jobs
+)
+
+// JobHandler runs a claimed job. Returning an error retries the job with
+// backoff until it runs out of attempts.
+type JobHandler func(job *models.Job) error
+
+// DeadJobHandler is called once a job has been moved to the dead-letter state
+type DeadJobHandler func(job *models.Job, err error)
+
+// JobNotReadyError asks the queue to run a job again later without spending
+// an attempt, e.g. while a payout is still being processed by the bank.
+type JobNotReadyError struct {
+	RetryAfter time.Duration
+}
+
+func (e *JobNotReadyError) Error() string {
+	return fmt.Sprintf("job not ready, retry after %s", e.RetryAfter)
+}
+
+type jobRegistration struct {
+	handle      JobHandler
+	onDead      DeadJobHandler
+	maxAttempts int
+}
+
+// JobQueue runs background jobs stored in Postgres
+type JobQueue struct {
+	jobRepo  *repositories.JobRepository
+	handlers map[string]jobRegistration
+	workerID string
+}
+
+func NewJobQueue(jobRepo *repositories.JobRepository) *JobQueue {
+	hostname, _ := os.Hostname()
+	return &JobQueue{
+		jobRepo:  jobRepo,
+		handlers: make(map[string]jobRegistration),
+		workerID: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+}
+
+// Register sets the handler for a job type. Handlers must be registered
+// before Start is called.
+func (q *JobQueue) Register(jobType string, maxAttempts int, handle JobHandler, onDead DeadJobHandler) {
+	q.handlers[jobType] = jobRegistration{
+		handle:      handle,
+		onDead:      onDead,
+		maxAttempts: maxAttempts,
+	}
+}
+
+// Enqueue schedules a job to run at runAt. When tx is given the job is only
+// visible once the surrounding transaction commits. Jobs with the dedupe key
+// of a job that is still queued or running are dropped.
+func (q *JobQueue) Enqueue(tx *gorm.DB, jobType string, payload interface{}, dedupeKey string, runAt time.Time) error {
+	registration, ok := q.handlers[jobType]
+	if !ok {
+		return fmt.Errorf("no handler registered for job type %s", jobType)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode job payload: %w", err)
+	}
+
+	job := &models.Job{
+		Type:        jobType,
+		Payload:     string(data),
+		Status:      models.JobStatusQueued,
+		MaxAttempts: registration.maxAttempts,
+		RunAt:       runAt,
+	}
+	if dedupeKey != "" {
+		job.DedupeKey = &dedupeKey
+	}
+
+	_, err = q.jobRepo.Enqueue(tx, job)
+	return err
+}
+
+// Start launches workers that claim and run due jobs
+func (q *JobQueue) Start(workers int) {
+	types := make([]string, 0, len(q.handlers))
+	for jobType := range q.handlers {
+		types = append(types, jobType)
+	}
+
+	for i := 0; i < workers; i++ {
+		go q.work(fmt.Sprintf("%s-%d", q.workerID, i), types)
+	}
+}
+
+func (q *JobQueue) work(workerID string, types []string) {
+	for {
+		jobs, err := q.jobRepo.Claim(workerID, types, JobVisibilityTimeout, 1)
+		if err != nil {
+			utils.LogError(err, map[string]interface{}{"worker_id": workerID, "action": "claim_jobs"})
+		}
+		if len(jobs) == 0 {
+			time.Sleep(JobPollInterval)
+			continue
+		}
+
+		for _, job := range jobs {
+			q.run(job)
+		}
+	}
+}
+
+// run executes a claimed job and records the outcome
+func (q *JobQueue) run(job *models.Job) {
+	registration := q.handlers[job.Type]
+
+	err := q.safeHandle(registration.handle, job)
+	if err == nil {
+		if err := q.jobRepo.Complete(job); err != nil {
+			utils.LogError(err, map[string]interface{}{"job_id": job.ID.String(), "action": "complete_job"})
+		}
+		return
+	}
+
+	var notReady *JobNotReadyError
+	if errors.As(err, &notReady) {
+		if err := q.jobRepo.Reschedule(job, time.Now().Add(notReady.RetryAfter)); err != nil {
+			utils.LogError(err, map[string]interface{}{"job_id": job.ID.String(), "action": "reschedule_job"})
+		}
+		return
+	}
+
+	if job.Attempts >= job.MaxAttempts {
+		utils.LogError(err, map[string]interface{}{
+			"job_id":   job.ID.String(),
+			"job_type": job.Type,
+			"attempts": job.Attempts,
+			"action":   "dead_letter_job",
+		})
+		if err := q.jobRepo.Kill(job, err.Error()); err != nil {
+			utils.LogError(err, map[string]interface{}{"job_id": job.ID.String(), "action": "kill_job"})
+			return
+		}
+		if registration.onDead != nil {
+			registration.onDead(job, err)
+		}
+		return
+	}
+
+	utils.LogWarning("Job failed, retrying", map[string]interface{}{
+		"job_id":   job.ID.String(),
+		"job_type": job.Type,
+		"attempts": job.Attempts,
+		"error":    err.Error(),
+	})
+	if err := q.jobRepo.Retry(job, time.Now().Add(utils.GetRetryDelay(job.Attempts)), err.Error()); err != nil {
+		utils.LogError(err, map[string]interface{}{"job_id": job.ID.String(), "action": "retry_job"})
+	}
+}
+
+// safeHandle turns a panicking handler into a failed attempt
+func (q *JobQueue) safeHandle(handle JobHandler, job *models.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handle(job)
+}
+
+// DecodeJobPayload unmarshals a job's payload into v
+func DecodeJobPayload(job *models.Job, v interface{}) error {
+	if err := json.Unmarshal([]byte(job.Payload), v); err != nil {
+		return fmt.Errorf("failed to decode job payload: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zeusnotfound04/Tranza/models"
+)
+
+func TestSafeHandleRecoversPanics(t *testing.T) {
+	q := &JobQueue{}
+	job := &models.Job{Type: models.JobTypePayoutSubmit}
+
+	err := q.safeHandle(func(*models.Job) error { panic("nil transfer") }, job)
+	if err == nil || !strings.Contains(err.Error(), "nil transfer") {
+		t.Fatalf("safeHandle of a panicking handler = %v, want the panic as an error", err)
+	}
+
+	want := errors.New("razorpay unreachable")
+	if err := q.safeHandle(func(*models.Job) error { return want }, job); !errors.Is(err, want) {
+		t.Fatalf("safeHandle = %v, want %v", err, want)
+	}
+}
+
+func TestJobNotReadyErrorIsRecognisedWhenWrapped(t *testing.T) {
+	err := error(&JobNotReadyError{RetryAfter: 2 * time.Minute})
+	wrapped := errors.Join(errors.New("payout processing"), err)
+
+	var notReady *JobNotReadyError
+	if !errors.As(wrapped, &notReady) || notReady.RetryAfter != 2*time.Minute {
+		t.Fatalf("errors.As(%v) = %v, want the retry delay back", wrapped, notReady)
+	}
+}
+
+func TestDecodeJobPayload(t *testing.T) {
+	job := &models.Job{Payload: `{"transfer_id":"7d1f0a52-5a8e-4a47-9a55-4f7f5c1d8e10"}`}
+
+	var payload payoutJobPayload
+	if err := DecodeJobPayload(job, &payload); err != nil {
+		t.Fatalf("DecodeJobPayload: %v", err)
+	}
+	if payload.TransferID != "7d1f0a52-5a8e-4a47-9a55-4f7f5c1d8e10" {
+		t.Fatalf("transfer ID = %q", payload.TransferID)
+	}
+
+	if err := DecodeJobPayload(&models.Job{Payload: "not json"}, &payload); err == nil {
+		t.Fatal("DecodeJobPayload of invalid JSON succeeded")
+	}
+}
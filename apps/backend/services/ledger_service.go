@@ -0,0 +1,530 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"github.com/zeusnotfound04/Tranza/utils"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnbalancedJournalEntry = errors.New("journal entry does not balance")
+	ErrDuplicateJournalEntry  = errors.New("journal entry already posted")
+)
+
+// systemLedgerAccounts describes the accounts created on first use
+var systemLedgerAccounts = map[string]struct {
+	Name string
+	Type string
+}{
+	models.LedgerAccountGatewaySettlement: {"Gateway Settlement", models.LedgerAccountTypeAsset},
+	models.LedgerAccountPayoutClearing:    {"Payout Clearing", models.LedgerAccountTypeLiability},
+	models.LedgerAccountMerchantPayable:   {"Merchant Payable", models.LedgerAccountTypeLiability},
+	models.LedgerAccountFeeRevenue:        {"Transfer Fee Revenue", models.LedgerAccountTypeRevenue},
+	models.LedgerAccountGSTPayable:        {"GST Payable", models.LedgerAccountTypeLiability},
+	models.LedgerAccountOpeningBalance:    {"Opening Balances", models.LedgerAccountTypeEquity},
+}
+
+// LedgerLine is one side of a journal entry before it is posted. Exactly one
+// of AccountCode or WalletID must be set. Positive amounts debit the account,
+// negative amounts credit it.
+type LedgerLine struct {
+	AccountCode string
+	WalletID    *uuid.UUID
+	Amount      decimal.Decimal
+}
+
+// LedgerService is the only writer of wallet balances. Every balance change is
+// recorded as a balanced journal entry and applied to the wallet in the same
+// database transaction.
+type LedgerService struct {
+	db         *gorm.DB
+	ledgerRepo *repositories.LedgerRepository
+	walletRepo *repositories.WalletRepository
+	holdRepo   *repositories.WalletHoldRepository
+}
+
+func NewLedgerService(
+	db *gorm.DB,
+	ledgerRepo *repositories.LedgerRepository,
+	walletRepo *repositories.WalletRepository,
+	holdRepo *repositories.WalletHoldRepository,
+) *LedgerService {
+	return &LedgerService{
+		db:         db,
+		ledgerRepo: ledgerRepo,
+		walletRepo: walletRepo,
+		holdRepo:   holdRepo,
+	}
+}
+
+// Post records a balanced journal entry. Wallet lines are applied to the
+// wallet balance; a debit fails if the wallet cannot cover it. When tx is nil
+// the entry is posted in its own database transaction.
+func (s *LedgerService) Post(tx *gorm.DB, referenceID, entryType, description string, transactionID *uuid.UUID, lines []LedgerLine) (*models.JournalEntry, error) {
+	if tx == nil {
+		var entry *models.JournalEntry
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			entry, err = s.post(tx, referenceID, entryType, description, transactionID, lines, true)
+			return err
+		})
+		return entry, err
+	}
+	return s.post(tx, referenceID, entryType, description, transactionID, lines, true)
+}
+
+func (s *LedgerService) post(tx *gorm.DB, referenceID, entryType, description string, transactionID *uuid.UUID, lines []LedgerLine, applyToWallets bool) (*models.JournalEntry, error) {
+	if referenceID == "" {
+		return nil, errors.New("journal entry reference is required")
+	}
+	if len(lines) < 2 {
+		return nil, ErrUnbalancedJournalEntry
+	}
+
+	sum := decimal.Zero
+	for _, line := range lines {
+		if line.Amount.IsZero() {
+			return nil, errors.New("journal entry contains a zero posting")
+		}
+		if (line.AccountCode == "") == (line.WalletID == nil) {
+			return nil, errors.New("ledger line must reference exactly one account")
+		}
+		sum = sum.Add(line.Amount)
+	}
+	if !sum.IsZero() {
+		return nil, ErrUnbalancedJournalEntry
+	}
+
+	if _, err := s.ledgerRepo.GetEntryByReferenceID(tx, referenceID); err == nil {
+		return nil, ErrDuplicateJournalEntry
+	}
+
+	entry := &models.JournalEntry{
+		ReferenceID:   referenceID,
+		EntryType:     entryType,
+		Description:   description,
+		TransactionID: transactionID,
+	}
+
+	for _, line := range lines {
+		var account *models.LedgerAccount
+		var err error
+		if line.WalletID != nil {
+			account, err = s.walletAccount(tx, *line.WalletID)
+		} else {
+			account, err = s.systemAccount(tx, line.AccountCode)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		entry.Postings = append(entry.Postings, models.Posting{
+			AccountID: account.ID,
+			Amount:    line.Amount,
+			Currency:  account.Currency,
+		})
+	}
+
+	if applyToWallets {
+		// Touch wallets in a stable order so concurrent entries between the
+		// same wallets cannot deadlock on row locks
+		walletLines := make([]LedgerLine, 0, len(lines))
+		for _, line := range lines {
+			if line.WalletID != nil {
+				walletLines = append(walletLines, line)
+			}
+		}
+		sort.Slice(walletLines, func(i, j int) bool {
+			return walletLines[i].WalletID.String() < walletLines[j].WalletID.String()
+		})
+
+		for _, line := range walletLines {
+			// Wallet accounts are liabilities: a debit reduces the balance owed to the user
+			var err error
+			if line.Amount.IsPositive() {
+				if err := s.checkAvailableBalance(tx, *line.WalletID, line.Amount); err != nil {
+					return nil, err
+				}
+				_, err = s.walletRepo.DecrementBalance(tx, *line.WalletID, line.Amount)
+			} else {
+				_, err = s.walletRepo.IncrementBalance(tx, *line.WalletID, line.Amount.Neg())
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := s.ledgerRepo.CreateEntry(tx, entry); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// checkAvailableBalance makes sure a wallet debit does not eat into funds
+// reserved by active holds. Holds being captured must be marked captured first.
+func (s *LedgerService) checkAvailableBalance(tx *gorm.DB, walletID uuid.UUID, amount decimal.Decimal) error {
+	wallet, err := s.walletRepo.GetByIDForUpdate(tx, walletID)
+	if err != nil {
+		return err
+	}
+
+	held, err := s.holdRepo.GetActiveTotal(tx, walletID)
+	if err != nil {
+		return err
+	}
+	if wallet.Balance.Sub(held).LessThan(amount) {
+		return ErrInsufficientAvailableBalance
+	}
+	return nil
+}
+
+// RecordWalletLoad credits a wallet with money collected through Razorpay
+func (s *LedgerService) RecordWalletLoad(tx *gorm.DB, walletID uuid.UUID, amount decimal.Decimal, referenceID string, transactionID *uuid.UUID) (*models.Wallet, error) {
+	_, err := s.Post(tx, referenceID, models.JournalEntryTypeWalletLoad, "Wallet load via Razorpay", transactionID, []LedgerLine{
+		{AccountCode: models.LedgerAccountGatewaySettlement, Amount: amount},
+		{WalletID: &walletID, Amount: amount.Neg()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.getWallet(tx, walletID)
+}
+
+// RecordExternalTransfer debits a wallet for an outgoing payout and its fee.
+// gst is the part of the fee collected as GST, which is owed on rather than earned.
+func (s *LedgerService) RecordExternalTransfer(tx *gorm.DB, walletID uuid.UUID, amount, fee, gst decimal.Decimal, referenceID string, transactionID *uuid.UUID) (*models.Wallet, error) {
+	lines := []LedgerLine{
+		{WalletID: &walletID, Amount: amount.Add(fee)},
+		{AccountCode: models.LedgerAccountPayoutClearing, Amount: amount.Neg()},
+	}
+	lines = append(lines, feeLines(fee, gst, true)...)
+
+	if _, err := s.Post(tx, referenceID, models.JournalEntryTypeExternalTransfer, "External transfer", transactionID, lines); err != nil {
+		return nil, err
+	}
+	return s.getWallet(tx, walletID)
+}
+
+// RecordPayoutSettlement moves a completed payout out of clearing
+func (s *LedgerService) RecordPayoutSettlement(tx *gorm.DB, amount decimal.Decimal, referenceID string) error {
+	_, err := s.Post(tx, settlementReference(referenceID), models.JournalEntryTypePayoutSettlement, "Payout settled by Razorpay", nil, []LedgerLine{
+		{AccountCode: models.LedgerAccountPayoutClearing, Amount: amount},
+		{AccountCode: models.LedgerAccountGatewaySettlement, Amount: amount.Neg()},
+	})
+	return err
+}
+
+// RecordPayoutReversal returns a failed or reversed payout and its fee to the
+// wallet, taking the fee back out of revenue and GST payable
+func (s *LedgerService) RecordPayoutReversal(tx *gorm.DB, walletID uuid.UUID, amount, fee, gst decimal.Decimal, referenceID string, transactionID *uuid.UUID) (*models.Wallet, error) {
+	// A payout reversed after settlement has already left clearing, so the
+	// money comes back through the gateway instead
+	source := models.LedgerAccountPayoutClearing
+	if _, err := s.ledgerRepo.GetEntryByReferenceID(tx, settlementReference(referenceID)); err == nil {
+		source = models.LedgerAccountGatewaySettlement
+	}
+
+	lines := []LedgerLine{
+		{AccountCode: source, Amount: amount},
+		{WalletID: &walletID, Amount: amount.Add(fee).Neg()},
+	}
+	lines = append(lines, feeLines(fee, gst, false)...)
+
+	if _, err := s.Post(tx, "REVERSAL_"+referenceID, models.JournalEntryTypePayoutReversal, "External transfer refund", transactionID, lines); err != nil {
+		return nil, err
+	}
+	return s.getWallet(tx, walletID)
+}
+
+// RecordAIPayment debits a wallet for a merchant payment made by an AI agent
+func (s *LedgerService) RecordAIPayment(tx *gorm.DB, walletID uuid.UUID, amount decimal.Decimal, referenceID string, transactionID *uuid.UUID) (*models.Wallet, error) {
+	_, err := s.Post(tx, referenceID, models.JournalEntryTypeAIPayment, "AI agent payment", transactionID, []LedgerLine{
+		{WalletID: &walletID, Amount: amount},
+		{AccountCode: models.LedgerAccountMerchantPayable, Amount: amount.Neg()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.getWallet(tx, walletID)
+}
+
+// RecordLoadRefund debits a wallet for money returned to the card or account
+// that loaded it
+func (s *LedgerService) RecordLoadRefund(tx *gorm.DB, walletID uuid.UUID, amount decimal.Decimal, referenceID string, transactionID *uuid.UUID) (*models.Wallet, error) {
+	_, err := s.Post(tx, referenceID, models.JournalEntryTypeRefund, "Wallet load refund via Razorpay", transactionID, []LedgerLine{
+		{WalletID: &walletID, Amount: amount},
+		{AccountCode: models.LedgerAccountGatewaySettlement, Amount: amount.Neg()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.getWallet(tx, walletID)
+}
+
+// RecordLoadRefundReversal returns a failed load refund to the wallet
+func (s *LedgerService) RecordLoadRefundReversal(tx *gorm.DB, walletID uuid.UUID, amount decimal.Decimal, referenceID string, transactionID *uuid.UUID) (*models.Wallet, error) {
+	_, err := s.Post(tx, "REVERSAL_"+referenceID, models.JournalEntryTypeRefundReversal, "Failed refund returned to wallet", transactionID, []LedgerLine{
+		{AccountCode: models.LedgerAccountGatewaySettlement, Amount: amount},
+		{WalletID: &walletID, Amount: amount.Neg()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.getWallet(tx, walletID)
+}
+
+// RecordAIPaymentRefund credits a wallet with money owed back by a merchant
+func (s *LedgerService) RecordAIPaymentRefund(tx *gorm.DB, walletID uuid.UUID, amount decimal.Decimal, referenceID string, transactionID *uuid.UUID) (*models.Wallet, error) {
+	_, err := s.Post(tx, referenceID, models.JournalEntryTypeRefund, "AI payment refund", transactionID, []LedgerLine{
+		{AccountCode: models.LedgerAccountMerchantPayable, Amount: amount},
+		{WalletID: &walletID, Amount: amount.Neg()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.getWallet(tx, walletID)
+}
+
+// RecordWalletTransfer moves money between two Tranza wallets
+func (s *LedgerService) RecordWalletTransfer(tx *gorm.DB, fromWalletID, toWalletID uuid.UUID, amount decimal.Decimal, referenceID string, transactionID *uuid.UUID) (*models.Wallet, *models.Wallet, error) {
+	_, err := s.Post(tx, referenceID, models.JournalEntryTypeWalletTransfer, "Wallet to wallet transfer", transactionID, []LedgerLine{
+		{WalletID: &fromWalletID, Amount: amount},
+		{WalletID: &toWalletID, Amount: amount.Neg()},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fromWallet, err := s.getWallet(tx, fromWalletID)
+	if err != nil {
+		return nil, nil, err
+	}
+	toWallet, err := s.getWallet(tx, toWalletID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fromWallet, toWallet, nil
+}
+
+// GetWalletLedger returns the journal entries that touched a user's wallet
+func (s *LedgerService) GetWalletLedger(userID string, page, limit int) ([]*models.JournalEntry, int64, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, 0, errors.New("invalid user ID")
+	}
+
+	wallet, err := s.walletRepo.GetByUserID(uid)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	account, err := s.ensureWalletAccount(wallet.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	return s.ledgerRepo.GetEntriesByAccount(account.ID, limit, offset)
+}
+
+// ReconcileWallet compares a user's stored wallet balance with the balance derived from the ledger
+func (s *LedgerService) ReconcileWallet(userID string) (*dto.LedgerReconciliationResponse, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	wallet, err := s.walletRepo.GetByUserID(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := s.ensureWalletAccount(wallet.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.reconcile(wallet, account)
+}
+
+// ReconcileAllWallets checks every wallet with a ledger account and returns the ones that drifted
+func (s *LedgerService) ReconcileAllWallets() ([]*dto.LedgerReconciliationResponse, error) {
+	accounts, err := s.ledgerRepo.GetWalletAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	var mismatches []*dto.LedgerReconciliationResponse
+	for _, account := range accounts {
+		wallet, err := s.walletRepo.GetByID(*account.WalletID)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := s.reconcile(wallet, account)
+		if err != nil {
+			return nil, err
+		}
+		if !result.Reconciled {
+			utils.LogWarning("Wallet balance does not match ledger", map[string]interface{}{
+				"wallet_id":      wallet.ID.String(),
+				"wallet_balance": result.WalletBalance.String(),
+				"ledger_balance": result.LedgerBalance.String(),
+			})
+			mismatches = append(mismatches, result)
+		}
+	}
+
+	return mismatches, nil
+}
+
+func (s *LedgerService) reconcile(wallet *models.Wallet, account *models.LedgerAccount) (*dto.LedgerReconciliationResponse, error) {
+	sum, err := s.ledgerRepo.GetAccountPostingSum(nil, account.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	ledgerBalance := account.NormalBalance(sum)
+	difference := wallet.Balance.Sub(ledgerBalance)
+
+	return &dto.LedgerReconciliationResponse{
+		WalletID:      wallet.ID.String(),
+		WalletBalance: wallet.Balance,
+		LedgerBalance: ledgerBalance,
+		Difference:    difference,
+		Reconciled:    difference.IsZero(),
+	}, nil
+}
+
+// ensureWalletAccount opens a wallet's ledger account outside of any caller transaction
+func (s *LedgerService) ensureWalletAccount(walletID uuid.UUID) (*models.LedgerAccount, error) {
+	var account *models.LedgerAccount
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		account, err = s.walletAccount(tx, walletID)
+		return err
+	})
+	return account, err
+}
+
+// walletAccount returns the ledger account for a wallet, opening it on first
+// use. Balances that existed before the ledger are brought in as an opening
+// balance entry so the account starts reconciled.
+func (s *LedgerService) walletAccount(tx *gorm.DB, walletID uuid.UUID) (*models.LedgerAccount, error) {
+	if account, err := s.ledgerRepo.GetAccountByWalletID(tx, walletID); err == nil {
+		return account, nil
+	}
+
+	wallet, err := s.getWallet(tx, walletID)
+	if err != nil {
+		return nil, err
+	}
+
+	account := &models.LedgerAccount{
+		Code:     walletAccountCode(walletID),
+		Name:     fmt.Sprintf("Wallet %s", walletID.String()),
+		Type:     models.LedgerAccountTypeLiability,
+		WalletID: &walletID,
+		Currency: wallet.Currency,
+	}
+	if err := s.ledgerRepo.CreateAccount(tx, account); err != nil {
+		return nil, err
+	}
+
+	created, err := s.ledgerRepo.GetAccountByCode(tx, account.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	// Another request opened the account first; it owns the opening balance
+	if created.ID != account.ID || wallet.Balance.IsZero() {
+		return created, nil
+	}
+
+	_, err = s.post(tx, "OPENING_"+walletID.String(), models.JournalEntryTypeOpeningBalance, "Opening balance", nil, []LedgerLine{
+		{AccountCode: models.LedgerAccountOpeningBalance, Amount: wallet.Balance},
+		{WalletID: &walletID, Amount: wallet.Balance.Neg()},
+	}, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to post opening balance: %w", err)
+	}
+
+	return created, nil
+}
+
+// systemAccount returns a system ledger account, creating it on first use
+func (s *LedgerService) systemAccount(tx *gorm.DB, code string) (*models.LedgerAccount, error) {
+	if account, err := s.ledgerRepo.GetAccountByCode(tx, code); err == nil {
+		return account, nil
+	}
+
+	def, ok := systemLedgerAccounts[code]
+	if !ok {
+		return nil, fmt.Errorf("unknown ledger account: %s", code)
+	}
+
+	account := &models.LedgerAccount{
+		Code:     code,
+		Name:     def.Name,
+		Type:     def.Type,
+		Currency: "INR",
+	}
+	if err := s.ledgerRepo.CreateAccount(tx, account); err != nil {
+		return nil, err
+	}
+
+	return s.ledgerRepo.GetAccountByCode(tx, code)
+}
+
+func (s *LedgerService) getWallet(tx *gorm.DB, walletID uuid.UUID) (*models.Wallet, error) {
+	db := s.db
+	if tx != nil {
+		db = tx
+	}
+
+	var wallet models.Wallet
+	if err := db.Where("id = ?", walletID).First(&wallet).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("wallet not found")
+		}
+		return nil, fmt.Errorf("failed to get wallet: %w", err)
+	}
+	return &wallet, nil
+}
+
+// feeLines splits a transfer fee into its base fee, earned as revenue, and
+// the GST on it, owed to the government. They are credited when the fee is
+// charged and debited when it is refunded.
+func feeLines(fee, gst decimal.Decimal, charged bool) []LedgerLine {
+	base := fee.Sub(gst)
+	if !charged {
+		base, gst = base.Neg(), gst.Neg()
+	}
+
+	var lines []LedgerLine
+	if !base.IsZero() {
+		lines = append(lines, LedgerLine{AccountCode: models.LedgerAccountFeeRevenue, Amount: base.Neg()})
+	}
+	if !gst.IsZero() {
+		lines = append(lines, LedgerLine{AccountCode: models.LedgerAccountGSTPayable, Amount: gst.Neg()})
+	}
+	return lines
+}
+
+func walletAccountCode(walletID uuid.UUID) string {
+	return "wallet:" + walletID.String()
+}
+
+func settlementReference(referenceID string) string {
+	return "SETTLE_" + referenceID
+}
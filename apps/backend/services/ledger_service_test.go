@@ -0,0 +1,241 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"gorm.io/gorm"
+)
+
+func TestPostRejectsMalformedEntries(t *testing.T) {
+	// Every entry here is refused before the ledger is read
+	s := &LedgerService{}
+	walletID := uuid.New()
+	amount := decimal.NewFromInt(100)
+
+	tests := []struct {
+		name      string
+		reference string
+		lines     []LedgerLine
+		want      error // nil for errors without a sentinel
+	}{
+		{
+			name:  "no reference",
+			lines: []LedgerLine{{WalletID: &walletID, Amount: amount}, {AccountCode: models.LedgerAccountMerchantPayable, Amount: amount.Neg()}},
+		},
+		{
+			name:      "single line",
+			reference: "REF1",
+			lines:     []LedgerLine{{WalletID: &walletID, Amount: amount}},
+			want:      ErrUnbalancedJournalEntry,
+		},
+		{
+			name:      "unbalanced",
+			reference: "REF2",
+			lines:     []LedgerLine{{WalletID: &walletID, Amount: amount}, {AccountCode: models.LedgerAccountMerchantPayable, Amount: decimal.NewFromInt(-99)}},
+			want:      ErrUnbalancedJournalEntry,
+		},
+		{
+			name:      "zero posting",
+			reference: "REF3",
+			lines: []LedgerLine{
+				{WalletID: &walletID, Amount: amount},
+				{AccountCode: models.LedgerAccountMerchantPayable, Amount: amount.Neg()},
+				{AccountCode: models.LedgerAccountFeeRevenue, Amount: decimal.Zero},
+			},
+		},
+		{
+			name:      "line with both accounts",
+			reference: "REF4",
+			lines:     []LedgerLine{{WalletID: &walletID, AccountCode: models.LedgerAccountFeeRevenue, Amount: amount}, {AccountCode: models.LedgerAccountMerchantPayable, Amount: amount.Neg()}},
+		},
+		{
+			name:      "line without an account",
+			reference: "REF5",
+			lines:     []LedgerLine{{Amount: amount}, {AccountCode: models.LedgerAccountMerchantPayable, Amount: amount.Neg()}},
+		},
+	}
+	for _, tt := range tests {
+		_, err := s.post(nil, tt.reference, models.JournalEntryTypeAIPayment, tt.name, nil, tt.lines, true)
+		if err == nil {
+			t.Errorf("%s: post succeeded", tt.name)
+			continue
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: post = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+// accountBalance is the sum of an account's postings, debits positive
+func accountBalance(t *testing.T, db *gorm.DB, code string) decimal.Decimal {
+	t.Helper()
+
+	ledgerRepo := repositories.NewLedgerRepository(db)
+	account, err := ledgerRepo.GetAccountByCode(nil, code)
+	if err != nil {
+		return decimal.Zero
+	}
+	sum, err := ledgerRepo.GetAccountPostingSum(nil, account.ID)
+	if err != nil {
+		t.Fatalf("failed to sum postings of %s: %v", code, err)
+	}
+	return sum
+}
+
+func TestPostBalancedEntries(t *testing.T) {
+	db := testDB(t)
+	s := newTestServices(db, nil)
+	from := createTestWallet(t, db, "1000", "50000")
+	to := createTestWallet(t, db, "0", "50000")
+
+	if _, err := s.ledger.RecordWalletLoad(nil, from.ID, decimal.NewFromInt(500), "LOAD_1", nil); err != nil {
+		t.Fatalf("RecordWalletLoad: %v", err)
+	}
+	if _, _, err := s.ledger.RecordWalletTransfer(nil, from.ID, to.ID, decimal.RequireFromString("300.50"), "P2P_1", nil); err != nil {
+		t.Fatalf("RecordWalletTransfer: %v", err)
+	}
+
+	requireAmount(t, "sender balance", walletBalance(t, db, from.ID), "1199.50")
+	requireAmount(t, "recipient balance", walletBalance(t, db, to.ID), "300.50")
+	requireAmount(t, "gateway settlement", accountBalance(t, db, models.LedgerAccountGatewaySettlement), "500")
+	// The balance held before the ledger came in as an opening balance
+	requireAmount(t, "opening balances", accountBalance(t, db, models.LedgerAccountOpeningBalance), "1000")
+
+	// Every entry balances, so all postings together sum to zero
+	total, err := repositories.NewLedgerRepository(db).GetTrialBalance()
+	if err != nil {
+		t.Fatalf("GetTrialBalance: %v", err)
+	}
+	requireAmount(t, "sum of all postings", total, "0")
+
+	for _, wallet := range []*models.Wallet{from, to} {
+		result, err := s.ledger.ReconcileWallet(wallet.UserID.String())
+		if err != nil {
+			t.Fatalf("ReconcileWallet: %v", err)
+		}
+		if !result.Reconciled {
+			t.Fatalf("wallet %s: balance %s, ledger %s; want them equal", wallet.ID, result.WalletBalance, result.LedgerBalance)
+		}
+	}
+}
+
+func TestPostRejectsDuplicateReference(t *testing.T) {
+	db := testDB(t)
+	s := newTestServices(db, nil)
+	wallet := createTestWallet(t, db, "0", "50000")
+
+	if _, err := s.ledger.RecordWalletLoad(nil, wallet.ID, decimal.NewFromInt(500), "LOAD_1", nil); err != nil {
+		t.Fatalf("RecordWalletLoad: %v", err)
+	}
+	_, err := s.ledger.RecordWalletLoad(nil, wallet.ID, decimal.NewFromInt(500), "LOAD_1", nil)
+	if !errors.Is(err, ErrDuplicateJournalEntry) {
+		t.Fatalf("second RecordWalletLoad = %v, want ErrDuplicateJournalEntry", err)
+	}
+	requireAmount(t, "balance", walletBalance(t, db, wallet.ID), "500")
+}
+
+func TestPostRejectsOverdraft(t *testing.T) {
+	db := testDB(t)
+	s := newTestServices(db, nil)
+	wallet := createTestWallet(t, db, "1000", "50000")
+
+	_, err := s.ledger.RecordAIPayment(nil, wallet.ID, decimal.RequireFromString("1000.01"), "AI_1", nil)
+	if !errors.Is(err, ErrInsufficientAvailableBalance) {
+		t.Fatalf("RecordAIPayment over the balance = %v, want ErrInsufficientAvailableBalance", err)
+	}
+
+	// Held funds cannot be spent by anything but their own hold
+	if _, err := s.holds.PlaceHold(nil, wallet.ID, decimal.NewFromInt(600), models.WalletHoldPurposeAIPayment, "hold_1", "held", AIPaymentHoldTTL); err != nil {
+		t.Fatalf("PlaceHold: %v", err)
+	}
+	_, err = s.ledger.RecordAIPayment(nil, wallet.ID, decimal.NewFromInt(401), "AI_2", nil)
+	if !errors.Is(err, ErrInsufficientAvailableBalance) {
+		t.Fatalf("RecordAIPayment into held funds = %v, want ErrInsufficientAvailableBalance", err)
+	}
+	requireAmount(t, "balance after refused debits", walletBalance(t, db, wallet.ID), "1000")
+
+	// Nothing of a refused entry is kept, so its reference can be posted again
+	if _, err := s.ledger.RecordAIPayment(nil, wallet.ID, decimal.NewFromInt(400), "AI_2", nil); err != nil {
+		t.Fatalf("RecordAIPayment within the available balance: %v", err)
+	}
+	requireAmount(t, "balance", walletBalance(t, db, wallet.ID), "600")
+	requireAmount(t, "merchant payable", accountBalance(t, db, models.LedgerAccountMerchantPayable), "-400")
+}
+
+func TestFeeLines(t *testing.T) {
+	tests := []struct {
+		fee, gst   string
+		charged    bool
+		revenue    string // Empty when there is no revenue line
+		gstPayable string // Empty when there is no GST line
+	}{
+		{"11.80", "1.80", true, "-10", "-1.80"},
+		{"11.80", "1.80", false, "10", "1.80"},
+		{"5", "0", true, "-5", ""}, // Fees quoted before GST was split out
+		{"0", "0", true, "", ""},
+	}
+	for _, tt := range tests {
+		lines := feeLines(decimal.RequireFromString(tt.fee), decimal.RequireFromString(tt.gst), tt.charged)
+
+		got := map[string]string{}
+		sum := decimal.Zero
+		for _, line := range lines {
+			got[line.AccountCode] = line.Amount.String()
+			sum = sum.Add(line.Amount)
+		}
+		want := map[string]string{}
+		if tt.revenue != "" {
+			want[models.LedgerAccountFeeRevenue] = decimal.RequireFromString(tt.revenue).String()
+		}
+		if tt.gstPayable != "" {
+			want[models.LedgerAccountGSTPayable] = decimal.RequireFromString(tt.gstPayable).String()
+		}
+		if len(got) != len(want) || len(lines) != len(want) {
+			t.Errorf("feeLines(%s, %s, %t) = %v, want %v", tt.fee, tt.gst, tt.charged, got, want)
+			continue
+		}
+		for code, amount := range want {
+			if got[code] != amount {
+				t.Errorf("feeLines(%s, %s, %t) = %v, want %v", tt.fee, tt.gst, tt.charged, got, want)
+			}
+		}
+
+		// The lines carry the whole fee, which the wallet line balances
+		fee := decimal.RequireFromString(tt.fee)
+		if tt.charged {
+			fee = fee.Neg()
+		}
+		if !sum.Equal(fee) {
+			t.Errorf("feeLines(%s, %s, %t) sum to %s", tt.fee, tt.gst, tt.charged, sum)
+		}
+	}
+}
+
+func TestExternalTransferPostsGSTAsPayable(t *testing.T) {
+	db := testDB(t)
+	s := newTestServices(db, nil)
+	wallet := createTestWallet(t, db, "5000", "50000")
+	amount, fee, gst := decimal.NewFromInt(1000), decimal.RequireFromString("11.80"), decimal.RequireFromString("1.80")
+
+	if _, err := s.ledger.RecordExternalTransfer(nil, wallet.ID, amount, fee, gst, "EXT_1", nil); err != nil {
+		t.Fatalf("RecordExternalTransfer: %v", err)
+	}
+	requireAmount(t, "balance", walletBalance(t, db, wallet.ID), "3988.20")
+	requireAmount(t, "payout clearing", accountBalance(t, db, models.LedgerAccountPayoutClearing), "-1000")
+	requireAmount(t, "fee revenue", accountBalance(t, db, models.LedgerAccountFeeRevenue), "-10")
+	requireAmount(t, "GST payable", accountBalance(t, db, models.LedgerAccountGSTPayable), "-1.80")
+
+	// A failed payout gives the whole fee back, GST included
+	if _, err := s.ledger.RecordPayoutReversal(nil, wallet.ID, amount, fee, gst, "EXT_1", nil); err != nil {
+		t.Fatalf("RecordPayoutReversal: %v", err)
+	}
+	requireAmount(t, "balance", walletBalance(t, db, wallet.ID), "5000")
+	for _, code := range []string{models.LedgerAccountPayoutClearing, models.LedgerAccountFeeRevenue, models.LedgerAccountGSTPayable} {
+		requireAmount(t, code, accountBalance(t, db, code), "0")
+	}
+}
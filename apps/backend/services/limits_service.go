@@ -0,0 +1,238 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"github.com/zeusnotfound04/Tranza/utils"
+	"gorm.io/gorm"
+)
+
+// Debit kinds checked by the limits engine
+const (
+	DebitKindExternalTransfer = "external_transfer"
+	DebitKindWalletTransfer   = "wallet_transfer"
+	DebitKindAIPayment        = "ai_payment"
+)
+
+// Rolling windows used for wallet limits
+const (
+	DailyLimitWindow   = 24 * time.Hour
+	MonthlyLimitWindow = 30 * 24 * time.Hour
+)
+
+// Limit names reported in LimitExceededError
+const (
+	LimitDaily            = "daily"
+	LimitMonthly          = "monthly"
+	LimitAIDaily          = "ai_daily"
+	LimitAIPerTransaction = "ai_per_transaction"
+
+	LimitAgentDaily          = "agent_daily"
+	LimitAgentPerTransaction = "agent_per_transaction"
+
+	// The user's AI spending limits, by calendar period in their time zone
+	LimitAITransaction = "ai_transaction"
+	LimitAIDay         = "ai_day"
+	LimitAIWeek        = "ai_week"
+	LimitAIMonth       = "ai_month"
+)
+
+// walletDebitTransactionTypes are the transaction types that count towards a
+// wallet's daily and monthly limits. "debit" covers AI payments recorded
+// before they had their own type.
+var walletDebitTransactionTypes = []string{
+	utils.TransactionTypeExternalTransfer,
+	utils.TransactionTypeWalletTransferOut,
+	utils.TransactionTypeAIPayment,
+	"debit",
+}
+
+var aiDebitTransactionTypes = []string{
+	utils.TransactionTypeAIPayment,
+	"debit",
+}
+
+// aiHoldPurposes are the holds that count towards a wallet's AI daily limit
+var aiHoldPurposes = []string{
+	models.WalletHoldPurposeAIPayment,
+	models.WalletHoldPurposeAIShopping,
+}
+
+// LimitExceededError is returned when a debit would take a wallet past one of its limits
+type LimitExceededError struct {
+	Limit     string          `json:"limit"`
+	Allowed   decimal.Decimal `json:"allowed"`
+	Used      decimal.Decimal `json:"used"`
+	Remaining decimal.Decimal `json:"remaining"`
+	Requested decimal.Decimal `json:"requested"`
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("%s limit exceeded: ₹%s remaining", strings.ReplaceAll(e.Limit, "_", " "), e.Remaining.StringFixed(2))
+}
+
+// LimitsService enforces wallet-level spending limits for every debit
+type LimitsService struct {
+	walletRepo      *repositories.WalletRepository
+	transactionRepo *repositories.TransactionRepository
+	holdRepo        *repositories.WalletHoldRepository
+}
+
+func NewLimitsService(
+	walletRepo *repositories.WalletRepository,
+	transactionRepo *repositories.TransactionRepository,
+	holdRepo *repositories.WalletHoldRepository,
+) *LimitsService {
+	return &LimitsService{
+		walletRepo:      walletRepo,
+		transactionRepo: transactionRepo,
+		holdRepo:        holdRepo,
+	}
+}
+
+// CheckDebit returns a *LimitExceededError if debiting amount from the wallet
+// would exceed any of its limits. It is a preview; the debit itself is checked
+// with CheckDebitWithTx.
+func (s *LimitsService) CheckDebit(wallet *models.Wallet, kind string, amount decimal.Decimal) error {
+	return s.checkDebit(nil, wallet, kind, amount, "")
+}
+
+// CheckDebitFromHold previews a debit paid out of the hold fundingHold, such
+// as a batch row's transfer. That hold already counts the debit's money, so
+// it is left out of what has been used.
+func (s *LimitsService) CheckDebitFromHold(wallet *models.Wallet, kind string, amount decimal.Decimal, fundingHold string) error {
+	return s.checkDebit(nil, wallet, kind, amount, fundingHold)
+}
+
+// CheckDebitWithTx locks the wallet and checks a debit inside the transaction
+// that records it or places its hold, so concurrent debits cannot both pass
+func (s *LimitsService) CheckDebitWithTx(tx *gorm.DB, walletID uuid.UUID, kind string, amount decimal.Decimal) error {
+	wallet, err := s.walletRepo.GetByIDForUpdate(tx, walletID)
+	if err != nil {
+		return err
+	}
+	return s.checkDebit(tx, wallet, kind, amount, "")
+}
+
+func (s *LimitsService) checkDebit(tx *gorm.DB, wallet *models.Wallet, kind string, amount decimal.Decimal, excludeHold string) error {
+	now := time.Now()
+
+	if kind == DebitKindAIPayment {
+		if !wallet.AIPerTransactionLimit.IsZero() && amount.GreaterThan(wallet.AIPerTransactionLimit) {
+			return &LimitExceededError{
+				Limit:     LimitAIPerTransaction,
+				Allowed:   wallet.AIPerTransactionLimit,
+				Used:      decimal.Zero,
+				Remaining: wallet.AIPerTransactionLimit,
+				Requested: amount,
+			}
+		}
+
+		aiDailyUsed, err := s.usedSince(tx, wallet.ID, aiDebitTransactionTypes, aiHoldPurposes, now.Add(-DailyLimitWindow), excludeHold)
+		if err != nil {
+			return err
+		}
+		if err := checkLimit(LimitAIDaily, wallet.AIDailyLimit, aiDailyUsed, amount); err != nil {
+			return err
+		}
+	}
+
+	dailyUsed, err := s.usedSince(tx, wallet.ID, walletDebitTransactionTypes, nil, now.Add(-DailyLimitWindow), excludeHold)
+	if err != nil {
+		return err
+	}
+	if err := checkLimit(LimitDaily, wallet.DailyLimit, dailyUsed, amount); err != nil {
+		return err
+	}
+
+	monthlyUsed, err := s.usedSince(tx, wallet.ID, walletDebitTransactionTypes, nil, now.Add(-MonthlyLimitWindow), excludeHold)
+	if err != nil {
+		return err
+	}
+	return checkLimit(LimitMonthly, wallet.MonthlyLimit, monthlyUsed, amount)
+}
+
+// usedSince is what counts against a limit window: debits recorded since it
+// started, plus active holds placed since then that have no transaction yet,
+// other than excludeHold
+func (s *LimitsService) usedSince(tx *gorm.DB, walletID uuid.UUID, types, holdPurposes []string, since time.Time, excludeHold string) (decimal.Decimal, error) {
+	debited, err := s.transactionRepo.GetDebitTotalSinceWithTx(tx, walletID, types, since)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	held, err := s.holdRepo.GetUnrecordedActiveTotal(tx, walletID, holdPurposes, since, excludeHold)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return debited.Add(held), nil
+}
+
+// GetLimitUsage reports how much of each wallet limit has been used
+func (s *LimitsService) GetLimitUsage(userID string) (*dto.WalletLimitsResponse, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	wallet, err := s.walletRepo.GetByUserID(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	dailyStart := now.Add(-DailyLimitWindow)
+	monthlyStart := now.Add(-MonthlyLimitWindow)
+
+	dailyUsed, err := s.usedSince(nil, wallet.ID, walletDebitTransactionTypes, nil, dailyStart, "")
+	if err != nil {
+		return nil, err
+	}
+	monthlyUsed, err := s.usedSince(nil, wallet.ID, walletDebitTransactionTypes, nil, monthlyStart, "")
+	if err != nil {
+		return nil, err
+	}
+	aiDailyUsed, err := s.usedSince(nil, wallet.ID, aiDebitTransactionTypes, aiHoldPurposes, dailyStart, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.WalletLimitsResponse{
+		Daily:                 limitUsage(wallet.DailyLimit, dailyUsed, dailyStart),
+		Monthly:               limitUsage(wallet.MonthlyLimit, monthlyUsed, monthlyStart),
+		AIDaily:               limitUsage(wallet.AIDailyLimit, aiDailyUsed, dailyStart),
+		AIPerTransactionLimit: wallet.AIPerTransactionLimit,
+		AIAccessEnabled:       wallet.AIAccessEnabled,
+	}, nil
+}
+
+func checkLimit(name string, allowed, used, requested decimal.Decimal) error {
+	// A zero limit means the limit is not configured
+	if allowed.IsZero() || !used.Add(requested).GreaterThan(allowed) {
+		return nil
+	}
+
+	return &LimitExceededError{
+		Limit:     name,
+		Allowed:   allowed,
+		Used:      used,
+		Remaining: decimal.Max(allowed.Sub(used), decimal.Zero),
+		Requested: requested,
+	}
+}
+
+func limitUsage(allowed, used decimal.Decimal, windowStart time.Time) dto.WalletLimitUsage {
+	return dto.WalletLimitUsage{
+		Limit:       allowed,
+		Used:        used,
+		Remaining:   decimal.Max(allowed.Sub(used), decimal.Zero),
+		WindowStart: windowStart.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/pkg/razorpay"
+	"github.com/zeusnotfound04/Tranza/utils"
+	"gorm.io/gorm"
+)
+
+func TestCheckLimit(t *testing.T) {
+	tests := []struct {
+		allowed, used, requested string
+		remaining                string // Empty when the debit fits
+	}{
+		{"1000", "0", "1000", ""},
+		{"1000", "600", "400", ""},
+		{"1000", "600", "400.01", "400"},
+		{"1000", "1200", "1", "0"}, // Over already, e.g. after the limit was lowered
+		{"0", "5000", "5000", ""},  // Not configured
+	}
+	for _, tt := range tests {
+		err := checkLimit(LimitDaily, decimal.RequireFromString(tt.allowed), decimal.RequireFromString(tt.used), decimal.RequireFromString(tt.requested))
+		if tt.remaining == "" {
+			if err != nil {
+				t.Errorf("checkLimit(%s, %s, %s) = %v, want nil", tt.allowed, tt.used, tt.requested, err)
+			}
+			continue
+		}
+
+		var limitErr *LimitExceededError
+		if !errors.As(err, &limitErr) {
+			t.Errorf("checkLimit(%s, %s, %s) = %v, want a LimitExceededError", tt.allowed, tt.used, tt.requested, err)
+			continue
+		}
+		if limitErr.Limit != LimitDaily || !limitErr.Remaining.Equal(decimal.RequireFromString(tt.remaining)) {
+			t.Errorf("checkLimit(%s, %s, %s) = %+v, want %s remaining", tt.allowed, tt.used, tt.requested, limitErr, tt.remaining)
+		}
+	}
+}
+
+// createTestDebit records a debit transaction made at the given time
+func createTestDebit(t *testing.T, db *gorm.DB, wallet *models.Wallet, txnType, status, amount, referenceID string, at time.Time) {
+	t.Helper()
+
+	txn := &models.Transaction{
+		ID:          uuid.New(),
+		WalletID:    wallet.ID,
+		UserID:      wallet.UserID,
+		Type:        txnType,
+		Amount:      decimal.RequireFromString(amount),
+		Currency:    "INR",
+		Status:      models.TransactionStatus(status),
+		ReferenceID: referenceID,
+		CreatedAt:   at,
+	}
+	if err := db.Create(txn).Error; err != nil {
+		t.Fatalf("failed to create transaction: %v", err)
+	}
+}
+
+// requireLimit checks that a debit of amount is refused by the named limit,
+// and that a debit of one rupee less is allowed
+func requireLimit(t *testing.T, s *LimitsService, wallet *models.Wallet, kind, amount, limit string) {
+	t.Helper()
+
+	requested := decimal.RequireFromString(amount)
+	var limitErr *LimitExceededError
+	if err := s.CheckDebit(wallet, kind, requested); !errors.As(err, &limitErr) || limitErr.Limit != limit {
+		t.Fatalf("CheckDebit(%s) = %v, want the %s limit exceeded", amount, err, limit)
+	}
+	if err := s.CheckDebit(wallet, kind, requested.Sub(decimal.NewFromInt(1))); err != nil {
+		t.Fatalf("CheckDebit(%s) = %v, want it allowed", requested.Sub(decimal.NewFromInt(1)), err)
+	}
+}
+
+func TestLimitWindows(t *testing.T) {
+	db := testDB(t)
+	s := newTestServices(db, nil)
+	wallet := createTestWallet(t, db, "100000", "1000")
+	wallet.MonthlyLimit = decimal.NewFromInt(3000)
+	now := time.Now()
+
+	// Two days ago counts for the month only; five weeks ago for neither
+	createTestDebit(t, db, wallet, utils.TransactionTypeExternalTransfer, utils.TransactionStatusSuccess, "1500", "OLD_1", now.Add(-48*time.Hour))
+	createTestDebit(t, db, wallet, utils.TransactionTypeWalletTransferOut, utils.TransactionStatusSuccess, "2000", "OLD_2", now.Add(-35*24*time.Hour))
+	// Today, but failed
+	createTestDebit(t, db, wallet, utils.TransactionTypeAIPayment, utils.TransactionStatusFailed, "900", "FAILED_1", now)
+	// Today, but not a debit
+	createTestDebit(t, db, wallet, utils.TransactionTypeLoadMoney, utils.TransactionStatusSuccess, "900", "LOAD_1", now)
+
+	requireLimit(t, s.limits, wallet, DebitKindWalletTransfer, "1001", LimitDaily)
+
+	createTestDebit(t, db, wallet, utils.TransactionTypeWalletTransferOut, utils.TransactionStatusSuccess, "800", "TODAY_1", now)
+	requireLimit(t, s.limits, wallet, DebitKindWalletTransfer, "201", LimitDaily)
+
+	// 1500 + 800 used this month leaves 700, less than the 1000 a day allows
+	wallet.DailyLimit = decimal.NewFromInt(5000)
+	requireLimit(t, s.limits, wallet, DebitKindExternalTransfer, "701", LimitMonthly)
+}
+
+func TestLimitWindowsCountUnrecordedHolds(t *testing.T) {
+	db := testDB(t)
+	s := newTestServices(db, nil)
+	wallet := createTestWallet(t, db, "100000", "1000")
+
+	// A transfer's hold counts from the moment it is placed
+	if _, err := s.holds.PlaceHold(nil, wallet.ID, decimal.NewFromInt(600), models.WalletHoldPurposeExternalTransfer, "TRF_1", "held", ExternalTransferHoldTTL); err != nil {
+		t.Fatalf("PlaceHold: %v", err)
+	}
+	requireLimit(t, s.limits, wallet, DebitKindExternalTransfer, "401", LimitDaily)
+
+	// Once the transfer's transaction is recorded the hold is not counted again
+	createTestDebit(t, db, wallet, utils.TransactionTypeExternalTransfer, utils.TransactionStatusPending, "600", "TRF_1", time.Now())
+	requireLimit(t, s.limits, wallet, DebitKindExternalTransfer, "401", LimitDaily)
+
+	// A hold released without a debit frees its share of the limit
+	if _, err := s.holds.PlaceHold(nil, wallet.ID, decimal.NewFromInt(300), models.WalletHoldPurposeAIPayment, "AI_1", "held", AIPaymentHoldTTL); err != nil {
+		t.Fatalf("PlaceHold: %v", err)
+	}
+	requireLimit(t, s.limits, wallet, DebitKindExternalTransfer, "101", LimitDaily)
+	if err := s.holds.ReleaseHold(nil, "AI_1", "cancelled"); err != nil {
+		t.Fatalf("ReleaseHold: %v", err)
+	}
+	requireLimit(t, s.limits, wallet, DebitKindExternalTransfer, "401", LimitDaily)
+
+	// A hold placed before the window started is outside it
+	if _, err := s.holds.PlaceHold(nil, wallet.ID, decimal.NewFromInt(200), models.WalletHoldPurposeAIPayment, "AI_2", "held", AIPaymentHoldTTL); err != nil {
+		t.Fatalf("PlaceHold: %v", err)
+	}
+	if err := db.Model(&models.WalletHold{}).Where("reference_id = ?", "AI_2").Update("created_at", time.Now().Add(-25*time.Hour)).Error; err != nil {
+		t.Fatalf("failed to backdate hold: %v", err)
+	}
+	requireLimit(t, s.limits, wallet, DebitKindExternalTransfer, "401", LimitDaily)
+
+	// The AI daily limit counts AI holds only
+	wallet.AIDailyLimit = decimal.NewFromInt(250)
+	wallet.AIPerTransactionLimit = decimal.Zero
+	if _, err := s.holds.PlaceHold(nil, wallet.ID, decimal.NewFromInt(100), models.WalletHoldPurposeAIShopping, "SHOP_1", "held", AIPaymentHoldTTL); err != nil {
+		t.Fatalf("PlaceHold: %v", err)
+	}
+	requireLimit(t, s.limits, wallet, DebitKindAIPayment, "151", LimitAIDaily)
+}
+
+func TestExternalTransfersUseTheWalletsLimits(t *testing.T) {
+	db := testDB(t)
+	s := newTestServices(db, nil)
+	wallet := createTestWallet(t, db, "500000", "100000")
+	userID := wallet.UserID.String()
+
+	// Past the ₹50,000 a day transfers were once held to, within the wallet's limit
+	response, err := s.externalTransfers.ValidateTransferRequest(userID, &dto.ValidateTransferRequest{
+		Amount:         decimal.NewFromInt(60000),
+		RecipientType:  models.RecipientTypeUPI,
+		RecipientValue: "alice@okhdfc",
+	})
+	if err != nil || !response.Valid {
+		t.Fatalf("ValidateTransferRequest(₹60,000) = %+v, %v; want valid", response, err)
+	}
+
+	// An RTGS amount is refused by the wallet's daily limit until it is raised
+	bankTransfer := &dto.ValidateTransferRequest{
+		Amount:         decimal.NewFromInt(250000),
+		RecipientType:  models.RecipientTypeIFSC,
+		RecipientValue: "123456789012",
+		RecipientIFSC:  "HDFC0000060",
+	}
+	response, err = s.externalTransfers.ValidateTransferRequest(userID, bankTransfer)
+	if err != nil || response.Valid {
+		t.Fatalf("ValidateTransferRequest(₹2,50,000) = %+v, %v; want the daily limit exceeded", response, err)
+	}
+	if err := db.Model(&models.Wallet{}).Where("id = ?", wallet.ID).Update("daily_limit", decimal.NewFromInt(300000)).Error; err != nil {
+		t.Fatalf("failed to raise the daily limit: %v", err)
+	}
+	response, err = s.externalTransfers.ValidateTransferRequest(userID, bankTransfer)
+	if err != nil || !response.Valid || response.TransferMode != razorpay.PayoutModeRTGS {
+		t.Fatalf("ValidateTransferRequest(₹2,50,000) = %+v, %v; want valid by RTGS", response, err)
+	}
+
+	// The limits reported with the fees are the wallet's
+	fees, err := s.externalTransfers.GetTransferFees(wallet.UserID)
+	if err != nil {
+		t.Fatalf("GetTransferFees: %v", err)
+	}
+	requireAmount(t, "daily limit", fees.DailyLimit, "300000")
+	requireAmount(t, "monthly limit", fees.MonthlyLimit, "1000000")
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/repositories"
+)
+
+// UPI ID format: username@provider
+var upiIDRegex = regexp.MustCompile(`^[a-zA-Z0-9._-]+@[a-zA-Z0-9.-]+$`)
+
+// MerchantService manages the registry of merchants AI payments are paid out to
+type MerchantService struct {
+	merchantRepo *repositories.MerchantRepository
+}
+
+func NewMerchantService(merchantRepo *repositories.MerchantRepository) *MerchantService {
+	return &MerchantService{
+		merchantRepo: merchantRepo,
+	}
+}
+
+// CreateMerchant adds a merchant to the registry
+func (s *MerchantService) CreateMerchant(req *dto.CreateMerchantRequest) (*models.Merchant, error) {
+	merchant := &models.Merchant{IsActive: true}
+	if err := s.apply(merchant, req.Name, req.UPIID, req.Category, req.Aliases); err != nil {
+		return nil, err
+	}
+
+	if existing, err := s.merchantRepo.FindActiveByName(merchant.Name); err == nil {
+		return nil, errors.New("a merchant named " + existing.Name + " already exists")
+	}
+
+	if err := s.merchantRepo.Save(merchant); err != nil {
+		return nil, err
+	}
+	return merchant, nil
+}
+
+// UpdateMerchant changes the fields set in the request
+func (s *MerchantService) UpdateMerchant(merchantID string, req *dto.UpdateMerchantRequest) (*models.Merchant, error) {
+	id, err := uuid.Parse(merchantID)
+	if err != nil {
+		return nil, errors.New("invalid merchant ID")
+	}
+
+	merchant, err := s.merchantRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	name, upiID, category, aliases := merchant.Name, merchant.UPIID, merchant.Category, merchant.Aliases
+	if req.Name != nil {
+		name = *req.Name
+	}
+	if req.UPIID != nil {
+		upiID = *req.UPIID
+	}
+	if req.Category != nil {
+		category = *req.Category
+	}
+	if req.Aliases != nil {
+		aliases = req.Aliases
+	}
+	if err := s.apply(merchant, name, upiID, category, aliases); err != nil {
+		return nil, err
+	}
+	if req.IsActive != nil {
+		merchant.IsActive = *req.IsActive
+	}
+
+	if err := s.merchantRepo.Save(merchant); err != nil {
+		return nil, err
+	}
+	return merchant, nil
+}
+
+// GetMerchants lists the registry
+func (s *MerchantService) GetMerchants(page, limit int) ([]*models.Merchant, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return s.merchantRepo.GetAll(limit, (page-1)*limit)
+}
+
+// FindMerchant returns the active registered merchant with the given name or alias
+func (s *MerchantService) FindMerchant(name string) (*models.Merchant, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, errors.New("merchant not found")
+	}
+
+	return s.merchantRepo.FindActiveByName(name)
+}
+
+func (s *MerchantService) apply(merchant *models.Merchant, name, upiID, category string, aliases []string) error {
+	name = strings.TrimSpace(name)
+	upiID = strings.TrimSpace(upiID)
+	if name == "" {
+		return errors.New("merchant name is required")
+	}
+	if !upiIDRegex.MatchString(upiID) {
+		return errors.New("invalid UPI ID format")
+	}
+
+	merchant.Name = name
+	merchant.NormalizedName = strings.ToLower(name)
+	merchant.UPIID = upiID
+	merchant.Category = strings.TrimSpace(category)
+
+	merchant.Aliases = []string{}
+	seen := map[string]bool{merchant.NormalizedName: true}
+	for _, alias := range aliases {
+		alias = strings.ToLower(strings.TrimSpace(alias))
+		if alias == "" || seen[alias] {
+			continue
+		}
+		seen[alias] = true
+		merchant.Aliases = append(merchant.Aliases, alias)
+	}
+	return nil
+}
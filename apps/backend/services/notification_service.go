@@ -1,18 +1,38 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"log"
 
 	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/config"
+	"github.com/zeusnotfound04/Tranza/pkg/sms"
+	"github.com/zeusnotfound04/Tranza/utils"
 )
 
 type NotificationService struct {
-	// Add SMS, Email, Push notification clients here
+	// Add Email, Push notification clients here
+	smsSender sms.Sender
 }
 
-func NewNotificationService() *NotificationService {
-	return &NotificationService{}
+func NewNotificationService(smsSender sms.Sender) *NotificationService {
+	return &NotificationService{
+		smsSender: smsSender,
+	}
+}
+
+// NewSMSSender builds the SMS provider selected by cfg. Without a usable
+// provider messages are not delivered and their sends fail.
+func NewSMSSender(cfg *config.SMSConfig) sms.Sender {
+	if cfg.Provider == sms.ProviderTwilio && cfg.AccountSID != "" && cfg.AuthToken != "" && cfg.From != "" {
+		return sms.NewTwilioSender(cfg.AccountSID, cfg.AuthToken, cfg.From, cfg.BaseURL, cfg.Timeout)
+	}
+
+	utils.LogWarning("SMS provider not usable, text messages will not be delivered", map[string]interface{}{
+		"provider": cfg.Provider,
+	})
+	return sms.NewLogSender()
 }
 
 // Send wallet credit notification
@@ -28,3 +48,46 @@ func (s *NotificationService) SendAIPaymentNotification(userID, agentID string,
 	log.Printf("Notification to user %s: %s", userID, message)
 	// Implement actual notification logic (SMS, Email, Push)
 }
+
+// Send wallet transfer sent notification
+func (s *NotificationService) SendWalletTransferSentNotification(userID, recipient string, amount, newBalance decimal.Decimal) {
+	message := fmt.Sprintf("₹%s sent to %s. New balance: ₹%s", amount.StringFixed(2), recipient, newBalance.StringFixed(2))
+	log.Printf("Notification to user %s: %s", userID, message)
+	// Implement actual notification logic (SMS, Email, Push)
+}
+
+// Send wallet transfer received notification
+func (s *NotificationService) SendWalletTransferReceivedNotification(userID, sender string, amount, newBalance decimal.Decimal) {
+	message := fmt.Sprintf("₹%s received from %s. New balance: ₹%s", amount.StringFixed(2), sender, newBalance.StringFixed(2))
+	log.Printf("Notification to user %s: %s", userID, message)
+	// Implement actual notification logic (SMS, Email, Push)
+}
+
+// Send scheduled payment run notification
+func (s *NotificationService) SendScheduledPaymentNotification(userID, scheduleName string, amount decimal.Decimal, status, detail string) {
+	message := fmt.Sprintf("Scheduled payment %q of ₹%s: %s", scheduleName, amount.StringFixed(2), status)
+	if detail != "" {
+		message += " (" + detail + ")"
+	}
+	log.Printf("Notification to user %s: %s", userID, message)
+	// Implement actual notification logic (SMS, Email, Push)
+}
+
+// Send bulk payout completion notification
+func (s *NotificationService) SendPayoutBatchNotification(userID, batchName string, succeeded, failed int, paid decimal.Decimal) {
+	message := fmt.Sprintf("Bulk payout %q finished: %d paid (₹%s), %d failed", batchName, succeeded, paid.StringFixed(2), failed)
+	log.Printf("Notification to user %s: %s", userID, message)
+	// Implement actual notification logic (SMS, Email, Push)
+}
+
+// SendPhoneVerificationCode texts a phone number verification code. The code
+// is never logged.
+func (s *NotificationService) SendPhoneVerificationCode(ctx context.Context, phone, code string) error {
+	message := fmt.Sprintf("%s is your Tranza verification code. It expires in %d minutes.", code, int(VerificationExpiration.Minutes()))
+	if err := s.smsSender.Send(ctx, phone, message); err != nil {
+		return err
+	}
+
+	log.Printf("Verification code sent by SMS to %s", sms.MaskPhone(phone))
+	return nil
+}
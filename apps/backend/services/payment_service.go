@@ -4,6 +4,7 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -18,10 +19,15 @@ import (
 	"gorm.io/gorm"
 )
 
+var ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+
 type PaymentService struct {
 	razorpayClient  *razorpay.Client
 	walletRepo      *repositories.WalletRepository
 	transactionRepo *repositories.TransactionRepository
+	ledgerService   *LedgerService
+	transferService *ExternalTransferService
+	refundService   *RefundService
 	notificationSvc *NotificationService
 	db              *gorm.DB
 	webhookSecret   string
@@ -31,6 +37,9 @@ func NewPaymentService(
 	razorpayClient *razorpay.Client,
 	walletRepo *repositories.WalletRepository,
 	transactionRepo *repositories.TransactionRepository,
+	ledgerService *LedgerService,
+	transferService *ExternalTransferService,
+	refundService *RefundService,
 	notificationSvc *NotificationService,
 	db *gorm.DB,
 	webhookSecret string,
@@ -39,6 +48,9 @@ func NewPaymentService(
 		razorpayClient:  razorpayClient,
 		walletRepo:      walletRepo,
 		transactionRepo: transactionRepo,
+		ledgerService:   ledgerService,
+		transferService: transferService,
+		refundService:   refundService,
 		notificationSvc: notificationSvc,
 		db:              db,
 		webhookSecret:   webhookSecret,
@@ -210,33 +222,11 @@ func (s *PaymentService) processPaymentTransaction(
 		return nil, errors.New("amount mismatch")
 	}
 
-	// Get wallet
-	wallet, err := s.walletRepo.GetByID(transaction.WalletID)
+	// Credit wallet through the ledger and mark transaction successful
+	newBalance, err := s.creditLoadTransaction(tx, transaction, paymentID, paymentMethod)
 	if err != nil {
 		tx.Rollback()
-		return nil, errors.New("wallet not found")
-	}
-
-	// Calculate new balance
-	newBalance := wallet.Balance.Add(amount)
-
-	// Update wallet balance
-	err = s.walletRepo.UpdateBalance(tx, wallet.ID, newBalance)
-	if err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("failed to update wallet balance: %w", err)
-	}
-
-	// Update transaction
-	transaction.Status = utils.TransactionStatusSuccess
-	transaction.RazorpayPaymentID = paymentID
-	transaction.BalanceAfter = newBalance
-	transaction.PaymentMethod = paymentMethod
-
-	err = s.transactionRepo.Update(tx, transaction)
-	if err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("failed to update transaction: %w", err)
+		return nil, err
 	}
 
 	// Commit transaction
@@ -253,6 +243,29 @@ func (s *PaymentService) processPaymentTransaction(
 	}, nil
 }
 
+// creditLoadTransaction posts a wallet load to the ledger and marks the pending transaction successful
+func (s *PaymentService) creditLoadTransaction(
+	tx *gorm.DB,
+	transaction *models.Transaction,
+	paymentID, paymentMethod string,
+) (decimal.Decimal, error) {
+	wallet, err := s.ledgerService.RecordWalletLoad(tx, transaction.WalletID, transaction.Amount, transaction.ReferenceID, &transaction.ID)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to credit wallet: %w", err)
+	}
+
+	transaction.Status = utils.TransactionStatusSuccess
+	transaction.RazorpayPaymentID = paymentID
+	transaction.BalanceAfter = wallet.Balance
+	transaction.PaymentMethod = paymentMethod
+
+	if err := s.transactionRepo.Update(tx, transaction); err != nil {
+		return decimal.Zero, fmt.Errorf("failed to update transaction: %w", err)
+	}
+
+	return wallet.Balance, nil
+}
+
 // ProcessWebhookEvent processes Razorpay webhook events
 func (s *PaymentService) ProcessWebhookEvent(body []byte, signature string) error {
 	// Verify webhook signature
@@ -260,7 +273,7 @@ func (s *PaymentService) ProcessWebhookEvent(body []byte, signature string) erro
 		utils.LogWarning("Invalid webhook signature", map[string]interface{}{
 			"signature": signature,
 		})
-		return errors.New("invalid webhook signature")
+		return ErrInvalidWebhookSignature
 	}
 
 	// Parse webhook event
@@ -287,6 +300,11 @@ func (s *PaymentService) ProcessWebhookEvent(body []byte, signature string) erro
 		return s.handlePaymentAuthorized(event)
 	case "order.paid":
 		return s.handleOrderPaid(event)
+	case "payout.queued", "payout.pending", "payout.initiated", "payout.processed",
+		"payout.failed", "payout.rejected", "payout.reversed":
+		return s.handlePayoutEvent(event)
+	case utils.WebhookRefundCreated, utils.WebhookRefundProcessed, utils.WebhookRefundFailed:
+		return s.handleRefundEvent(event)
 	default:
 		utils.LogInfo("Unhandled webhook event", map[string]interface{}{
 			"event": event.Event,
@@ -297,7 +315,7 @@ func (s *PaymentService) ProcessWebhookEvent(body []byte, signature string) erro
 
 // handlePaymentCaptured handles payment.captured webhook
 func (s *PaymentService) handlePaymentCaptured(event *razorpay.WebhookEvent) error {
-	paymentData, ok := event.Payload["payment"].(map[string]interface{})
+	paymentData, ok := webhookEntity(event, "payment")
 	if !ok {
 		return errors.New("invalid payment data in webhook")
 	}
@@ -330,14 +348,18 @@ func (s *PaymentService) handlePaymentCaptured(event *razorpay.WebhookEvent) err
 		return nil
 	}
 
-	// Update transaction status
-	err = s.transactionRepo.UpdateStatus(
-		transaction.ID,
-		utils.TransactionStatusSuccess,
-		"",
-	)
+	// Credit wallet through the ledger
+	paymentMethod, _ := paymentData["method"].(string)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		_, err := s.creditLoadTransaction(tx, transaction, paymentID, paymentMethod)
+		return err
+	})
+	if errors.Is(err, ErrDuplicateJournalEntry) {
+		// Credited concurrently by payment verification
+		return nil
+	}
 	if err != nil {
-		return fmt.Errorf("failed to update transaction status: %w", err)
+		return fmt.Errorf("failed to credit wallet: %w", err)
 	}
 
 	utils.LogInfo("Payment captured via webhook", map[string]interface{}{
@@ -351,7 +373,7 @@ func (s *PaymentService) handlePaymentCaptured(event *razorpay.WebhookEvent) err
 
 // handlePaymentFailed handles payment.failed webhook
 func (s *PaymentService) handlePaymentFailed(event *razorpay.WebhookEvent) error {
-	paymentData, ok := event.Payload["payment"].(map[string]interface{})
+	paymentData, ok := webhookEntity(event, "payment")
 	if !ok {
 		return errors.New("invalid payment data in webhook")
 	}
@@ -409,7 +431,7 @@ func (s *PaymentService) handlePaymentAuthorized(event *razorpay.WebhookEvent) e
 
 // handleOrderPaid handles order.paid webhook
 func (s *PaymentService) handleOrderPaid(event *razorpay.WebhookEvent) error {
-	orderData, ok := event.Payload["order"].(map[string]interface{})
+	orderData, ok := webhookEntity(event, "order")
 	if !ok {
 		return errors.New("invalid order data in webhook")
 	}
@@ -426,6 +448,93 @@ func (s *PaymentService) handleOrderPaid(event *razorpay.WebhookEvent) error {
 	return nil
 }
 
+// handlePayoutEvent handles payout.* webhooks for external transfers
+func (s *PaymentService) handlePayoutEvent(event *razorpay.WebhookEvent) error {
+	payout, err := webhookPayout(event)
+	if err != nil {
+		return err
+	}
+
+	if err := s.transferService.HandlePayoutEvent(event.Event, payout); err != nil {
+		return err
+	}
+
+	utils.LogInfo("Payout event processed via webhook", map[string]interface{}{
+		"event":     event.Event,
+		"payout_id": payout.ID,
+		"status":    payout.Status,
+	})
+
+	return nil
+}
+
+// handleRefundEvent handles refund.* webhooks for wallet load refunds
+func (s *PaymentService) handleRefundEvent(event *razorpay.WebhookEvent) error {
+	refund, err := webhookRefund(event)
+	if err != nil {
+		return err
+	}
+
+	return s.refundService.HandleRefundEvent(event.Event, refund)
+}
+
+// webhookRefund reads the refund a refund.* webhook is about
+func webhookRefund(event *razorpay.WebhookEvent) (*razorpay.Refund, error) {
+	refundData, ok := webhookEntity(event, "refund")
+	if !ok {
+		return nil, errors.New("invalid refund data in webhook")
+	}
+
+	raw, err := json.Marshal(refundData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read refund data: %w", err)
+	}
+
+	var refund razorpay.Refund
+	if err := json.Unmarshal(raw, &refund); err != nil {
+		return nil, fmt.Errorf("failed to read refund data: %w", err)
+	}
+	if refund.ID == "" {
+		return nil, errors.New("refund ID not found in webhook")
+	}
+	return &refund, nil
+}
+
+// webhookPayout reads the payout a payout.* webhook is about
+func webhookPayout(event *razorpay.WebhookEvent) (*razorpay.Payout, error) {
+	payoutData, ok := webhookEntity(event, "payout")
+	if !ok {
+		return nil, errors.New("invalid payout data in webhook")
+	}
+
+	raw, err := json.Marshal(payoutData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payout data: %w", err)
+	}
+
+	var payout razorpay.Payout
+	if err := json.Unmarshal(raw, &payout); err != nil {
+		return nil, fmt.Errorf("failed to read payout data: %w", err)
+	}
+	if payout.ID == "" {
+		return nil, errors.New("payout ID not found in webhook")
+	}
+	return &payout, nil
+}
+
+// webhookEntity returns the named object of a webhook payload. Razorpay nests
+// each object under an "entity" key; flat payloads are accepted as well.
+func webhookEntity(event *razorpay.WebhookEvent, name string) (map[string]interface{}, bool) {
+	data, ok := event.Payload[name].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	if entity, ok := data["entity"].(map[string]interface{}); ok {
+		return entity, true
+	}
+	return data, true
+}
+
 // GetPaymentStatus gets payment status from Razorpay
 func (s *PaymentService) GetPaymentStatus(paymentID string) (*razorpay.Payment, error) {
 	payment, err := s.razorpayClient.GetPayment(paymentID)
@@ -444,18 +553,19 @@ func (s *PaymentService) GetOrderStatus(orderID string) (*razorpay.Order, error)
 	return order, nil
 }
 
-// RefundPayment processes a refund (for future use)
+// RefundPayment refunds a wallet load by its Razorpay payment ID
 func (s *PaymentService) RefundPayment(paymentID string, amount decimal.Decimal, reason string) error {
-	// This would use Razorpay's refund API
-	// Implementation depends on your refund requirements
-	utils.LogInfo("Refund requested", map[string]interface{}{
-		"payment_id": paymentID,
-		"amount":     amount.String(),
-		"reason":     reason,
-	})
+	transaction, err := s.transactionRepo.GetByPaymentID(paymentID)
+	if err != nil {
+		return fmt.Errorf("transaction not found for payment %s: %w", paymentID, err)
+	}
 
-	// TODO: Implement refund logic using Razorpay refund API
-	return errors.New("refund functionality not implemented yet")
+	_, err = s.refundService.CreateRefund(transaction.UserID.String(), &dto.CreateRefundRequest{
+		TransactionID: transaction.ID.String(),
+		Amount:        amount,
+		Reason:        reason,
+	})
+	return err
 }
 
 // CleanupExpiredOrders marks expired pending orders as failed
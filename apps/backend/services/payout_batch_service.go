@@ -0,0 +1,768 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/pkg/ifsc"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"github.com/zeusnotfound04/Tranza/utils"
+	"gorm.io/gorm"
+)
+
+// Payout batch settings
+const (
+	PayoutBatchMaxRows       = 500              // Rows one upload may have
+	PayoutBatchMaxFileSize   = 1 << 20          // 1 MB
+	PayoutBatchConfirmWindow = 30 * time.Minute // How long a validated batch waits for confirmation
+	PayoutBatchRowStaleAfter = 5 * time.Minute  // When a row left processing is taken to be interrupted
+)
+
+var (
+	ErrPayoutBatchExpired    = errors.New("payout batch was not confirmed in time; upload it again")
+	ErrPayoutBatchNotPending = errors.New("payout batch is not waiting for confirmation")
+)
+
+// payoutBatchColumns are the columns a CSV upload may have. Each row needs an
+// amount and either recipient_type and recipient_value or a beneficiary.
+var payoutBatchColumns = []string{
+	"recipient_type", "recipient_value", "recipient_ifsc", "recipient_name", "beneficiary", "amount", "description",
+}
+
+// PayoutBatchService pays many external transfers from one upload. Every row
+// is checked with the same validation as a single transfer before the user
+// confirms, and each confirmed row then goes through the transfer pipeline.
+type PayoutBatchService struct {
+	db                      *gorm.DB
+	batchRepo               *repositories.PayoutBatchRepository
+	walletRepo              *repositories.WalletRepository
+	externalTransferRepo    *repositories.ExternalTransferRepository
+	holdService             *HoldService
+	externalTransferService *ExternalTransferService
+	jobQueue                *JobQueue
+	notificationService     *NotificationService
+}
+
+// PayoutBatchUpload is a validated batch with its rows
+type PayoutBatchUpload struct {
+	Batch *models.PayoutBatch      `json:"batch"`
+	Rows  []*models.PayoutBatchRow `json:"rows"`
+}
+
+// payoutBatchJobPayload identifies the batch a job pays
+type payoutBatchJobPayload struct {
+	BatchID string `json:"batch_id"`
+}
+
+func NewPayoutBatchService(
+	db *gorm.DB,
+	batchRepo *repositories.PayoutBatchRepository,
+	walletRepo *repositories.WalletRepository,
+	externalTransferRepo *repositories.ExternalTransferRepository,
+	holdService *HoldService,
+	externalTransferService *ExternalTransferService,
+	jobQueue *JobQueue,
+	notificationService *NotificationService,
+) *PayoutBatchService {
+	return &PayoutBatchService{
+		db:                      db,
+		batchRepo:               batchRepo,
+		walletRepo:              walletRepo,
+		externalTransferRepo:    externalTransferRepo,
+		holdService:             holdService,
+		externalTransferService: externalTransferService,
+		jobQueue:                jobQueue,
+		notificationService:     notificationService,
+	}
+}
+
+// RegisterJobHandlers registers the batch payout job with the job queue
+func (s *PayoutBatchService) RegisterJobHandlers() {
+	s.jobQueue.Register(models.JobTypePayoutBatch, utils.GetMaxRetryAttempts(), s.handleBatchJob, s.handleBatchDead)
+}
+
+// RegisterTransferHandlers has batch transfers report back to their rows.
+// Call before the job queue starts.
+func (s *PayoutBatchService) RegisterTransferHandlers() {
+	s.externalTransferService.OnTransferComplete(s.handleTransferComplete)
+}
+
+// ParsePayoutBatchFile reads the rows of an uploaded .csv file, with the
+// columns in payoutBatchColumns, or .json file holding an array of rows
+func ParsePayoutBatchFile(fileName string, data []byte) ([]dto.PayoutBatchRowInput, error) {
+	var rows []dto.PayoutBatchRowInput
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".json":
+		if err := json.Unmarshal(data, &rows); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", fileName, err)
+		}
+	case ".csv":
+		var err error
+		if rows, err = readPayoutBatchCSV(bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", fileName, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported file %s, use .csv or .json", fileName)
+	}
+	return rows, nil
+}
+
+func readPayoutBatchCSV(r io.Reader) ([]dto.PayoutBatchRowInput, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	column := make(map[string]int, len(header))
+	for i, name := range header {
+		column[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := column["amount"]; !ok {
+		return nil, errors.New(`missing column "amount"`)
+	}
+	_, hasBeneficiary := column["beneficiary"]
+	_, hasType := column["recipient_type"]
+	_, hasValue := column["recipient_value"]
+	if !hasBeneficiary && !(hasType && hasValue) {
+		return nil, errors.New(`need the columns "recipient_type" and "recipient_value", or "beneficiary"`)
+	}
+	for name := range column {
+		if !slices.Contains(payoutBatchColumns, name) {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+	}
+
+	var rows []dto.PayoutBatchRowInput
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(name string) string {
+			i, ok := column[name]
+			if !ok {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		amount, err := decimal.NewFromString(strings.ReplaceAll(field("amount"), ",", ""))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid amount %q", line, field("amount"))
+		}
+		rows = append(rows, dto.PayoutBatchRowInput{
+			RecipientType:  field("recipient_type"),
+			RecipientValue: field("recipient_value"),
+			RecipientIFSC:  field("recipient_ifsc"),
+			RecipientName:  field("recipient_name"),
+			Beneficiary:    field("beneficiary"),
+			Amount:         amount,
+			Description:    field("description"),
+		})
+	}
+	return rows, nil
+}
+
+// CreateBatch validates every row of an upload and saves the batch to wait
+// for confirmation. Rows that fail validation are kept, marked invalid, and
+// are not paid.
+func (s *PayoutBatchService) CreateBatch(userID uuid.UUID, name, fileName string, inputs []dto.PayoutBatchRowInput) (*PayoutBatchUpload, error) {
+	if len(inputs) == 0 {
+		return nil, errors.New("payout batch has no rows")
+	}
+	if len(inputs) > PayoutBatchMaxRows {
+		return nil, fmt.Errorf("payout batch has %d rows; the limit is %d", len(inputs), PayoutBatchMaxRows)
+	}
+
+	wallet, err := s.walletRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, errors.New("wallet not found")
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Bulk payout " + time.Now().In(RiskLocation).Format("02 Jan 2006 15:04")
+	}
+	batch := &models.PayoutBatch{
+		UserID:      userID,
+		Name:        name,
+		FileName:    fileName,
+		TotalRows:   len(inputs),
+		TotalAmount: decimal.Zero,
+		TotalFees:   decimal.Zero,
+		Status:      models.PayoutBatchStatusPendingConfirmation,
+		ExpiresAt:   time.Now().Add(PayoutBatchConfirmWindow),
+	}
+
+	var warnings []string
+	seen := make(map[string]int)
+	rows := make([]*models.PayoutBatchRow, 0, len(inputs))
+	for i, input := range inputs {
+		row, err := s.validateRow(userID, i+1, input)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+
+		if row.Status == models.PayoutBatchRowStatusInvalid {
+			batch.InvalidRows++
+			continue
+		}
+		batch.ValidRows++
+		batch.TotalAmount = batch.TotalAmount.Add(row.Amount)
+		batch.TotalFees = batch.TotalFees.Add(row.TransferFee)
+
+		key := strings.Join([]string{row.RecipientType, row.RecipientValue, row.RecipientIFSC, row.Amount.String()}, "|")
+		if first, ok := seen[key]; ok {
+			warnings = append(warnings, fmt.Sprintf("Rows %d and %d pay ₹%s to the same recipient", first, row.RowNumber, row.Amount.StringFixed(2)))
+		} else {
+			seen[key] = row.RowNumber
+		}
+	}
+	batch.GrandTotal = batch.TotalAmount.Add(batch.TotalFees)
+
+	// The rows were each checked on their own; check the batch as a whole
+	available, _, err := s.holdService.GetAvailableBalance(wallet)
+	if err != nil {
+		available = wallet.Balance
+	}
+	batch.AvailableBalance = available
+	batch.BalanceAfter = available.Sub(batch.GrandTotal)
+	if available.LessThan(batch.GrandTotal) {
+		warnings = append(warnings, fmt.Sprintf("Available balance ₹%s does not cover the batch total ₹%s; add funds before confirming",
+			available.StringFixed(2), batch.GrandTotal.StringFixed(2)))
+	}
+	var limitErr *LimitExceededError
+	if err := s.externalTransferService.limitsService.CheckDebit(wallet, DebitKindExternalTransfer, batch.GrandTotal); errors.As(err, &limitErr) {
+		warnings = append(warnings, fmt.Sprintf("The batch goes past the wallet's %s limit of ₹%s with ₹%s remaining; rows past it will fail",
+			limitErr.Limit, limitErr.Allowed.StringFixed(2), limitErr.Remaining.StringFixed(2)))
+	}
+	batch.Warnings = truncateField(strings.Join(warnings, "; "), 1000)
+	batch.PendingRows = batch.ValidRows
+
+	if err := s.batchRepo.Create(batch, rows); err != nil {
+		return nil, err
+	}
+	return &PayoutBatchUpload{Batch: batch, Rows: rows}, nil
+}
+
+// validateRow checks one upload row the way a single transfer is checked
+func (s *PayoutBatchService) validateRow(userID uuid.UUID, rowNumber int, input dto.PayoutBatchRowInput) (*models.PayoutBatchRow, error) {
+	row := &models.PayoutBatchRow{
+		RowNumber:      rowNumber,
+		RecipientType:  strings.ToLower(strings.TrimSpace(input.RecipientType)),
+		RecipientValue: strings.TrimSpace(input.RecipientValue),
+		RecipientIFSC:  ifsc.Normalize(input.RecipientIFSC),
+		RecipientName:  truncateField(strings.TrimSpace(input.RecipientName), 100),
+		Beneficiary:    truncateField(strings.TrimSpace(input.Beneficiary), 50),
+		Amount:         input.Amount.Round(2),
+		Description:    truncateField(strings.TrimSpace(input.Description), 500),
+		TransferFee:    decimal.Zero,
+		Status:         models.PayoutBatchRowStatusPending,
+	}
+
+	var errs []string
+	if row.Beneficiary == "" && (row.RecipientType == "" || row.RecipientValue == "") {
+		errs = append(errs, "recipient_type and recipient_value, or beneficiary, are required")
+	} else {
+		req := &dto.ValidateTransferRequest{
+			Amount:         row.Amount,
+			RecipientType:  row.RecipientType,
+			RecipientValue: row.RecipientValue,
+			RecipientIFSC:  row.RecipientIFSC,
+			BeneficiaryID:  row.Beneficiary,
+			InitiatedBy:    models.InitiatedByBatch,
+		}
+		result, err := s.externalTransferService.ValidateTransferRequest(userID.String(), req)
+		if err != nil {
+			return nil, err
+		}
+
+		// A beneficiary fills in the recipient
+		row.RecipientType = req.RecipientType
+		row.RecipientValue = req.RecipientValue
+		row.RecipientIFSC = ifsc.Normalize(req.RecipientIFSC)
+		if row.RecipientName == "" {
+			row.RecipientName = result.RecipientName
+		}
+		row.TransferFee = result.TransferFee
+		row.TransferMode = result.TransferMode
+		if row.RecipientType == models.RecipientTypePhone && row.Beneficiary == "" {
+			row.ResolvedVia = result.ResolvedVia
+			row.ResolvedVPA = result.ResolvedVPA
+		}
+
+		errs = append(errs, result.Errors...)
+		if row.RecipientName == "" {
+			errs = append(errs, "recipient_name is required")
+		}
+	}
+
+	if len(errs) > 0 {
+		row.Status = models.PayoutBatchRowStatusInvalid
+		row.Errors = truncateField(strings.Join(errs, "; "), 1000)
+	}
+	return row, nil
+}
+
+// ConfirmBatch starts paying a validated batch. The whole batch is held on
+// the wallet, so other debits cannot spend the funds its rows are paid from.
+func (s *PayoutBatchService) ConfirmBatch(userID, batchID uuid.UUID) (*models.PayoutBatch, error) {
+	var batch *models.PayoutBatch
+	expired := false
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		batch, err = s.batchRepo.GetByIDForUpdate(tx, batchID)
+		if err != nil {
+			return err
+		}
+		if batch.UserID != userID {
+			return errors.New("payout batch not found")
+		}
+		if batch.Status != models.PayoutBatchStatusPendingConfirmation {
+			return ErrPayoutBatchNotPending
+		}
+		if time.Now().After(batch.ExpiresAt) {
+			// Commit the expiry, then report it
+			expired = true
+			return s.expire(tx, batch)
+		}
+		if batch.ValidRows == 0 {
+			return errors.New("payout batch has no valid rows to pay")
+		}
+
+		wallet, err := s.walletRepo.GetByUserID(userID)
+		if err != nil {
+			return errors.New("wallet not found")
+		}
+		_, err = s.holdService.PlaceHold(tx, wallet.ID, batch.GrandTotal, models.WalletHoldPurposePayoutBatch,
+			payoutBatchHoldReference(batch.ID), "Bulk payout: "+batch.Name, PayoutBatchHoldTTL)
+		if err != nil {
+			if errors.Is(err, ErrInsufficientAvailableBalance) {
+				available, _, _ := s.holdService.GetAvailableBalance(wallet)
+				return fmt.Errorf("insufficient wallet balance: ₹%s needed, ₹%s available",
+					batch.GrandTotal.StringFixed(2), available.StringFixed(2))
+			}
+			return err
+		}
+
+		now := time.Now()
+		batch.Status = models.PayoutBatchStatusProcessing
+		batch.ConfirmedAt = &now
+		if err := s.batchRepo.Update(tx, batch); err != nil {
+			return err
+		}
+
+		dedupeKey := "payout_batch:" + batch.ID.String()
+		return s.jobQueue.Enqueue(tx, models.JobTypePayoutBatch, payoutBatchJobPayload{BatchID: batch.ID.String()}, dedupeKey, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if expired {
+		return nil, ErrPayoutBatchExpired
+	}
+	return batch, nil
+}
+
+// CancelBatch drops a batch that has not been confirmed
+func (s *PayoutBatchService) CancelBatch(userID, batchID uuid.UUID) (*models.PayoutBatch, error) {
+	var batch *models.PayoutBatch
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		batch, err = s.batchRepo.GetByIDForUpdate(tx, batchID)
+		if err != nil {
+			return err
+		}
+		if batch.UserID != userID {
+			return errors.New("payout batch not found")
+		}
+		if batch.Status != models.PayoutBatchStatusPendingConfirmation {
+			return ErrPayoutBatchNotPending
+		}
+
+		batch.Status = models.PayoutBatchStatusCancelled
+		batch.PendingRows = 0
+		if err := s.batchRepo.TransitionRows(tx, batch.ID, models.PayoutBatchRowStatusPending, models.PayoutBatchRowStatusSkipped); err != nil {
+			return err
+		}
+		return s.batchRepo.Update(tx, batch)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// GetBatches lists the user's batches
+func (s *PayoutBatchService) GetBatches(userID uuid.UUID, page, limit int) ([]*models.PayoutBatch, int64, error) {
+	batches, total, err := s.batchRepo.GetByUserID(userID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, batch := range batches {
+		s.expireIfStale(batch)
+	}
+	return batches, total, nil
+}
+
+// GetBatch returns one of the user's batches with its progress
+func (s *PayoutBatchService) GetBatch(userID, batchID uuid.UUID) (*models.PayoutBatch, error) {
+	batch, err := s.batchRepo.GetByIDAndUserID(batchID, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.expireIfStale(batch)
+	return batch, nil
+}
+
+// GetBatchRows lists a batch's rows, optionally only those in one status
+func (s *PayoutBatchService) GetBatchRows(userID, batchID uuid.UUID, status string, page, limit int) ([]*models.PayoutBatchRow, int64, error) {
+	if _, err := s.batchRepo.GetByIDAndUserID(batchID, userID); err != nil {
+		return nil, 0, err
+	}
+	return s.batchRepo.GetRows(batchID, status, limit, (page-1)*limit)
+}
+
+// ExportBatchResults writes every row of a batch and its outcome as CSV
+func (s *PayoutBatchService) ExportBatchResults(userID, batchID uuid.UUID) ([]byte, string, error) {
+	batch, err := s.GetBatch(userID, batchID)
+	if err != nil {
+		return nil, "", err
+	}
+	rows, _, err := s.batchRepo.GetRows(batch.ID, "", 0, 0)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write([]string{
+		"row", "recipient_type", "recipient_value", "recipient_ifsc", "recipient_name", "beneficiary",
+		"amount", "transfer_fee", "transfer_mode", "status", "reference_id", "errors",
+	})
+	for _, row := range rows {
+		writer.Write([]string{
+			fmt.Sprintf("%d", row.RowNumber),
+			row.RecipientType,
+			row.RecipientValue,
+			row.RecipientIFSC,
+			row.RecipientName,
+			row.Beneficiary,
+			row.Amount.StringFixed(2),
+			row.TransferFee.StringFixed(2),
+			row.TransferMode,
+			row.Status,
+			row.ReferenceID,
+			row.Errors,
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, "", fmt.Errorf("failed to write batch results: %w", err)
+	}
+
+	return buf.Bytes(), fmt.Sprintf("payout_batch_%s_results.csv", batch.ID.String()[:8]), nil
+}
+
+// handleBatchJob creates the transfers of a confirmed batch one row at a
+// time, in file order. A failing row does not stop the rest.
+func (s *PayoutBatchService) handleBatchJob(job *models.Job) error {
+	var payload payoutBatchJobPayload
+	if err := DecodeJobPayload(job, &payload); err != nil {
+		return err
+	}
+	batchID, err := uuid.Parse(payload.BatchID)
+	if err != nil {
+		return fmt.Errorf("invalid batch ID in job payload: %w", err)
+	}
+	batch, err := s.batchRepo.GetByID(batchID)
+	if err != nil {
+		return err
+	}
+	if batch.Status != models.PayoutBatchStatusProcessing {
+		return nil
+	}
+
+	rows, _, err := s.batchRepo.GetRows(batch.ID, "", 0, 0)
+	if err != nil {
+		return err
+	}
+
+	// The batch hold keeps the share of the rows still to be paid
+	holdRef := payoutBatchHoldReference(batch.ID)
+	unpaid := decimal.Zero
+	for _, row := range rows {
+		if row.Status == models.PayoutBatchRowStatusPending {
+			unpaid = unpaid.Add(row.Amount).Add(row.TransferFee)
+		}
+	}
+
+	for _, row := range rows {
+		switch row.Status {
+		case models.PayoutBatchRowStatusPending:
+			unpaid = unpaid.Sub(row.Amount).Sub(row.TransferFee)
+			s.payRow(batch, row)
+			if err := s.holdService.ReduceHoldTo(nil, holdRef, unpaid, "batch row paid"); err != nil {
+				utils.LogError(err, map[string]interface{}{"batch_id": batch.ID.String(), "action": "reduce_payout_batch_hold"})
+			}
+		case models.PayoutBatchRowStatusProcessing:
+			// An earlier attempt stopped while creating this row's transfer.
+			// It may exist, so the row is not paid again.
+			if time.Since(row.UpdatedAt) > PayoutBatchRowStaleAfter {
+				s.finishRow(row, models.PayoutBatchRowStatusFailed, "interrupted while the transfer was being created; check your transfers before paying this row again")
+			}
+		}
+	}
+
+	if err := s.holdService.ReduceHoldTo(nil, holdRef, decimal.Zero, "batch rows paid"); err != nil {
+		utils.LogError(err, map[string]interface{}{"batch_id": batch.ID.String(), "action": "release_payout_batch_hold"})
+	}
+	return s.refreshBatch(batch.ID)
+}
+
+// handleBatchDead fails the rows of a batch whose job could not be handled
+// and releases what is left of its hold
+func (s *PayoutBatchService) handleBatchDead(job *models.Job, err error) {
+	utils.LogError(err, map[string]interface{}{"job_id": job.ID.String(), "action": "payout_batch_dead"})
+
+	var payload payoutBatchJobPayload
+	if DecodeJobPayload(job, &payload) != nil {
+		return
+	}
+	batchID, parseErr := uuid.Parse(payload.BatchID)
+	if parseErr != nil {
+		return
+	}
+	rows, _, getErr := s.batchRepo.GetRows(batchID, models.PayoutBatchRowStatusPending, 0, 0)
+	if getErr != nil {
+		return
+	}
+	for _, row := range rows {
+		s.finishRow(row, models.PayoutBatchRowStatusFailed, "batch processing stopped: "+err.Error())
+	}
+	if releaseErr := s.holdService.ReduceHoldTo(nil, payoutBatchHoldReference(batchID), decimal.Zero, "batch processing stopped"); releaseErr != nil {
+		utils.LogError(releaseErr, map[string]interface{}{"batch_id": batchID.String(), "action": "release_payout_batch_hold"})
+	}
+	if refreshErr := s.refreshBatch(batchID); refreshErr != nil {
+		utils.LogError(refreshErr, map[string]interface{}{"batch_id": batchID.String(), "action": "refresh_payout_batch"})
+	}
+}
+
+// payRow creates the transfer for one row. Once claimed, a row is never
+// retried, since a transfer that errored part way must not be paid twice.
+func (s *PayoutBatchService) payRow(batch *models.PayoutBatch, row *models.PayoutBatchRow) {
+	if err := s.batchRepo.TransitionRow(row.ID, []string{models.PayoutBatchRowStatusPending}, models.PayoutBatchRowStatusProcessing); err != nil {
+		if !errors.Is(err, repositories.ErrBatchRowStatusChanged) {
+			utils.LogError(err, map[string]interface{}{"row_id": row.ID.String(), "action": "claim_payout_batch_row"})
+		}
+		return
+	}
+	row.Status = models.PayoutBatchRowStatusProcessing
+
+	description := row.Description
+	if description == "" {
+		description = "Bulk payout: " + batch.Name
+	}
+
+	// Confirming the batch does not confirm a transfer the risk checks ask
+	// about; such a row fails and can be paid on its own once confirmed
+	result, err := s.externalTransferService.CreateExternalTransfer(batch.UserID.String(), &dto.CreateExternalTransferRequest{
+		Amount:         row.Amount,
+		Currency:       "INR",
+		Description:    description,
+		RecipientType:  row.RecipientType,
+		RecipientValue: row.RecipientValue,
+		RecipientIFSC:  row.RecipientIFSC,
+		RecipientName:  row.RecipientName,
+		BeneficiaryID:  row.Beneficiary,
+		ResolvedVia:    row.ResolvedVia,
+		ResolvedVPA:    row.ResolvedVPA,
+		InitiatedBy:    models.InitiatedByBatch,
+		FundingHold:    payoutBatchHoldReference(batch.ID),
+	})
+	if err != nil {
+		s.finishRow(row, models.PayoutBatchRowStatusFailed, err.Error())
+		return
+	}
+
+	// A phone number of a Tranza user was paid straight into their wallet
+	if result.WalletTransactionID != "" {
+		row.ReferenceID = result.ReferenceID
+		row.TransferFee = result.TransferFee
+		row.TransferMode = result.TransferMode
+		s.finishRow(row, models.PayoutBatchRowStatusSuccess, "")
+		return
+	}
+
+	transferID, err := uuid.Parse(result.ID)
+	if err != nil {
+		s.finishRow(row, models.PayoutBatchRowStatusFailed, "invalid transfer ID")
+		return
+	}
+	row.Status = models.PayoutBatchRowStatusSubmitted
+	row.TransferID = &transferID
+	row.ReferenceID = result.ReferenceID
+	row.TransferFee = result.TransferFee
+	row.TransferMode = result.TransferMode
+	if err := s.batchRepo.UpdateRow(row); err != nil {
+		utils.LogError(err, map[string]interface{}{"row_id": row.ID.String(), "action": "save_payout_batch_row"})
+		return
+	}
+
+	// The transfer may have finished before the row recorded it
+	if transfer, err := s.externalTransferRepo.GetByID(transferID); err == nil && transfer.IsCompleted() {
+		s.applyTransferOutcome(row, transfer)
+	}
+}
+
+// handleTransferComplete records the outcome of a batch row's transfer
+func (s *PayoutBatchService) handleTransferComplete(transfer *models.ExternalTransfer) {
+	if transfer.InitiatedBy != models.InitiatedByBatch {
+		return
+	}
+
+	row, err := s.batchRepo.GetRowByTransferID(transfer.ID)
+	if err != nil {
+		// The row records its transfer right after creating it and checks
+		// for an early outcome then
+		return
+	}
+	s.applyTransferOutcome(row, transfer)
+	if err := s.refreshBatch(row.BatchID); err != nil {
+		utils.LogError(err, map[string]interface{}{"batch_id": row.BatchID.String(), "action": "refresh_payout_batch"})
+	}
+}
+
+func (s *PayoutBatchService) applyTransferOutcome(row *models.PayoutBatchRow, transfer *models.ExternalTransfer) {
+	switch transfer.Status {
+	case models.ExternalTransferStatusSuccess:
+		if row.Status == models.PayoutBatchRowStatusSubmitted {
+			s.finishRow(row, models.PayoutBatchRowStatusSuccess, "")
+		}
+	case models.ExternalTransferStatusFailed, models.ExternalTransferStatusCancelled, models.ExternalTransferStatusRefunded:
+		// A payout can be reversed after it succeeded
+		if row.Status == models.PayoutBatchRowStatusSubmitted || row.Status == models.PayoutBatchRowStatusSuccess {
+			reason := transfer.FailureReason
+			if reason == "" {
+				reason = "transfer " + transfer.Status
+			}
+			s.finishRow(row, models.PayoutBatchRowStatusFailed, reason)
+		}
+	}
+}
+
+// finishRow records a row's final status
+func (s *PayoutBatchService) finishRow(row *models.PayoutBatchRow, status, reason string) {
+	now := time.Now()
+	row.Status = status
+	row.CompletedAt = &now
+	if reason != "" {
+		row.Errors = truncateField(reason, 1000)
+	}
+	if err := s.batchRepo.UpdateRow(row); err != nil {
+		utils.LogError(err, map[string]interface{}{"row_id": row.ID.String(), "action": "finish_payout_batch_row"})
+	}
+}
+
+// refreshBatch recounts a batch's rows and completes the batch, telling the
+// user, once no valid row is still in flight
+func (s *PayoutBatchService) refreshBatch(batchID uuid.UUID) error {
+	totals, err := s.batchRepo.GetRowTotals(batchID)
+	if err != nil {
+		return err
+	}
+
+	var completed *models.PayoutBatch
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		batch, err := s.batchRepo.GetByIDForUpdate(tx, batchID)
+		if err != nil {
+			return err
+		}
+
+		batch.SucceededRows, batch.FailedRows, batch.PendingRows = 0, 0, 0
+		batch.PaidAmount = decimal.Zero
+		for _, total := range totals {
+			switch total.Status {
+			case models.PayoutBatchRowStatusSuccess:
+				batch.SucceededRows += total.Count
+				batch.PaidAmount = batch.PaidAmount.Add(total.Amount)
+			case models.PayoutBatchRowStatusFailed:
+				batch.FailedRows += total.Count
+			case models.PayoutBatchRowStatusPending, models.PayoutBatchRowStatusProcessing, models.PayoutBatchRowStatusSubmitted:
+				batch.PendingRows += total.Count
+			}
+		}
+
+		if batch.Status == models.PayoutBatchStatusProcessing && batch.PendingRows == 0 {
+			now := time.Now()
+			batch.Status = models.PayoutBatchStatusCompleted
+			batch.CompletedAt = &now
+			completed = batch
+		}
+		return s.batchRepo.Update(tx, batch)
+	})
+	if err != nil {
+		return err
+	}
+
+	if completed != nil {
+		go s.notificationService.SendPayoutBatchNotification(
+			completed.UserID.String(), completed.Name, completed.SucceededRows, completed.FailedRows, completed.PaidAmount)
+	}
+	return nil
+}
+
+// expireIfStale expires a batch left waiting past its confirmation window
+func (s *PayoutBatchService) expireIfStale(batch *models.PayoutBatch) {
+	if batch.Status != models.PayoutBatchStatusPendingConfirmation || time.Now().Before(batch.ExpiresAt) {
+		return
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		return s.expire(tx, batch)
+	})
+	if err != nil {
+		utils.LogError(err, map[string]interface{}{"batch_id": batch.ID.String(), "action": "expire_payout_batch"})
+	}
+}
+
+func (s *PayoutBatchService) expire(tx *gorm.DB, batch *models.PayoutBatch) error {
+	batch.Status = models.PayoutBatchStatusExpired
+	batch.PendingRows = 0
+	if err := s.batchRepo.TransitionRows(tx, batch.ID, models.PayoutBatchRowStatusPending, models.PayoutBatchRowStatusSkipped); err != nil {
+		return err
+	}
+	return s.batchRepo.Update(tx, batch)
+}
+
+// payoutBatchHoldReference is the reference of the hold placed for a batch
+func payoutBatchHoldReference(batchID uuid.UUID) string {
+	return "payout_batch:" + batchID.String()
+}
+
+// truncateField cuts s to at most n characters to fit its column
+func truncateField(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
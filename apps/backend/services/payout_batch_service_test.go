@@ -0,0 +1,235 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/pkg/razorpay"
+	"github.com/zeusnotfound04/Tranza/pkg/razorpay/fake"
+	"gorm.io/gorm"
+)
+
+// confirmTestBatch uploads and confirms a batch paying each amount to its
+// own UPI ID
+func confirmTestBatch(t *testing.T, s *testServices, wallet *models.Wallet, amounts ...string) *models.PayoutBatch {
+	t.Helper()
+
+	inputs := make([]dto.PayoutBatchRowInput, len(amounts))
+	for i, amount := range amounts {
+		inputs[i] = dto.PayoutBatchRowInput{
+			RecipientType:  models.RecipientTypeUPI,
+			RecipientValue: "payee" + string(rune('a'+i)) + "@upi",
+			RecipientName:  "Payee",
+			Amount:         decimal.RequireFromString(amount),
+		}
+	}
+	upload, err := s.batches.CreateBatch(wallet.UserID, "Test batch", "batch.csv", inputs)
+	if err != nil {
+		t.Fatalf("CreateBatch: %v", err)
+	}
+	if upload.Batch.InvalidRows != 0 {
+		t.Fatalf("%d invalid rows: %+v", upload.Batch.InvalidRows, upload.Rows)
+	}
+	batch, err := s.batches.ConfirmBatch(wallet.UserID, upload.Batch.ID)
+	if err != nil {
+		t.Fatalf("ConfirmBatch: %v", err)
+	}
+	return batch
+}
+
+// batchRowStatuses lists a batch's row statuses in file order
+func batchRowStatuses(t *testing.T, s *testServices, batch *models.PayoutBatch) []string {
+	t.Helper()
+
+	rows, _, err := s.batches.GetBatchRows(batch.UserID, batch.ID, "", 0, 0)
+	if err != nil {
+		t.Fatalf("GetBatchRows: %v", err)
+	}
+	statuses := make([]string, len(rows))
+	for i, row := range rows {
+		statuses[i] = row.Status
+		if row.Errors != "" {
+			statuses[i] += ": " + row.Errors
+		}
+	}
+	return statuses
+}
+
+func TestPayoutBatchJustUnderDailyLimit(t *testing.T) {
+	db := testDB(t)
+	s, _ := newTestServicesWithRazorpay(t, db, fake.Config{})
+	wallet := createTestWallet(t, db, "100000", "50000")
+
+	// The batch hold counts against the limit until each row's transfer
+	// takes over its share, so no row may count its money twice
+	batch := confirmTestBatch(t, s, wallet, "10000", "20000", "15000")
+	if batch.GrandTotal.GreaterThan(wallet.DailyLimit) {
+		t.Fatalf("batch total %s is over the daily limit", batch.GrandTotal)
+	}
+	requireHold(t, s, payoutBatchHoldReference(batch.ID), models.WalletHoldStatusActive, batch.GrandTotal.String())
+
+	if n := s.runJobs(t, models.JobTypePayoutBatch); n != 1 {
+		t.Fatalf("ran %d batch jobs, want 1", n)
+	}
+	for i, status := range batchRowStatuses(t, s, batch) {
+		if status != models.PayoutBatchRowStatusSubmitted {
+			t.Fatalf("row %d is %s, want submitted", i+1, status)
+		}
+	}
+	if hold, err := s.holds.GetHold(payoutBatchHoldReference(batch.ID)); err != nil || hold.Status != models.WalletHoldStatusReleased {
+		t.Fatalf("batch hold = %+v, %v; want it released", hold, err)
+	}
+	requireAvailable(t, s, wallet, decimal.NewFromInt(100000).Sub(batch.GrandTotal).String())
+}
+
+// requireBatch checks a batch's status and row counts
+func requireBatch(t *testing.T, s *testServices, batchID uuid.UUID, status string, succeeded, failed int) {
+	t.Helper()
+
+	var batch models.PayoutBatch
+	if err := s.db.First(&batch, "id = ?", batchID).Error; err != nil {
+		t.Fatalf("failed to load batch: %v", err)
+	}
+	if batch.Status != status || batch.SucceededRows != succeeded || batch.FailedRows != failed {
+		t.Fatalf("batch is %s with %d succeeded and %d failed rows, want %s with %d and %d",
+			batch.Status, batch.SucceededRows, batch.FailedRows, status, succeeded, failed)
+	}
+}
+
+// setRowStatus moves a batch row to status as of the given time, as a worker
+// that stopped part way would have left it
+func setRowStatus(t *testing.T, s *testServices, batch *models.PayoutBatch, rowNumber int, status string, at time.Time) {
+	t.Helper()
+
+	err := s.db.Model(&models.PayoutBatchRow{}).
+		Where("batch_id = ? AND row_number = ?", batch.ID, rowNumber).
+		UpdateColumns(map[string]interface{}{"status": status, "updated_at": at}).Error
+	if err != nil {
+		t.Fatalf("failed to update row %d: %v", rowNumber, err)
+	}
+}
+
+// submitBeforeRecorded submits a new transfer's payout as its batch row or
+// scheduled run is being saved with it, so the payout's outcome arrives
+// before the transfer is recorded against the row or run
+func submitBeforeRecorded(t *testing.T, s *testServices) {
+	t.Helper()
+
+	err := s.db.Callback().Update().Before("gorm:update").Register("test:submit_before_recorded", func(tx *gorm.DB) {
+		switch dest := tx.Statement.Dest.(type) {
+		case *models.PayoutBatchRow:
+			if dest.Status != models.PayoutBatchRowStatusSubmitted {
+				return
+			}
+		case *models.ScheduledPaymentRun:
+			if dest.Status != models.ScheduledRunStatusSubmitted {
+				return
+			}
+		default:
+			return
+		}
+		s.runJobs(t, models.JobTypePayoutSubmit)
+	})
+	if err != nil {
+		t.Fatalf("failed to register callback: %v", err)
+	}
+}
+
+func TestPayoutBatchRowSettledBeforeItRecordsItsTransfer(t *testing.T) {
+	db := testDB(t)
+	s, server := newTestServicesWithRazorpay(t, db, fake.Config{})
+	server.SetPayoutScript("payeea@upi", fake.PayoutStep{Status: razorpay.PayoutStatusProcessed})
+	server.SetPayoutScript("payeeb@upi", fake.PayoutStep{Status: razorpay.PayoutStatusFailed, FailureReason: "beneficiary bank down"})
+	wallet := createTestWallet(t, db, "100000", "50000")
+	batch := confirmTestBatch(t, s, wallet, "1000", "2000")
+
+	// Each transfer finishes while its row is still being saved, so the
+	// completion handler finds no row and the row settles itself
+	submitBeforeRecorded(t, s)
+	s.runJobs(t, models.JobTypePayoutBatch)
+
+	statuses := batchRowStatuses(t, s, batch)
+	if statuses[0] != models.PayoutBatchRowStatusSuccess || statuses[1] != models.PayoutBatchRowStatusFailed+": beneficiary bank down" {
+		t.Fatalf("rows are %q, want success and the payout's failure", statuses)
+	}
+	requireBatch(t, s, batch.ID, models.PayoutBatchStatusCompleted, 1, 1)
+	requireAvailable(t, s, wallet, walletBalance(t, db, wallet.ID).String())
+}
+
+func TestPayoutBatchInterruptedRows(t *testing.T) {
+	db := testDB(t)
+	s, server := newTestServicesWithRazorpay(t, db, fake.Config{})
+	server.SetPayoutScript("", fake.PayoutStep{Status: razorpay.PayoutStatusProcessed})
+	wallet := createTestWallet(t, db, "100000", "50000")
+	batch := confirmTestBatch(t, s, wallet, "1000", "2000", "3000")
+
+	// A worker stopped while creating row 1's transfer; another may still be
+	// creating row 2's
+	setRowStatus(t, s, batch, 1, models.PayoutBatchRowStatusProcessing, time.Now().Add(-2*PayoutBatchRowStaleAfter))
+	setRowStatus(t, s, batch, 2, models.PayoutBatchRowStatusProcessing, time.Now())
+
+	s.runJobs(t, models.JobTypePayoutBatch)
+	statuses := batchRowStatuses(t, s, batch)
+	if !strings.HasPrefix(statuses[0], models.PayoutBatchRowStatusFailed+": interrupted") ||
+		statuses[1] != models.PayoutBatchRowStatusProcessing ||
+		statuses[2] != models.PayoutBatchRowStatusSubmitted {
+		t.Fatalf("rows are %q, want the stale row failed, the fresh one left alone and the pending one paid", statuses)
+	}
+	if n := countRows(t, db, &models.ExternalTransfer{}); n != 1 {
+		t.Fatalf("%d transfers made, want 1 for the pending row only", n)
+	}
+
+	// Row 2's worker never came back, and the batch job is delivered again
+	setRowStatus(t, s, batch, 2, models.PayoutBatchRowStatusProcessing, time.Now().Add(-2*PayoutBatchRowStaleAfter))
+	s.redeliverJobs(t, models.JobTypePayoutBatch)
+	s.runJobs(t, models.JobTypePayoutBatch)
+	s.runJobs(t, models.JobTypePayoutSubmit)
+
+	if n := countRows(t, db, &models.ExternalTransfer{}); n != 1 {
+		t.Fatalf("%d transfers made, want interrupted rows left unpaid", n)
+	}
+	requireBatch(t, s, batch.ID, models.PayoutBatchStatusCompleted, 1, 2)
+}
+
+func TestPayoutBatchPaysEachRowOnce(t *testing.T) {
+	db := testDB(t)
+	s, server := newTestServicesWithRazorpay(t, db, fake.Config{})
+	server.SetPayoutScript("", fake.PayoutStep{Status: razorpay.PayoutStatusProcessed})
+	wallet := createTestWallet(t, db, "100000", "50000")
+	batch := confirmTestBatch(t, s, wallet, "1000", "2000")
+
+	if _, err := s.batches.ConfirmBatch(wallet.UserID, batch.ID); !errors.Is(err, ErrPayoutBatchNotPending) {
+		t.Fatalf("second ConfirmBatch = %v, want ErrPayoutBatchNotPending", err)
+	}
+	if n := countJobs(t, db, models.JobTypePayoutBatch); n != 1 {
+		t.Fatalf("%d batch jobs queued, want 1", n)
+	}
+
+	// The worker dies after paying the rows and the job runs again
+	s.runJobs(t, models.JobTypePayoutBatch)
+	s.redeliverJobs(t, models.JobTypePayoutBatch)
+	if n := s.runJobs(t, models.JobTypePayoutBatch); n != 1 {
+		t.Fatalf("ran %d redelivered batch jobs, want 1", n)
+	}
+	if n := countRows(t, db, &models.ExternalTransfer{}); n != 2 {
+		t.Fatalf("%d transfers made, want one per row", n)
+	}
+
+	s.runJobs(t, models.JobTypePayoutSubmit)
+	requireBatch(t, s, batch.ID, models.PayoutBatchStatusCompleted, 2, 0)
+
+	// Once the batch is complete a late delivery changes nothing
+	s.redeliverJobs(t, models.JobTypePayoutBatch)
+	s.runJobs(t, models.JobTypePayoutBatch)
+	if n := countRows(t, db, &models.ExternalTransfer{}); n != 2 {
+		t.Fatalf("%d transfers made, want one per row", n)
+	}
+	requireBatch(t, s, batch.ID, models.PayoutBatchStatusCompleted, 2, 0)
+	requireAvailable(t, s, wallet, walletBalance(t, db, wallet.ID).String())
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/pkg/razorpay"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Indian mobile numbers: 10 digits starting with 6-9
+var phoneNumberRegex = regexp.MustCompile(`^[6-9]\d{9}$`)
+
+var (
+	ErrInvalidPhoneNumber = errors.New("invalid phone number format")
+	ErrPhoneTaken         = errors.New("phone number is verified on another account")
+	ErrPhoneAlreadyOwned  = errors.New("phone number is already verified on your account")
+	ErrVPANotFound        = errors.New("UPI ID not found; check it and try again")
+)
+
+// PhoneService verifies users' own mobile numbers, so that phone transfers to
+// them credit their Tranza wallet, and keeps the UPI IDs users save for other
+// people's numbers
+type PhoneService struct {
+	phoneVerificationRepo *repositories.PhoneVerificationRepository
+	phoneVPARepo          *repositories.PhoneVPARepository
+	userRepo              repositories.UserRepository
+	emailService          *EmailService
+	notificationService   *NotificationService
+	razorpayClient        *razorpay.Client
+}
+
+func NewPhoneService(
+	phoneVerificationRepo *repositories.PhoneVerificationRepository,
+	phoneVPARepo *repositories.PhoneVPARepository,
+	userRepo repositories.UserRepository,
+	emailService *EmailService,
+	notificationService *NotificationService,
+	razorpayClient *razorpay.Client,
+) *PhoneService {
+	return &PhoneService{
+		phoneVerificationRepo: phoneVerificationRepo,
+		phoneVPARepo:          phoneVPARepo,
+		userRepo:              userRepo,
+		emailService:          emailService,
+		notificationService:   notificationService,
+		razorpayClient:        razorpayClient,
+	}
+}
+
+// StartPhoneVerification texts a code to a mobile number the user wants to add
+// to their account
+func (s *PhoneService) StartPhoneVerification(ctx context.Context, userID uuid.UUID, phone string) (*dto.PhoneVerificationResponse, error) {
+	phone = strings.TrimSpace(phone)
+	if !phoneNumberRegex.MatchString(phone) {
+		return nil, ErrInvalidPhoneNumber
+	}
+	if err := s.checkPhoneAvailable(ctx, userID, phone); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.phoneVerificationRepo.GetVerificationByUserID(ctx, userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to check existing verification: %w", err)
+	}
+	if existing != nil {
+		if existing.Phone == phone && time.Since(existing.UpdatedAt) < ResendCooldown {
+			return nil, ErrResendCooldown
+		}
+		if err := s.phoneVerificationRepo.DeleteVerification(ctx, userID); err != nil {
+			return nil, fmt.Errorf("failed to delete old verification: %w", err)
+		}
+	}
+
+	code, err := s.emailService.GenerateVerificationCode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate verification code: %w", err)
+	}
+	hashedCode, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash verification code: %w", err)
+	}
+
+	verification := &models.PhoneVerification{
+		UserID:    userID,
+		Phone:     phone,
+		Code:      string(hashedCode),
+		ExpiresAt: time.Now().Add(VerificationExpiration),
+	}
+	if err := s.phoneVerificationRepo.CreateVerification(ctx, verification); err != nil {
+		return nil, fmt.Errorf("failed to create verification record: %w", err)
+	}
+
+	if err := s.notificationService.SendPhoneVerificationCode(ctx, phone, code); err != nil {
+		s.phoneVerificationRepo.DeleteVerification(ctx, userID)
+		return nil, fmt.Errorf("failed to send verification code: %w", err)
+	}
+
+	return &dto.PhoneVerificationResponse{
+		Phone:     phone,
+		ExpiresAt: verification.ExpiresAt,
+	}, nil
+}
+
+// VerifyPhone checks the code sent to the user's new mobile number and adds
+// the number to their account
+func (s *PhoneService) VerifyPhone(ctx context.Context, userID uuid.UUID, code string) (*models.User, error) {
+	verification, err := s.phoneVerificationRepo.GetVerificationByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVerificationNotFound
+		}
+		return nil, fmt.Errorf("failed to get verification record: %w", err)
+	}
+
+	if time.Now().After(verification.ExpiresAt) {
+		s.phoneVerificationRepo.DeleteVerification(ctx, userID)
+		return nil, ErrVerificationExpired
+	}
+	if verification.Attempts >= MaxVerificationAttempts {
+		return nil, ErrTooManyAttempts
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(verification.Code), []byte(code)); err != nil {
+		s.phoneVerificationRepo.IncrementAttempts(ctx, userID)
+		return nil, ErrInvalidVerificationCode
+	}
+
+	// Another account may have verified the number in the meantime
+	if err := s.checkPhoneAvailable(ctx, userID, verification.Phone); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.UpdatePhone(ctx, userID, &verification.Phone); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) || strings.Contains(err.Error(), "duplicate key") {
+			return nil, ErrPhoneTaken
+		}
+		return nil, fmt.Errorf("failed to save phone number: %w", err)
+	}
+
+	if err := s.phoneVerificationRepo.DeleteVerification(ctx, userID); err != nil {
+		// Log error but don't fail the operation
+		log.Printf("Warning: failed to delete phone verification record: %v", err)
+	}
+
+	return s.userRepo.FindByID(ctx, userID)
+}
+
+// RemovePhone takes the user's mobile number off their account; phone
+// transfers to it are paid by UPI again
+func (s *PhoneService) RemovePhone(ctx context.Context, userID uuid.UUID) error {
+	if err := s.userRepo.UpdatePhone(ctx, userID, nil); err != nil {
+		return fmt.Errorf("failed to remove phone number: %w", err)
+	}
+	return nil
+}
+
+// checkPhoneAvailable makes sure no account, the user's included, has
+// verified the number yet
+func (s *PhoneService) checkPhoneAvailable(ctx context.Context, userID uuid.UUID, phone string) error {
+	owner, err := s.userRepo.FindByPhone(ctx, phone)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check phone number: %w", err)
+	}
+	if owner.ID == userID {
+		return ErrPhoneAlreadyOwned
+	}
+	return ErrPhoneTaken
+}
+
+// SavePhoneVPA saves the UPI ID phone transfers to a number should be paid
+// at, after Razorpay confirms the UPI ID exists
+func (s *PhoneService) SavePhoneVPA(userID uuid.UUID, req *dto.SavePhoneVPARequest) (*models.PhoneVPA, error) {
+	phone := strings.TrimSpace(req.Phone)
+	vpa := strings.TrimSpace(req.VPA)
+	if !phoneNumberRegex.MatchString(phone) {
+		return nil, ErrInvalidPhoneNumber
+	}
+	if !upiIDRegex.MatchString(vpa) {
+		return nil, errors.New("invalid UPI ID format")
+	}
+
+	result, err := s.razorpayClient.ValidateVPA(vpa)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check UPI ID: %w", err)
+	}
+	if !result.Success {
+		return nil, ErrVPANotFound
+	}
+
+	mapping, err := s.phoneVPARepo.GetByPhone(userID, phone)
+	if err != nil {
+		if !errors.Is(err, repositories.ErrPhoneVPANotFound) {
+			return nil, err
+		}
+		mapping = &models.PhoneVPA{UserID: userID, Phone: phone, VPA: vpa, Name: result.CustomerName}
+		if err := s.phoneVPARepo.Create(mapping); err != nil {
+			return nil, err
+		}
+		return mapping, nil
+	}
+
+	mapping.VPA = vpa
+	mapping.Name = result.CustomerName
+	if err := s.phoneVPARepo.Update(mapping); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// GetPhoneVPAs lists the UPI IDs the user saved for phone numbers
+func (s *PhoneService) GetPhoneVPAs(userID uuid.UUID) ([]*models.PhoneVPA, error) {
+	return s.phoneVPARepo.GetByUserID(userID)
+}
+
+// DeletePhoneVPA removes a saved phone UPI ID
+func (s *PhoneService) DeletePhoneVPA(userID, id uuid.UUID) error {
+	return s.phoneVPARepo.Delete(id, userID)
+}
@@ -24,17 +24,10 @@ type RazorpayService struct {
 func NewRazorpayService() *RazorpayService {
 	cfg := config.LoadConfig()
 	
-	baseURL := "https://api.razorpay.com/v1"
-	if cfg.Environment == "test" {
-		// Razorpay uses the same URL for both test and live, 
-		// the environment is determined by the API keys
-		baseURL = "https://api.razorpay.com/v1"
-	}
-	
 	return &RazorpayService{
 		KeyID:     cfg.RazorpayKeyID,
 		KeySecret: cfg.RazorpayKeySecret,
-		BaseURL:   baseURL,
+		BaseURL:   cfg.GetBaseURL(),
 		Client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/pkg/razorpay"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"github.com/zeusnotfound04/Tranza/utils"
+	"gorm.io/gorm"
+)
+
+// Where a phone number recipient was found
+const (
+	RecipientSourceTranza      = "tranza"      // A Tranza user verified the number; their wallet is credited
+	RecipientSourceSaved       = "saved"       // UPI ID the user saved for the number
+	RecipientSourceUPILookup   = "upi_lookup"  // UPI ID of the number found with Razorpay
+	RecipientSourceBeneficiary = "beneficiary" // Saved beneficiary with its own Razorpay fund account
+)
+
+// PhoneLookupCacheTTL is how long a Razorpay UPI ID lookup for a phone number is reused
+const PhoneLookupCacheTTL = 10 * time.Minute
+
+var ErrRecipientNotResolved = errors.New("no UPI ID found for this phone number; save one with POST /api/v1/phone-vpas or pay the UPI ID directly")
+
+// ErrRecipientChanged is returned when a phone number no longer resolves to
+// the recipient the user confirmed
+var ErrRecipientChanged = errors.New("this phone number now belongs to a different recipient; validate the transfer again and confirm the new recipient")
+
+// ResolvedRecipient is who a phone number belongs to
+type ResolvedRecipient struct {
+	Phone    string
+	Name     string     // Shown to the user to confirm before any money moves
+	VPA      string     // UPI ID to pay; empty for Tranza users
+	Source   string     // One of the RecipientSource constants
+	UserID   *uuid.UUID // Tranza user the number belongs to
+	WalletID *uuid.UUID // Their wallet
+}
+
+// IsTranzaUser reports whether the recipient is paid wallet to wallet
+func (r *ResolvedRecipient) IsTranzaUser() bool {
+	return r.Source == RecipientSourceTranza && r.WalletID != nil
+}
+
+// Matches reports whether the recipient is still the one the user confirmed
+// from validation: the wallet of a Tranza user when confirmedVia is tranza,
+// otherwise the UPI ID confirmedVPA
+func (r *ResolvedRecipient) Matches(confirmedVia, confirmedVPA string) bool {
+	if confirmedVia == RecipientSourceTranza {
+		return r.IsTranzaUser()
+	}
+	return !r.IsTranzaUser() && strings.EqualFold(r.VPA, strings.TrimSpace(confirmedVPA))
+}
+
+// RecipientResolver finds who a phone number belongs to. It returns
+// ErrRecipientNotResolved when it does not know the number.
+type RecipientResolver interface {
+	Resolve(userID uuid.UUID, phone string) (*ResolvedRecipient, error)
+}
+
+// PhoneResolver asks each resolver in turn and uses the first that knows the
+// number. userID is the user paying, whose saved UPI IDs are consulted.
+type PhoneResolver struct {
+	resolvers []RecipientResolver
+}
+
+func NewPhoneResolver(resolvers ...RecipientResolver) *PhoneResolver {
+	return &PhoneResolver{
+		resolvers: resolvers,
+	}
+}
+
+// Resolve finds the Tranza user or UPI ID a phone number belongs to
+func (r *PhoneResolver) Resolve(userID uuid.UUID, phone string) (*ResolvedRecipient, error) {
+	return r.resolve(userID, phone, true)
+}
+
+// ResolveVPA finds the UPI ID a phone number belongs to, passing over Tranza
+// users, for payouts that can only go out by UPI
+func (r *PhoneResolver) ResolveVPA(userID uuid.UUID, phone string) (*ResolvedRecipient, error) {
+	return r.resolve(userID, phone, false)
+}
+
+func (r *PhoneResolver) resolve(userID uuid.UUID, phone string, wallets bool) (*ResolvedRecipient, error) {
+	for _, resolver := range r.resolvers {
+		recipient, err := resolver.Resolve(userID, phone)
+		if errors.Is(err, ErrRecipientNotResolved) {
+			continue
+		}
+		if err != nil {
+			// A resolver that could not answer must not let a later one pick
+			// a different UPI ID than it would have
+			return nil, err
+		}
+		if recipient.VPA == "" && !(wallets && recipient.IsTranzaUser()) {
+			continue
+		}
+		return recipient, nil
+	}
+	return nil, ErrRecipientNotResolved
+}
+
+// TranzaUserResolver finds the Tranza user who verified a phone number. A
+// user paying their own number is left to the other resolvers.
+type TranzaUserResolver struct {
+	userRepo   repositories.UserRepository
+	walletRepo *repositories.WalletRepository
+}
+
+func NewTranzaUserResolver(userRepo repositories.UserRepository, walletRepo *repositories.WalletRepository) *TranzaUserResolver {
+	return &TranzaUserResolver{
+		userRepo:   userRepo,
+		walletRepo: walletRepo,
+	}
+}
+
+func (r *TranzaUserResolver) Resolve(userID uuid.UUID, phone string) (*ResolvedRecipient, error) {
+	user, err := r.userRepo.FindByPhone(context.Background(), phone)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRecipientNotResolved
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up phone number: %w", err)
+	}
+	if user.ID == userID || !user.IsActive {
+		return nil, ErrRecipientNotResolved
+	}
+
+	wallet, err := r.walletRepo.GetByUserID(user.ID)
+	if err != nil || wallet.Status != "active" {
+		return nil, ErrRecipientNotResolved
+	}
+
+	return &ResolvedRecipient{
+		Phone:    phone,
+		Name:     user.Username,
+		Source:   RecipientSourceTranza,
+		UserID:   &user.ID,
+		WalletID: &wallet.ID,
+	}, nil
+}
+
+// SavedVPAResolver returns the UPI ID the paying user saved for a phone number
+type SavedVPAResolver struct {
+	phoneVPARepo *repositories.PhoneVPARepository
+}
+
+func NewSavedVPAResolver(phoneVPARepo *repositories.PhoneVPARepository) *SavedVPAResolver {
+	return &SavedVPAResolver{
+		phoneVPARepo: phoneVPARepo,
+	}
+}
+
+func (r *SavedVPAResolver) Resolve(userID uuid.UUID, phone string) (*ResolvedRecipient, error) {
+	mapping, err := r.phoneVPARepo.GetByPhone(userID, phone)
+	if err != nil {
+		if errors.Is(err, repositories.ErrPhoneVPANotFound) {
+			return nil, ErrRecipientNotResolved
+		}
+		return nil, err
+	}
+
+	return &ResolvedRecipient{
+		Phone:  phone,
+		Name:   mapping.Name,
+		VPA:    mapping.VPA,
+		Source: RecipientSourceSaved,
+	}, nil
+}
+
+// UPILookupResolver looks for the UPI ID UPI apps create from a phone number,
+// e.g. 9876543210@ybl, checking each handle with Razorpay in order. Results
+// are kept for PhoneLookupCacheTTL so bulk payouts do not repeat lookups.
+type UPILookupResolver struct {
+	razorpayClient *razorpay.Client
+	handles        []string
+
+	mu    sync.Mutex
+	cache map[string]upiLookup
+}
+
+type upiLookup struct {
+	vpa       string // Empty when no handle knew the number
+	name      string
+	expiresAt time.Time
+}
+
+func NewUPILookupResolver(razorpayClient *razorpay.Client, handles []string) *UPILookupResolver {
+	return &UPILookupResolver{
+		razorpayClient: razorpayClient,
+		handles:        handles,
+		cache:          make(map[string]upiLookup),
+	}
+}
+
+func (r *UPILookupResolver) Resolve(userID uuid.UUID, phone string) (*ResolvedRecipient, error) {
+	if len(r.handles) == 0 {
+		return nil, ErrRecipientNotResolved
+	}
+
+	lookup, ok := r.cached(phone)
+	if !ok {
+		lookup = upiLookup{expiresAt: time.Now().Add(PhoneLookupCacheTTL)}
+		for _, handle := range r.handles {
+			result, err := r.razorpayClient.ValidateVPA(phone + "@" + handle)
+			if err != nil {
+				utils.LogWarning("UPI ID lookup failed", map[string]interface{}{"handle": handle, "error": err.Error()})
+				return nil, fmt.Errorf("could not look up the UPI ID of this phone number, try again shortly: %w", err)
+			}
+			if result.Success {
+				lookup.vpa = phone + "@" + handle
+				lookup.name = result.CustomerName
+				break
+			}
+		}
+
+		r.mu.Lock()
+		r.cache[phone] = lookup
+		r.mu.Unlock()
+	}
+
+	if lookup.vpa == "" {
+		return nil, ErrRecipientNotResolved
+	}
+	return &ResolvedRecipient{
+		Phone:  phone,
+		Name:   lookup.name,
+		VPA:    lookup.vpa,
+		Source: RecipientSourceUPILookup,
+	}, nil
+}
+
+// cached returns an unexpired lookup of a phone number, dropping expired ones
+func (r *UPILookupResolver) cached(phone string) (upiLookup, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for key, lookup := range r.cache {
+		if now.After(lookup.expiresAt) {
+			delete(r.cache, key)
+		}
+	}
+	lookup, ok := r.cache[phone]
+	return lookup, ok
+}
+
+// beneficiaryRecipient describes a phone number beneficiary, which is paid at
+// the fund account registered when it was saved
+func beneficiaryRecipient(beneficiary *models.Beneficiary) *ResolvedRecipient {
+	return &ResolvedRecipient{
+		Phone:  beneficiary.RecipientValue,
+		Name:   beneficiary.Name,
+		Source: RecipientSourceBeneficiary,
+	}
+}
@@ -0,0 +1,595 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/pkg/razorpay"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"github.com/zeusnotfound04/Tranza/utils"
+)
+
+// Constants for reconciliation
+const (
+	// ReconciliationSlack widens both sides of the comparison so records made
+	// just across a period boundary from their counterpart still match
+	ReconciliationSlack = time.Hour
+
+	// ReconciliationRunHour is when the daily run for the previous day starts (IST)
+	ReconciliationRunHour = 2
+)
+
+// ReconciliationLocation is the timezone reconciliation days are cut in
+var ReconciliationLocation = time.FixedZone("IST", 5*60*60+30*60)
+
+type reconcileJobPayload struct {
+	Date string `json:"date"` // YYYY-MM-DD in ReconciliationLocation
+}
+
+// ReconciliationService compares Razorpay's payments, refunds and payouts with
+// Tranza's transactions, refunds and external transfers, and stores every
+// discrepancy for ops to review.
+type ReconciliationService struct {
+	reconciliationRepo   *repositories.ReconciliationRepository
+	transactionRepo      *repositories.TransactionRepository
+	externalTransferRepo *repositories.ExternalTransferRepository
+	refundRepo           *repositories.RefundRepository
+	jobQueue             *JobQueue
+	razorpayClient       *razorpay.Client
+}
+
+// NewReconciliationService creates the service. jobQueue may be nil when the
+// service is only used for one-off runs, e.g. from cmd/reconcile.
+func NewReconciliationService(
+	reconciliationRepo *repositories.ReconciliationRepository,
+	transactionRepo *repositories.TransactionRepository,
+	externalTransferRepo *repositories.ExternalTransferRepository,
+	refundRepo *repositories.RefundRepository,
+	jobQueue *JobQueue,
+	razorpayClient *razorpay.Client,
+) *ReconciliationService {
+	return &ReconciliationService{
+		reconciliationRepo:   reconciliationRepo,
+		transactionRepo:      transactionRepo,
+		externalTransferRepo: externalTransferRepo,
+		refundRepo:           refundRepo,
+		jobQueue:             jobQueue,
+		razorpayClient:       razorpayClient,
+	}
+}
+
+// ReconciliationDay returns the period covered by a calendar day (YYYY-MM-DD)
+func ReconciliationDay(date string) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation("2006-01-02", date, ReconciliationLocation)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", date)
+	}
+	return start, start.AddDate(0, 0, 1).Add(-time.Second), nil
+}
+
+// RegisterJobHandlers registers the daily reconciliation job; call before starting the queue
+func (s *ReconciliationService) RegisterJobHandlers() {
+	s.jobQueue.Register(models.JobTypeReconcile, utils.GetMaxRetryAttempts(), s.handleReconcileJob, s.handleReconcileDead)
+}
+
+// ScheduleDaily queues the reconciliation of yesterday. Each daily job queues
+// the next day's when it finishes, so this only needs to run at startup.
+func (s *ReconciliationService) ScheduleDaily() error {
+	yesterday := time.Now().In(ReconciliationLocation).AddDate(0, 0, -1)
+	return s.enqueueDay(yesterday.Format("2006-01-02"))
+}
+
+func (s *ReconciliationService) enqueueDay(date string) error {
+	start, _, err := ReconciliationDay(date)
+	if err != nil {
+		return err
+	}
+
+	return s.jobQueue.Enqueue(
+		nil,
+		models.JobTypeReconcile,
+		reconcileJobPayload{Date: date},
+		"reconcile:"+date,
+		start.AddDate(0, 0, 1).Add(ReconciliationRunHour*time.Hour),
+	)
+}
+
+// handleReconcileJob reconciles one day, skipping days already reconciled
+func (s *ReconciliationService) handleReconcileJob(job *models.Job) error {
+	var payload reconcileJobPayload
+	if err := DecodeJobPayload(job, &payload); err != nil {
+		return err
+	}
+
+	start, end, err := ReconciliationDay(payload.Date)
+	if err != nil {
+		return err
+	}
+
+	done, err := s.reconciliationRepo.HasCompletedRun(start, end)
+	if err != nil {
+		return err
+	}
+	if !done {
+		if _, err := s.Run(start, end, models.ReconciliationTriggerJob); err != nil {
+			return err
+		}
+	}
+
+	return s.enqueueDay(start.AddDate(0, 0, 1).Format("2006-01-02"))
+}
+
+// handleReconcileDead keeps the daily chain going after a day that could not be reconciled
+func (s *ReconciliationService) handleReconcileDead(job *models.Job, err error) {
+	var payload reconcileJobPayload
+	if decodeErr := DecodeJobPayload(job, &payload); decodeErr != nil {
+		utils.LogError(decodeErr, map[string]interface{}{"job_id": job.ID.String(), "action": "decode_reconcile_job"})
+		return
+	}
+
+	utils.LogError(err, map[string]interface{}{"date": payload.Date, "action": "daily_reconciliation"})
+
+	start, _, parseErr := ReconciliationDay(payload.Date)
+	if parseErr != nil {
+		return
+	}
+	if enqueueErr := s.enqueueDay(start.AddDate(0, 0, 1).Format("2006-01-02")); enqueueErr != nil {
+		utils.LogError(enqueueErr, map[string]interface{}{"action": "schedule_reconciliation"})
+	}
+}
+
+// Run reconciles a period and stores the report. A run that fails part way is
+// kept with its error so the failure is visible alongside successful runs.
+func (s *ReconciliationService) Run(periodStart, periodEnd time.Time, trigger string) (*models.ReconciliationRun, error) {
+	if !periodEnd.After(periodStart) {
+		return nil, errors.New("period end must be after period start")
+	}
+
+	run := &models.ReconciliationRun{
+		PeriodStart: periodStart,
+		PeriodEnd:   periodEnd,
+		Trigger:     trigger,
+		Status:      models.ReconciliationStatusRunning,
+		StartedAt:   time.Now(),
+	}
+	if err := s.reconciliationRepo.CreateRun(run); err != nil {
+		return nil, err
+	}
+
+	var mismatches []*models.ReconciliationMismatch
+	err := s.reconcilePayments(run, &mismatches)
+	if err == nil {
+		err = s.reconcileRefunds(run, &mismatches)
+	}
+	if err == nil {
+		err = s.reconcilePayouts(run, &mismatches)
+	}
+
+	now := time.Now()
+	run.CompletedAt = &now
+	if err != nil {
+		run.Status = models.ReconciliationStatusFailed
+		run.Error = err.Error()
+		if updateErr := s.reconciliationRepo.UpdateRun(run); updateErr != nil {
+			utils.LogError(updateErr, map[string]interface{}{"run_id": run.ID.String(), "action": "update_reconciliation_run"})
+		}
+		return run, fmt.Errorf("reconciliation failed: %w", err)
+	}
+
+	run.Status = models.ReconciliationStatusCompleted
+	run.MismatchCount = len(mismatches)
+	if err := s.reconciliationRepo.CompleteRun(run, mismatches); err != nil {
+		return run, err
+	}
+	run.Mismatches = make([]models.ReconciliationMismatch, len(mismatches))
+	for i, mismatch := range mismatches {
+		run.Mismatches[i] = *mismatch
+	}
+
+	utils.LogInfo("Reconciliation completed", map[string]interface{}{
+		"run_id":          run.ID.String(),
+		"period_start":    periodStart.Format(time.RFC3339),
+		"period_end":      periodEnd.Format(time.RFC3339),
+		"remote_payments": run.RemotePayments,
+		"remote_refunds":  run.RemoteRefunds,
+		"remote_payouts":  run.RemotePayouts,
+		"matched":         run.Matched,
+		"mismatches":      run.MismatchCount,
+	})
+
+	return run, nil
+}
+
+// reconcilePayments matches Razorpay payments with wallet load transactions
+func (s *ReconciliationService) reconcilePayments(run *models.ReconciliationRun, mismatches *[]*models.ReconciliationMismatch) error {
+	from, to := run.PeriodStart.Add(-ReconciliationSlack), run.PeriodEnd.Add(ReconciliationSlack)
+
+	payments, err := s.fetchPayments(from, to)
+	if err != nil {
+		return err
+	}
+	loads, err := s.transactionRepo.GetByTypeAndDateRange(utils.TransactionTypeLoadMoney, from, to)
+	if err != nil {
+		return err
+	}
+
+	byPaymentID := make(map[string]*models.Transaction)
+	byOrderID := make(map[string]*models.Transaction)
+	for _, load := range loads {
+		if load.RazorpayPaymentID != "" {
+			byPaymentID[load.RazorpayPaymentID] = load
+		}
+		if load.RazorpayOrderID != "" {
+			byOrderID[load.RazorpayOrderID] = load
+		}
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	for i := range payments {
+		payment := &payments[i]
+		inRange := inPeriod(run, time.Unix(payment.CreatedAt, 0))
+		if inRange {
+			run.RemotePayments++
+		}
+
+		received := payment.Status == utils.PaymentStatusCaptured || payment.Status == utils.PaymentStatusRefunded
+
+		load := byPaymentID[payment.ID]
+		if load == nil && received {
+			// Failed attempts share the order with the payment that succeeded,
+			// so only a captured payment may be matched by order
+			load = byOrderID[payment.OrderID]
+		}
+		if load == nil {
+			if received && inRange {
+				*mismatches = append(*mismatches, paymentMismatch(models.MismatchKindMissingLocally, payment, nil,
+					"Payment captured on Razorpay but no wallet load was recorded"))
+			}
+			continue
+		}
+		seen[load.ID] = true
+
+		found := false
+		if received && load.Status != utils.TransactionStatusSuccess {
+			*mismatches = append(*mismatches, paymentMismatch(models.MismatchKindStatus, payment, load,
+				fmt.Sprintf("Payment %s on Razorpay but wallet load is %s", payment.Status, load.Status)))
+			found = true
+		}
+		if !received && load.Status == utils.TransactionStatusSuccess {
+			*mismatches = append(*mismatches, paymentMismatch(models.MismatchKindStatus, payment, load,
+				fmt.Sprintf("Wallet credited but Razorpay payment is %s", payment.Status)))
+			found = true
+		}
+		if !paiseToRupees(payment.Amount).Equal(load.Amount) {
+			*mismatches = append(*mismatches, paymentMismatch(models.MismatchKindAmount, payment, load,
+				"Razorpay payment amount differs from the wallet load"))
+			found = true
+		}
+		if !found && inRange {
+			run.Matched++
+		}
+	}
+
+	for _, load := range loads {
+		if seen[load.ID] || load.Status != utils.TransactionStatusSuccess || !inPeriod(run, load.CreatedAt) {
+			continue
+		}
+		*mismatches = append(*mismatches, paymentMismatch(models.MismatchKindMissingRemotely, nil, load,
+			"Wallet credited but the payment was not found on Razorpay"))
+	}
+
+	return nil
+}
+
+// reconcileRefunds matches Razorpay refunds with wallet load refunds
+func (s *ReconciliationService) reconcileRefunds(run *models.ReconciliationRun, mismatches *[]*models.ReconciliationMismatch) error {
+	from, to := run.PeriodStart.Add(-ReconciliationSlack), run.PeriodEnd.Add(ReconciliationSlack)
+
+	remoteRefunds, err := s.fetchRefunds(from, to)
+	if err != nil {
+		return err
+	}
+	refunds, err := s.refundRepo.GetByKindAndDateRange(models.RefundKindWalletLoad, from, to)
+	if err != nil {
+		return err
+	}
+
+	byRazorpayID := make(map[string]*models.Refund)
+	byReferenceID := make(map[string]*models.Refund)
+	for _, refund := range refunds {
+		if refund.RazorpayRefundID != "" {
+			byRazorpayID[refund.RazorpayRefundID] = refund
+		}
+		byReferenceID[refund.ReferenceID] = refund
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	for i := range remoteRefunds {
+		remote := &remoteRefunds[i]
+		inRange := inPeriod(run, time.Unix(remote.CreatedAt, 0))
+		if inRange {
+			run.RemoteRefunds++
+		}
+
+		refund := byRazorpayID[remote.ID]
+		if refund == nil && remote.Receipt != "" {
+			refund = byReferenceID[remote.Receipt]
+		}
+		if refund == nil {
+			if remote.Status != razorpay.RefundStatusFailed && inRange {
+				*mismatches = append(*mismatches, refundMismatch(models.MismatchKindMissingLocally, remote, nil,
+					"Refund issued on Razorpay but no refund was recorded"))
+			}
+			continue
+		}
+		seen[refund.ID] = true
+
+		found := false
+		if remote.Status != refund.Status {
+			*mismatches = append(*mismatches, refundMismatch(models.MismatchKindStatus, remote, refund,
+				fmt.Sprintf("Refund %s on Razorpay but %s in Tranza", remote.Status, refund.Status)))
+			found = true
+		}
+		if !paiseToRupees(remote.Amount).Equal(refund.Amount) {
+			*mismatches = append(*mismatches, refundMismatch(models.MismatchKindAmount, remote, refund,
+				"Razorpay refund amount differs from the recorded refund"))
+			found = true
+		}
+		if !found && inRange {
+			run.Matched++
+		}
+	}
+
+	for _, refund := range refunds {
+		if seen[refund.ID] || refund.Status == models.RefundStatusFailed || !inPeriod(run, refund.CreatedAt) {
+			continue
+		}
+		*mismatches = append(*mismatches, refundMismatch(models.MismatchKindMissingRemotely, nil, refund,
+			"Wallet debited for a refund that was not found on Razorpay"))
+	}
+
+	return nil
+}
+
+// reconcilePayouts matches Razorpay payouts with external transfers
+func (s *ReconciliationService) reconcilePayouts(run *models.ReconciliationRun, mismatches *[]*models.ReconciliationMismatch) error {
+	from, to := run.PeriodStart.Add(-ReconciliationSlack), run.PeriodEnd.Add(ReconciliationSlack)
+
+	payouts, err := s.fetchPayouts(from, to)
+	if err != nil {
+		return err
+	}
+	transfers, err := s.externalTransferRepo.GetByDateRange(from, to)
+	if err != nil {
+		return fmt.Errorf("failed to get external transfers: %w", err)
+	}
+
+	byPayoutID := make(map[string]*models.ExternalTransfer)
+	byReferenceID := make(map[string]*models.ExternalTransfer)
+	for _, transfer := range transfers {
+		if transfer.RazorpayPayoutID != "" {
+			byPayoutID[transfer.RazorpayPayoutID] = transfer
+		}
+		byReferenceID[transfer.ReferenceID] = transfer
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	for i := range payouts {
+		payout := &payouts[i]
+		inRange := inPeriod(run, time.Unix(payout.CreatedAt, 0))
+		if inRange {
+			run.RemotePayouts++
+		}
+
+		transfer := byPayoutID[payout.ID]
+		if transfer == nil && payout.Reference != "" {
+			transfer = byReferenceID[payout.Reference]
+		}
+		if transfer == nil {
+			if inRange {
+				*mismatches = append(*mismatches, payoutMismatch(models.MismatchKindMissingLocally, payout, nil,
+					"Payout on Razorpay but no external transfer was recorded"))
+			}
+			continue
+		}
+		seen[transfer.ID] = true
+
+		found := false
+		if !payoutStatusMatches(payout.Status, transfer.Status) {
+			*mismatches = append(*mismatches, payoutMismatch(models.MismatchKindStatus, payout, transfer,
+				fmt.Sprintf("Payout %s on Razorpay but transfer is %s", payout.Status, transfer.Status)))
+			found = true
+		}
+		if !paiseToRupees(payout.Amount).Equal(transfer.Amount) {
+			*mismatches = append(*mismatches, payoutMismatch(models.MismatchKindAmount, payout, transfer,
+				"Razorpay payout amount differs from the transfer"))
+			found = true
+		}
+		if !found && inRange {
+			run.Matched++
+		}
+	}
+
+	for _, transfer := range transfers {
+		// Transfers that never reached Razorpay have no payout to find
+		if seen[transfer.ID] || transfer.RazorpayPayoutID == "" || !inPeriod(run, transfer.CreatedAt) {
+			continue
+		}
+		*mismatches = append(*mismatches, payoutMismatch(models.MismatchKindMissingRemotely, nil, transfer,
+			"Transfer has a payout ID that was not found on Razorpay"))
+	}
+
+	return nil
+}
+
+func (s *ReconciliationService) fetchPayments(from, to time.Time) ([]razorpay.Payment, error) {
+	var payments []razorpay.Payment
+	for skip := 0; ; skip += razorpay.MaxListCount {
+		page, err := s.razorpayClient.ListPayments(razorpay.ListOptions{From: from, To: to, Count: razorpay.MaxListCount, Skip: skip})
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, page.Items...)
+		if len(page.Items) < razorpay.MaxListCount {
+			return payments, nil
+		}
+	}
+}
+
+func (s *ReconciliationService) fetchRefunds(from, to time.Time) ([]razorpay.Refund, error) {
+	var refunds []razorpay.Refund
+	for skip := 0; ; skip += razorpay.MaxListCount {
+		page, err := s.razorpayClient.ListRefunds(razorpay.ListOptions{From: from, To: to, Count: razorpay.MaxListCount, Skip: skip})
+		if err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, page.Items...)
+		if len(page.Items) < razorpay.MaxListCount {
+			return refunds, nil
+		}
+	}
+}
+
+func (s *ReconciliationService) fetchPayouts(from, to time.Time) ([]razorpay.Payout, error) {
+	var payouts []razorpay.Payout
+	for skip := 0; ; skip += razorpay.MaxListCount {
+		page, err := s.razorpayClient.ListPayouts(razorpay.ListOptions{From: from, To: to, Count: razorpay.MaxListCount, Skip: skip})
+		if err != nil {
+			return nil, err
+		}
+		payouts = append(payouts, page.Items...)
+		if len(page.Items) < razorpay.MaxListCount {
+			return payouts, nil
+		}
+	}
+}
+
+// GetRuns lists reconciliation runs, newest first
+func (s *ReconciliationService) GetRuns(page, limit int) ([]*models.ReconciliationRun, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return s.reconciliationRepo.GetRuns(limit, (page-1)*limit)
+}
+
+// GetRun retrieves a run with its mismatch report
+func (s *ReconciliationService) GetRun(runID string) (*models.ReconciliationRun, error) {
+	id, err := uuid.Parse(runID)
+	if err != nil {
+		return nil, errors.New("invalid run ID")
+	}
+	return s.reconciliationRepo.GetRun(id)
+}
+
+// GetUnresolvedMismatches lists mismatches from all runs that are awaiting review
+func (s *ReconciliationService) GetUnresolvedMismatches(page, limit int) ([]*models.ReconciliationMismatch, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return s.reconciliationRepo.GetUnresolvedMismatches(limit, (page-1)*limit)
+}
+
+// ResolveMismatch marks a mismatch as reviewed with a note on what was done
+func (s *ReconciliationService) ResolveMismatch(mismatchID, note string) error {
+	id, err := uuid.Parse(mismatchID)
+	if err != nil {
+		return errors.New("invalid mismatch ID")
+	}
+	if note == "" {
+		return errors.New("a resolution note is required")
+	}
+	return s.reconciliationRepo.ResolveMismatch(id, note)
+}
+
+func paymentMismatch(kind string, payment *razorpay.Payment, load *models.Transaction, details string) *models.ReconciliationMismatch {
+	mismatch := &models.ReconciliationMismatch{Source: models.MismatchSourcePayment, Kind: kind, Details: details}
+	if payment != nil {
+		mismatch.RazorpayID = payment.ID
+		mismatch.RemoteAmount = paiseToRupees(payment.Amount)
+		mismatch.RemoteStatus = payment.Status
+	}
+	if load != nil {
+		mismatch.LocalType = "transaction"
+		mismatch.LocalID = &load.ID
+		mismatch.LocalAmount = load.Amount
+		mismatch.LocalStatus = string(load.Status)
+		if mismatch.RazorpayID == "" {
+			mismatch.RazorpayID = load.RazorpayPaymentID
+		}
+	}
+	return mismatch
+}
+
+func refundMismatch(kind string, remote *razorpay.Refund, refund *models.Refund, details string) *models.ReconciliationMismatch {
+	mismatch := &models.ReconciliationMismatch{Source: models.MismatchSourceRefund, Kind: kind, Details: details}
+	if remote != nil {
+		mismatch.RazorpayID = remote.ID
+		mismatch.RemoteAmount = paiseToRupees(remote.Amount)
+		mismatch.RemoteStatus = remote.Status
+	}
+	if refund != nil {
+		mismatch.LocalType = "refund"
+		mismatch.LocalID = &refund.ID
+		mismatch.LocalAmount = refund.Amount
+		mismatch.LocalStatus = refund.Status
+		if mismatch.RazorpayID == "" {
+			mismatch.RazorpayID = refund.RazorpayRefundID
+		}
+	}
+	return mismatch
+}
+
+func payoutMismatch(kind string, payout *razorpay.Payout, transfer *models.ExternalTransfer, details string) *models.ReconciliationMismatch {
+	mismatch := &models.ReconciliationMismatch{Source: models.MismatchSourcePayout, Kind: kind, Details: details}
+	if payout != nil {
+		mismatch.RazorpayID = payout.ID
+		mismatch.RemoteAmount = paiseToRupees(payout.Amount)
+		mismatch.RemoteStatus = payout.Status
+	}
+	if transfer != nil {
+		mismatch.LocalType = "external_transfer"
+		mismatch.LocalID = &transfer.ID
+		mismatch.LocalAmount = transfer.Amount
+		mismatch.LocalStatus = transfer.Status
+		if mismatch.RazorpayID == "" {
+			mismatch.RazorpayID = transfer.RazorpayPayoutID
+		}
+	}
+	return mismatch
+}
+
+// payoutStatusMatches reports whether a transfer's status agrees with its payout's
+func payoutStatusMatches(payoutStatus, transferStatus string) bool {
+	switch payoutStatus {
+	case razorpay.PayoutStatusProcessed:
+		return transferStatus == models.ExternalTransferStatusSuccess
+	case razorpay.PayoutStatusReversed:
+		return transferStatus == models.ExternalTransferStatusRefunded ||
+			transferStatus == models.ExternalTransferStatusFailed
+	case razorpay.PayoutStatusFailed, razorpay.PayoutStatusCancelled, "rejected":
+		return transferStatus == models.ExternalTransferStatusFailed ||
+			transferStatus == models.ExternalTransferStatusCancelled ||
+			transferStatus == models.ExternalTransferStatusRefunded
+	default:
+		return transferStatus == models.ExternalTransferStatusPending ||
+			transferStatus == models.ExternalTransferStatusProcessing
+	}
+}
+
+func inPeriod(run *models.ReconciliationRun, t time.Time) bool {
+	return !t.Before(run.PeriodStart) && !t.After(run.PeriodEnd)
+}
+
+func paiseToRupees(paise int64) decimal.Decimal {
+	return decimal.New(paise, -2)
+}
@@ -0,0 +1,342 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/pkg/razorpay"
+	"github.com/zeusnotfound04/Tranza/pkg/razorpay/fake"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"github.com/zeusnotfound04/Tranza/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func TestPayoutStatusMatches(t *testing.T) {
+	tests := []struct {
+		payout   string
+		transfer string
+		matches  bool
+	}{
+		{razorpay.PayoutStatusProcessed, models.ExternalTransferStatusSuccess, true},
+		{razorpay.PayoutStatusProcessed, models.ExternalTransferStatusProcessing, false},
+		{razorpay.PayoutStatusProcessed, models.ExternalTransferStatusFailed, false},
+		// A reversed payout has been refunded to the wallet, or is about to be
+		{razorpay.PayoutStatusReversed, models.ExternalTransferStatusRefunded, true},
+		{razorpay.PayoutStatusReversed, models.ExternalTransferStatusFailed, true},
+		{razorpay.PayoutStatusReversed, models.ExternalTransferStatusSuccess, false},
+		{razorpay.PayoutStatusFailed, models.ExternalTransferStatusFailed, true},
+		{razorpay.PayoutStatusFailed, models.ExternalTransferStatusRefunded, true},
+		{razorpay.PayoutStatusCancelled, models.ExternalTransferStatusCancelled, true},
+		{"rejected", models.ExternalTransferStatusFailed, true},
+		{razorpay.PayoutStatusCancelled, models.ExternalTransferStatusSuccess, false},
+		// Payouts still on their way
+		{razorpay.PayoutStatusQueued, models.ExternalTransferStatusPending, true},
+		{razorpay.PayoutStatusPending, models.ExternalTransferStatusProcessing, true},
+		{razorpay.PayoutStatusProcessing, models.ExternalTransferStatusProcessing, true},
+		{razorpay.PayoutStatusProcessing, models.ExternalTransferStatusSuccess, false},
+		{razorpay.PayoutStatusProcessing, models.ExternalTransferStatusFailed, false},
+	}
+	for _, tt := range tests {
+		if got := payoutStatusMatches(tt.payout, tt.transfer); got != tt.matches {
+			t.Errorf("payoutStatusMatches(%s, %s) = %t, want %t", tt.payout, tt.transfer, got, tt.matches)
+		}
+	}
+}
+
+func newTestReconciliationService(db *gorm.DB, client *razorpay.Client) *ReconciliationService {
+	return NewReconciliationService(
+		repositories.NewReconciliationRepository(db),
+		repositories.NewTransactionRepository(db),
+		repositories.NewExternalTransferRepository(db),
+		repositories.NewRefundRepository(db),
+		nil,
+		client,
+	)
+}
+
+// payOrder pays a new order of amount paise on the fake server
+func payOrder(t *testing.T, server *fake.Server, client *razorpay.Client, amount int64) *razorpay.Payment {
+	t.Helper()
+
+	order, err := client.CreateOrder(amount, "INR", "rcpt_"+uuid.NewString()[:8])
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	payment, err := server.PayOrder(order.ID, "upi")
+	if err != nil {
+		t.Fatalf("PayOrder: %v", err)
+	}
+	return payment
+}
+
+// createLoad records a wallet load transaction
+func createLoad(t *testing.T, db *gorm.DB, wallet *models.Wallet, amount string, status models.TransactionStatus, orderID, paymentID string) *models.Transaction {
+	t.Helper()
+
+	load := &models.Transaction{
+		ID:                uuid.New(),
+		WalletID:          wallet.ID,
+		UserID:            wallet.UserID,
+		Type:              utils.TransactionTypeLoadMoney,
+		Amount:            decimal.RequireFromString(amount),
+		Status:            status,
+		RazorpayOrderID:   orderID,
+		RazorpayPaymentID: paymentID,
+		ReferenceID:       "load_" + uuid.NewString(),
+	}
+	if err := db.Create(load).Error; err != nil {
+		t.Fatalf("failed to create load: %v", err)
+	}
+	return load
+}
+
+// createTransfer records an external transfer
+func createTransfer(t *testing.T, db *gorm.DB, wallet *models.Wallet, amount, status, payoutID, referenceID string) *models.ExternalTransfer {
+	t.Helper()
+
+	transfer := &models.ExternalTransfer{
+		ID:               uuid.New(),
+		UserID:           wallet.UserID,
+		WalletID:         wallet.ID,
+		Amount:           decimal.RequireFromString(amount),
+		TotalAmount:      decimal.RequireFromString(amount),
+		Currency:         "INR",
+		RecipientType:    models.RecipientTypeUPI,
+		RecipientValue:   "alice@okhdfc",
+		Status:           status,
+		TransferMethod:   models.TransferMethodRazorpayPayout,
+		RazorpayPayoutID: payoutID,
+		ReferenceID:      referenceID,
+		InitiatedBy:      models.InitiatedByUser,
+	}
+	if err := db.Omit(clause.Associations).Create(transfer).Error; err != nil {
+		t.Fatalf("failed to create transfer: %v", err)
+	}
+	return transfer
+}
+
+// mismatchesByKind groups a run's mismatches as "source kind" with their Razorpay IDs
+func mismatchesByKind(run *models.ReconciliationRun) map[string][]string {
+	found := make(map[string][]string)
+	for _, mismatch := range run.Mismatches {
+		key := mismatch.Source + " " + mismatch.Kind
+		found[key] = append(found[key], mismatch.RazorpayID)
+	}
+	return found
+}
+
+func requireMismatch(t *testing.T, found map[string][]string, source, kind, razorpayID string) {
+	t.Helper()
+	for _, id := range found[source+" "+kind] {
+		if id == razorpayID {
+			return
+		}
+	}
+	t.Errorf("no %s %s mismatch for %s in %v", source, kind, razorpayID, found)
+}
+
+func TestReconcilePayments(t *testing.T) {
+	db := testDB(t)
+	server := fake.NewServer(fake.Config{}).Start()
+	t.Cleanup(server.Close)
+	client := server.Client()
+	wallet := createTestWallet(t, db, "0", "100000")
+
+	matched := payOrder(t, server, client, 10000)
+	createLoad(t, db, wallet, "100", utils.TransactionStatusSuccess, matched.OrderID, matched.ID)
+
+	// The payment ID was never recorded, e.g. the verify call was lost
+	byOrder := payOrder(t, server, client, 20000)
+	createLoad(t, db, wallet, "200", utils.TransactionStatusSuccess, byOrder.OrderID, "")
+
+	drifted := payOrder(t, server, client, 30000)
+	createLoad(t, db, wallet, "250", utils.TransactionStatusSuccess, drifted.OrderID, drifted.ID)
+
+	pending := payOrder(t, server, client, 40000)
+	createLoad(t, db, wallet, "400", utils.TransactionStatusPending, pending.OrderID, pending.ID)
+
+	unrecorded := payOrder(t, server, client, 50000)
+
+	gone := createLoad(t, db, wallet, "600", utils.TransactionStatusSuccess, "order_gone", "pay_gone")
+	// Loads that never succeeded have nothing to find on Razorpay
+	createLoad(t, db, wallet, "700", utils.TransactionStatusPending, "order_abandoned", "")
+
+	now := time.Now()
+	run, err := newTestReconciliationService(db, client).Run(now.Add(-time.Hour), now.Add(time.Hour), models.ReconciliationTriggerAPI)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if run.Status != models.ReconciliationStatusCompleted || run.RemotePayments != 5 || run.Matched != 2 || run.MismatchCount != 4 {
+		t.Fatalf("run = %s with %d payments, %d matched and %d mismatches; want completed with 5, 2 and 4",
+			run.Status, run.RemotePayments, run.Matched, run.MismatchCount)
+	}
+
+	found := mismatchesByKind(run)
+	requireMismatch(t, found, models.MismatchSourcePayment, models.MismatchKindAmount, drifted.ID)
+	requireMismatch(t, found, models.MismatchSourcePayment, models.MismatchKindStatus, pending.ID)
+	requireMismatch(t, found, models.MismatchSourcePayment, models.MismatchKindMissingLocally, unrecorded.ID)
+	requireMismatch(t, found, models.MismatchSourcePayment, models.MismatchKindMissingRemotely, gone.RazorpayPaymentID)
+
+	// The report is stored for review
+	unresolved, total, err := newTestReconciliationService(db, client).GetUnresolvedMismatches(1, 20)
+	if err != nil || total != 4 || len(unresolved) != 4 {
+		t.Fatalf("GetUnresolvedMismatches = %d of %d, %v; want 4", len(unresolved), total, err)
+	}
+}
+
+func TestReconcileRefunds(t *testing.T) {
+	db := testDB(t)
+	server := fake.NewServer(fake.Config{}).Start()
+	t.Cleanup(server.Close)
+	client := server.Client()
+	wallet := createTestWallet(t, db, "0", "100000")
+
+	payment := payOrder(t, server, client, 100000)
+	load := createLoad(t, db, wallet, "1000", utils.TransactionStatusSuccess, payment.OrderID, payment.ID)
+
+	createRefund := func(amount, status, razorpayID, referenceID string) {
+		t.Helper()
+		refund := &models.Refund{
+			UserID:            wallet.UserID,
+			WalletID:          wallet.ID,
+			TransactionID:     load.ID,
+			Kind:              models.RefundKindWalletLoad,
+			Amount:            decimal.RequireFromString(amount),
+			Currency:          "INR",
+			Status:            status,
+			ReferenceID:       referenceID,
+			RazorpayPaymentID: payment.ID,
+			RazorpayRefundID:  razorpayID,
+		}
+		if err := db.Create(refund).Error; err != nil {
+			t.Fatalf("failed to create refund: %v", err)
+		}
+	}
+	refund := func(amount int64, receipt string) *razorpay.Refund {
+		t.Helper()
+		remote, err := client.CreateRefund(payment.ID, &razorpay.CreateRefundRequest{Amount: amount, Receipt: receipt}, receipt)
+		if err != nil {
+			t.Fatalf("CreateRefund: %v", err)
+		}
+		return remote
+	}
+
+	matched := refund(10000, "rfd_matched")
+	createRefund("100", models.RefundStatusProcessed, matched.ID, "rfd_matched")
+
+	// Matched by its receipt, as the refund ID was never recorded
+	refund(20000, "rfd_receipt")
+	createRefund("200", models.RefundStatusProcessed, "", "rfd_receipt")
+
+	drifted := refund(30000, "rfd_drifted")
+	createRefund("250", models.RefundStatusProcessed, drifted.ID, "rfd_drifted")
+
+	stuck := refund(5000, "rfd_stuck")
+	createRefund("50", models.RefundStatusPending, stuck.ID, "rfd_stuck")
+
+	unrecorded := refund(1000, "rfd_unrecorded")
+
+	createRefund("75", models.RefundStatusProcessed, "rfnd_gone", "rfd_gone")
+	// Failed refunds moved no money, so nothing is missing
+	createRefund("80", models.RefundStatusFailed, "", "rfd_failed")
+
+	now := time.Now()
+	run, err := newTestReconciliationService(db, client).Run(now.Add(-time.Hour), now.Add(time.Hour), models.ReconciliationTriggerAPI)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	// The payment itself matches its load
+	if run.RemoteRefunds != 5 || run.Matched != 3 || run.MismatchCount != 4 {
+		t.Fatalf("run has %d refunds, %d matched and %d mismatches; want 5, 3 and 4", run.RemoteRefunds, run.Matched, run.MismatchCount)
+	}
+
+	found := mismatchesByKind(run)
+	requireMismatch(t, found, models.MismatchSourceRefund, models.MismatchKindAmount, drifted.ID)
+	requireMismatch(t, found, models.MismatchSourceRefund, models.MismatchKindStatus, stuck.ID)
+	requireMismatch(t, found, models.MismatchSourceRefund, models.MismatchKindMissingLocally, unrecorded.ID)
+	requireMismatch(t, found, models.MismatchSourceRefund, models.MismatchKindMissingRemotely, "rfnd_gone")
+	if len(found[models.MismatchSourceRefund+" "+models.MismatchKindMissingRemotely]) != 1 {
+		t.Errorf("missing remotely = %v, want only rfnd_gone", found)
+	}
+}
+
+func TestReconcilePayouts(t *testing.T) {
+	db := testDB(t)
+	server := fake.NewServer(fake.Config{}).Start()
+	t.Cleanup(server.Close)
+	client := server.Client()
+	wallet := createTestWallet(t, db, "0", "100000")
+
+	payout := func(amount int64, referenceID string) *razorpay.Payout {
+		t.Helper()
+		created, err := client.CreateUPIPayout("alice@okhdfc", amount, "INR", "payout", "Tranza transfer", "Alice", "", referenceID)
+		if err != nil {
+			t.Fatalf("CreateUPIPayout: %v", err)
+		}
+		return created
+	}
+
+	// New payouts are processing on the fake server
+	matched := payout(10000, "TXN_MATCHED")
+	createTransfer(t, db, wallet, "100", models.ExternalTransferStatusProcessing, matched.ID, "TXN_MATCHED")
+
+	// Matched by reference, as the payout ID was never recorded
+	payout(20000, "TXN_REFERENCE")
+	createTransfer(t, db, wallet, "200", models.ExternalTransferStatusPending, "", "TXN_REFERENCE")
+
+	drifted := payout(30000, "TXN_DRIFTED")
+	createTransfer(t, db, wallet, "250", models.ExternalTransferStatusProcessing, drifted.ID, "TXN_DRIFTED")
+
+	reversed := payout(40000, "TXN_REVERSED")
+	if _, err := server.SetPayoutStatus(reversed.ID, razorpay.PayoutStatusReversed, "beneficiary bank offline"); err != nil {
+		t.Fatal(err)
+	}
+	createTransfer(t, db, wallet, "400", models.ExternalTransferStatusSuccess, reversed.ID, "TXN_REVERSED")
+
+	unrecorded := payout(50000, "TXN_UNRECORDED")
+
+	createTransfer(t, db, wallet, "600", models.ExternalTransferStatusSuccess, "pout_gone", "TXN_GONE")
+	// Transfers that never reached Razorpay have no payout to find
+	createTransfer(t, db, wallet, "700", models.ExternalTransferStatusQueued, "", "TXN_QUEUED")
+
+	now := time.Now()
+	run, err := newTestReconciliationService(db, client).Run(now.Add(-time.Hour), now.Add(time.Hour), models.ReconciliationTriggerAPI)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if run.RemotePayouts != 5 || run.Matched != 2 || run.MismatchCount != 4 {
+		t.Fatalf("run has %d payouts, %d matched and %d mismatches; want 5, 2 and 4", run.RemotePayouts, run.Matched, run.MismatchCount)
+	}
+
+	found := mismatchesByKind(run)
+	requireMismatch(t, found, models.MismatchSourcePayout, models.MismatchKindAmount, drifted.ID)
+	requireMismatch(t, found, models.MismatchSourcePayout, models.MismatchKindStatus, reversed.ID)
+	requireMismatch(t, found, models.MismatchSourcePayout, models.MismatchKindMissingLocally, unrecorded.ID)
+	requireMismatch(t, found, models.MismatchSourcePayout, models.MismatchKindMissingRemotely, "pout_gone")
+}
+
+func TestReconcileOnlyCountsThePeriod(t *testing.T) {
+	db := testDB(t)
+	server := fake.NewServer(fake.Config{}).Start()
+	t.Cleanup(server.Close)
+	client := server.Client()
+
+	// A payment made now, with no load, is not missing from yesterday
+	payOrder(t, server, client, 10000)
+
+	end := time.Now().Add(-2 * time.Hour)
+	run, err := newTestReconciliationService(db, client).Run(end.Add(-24*time.Hour), end, models.ReconciliationTriggerAPI)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if run.RemotePayments != 0 || run.MismatchCount != 0 {
+		t.Errorf("run has %d payments and %d mismatches, want none", run.RemotePayments, run.MismatchCount)
+	}
+}
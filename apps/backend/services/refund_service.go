@@ -0,0 +1,496 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/pkg/razorpay"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"github.com/zeusnotfound04/Tranza/utils"
+	"gorm.io/gorm"
+)
+
+// MinRefundAmount is the smallest refund Razorpay accepts (₹1)
+const MinRefundAmount = 1.0
+
+var ErrAlreadyRefunded = errors.New("transaction has already been fully refunded")
+
+type refundJobPayload struct {
+	RefundID string `json:"refund_id"`
+}
+
+// RefundService refunds wallet loads and AI payments. Load refunds debit the
+// wallet up front and are sent to Razorpay from the job queue; the wallet is
+// credited back if Razorpay fails the refund. AI payment refunds are paid out
+// of the merchant payable account and complete immediately.
+type RefundService struct {
+	db              *gorm.DB
+	refundRepo      *repositories.RefundRepository
+	transactionRepo *repositories.TransactionRepository
+	ledgerService   *LedgerService
+	jobQueue        *JobQueue
+	razorpayClient  *razorpay.Client
+}
+
+func NewRefundService(
+	db *gorm.DB,
+	refundRepo *repositories.RefundRepository,
+	transactionRepo *repositories.TransactionRepository,
+	ledgerService *LedgerService,
+	jobQueue *JobQueue,
+	razorpayClient *razorpay.Client,
+) *RefundService {
+	return &RefundService{
+		db:              db,
+		refundRepo:      refundRepo,
+		transactionRepo: transactionRepo,
+		ledgerService:   ledgerService,
+		jobQueue:        jobQueue,
+		razorpayClient:  razorpayClient,
+	}
+}
+
+// RegisterJobHandlers registers the refund job handlers; call before starting the queue
+func (s *RefundService) RegisterJobHandlers() {
+	s.jobQueue.Register(models.JobTypeRefundSubmit, utils.GetMaxRetryAttempts(), s.handleRefundSubmitJob, s.handleRefundSubmitDead)
+}
+
+// CreateRefund refunds all or part of one of the user's wallet loads or AI
+// payments. Payments for shopping orders are refunded only by cancelling the
+// order, so the order and its payment cannot disagree.
+func (s *RefundService) CreateRefund(userID string, req *dto.CreateRefundRequest) (*models.Refund, error) {
+	return s.createRefund(userID, req, false)
+}
+
+// RefundOrderPayment refunds the payment of a cancelled shopping order
+func (s *RefundService) RefundOrderPayment(userID string, req *dto.CreateRefundRequest) (*models.Refund, error) {
+	return s.createRefund(userID, req, true)
+}
+
+func (s *RefundService) createRefund(userID string, req *dto.CreateRefundRequest, forOrder bool) (*models.Refund, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	transactionID, err := uuid.Parse(req.TransactionID)
+	if err != nil {
+		return nil, errors.New("invalid transaction ID")
+	}
+
+	if err := checkRefundAmount(req.Amount); err != nil {
+		return nil, err
+	}
+
+	original, err := s.transactionRepo.GetByIDAndUserID(transactionID, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	kind, err := refundKind(original)
+	if err != nil {
+		return nil, err
+	}
+	if kind == models.RefundKindAIPayment && !forOrder {
+		var orders int64
+		if err := s.db.Model(&models.ExternalOrder{}).
+			Where("transaction_id = ? OR order_number = ?", original.ID.String(), original.ReferenceID).
+			Count(&orders).Error; err != nil {
+			return nil, fmt.Errorf("failed to check for a shopping order: %w", err)
+		}
+		if orders > 0 {
+			return nil, errors.New("shopping orders are refunded by cancelling the order")
+		}
+	}
+
+	refund := &models.Refund{
+		UserID:            uid,
+		WalletID:          original.WalletID,
+		TransactionID:     original.ID,
+		Kind:              kind,
+		Currency:          "INR",
+		Status:            models.RefundStatusPending,
+		Reason:            req.Reason,
+		ReferenceID:       utils.GenerateTransactionReference("RFD"),
+		RazorpayPaymentID: original.RazorpayPaymentID,
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the original transaction so concurrent refunds cannot exceed it
+		if _, err := s.transactionRepo.GetByIDForUpdate(tx, original.ID); err != nil {
+			return err
+		}
+
+		refunded, err := s.refundRepo.GetRefundedTotal(tx, original.ID)
+		if err != nil {
+			return err
+		}
+		remaining := original.Amount.Sub(refunded)
+		refund.Amount, err = refundAmount(req.Amount, remaining)
+		if err != nil {
+			return err
+		}
+
+		refundTransaction := &models.Transaction{
+			ID:            uuid.New(),
+			WalletID:      original.WalletID,
+			UserID:        uid,
+			Type:          utils.TransactionTypeRefund,
+			Amount:        refund.Amount,
+			Currency:      "INR",
+			Description:   s.describeRefund(original, req.Reason),
+			Status:        utils.TransactionStatusPending,
+			PaymentMethod: original.PaymentMethod,
+			ReferenceID:   refund.ReferenceID,
+			MerchantName:  original.MerchantName,
+		}
+		refund.RefundTransactionID = &refundTransaction.ID
+
+		var wallet *models.Wallet
+		if kind == models.RefundKindWalletLoad {
+			wallet, err = s.ledgerService.RecordLoadRefund(tx, original.WalletID, refund.Amount, refund.ReferenceID, &refundTransaction.ID)
+		} else {
+			wallet, err = s.ledgerService.RecordAIPaymentRefund(tx, original.WalletID, refund.Amount, refund.ReferenceID, &refundTransaction.ID)
+		}
+		if err != nil {
+			return err
+		}
+		refundTransaction.BalanceAfter = wallet.Balance
+
+		// AI payments were paid from the wallet, so there is nothing to wait for
+		if kind == models.RefundKindAIPayment {
+			now := time.Now()
+			refund.Status = models.RefundStatusProcessed
+			refund.ProcessedAt = &now
+			refundTransaction.Status = utils.TransactionStatusSuccess
+
+			if refund.Amount.Equal(remaining) {
+				if err := tx.Model(&models.AIPaymentRequest{}).
+					Where("transaction_id = ?", original.ID.String()).
+					Update("status", "refunded").Error; err != nil {
+					return fmt.Errorf("failed to update payment request: %w", err)
+				}
+			}
+		}
+
+		if _, err := s.transactionRepo.CreateWithTx(tx, refundTransaction); err != nil {
+			return err
+		}
+		if err := s.refundRepo.Create(tx, refund, models.RefundEventSourceAPI, req.Reason); err != nil {
+			return err
+		}
+
+		if kind == models.RefundKindWalletLoad {
+			return s.jobQueue.Enqueue(
+				tx,
+				models.JobTypeRefundSubmit,
+				refundJobPayload{RefundID: refund.ID.String()},
+				"refund_submit:"+refund.ID.String(),
+				time.Now(),
+			)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refund: %w", err)
+	}
+
+	utils.LogInfo("Refund created", map[string]interface{}{
+		"refund_id":      refund.ID.String(),
+		"transaction_id": original.ID.String(),
+		"kind":           kind,
+		"amount":         refund.Amount.String(),
+		"status":         refund.Status,
+	})
+
+	return s.refundRepo.GetByID(refund.ID)
+}
+
+// GetRefund retrieves one of the user's refunds with its status history
+func (s *RefundService) GetRefund(userID, refundID string) (*models.Refund, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	id, err := uuid.Parse(refundID)
+	if err != nil {
+		return nil, errors.New("invalid refund ID")
+	}
+
+	return s.refundRepo.GetByIDAndUserID(id, uid)
+}
+
+// GetRefunds retrieves the user's refunds, newest first
+func (s *RefundService) GetRefunds(userID string, page, limit int) ([]*models.Refund, int64, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, 0, errors.New("invalid user ID")
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	return s.refundRepo.GetByUserID(uid, limit, (page-1)*limit)
+}
+
+// HandleRefundEvent applies a refund.* webhook from Razorpay
+func (s *RefundService) HandleRefundEvent(event string, rzpRefund *razorpay.Refund) error {
+	refund, err := s.refundRepo.GetByRazorpayRefundID(rzpRefund.ID)
+	if err != nil {
+		// The webhook can arrive before the submit job has stored the Razorpay ID
+		refund, err = s.refundRepo.GetByReferenceID(rzpRefund.Receipt)
+		if err != nil {
+			utils.LogWarning("Webhook for unknown refund", map[string]interface{}{
+				"event":     event,
+				"refund_id": rzpRefund.ID,
+				"receipt":   rzpRefund.Receipt,
+			})
+			return nil
+		}
+		if err := s.refundRepo.SetRazorpayRefundID(refund.ID, rzpRefund.ID); err != nil {
+			return err
+		}
+	}
+
+	switch event {
+	case utils.WebhookRefundProcessed:
+		return s.markProcessed(refund, models.RefundEventSourceWebhook, "Refund processed by Razorpay")
+	case utils.WebhookRefundFailed:
+		return s.markFailed(refund, models.RefundEventSourceWebhook, "Refund failed at Razorpay")
+	default:
+		utils.LogInfo("Refund event received", map[string]interface{}{
+			"event":     event,
+			"refund_id": refund.ID.String(),
+			"status":    rzpRefund.Status,
+		})
+		return nil
+	}
+}
+
+func (s *RefundService) refundFromJob(job *models.Job) (*models.Refund, error) {
+	var payload refundJobPayload
+	if err := DecodeJobPayload(job, &payload); err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.Parse(payload.RefundID)
+	if err != nil {
+		return nil, errors.New("invalid refund ID")
+	}
+
+	return s.refundRepo.GetByID(id)
+}
+
+// handleRefundSubmitJob sends a pending load refund to Razorpay. The refund ID
+// is the idempotency key, so a retried job cannot refund twice.
+func (s *RefundService) handleRefundSubmitJob(job *models.Job) error {
+	refund, err := s.refundFromJob(job)
+	if err != nil {
+		return err
+	}
+	if refund.Status != models.RefundStatusPending || refund.RazorpayRefundID != "" {
+		return nil
+	}
+
+	rzpRefund, err := s.razorpayClient.CreateRefund(refund.RazorpayPaymentID, &razorpay.CreateRefundRequest{
+		Amount:  refund.Amount.Mul(decimal.NewFromInt(100)).IntPart(),
+		Receipt: refund.ReferenceID,
+		Notes: map[string]string{
+			"refund_id": refund.ID.String(),
+			"reason":    refund.Reason,
+		},
+	}, refund.ID.String())
+	if err != nil {
+		if reason, rejected := refundRejected(err); rejected {
+			return s.markFailed(refund, models.RefundEventSourceJob, reason)
+		}
+		return err
+	}
+
+	if err := s.refundRepo.SetRazorpayRefundID(refund.ID, rzpRefund.ID); err != nil {
+		return err
+	}
+
+	// A webhook may already have settled the refund while the request was in flight
+	if refund, err = s.refundRepo.GetByID(refund.ID); err != nil {
+		return err
+	}
+
+	switch rzpRefund.Status {
+	case razorpay.RefundStatusProcessed:
+		return s.markProcessed(refund, models.RefundEventSourceJob, "Refund processed by Razorpay")
+	case razorpay.RefundStatusFailed:
+		return s.markFailed(refund, models.RefundEventSourceJob, "Refund failed at Razorpay")
+	}
+	return nil
+}
+
+// handleRefundSubmitDead fails a refund that could not be sent to Razorpay
+func (s *RefundService) handleRefundSubmitDead(job *models.Job, err error) {
+	refund, getErr := s.refundFromJob(job)
+	if getErr != nil {
+		utils.LogError(getErr, map[string]interface{}{"job_id": job.ID.String(), "action": "get_refund_for_dead_job"})
+		return
+	}
+	if refund.RazorpayRefundID != "" {
+		return
+	}
+
+	if failErr := s.markFailed(refund, models.RefundEventSourceJob, err.Error()); failErr != nil {
+		utils.LogError(failErr, map[string]interface{}{"refund_id": refund.ID.String(), "action": "fail_refund"})
+	}
+}
+
+// markProcessed completes a pending refund
+func (s *RefundService) markProcessed(refund *models.Refund, source, note string) error {
+	if refund.IsFinal() {
+		return nil
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.refundRepo.Transition(tx, refund.ID, models.RefundStatusProcessed, source, note); err != nil {
+			return err
+		}
+		return s.updateRefundTransaction(tx, refund, utils.TransactionStatusSuccess, "", nil)
+	})
+}
+
+// markFailed fails a pending refund and returns a load refund to the wallet
+func (s *RefundService) markFailed(refund *models.Refund, source, reason string) error {
+	if refund.IsFinal() {
+		return nil
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.refundRepo.Transition(tx, refund.ID, models.RefundStatusFailed, source, reason); err != nil {
+			return err
+		}
+
+		var balanceAfter *decimal.Decimal
+		if refund.Kind == models.RefundKindWalletLoad {
+			wallet, err := s.ledgerService.RecordLoadRefundReversal(tx, refund.WalletID, refund.Amount, refund.ReferenceID, refund.RefundTransactionID)
+			if err != nil {
+				return err
+			}
+			balanceAfter = &wallet.Balance
+		}
+
+		return s.updateRefundTransaction(tx, refund, utils.TransactionStatusFailed, reason, balanceAfter)
+	})
+	if err != nil {
+		return err
+	}
+
+	utils.LogWarning("Refund failed", map[string]interface{}{
+		"refund_id": refund.ID.String(),
+		"reason":    reason,
+	})
+	return nil
+}
+
+func (s *RefundService) updateRefundTransaction(tx *gorm.DB, refund *models.Refund, status, failureReason string, balanceAfter *decimal.Decimal) error {
+	if refund.RefundTransactionID == nil {
+		return nil
+	}
+
+	updates := map[string]interface{}{
+		"status":     status,
+		"updated_at": time.Now(),
+	}
+	if failureReason != "" {
+		updates["failure_reason"] = failureReason
+	}
+	if balanceAfter != nil {
+		updates["balance_after"] = *balanceAfter
+	}
+
+	if err := tx.Model(&models.Transaction{}).Where("id = ?", *refund.RefundTransactionID).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to update refund transaction: %w", err)
+	}
+	return nil
+}
+
+// checkRefundAmount checks a requested refund amount; zero asks for the rest
+// of the transaction
+func checkRefundAmount(amount decimal.Decimal) error {
+	if amount.IsNegative() {
+		return errors.New("refund amount must be positive")
+	}
+	if !amount.IsZero() && amount.LessThan(decimal.NewFromFloat(MinRefundAmount)) {
+		return fmt.Errorf("minimum refund amount is ₹%.2f", MinRefundAmount)
+	}
+	return nil
+}
+
+// refundKind returns the kind of refund a transaction can take
+func refundKind(original *models.Transaction) (string, error) {
+	var kind string
+	switch original.Type {
+	case utils.TransactionTypeLoadMoney:
+		if original.RazorpayPaymentID == "" {
+			return "", errors.New("wallet load has no Razorpay payment to refund")
+		}
+		kind = models.RefundKindWalletLoad
+	case utils.TransactionTypeAIPayment:
+		if original.MerchantUPIID != "" {
+			// The money left Tranza in a UPI payout, so only the merchant can return it
+			return "", errors.New("AI payments paid out to a merchant must be refunded by the merchant")
+		}
+		kind = models.RefundKindAIPayment
+	default:
+		return "", errors.New("only wallet loads and AI payments can be refunded")
+	}
+	if original.Status != utils.TransactionStatusSuccess {
+		return "", errors.New("only successful transactions can be refunded")
+	}
+	return kind, nil
+}
+
+// refundAmount returns the amount to refund out of what is left of the
+// transaction; a zero request refunds all of it
+func refundAmount(requested, remaining decimal.Decimal) (decimal.Decimal, error) {
+	if !remaining.IsPositive() {
+		return decimal.Zero, ErrAlreadyRefunded
+	}
+	if requested.IsZero() {
+		return remaining, nil
+	}
+	if requested.GreaterThan(remaining) {
+		return decimal.Zero, fmt.Errorf("refund amount exceeds the refundable ₹%s", remaining.StringFixed(2))
+	}
+	return requested, nil
+}
+
+// refundRejected reports whether Razorpay turned a refund down outright, so
+// retrying will not help, and why
+func refundRejected(err error) (string, bool) {
+	var rzpErr *razorpay.RazorpayError
+	if errors.As(err, &rzpErr) && rzpErr.RazorpayError.Code == "BAD_REQUEST_ERROR" {
+		return rzpErr.RazorpayError.Description, true
+	}
+	return "", false
+}
+
+func (s *RefundService) describeRefund(original *models.Transaction, reason string) string {
+	description := "Refund of wallet load"
+	if original.Type == utils.TransactionTypeAIPayment {
+		description = "Refund of AI payment"
+		if original.MerchantName != "" {
+			description += " to " + original.MerchantName
+		}
+	}
+	if reason != "" {
+		description += ": " + reason
+	}
+	return description
+}
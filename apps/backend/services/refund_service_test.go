@@ -0,0 +1,227 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/pkg/razorpay"
+	"github.com/zeusnotfound04/Tranza/pkg/razorpay/fake"
+	"github.com/zeusnotfound04/Tranza/utils"
+)
+
+func TestCheckRefundAmount(t *testing.T) {
+	tests := []struct {
+		amount string
+		ok     bool
+	}{
+		{"0", true}, // The rest of the transaction
+		{"1", true},
+		{"250.50", true},
+		{"0.50", false},
+		{"-10", false},
+	}
+	for _, tt := range tests {
+		err := checkRefundAmount(decimal.RequireFromString(tt.amount))
+		if (err == nil) != tt.ok {
+			t.Errorf("checkRefundAmount(%s) = %v, want ok=%t", tt.amount, err, tt.ok)
+		}
+	}
+}
+
+func TestRefundKind(t *testing.T) {
+	tests := []struct {
+		name     string
+		original models.Transaction
+		want     string // Empty when the transaction cannot be refunded
+	}{
+		{
+			name:     "wallet load",
+			original: models.Transaction{Type: utils.TransactionTypeLoadMoney, RazorpayPaymentID: "pay_1", Status: utils.TransactionStatusSuccess},
+			want:     models.RefundKindWalletLoad,
+		},
+		{
+			name:     "wallet load without a payment",
+			original: models.Transaction{Type: utils.TransactionTypeLoadMoney, Status: utils.TransactionStatusSuccess},
+		},
+		{
+			name:     "AI payment from the wallet",
+			original: models.Transaction{Type: utils.TransactionTypeAIPayment, Status: utils.TransactionStatusSuccess},
+			want:     models.RefundKindAIPayment,
+		},
+		{
+			name:     "AI payment paid out to a merchant",
+			original: models.Transaction{Type: utils.TransactionTypeAIPayment, MerchantUPIID: "shop@upi", Status: utils.TransactionStatusSuccess},
+		},
+		{
+			name:     "pending wallet load",
+			original: models.Transaction{Type: utils.TransactionTypeLoadMoney, RazorpayPaymentID: "pay_2", Status: utils.TransactionStatusPending},
+		},
+		{
+			name:     "external transfer",
+			original: models.Transaction{Type: utils.TransactionTypeExternalTransfer, Status: utils.TransactionStatusSuccess},
+		},
+		{
+			name:     "refund",
+			original: models.Transaction{Type: utils.TransactionTypeRefund, Status: utils.TransactionStatusSuccess},
+		},
+	}
+	for _, tt := range tests {
+		kind, err := refundKind(&tt.original)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%s: refundKind = %q, want an error", tt.name, kind)
+			}
+			continue
+		}
+		if err != nil || kind != tt.want {
+			t.Errorf("%s: refundKind = %q, %v; want %q", tt.name, kind, err, tt.want)
+		}
+	}
+}
+
+func TestRefundAmount(t *testing.T) {
+	tests := []struct {
+		requested, remaining string
+		want                 string // Empty when the refund is refused
+	}{
+		{"0", "1000", "1000"},
+		{"400", "1000", "400"},
+		{"1000", "1000", "1000"},
+		{"0", "250.75", "250.75"},
+		{"1000.01", "1000", ""},
+		{"700", "600", ""},
+	}
+	for _, tt := range tests {
+		got, err := refundAmount(decimal.RequireFromString(tt.requested), decimal.RequireFromString(tt.remaining))
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("refundAmount(%s, %s) = %s, want an error", tt.requested, tt.remaining, got)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("refundAmount(%s, %s) = %s, %v; want %s", tt.requested, tt.remaining, got, err, tt.want)
+		}
+	}
+
+	// Nothing left to refund
+	for _, remaining := range []string{"0", "-5"} {
+		if _, err := refundAmount(decimal.Zero, decimal.RequireFromString(remaining)); !errors.Is(err, ErrAlreadyRefunded) {
+			t.Errorf("refundAmount with %s remaining = %v, want ErrAlreadyRefunded", remaining, err)
+		}
+	}
+}
+
+func TestLoadRefundAgainstFakeRazorpay(t *testing.T) {
+	server, client, recorder := startFakeRazorpay(t, fake.Config{PendingRefunds: true})
+
+	order, err := client.CreateOrder(100000, "INR", "load_1")
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	payment, err := server.PayOrder(order.ID, "upi")
+	if err != nil {
+		t.Fatalf("PayOrder: %v", err)
+	}
+
+	// The submit job keys the refund by its ID, so a retry does not refund twice
+	request := &razorpay.CreateRefundRequest{Amount: 40000, Receipt: "RFD001"}
+	first, err := client.CreateRefund(payment.ID, request, "refund-1")
+	if err != nil {
+		t.Fatalf("CreateRefund: %v", err)
+	}
+	retried, err := client.CreateRefund(payment.ID, request, "refund-1")
+	if err != nil || retried.ID != first.ID {
+		t.Fatalf("retried CreateRefund = %+v, %v; want refund %s again", retried, err, first.ID)
+	}
+
+	// A refund Razorpay turns down fails at once; an outage is retried
+	_, err = client.CreateRefund(payment.ID, &razorpay.CreateRefundRequest{Amount: 70000, Receipt: "RFD002"}, "refund-2")
+	if reason, rejected := refundRejected(err); !rejected || reason == "" {
+		t.Fatalf("refundRejected(%v) = %q, %t; want a rejection with Razorpay's reason", err, reason, rejected)
+	}
+	server.FailNext(1)
+	_, err = client.CreateRefund(payment.ID, &razorpay.CreateRefundRequest{Amount: 10000, Receipt: "RFD003"}, "refund-3")
+	if err == nil {
+		t.Fatal("CreateRefund succeeded while a failure was simulated")
+	}
+	if _, rejected := refundRejected(err); rejected {
+		t.Fatalf("refundRejected(%v) = true, want the outage retried", err)
+	}
+
+	// Razorpay settles the first refund; a second one fails
+	if _, err := server.SetRefundStatus(first.ID, razorpay.RefundStatusProcessed); err != nil {
+		t.Fatalf("SetRefundStatus: %v", err)
+	}
+	second, err := client.CreateRefund(payment.ID, &razorpay.CreateRefundRequest{Amount: 20000, Receipt: "RFD004"}, "refund-4")
+	if err != nil {
+		t.Fatalf("CreateRefund: %v", err)
+	}
+	if _, err := server.SetRefundStatus(second.ID, razorpay.RefundStatusFailed); err != nil {
+		t.Fatalf("SetRefundStatus: %v", err)
+	}
+
+	// The webhooks carry the receipt, which finds the refund before the
+	// submit job has stored Razorpay's ID
+	settled := map[string]string{}
+	for _, event := range recorder.events(t, client, "whsec_test") {
+		if event.Event != utils.WebhookRefundProcessed && event.Event != utils.WebhookRefundFailed {
+			continue
+		}
+		refund, err := webhookRefund(event)
+		if err != nil {
+			t.Fatalf("webhookRefund(%s): %v", event.Event, err)
+		}
+		if refund.PaymentID != payment.ID {
+			t.Fatalf("refund %s is for payment %s, want %s", refund.ID, refund.PaymentID, payment.ID)
+		}
+		settled[refund.Receipt] = event.Event
+	}
+	want := map[string]string{"RFD001": utils.WebhookRefundProcessed, "RFD004": utils.WebhookRefundFailed}
+	if len(settled) != len(want) {
+		t.Fatalf("settled refunds = %v, want %v", settled, want)
+	}
+	for receipt, event := range want {
+		if settled[receipt] != event {
+			t.Fatalf("refund %s settled by %q, want %q", receipt, settled[receipt], event)
+		}
+	}
+
+	// The failed refund's amount can be refunded again
+	rest, err := client.CreateRefund(payment.ID, &razorpay.CreateRefundRequest{Receipt: "RFD005"}, "refund-5")
+	if err != nil {
+		t.Fatalf("CreateRefund of the rest: %v", err)
+	}
+	if rest.Amount != 60000 {
+		t.Fatalf("remaining refund = %d paise, want 60000", rest.Amount)
+	}
+}
+
+func TestWebhookRefund(t *testing.T) {
+	event := &razorpay.WebhookEvent{
+		Event: utils.WebhookRefundProcessed,
+		Payload: map[string]interface{}{
+			"refund": map[string]interface{}{
+				"entity": map[string]interface{}{"id": "rfnd_1", "amount": 5000, "receipt": "RFD1", "status": "processed"},
+			},
+		},
+	}
+	refund, err := webhookRefund(event)
+	if err != nil {
+		t.Fatalf("webhookRefund: %v", err)
+	}
+	if refund.ID != "rfnd_1" || refund.Amount != 5000 || refund.Receipt != "RFD1" || refund.Status != razorpay.RefundStatusProcessed {
+		t.Fatalf("refund = %+v", refund)
+	}
+
+	for _, payload := range []map[string]interface{}{
+		{},
+		{"refund": map[string]interface{}{"entity": map[string]interface{}{"receipt": "RFD2"}}},
+	} {
+		if _, err := webhookRefund(&razorpay.WebhookEvent{Payload: payload}); err == nil {
+			t.Errorf("webhookRefund(%v) succeeded", payload)
+		}
+	}
+}
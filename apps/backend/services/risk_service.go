@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/pkg/risk"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"github.com/zeusnotfound04/Tranza/utils"
+	"gorm.io/gorm"
+)
+
+// RiskLocation is the time zone the unusual hour rule reads payment times in
+var RiskLocation = time.FixedZone("IST", 5*60*60+30*60)
+
+// RiskConfirmationTTL is how long the user has to confirm a payment the risk
+// checks asked about
+const RiskConfirmationTTL = 15 * time.Minute
+
+// RiskDecisionError is returned when the risk engine blocks a payment, or
+// wants the user to confirm it first
+type RiskDecisionError struct {
+	AssessmentID string   `json:"assessment_id"`
+	Action       string   `json:"action"`
+	Score        int      `json:"score"`
+	Reasons      []string `json:"reasons"`
+}
+
+func (e *RiskDecisionError) Error() string {
+	if e.Action == risk.ActionBlock {
+		return "payment blocked by risk checks: " + strings.Join(e.Reasons, "; ")
+	}
+	return "payment needs confirmation: " + strings.Join(e.Reasons, "; ")
+}
+
+// NeedsConfirmation reports whether the payment can go ahead once the user confirms it
+func (e *RiskDecisionError) NeedsConfirmation() bool {
+	return e.Action == risk.ActionConfirm
+}
+
+// RiskService scores outgoing payments and keeps a record of every decision
+type RiskService struct {
+	riskRepo *repositories.RiskRepository
+	engine   *risk.Engine
+}
+
+func NewRiskService(riskRepo *repositories.RiskRepository, engine *risk.Engine) *RiskService {
+	return &RiskService{
+		riskRepo: riskRepo,
+		engine:   engine,
+	}
+}
+
+// Assess scores a payment and records the assessment. It returns a
+// RiskDecisionError, alongside the assessment, when the payment is blocked or
+// needs a confirmation the user has not given yet. confirmationID is the
+// assessment_id of an earlier RiskDecisionError the user confirmed; it lets
+// the payment through once, if it was the same user, kind, amount and
+// recipient within RiskConfirmationTTL.
+func (s *RiskService) Assess(payment *risk.Payment, confirmationID string) (*models.RiskAssessment, error) {
+	payment.Recipient = strings.TrimSpace(payment.Recipient)
+
+	decision, err := s.engine.Evaluate(context.Background(), payment, s.riskRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	confirmed := false
+	if decision.Action == risk.ActionConfirm && confirmationID != "" {
+		if id, err := uuid.Parse(confirmationID); err == nil {
+			confirmed, err = s.riskRepo.UseConfirmation(id, payment.UserID, payment.Kind, payment.Amount, payment.Recipient, time.Now().Add(-RiskConfirmationTTL))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	assessment := &models.RiskAssessment{
+		UserID:    payment.UserID,
+		Kind:      payment.Kind,
+		Amount:    payment.Amount,
+		Recipient: payment.Recipient,
+		IPAddress: payment.IPAddress,
+		Score:     decision.Score,
+		Level:     decision.Level,
+		Action:    decision.Action,
+		Reasons:   decision.Reasons,
+		Confirmed: confirmed,
+	}
+	if err := s.riskRepo.Create(assessment); err != nil {
+		return nil, err
+	}
+
+	if decision.Allowed() || assessment.Confirmed {
+		return assessment, nil
+	}
+
+	utils.LogWarning("Payment held by risk checks", map[string]interface{}{
+		"assessment_id": assessment.ID.String(),
+		"user_id":       payment.UserID.String(),
+		"kind":          payment.Kind,
+		"amount":        payment.Amount.String(),
+		"score":         decision.Score,
+		"action":        decision.Action,
+		"reasons":       decision.Summary(),
+	})
+
+	return assessment, &RiskDecisionError{
+		AssessmentID: assessment.ID.String(),
+		Action:       decision.Action,
+		Score:        decision.Score,
+		Reasons:      decision.Messages(),
+	}
+}
+
+// LinkAssessment points an assessment at the payment created after it. A
+// failure is only logged; the payment has already been made.
+func (s *RiskService) LinkAssessment(assessment *models.RiskAssessment, subjectID uuid.UUID) {
+	if err := s.riskRepo.LinkSubject(assessment.ID, subjectID); err != nil {
+		utils.LogError(err, map[string]interface{}{
+			"assessment_id": assessment.ID.String(),
+			"subject_id":    subjectID.String(),
+			"action":        "link_risk_assessment",
+		})
+	}
+}
+
+// ConfirmAssessment records that the user confirmed a payment the risk checks asked about
+func (s *RiskService) ConfirmAssessment(tx *gorm.DB, subjectID uuid.UUID) error {
+	return s.riskRepo.MarkConfirmed(tx, subjectID)
+}
+
+// GetAssessments lists assessments newest first, optionally for one user or action
+func (s *RiskService) GetAssessments(userID, action string, page, limit int) ([]*models.RiskAssessment, int64, error) {
+	var uid *uuid.UUID
+	if userID != "" {
+		parsed, err := uuid.Parse(userID)
+		if err != nil {
+			return nil, 0, errors.New("invalid user ID")
+		}
+		uid = &parsed
+	}
+
+	switch action {
+	case "", risk.ActionAllow, risk.ActionConfirm, risk.ActionBlock:
+	default:
+		return nil, 0, errors.New("action must be allow, require_confirmation or block")
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	return s.riskRepo.GetAll(uid, action, limit, (page-1)*limit)
+}
+
+// GetAssessment retrieves the assessment of an AI payment request or external transfer
+func (s *RiskService) GetAssessment(subjectID string) (*models.RiskAssessment, error) {
+	id, err := uuid.Parse(subjectID)
+	if err != nil {
+		return nil, errors.New("invalid payment ID")
+	}
+	return s.riskRepo.GetBySubjectID(id)
+}
@@ -0,0 +1,695 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/pkg/period"
+	"github.com/zeusnotfound04/Tranza/pkg/schedule"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"github.com/zeusnotfound04/Tranza/utils"
+	"gorm.io/gorm"
+)
+
+// Scheduled payment settings
+const (
+	ScheduledPaymentRetryInterval  = time.Hour // How long to wait for funds before retrying a run
+	DefaultScheduledPaymentRetries = 3         // Retries when the request does not set max_retries
+)
+
+var ErrScheduleNoFutureRuns = errors.New("schedule has no runs left after its start and end dates")
+
+// ScheduledPaymentService runs transfers the user set up for later, once or
+// on a repeating rule. Each due run is a job on the job queue, so runs survive
+// restarts and are picked up by exactly one worker.
+type ScheduledPaymentService struct {
+	db                      *gorm.DB
+	scheduleRepo            *repositories.ScheduledPaymentRepository
+	walletRepo              *repositories.WalletRepository
+	externalTransferRepo    *repositories.ExternalTransferRepository
+	holdService             *HoldService
+	externalTransferService *ExternalTransferService
+	walletTransferService   *WalletTransferService
+	jobQueue                *JobQueue
+	notificationService     *NotificationService
+}
+
+// scheduledRunJobPayload identifies the occurrence a run job pays
+type scheduledRunJobPayload struct {
+	ScheduleID   string    `json:"schedule_id"`
+	ScheduledFor time.Time `json:"scheduled_for"`
+}
+
+func NewScheduledPaymentService(
+	db *gorm.DB,
+	scheduleRepo *repositories.ScheduledPaymentRepository,
+	walletRepo *repositories.WalletRepository,
+	externalTransferRepo *repositories.ExternalTransferRepository,
+	holdService *HoldService,
+	externalTransferService *ExternalTransferService,
+	walletTransferService *WalletTransferService,
+	jobQueue *JobQueue,
+	notificationService *NotificationService,
+) *ScheduledPaymentService {
+	return &ScheduledPaymentService{
+		db:                      db,
+		scheduleRepo:            scheduleRepo,
+		walletRepo:              walletRepo,
+		externalTransferRepo:    externalTransferRepo,
+		holdService:             holdService,
+		externalTransferService: externalTransferService,
+		walletTransferService:   walletTransferService,
+		jobQueue:                jobQueue,
+		notificationService:     notificationService,
+	}
+}
+
+// RegisterJobHandlers registers the scheduled payment run job with the job queue
+func (s *ScheduledPaymentService) RegisterJobHandlers() {
+	s.jobQueue.Register(models.JobTypeScheduledRun, utils.GetMaxRetryAttempts(), s.handleRunJob, s.handleRunDead)
+}
+
+// RegisterTransferHandlers has scheduled transfers report back to their runs.
+// Call before the job queue starts.
+func (s *ScheduledPaymentService) RegisterTransferHandlers() {
+	s.externalTransferService.OnTransferComplete(s.handleTransferComplete)
+}
+
+// ResumeSchedules queues the next run of every active schedule, e.g. after a
+// restart lost the queue. Runs that are already queued are skipped by the
+// dedupe key.
+func (s *ScheduledPaymentService) ResumeSchedules() error {
+	schedules, err := s.scheduleRepo.GetActive()
+	if err != nil {
+		return err
+	}
+
+	for _, sp := range schedules {
+		if sp.NextRunAt == nil {
+			continue
+		}
+		if err := s.enqueueRun(nil, sp.ID, *sp.NextRunAt); err != nil {
+			utils.LogError(err, map[string]interface{}{"schedule_id": sp.ID.String(), "action": "resume_schedule"})
+		}
+	}
+	return nil
+}
+
+// CreateSchedule sets up a one-off or recurring payment and queues its first run
+func (s *ScheduledPaymentService) CreateSchedule(userID uuid.UUID, req *dto.CreateScheduledPaymentRequest) (*models.ScheduledPayment, error) {
+	if _, err := s.walletRepo.GetByUserID(userID); err != nil {
+		return nil, errors.New("wallet not found")
+	}
+
+	sp := &models.ScheduledPayment{
+		UserID:            userID,
+		Name:              strings.TrimSpace(req.Name),
+		PaymentType:       req.PaymentType,
+		RecipientType:     req.RecipientType,
+		RecipientValue:    strings.TrimSpace(req.RecipientValue),
+		RecipientName:     req.RecipientName,
+		Amount:            req.Amount,
+		Description:       req.Description,
+		Frequency:         req.Frequency,
+		TimeOfDay:         req.TimeOfDay,
+		DayOfWeek:         req.DayOfWeek,
+		DayOfMonth:        req.DayOfMonth,
+		CronExpr:          strings.TrimSpace(req.CronExpr),
+		Timezone:          req.Timezone,
+		EndAt:             req.EndAt,
+		MaxRuns:           req.MaxRuns,
+		InsufficientFunds: req.InsufficientFunds,
+		MaxRetries:        DefaultScheduledPaymentRetries,
+		Status:            models.ScheduledPaymentStatusActive,
+	}
+	// Whole seconds, so run times survive the round trip through Postgres
+	sp.StartAt = time.Now().Truncate(time.Second)
+	if req.StartAt != nil {
+		sp.StartAt = req.StartAt.Truncate(time.Second)
+	}
+	if req.MaxRetries != nil {
+		sp.MaxRetries = *req.MaxRetries
+	}
+	if sp.InsufficientFunds == "" {
+		sp.InsufficientFunds = models.InsufficientFundsRetry
+	}
+	if sp.Timezone == "" {
+		sp.Timezone = period.DefaultTimezone
+	} else if !period.Valid(sp.Timezone) {
+		return nil, fmt.Errorf("unknown timezone %q", sp.Timezone)
+	}
+
+	if err := s.validateRecipient(userID, sp); err != nil {
+		return nil, err
+	}
+	if err := scheduleRule(sp).Validate(); err != nil {
+		return nil, err
+	}
+	if sp.Frequency == schedule.Once && !sp.StartAt.After(time.Now()) {
+		return nil, errors.New("a one-off payment must be scheduled in the future")
+	}
+	if sp.EndAt != nil && !sp.EndAt.After(sp.StartAt) {
+		return nil, errors.New("end date must be after the start date")
+	}
+
+	next, ok := s.nextRun(sp, s.firstRunAfter(sp))
+	if !ok {
+		return nil, ErrScheduleNoFutureRuns
+	}
+	sp.NextRunAt = &next
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.scheduleRepo.Create(tx, sp); err != nil {
+			return err
+		}
+		return s.enqueueRun(tx, sp.ID, next)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	utils.LogInfo("Scheduled payment created", map[string]interface{}{
+		"schedule_id": sp.ID.String(),
+		"user_id":     userID.String(),
+		"schedule":    scheduleRule(sp).Describe(),
+		"next_run_at": next,
+	})
+
+	return sp, nil
+}
+
+// GetSchedules lists a user's scheduled payments, optionally with one status
+func (s *ScheduledPaymentService) GetSchedules(userID uuid.UUID, status string, page, limit int) ([]*models.ScheduledPayment, int64, error) {
+	return s.scheduleRepo.GetByUserID(userID, status, limit, (page-1)*limit)
+}
+
+// GetSchedule returns one of the user's scheduled payments
+func (s *ScheduledPaymentService) GetSchedule(userID, scheduleID uuid.UUID) (*models.ScheduledPayment, error) {
+	return s.scheduleRepo.GetByIDAndUserID(scheduleID, userID)
+}
+
+// GetScheduleRuns lists the runs of one of the user's scheduled payments
+func (s *ScheduledPaymentService) GetScheduleRuns(userID, scheduleID uuid.UUID, page, limit int) ([]*models.ScheduledPaymentRun, int64, error) {
+	if _, err := s.scheduleRepo.GetByIDAndUserID(scheduleID, userID); err != nil {
+		return nil, 0, err
+	}
+	return s.scheduleRepo.GetRuns(scheduleID, limit, (page-1)*limit)
+}
+
+// PauseSchedule stops an active schedule from running until it is resumed.
+// A run already waiting for funds is skipped.
+func (s *ScheduledPaymentService) PauseSchedule(userID, scheduleID uuid.UUID) (*models.ScheduledPayment, error) {
+	return s.updateSchedule(userID, scheduleID, func(tx *gorm.DB, sp *models.ScheduledPayment) error {
+		if sp.Status != models.ScheduledPaymentStatusActive {
+			return fmt.Errorf("cannot pause a %s schedule", sp.Status)
+		}
+		sp.Status = models.ScheduledPaymentStatusPaused
+		sp.NextRunAt = nil
+		return nil
+	})
+}
+
+// ResumeSchedule restarts a paused schedule from its next run after now. Runs
+// missed while paused are not made up.
+func (s *ScheduledPaymentService) ResumeSchedule(userID, scheduleID uuid.UUID) (*models.ScheduledPayment, error) {
+	return s.updateSchedule(userID, scheduleID, func(tx *gorm.DB, sp *models.ScheduledPayment) error {
+		if sp.Status != models.ScheduledPaymentStatusPaused {
+			return fmt.Errorf("cannot resume a %s schedule", sp.Status)
+		}
+
+		next, ok := s.nextRun(sp, s.firstRunAfter(sp))
+		if !ok {
+			return ErrScheduleNoFutureRuns
+		}
+		sp.Status = models.ScheduledPaymentStatusActive
+		sp.NextRunAt = &next
+		return s.enqueueRun(tx, sp.ID, next)
+	})
+}
+
+// CancelSchedule stops a schedule for good
+func (s *ScheduledPaymentService) CancelSchedule(userID, scheduleID uuid.UUID) (*models.ScheduledPayment, error) {
+	return s.updateSchedule(userID, scheduleID, func(tx *gorm.DB, sp *models.ScheduledPayment) error {
+		if sp.Status != models.ScheduledPaymentStatusActive && sp.Status != models.ScheduledPaymentStatusPaused {
+			return fmt.Errorf("cannot cancel a %s schedule", sp.Status)
+		}
+		sp.Status = models.ScheduledPaymentStatusCancelled
+		sp.NextRunAt = nil
+		return nil
+	})
+}
+
+// updateSchedule applies change to one of the user's schedules with its row
+// locked, so it cannot race a run being claimed
+func (s *ScheduledPaymentService) updateSchedule(userID, scheduleID uuid.UUID, change func(tx *gorm.DB, sp *models.ScheduledPayment) error) (*models.ScheduledPayment, error) {
+	var updated *models.ScheduledPayment
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		sp, err := s.scheduleRepo.GetByIDForUpdate(tx, scheduleID)
+		if err != nil {
+			return err
+		}
+		if sp.UserID != userID {
+			return errors.New("scheduled payment not found")
+		}
+
+		if err := change(tx, sp); err != nil {
+			return err
+		}
+		if err := s.scheduleRepo.Update(tx, sp); err != nil {
+			return err
+		}
+		updated = sp
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// handleRunJob pays one occurrence of a schedule. The first time an
+// occurrence is handled its run is recorded and the schedule moves on to the
+// next occurrence, in one transaction, so every occurrence runs at most once.
+func (s *ScheduledPaymentService) handleRunJob(job *models.Job) error {
+	var payload scheduledRunJobPayload
+	if err := DecodeJobPayload(job, &payload); err != nil {
+		return err
+	}
+	scheduleID, err := uuid.Parse(payload.ScheduleID)
+	if err != nil {
+		return errors.New("invalid schedule ID")
+	}
+
+	var sp *models.ScheduledPayment
+	var run *models.ScheduledPaymentRun
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		sp, err = s.scheduleRepo.GetByIDForUpdate(tx, scheduleID)
+		if err != nil {
+			return err
+		}
+
+		if sp.Status == models.ScheduledPaymentStatusActive && sp.NextRunAt != nil && sp.NextRunAt.Equal(payload.ScheduledFor) {
+			run = &models.ScheduledPaymentRun{
+				ScheduleID:   sp.ID,
+				UserID:       sp.UserID,
+				ScheduledFor: payload.ScheduledFor,
+				Status:       models.ScheduledRunStatusPending,
+				Amount:       sp.Amount,
+			}
+			if err := s.scheduleRepo.CreateRun(tx, run); err != nil {
+				return err
+			}
+			return s.advance(tx, sp, payload.ScheduledFor)
+		}
+
+		// A retry of an occurrence already claimed, or a stale job left
+		// behind by a pause or resume
+		run, err = s.scheduleRepo.GetRun(tx, scheduleID, payload.ScheduledFor)
+		if errors.Is(err, repositories.ErrRunNotFound) {
+			run = nil
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if run == nil {
+		return nil
+	}
+
+	switch run.Status {
+	case models.ScheduledRunStatusPending, models.ScheduledRunStatusRetrying:
+	case models.ScheduledRunStatusProcessing:
+		// The worker paying this run went away part way through. The transfer
+		// may or may not have been made, so it is not tried again.
+		s.finishRun(sp, run, models.ScheduledRunStatusFailed, "", "run was interrupted; check your transactions before paying again")
+		return nil
+	default:
+		return nil
+	}
+
+	// Claiming the last occurrence completes the schedule; only a pause or
+	// cancel since the run was claimed stops it
+	if sp.Status == models.ScheduledPaymentStatusPaused || sp.Status == models.ScheduledPaymentStatusCancelled {
+		s.finishRun(sp, run, models.ScheduledRunStatusSkipped, "", "schedule is "+sp.Status)
+		return nil
+	}
+
+	return s.executeRun(sp, run)
+}
+
+// handleRunDead fails a run whose job could not be handled
+func (s *ScheduledPaymentService) handleRunDead(job *models.Job, err error) {
+	utils.LogError(err, map[string]interface{}{"job_id": job.ID.String(), "action": "scheduled_payment_run_dead"})
+
+	var payload scheduledRunJobPayload
+	if DecodeJobPayload(job, &payload) != nil {
+		return
+	}
+	scheduleID, parseErr := uuid.Parse(payload.ScheduleID)
+	if parseErr != nil {
+		return
+	}
+	sp, getErr := s.scheduleRepo.GetByID(scheduleID)
+	if getErr != nil {
+		return
+	}
+	run, getErr := s.scheduleRepo.GetRun(nil, scheduleID, payload.ScheduledFor)
+	if getErr != nil {
+		return
+	}
+	if run.Status == models.ScheduledRunStatusPending || run.Status == models.ScheduledRunStatusRetrying {
+		s.finishRun(sp, run, models.ScheduledRunStatusFailed, "", err.Error())
+	}
+}
+
+// executeRun makes the transfer for a claimed run. Short balances are retried
+// or skipped as the schedule asks; any other failure fails the run without a
+// retry, since a transfer that errored part way must not be paid twice. A
+// payout leaves the run submitted until the transfer finishes.
+func (s *ScheduledPaymentService) executeRun(sp *models.ScheduledPayment, run *models.ScheduledPaymentRun) error {
+	wallet, err := s.walletRepo.GetByUserID(sp.UserID)
+	if err != nil {
+		s.finishRun(sp, run, models.ScheduledRunStatusFailed, "", "wallet not found")
+		return nil
+	}
+
+	required := sp.Amount
+	if sp.PaymentType == models.ScheduledPaymentTypeExternal {
+		// A transfer that cannot be priced fails below with the reason
+		if quote, err := s.externalTransferService.QuoteFee(sp.UserID, sp.RecipientType, "", sp.Amount); err == nil {
+			required = required.Add(quote.Fee)
+		}
+	}
+	available, _, err := s.holdService.GetAvailableBalance(wallet)
+	if err != nil {
+		return err
+	}
+	if available.LessThan(required) {
+		return s.handleInsufficientFunds(sp, run, required, available)
+	}
+
+	if err := s.scheduleRepo.TransitionRun(run.ID, []string{models.ScheduledRunStatusPending, models.ScheduledRunStatusRetrying}, models.ScheduledRunStatusProcessing); err != nil {
+		if errors.Is(err, repositories.ErrRunStatusChanged) {
+			return nil
+		}
+		return err
+	}
+	run.Status = models.ScheduledRunStatusProcessing
+	run.Attempts++
+
+	referenceID, transferID, err := s.pay(sp)
+	if err != nil {
+		s.finishRun(sp, run, models.ScheduledRunStatusFailed, "", err.Error())
+		return nil
+	}
+	if transferID == nil {
+		s.finishRun(sp, run, models.ScheduledRunStatusSuccess, referenceID, "")
+		return nil
+	}
+
+	run.Status = models.ScheduledRunStatusSubmitted
+	run.ReferenceID = referenceID
+	run.TransferID = transferID
+	if err := s.scheduleRepo.UpdateRun(nil, run); err != nil {
+		utils.LogError(err, map[string]interface{}{"run_id": run.ID.String(), "action": "update_scheduled_payment_run"})
+		return nil
+	}
+	if err := s.scheduleRepo.RecordRun(sp.ID, time.Now(), run.Status); err != nil {
+		utils.LogError(err, map[string]interface{}{"schedule_id": sp.ID.String(), "action": "record_scheduled_payment_run"})
+	}
+
+	// The transfer may have finished before the run recorded it
+	if transfer, err := s.externalTransferRepo.GetByID(*transferID); err == nil && transfer.IsCompleted() {
+		s.applyTransferOutcome(sp, run, transfer)
+	}
+	return nil
+}
+
+// handleTransferComplete settles the run that made a scheduled transfer
+func (s *ScheduledPaymentService) handleTransferComplete(transfer *models.ExternalTransfer) {
+	if transfer.InitiatedBy != models.InitiatedBySchedule {
+		return
+	}
+
+	run, err := s.scheduleRepo.GetRunByTransferID(transfer.ID)
+	if err != nil {
+		// The run records its transfer right after creating it and checks
+		// for an early outcome then
+		return
+	}
+	sp, err := s.scheduleRepo.GetByID(run.ScheduleID)
+	if err != nil {
+		utils.LogError(err, map[string]interface{}{"run_id": run.ID.String(), "action": "get_schedule_for_transfer"})
+		return
+	}
+	s.applyTransferOutcome(sp, run, transfer)
+}
+
+// applyTransferOutcome finishes a submitted run with its transfer's final
+// status. A payout reversed after it succeeded fails the run.
+func (s *ScheduledPaymentService) applyTransferOutcome(sp *models.ScheduledPayment, run *models.ScheduledPaymentRun, transfer *models.ExternalTransfer) {
+	var status, reason string
+	var from []string
+	switch transfer.Status {
+	case models.ExternalTransferStatusSuccess:
+		status = models.ScheduledRunStatusSuccess
+		from = []string{models.ScheduledRunStatusSubmitted}
+	case models.ExternalTransferStatusFailed, models.ExternalTransferStatusCancelled, models.ExternalTransferStatusRefunded:
+		status = models.ScheduledRunStatusFailed
+		from = []string{models.ScheduledRunStatusSubmitted, models.ScheduledRunStatusSuccess}
+		reason = transfer.FailureReason
+		if reason == "" {
+			reason = "transfer " + transfer.Status
+		}
+	default:
+		return
+	}
+
+	// Claim the outcome so the completion handler and the early check do
+	// not both report it
+	if err := s.scheduleRepo.TransitionRun(run.ID, from, status); err != nil {
+		if !errors.Is(err, repositories.ErrRunStatusChanged) {
+			utils.LogError(err, map[string]interface{}{"run_id": run.ID.String(), "action": "settle_scheduled_payment_run"})
+		}
+		return
+	}
+	s.finishRun(sp, run, status, run.ReferenceID, reason)
+}
+
+// handleInsufficientFunds retries a run later or skips it
+func (s *ScheduledPaymentService) handleInsufficientFunds(sp *models.ScheduledPayment, run *models.ScheduledPaymentRun, required, available decimal.Decimal) error {
+	reason := fmt.Sprintf("insufficient wallet balance: ₹%s needed, ₹%s available", required.StringFixed(2), available.StringFixed(2))
+
+	if sp.InsufficientFunds == models.InsufficientFundsRetry && run.Attempts < sp.MaxRetries {
+		run.Attempts++
+		run.Status = models.ScheduledRunStatusRetrying
+		run.Reason = reason
+		if err := s.scheduleRepo.UpdateRun(nil, run); err != nil {
+			return err
+		}
+
+		go s.notificationService.SendScheduledPaymentNotification(
+			sp.UserID.String(), sp.Name, sp.Amount, "waiting for funds",
+			fmt.Sprintf("%s; retrying in %s", reason, ScheduledPaymentRetryInterval))
+		return &JobNotReadyError{RetryAfter: ScheduledPaymentRetryInterval}
+	}
+
+	s.finishRun(sp, run, models.ScheduledRunStatusSkipped, "", reason)
+	return nil
+}
+
+// pay makes the schedule's transfer and returns its reference, and the ID of
+// the external transfer when a payout is still to be made
+func (s *ScheduledPaymentService) pay(sp *models.ScheduledPayment) (string, *uuid.UUID, error) {
+	description := sp.Description
+	if description == "" {
+		description = "Scheduled payment: " + sp.Name
+	}
+
+	if sp.PaymentType == models.ScheduledPaymentTypeWallet {
+		result, err := s.walletTransferService.TransferToWallet(sp.UserID.String(), &dto.WalletTransferRequest{
+			Recipient:   sp.RecipientValue,
+			Amount:      sp.Amount,
+			Description: description,
+			InitiatedBy: models.InitiatedBySchedule,
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		return result.ReferenceID, nil, nil
+	}
+
+	result, err := s.externalTransferService.CreateExternalTransfer(sp.UserID.String(), &dto.CreateExternalTransferRequest{
+		Amount:         sp.Amount,
+		Currency:       "INR",
+		Description:    description,
+		RecipientType:  sp.RecipientType,
+		RecipientValue: sp.RecipientValue,
+		RecipientName:  sp.RecipientName,
+		InitiatedBy:    models.InitiatedBySchedule,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	// A phone number of a Tranza user was paid straight into their wallet
+	if result.WalletTransactionID != "" {
+		return result.ReferenceID, nil, nil
+	}
+	transferID, err := uuid.Parse(result.ID)
+	if err != nil {
+		return "", nil, errors.New("invalid transfer ID")
+	}
+	return result.ReferenceID, &transferID, nil
+}
+
+// finishRun records a run's outcome on the run and its schedule and tells the user
+func (s *ScheduledPaymentService) finishRun(sp *models.ScheduledPayment, run *models.ScheduledPaymentRun, status, referenceID, reason string) {
+	now := time.Now()
+	run.Status = status
+	run.ReferenceID = referenceID
+	run.Reason = reason
+	run.CompletedAt = &now
+	if err := s.scheduleRepo.UpdateRun(nil, run); err != nil {
+		utils.LogError(err, map[string]interface{}{"run_id": run.ID.String(), "action": "update_scheduled_payment_run"})
+	}
+	if err := s.scheduleRepo.RecordRun(sp.ID, now, status); err != nil {
+		utils.LogError(err, map[string]interface{}{"schedule_id": sp.ID.String(), "action": "record_scheduled_payment_run"})
+	}
+
+	detail := reason
+	if status == models.ScheduledRunStatusSuccess {
+		detail = "reference " + referenceID
+	}
+	go s.notificationService.SendScheduledPaymentNotification(sp.UserID.String(), sp.Name, sp.Amount, status, detail)
+
+	utils.LogInfo("Scheduled payment run finished", map[string]interface{}{
+		"schedule_id":   sp.ID.String(),
+		"run_id":        run.ID.String(),
+		"scheduled_for": run.ScheduledFor,
+		"status":        status,
+		"reason":        reason,
+	})
+}
+
+// advance moves a schedule past an occurrence that has just been claimed and
+// queues the one after it, or completes the schedule if none is left
+func (s *ScheduledPaymentService) advance(tx *gorm.DB, sp *models.ScheduledPayment, occurrence time.Time) error {
+	sp.RunCount++
+
+	next, ok := s.nextRun(sp, occurrence)
+	if !ok {
+		sp.NextRunAt = nil
+		sp.Status = models.ScheduledPaymentStatusCompleted
+		return s.scheduleRepo.Update(tx, sp)
+	}
+
+	sp.NextRunAt = &next
+	if err := s.scheduleRepo.Update(tx, sp); err != nil {
+		return err
+	}
+	return s.enqueueRun(tx, sp.ID, next)
+}
+
+// nextRun returns the schedule's first occurrence after after that is within
+// its end date and run count
+func (s *ScheduledPaymentService) nextRun(sp *models.ScheduledPayment, after time.Time) (time.Time, bool) {
+	if sp.MaxRuns > 0 && sp.RunCount >= sp.MaxRuns {
+		return time.Time{}, false
+	}
+
+	next, ok := scheduleRule(sp).Next(after, period.Location(sp.Timezone))
+	if !ok {
+		return time.Time{}, false
+	}
+	if sp.EndAt != nil && next.After(*sp.EndAt) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// firstRunAfter returns the time the next run is searched from: now, or just
+// before the start date so a run due exactly at the start is included
+func (s *ScheduledPaymentService) firstRunAfter(sp *models.ScheduledPayment) time.Time {
+	now := time.Now()
+	if sp.StartAt.After(now) {
+		return sp.StartAt.Add(-time.Nanosecond)
+	}
+	return now
+}
+
+// validateRecipient checks the schedule's recipient and amount the way the
+// transfer it will make does, so mistakes show up now rather than on the
+// first run
+func (s *ScheduledPaymentService) validateRecipient(userID uuid.UUID, sp *models.ScheduledPayment) error {
+	switch sp.PaymentType {
+	case models.ScheduledPaymentTypeExternal:
+		if sp.RecipientType == "" {
+			return errors.New("recipient_type is required for external payments")
+		}
+		if err := s.externalTransferService.validateRecipient(sp.RecipientType, sp.RecipientValue, ""); err != nil {
+			return err
+		}
+		// A phone number's name is looked up when the run pays it
+		if sp.RecipientType == models.RecipientTypeUPI && strings.TrimSpace(sp.RecipientName) == "" {
+			return errors.New("recipient_name is required for UPI payments")
+		}
+		if sp.Amount.LessThan(decimal.NewFromFloat(MinTransferAmount)) {
+			return fmt.Errorf("minimum transfer amount is ₹%.2f", MinTransferAmount)
+		}
+		if sp.Amount.GreaterThan(decimal.NewFromFloat(MaxTransferAmount)) {
+			return fmt.Errorf("maximum transfer amount is ₹%d", MaxTransferAmount)
+		}
+	case models.ScheduledPaymentTypeWallet:
+		sp.RecipientType = ""
+		wallet, user, err := s.walletTransferService.resolveRecipient(sp.RecipientValue)
+		if err != nil {
+			return err
+		}
+		if wallet.UserID == userID {
+			return errors.New("cannot schedule a transfer to your own wallet")
+		}
+		if sp.RecipientName == "" {
+			sp.RecipientName = user.Username
+		}
+		if sp.Amount.LessThan(decimal.NewFromFloat(MinWalletTransferAmount)) {
+			return fmt.Errorf("minimum transfer amount is ₹%.2f", MinWalletTransferAmount)
+		}
+		if sp.Amount.GreaterThan(decimal.NewFromFloat(MaxWalletTransferAmount)) {
+			return fmt.Errorf("maximum transfer amount is ₹%d", MaxWalletTransferAmount)
+		}
+	default:
+		return fmt.Errorf("unknown payment type %q", sp.PaymentType)
+	}
+	return nil
+}
+
+func (s *ScheduledPaymentService) enqueueRun(tx *gorm.DB, scheduleID uuid.UUID, runAt time.Time) error {
+	return s.jobQueue.Enqueue(
+		tx,
+		models.JobTypeScheduledRun,
+		scheduledRunJobPayload{ScheduleID: scheduleID.String(), ScheduledFor: runAt},
+		fmt.Sprintf("scheduled_payment:%s:%d", scheduleID, runAt.Unix()),
+		runAt,
+	)
+}
+
+// scheduleRule returns the schedule's repeat rule
+func scheduleRule(sp *models.ScheduledPayment) schedule.Rule {
+	return schedule.Rule{
+		Frequency:  sp.Frequency,
+		TimeOfDay:  sp.TimeOfDay,
+		DayOfWeek:  time.Weekday(sp.DayOfWeek),
+		DayOfMonth: sp.DayOfMonth,
+		Cron:       sp.CronExpr,
+		At:         sp.StartAt,
+	}
+}
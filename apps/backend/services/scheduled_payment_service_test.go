@@ -0,0 +1,212 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/pkg/razorpay"
+	"github.com/zeusnotfound04/Tranza/pkg/razorpay/fake"
+	"github.com/zeusnotfound04/Tranza/pkg/schedule"
+)
+
+// createTestSchedule schedules a one-off UPI payment an hour from now that
+// retries up to maxRetries times when the wallet is short
+func createTestSchedule(t *testing.T, s *testServices, wallet *models.Wallet, vpa, amount string, maxRetries int) *models.ScheduledPayment {
+	t.Helper()
+
+	startAt := time.Now().Add(time.Hour)
+	sp, err := s.scheduled.CreateSchedule(wallet.UserID, &dto.CreateScheduledPaymentRequest{
+		Name:              "Rent",
+		PaymentType:       models.ScheduledPaymentTypeExternal,
+		RecipientType:     models.RecipientTypeUPI,
+		RecipientValue:    vpa,
+		RecipientName:     "Landlord",
+		Amount:            decimal.RequireFromString(amount),
+		Frequency:         schedule.Once,
+		StartAt:           &startAt,
+		InsufficientFunds: models.InsufficientFundsRetry,
+		MaxRetries:        &maxRetries,
+	})
+	if err != nil {
+		t.Fatalf("CreateSchedule: %v", err)
+	}
+	return sp
+}
+
+// scheduleRun loads the one run of a one-off schedule
+func scheduleRun(t *testing.T, s *testServices, sp *models.ScheduledPayment) *models.ScheduledPaymentRun {
+	t.Helper()
+
+	var runs []*models.ScheduledPaymentRun
+	if err := s.db.Where("schedule_id = ?", sp.ID).Find(&runs).Error; err != nil {
+		t.Fatalf("failed to load runs: %v", err)
+	}
+	if len(runs) != 1 {
+		t.Fatalf("schedule has %d runs, want 1", len(runs))
+	}
+	return runs[0]
+}
+
+func requireRun(t *testing.T, s *testServices, sp *models.ScheduledPayment, status string, attempts int) *models.ScheduledPaymentRun {
+	t.Helper()
+
+	run := scheduleRun(t, s, sp)
+	if run.Status != status || run.Attempts != attempts {
+		t.Fatalf("run is %s after %d attempts (%s), want %s after %d", run.Status, run.Attempts, run.Reason, status, attempts)
+	}
+	return run
+}
+
+func TestScheduledRunPaysOnce(t *testing.T) {
+	db := testDB(t)
+	s, server := newTestServicesWithRazorpay(t, db, fake.Config{})
+	server.SetPayoutScript("", fake.PayoutStep{Status: razorpay.PayoutStatusProcessing}, fake.PayoutStep{Status: razorpay.PayoutStatusProcessed})
+	wallet := createTestWallet(t, db, "10000", "50000")
+	sp := createTestSchedule(t, s, wallet, "landlord@upi", "1000", 3)
+
+	// A restart queues the run again, which the dedupe key drops
+	if err := s.scheduled.ResumeSchedules(); err != nil {
+		t.Fatalf("ResumeSchedules: %v", err)
+	}
+	if n := countJobs(t, db, models.JobTypeScheduledRun); n != 1 {
+		t.Fatalf("%d run jobs queued, want 1", n)
+	}
+
+	if n := s.runJobs(t, models.JobTypeScheduledRun); n != 1 {
+		t.Fatalf("ran %d run jobs, want 1", n)
+	}
+	run := requireRun(t, s, sp, models.ScheduledRunStatusSubmitted, 1)
+	if run.TransferID == nil {
+		t.Fatal("submitted run has no transfer")
+	}
+
+	// The worker dies before marking the job done and the job runs again
+	s.redeliverJobs(t, models.JobTypeScheduledRun)
+	if n := s.runJobs(t, models.JobTypeScheduledRun); n != 1 {
+		t.Fatalf("ran %d redelivered run jobs, want 1", n)
+	}
+	if n := countRows(t, db, &models.ExternalTransfer{}); n != 1 {
+		t.Fatalf("%d transfers made, want 1", n)
+	}
+
+	// The payout is still processing when submitted; its processed webhook
+	// settles the run
+	s.runJobs(t, models.JobTypePayoutSubmit)
+	requireRun(t, s, sp, models.ScheduledRunStatusSubmitted, 1)
+	transfer, err := s.transferRepo.GetByID(*run.TransferID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if _, err := server.AdvancePayout(transfer.RazorpayPayoutID); err != nil {
+		t.Fatalf("AdvancePayout: %v", err)
+	}
+	requireRun(t, s, sp, models.ScheduledRunStatusSuccess, 1)
+
+	s.redeliverJobs(t, models.JobTypeScheduledRun)
+	s.runJobs(t, models.JobTypeScheduledRun)
+	requireRun(t, s, sp, models.ScheduledRunStatusSuccess, 1)
+	if n := countRows(t, db, &models.ExternalTransfer{}); n != 1 {
+		t.Fatalf("%d transfers made, want 1", n)
+	}
+}
+
+func TestScheduledRunSettledBeforeItRecordsItsTransfer(t *testing.T) {
+	db := testDB(t)
+	s, server := newTestServicesWithRazorpay(t, db, fake.Config{})
+	server.SetPayoutScript("paid@upi", fake.PayoutStep{Status: razorpay.PayoutStatusProcessed})
+	server.SetPayoutScript("closed@upi", fake.PayoutStep{Status: razorpay.PayoutStatusFailed, FailureReason: "VPA closed"})
+	wallet := createTestWallet(t, db, "10000", "50000")
+	paid := createTestSchedule(t, s, wallet, "paid@upi", "1000", 3)
+	failed := createTestSchedule(t, s, wallet, "closed@upi", "2000", 3)
+
+	// Each transfer finishes while its run is still being saved, so the
+	// completion handler finds no run and the run settles itself
+	submitBeforeRecorded(t, s)
+	if n := s.runJobs(t, models.JobTypeScheduledRun); n != 2 {
+		t.Fatalf("ran %d run jobs, want 2", n)
+	}
+
+	run := requireRun(t, s, paid, models.ScheduledRunStatusSuccess, 1)
+	if run.ReferenceID == "" {
+		t.Fatal("paid run has no reference")
+	}
+	if run := requireRun(t, s, failed, models.ScheduledRunStatusFailed, 1); run.Reason != "VPA closed" {
+		t.Fatalf("failed run's reason is %q, want the payout's", run.Reason)
+	}
+	requireAvailable(t, s, wallet, walletBalance(t, db, wallet.ID).String())
+}
+
+func TestScheduledRunInterrupted(t *testing.T) {
+	db := testDB(t)
+	s, _ := newTestServicesWithRazorpay(t, db, fake.Config{})
+	wallet := createTestWallet(t, db, "10000", "50000")
+	sp := createTestSchedule(t, s, wallet, "landlord@upi", "1000", 3)
+
+	s.runJobs(t, models.JobTypeScheduledRun)
+
+	// The worker stopped after claiming the run, before it recorded the
+	// transfer it made, and the job is delivered again
+	run := scheduleRun(t, s, sp)
+	err := db.Model(run).UpdateColumns(map[string]interface{}{
+		"status":       models.ScheduledRunStatusProcessing,
+		"transfer_id":  nil,
+		"reference_id": "",
+	}).Error
+	if err != nil {
+		t.Fatalf("failed to update run: %v", err)
+	}
+	s.redeliverJobs(t, models.JobTypeScheduledRun)
+	s.runJobs(t, models.JobTypeScheduledRun)
+
+	run = requireRun(t, s, sp, models.ScheduledRunStatusFailed, 1)
+	if !strings.Contains(run.Reason, "interrupted") {
+		t.Fatalf("run failed with %q, want it reported as interrupted", run.Reason)
+	}
+	if n := countRows(t, db, &models.ExternalTransfer{}); n != 1 {
+		t.Fatalf("%d transfers made, want the interrupted run not paid again", n)
+	}
+}
+
+func TestScheduledRunRetriesOnInsufficientFunds(t *testing.T) {
+	db := testDB(t)
+	s, _ := newTestServicesWithRazorpay(t, db, fake.Config{})
+
+	// Funds arrive before the retries run out
+	wallet := createTestWallet(t, db, "500", "50000")
+	sp := createTestSchedule(t, s, wallet, "landlord@upi", "1000", 2)
+
+	s.runJobs(t, models.JobTypeScheduledRun)
+	run := requireRun(t, s, sp, models.ScheduledRunStatusRetrying, 1)
+	if !strings.HasPrefix(run.Reason, "insufficient wallet balance") {
+		t.Fatalf("run is waiting with %q, want the short balance", run.Reason)
+	}
+	var job models.Job
+	if err := db.Where("type = ?", models.JobTypeScheduledRun).First(&job).Error; err != nil {
+		t.Fatalf("failed to load job: %v", err)
+	}
+	if job.Status != models.JobStatusQueued || job.RunAt.Before(time.Now().Add(ScheduledPaymentRetryInterval-time.Minute)) {
+		t.Fatalf("job is %s at %s, want it queued for the retry interval", job.Status, job.RunAt)
+	}
+
+	if _, err := s.ledger.RecordWalletLoad(nil, wallet.ID, decimal.NewFromInt(1000), "LOAD_1", nil); err != nil {
+		t.Fatalf("RecordWalletLoad: %v", err)
+	}
+	s.runJobs(t, models.JobTypeScheduledRun)
+	requireRun(t, s, sp, models.ScheduledRunStatusSubmitted, 2)
+
+	// They never do
+	short := createTestWallet(t, db, "500", "50000")
+	skipped := createTestSchedule(t, s, short, "landlord@upi", "1000", 1)
+
+	s.runJobs(t, models.JobTypeScheduledRun)
+	requireRun(t, s, skipped, models.ScheduledRunStatusRetrying, 1)
+	s.runJobs(t, models.JobTypeScheduledRun)
+	requireRun(t, s, skipped, models.ScheduledRunStatusSkipped, 1)
+	if n := countRows(t, db, &models.ExternalTransfer{}); n != 1 {
+		t.Fatalf("%d transfers made, want only the funded run paid", n)
+	}
+}
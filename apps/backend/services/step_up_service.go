@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/pkg/totp"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"github.com/zeusnotfound04/Tranza/utils"
+)
+
+// TOTPIssuer names the account in authenticator apps
+const TOTPIssuer = "Tranza"
+
+// Step-up methods
+const (
+	StepUpMethodPassword = "password"
+	StepUpMethodTOTP     = "totp"
+)
+
+var (
+	ErrStepUpFailed       = errors.New("step-up verification failed")
+	ErrTOTPNotEnrolled    = errors.New("authenticator is not set up; call setup first")
+	ErrTOTPAlreadyEnabled = errors.New("authenticator is already enabled")
+	ErrInvalidTOTPCode    = errors.New("invalid authenticator code")
+)
+
+// StepUpRequiredError is returned when an action needs the user to prove it
+// is them again. Methods lists what they can use.
+type StepUpRequiredError struct {
+	Methods []string `json:"methods"`
+}
+
+func (e *StepUpRequiredError) Error() string {
+	if len(e.Methods) == 0 {
+		return "step-up verification required, but the account has no password or authenticator set up"
+	}
+	return "step-up verification required: send " + strings.Join(e.Methods, " or ")
+}
+
+// StepUpService verifies a user again, with their password or an
+// authenticator code, before sensitive actions
+type StepUpService struct {
+	userRepo repositories.UserRepository
+}
+
+func NewStepUpService(userRepo repositories.UserRepository) *StepUpService {
+	return &StepUpService{
+		userRepo: userRepo,
+	}
+}
+
+// Methods lists the step-up methods the user can use. OAuth accounts have no
+// password, so they can only use an authenticator.
+func (s *StepUpService) Methods(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	var methods []string
+	if user.Password != "" {
+		methods = append(methods, StepUpMethodPassword)
+	}
+	if user.TOTPEnabled {
+		methods = append(methods, StepUpMethodTOTP)
+	}
+	return methods, nil
+}
+
+// Verify checks a password or authenticator code and returns the method used.
+// An authenticator code is preferred when both are given.
+func (s *StepUpService) Verify(ctx context.Context, userID uuid.UUID, password, code string) (string, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get user: %w", err)
+	}
+
+	switch {
+	case code != "" && user.TOTPEnabled:
+		if totp.Validate(user.TOTPSecret, code, time.Now()) {
+			return StepUpMethodTOTP, nil
+		}
+		return StepUpMethodTOTP, ErrStepUpFailed
+	case password != "" && user.Password != "":
+		if utils.VerifyPassword(password, user.Password) {
+			return StepUpMethodPassword, nil
+		}
+		return StepUpMethodPassword, ErrStepUpFailed
+	}
+
+	methods, err := s.Methods(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	return "", &StepUpRequiredError{Methods: methods}
+}
+
+// SetupTOTP creates a new authenticator secret for the user. It is not used
+// until EnableTOTP confirms the user's app produces valid codes.
+func (s *StepUpService) SetupTOTP(ctx context.Context, userID uuid.UUID) (*dto.TOTPSetupResponse, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.UpdateTOTP(ctx, userID, secret, false); err != nil {
+		return nil, fmt.Errorf("failed to save authenticator secret: %w", err)
+	}
+
+	return &dto.TOTPSetupResponse{
+		Secret:     secret,
+		OTPAuthURL: totp.URL(TOTPIssuer, user.Email, secret),
+	}, nil
+}
+
+// EnableTOTP turns on the authenticator once the user shows a valid code
+func (s *StepUpService) EnableTOTP(ctx context.Context, userID uuid.UUID, code string) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.TOTPEnabled {
+		return ErrTOTPAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return ErrTOTPNotEnrolled
+	}
+	if !totp.Validate(user.TOTPSecret, code, time.Now()) {
+		return ErrInvalidTOTPCode
+	}
+
+	if err := s.userRepo.UpdateTOTP(ctx, userID, user.TOTPSecret, true); err != nil {
+		return fmt.Errorf("failed to enable authenticator: %w", err)
+	}
+	return nil
+}
+
+// DisableTOTP turns off the authenticator. A current code is required.
+func (s *StepUpService) DisableTOTP(ctx context.Context, userID uuid.UUID, code string) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.TOTPEnabled {
+		return ErrTOTPNotEnrolled
+	}
+	if !totp.Validate(user.TOTPSecret, code, time.Now()) {
+		return ErrInvalidTOTPCode
+	}
+
+	if err := s.userRepo.UpdateTOTP(ctx, userID, "", false); err != nil {
+		return fmt.Errorf("failed to disable authenticator: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/pkg/razorpay"
+	"github.com/zeusnotfound04/Tranza/pkg/risk"
+	"github.com/zeusnotfound04/Tranza/pkg/sms"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB opens the database named by TEST_DATABASE_URL in a schema of its
+// own, migrated like the server's, and drops the schema when the test ends.
+// Tests that need a database are skipped without one, e.g.
+//
+//	TEST_DATABASE_URL="host=localhost user=postgres password=... dbname=postgres sslmode=disable" go test ./services/
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	admin, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to connect to the test database: %v", err)
+	}
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("failed to create schema %s: %v", schema, err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if strings.Contains(dsn, "://") {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		dsn += separator + "search_path=" + schema
+	} else {
+		dsn += " search_path=" + schema
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to connect to schema %s: %v", schema, err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	err = db.AutoMigrate(
+		&models.User{},
+		&models.Transaction{},
+		&models.Wallet{},
+		&models.ScheduledPayment{},
+		&models.ScheduledPaymentRun{},
+		&models.Beneficiary{},
+		&models.PayoutBatch{},
+		&models.PayoutBatchRow{},
+		&models.PhoneVPA{},
+		&models.FeePlan{},
+		&models.FeePlanVersion{},
+		&models.FeePlanAssignment{},
+		&models.ExternalTransfer{},
+		&models.LedgerAccount{},
+		&models.JournalEntry{},
+		&models.Posting{},
+		&models.WalletHold{},
+		&models.Job{},
+		&models.RiskAssessment{},
+		&models.Refund{},
+		&models.RefundStatusEvent{},
+		&models.ReconciliationRun{},
+		&models.ReconciliationMismatch{},
+		&models.IdempotencyKey{},
+	)
+	if err != nil {
+		t.Fatalf("failed to migrate the test database: %v", err)
+	}
+	return db
+}
+
+// createTestWallet adds a user and an active wallet holding balance, with
+// the given daily limit and a monthly limit ten times that
+func createTestWallet(t *testing.T, db *gorm.DB, balance, dailyLimit string) *models.Wallet {
+	t.Helper()
+
+	id := uuid.New()
+	user := &models.User{
+		ID:       id,
+		Email:    id.String() + "@example.com",
+		Username: "user_" + id.String()[:8],
+		IsActive: true,
+		Role:     models.UserRoleUser,
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	limit := decimal.RequireFromString(dailyLimit)
+	wallet := &models.Wallet{
+		ID:           uuid.New(),
+		UserID:       user.ID,
+		Balance:      decimal.RequireFromString(balance),
+		Currency:     "INR",
+		Status:       "active",
+		DailyLimit:   limit,
+		MonthlyLimit: limit.Mul(decimal.NewFromInt(10)),
+	}
+	if err := db.Create(wallet).Error; err != nil {
+		t.Fatalf("failed to create wallet: %v", err)
+	}
+	return wallet
+}
+
+// walletBalance reads a wallet's stored balance
+func walletBalance(t *testing.T, db *gorm.DB, walletID uuid.UUID) decimal.Decimal {
+	t.Helper()
+
+	var wallet models.Wallet
+	if err := db.First(&wallet, "id = ?", walletID).Error; err != nil {
+		t.Fatalf("failed to load wallet %s: %v", walletID, err)
+	}
+	return wallet.Balance
+}
+
+func requireAmount(t *testing.T, what string, got decimal.Decimal, want string) {
+	t.Helper()
+	if !got.Equal(decimal.RequireFromString(want)) {
+		t.Fatalf("%s = %s, want %s", what, got.StringFixed(2), want)
+	}
+}
+
+// testServices are the money services wired as routes.SetupRoutes wires
+// them, paying out through the Razorpay client given
+type testServices struct {
+	db                *gorm.DB
+	jobs              *JobQueue
+	jobRepo           *repositories.JobRepository
+	transferRepo      *repositories.ExternalTransferRepository
+	holdRepo          *repositories.WalletHoldRepository
+	ledger            *LedgerService
+	holds             *HoldService
+	limits            *LimitsService
+	walletTransfers   *WalletTransferService
+	externalTransfers *ExternalTransferService
+	batches           *PayoutBatchService
+	scheduled         *ScheduledPaymentService
+}
+
+func newTestServices(db *gorm.DB, client *razorpay.Client) *testServices {
+	userRepo := repositories.NewUserRepository(db)
+	txnRepo := repositories.NewTransactionRepository(db)
+	walletRepo := repositories.NewWalletRepository(db)
+	externalTransferRepo := repositories.NewExternalTransferRepository(db)
+	ledgerRepo := repositories.NewLedgerRepository(db)
+	walletHoldRepo := repositories.NewWalletHoldRepository(db)
+	jobRepo := repositories.NewJobRepository(db)
+	riskRepo := repositories.NewRiskRepository(db)
+	beneficiaryRepo := repositories.NewBeneficiaryRepository(db)
+	feePlanRepo := repositories.NewFeePlanRepository(db)
+
+	notificationService := NewNotificationService(sms.NewLogSender())
+	ledgerService := NewLedgerService(db, ledgerRepo, walletRepo, walletHoldRepo)
+	holdService := NewHoldService(db, walletHoldRepo, walletRepo)
+	jobQueue := NewJobQueue(jobRepo)
+	limitsService := NewLimitsService(walletRepo, txnRepo, walletHoldRepo)
+	// No risk rules, so every payment is allowed
+	riskService := NewRiskService(riskRepo, risk.NewEngine(risk.DefaultThresholds))
+	walletTransferService := NewWalletTransferService(db, walletRepo, userRepo, txnRepo, ledgerService, limitsService, holdService, riskService, notificationService)
+	phoneResolver := NewPhoneResolver(NewTranzaUserResolver(userRepo, walletRepo))
+	feeService := NewFeeService(db, feePlanRepo, externalTransferRepo, walletRepo, userRepo)
+	externalTransferService := NewExternalTransferService(db, externalTransferRepo, beneficiaryRepo, walletRepo, txnRepo, ledgerService, limitsService, holdService, riskService, jobQueue, client, NewIFSCDirectory(""), 0, phoneResolver, walletTransferService, feeService, notificationService)
+	scheduledPaymentService := NewScheduledPaymentService(db, repositories.NewScheduledPaymentRepository(db), walletRepo, externalTransferRepo, holdService, externalTransferService, walletTransferService, jobQueue, notificationService)
+	payoutBatchService := NewPayoutBatchService(db, repositories.NewPayoutBatchRepository(db), walletRepo, externalTransferRepo, holdService, externalTransferService, jobQueue, notificationService)
+
+	externalTransferService.RegisterJobHandlers()
+	scheduledPaymentService.RegisterJobHandlers()
+	scheduledPaymentService.RegisterTransferHandlers()
+	payoutBatchService.RegisterJobHandlers()
+	payoutBatchService.RegisterTransferHandlers()
+
+	return &testServices{
+		db:                db,
+		jobs:              jobQueue,
+		jobRepo:           jobRepo,
+		transferRepo:      externalTransferRepo,
+		holdRepo:          walletHoldRepo,
+		ledger:            ledgerService,
+		holds:             holdService,
+		limits:            limitsService,
+		walletTransfers:   walletTransferService,
+		externalTransfers: externalTransferService,
+		batches:           payoutBatchService,
+		scheduled:         scheduledPaymentService,
+	}
+}
+
+// runJobs runs every queued job of the given types once, as a worker would,
+// without waiting for the time they are due
+func (s *testServices) runJobs(t *testing.T, jobTypes ...string) int {
+	t.Helper()
+
+	err := s.db.Model(&models.Job{}).
+		Where("type IN ? AND status = ?", jobTypes, models.JobStatusQueued).
+		Update("run_at", time.Now().Add(-time.Second)).Error
+	if err != nil {
+		t.Fatalf("failed to make jobs due: %v", err)
+	}
+	jobs, err := s.jobRepo.Claim("test-worker", jobTypes, JobVisibilityTimeout, 100)
+	if err != nil {
+		t.Fatalf("failed to claim jobs: %v", err)
+	}
+	for _, job := range jobs {
+		s.jobs.run(job)
+	}
+	return len(jobs)
+}
+
+// redeliverJobs makes the finished jobs of a type claimable again, as if
+// their worker had died before recording that they were done
+func (s *testServices) redeliverJobs(t *testing.T, jobType string) {
+	t.Helper()
+
+	err := s.db.Model(&models.Job{}).
+		Where("type = ? AND status = ?", jobType, models.JobStatusSucceeded).
+		Updates(map[string]interface{}{
+			"status":       models.JobStatusRunning,
+			"locked_until": time.Now().Add(-time.Second),
+			"completed_at": nil,
+		}).Error
+	if err != nil {
+		t.Fatalf("failed to redeliver jobs: %v", err)
+	}
+}
@@ -20,6 +20,7 @@ import (
 type WalletService struct {
 	walletRepo      *repositories.WalletRepository
 	transactionRepo *repositories.TransactionRepository
+	ledgerService   *LedgerService
 	razorpayClient  *razorpay.Client
 	notificationSvc *NotificationService
 	db              *gorm.DB
@@ -28,6 +29,7 @@ type WalletService struct {
 func NewWalletService(
 	walletRepo *repositories.WalletRepository,
 	transactionRepo *repositories.TransactionRepository,
+	ledgerService *LedgerService,
 	razorpayClient *razorpay.Client,
 	notificationSvc *NotificationService,
 	db *gorm.DB,
@@ -35,6 +37,7 @@ func NewWalletService(
 	return &WalletService{
 		walletRepo:      walletRepo,
 		transactionRepo: transactionRepo,
+		ledgerService:   ledgerService,
 		razorpayClient:  razorpayClient,
 		notificationSvc: notificationSvc,
 		db:              db,
@@ -164,19 +167,13 @@ func (s *WalletService) VerifyAndCreditWallet(userID, paymentID, orderID, signat
 		return nil, errors.New("transaction already processed")
 	}
 
-	// Update wallet balance
-	wallet, err := s.walletRepo.GetByID(transaction.WalletID)
-	if err != nil {
-		tx.Rollback()
-		return nil, errors.New("wallet not found")
-	}
-
-	newBalance := wallet.Balance.Add(amount)
-	err = s.walletRepo.UpdateBalance(tx, wallet.ID, newBalance)
+	// Credit wallet through the ledger
+	wallet, err := s.ledgerService.RecordWalletLoad(tx, transaction.WalletID, amount, transaction.ReferenceID, &transaction.ID)
 	if err != nil {
 		tx.Rollback()
 		return nil, fmt.Errorf("failed to update wallet balance: %v", err)
 	}
+	newBalance := wallet.Balance
 
 	// Update transaction
 	transaction.Status = "success"
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/pkg/risk"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"github.com/zeusnotfound04/Tranza/utils"
+	"gorm.io/gorm"
+)
+
+// Constants for wallet to wallet transfers
+const (
+	MinWalletTransferAmount = 1.0    // ₹1
+	MaxWalletTransferAmount = 100000 // ₹1,00,000
+)
+
+type WalletTransferService struct {
+	db              *gorm.DB
+	walletRepo      *repositories.WalletRepository
+	userRepo        repositories.UserRepository
+	transactionRepo *repositories.TransactionRepository
+	ledgerService   *LedgerService
+	limitsService   *LimitsService
+	holdService     *HoldService
+	riskService     *RiskService
+	notificationSvc *NotificationService
+}
+
+func NewWalletTransferService(
+	db *gorm.DB,
+	walletRepo *repositories.WalletRepository,
+	userRepo repositories.UserRepository,
+	transactionRepo *repositories.TransactionRepository,
+	ledgerService *LedgerService,
+	limitsService *LimitsService,
+	holdService *HoldService,
+	riskService *RiskService,
+	notificationSvc *NotificationService,
+) *WalletTransferService {
+	return &WalletTransferService{
+		db:              db,
+		walletRepo:      walletRepo,
+		userRepo:        userRepo,
+		transactionRepo: transactionRepo,
+		ledgerService:   ledgerService,
+		limitsService:   limitsService,
+		holdService:     holdService,
+		riskService:     riskService,
+		notificationSvc: notificationSvc,
+	}
+}
+
+// TransferToWallet moves money from the user's wallet to another Tranza
+// wallet, once the risk checks have scored it
+func (s *WalletTransferService) TransferToWallet(userID string, req *dto.WalletTransferRequest) (*dto.WalletTransferResponse, error) {
+	return s.transfer(userID, req, true)
+}
+
+// transfer makes a wallet transfer, scoring it first when assess is set.
+// Callers that already scored the payment, such as a phone transfer to a
+// Tranza user, pass false.
+func (s *WalletTransferService) transfer(userID string, req *dto.WalletTransferRequest, assess bool) (*dto.WalletTransferResponse, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	if req.Amount.LessThan(decimal.NewFromFloat(MinWalletTransferAmount)) {
+		return nil, fmt.Errorf("minimum transfer amount is ₹%.2f", MinWalletTransferAmount)
+	}
+	if req.Amount.GreaterThan(decimal.NewFromFloat(MaxWalletTransferAmount)) {
+		return nil, fmt.Errorf("maximum transfer amount is ₹%d", MaxWalletTransferAmount)
+	}
+
+	senderWallet, err := s.walletRepo.GetByUserID(uid)
+	if err != nil {
+		return nil, errors.New("wallet not found")
+	}
+	if senderWallet.Status != "active" {
+		return nil, errors.New("wallet is not active")
+	}
+
+	recipientWallet, recipientUser, err := s.resolveRecipient(req.Recipient)
+	if err != nil {
+		return nil, err
+	}
+	if recipientWallet.ID == senderWallet.ID {
+		return nil, errors.New("cannot transfer to your own wallet")
+	}
+	if recipientWallet.Status != "active" {
+		return nil, errors.New("recipient wallet is not active")
+	}
+
+	// Funds held to pay this transfer are its to spend
+	available, _, err := s.holdService.GetAvailableBalance(senderWallet)
+	if err != nil {
+		return nil, err
+	}
+	if req.FundingHold != "" {
+		if hold, err := s.holdService.GetHold(req.FundingHold); err == nil && hold.IsActive() {
+			available = available.Add(decimal.Min(hold.Amount, req.Amount))
+		}
+	}
+	if available.LessThan(req.Amount) {
+		return nil, errors.New("insufficient wallet balance")
+	}
+
+	// Score the transfer before any money moves
+	var assessment *models.RiskAssessment
+	if assess {
+		assessment, err = s.riskService.Assess(&risk.Payment{
+			Kind:      walletTransferRiskKind(req.InitiatedBy),
+			UserID:    uid,
+			Amount:    req.Amount,
+			Recipient: "@" + recipientUser.Username,
+			IPAddress: req.IPAddress,
+			UserAgent: req.UserAgent,
+		}, walletTransferConfirmation(req))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	senderName := userID
+	if sender, err := s.userRepo.FindByID(context.Background(), uid); err == nil {
+		senderName = sender.Username
+	}
+
+	referenceID := utils.GenerateTransactionReference("P2P")
+	debitTransactionID := uuid.New()
+
+	var debitTransaction, creditTransaction *models.Transaction
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// The transfer's share of its funding hold is freed only for this
+		// debit, and stays held if the debit fails
+		if req.FundingHold != "" {
+			if err := s.holdService.ReduceHold(tx, req.FundingHold, req.Amount, "paid by wallet transfer"); err != nil {
+				return fmt.Errorf("failed to update funding hold: %w", err)
+			}
+		}
+
+		// Checked with the wallet locked so concurrent debits cannot both fit under the limits
+		if err := s.limitsService.CheckDebitWithTx(tx, senderWallet.ID, DebitKindWalletTransfer, req.Amount); err != nil {
+			return err
+		}
+
+		fromWallet, toWallet, err := s.ledgerService.RecordWalletTransfer(tx, senderWallet.ID, recipientWallet.ID, req.Amount, referenceID, &debitTransactionID)
+		if err != nil {
+			return err
+		}
+
+		debitTransaction = &models.Transaction{
+			ID:           debitTransactionID,
+			WalletID:     fromWallet.ID,
+			UserID:       uid,
+			Type:         utils.TransactionTypeWalletTransferOut,
+			Amount:       req.Amount,
+			BalanceAfter: fromWallet.Balance,
+			Currency:     "INR",
+			Description:  s.describeTransfer(req.Description, "Transfer to "+recipientUser.Username),
+			Status:       utils.TransactionStatusSuccess,
+			ReferenceID:  referenceID,
+			IPAddress:    req.IPAddress,
+			UserAgent:    req.UserAgent,
+		}
+		if _, err := s.transactionRepo.CreateWithTx(tx, debitTransaction); err != nil {
+			return err
+		}
+
+		creditTransaction = &models.Transaction{
+			WalletID:     toWallet.ID,
+			UserID:       toWallet.UserID,
+			Type:         utils.TransactionTypeWalletTransferIn,
+			Amount:       req.Amount,
+			BalanceAfter: toWallet.Balance,
+			Currency:     "INR",
+			Description:  s.describeTransfer(req.Description, "Transfer from "+senderName),
+			Status:       utils.TransactionStatusSuccess,
+			ReferenceID:  referenceID + "_IN",
+		}
+		if _, err := s.transactionRepo.CreateWithTx(tx, creditTransaction); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to transfer funds: %w", err)
+	}
+
+	if assessment != nil {
+		s.riskService.LinkAssessment(assessment, debitTransaction.ID)
+	}
+
+	// Notify both parties
+	go s.notificationSvc.SendWalletTransferSentNotification(userID, recipientUser.Username, req.Amount, debitTransaction.BalanceAfter)
+	go s.notificationSvc.SendWalletTransferReceivedNotification(recipientUser.ID.String(), senderName, req.Amount, creditTransaction.BalanceAfter)
+
+	utils.LogTransaction(
+		debitTransaction.ID.String(),
+		userID,
+		utils.TransactionTypeWalletTransferOut,
+		req.Amount.String(),
+		utils.TransactionStatusSuccess,
+	)
+
+	return &dto.WalletTransferResponse{
+		ReferenceID:       referenceID,
+		TransactionID:     debitTransaction.ID.String(),
+		Amount:            req.Amount,
+		Currency:          "INR",
+		RecipientWalletID: recipientWallet.ID.String(),
+		RecipientName:     recipientUser.Username,
+		NewBalance:        debitTransaction.BalanceAfter,
+		Status:            utils.TransactionStatusSuccess,
+		Message:           fmt.Sprintf("₹%s sent to %s", req.Amount.StringFixed(2), recipientUser.Username),
+		CreatedAt:         debitTransaction.CreatedAt.Format(time.RFC3339),
+	}, nil
+}
+
+// resolveRecipient finds the recipient wallet by wallet ID, email or username
+func (s *WalletTransferService) resolveRecipient(recipient string) (*models.Wallet, *models.User, error) {
+	recipient = strings.TrimSpace(recipient)
+	ctx := context.Background()
+
+	if walletID, err := uuid.Parse(recipient); err == nil {
+		wallet, err := s.walletRepo.GetByID(walletID)
+		if err != nil {
+			return nil, nil, errors.New("recipient not found")
+		}
+		user, err := s.userRepo.FindByID(ctx, wallet.UserID)
+		if err != nil {
+			return nil, nil, errors.New("recipient not found")
+		}
+		return wallet, user, nil
+	}
+
+	var user *models.User
+	var err error
+	if strings.Contains(recipient, "@") && !strings.HasPrefix(recipient, "@") {
+		user, err = s.userRepo.FindByEmail(ctx, recipient)
+	} else {
+		user, err = s.userRepo.FindByUsername(ctx, strings.TrimPrefix(recipient, "@"))
+	}
+	if err != nil {
+		return nil, nil, errors.New("recipient not found")
+	}
+
+	wallet, err := s.walletRepo.GetByUserID(user.ID)
+	if err != nil {
+		return nil, nil, errors.New("recipient does not have a wallet")
+	}
+
+	return wallet, user, nil
+}
+
+// walletTransferRiskKind scores transfers made by bots apart from the user's own
+func walletTransferRiskKind(initiatedBy string) string {
+	if initiatedBy == models.InitiatedByBot {
+		return risk.KindBotTransfer
+	}
+	return risk.KindWalletTransfer
+}
+
+// walletTransferConfirmation returns the risk assessment the user confirmed
+// for a transfer. Only the user can confirm a risky transfer, from the app.
+func walletTransferConfirmation(req *dto.WalletTransferRequest) string {
+	if req.InitiatedBy != models.InitiatedByUser {
+		return ""
+	}
+	return req.ConfirmAssessmentID
+}
+
+func (s *WalletTransferService) describeTransfer(note, fallback string) string {
+	if note != "" {
+		return note
+	}
+	return fallback
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/zeusnotfound04/Tranza/models"
+	"github.com/zeusnotfound04/Tranza/models/dto"
+	"github.com/zeusnotfound04/Tranza/pkg/risk"
+	"github.com/zeusnotfound04/Tranza/repositories"
+	"github.com/zeusnotfound04/Tranza/utils"
+	"gorm.io/gorm"
+)
+
+func countRows(t *testing.T, db *gorm.DB, model interface{}) int64 {
+	t.Helper()
+
+	var count int64
+	if err := db.Model(model).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+	return count
+}
+
+// failWalletCredits makes saving the recipient's side of a wallet transfer
+// fail with the returned error
+func failWalletCredits(t *testing.T, db *gorm.DB) error {
+	t.Helper()
+
+	failCredit := errors.New("credit transaction not saved")
+	err := db.Callback().Create().Before("gorm:create").Register("test:fail_credit", func(tx *gorm.DB) {
+		if txn, ok := tx.Statement.Dest.(*models.Transaction); ok && txn.Type == utils.TransactionTypeWalletTransferIn {
+			tx.AddError(failCredit)
+		}
+	})
+	if err != nil {
+		t.Fatalf("failed to register callback: %v", err)
+	}
+	return failCredit
+}
+
+func TestTransferToWallet(t *testing.T) {
+	db := testDB(t)
+	s := newTestServices(db, nil)
+	sender := createTestWallet(t, db, "1000", "50000")
+	recipient := createTestWallet(t, db, "100", "50000")
+
+	result, err := s.walletTransfers.TransferToWallet(sender.UserID.String(), &dto.WalletTransferRequest{
+		Recipient: recipient.ID.String(),
+		Amount:    decimal.NewFromInt(250),
+	})
+	if err != nil {
+		t.Fatalf("TransferToWallet: %v", err)
+	}
+	requireAmount(t, "new balance", result.NewBalance, "750")
+	requireAmount(t, "sender balance", walletBalance(t, db, sender.ID), "750")
+	requireAmount(t, "recipient balance", walletBalance(t, db, recipient.ID), "350")
+
+	var transactions []models.Transaction
+	if err := db.Order("reference_id").Find(&transactions).Error; err != nil {
+		t.Fatalf("failed to load transactions: %v", err)
+	}
+	if len(transactions) != 2 {
+		t.Fatalf("got %d transactions, want a debit and a credit", len(transactions))
+	}
+	debit, credit := transactions[0], transactions[1]
+	if debit.Type != utils.TransactionTypeWalletTransferOut || debit.WalletID != sender.ID || debit.ReferenceID != result.ReferenceID {
+		t.Fatalf("debit = %+v", debit)
+	}
+	if credit.Type != utils.TransactionTypeWalletTransferIn || credit.WalletID != recipient.ID || credit.ReferenceID != result.ReferenceID+"_IN" {
+		t.Fatalf("credit = %+v", credit)
+	}
+}
+
+func TestTransferToWalletIsAllOrNothing(t *testing.T) {
+	db := testDB(t)
+	s := newTestServices(db, nil)
+	sender := createTestWallet(t, db, "1000", "50000")
+	recipient := createTestWallet(t, db, "100", "50000")
+
+	// The recipient's side fails after the ledger has moved the money
+	failCredit := failWalletCredits(t, db)
+
+	_, err := s.walletTransfers.TransferToWallet(sender.UserID.String(), &dto.WalletTransferRequest{
+		Recipient: recipient.ID.String(),
+		Amount:    decimal.NewFromInt(250),
+	})
+	if !errors.Is(err, failCredit) {
+		t.Fatalf("TransferToWallet = %v, want %v", err, failCredit)
+	}
+
+	requireAmount(t, "sender balance", walletBalance(t, db, sender.ID), "1000")
+	requireAmount(t, "recipient balance", walletBalance(t, db, recipient.ID), "100")
+	if n := countRows(t, db, &models.Transaction{}); n != 0 {
+		t.Fatalf("%d transactions left behind, want none", n)
+	}
+	if n := countRows(t, db, &models.JournalEntry{}); n != 0 {
+		t.Fatalf("%d journal entries left behind, want none", n)
+	}
+}
+
+func TestTransferToWalletRefusals(t *testing.T) {
+	db := testDB(t)
+	s := newTestServices(db, nil)
+	sender := createTestWallet(t, db, "1000", "500")
+	recipient := createTestWallet(t, db, "0", "50000")
+
+	transfer := func(amount string) error {
+		_, err := s.walletTransfers.TransferToWallet(sender.UserID.String(), &dto.WalletTransferRequest{
+			Recipient: recipient.ID.String(),
+			Amount:    decimal.RequireFromString(amount),
+		})
+		return err
+	}
+
+	// Over the sender's daily limit
+	var limitErr *LimitExceededError
+	if err := transfer("500.01"); !errors.As(err, &limitErr) || limitErr.Limit != LimitDaily {
+		t.Fatalf("transfer over the daily limit = %v, want a daily LimitExceededError", err)
+	}
+
+	// Within the limit, but part of the balance is held
+	if _, err := s.holds.PlaceHold(nil, sender.ID, decimal.NewFromInt(700), models.WalletHoldPurposeAIPayment, "hold_1", "held", AIPaymentHoldTTL); err != nil {
+		t.Fatalf("PlaceHold: %v", err)
+	}
+	if err := transfer("301"); err == nil {
+		t.Fatal("transfer into held funds succeeded")
+	}
+
+	// To the sender's own wallet
+	if _, err := s.walletTransfers.TransferToWallet(sender.UserID.String(), &dto.WalletTransferRequest{
+		Recipient: sender.ID.String(),
+		Amount:    decimal.NewFromInt(10),
+	}); err == nil {
+		t.Fatal("transfer to the sender's own wallet succeeded")
+	}
+
+	requireAmount(t, "sender balance", walletBalance(t, db, sender.ID), "1000")
+	requireAmount(t, "recipient balance", walletBalance(t, db, recipient.ID), "0")
+	if n := countRows(t, db, &models.Transaction{}); n != 0 {
+		t.Fatalf("%d transactions recorded for refused transfers, want none", n)
+	}
+}
+
+func TestTransferToWalletFromFundingHold(t *testing.T) {
+	db := testDB(t)
+	s := newTestServices(db, nil)
+	sender := createTestWallet(t, db, "1000", "1000")
+	recipient := createTestWallet(t, db, "0", "50000")
+
+	// Everything is held, and the hold counts against the daily limit
+	if _, err := s.holds.PlaceHold(nil, sender.ID, decimal.NewFromInt(1000), models.WalletHoldPurposePayoutBatch, "payout_batch:1", "batch", PayoutBatchHoldTTL); err != nil {
+		t.Fatalf("PlaceHold: %v", err)
+	}
+	transfer := func(amount, fundingHold string) error {
+		_, err := s.walletTransfers.TransferToWallet(sender.UserID.String(), &dto.WalletTransferRequest{
+			Recipient:   recipient.ID.String(),
+			Amount:      decimal.RequireFromString(amount),
+			FundingHold: fundingHold,
+		})
+		return err
+	}
+
+	if err := transfer("100", ""); err == nil {
+		t.Fatal("transfer of held funds without their hold succeeded")
+	}
+	if err := transfer("400", "payout_batch:1"); err != nil {
+		t.Fatalf("transfer from the funding hold: %v", err)
+	}
+	requireHold(t, s, "payout_batch:1", models.WalletHoldStatusActive, "600")
+	requireAmount(t, "sender balance", walletBalance(t, db, sender.ID), "600")
+	requireAmount(t, "recipient balance", walletBalance(t, db, recipient.ID), "400")
+
+	// A transfer that fails leaves its share of the hold reserved
+	failCredit := failWalletCredits(t, db)
+	if err := transfer("250", "payout_batch:1"); !errors.Is(err, failCredit) {
+		t.Fatalf("transfer = %v, want %v", err, failCredit)
+	}
+	requireHold(t, s, "payout_batch:1", models.WalletHoldStatusActive, "600")
+	requireAmount(t, "sender balance", walletBalance(t, db, sender.ID), "600")
+}
+
+func TestTransferToWalletIsScored(t *testing.T) {
+	db := testDB(t)
+	s := newTestServices(db, nil)
+	// Every first payment to a recipient needs the user's confirmation
+	s.walletTransfers.riskService = NewRiskService(repositories.NewRiskRepository(db), risk.NewEngine(risk.DefaultThresholds, &risk.NewRecipientRule{Score: 60}))
+	sender := createTestWallet(t, db, "1000", "50000")
+	recipient := createTestWallet(t, db, "100", "50000")
+	var recipientUser models.User
+	if err := db.First(&recipientUser, "id = ?", recipient.UserID).Error; err != nil {
+		t.Fatalf("failed to load recipient: %v", err)
+	}
+
+	req := func(initiatedBy, confirmation string) *dto.WalletTransferRequest {
+		return &dto.WalletTransferRequest{
+			Recipient:           "@" + recipientUser.Username,
+			Amount:              decimal.NewFromInt(100),
+			ConfirmAssessmentID: confirmation,
+			InitiatedBy:         initiatedBy,
+		}
+	}
+
+	// The first transfer stops before any money moves
+	_, err := s.walletTransfers.TransferToWallet(sender.UserID.String(), req(models.InitiatedByUser, ""))
+	var riskErr *RiskDecisionError
+	if !errors.As(err, &riskErr) || !riskErr.NeedsConfirmation() {
+		t.Fatalf("first transfer = %v, want a risk confirmation", err)
+	}
+	requireAmount(t, "sender balance", walletBalance(t, db, sender.ID), "1000")
+
+	// A bot cannot confirm it on the user's behalf
+	if _, err := s.walletTransfers.TransferToWallet(sender.UserID.String(), req(models.InitiatedByBot, riskErr.AssessmentID)); !errors.As(err, &riskErr) {
+		t.Fatalf("bot transfer with a confirmation = %v, want a risk confirmation", err)
+	}
+
+	// The user can, once
+	_, err = s.walletTransfers.TransferToWallet(sender.UserID.String(), req(models.InitiatedByUser, ""))
+	if !errors.As(err, &riskErr) {
+		t.Fatalf("transfer = %v, want a risk confirmation", err)
+	}
+	result, err := s.walletTransfers.TransferToWallet(sender.UserID.String(), req(models.InitiatedByUser, riskErr.AssessmentID))
+	if err != nil {
+		t.Fatalf("confirmed transfer: %v", err)
+	}
+	requireAmount(t, "sender balance", walletBalance(t, db, sender.ID), "900")
+
+	var assessment models.RiskAssessment
+	if err := db.Where("subject_id = ?", result.TransactionID).First(&assessment).Error; err != nil {
+		t.Fatalf("no assessment linked to the transfer: %v", err)
+	}
+	if assessment.Kind != risk.KindWalletTransfer || !assessment.Confirmed {
+		t.Fatalf("assessment = %+v, want a confirmed wallet transfer", assessment)
+	}
+
+	// The recipient has been paid now, so the next transfer goes straight through
+	if _, err := s.walletTransfers.TransferToWallet(sender.UserID.String(), req(models.InitiatedByBot, "")); err != nil {
+		t.Fatalf("transfer to a known recipient: %v", err)
+	}
+	requireAmount(t, "sender balance", walletBalance(t, db, sender.ID), "800")
+}
@@ -18,11 +18,13 @@ import (
 
 // Transaction Types
 const (
-	TransactionTypeLoadMoney        = "load_money"
-	TransactionTypeAIPayment        = "ai_payment"
-	TransactionTypeRefund           = "refund"
-	TransactionTypeWithdrawal       = "withdrawal"
-	TransactionTypeExternalTransfer = "external_transfer"
+	TransactionTypeLoadMoney         = "load_money"
+	TransactionTypeAIPayment         = "ai_payment"
+	TransactionTypeRefund            = "refund"
+	TransactionTypeWithdrawal        = "withdrawal"
+	TransactionTypeExternalTransfer  = "external_transfer"
+	TransactionTypeWalletTransferOut = "wallet_transfer_out"
+	TransactionTypeWalletTransferIn  = "wallet_transfer_in"
 )
 
 // Transaction Status
@@ -70,6 +72,7 @@ const (
 	WebhookOrderPaid         = "order.paid"
 	WebhookRefundCreated     = "refund.created"
 	WebhookRefundProcessed   = "refund.processed"
+	WebhookRefundFailed      = "refund.failed"
 )
 
 // Logger instance